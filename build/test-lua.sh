@@ -24,6 +24,7 @@ resty \
   -I /usr/lib/lua-platform-path/lua/5.1 \
   --shdict "configuration_data 5M" \
   --shdict "certificate_data 16M" \
+  --shdict "ocsp_response_data 5M" \
   --shdict "balancer_ewma 1M" \
   --shdict "balancer_ewma_last_touched_at 1M" \
   ./rootfs/etc/nginx/lua/test/run.lua ${BUSTED_ARGS} ./rootfs/etc/nginx/lua/test/
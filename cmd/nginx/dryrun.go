@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/controller"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+const (
+	dryRunPodName = "nginx-ingress-controller-dry-run"
+)
+
+// dryRunClientset is a Kubernetes client discarding the Events, the dry-run
+// mode must not leave any trace in the cluster
+type dryRunClientset struct {
+	kubernetes.Interface
+	events kubernetes.Interface
+}
+
+func newDryRunClientset(client kubernetes.Interface) kubernetes.Interface {
+	return &dryRunClientset{
+		Interface: client,
+		events:    fake.NewSimpleClientset(),
+	}
+}
+
+func (c *dryRunClientset) CoreV1() corev1.CoreV1Interface {
+	return &dryRunCoreV1{
+		CoreV1Interface: c.Interface.CoreV1(),
+		events:          c.events.CoreV1(),
+	}
+}
+
+func (c *dryRunClientset) Core() corev1.CoreV1Interface {
+	return c.CoreV1()
+}
+
+type dryRunCoreV1 struct {
+	corev1.CoreV1Interface
+	events corev1.CoreV1Interface
+}
+
+func (c *dryRunCoreV1) Events(namespace string) corev1.EventInterface {
+	return c.events.Events(namespace)
+}
+
+// setDryRunPodDetails defines the Pod of the controller when the dry-run
+// mode does not run inside a Pod
+func setDryRunPodDetails(namespace string) {
+	if os.Getenv("POD_NAME") != "" && os.Getenv("POD_NAMESPACE") != "" {
+		return
+	}
+
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+
+	os.Setenv("POD_NAME", dryRunPodName)
+	os.Setenv("POD_NAMESPACE", namespace)
+}
+
+// setDryRunDirectories replaces the directories of the certificates and the
+// authentication files with temporal ones, the dry-run mode must not modify
+// the files used by a running controller in the same container
+func setDryRunDirectories() (string, error) {
+	dir, err := ioutil.TempDir("", "nginx-ingress-dry-run")
+	if err != nil {
+		return "", err
+	}
+
+	file.DefaultSSLDirectory = filepath.Join(dir, "ssl")
+	file.AuthDirectory = filepath.Join(dir, "auth")
+	auth.AuthDirectory = file.AuthDirectory
+
+	return dir, nil
+}
+
+// createManifestsClient creates a fake Kubernetes client containing the
+// objects defined in the manifests of a directory and the Pod of the
+// controller
+func createManifestsClient(dir string) (kubernetes.Interface, error) {
+	objects, err := k8s.ReadManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Read %v objects from the manifests of %v", len(objects), dir)
+
+	client := fake.NewSimpleClientset(objects...)
+
+	podName := os.Getenv("POD_NAME")
+	podNs := os.Getenv("POD_NAMESPACE")
+	_, err = client.CoreV1().Pods(podNs).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		_, err = client.CoreV1().Pods(podNs).Create(&apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      podName,
+				Namespace: podNs,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// dryRun prints the NGINX configuration resulting from the objects of the
+// cluster and returns the exit code of the dry-run mode. The temporal
+// directory dir is removed before returning.
+func dryRun(conf *controller.Configuration, fs file.Filesystem, dir string) int {
+	defer os.RemoveAll(dir)
+
+	ngx := controller.NewNGINXController(conf, metric.NewDummyCollector(), fs)
+
+	err := ngx.DryRun(os.Stdout)
+	if err != nil {
+		klog.Errorf("Invalid NGINX configuration: %v", err)
+		klog.Flush()
+		return 1
+	}
+
+	klog.Info("The NGINX configuration is valid")
+	klog.Flush()
+	return 0
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+)
+
+func TestCreateManifestsClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifests")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "service.yaml"), []byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: demo
+spec:
+  ports:
+  - port: 80
+`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	os.Setenv("POD_NAME", "controller")
+	os.Setenv("POD_NAMESPACE", "ingress-nginx")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("POD_NAMESPACE")
+
+	client, err := createManifestsClient(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = client.CoreV1().Services(apiv1.NamespaceDefault).Get("demo", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("expected the Service of the manifests but returned %v", err)
+	}
+
+	_, err = client.CoreV1().Pods("ingress-nginx").Get("controller", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("expected the Pod of the controller but returned %v", err)
+	}
+}
+
+func TestDryRunClientsetDiscardsEvents(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	dryRunClient := newDryRunClientset(client)
+
+	_, err := dryRunClient.CoreV1().Events(apiv1.NamespaceDefault).Create(&apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "event",
+			Namespace: apiv1.NamespaceDefault,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events, err := client.CoreV1().Events(apiv1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.Items) != 0 {
+		t.Errorf("expected no events in the cluster but %v were created", len(events.Items))
+	}
+
+	_, err = dryRunClient.CoreV1().Services(apiv1.NamespaceDefault).Create(&apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo",
+			Namespace: apiv1.NamespaceDefault,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = client.CoreV1().Services(apiv1.NamespaceDefault).Get("demo", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("expected the requests other than events to reach the cluster but returned %v", err)
+	}
+}
+
+func TestSetDryRunPodDetails(t *testing.T) {
+	os.Unsetenv("POD_NAME")
+	os.Unsetenv("POD_NAMESPACE")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("POD_NAMESPACE")
+
+	setDryRunPodDetails("")
+
+	if os.Getenv("POD_NAME") != dryRunPodName {
+		t.Errorf("expected %v as POD_NAME but %v was returned", dryRunPodName, os.Getenv("POD_NAME"))
+	}
+	if os.Getenv("POD_NAMESPACE") != apiv1.NamespaceDefault {
+		t.Errorf("expected default as POD_NAMESPACE but %v was returned", os.Getenv("POD_NAMESPACE"))
+	}
+}
+
+func TestSetDryRunDirectories(t *testing.T) {
+	sslDir, authDir := file.DefaultSSLDirectory, file.AuthDirectory
+	defer func() {
+		file.DefaultSSLDirectory, file.AuthDirectory, auth.AuthDirectory = sslDir, authDir, authDir
+	}()
+
+	dir, err := setDryRunDirectories()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{file.DefaultSSLDirectory, file.AuthDirectory, auth.AuthDirectory} {
+		if !strings.HasPrefix(d, dir) {
+			t.Errorf("expected %v to be inside the temporal directory %v", d, dir)
+		}
+	}
+}
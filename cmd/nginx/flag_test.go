@@ -18,8 +18,11 @@ package main
 
 import (
 	"flag"
+	"net"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 )
 
 // resetForTesting clears all flag state and sets the usage function as directed.
@@ -75,3 +78,143 @@ func TestFlagConflict(t *testing.T) {
 		t.Fatalf("Expected an error parsing flags but none returned")
 	}
 }
+
+func TestInvalidIngressLabelSelector(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--ingress-label-selector", "tier in edge"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestValidatingWebhookWithoutCertificate(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--validating-webhook", ":8443"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidPublishService(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--publish-service", "ingress-nginx"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidMetricsMaxHosts(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--metrics-max-hosts", "-1"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidSSLSessionTicketKeyFlags(t *testing.T) {
+	invalid := [][]string{
+		{"--ssl-session-ticket-key-secret", "tickets"},
+		{"--ssl-session-ticket-key-rotation-period", "1h"},
+		{"--ssl-session-ticket-key-secret", "default/tickets", "--ssl-session-ticket-key-rotation-period", "30s"},
+		{"--ssl-session-ticket-key-secret", "default/tickets", "--disable-ssl-session-tickets"},
+	}
+
+	for _, args := range invalid {
+		resetForTesting(func() { t.Fatal("Parsing failed") })
+
+		oldArgs := os.Args
+		os.Args = append([]string{"cmd", "--http-port", "0", "--https-port", "0"}, args...)
+
+		_, _, err := parseFlags()
+		if err == nil {
+			t.Errorf("Expected an error parsing flags %v but none returned", args)
+		}
+
+		os.Args = oldArgs
+	}
+}
+
+func TestSyncQueueFlags(t *testing.T) {
+	invalid := [][]string{
+		{"--sync-queue-base-delay", "0s"},
+		{"--sync-queue-base-delay", "1s", "--sync-queue-max-delay", "500ms"},
+		{"--sync-queue-burst", "0"},
+	}
+
+	for _, args := range invalid {
+		resetForTesting(func() { t.Fatal("Parsing failed") })
+
+		oldArgs := os.Args
+		os.Args = append([]string{"cmd", "--http-port", "0", "--https-port", "0"}, args...)
+
+		_, _, err := parseFlags()
+		if err == nil {
+			t.Errorf("Expected an error parsing flags %v but none returned", args)
+		}
+
+		os.Args = oldArgs
+	}
+
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0",
+		"--sync-queue-base-delay", "1s", "--sync-queue-max-delay", "1m", "--sync-queue-burst", "10"}
+
+	_, conf, err := parseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	if conf.SyncQueueBaseDelay != time.Second || conf.SyncQueueMaxDelay != time.Minute || conf.SyncQueueBurst != 10 {
+		t.Errorf("unexpected sync queue configuration: %v, %v, %v", conf.SyncQueueBaseDelay, conf.SyncQueueMaxDelay, conf.SyncQueueBurst)
+	}
+}
+
+func TestDryRunConfigDir(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", port, "--https-port", port, "--dry-run-config-dir", "/tmp/manifests"}
+
+	_, conf, err := parseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	if !conf.DryRun {
+		t.Errorf("Expected the flag --dry-run-config-dir to enable the dry-run mode")
+	}
+	if conf.DryRunConfigDir != "/tmp/manifests" {
+		t.Errorf("Expected /tmp/manifests as the dry-run directory but %v was returned", conf.DryRunConfigDir)
+	}
+}
@@ -20,18 +20,25 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/k8s"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/internal/task"
 )
 
 func parseFlags() (bool, *controller.Configuration, error) {
@@ -76,6 +83,10 @@ controller for servicing HTTP traffic.`)
 The key in the map indicates the external port to be used. The value is a
 reference to a Service in the form "namespace/name:port", where "port" can
 either be a port name or number.`)
+		ipAccessConfigMapName = flags.String("ip-access-configmap", "",
+			`Name of the ConfigMap containing the CIDRs denied ("deny" key) and allowed
+("allow" key) to access every server, as comma separated lists. Allowed CIDRs
+take precedence over denied ones. Changes are applied without reloading NGINX.`)
 
 		resyncPeriod = flags.Duration("sync-period", 0,
 			`Period at which the controller forces the repopulation of its local object stores. Disabled by default.`)
@@ -99,6 +110,26 @@ defined by the healthz-port parameter are forwarded internally to this path.`)
 
 		healthCheckTimeout = flags.Duration("health-check-timeout", 10, `Time limit, in seconds, for a probe to health-check-path to succeed.`)
 
+		deepHealthCheck = flags.Bool("deep-health-check", false,
+			`Extend the healthz endpoint to verify the NGINX worker processes are running
+and the dynamic configuration endpoint responds.`)
+
+		deepHealthCheckReload = flags.Bool("deep-health-check-reload", false,
+			`Extend the deep health check to verify the last reload of NGINX succeeded.
+Requires deep-health-check.`)
+
+		publishNotReadyEndpoints = flags.Bool("publish-not-ready-endpoints", false,
+			`Include the not ready addresses of the Endpoints in the upstreams of the Services with
+publishNotReadyAddresses enabled.`)
+
+		dryRun = flags.Bool("dry-run", false,
+			`Render the NGINX configuration resulting from the Ingress objects, check it with "nginx -t",
+print it and exit. The objects are read from the API server unless dry-run-config-dir is set.`)
+
+		dryRunConfigDir = flags.String("dry-run-config-dir", "",
+			`Directory with the YAML or JSON manifests (Ingresses, Services, Endpoints, Secrets and ConfigMaps)
+used in place of the objects of the API server. Implies dry-run.`)
+
 		updateStatus = flags.Bool("update-status", true,
 			`Update the load-balancer status of Ingress objects this controller satisfies.
 Requires setting the publish-service parameter to a valid Service reference.`)
@@ -125,6 +156,11 @@ Requires the update-status parameter.`)
 		enableSSLPassthrough = flags.Bool("enable-ssl-passthrough", false,
 			`Enable SSL Passthrough.`)
 
+		enableStreamServices = flags.Bool("enable-stream-services", false,
+			`Watch StreamService custom resources to expose TCP and UDP services, in addition
+to the ConfigMaps configured with the tcp-services-configmap and udp-services-configmap
+parameters. Requires the StreamService CustomResourceDefinition.`)
+
 		annotationsPrefix = flags.String("annotations-prefix", "nginx.ingress.kubernetes.io",
 			`Prefix of the Ingress annotations specific to the NGINX controller.`)
 
@@ -137,8 +173,38 @@ extension for this to succeed.`)
 		syncRateLimit = flags.Float32("sync-rate-limit", 0.3,
 			`Define the sync frequency upper limit`)
 
+		syncQueueBaseDelay = flags.Duration("sync-queue-base-delay", task.DefaultBaseDelay,
+			`Delay before retrying a failed sync. The delay doubles after each consecutive failure.`)
+
+		syncQueueMaxDelay = flags.Duration("sync-queue-max-delay", task.DefaultMaxDelay,
+			`Maximum delay before retrying a failed sync.`)
+
+		syncQueueBurst = flags.Int("sync-queue-burst", task.DefaultBurst,
+			`Number of failed syncs retried without delay before limiting the retries to 10 per second.`)
+
+		configDriftCheckPeriod = flags.Duration("config-drift-check-period", 1*time.Minute,
+			`Period at which the running NGINX configuration is compared against the one applied by the controller.
+The desired configuration is re-applied when they diverge. A value of 0 disables the check.`)
+
+		sslSessionTicketKeySecret = flags.String("ssl-session-ticket-key-secret", "",
+			`Secret containing the TLS session ticket keys shared by all the replicas of the controller,
+in the form "namespace/name". The keys current.key, next.key and previous.key must contain
+either 48 or 80 bytes and current.key is used to encrypt new tickets.`)
+
+		sslSessionTicketKeyRotationPeriod = flags.Duration("ssl-session-ticket-key-rotation-period", 0,
+			`Period at which the keys of the Secret defined in --ssl-session-ticket-key-secret are rotated.
+The Secret is created when it does not exist. A value of 0 disables the rotation.`)
+
+		maxmindLicenseKeySecret = flags.String("maxmind-license-key-secret", "",
+			`Secret containing the MaxMind license key used to download the GeoIP2 databases when use-geoip2
+is enabled, in the form "namespace/name". The key license-key contains the license key.`)
+
+		disableSSLSessionTickets = flags.Bool("disable-ssl-session-tickets", false,
+			`Disable TLS session tickets regardless of the ssl-session-tickets setting of the configuration ConfigMap.`)
+
 		publishStatusAddress = flags.String("publish-status-address", "",
 			`Customized address to set as the load-balancer status of Ingress objects this controller satisfies.
+Accepts a comma separated list of IP addresses and/or hostnames.
 Requires the update-status parameter.`)
 
 		dynamicCertificatesEnabled = flags.Bool("enable-dynamic-certificates", false,
@@ -149,6 +215,9 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 			`Enables the collection of NGINX metrics`)
 		metricsPerHost = flags.Bool("metrics-per-host", true,
 			`Export metrics per-host`)
+		metricsMaxHosts = flags.Int("metrics-max-hosts", 0,
+			`Maximum number of hosts exported as label of the metrics per-host.
+The requests of additional hosts are recorded without the host label. No limit when zero.`)
 
 		httpPort      = flags.Int("http-port", 80, `Port to use for servicing HTTP traffic.`)
 		httpsPort     = flags.Int("https-port", 443, `Port to use for servicing HTTPS traffic.`)
@@ -156,9 +225,31 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		sslProxyPort  = flags.Int("ssl-passthrough-proxy-port", 442, `Port to use internally for SSL Passthrough.`)
 		defServerPort = flags.Int("default-server-port", 8181, `Port to use for exposing the default server (catch-all).`)
 		healthzPort   = flags.Int("healthz-port", 10254, "Port to use for the healthz endpoint.")
+		healthzHost   = flags.String("healthz-host", "", `Address to bind the healthz, metrics and profiling endpoints to.
+All the addresses are used if this parameter is left empty.`)
 
 		disableCatchAll = flags.Bool("disable-catch-all", false,
 			`Disable support for catch-all Ingresses`)
+
+		ingressLabelSelector = flags.String("ingress-label-selector", "",
+			`Label selector used to filter the Ingress objects this controller processes (e.g. "tier=edge").
+Allows multiple controller deployments to shard the Ingresses of a cluster.
+All Ingresses are processed if this parameter is left empty.`)
+
+		disableSnippetAnnotations = flags.Bool("disable-snippet-annotations", false,
+			`Ignore the annotations containing NGINX configuration snippets, like configuration-snippet and server-snippet.`)
+
+		snippetBlockedDirectives = flags.String("snippet-blocked-directives", "*_by_lua*,lua_*,load_module,include,alias,root",
+			`Comma separated list of the NGINX directives not allowed in the snippet annotations.
+Patterns like "*_by_lua*" match groups of directives. Snippets containing a blocked directive are ignored.`)
+
+		validationWebhook = flags.String("validating-webhook", "",
+			`The address to start an admission controller on to validate incoming ingresses.
+Takes the form "<host>:port". If not provided, no admission controller is started.`)
+		validationWebhookCert = flags.String("validating-webhook-certificate", "",
+			`The path of the validating webhook certificate PEM.`)
+		validationWebhookKey = flags.String("validating-webhook-key", "",
+			`The path of the validating webhook key PEM.`)
 	)
 
 	flags.MarkDeprecated("status-port", `The status port is a unix socket now.`)
@@ -190,23 +281,57 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		class.IngressClass = *ingressClass
 	}
 
-	parser.AnnotationsPrefix = *annotationsPrefix
+	if *ingressLabelSelector != "" {
+		_, err := labels.Parse(*ingressLabelSelector)
+		if err != nil {
+			return false, nil, fmt.Errorf("Invalid value for flag --ingress-label-selector: %v", err)
+		}
 
-	// check port collisions
-	if !ing_net.IsPortAvailable(*httpPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --http-port", *httpPort)
+		klog.Infof("Watching for Ingresses matching label selector %q", *ingressLabelSelector)
 	}
 
-	if !ing_net.IsPortAvailable(*httpsPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --https-port", *httpsPort)
+	if *validationWebhook != "" && (*validationWebhookCert == "" || *validationWebhookKey == "") {
+		return false, nil, fmt.Errorf("The validating webhook requires a certificate and a key. Please check the flags --validating-webhook-certificate and --validating-webhook-key")
 	}
 
-	if !ing_net.IsPortAvailable(*defServerPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --default-server-port", *defServerPort)
+	parser.AnnotationsPrefix = *annotationsPrefix
+
+	snippet.Disabled = *disableSnippetAnnotations
+	snippet.BlockedDirectives = []string{}
+	for _, directive := range strings.Split(*snippetBlockedDirectives, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		if _, err := path.Match(directive, ""); err != nil {
+			return false, nil, fmt.Errorf("Invalid directive %q: %v. Please check the flag --snippet-blocked-directives", directive, err)
+		}
+
+		snippet.BlockedDirectives = append(snippet.BlockedDirectives, directive)
 	}
 
-	if *enableSSLPassthrough && !ing_net.IsPortAvailable(*sslProxyPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passthrough-proxy-port", *sslProxyPort)
+	if *dryRunConfigDir != "" {
+		*dryRun = true
+	}
+
+	// check port collisions, the dry-run mode does not listen on any port
+	if !*dryRun {
+		if !ing_net.IsPortAvailable(*httpPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --http-port", *httpPort)
+		}
+
+		if !ing_net.IsPortAvailable(*httpsPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --https-port", *httpsPort)
+		}
+
+		if !ing_net.IsPortAvailable(*defServerPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --default-server-port", *defServerPort)
+		}
+
+		if *enableSSLPassthrough && !ing_net.IsPortAvailable(*sslProxyPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passthrough-proxy-port", *sslProxyPort)
+		}
 	}
 
 	if !*enableSSLChainCompletion {
@@ -221,6 +346,63 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		return false, nil, fmt.Errorf("Flags --publish-service and --publish-status-address are mutually exclusive")
 	}
 
+	if *publishSvc != "" {
+		_, _, err := k8s.ParseNameNS(*publishSvc)
+		if err != nil {
+			return false, nil, fmt.Errorf("%v. Please check the flag --publish-service", err)
+		}
+	}
+
+	if *ipAccessConfigMapName != "" {
+		_, _, err := k8s.ParseNameNS(*ipAccessConfigMapName)
+		if err != nil {
+			return false, nil, fmt.Errorf("%v. Please check the flag --ip-access-configmap", err)
+		}
+	}
+
+	if *maxmindLicenseKeySecret != "" {
+		_, _, err := k8s.ParseNameNS(*maxmindLicenseKeySecret)
+		if err != nil {
+			return false, nil, fmt.Errorf("%v. Please check the flag --maxmind-license-key-secret", err)
+		}
+	}
+
+	if *sslSessionTicketKeySecret != "" {
+		_, _, err := k8s.ParseNameNS(*sslSessionTicketKeySecret)
+		if err != nil {
+			return false, nil, fmt.Errorf("%v. Please check the flag --ssl-session-ticket-key-secret", err)
+		}
+	}
+
+	if *sslSessionTicketKeyRotationPeriod != 0 {
+		if *sslSessionTicketKeySecret == "" {
+			return false, nil, fmt.Errorf("Flag --ssl-session-ticket-key-rotation-period requires the flag --ssl-session-ticket-key-secret")
+		}
+		if *sslSessionTicketKeyRotationPeriod < time.Minute {
+			return false, nil, fmt.Errorf("Flag --ssl-session-ticket-key-rotation-period must be at least one minute")
+		}
+	}
+
+	if *syncQueueBaseDelay <= 0 {
+		return false, nil, fmt.Errorf("Flag --sync-queue-base-delay must be greater than zero")
+	}
+
+	if *syncQueueMaxDelay < *syncQueueBaseDelay {
+		return false, nil, fmt.Errorf("Flag --sync-queue-max-delay must be greater than or equal to --sync-queue-base-delay")
+	}
+
+	if *metricsMaxHosts < 0 {
+		return false, nil, fmt.Errorf("Flag --metrics-max-hosts must not be negative")
+	}
+
+	if *syncQueueBurst < 1 {
+		return false, nil, fmt.Errorf("Flag --sync-queue-burst must be at least one")
+	}
+
+	if *disableSSLSessionTickets && *sslSessionTicketKeySecret != "" {
+		return false, nil, fmt.Errorf("Flags --disable-ssl-session-tickets and --ssl-session-ticket-key-secret are mutually exclusive")
+	}
+
 	nginx.HealthPath = *defHealthzURL
 
 	config := &controller.Configuration{
@@ -229,9 +411,13 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		UpdateStatus:               *updateStatus,
 		ElectionID:                 *electionID,
 		EnableProfiling:            *profiling,
+		DryRun:                     *dryRun,
+		DryRunConfigDir:            *dryRunConfigDir,
 		EnableMetrics:              *enableMetrics,
 		MetricsPerHost:             *metricsPerHost,
+		MetricsMaxHosts:            *metricsMaxHosts,
 		EnableSSLPassthrough:       *enableSSLPassthrough,
+		EnableStreamServices:       *enableStreamServices,
 		EnableSSLChainCompletion:   *enableSSLChainCompletion,
 		ResyncPeriod:               *resyncPeriod,
 		DefaultService:             *defaultSvc,
@@ -239,15 +425,24 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		ConfigMapName:              *configMap,
 		TCPConfigMapName:           *tcpConfigMapName,
 		UDPConfigMapName:           *udpConfigMapName,
+		IPAccessConfigMapName:      *ipAccessConfigMapName,
 		DefaultSSLCertificate:      *defSSLCertificate,
 		HealthCheckTimeout:         *healthCheckTimeout,
+		DeepHealthCheck:            *deepHealthCheck,
+		DeepHealthCheckReload:      *deepHealthCheckReload,
+		PublishNotReadyEndpoints:   *publishNotReadyEndpoints,
 		PublishService:             *publishSvc,
 		PublishStatusAddress:       *publishStatusAddress,
 		ForceNamespaceIsolation:    *forceIsolation,
 		UpdateStatusOnShutdown:     *updateStatusOnShutdown,
 		UseNodeInternalIP:          *useNodeInternalIP,
 		SyncRateLimit:              *syncRateLimit,
+		SyncQueueBaseDelay:         *syncQueueBaseDelay,
+		SyncQueueMaxDelay:          *syncQueueMaxDelay,
+		SyncQueueBurst:             *syncQueueBurst,
+		ConfigDriftCheckPeriod:     *configDriftCheckPeriod,
 		DynamicCertificatesEnabled: *dynamicCertificatesEnabled,
+		HealthCheckHost:            *healthzHost,
 		ListenPorts: &ngx_config.ListenPorts{
 			Default:  *defServerPort,
 			Health:   *healthzPort,
@@ -255,7 +450,17 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 			HTTPS:    *httpsPort,
 			SSLProxy: *sslProxyPort,
 		},
-		DisableCatchAll: *disableCatchAll,
+		DisableCatchAll:           *disableCatchAll,
+		IngressLabelSelector:      *ingressLabelSelector,
+		ValidationWebhook:         *validationWebhook,
+		ValidationWebhookCertPath: *validationWebhookCert,
+		ValidationWebhookKeyPath:  *validationWebhookKey,
+
+		SSLSessionTicketKeySecret:         *sslSessionTicketKeySecret,
+		SSLSessionTicketKeyRotationPeriod: *sslSessionTicketKeyRotationPeriod,
+		DisableSSLSessionTickets:          *disableSSLSessionTickets,
+
+		MaxmindLicenseKeySecret: *maxmindLicenseKeySecret,
 	}
 
 	return false, config, nil
@@ -20,10 +20,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -36,14 +38,18 @@ import (
 	discovery "k8s.io/apimachinery/pkg/version"
 	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 
+	"k8s.io/ingress-nginx/internal/dbg"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/internal/net/ssl"
+	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/version"
 )
 
@@ -59,13 +65,25 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "dbg" {
+		cmd := dbg.NewCommand()
+		cmd.SetArgs(os.Args[2:])
+		if err := cmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	klog.InitFlags(nil)
 
 	rand.Seed(time.Now().UnixNano())
 
-	fmt.Println(version.String())
-
 	showVersion, conf, err := parseFlags()
+	if conf == nil || !conf.DryRun {
+		// the output of the dry-run mode is the NGINX configuration
+		fmt.Println(version.String())
+	}
+
 	if showVersion {
 		os.Exit(0)
 	}
@@ -76,14 +94,38 @@ func main() {
 
 	nginxVersion()
 
+	ngxBuild, err := nginx.ReadBuildInfo("nginx")
+	if err != nil {
+		klog.Warningf("Error reading NGINX build information: %v", err)
+	}
+
+	var dryRunDir string
+	if conf.DryRun {
+		setDryRunPodDetails(conf.Namespace)
+
+		dryRunDir, err = setDryRunDirectories()
+		if err != nil {
+			klog.Fatalf("Error creating the directories of the dry-run mode: %v", err)
+		}
+	}
+
 	fs, err := file.NewLocalFS()
 	if err != nil {
 		klog.Fatal(err)
 	}
 
-	kubeClient, err := createApiserverClient(conf.APIServerHost, conf.KubeConfigFile)
-	if err != nil {
-		handleFatalInitError(err)
+	var kubeClient kubernetes.Interface
+	var restConfig *rest.Config
+	if conf.DryRunConfigDir != "" {
+		kubeClient, err = createManifestsClient(conf.DryRunConfigDir)
+		if err != nil {
+			klog.Fatalf("Error reading manifests: %v", err)
+		}
+	} else {
+		kubeClient, restConfig, err = createApiserverClient(conf.APIServerHost, conf.KubeConfigFile)
+		if err != nil {
+			handleFatalInitError(err)
+		}
 	}
 
 	if len(conf.DefaultService) > 0 {
@@ -121,6 +163,22 @@ func main() {
 
 	conf.Client = kubeClient
 
+	if conf.EnableStreamServices {
+		if restConfig == nil {
+			klog.Warning("StreamServices are not read from manifests (flag --dry-run-config-dir)")
+		} else {
+			conf.StreamServiceClient, err = streamservice.NewForConfig(restConfig)
+			if err != nil {
+				klog.Fatalf("Error creating StreamService client: %v", err)
+			}
+		}
+	}
+
+	if conf.DryRun {
+		conf.Client = newDryRunClientset(kubeClient)
+		os.Exit(dryRun(conf, fs, dryRunDir))
+	}
+
 	reg := prometheus.NewRegistry()
 
 	reg.MustRegister(prometheus.NewGoCollector())
@@ -131,7 +189,7 @@ func main() {
 
 	mc := metric.NewDummyCollector()
 	if conf.EnableMetrics {
-		mc, err = metric.NewCollector(conf.MetricsPerHost, reg)
+		mc, err = metric.NewCollector(conf.MetricsPerHost, conf.MetricsMaxHosts, reg, ngxBuild)
 		if err != nil {
 			klog.Fatalf("Error creating prometheus collector:  %v", err)
 		}
@@ -142,6 +200,7 @@ func main() {
 	go handleSigterm(ngx, func(code int) {
 		os.Exit(code)
 	})
+	go handleSighup(ngx)
 
 	mux := http.NewServeMux()
 
@@ -151,9 +210,9 @@ func main() {
 
 	registerHealthz(ngx, mux)
 	registerMetrics(reg, mux)
-	registerHandlers(mux)
+	registerHandlers(mux, ngxBuild)
 
-	go startHTTPServer(conf.ListenPorts.Health, mux)
+	go startHTTPServer(conf.HealthCheckHost, conf.ListenPorts.Health, mux)
 
 	ngx.Start()
 }
@@ -179,18 +238,34 @@ func handleSigterm(ngx *controller.NGINXController, exit exiter) {
 	exit(exitCode)
 }
 
-// createApiserverClient creates a new Kubernetes REST client. apiserverHost is
-// the URL of the API server in the format protocol://address:port/pathPrefix,
-// kubeConfig is the location of a kubeconfig file. If defined, the kubeconfig
-// file is loaded first, the URL of the API server read from the file is then
-// optionally overridden by the value of apiserverHost.
+type resyncer interface {
+	Resync()
+}
+
+// handleSighup reads the template and the configuration ConfigMap again
+// each time the process receives a SIGHUP signal
+func handleSighup(ngx resyncer) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGHUP)
+	for range signalChan {
+		klog.Info("Received SIGHUP, reading the template and the configuration ConfigMap")
+		ngx.Resync()
+	}
+}
+
+// createApiserverClient creates a new Kubernetes REST client and returns it
+// with its configuration. apiserverHost is the URL of the API server in the
+// format protocol://address:port/pathPrefix, kubeConfig is the location of a
+// kubeconfig file. If defined, the kubeconfig file is loaded first, the URL of
+// the API server read from the file is then optionally overridden by the value
+// of apiserverHost.
 // If neither apiserverHost nor kubeConfig is passed in, we assume the
 // controller runs inside Kubernetes and fallback to the in-cluster config. If
 // the in-cluster config is missing or fails, we fallback to the default config.
-func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Clientset, error) {
+func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Clientset, *rest.Config, error) {
 	cfg, err := clientcmd.BuildConfigFromFlags(apiserverHost, kubeConfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cfg.QPS = defaultQPS
@@ -201,7 +276,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var v *discovery.Info
@@ -233,7 +308,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 
 	// err is returned in case of timeout in the exponential backoff (ErrWaitTimeout)
 	if err != nil {
-		return nil, lastErr
+		return nil, nil, lastErr
 	}
 
 	// this should not happen, warn the user
@@ -244,7 +319,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 	klog.Infof("Running in Kubernetes cluster version v%v.%v (%v) - git (%v) commit %v - platform %v",
 		v.Major, v.Minor, v.GitVersion, v.GitTreeState, v.GitCommit, v.Platform)
 
-	return client, nil
+	return client, cfg, nil
 }
 
 // Handler for fatal init errors. Prints a verbose error message and exits.
@@ -257,10 +332,11 @@ func handleFatalInitError(err error) {
 		err)
 }
 
-func registerHandlers(mux *http.ServeMux) {
+func registerHandlers(mux *http.ServeMux, ngxBuild *nginx.BuildInfo) {
 	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		b, _ := json.Marshal(version.String())
+		b, _ := json.Marshal(newBuildInfo(ngxBuild))
 		w.Write(b)
 	})
 
@@ -293,6 +369,7 @@ func registerMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
 
 func registerProfiler(mux *http.ServeMux) {
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/allocs", pprof.Index)
 	mux.HandleFunc("/debug/pprof/heap", pprof.Index)
 	mux.HandleFunc("/debug/pprof/mutex", pprof.Index)
 	mux.HandleFunc("/debug/pprof/goroutine", pprof.Index)
@@ -304,9 +381,9 @@ func registerProfiler(mux *http.ServeMux) {
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 }
 
-func startHTTPServer(port int, mux *http.ServeMux) {
+func startHTTPServer(host string, port int, mux *http.ServeMux) {
 	server := &http.Server{
-		Addr:              fmt.Sprintf(":%v", port),
+		Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
 		Handler:           mux,
 		ReadTimeout:       10 * time.Second,
 		ReadHeaderTimeout: 10 * time.Second,
@@ -17,7 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"syscall"
 	"testing"
@@ -30,10 +33,12 @@ import (
 
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/version"
 )
 
 func TestCreateApiserverClient(t *testing.T) {
-	_, err := createApiserverClient("", "")
+	_, _, err := createApiserverClient("", "")
 	if err == nil {
 		t.Fatal("Expected an error creating REST client without an API server URL or kubeconfig file.")
 	}
@@ -136,3 +141,57 @@ func deleteConfigMap(cm, ns string, clientSet kubernetes.Interface, t *testing.T
 	}
 	t.Logf("Temporal configmap %v deleted", cm)
 }
+
+func TestBuildHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	registerHandlers(mux, &nginx.BuildInfo{
+		Version: "openresty/1.15.8.1",
+		Modules: []string{"http_ssl_module"},
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/build", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code 200 but %v was returned", w.Code)
+	}
+
+	info := &buildInfo{}
+	err := json.Unmarshal(w.Body.Bytes(), info)
+	if err != nil {
+		t.Fatalf("Unexpected error decoding the build information: %v", err)
+	}
+
+	if info.Release != version.RELEASE || info.Build != version.COMMIT || info.Repository != version.REPO {
+		t.Errorf("Expected the release of the controller but %+v was returned", info)
+	}
+	if info.NGINX == nil || info.NGINX.Version != "openresty/1.15.8.1" || len(info.NGINX.Modules) != 1 {
+		t.Errorf("Expected the build information of NGINX but %+v was returned", info.NGINX)
+	}
+}
+
+type fakeResyncer struct {
+	resyncs chan struct{}
+}
+
+func (f *fakeResyncer) Resync() {
+	f.resyncs <- struct{}{}
+}
+
+func TestHandleSighup(t *testing.T) {
+	ngx := &fakeResyncer{resyncs: make(chan struct{}, 1)}
+	go handleSighup(ngx)
+
+	time.Sleep(1 * time.Second)
+
+	err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	if err != nil {
+		t.Fatalf("Unexpected error sending SIGHUP signal: %v", err)
+	}
+
+	select {
+	case <-ngx.resyncs:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected a resync after receiving SIGHUP")
+	}
+}
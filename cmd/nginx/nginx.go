@@ -21,8 +21,29 @@ import (
 	"os/exec"
 
 	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/version"
 )
 
+// buildInfo contains the release of the controller and the build
+// information of NGINX exposed in the /build endpoint
+type buildInfo struct {
+	Release    string           `json:"release"`
+	Build      string           `json:"build"`
+	Repository string           `json:"repository"`
+	NGINX      *nginx.BuildInfo `json:"nginx"`
+}
+
+func newBuildInfo(ngxBuild *nginx.BuildInfo) *buildInfo {
+	return &buildInfo{
+		Release:    version.RELEASE,
+		Build:      version.COMMIT,
+		Repository: version.REPO,
+		NGINX:      ngxBuild,
+	}
+}
+
 func nginxVersion() {
 	flag := "-v"
 
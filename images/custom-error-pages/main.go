@@ -23,6 +23,8 @@ import (
 	"mime"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -55,6 +57,12 @@ const (
 	// ServicePort name of the header that contains the matched Service port in the Ingress
 	ServicePort = "X-Service-Port"
 
+	// RequestID is a unique ID that identifies the request - same as for backend service
+	RequestID = "X-Request-ID"
+
+	// LanguageHeader name of the header with the languages preferred by the client
+	LanguageHeader = "Accept-Language"
+
 	// ErrFilesPathVar is the name of the environment variable indicating
 	// the location on disk of files served by the handler.
 	ErrFilesPathVar = "ERROR_FILES_PATH"
@@ -91,21 +99,18 @@ func errorHandler(path string) func(http.ResponseWriter, *http.Request) {
 			w.Header().Set(IngressName, r.Header.Get(IngressName))
 			w.Header().Set(ServiceName, r.Header.Get(ServiceName))
 			w.Header().Set(ServicePort, r.Header.Get(ServicePort))
+			w.Header().Set(RequestID, r.Header.Get(RequestID))
 		}
 
-		format := r.Header.Get(FormatHeader)
-		if format == "" {
-			format = "text/html"
-			log.Printf("format not specified. Using %v", format)
-		}
-
-		cext, err := mime.ExtensionsByType(format)
-		if err != nil {
-			log.Printf("unexpected error reading media type extension: %v. Using %v", err, ext)
-		} else if len(cext) == 0 {
-			log.Printf("couldn't get media type extension. Using %v", ext)
-		} else {
+		format := "text/html"
+		for _, mediaType := range preferred(r.Header.Get(FormatHeader)) {
+			cext, err := mime.ExtensionsByType(mediaType)
+			if err != nil || len(cext) == 0 {
+				continue
+			}
+			format = mediaType
 			ext = cext[0]
+			break
 		}
 		w.Header().Set(ContentType, format)
 
@@ -120,21 +125,34 @@ func errorHandler(path string) func(http.ResponseWriter, *http.Request) {
 		if !strings.HasPrefix(ext, ".") {
 			ext = "." + ext
 		}
-		file := fmt.Sprintf("%v/%v%v", path, code, ext)
-		f, err := os.Open(file)
+
+		// localized pages are located in a directory for each language
+		dirs := []string{}
+		for _, lang := range preferred(r.Header.Get(LanguageHeader)) {
+			if validLanguage.MatchString(lang) {
+				dirs = append(dirs, fmt.Sprintf("%v/%v", path, lang))
+			}
+		}
+		dirs = append(dirs, path)
+
+		scode := strconv.Itoa(code)
+		var f *os.File
+		var file string
+		for _, dir := range dirs {
+			for _, name := range []string{scode, fmt.Sprintf("%cxx", scode[0])} {
+				file = fmt.Sprintf("%v/%v%v", dir, name, ext)
+				f, err = os.Open(file)
+				if err == nil {
+					break
+				}
+			}
+			if err == nil {
+				break
+			}
+		}
 		if err != nil {
 			log.Printf("unexpected error opening file: %v", err)
-			scode := strconv.Itoa(code)
-			file := fmt.Sprintf("%v/%cxx%v", path, scode[0], ext)
-			f, err := os.Open(file)
-			if err != nil {
-				log.Printf("unexpected error opening file: %v", err)
-				http.NotFound(w, r)
-				return
-			}
-			defer f.Close()
-			log.Printf("serving custom error response for code %v and format %v from file %v", code, format, file)
-			io.Copy(w, f)
+			http.NotFound(w, r)
 			return
 		}
 		defer f.Close()
@@ -150,3 +168,53 @@ func errorHandler(path string) func(http.ResponseWriter, *http.Request) {
 		requestDuration.WithLabelValues(proto).Observe(duration)
 	}
 }
+
+// validLanguage matches language tags like "en" or "pt-BR", which are
+// safe to use as the name of a directory
+var validLanguage = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// preferred returns the values of a header with quality values, like
+// Accept or Accept-Language, sorted by preference. Wildcards and values
+// with quality 0 are ignored.
+func preferred(header string) []string {
+	type value struct {
+		name    string
+		quality float64
+	}
+
+	values := []value{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.TrimSpace(params[0])
+		if name == "" || name == "*" || strings.HasSuffix(name, "/*") {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		values = append(values, value{name, quality})
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].quality > values[j].quality
+	})
+
+	names := []string{}
+	for _, v := range values {
+		names = append(names, v.name)
+	}
+
+	return names
+}
@@ -251,6 +251,8 @@ fi
 
 cd "$BUILD_PATH"
 luarocks install lrexlib-pcre 2.7.2-1 PCRE_LIBDIR=${PCRE_DIR}
+# OpenID Connect authentication (includes lua-resty-http, lua-resty-session and lua-resty-jwt)
+luarocks install lua-resty-openidc 1.7.1-1
 
 cd "$BUILD_PATH/lua-resty-core-0.1.16rc4"
 make install
@@ -280,6 +282,17 @@ make install
 cd "$BUILD_PATH/lua-resty-cookie-0.1.0"
 make install
 
+# shared stores of the global rate limits
+cd "$BUILD_PATH"
+git clone --depth=1 -b v0.14 https://github.com/openresty/lua-resty-memcached.git
+cd "$BUILD_PATH/lua-resty-memcached"
+make install
+
+cd "$BUILD_PATH"
+git clone --depth=1 -b v0.27 https://github.com/openresty/lua-resty-redis.git
+cd "$BUILD_PATH/lua-resty-redis"
+make install
+
 # build and install lua-resty-waf with dependencies
 /install_lua_resty_waf.sh
 
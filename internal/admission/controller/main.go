@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/api/admission/v1beta1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// Checker must return an error if the ingress provided as argument
+// contains invalid instructions
+type Checker interface {
+	CheckIngress(ing *extensions.Ingress) error
+}
+
+// IngressAdmission implements the AdmissionController interface
+// to handle Admission Reviews and deny requests that are not validated
+type IngressAdmission struct {
+	Checker Checker
+}
+
+var ingressResource = metav1.GroupVersionResource{
+	Group:    extensions.SchemeGroupVersion.Group,
+	Version:  extensions.SchemeGroupVersion.Version,
+	Resource: "ingresses",
+}
+
+// HandleAdmission populates the admission Response with Allowed=false if
+// the Object is an Ingress that would prevent NGINX from reloading the
+// configuration, and with Allowed=true otherwise
+func (ia *IngressAdmission) HandleAdmission(ar *v1beta1.AdmissionReview) error {
+	if ar.Request == nil {
+		klog.Infof("rejecting nil request")
+		ar.Response = &v1beta1.AdmissionResponse{
+			Allowed: false,
+		}
+		return nil
+	}
+
+	klog.V(3).Infof("handling ingress admission webhook request for %s %s in namespace %s", ar.Request.Resource.String(), ar.Request.Name, ar.Request.Namespace)
+
+	if ar.Request.Resource != ingressResource {
+		err := fmt.Errorf("rejecting admission review because the request does not contain an Ingress resource but %s with name %s in namespace %s",
+			ar.Request.Resource.String(), ar.Request.Name, ar.Request.Namespace)
+		ar.Response = &v1beta1.AdmissionResponse{
+			UID:     ar.Request.UID,
+			Allowed: false,
+			Result:  &metav1.Status{Message: err.Error()},
+		}
+		return err
+	}
+
+	ingress := extensions.Ingress{}
+	err := json.Unmarshal(ar.Request.Object.Raw, &ingress)
+	if err != nil {
+		klog.Errorf("failed to decode ingress %s in namespace %s: %s, refusing it", ar.Request.Name, ar.Request.Namespace, err.Error())
+		ar.Response = &v1beta1.AdmissionResponse{
+			UID:     ar.Request.UID,
+			Allowed: false,
+			Result:  &metav1.Status{Message: err.Error()},
+		}
+		return err
+	}
+
+	err = ia.Checker.CheckIngress(&ingress)
+	if err != nil {
+		klog.Errorf("failed to generate configuration for ingress %s in namespace %s: %s, refusing it", ar.Request.Name, ar.Request.Namespace, err.Error())
+		ar.Response = &v1beta1.AdmissionResponse{
+			UID:     ar.Request.UID,
+			Allowed: false,
+			Result:  &metav1.Status{Message: err.Error()},
+		}
+		return err
+	}
+
+	klog.V(3).Infof("successfully validated configuration, accepting ingress %s in namespace %s", ar.Request.Name, ar.Request.Namespace)
+	ar.Response = &v1beta1.AdmissionResponse{
+		UID:     ar.Request.UID,
+		Allowed: true,
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/klog"
+)
+
+// AdmissionController checks if an object
+// is allowed in the cluster
+type AdmissionController interface {
+	HandleAdmission(*v1beta1.AdmissionReview) error
+}
+
+// AdmissionControllerServer implements an HTTP server
+// for kubernetes validating webhook
+// https://kubernetes.io/docs/reference/access-authn-authz/admission-controllers/#validatingadmissionwebhook
+type AdmissionControllerServer struct {
+	AdmissionController AdmissionController
+}
+
+// NewAdmissionControllerServer instantiates an admission controller server
+func NewAdmissionControllerServer(ac AdmissionController) *AdmissionControllerServer {
+	return &AdmissionControllerServer{
+		AdmissionController: ac,
+	}
+}
+
+// ServeHTTP implements http.Server method
+func (acs *AdmissionControllerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	review, err := parseAdmissionReview(r.Body)
+	if err != nil {
+		klog.Errorf("Unexpected error decoding request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the outcome of the validation is returned in the review response
+	err = acs.AdmissionController.HandleAdmission(review)
+	if err != nil {
+		klog.Errorf("Unexpected error handling admission review: %v", err)
+	}
+
+	err = writeAdmissionReview(w, review)
+	if err != nil {
+		klog.Errorf("Unexpected error writing response: %v", err)
+	}
+}
+
+func parseAdmissionReview(r io.Reader) (*v1beta1.AdmissionReview, error) {
+	review := &v1beta1.AdmissionReview{}
+	err := json.NewDecoder(r).Decode(review)
+	return review, err
+}
+
+func writeAdmissionReview(w http.ResponseWriter, ar *v1beta1.AdmissionReview) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(ar)
+}
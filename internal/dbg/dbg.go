@@ -0,0 +1,293 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dbg implements the commands used to inspect the state of the
+// NGINX instance managed by the ingress controller.
+package dbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+const (
+	backendsPath = "/configuration/backends"
+	generalPath  = "/configuration/general"
+	certsPath    = "/configuration/certs"
+	ipAccessPath = "/configuration/ip-access"
+
+	statePath = "/state"
+)
+
+// NewCommand returns the dbg command, used by the dbg binary and by the
+// dbg subcommand of the ingress controller
+func NewCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:          "dbg",
+		Short:        "dbg is a tool for quickly inspecting the state of the nginx instance",
+		SilenceUsage: true,
+	}
+
+	backendsCmd := &cobra.Command{
+		Use:   "backends",
+		Short: "Inspect the dynamically-loaded backends information",
+	}
+	rootCmd.AddCommand(backendsCmd)
+
+	backendsAllCmd := &cobra.Command{
+		Use:   "all",
+		Short: "Output the all dynamic backend information as a JSON array",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printJSON(cmd.OutOrStdout(), backendsPath)
+		},
+	}
+	backendsCmd.AddCommand(backendsAllCmd)
+
+	backendsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Output a newline-separated list of the backend names",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return backendsList(cmd.OutOrStdout())
+		},
+	}
+	backendsCmd.AddCommand(backendsListCmd)
+
+	backendsGetCmd := &cobra.Command{
+		Use:   "get [backend name]",
+		Short: "Output the backend information only for the backend that has this name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return backendsGet(cmd.OutOrStdout(), args[0])
+		},
+	}
+	backendsCmd.AddCommand(backendsGetCmd)
+
+	certCmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Inspect dynamic SSL certificates",
+	}
+
+	certGetCmd := &cobra.Command{
+		Use:   "get [hostname]",
+		Short: "Get the dynamically-loaded certificate information for the given hostname",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return certGet(cmd.OutOrStdout(), args[0])
+		},
+	}
+	certCmd.AddCommand(certGetCmd)
+
+	rootCmd.AddCommand(certCmd)
+
+	generalCmd := &cobra.Command{
+		Use:   "general",
+		Short: "Output the general dynamic lua state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printJSON(cmd.OutOrStdout(), generalPath)
+		},
+	}
+	rootCmd.AddCommand(generalCmd)
+
+	ipAccessCmd := &cobra.Command{
+		Use:   "ip-access",
+		Short: "Output the IP access lists loaded in the lua state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printJSON(cmd.OutOrStdout(), ipAccessPath)
+		},
+	}
+	rootCmd.AddCommand(ipAccessCmd)
+
+	stateCmd := &cobra.Command{
+		Use:   "state [backends|servers|certificates|configuration|nginx.conf|checksum]",
+		Short: "Output the running state of the controller, or only one of its sections",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := statePath
+			if len(args) == 1 {
+				path = statePath + "/" + args[0]
+			}
+
+			return printState(cmd.OutOrStdout(), path)
+		},
+	}
+	rootCmd.AddCommand(stateCmd)
+
+	confCmd := &cobra.Command{
+		Use:   "conf",
+		Short: "Dump the contents of /etc/nginx/nginx.conf",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := nginx.ReadNginxConf()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), conf)
+			return nil
+		},
+	}
+	rootCmd.AddCommand(confCmd)
+
+	return rootCmd
+}
+
+// getStatus returns the body of a successful request to the NGINX status server
+func getStatus(path string) ([]byte, error) {
+	statusCode, body, err := nginx.NewGetStatusRequest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != 200 {
+		return nil, fmt.Errorf("nginx returned code %v", statusCode)
+	}
+
+	return body, nil
+}
+
+func printJSON(out io.Writer, path string) error {
+	body, err := getStatus(path)
+	if err != nil {
+		return err
+	}
+
+	var prettyBuffer bytes.Buffer
+	err = json.Indent(&prettyBuffer, body, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, prettyBuffer.String())
+	return nil
+}
+
+// printState outputs the state exposed by the introspection server of the controller
+func printState(out io.Writer, path string) error {
+	statusCode, body, err := nginx.NewGetIntrospectionRequest(path)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != 200 {
+		return fmt.Errorf("the controller returned code %v: %v", statusCode, string(body))
+	}
+
+	var prettyBuffer bytes.Buffer
+	err = json.Indent(&prettyBuffer, body, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, prettyBuffer.String())
+	return nil
+}
+
+func backendsList(out io.Writer) error {
+	body, err := getStatus(backendsPath)
+	if err != nil {
+		return err
+	}
+
+	names, err := backendNames(body)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Fprintln(out, name)
+	}
+
+	return nil
+}
+
+func backendsGet(out io.Writer, name string) error {
+	body, err := getStatus(backendsPath)
+	if err != nil {
+		return err
+	}
+
+	backend, err := findBackend(body, name)
+	if err != nil {
+		return err
+	}
+
+	printed, err := json.MarshalIndent(backend, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, string(printed))
+	return nil
+}
+
+func certGet(out io.Writer, host string) error {
+	statusCode, body, err := nginx.NewGetStatusRequest(certsPath + "?hostname=" + url.QueryEscape(host))
+	if err != nil {
+		return err
+	}
+
+	switch statusCode {
+	case 200:
+		fmt.Fprint(out, string(body))
+		return nil
+	case 404:
+		return fmt.Errorf("no cert found for host %v", host)
+	default:
+		return fmt.Errorf("nginx returned code %v: %v", statusCode, string(body))
+	}
+}
+
+// backendNames returns the names of the backends contained in the JSON
+// array returned by the dynamic configuration
+func backendNames(body []byte) ([]string, error) {
+	var backends []map[string]interface{}
+	err := json.Unmarshal(body, &backends)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(backends))
+	for _, backend := range backends {
+		name, _ := backend["name"].(string)
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// findBackend returns the backend with the given name contained in the
+// JSON array returned by the dynamic configuration
+func findBackend(body []byte, name string) (map[string]interface{}, error) {
+	var backends []map[string]interface{}
+	err := json.Unmarshal(body, &backends)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backend := range backends {
+		if backend["name"] == name {
+			return backend, nil
+		}
+	}
+
+	return nil, fmt.Errorf("backend %v was not found", name)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbg
+
+import (
+	"reflect"
+	"testing"
+)
+
+const backends = `[{"name":"default-echo-80","port":0},{"name":"upstream-default-backend","port":0}]`
+
+func TestBackendNames(t *testing.T) {
+	names, err := backendNames([]byte(backends))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"default-echo-80", "upstream-default-backend"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v but returned %v", expected, names)
+	}
+
+	_, err = backendNames([]byte("{}"))
+	if err == nil {
+		t.Errorf("expected an error parsing an invalid list of backends")
+	}
+}
+
+func TestFindBackend(t *testing.T) {
+	backend, err := findBackend([]byte(backends), "default-echo-80")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if backend["name"] != "default-echo-80" {
+		t.Errorf("expected the backend default-echo-80 but returned %v", backend)
+	}
+
+	_, err = findBackend([]byte(backends), "missing")
+	if err == nil {
+		t.Errorf("expected an error looking for a missing backend")
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	for _, args := range [][]string{
+		{"backends", "all"},
+		{"backends", "list"},
+		{"backends", "get"},
+		{"certs", "get"},
+		{"general"},
+		{"ip-access"},
+		{"state"},
+		{"conf"},
+	} {
+		found, _, err := cmd.Find(args)
+		if err != nil {
+			t.Errorf("unexpected error looking for the command %v: %v", args, err)
+			continue
+		}
+
+		if found.Name() != args[len(args)-1] {
+			t.Errorf("expected the command %v but returned %v", args, found.Name())
+		}
+	}
+}
@@ -25,13 +25,18 @@ import (
 
 // SHA1 returns the SHA1 of a file.
 func SHA1(filename string) string {
-	hasher := sha1.New()
 	s, err := ioutil.ReadFile(filename)
 	if err != nil {
 		klog.Errorf("Error reading file %v", err)
 		return ""
 	}
 
-	hasher.Write(s)
+	return SHA1Content(s)
+}
+
+// SHA1Content returns the SHA1 of the provided content.
+func SHA1Content(content []byte) string {
+	hasher := sha1.New()
+	hasher.Write(content)
 	return hex.EncodeToString(hasher.Sum(nil))
 }
@@ -44,6 +44,11 @@ func TestSHA1(t *testing.T) {
 		if sha != test.sha {
 			t.Fatalf("expected %v but returned %s", test.sha, sha)
 		}
+
+		sha = SHA1Content(test.content)
+		if sha != test.sha {
+			t.Fatalf("expected %v but returned %s", test.sha, sha)
+		}
 	}
 
 	sha := SHA1("")
@@ -40,7 +40,7 @@ type Filesystem interface {
 func NewLocalFS() (Filesystem, error) {
 	fs := filesystem.DefaultFs{}
 
-	for _, directory := range directories {
+	for _, directory := range []string{DefaultSSLDirectory, AuthDirectory} {
 		err := fs.MkdirAll(directory, ReadWriteByUser)
 		if err != nil {
 			return nil, err
@@ -16,7 +16,9 @@ limitations under the License.
 
 package file
 
-const (
+// The directories are variables to allow the dry-run mode to write the
+// files to a temporal directory instead of the ones used by NGINX.
+var (
 	// AuthDirectory default directory used to store files
 	// to authenticate request
 	AuthDirectory = "/etc/ingress-controller/auth"
@@ -27,10 +29,3 @@ const (
 	// certificate and key.
 	DefaultSSLDirectory = "/etc/ingress-controller/ssl"
 )
-
-var (
-	directories = []string{
-		DefaultSSLDirectory,
-		AuthDirectory,
-	}
-)
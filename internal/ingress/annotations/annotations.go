@@ -17,10 +17,15 @@ limitations under the License.
 package annotations
 
 import (
+	"fmt"
+
 	"github.com/imdario/mergo"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwt"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/oidc"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslcipher"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/sslprotocol"
 	"k8s.io/klog"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -30,22 +35,36 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/alias"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreqglobal"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/backendprotocol"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/bodysize"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/clientbodybuffersize"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/compression"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customhttperrors"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/defaultbackend"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/globalratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/grpcweb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/http2pushpreload"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/ipaccess"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/loadbalancing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentracing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/plugins"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/portinredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyprotocol"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
@@ -56,8 +75,13 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sessionaffinity"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslpassthrough"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/streamsnippet"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/tcpport"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamhashby"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamkeepalive"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamvhost"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/usehttp3"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/websocket"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/xforwardedprefix"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
@@ -75,17 +99,27 @@ type Ingress struct {
 	Canary               canary.Config
 	CertificateAuth      authtls.Config
 	ClientBodyBufferSize string
+	BodySizeError        bodysize.Config
 	ConfigurationSnippet string
 	Connection           connection.Config
 	CorsConfig           cors.Config
+	CustomHeaders        customheaders.Config
 	CustomHTTPErrors     []int
 	DefaultBackend       *apiv1.Service
+	DisableCompression   bool
 	//TODO: Change this back into an error when https://github.com/imdario/mergo/issues/100 is resolved
 	Denied             *string
 	ExternalAuth       authreq.Config
+	EnableGlobalAuth   bool
+	FastCGI            fastcgi.Config
+	GRPCWeb            bool
 	HTTP2PushPreload   bool
+	Mirror             mirror.Config
 	Proxy              proxy.Config
+	ProxySSL           proxyssl.Config
 	RateLimit          ratelimit.Config
+	GlobalRateLimit    globalratelimit.Config
+	BypassIPAccess     bool
 	Redirect           redirect.Config
 	Rewrite            rewrite.Config
 	Satisfy            string
@@ -94,17 +128,31 @@ type Ingress struct {
 	ServiceUpstream    bool
 	SessionAffinity    sessionaffinity.Config
 	SSLPassthrough     bool
+	TCPPort            int
+	StreamSnippet      string
+	ProxyProtocol      string
 	UsePortInRedirects bool
+	UseHTTP3           bool
 	UpstreamHashBy     upstreamhashby.Config
+	UpstreamKeepalive  upstreamkeepalive.Config
 	LoadBalancing      string
 	UpstreamVhost      string
+	WebSocket          websocket.Config
 	Whitelist          ipwhitelist.SourceRange
-	XForwardedPrefix   bool
+	XForwardedPrefix   xforwardedprefix.Config
+	HSTS               hsts.Config
 	SSLCiphers         string
+	SSLProtocols       string
 	Logs               log.Config
+	Opentracing        opentracing.Config
 	LuaRestyWAF        luarestywaf.Config
 	InfluxDB           influxdb.Config
 	ModSecurity        modsecurity.Config
+	OIDC               oidc.Config
+	JWT                jwt.Config
+	Plugins            []string
+	// Warnings contains the invalid annotations replaced with default values
+	Warnings []string
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
@@ -121,15 +169,25 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"Canary":               canary.NewParser(cfg),
 			"CertificateAuth":      authtls.NewParser(cfg),
 			"ClientBodyBufferSize": clientbodybuffersize.NewParser(cfg),
+			"BodySizeError":        bodysize.NewParser(cfg),
 			"ConfigurationSnippet": snippet.NewParser(cfg),
 			"Connection":           connection.NewParser(cfg),
 			"CorsConfig":           cors.NewParser(cfg),
+			"CustomHeaders":        customheaders.NewParser(cfg),
 			"CustomHTTPErrors":     customhttperrors.NewParser(cfg),
 			"DefaultBackend":       defaultbackend.NewParser(cfg),
+			"DisableCompression":   compression.NewParser(cfg),
 			"ExternalAuth":         authreq.NewParser(cfg),
+			"EnableGlobalAuth":     authreqglobal.NewParser(cfg),
+			"FastCGI":              fastcgi.NewParser(cfg),
+			"GRPCWeb":              grpcweb.NewParser(cfg),
 			"HTTP2PushPreload":     http2pushpreload.NewParser(cfg),
+			"Mirror":               mirror.NewParser(cfg),
 			"Proxy":                proxy.NewParser(cfg),
+			"ProxySSL":             proxyssl.NewParser(cfg),
 			"RateLimit":            ratelimit.NewParser(cfg),
+			"GlobalRateLimit":      globalratelimit.NewParser(cfg),
+			"BypassIPAccess":       ipaccess.NewParser(cfg),
 			"Redirect":             redirect.NewParser(cfg),
 			"Rewrite":              rewrite.NewParser(cfg),
 			"Satisfy":              satisfy.NewParser(cfg),
@@ -138,18 +196,30 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"ServiceUpstream":      serviceupstream.NewParser(cfg),
 			"SessionAffinity":      sessionaffinity.NewParser(cfg),
 			"SSLPassthrough":       sslpassthrough.NewParser(cfg),
+			"TCPPort":              tcpport.NewParser(cfg),
+			"StreamSnippet":        streamsnippet.NewParser(cfg),
+			"ProxyProtocol":        proxyprotocol.NewParser(cfg),
 			"UsePortInRedirects":   portinredirect.NewParser(cfg),
+			"UseHTTP3":             usehttp3.NewParser(cfg),
 			"UpstreamHashBy":       upstreamhashby.NewParser(cfg),
+			"UpstreamKeepalive":    upstreamkeepalive.NewParser(cfg),
 			"LoadBalancing":        loadbalancing.NewParser(cfg),
 			"UpstreamVhost":        upstreamvhost.NewParser(cfg),
+			"WebSocket":            websocket.NewParser(cfg),
 			"Whitelist":            ipwhitelist.NewParser(cfg),
 			"XForwardedPrefix":     xforwardedprefix.NewParser(cfg),
+			"HSTS":                 hsts.NewParser(cfg),
 			"SSLCiphers":           sslcipher.NewParser(cfg),
+			"SSLProtocols":         sslprotocol.NewParser(cfg),
 			"Logs":                 log.NewParser(cfg),
+			"Opentracing":          opentracing.NewParser(cfg),
 			"LuaRestyWAF":          luarestywaf.NewParser(cfg),
 			"InfluxDB":             influxdb.NewParser(cfg),
 			"BackendProtocol":      backendprotocol.NewParser(cfg),
 			"ModSecurity":          modsecurity.NewParser(cfg),
+			"OIDC":                 oidc.NewParser(auth.AuthDirectory, cfg),
+			"JWT":                  jwt.NewParser(cfg),
+			"Plugins":              plugins.NewParser(cfg),
 		},
 	}
 }
@@ -169,7 +239,18 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 				continue
 			}
 
+			pia.Warnings = append(pia.Warnings, fmt.Sprintf("error reading %v annotation: %v", name, err))
+
+			// parsers returning a configuration along with an invalid
+			// content error use default values in place of the invalid ones
+			if errors.IsInvalidContent(err) && val != nil {
+				klog.Warningf("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
+				data[name] = val
+				continue
+			}
+
 			if !errors.IsLocationDenied(err) {
+				klog.Warningf("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
 				continue
 			}
 
@@ -17,6 +17,8 @@ limitations under the License.
 package annotations
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -351,3 +353,114 @@ func TestMergeLocationAnnotations(t *testing.T) {
 	}
 }
 */
+
+func TestInvalidProxyTimeoutWarnings(t *testing.T) {
+	ec := NewAnnotationExtractor(mockCfg{})
+	ing := buildIngress()
+
+	ing.SetAnnotations(map[string]string{
+		parser.GetAnnotationWithPrefix("proxy-connect-timeout"): "ten",
+		parser.GetAnnotationWithPrefix("proxy-read-timeout"):    "30",
+	})
+
+	anns := ec.Extract(ing)
+	if anns.Proxy.ReadTimeout != 30 {
+		t.Errorf("expected 30 as read-timeout but returned %v", anns.Proxy.ReadTimeout)
+	}
+	if len(anns.Warnings) != 1 {
+		t.Fatalf("expected one warning but returned %v", anns.Warnings)
+	}
+	if !strings.Contains(anns.Warnings[0], "proxy-connect-timeout") {
+		t.Errorf("expected a warning about proxy-connect-timeout but returned %v", anns.Warnings[0])
+	}
+}
+
+func TestInvalidWhitelistWarnings(t *testing.T) {
+	ec := NewAnnotationExtractor(mockCfg{})
+	ing := buildIngress()
+
+	testCases := map[string]struct {
+		whitelist string
+		cidrs     []string
+		denied    bool
+		warning   bool
+	}{
+		"invalid entry":  {"10.0.0.0/8,10.0.0.0/33", []string{"10.0.0.0/8"}, false, true},
+		"no valid entry": {"10.0.0.0/33", nil, true, true},
+		"valid entries":  {"10.0.0.0/8", []string{"10.0.0.0/8"}, false, false},
+	}
+
+	for title, tc := range testCases {
+		ing.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("whitelist-source-range"): tc.whitelist,
+		})
+
+		anns := ec.Extract(ing)
+		if !reflect.DeepEqual(anns.Whitelist.CIDR, tc.cidrs) {
+			t.Errorf("%v: expected whitelist %v but returned %v", title, tc.cidrs, anns.Whitelist.CIDR)
+		}
+		if (anns.Denied != nil) != tc.denied {
+			t.Errorf("%v: expected denied %v but returned %v", title, tc.denied, anns.Denied)
+		}
+
+		if tc.warning != (len(anns.Warnings) == 1) {
+			t.Errorf("%v: unexpected warnings %v", title, anns.Warnings)
+		}
+		if len(anns.Warnings) == 1 && !strings.Contains(anns.Warnings[0], "10.0.0.0/33") {
+			t.Errorf("%v: expected a warning naming the invalid entry but returned %v", title, anns.Warnings[0])
+		}
+	}
+}
+
+func TestAnnotationWarnings(t *testing.T) {
+	ec := NewAnnotationExtractor(mockCfg{})
+	ing := buildIngress()
+
+	testCases := map[string]struct {
+		annotations map[string]string
+		warning     string
+	}{
+		"valid annotations": {
+			map[string]string{
+				parser.GetAnnotationWithPrefix("satisfy"):         "any",
+				parser.GetAnnotationWithPrefix("proxy-body-size"): "8m",
+				parser.GetAnnotationWithPrefix("ssl-redirect"):    "false",
+			},
+			"",
+		},
+		"invalid value without default": {
+			map[string]string{
+				parser.GetAnnotationWithPrefix("satisfy"): "some",
+			},
+			"error reading Satisfy annotation: the annotation satisfy does not contain a valid value (some)",
+		},
+		"invalid configuration": {
+			map[string]string{
+				parser.GetAnnotationWithPrefix("canary-weight"): "10",
+			},
+			"error reading Canary annotation: the annotation canary does not contain a valid configuration: configured but not enabled",
+		},
+		"denied location": {
+			map[string]string{
+				parser.GetAnnotationWithPrefix("auth-url"): "invalid",
+			},
+			"error reading ExternalAuth annotation: Location denied, reason: url scheme is empty",
+		},
+	}
+
+	for title, tc := range testCases {
+		ing.SetAnnotations(tc.annotations)
+
+		anns := ec.Extract(ing)
+		if tc.warning == "" {
+			if len(anns.Warnings) != 0 {
+				t.Errorf("%v: expected no warnings but returned %v", title, anns.Warnings)
+			}
+			continue
+		}
+
+		if len(anns.Warnings) != 1 || anns.Warnings[0] != tc.warning {
+			t.Errorf("%v: expected the warning %q but returned %q", title, tc.warning, anns.Warnings)
+		}
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package auth
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	api "k8s.io/api/core/v1"
@@ -31,6 +34,15 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+const (
+	// SecretTypeAuthFile indicates the secret contains a htpasswd
+	// compatible file in the key auth
+	SecretTypeAuthFile = "auth-file"
+	// SecretTypeAuthMap indicates the keys of the secret are the
+	// usernames and the values the password hashes
+	SecretTypeAuthMap = "auth-map"
+)
+
 var (
 	authTypeRegex = regexp.MustCompile(`basic|digest`)
 	// AuthDirectory default directory used to store files
@@ -45,7 +57,10 @@ type Config struct {
 	File    string `json:"file"`
 	Secured bool   `json:"secured"`
 	FileSHA string `json:"fileSha"`
-	Secret  string `json:"secret"`
+	// Secret contains the comma separated list of secrets
+	// merged to generate the file
+	Secret     string `json:"secret"`
+	SecretType string `json:"secretType"`
 }
 
 // Equal tests for equality between two Config types
@@ -74,6 +89,9 @@ func (bd1 *Config) Equal(bd2 *Config) bool {
 	if bd1.Secret != bd2.Secret {
 		return false
 	}
+	if bd1.SecretType != bd2.SecretType {
+		return false
+	}
 	return true
 }
 
@@ -90,7 +108,8 @@ func NewParser(authDirectory string, r resolver.Resolver) parser.IngressAnnotati
 // Parse parses the annotations contained in the ingress
 // rule used to add authentication in the paths defined in the rule
 // and generated an htpasswd compatible file to be used as source
-// during the authentication process
+// during the authentication process. The content of multiple
+// secrets is merged into the same file
 func (a auth) Parse(ing *extensions.Ingress) (interface{}, error) {
 	at, err := parser.GetStringAnnotation("auth-type", ing)
 	if err != nil {
@@ -101,6 +120,15 @@ func (a auth) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return nil, ing_errors.NewLocationDenied("invalid authentication type")
 	}
 
+	secretType, err := parser.GetStringAnnotation("auth-secret-type", ing)
+	if err != nil {
+		secretType = SecretTypeAuthFile
+	}
+
+	if secretType != SecretTypeAuthFile && secretType != SecretTypeAuthMap {
+		return nil, ing_errors.NewLocationDenied("invalid authentication secret type")
+	}
+
 	s, err := parser.GetStringAnnotation("auth-secret", ing)
 	if err != nil {
 		return nil, ing_errors.LocationDenied{
@@ -108,43 +136,88 @@ func (a auth) Parse(ing *extensions.Ingress) (interface{}, error) {
 		}
 	}
 
-	name := fmt.Sprintf("%v/%v", ing.Namespace, s)
-	secret, err := a.r.GetSecret(name)
-	if err != nil {
-		return nil, ing_errors.LocationDenied{
-			Reason: errors.Wrapf(err, "unexpected error reading secret %v", name),
+	names := []string{}
+	secrets := []*api.Secret{}
+	for _, secretName := range strings.Split(s, ",") {
+		secretName = strings.TrimSpace(secretName)
+		if secretName == "" {
+			continue
 		}
+
+		name := fmt.Sprintf("%v/%v", ing.Namespace, secretName)
+		secret, err := a.r.GetSecret(name)
+		if err != nil {
+			return nil, ing_errors.LocationDenied{
+				Reason: errors.Wrapf(err, "unexpected error reading secret %v", name),
+			}
+		}
+
+		names = append(names, name)
+		secrets = append(secrets, secret)
+	}
+
+	if len(secrets) == 0 {
+		return nil, ing_errors.NewLocationDenied("invalid authentication secret")
 	}
 
 	realm, _ := parser.GetStringAnnotation("auth-realm", ing)
 
 	passFile := fmt.Sprintf("%v/%v-%v.passwd", a.authDirectory, ing.GetNamespace(), ing.GetName())
-	err = dumpSecret(passFile, secret)
+	err = dumpSecrets(passFile, secretType, secrets)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Config{
-		Type:    at,
-		Realm:   realm,
-		File:    passFile,
-		Secured: true,
-		FileSHA: file.SHA1(passFile),
-		Secret:  name,
+		Type:       at,
+		Realm:      realm,
+		File:       passFile,
+		Secured:    true,
+		FileSHA:    file.SHA1(passFile),
+		Secret:     strings.Join(names, ","),
+		SecretType: secretType,
 	}, nil
 }
 
-// dumpSecret dumps the content of a secret into a file
+// dumpSecrets dumps the content of the secrets into a file
 // in the expected format for the specified authorization
-func dumpSecret(filename string, secret *api.Secret) error {
-	val, ok := secret.Data["auth"]
-	if !ok {
-		return ing_errors.LocationDenied{
-			Reason: errors.Errorf("the secret %v does not contain a key with value auth", secret.Name),
+func dumpSecrets(filename, secretType string, secrets []*api.Secret) error {
+	var buf bytes.Buffer
+	for _, secret := range secrets {
+		if secretType == SecretTypeAuthMap {
+			if len(secret.Data) == 0 {
+				return ing_errors.LocationDenied{
+					Reason: errors.Errorf("the secret %v does not contain any user", secret.Name),
+				}
+			}
+
+			users := make([]string, 0, len(secret.Data))
+			for user := range secret.Data {
+				users = append(users, user)
+			}
+			// the order of the keys must not change the content of the file
+			sort.Strings(users)
+
+			for _, user := range users {
+				fmt.Fprintf(&buf, "%v:%v\n", user, strings.TrimSpace(string(secret.Data[user])))
+			}
+			continue
+		}
+
+		val, ok := secret.Data["auth"]
+		if !ok {
+			return ing_errors.LocationDenied{
+				Reason: errors.Errorf("the secret %v does not contain a key with value auth", secret.Name),
+			}
+		}
+
+		buf.Write(val)
+		if len(val) > 0 && val[len(val)-1] != '\n' {
+			buf.WriteByte('\n')
 		}
 	}
 
-	err := ioutil.WriteFile(filename, val, file.ReadWriteByUser)
+	err := ioutil.WriteFile(filename, buf.Bytes(), file.ReadWriteByUser)
 	if err != nil {
 		return ing_errors.LocationDenied{
 			Reason: errors.Wrap(err, "unexpected error creating password file"),
@@ -74,17 +74,37 @@ type mockSecret struct {
 }
 
 func (m mockSecret) GetSecret(name string) (*api.Secret, error) {
-	if name != "default/demo-secret" {
-		return nil, errors.Errorf("there is no secret with name %v", name)
+	switch name {
+	case "default/demo-secret":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: api.NamespaceDefault,
+				Name:      "demo-secret",
+			},
+			Data: map[string][]byte{"auth": []byte("foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0")},
+		}, nil
+	case "default/other-secret":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: api.NamespaceDefault,
+				Name:      "other-secret",
+			},
+			Data: map[string][]byte{"auth": []byte("bar:$apr1$2hNX7kTO$4CVmHmYIzBzCdIrJKuXCA1\n")},
+		}, nil
+	case "default/map-secret":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: api.NamespaceDefault,
+				Name:      "map-secret",
+			},
+			Data: map[string][]byte{
+				"foo": []byte("$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0"),
+				"bar": []byte("$apr1$2hNX7kTO$4CVmHmYIzBzCdIrJKuXCA1\n"),
+			},
+		}, nil
 	}
 
-	return &api.Secret{
-		ObjectMeta: meta_v1.ObjectMeta{
-			Namespace: api.NamespaceDefault,
-			Name:      "demo-secret",
-		},
-		Data: map[string][]byte{"auth": []byte("foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0")},
-	}, nil
+	return nil, errors.Errorf("there is no secret with name %v", name)
 }
 
 func TestIngressWithoutAuth(t *testing.T) {
@@ -197,20 +217,91 @@ func dummySecretContent(t *testing.T) (string, string, *api.Secret) {
 	return tmpfile.Name(), dir, s
 }
 
-func TestDumpSecret(t *testing.T) {
+func TestIngressAuthWithMultipleSecrets(t *testing.T) {
+	testCases := map[string]struct {
+		secretType string
+		secrets    string
+		expected   string
+	}{
+		"auth-file secrets": {
+			"",
+			"demo-secret, other-secret",
+			"foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0\nbar:$apr1$2hNX7kTO$4CVmHmYIzBzCdIrJKuXCA1\n",
+		},
+		"auth-map secret": {
+			"auth-map",
+			"map-secret",
+			"bar:$apr1$2hNX7kTO$4CVmHmYIzBzCdIrJKuXCA1\nfoo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		ing := buildIngress()
+
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("auth-type")] = "basic"
+		data[parser.GetAnnotationWithPrefix("auth-secret")] = tc.secrets
+		if tc.secretType != "" {
+			data[parser.GetAnnotationWithPrefix("auth-secret-type")] = tc.secretType
+		}
+		ing.SetAnnotations(data)
+
+		_, dir, _ := dummySecretContent(t)
+		defer os.RemoveAll(dir)
+
+		i, err := NewParser(dir, &mockSecret{}).Parse(ing)
+		if err != nil {
+			t.Fatalf("%v: unexpected error with ingress: %v", name, err)
+		}
+
+		auth := i.(*Config)
+		content, err := ioutil.ReadFile(auth.File)
+		if err != nil {
+			t.Fatalf("%v: unexpected error reading password file: %v", name, err)
+		}
+		if string(content) != tc.expected {
+			t.Errorf("%v: expected password file %q but returned %q", name, tc.expected, content)
+		}
+	}
+}
+
+func TestIngressAuthInvalidSecretType(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("auth-type")] = "basic"
+	data[parser.GetAnnotationWithPrefix("auth-secret")] = "demo-secret"
+	data[parser.GetAnnotationWithPrefix("auth-secret-type")] = "invalid"
+	ing.SetAnnotations(data)
+
+	_, dir, _ := dummySecretContent(t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewParser(dir, &mockSecret{}).Parse(ing)
+	if err == nil {
+		t.Errorf("expected an error with invalid secret type")
+	}
+}
+
+func TestDumpSecrets(t *testing.T) {
 	tmpfile, dir, s := dummySecretContent(t)
 	defer os.RemoveAll(dir)
 
 	sd := s.Data
 	s.Data = nil
 
-	err := dumpSecret(tmpfile, s)
+	err := dumpSecrets(tmpfile, SecretTypeAuthFile, []*api.Secret{s})
 	if err == nil {
 		t.Errorf("Expected error with secret without auth")
 	}
 
+	err = dumpSecrets(tmpfile, SecretTypeAuthMap, []*api.Secret{s})
+	if err == nil {
+		t.Errorf("Expected error with secret without users")
+	}
+
 	s.Data = sd
-	err = dumpSecret(tmpfile, s)
+	err = dumpSecrets(tmpfile, SecretTypeAuthFile, []*api.Secret{s})
 	if err != nil {
 		t.Errorf("Unexpected error creating htpasswd file %v: %v", tmpfile, err)
 	}
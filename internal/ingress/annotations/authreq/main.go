@@ -17,6 +17,7 @@ limitations under the License.
 package authreq
 
 import (
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
@@ -40,8 +42,18 @@ type Config struct {
 	ResponseHeaders []string `json:"responseHeaders,omitempty"`
 	RequestRedirect string   `json:"requestRedirect"`
 	AuthSnippet     string   `json:"authSnippet"`
+	// AuthCacheKey contains the key of the cached responses of the
+	// authentication service. An empty key disables the cache
+	AuthCacheKey string `json:"authCacheKey"`
+	// AuthCacheDuration contains the time the responses of the
+	// authentication service are cached, optionally per status code
+	AuthCacheDuration []string `json:"authCacheDuration"`
 }
 
+// DefaultCacheDuration is the time responses of the authentication
+// service are cached when no duration is configured
+const DefaultCacheDuration = "200 202 401 5m"
+
 // Equal tests for equality between two Config types
 func (e1 *Config) Equal(e2 *Config) bool {
 	if e1 == e2 {
@@ -80,6 +92,17 @@ func (e1 *Config) Equal(e2 *Config) bool {
 	if e1.AuthSnippet != e2.AuthSnippet {
 		return false
 	}
+	if e1.AuthCacheKey != e2.AuthCacheKey {
+		return false
+	}
+	if len(e1.AuthCacheDuration) != len(e2.AuthCacheDuration) {
+		return false
+	}
+	for i := range e1.AuthCacheDuration {
+		if e1.AuthCacheDuration[i] != e2.AuthCacheDuration[i] {
+			return false
+		}
+	}
 
 	return true
 }
@@ -87,9 +110,14 @@ func (e1 *Config) Equal(e2 *Config) bool {
 var (
 	methods      = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"}
 	headerRegexp = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+	// cache keys are NGINX variables or text without quotes or separators
+	cacheKeyRegexp = regexp.MustCompile(`^[^\s'";\\{}]+$`)
+	// optional list of status codes followed by an NGINX time, like 200 202 10m
+	cacheDurationRegexp = regexp.MustCompile(`^((\d{3}|any)\s+)*(\d+(ms|[smhdwMy])?)+$`)
 )
 
-func validMethod(method string) bool {
+// ValidMethod checks if the provided string is a valid HTTP method
+func ValidMethod(method string) bool {
 	if len(method) == 0 {
 		return false
 	}
@@ -102,10 +130,53 @@ func validMethod(method string) bool {
 	return false
 }
 
-func validHeader(header string) bool {
+// ValidHeader checks if the provided string is a valid header name
+func ValidHeader(header string) bool {
 	return headerRegexp.Match([]byte(header))
 }
 
+// ParseURL parses the URL of an authentication service or a sign-in
+// page, which must be absolute
+func ParseURL(urlString string) (*url.URL, error) {
+	authURL, err := url.Parse(urlString)
+	if err != nil {
+		return nil, err
+	}
+	if authURL.Scheme == "" {
+		return nil, ing_errors.NewLocationDenied("url scheme is empty")
+	}
+	if authURL.Host == "" {
+		return nil, ing_errors.NewLocationDenied("url host is empty")
+	}
+	if strings.Contains(authURL.Host, "..") {
+		return nil, ing_errors.NewLocationDenied("invalid url host")
+	}
+
+	return authURL, nil
+}
+
+// parseCacheDurations splits a comma separated list of cache durations,
+// returning the default duration when the list is empty
+func parseCacheDurations(input string) ([]string, error) {
+	durations := []string{}
+	for _, duration := range strings.Split(input, ",") {
+		duration = strings.Join(strings.Fields(duration), " ")
+		if duration == "" {
+			continue
+		}
+		if !cacheDurationRegexp.MatchString(duration) {
+			return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid cache duration %q", duration))
+		}
+		durations = append(durations, duration)
+	}
+
+	if len(durations) == 0 {
+		durations = append(durations, DefaultCacheDuration)
+	}
+
+	return durations, nil
+}
+
 type authReq struct {
 	r resolver.Resolver
 }
@@ -124,22 +195,13 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return nil, err
 	}
 
-	authURL, err := url.Parse(urlString)
+	authURL, err := ParseURL(urlString)
 	if err != nil {
 		return nil, err
 	}
-	if authURL.Scheme == "" {
-		return nil, ing_errors.NewLocationDenied("url scheme is empty")
-	}
-	if authURL.Host == "" {
-		return nil, ing_errors.NewLocationDenied("url host is empty")
-	}
-	if strings.Contains(authURL.Host, "..") {
-		return nil, ing_errors.NewLocationDenied("invalid url host")
-	}
 
 	authMethod, _ := parser.GetStringAnnotation("auth-method", ing)
-	if len(authMethod) != 0 && !validMethod(authMethod) {
+	if len(authMethod) != 0 && !ValidMethod(authMethod) {
 		return nil, ing_errors.NewLocationDenied("invalid HTTP method")
 	}
 
@@ -149,8 +211,8 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 		klog.V(3).Infof("auth-signin annotation is undefined and will not be set")
 	}
 
-	authSnippet, err := parser.GetStringAnnotation("auth-snippet", ing)
-	if err != nil {
+	authSnippet, err := snippet.Read("auth-snippet", ing)
+	if ing_errors.IsMissingAnnotations(err) {
 		klog.V(3).Infof("auth-snippet annotation is undefined and will not be set")
 	}
 
@@ -161,7 +223,7 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 		for _, header := range harr {
 			header = strings.TrimSpace(header)
 			if len(header) > 0 {
-				if !validHeader(header) {
+				if !ValidHeader(header) {
 					return nil, ing_errors.NewLocationDenied("invalid headers list")
 				}
 				responseHeaders = append(responseHeaders, header)
@@ -171,13 +233,26 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 
 	requestRedirect, _ := parser.GetStringAnnotation("auth-request-redirect", ing)
 
+	authCacheKey, _ := parser.GetStringAnnotation("auth-cache-key", ing)
+	if authCacheKey != "" && !cacheKeyRegexp.MatchString(authCacheKey) {
+		return nil, ing_errors.NewLocationDenied("invalid cache key")
+	}
+
+	durstr, _ := parser.GetStringAnnotation("auth-cache-duration", ing)
+	authCacheDuration, err := parseCacheDurations(durstr)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		URL:             urlString,
-		Host:            authURL.Hostname(),
-		SigninURL:       signIn,
-		Method:          authMethod,
-		ResponseHeaders: responseHeaders,
-		RequestRedirect: requestRedirect,
-		AuthSnippet:     authSnippet,
+		URL:               urlString,
+		Host:              authURL.Hostname(),
+		SigninURL:         signIn,
+		Method:            authMethod,
+		ResponseHeaders:   responseHeaders,
+		RequestRedirect:   requestRedirect,
+		AuthSnippet:       authSnippet,
+		AuthCacheKey:      authCacheKey,
+		AuthCacheDuration: authCacheDuration,
 	}, nil
 }
@@ -178,3 +178,59 @@ func TestHeaderAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title             string
+		key               string
+		duration          string
+		expectedDurations []string
+		expErr            bool
+	}{
+		{"no cache", "", "", []string{DefaultCacheDuration}, false},
+		{"default duration", "$remote_user", "", []string{DefaultCacheDuration}, false},
+		{"single duration", "$http_authorization", "200 10m", []string{"200 10m"}, false},
+		{"multiple durations", "$http_authorization", "200 202 10m, 401 5s ,any 1h30m", []string{"200 202 10m", "401 5s", "any 1h30m"}, false},
+		{"duration without codes", "$http_authorization", "5m", []string{"5m"}, false},
+		{"invalid duration", "$http_authorization", "200 forever", nil, true},
+		{"invalid status code", "$http_authorization", "2000 5m", nil, true},
+		{"key with quotes", "$http_authorization'", "", nil, true},
+		{"key with separator", "$http_authorization;", "", nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("auth-url")] = "http://goog.url"
+		data[parser.GetAnnotationWithPrefix("auth-cache-key")] = test.key
+		data[parser.GetAnnotationWithPrefix("auth-cache-duration")] = test.duration
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+			continue
+		}
+
+		u, ok := i.(*Config)
+		if !ok {
+			t.Errorf("%v: expected an External type", test.title)
+			continue
+		}
+
+		if u.AuthCacheKey != test.key {
+			t.Errorf("%v: expected cache key %q but %q was returned", test.title, test.key, u.AuthCacheKey)
+		}
+		if !reflect.DeepEqual(u.AuthCacheDuration, test.expectedDurations) {
+			t.Errorf("%v: expected %v but %v was returned", test.title, test.expectedDurations, u.AuthCacheDuration)
+		}
+	}
+}
@@ -34,7 +34,7 @@ const (
 )
 
 var (
-	authVerifyClientRegex = regexp.MustCompile(`on|off|optional|optional_no_ca`)
+	authVerifyClientRegex = regexp.MustCompile(`^(on|off|optional|optional_no_ca)$`)
 )
 
 // Config contains the AuthSSLCert used for mutual authentication
@@ -113,7 +113,7 @@ func (a authTLS) Parse(ing *extensions.Ingress) (interface{}, error) {
 	}
 
 	config.ValidationDepth, err = parser.GetIntAnnotation("auth-tls-verify-depth", ing)
-	if err != nil || config.ValidationDepth == 0 {
+	if err != nil || config.ValidationDepth <= 0 {
 		config.ValidationDepth = defaultAuthTLSDepth
 	}
 
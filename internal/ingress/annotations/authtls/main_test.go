@@ -126,3 +126,31 @@ func TestAnnotations(t *testing.T) {
 		t.Errorf("expected %v but got %v", true, u.PassCertToUpstream)
 	}
 }
+
+func TestInvalidAnnotations(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{}
+
+	data[parser.GetAnnotationWithPrefix("auth-tls-secret")] = "default/demo-secret"
+	data[parser.GetAnnotationWithPrefix("auth-tls-verify-client")] = "on; return 200"
+	data[parser.GetAnnotationWithPrefix("auth-tls-verify-depth")] = "-1"
+
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&mockSecret{}).Parse(ing)
+	if err != nil {
+		t.Errorf("Uxpected error with ingress: %v", err)
+	}
+
+	u, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected *Config but got %v", u)
+	}
+
+	if u.VerifyClient != defaultAuthVerifyClient {
+		t.Errorf("expected %v but got %v", defaultAuthVerifyClient, u.VerifyClient)
+	}
+	if u.ValidationDepth != defaultAuthTLSDepth {
+		t.Errorf("expected %v but got %v", defaultAuthTLSDepth, u.ValidationDepth)
+	}
+}
@@ -110,3 +110,23 @@ func TestParseAnnotations(t *testing.T) {
 		t.Errorf("expected HTTPS but %v returned", val)
 	}
 }
+
+func TestParseFastCGIAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("backend-protocol")] = "fcgi"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error parsing ingress with backend-protocol")
+	}
+	val, ok := i.(string)
+	if !ok {
+		t.Errorf("expected a string type")
+	}
+	if val != "FCGI" {
+		t.Errorf("expected FCGI but %v returned", val)
+	}
+}
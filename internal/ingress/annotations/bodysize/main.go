@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bodysize
+
+import (
+	"regexp"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const defaultErrorContentType = "text/plain"
+
+var contentTypeRegexp = regexp.MustCompile(`^[A-Za-z0-9_.+/-]+(;\s*[A-Za-z0-9_.-]+=[A-Za-z0-9_.-]+)*$`)
+
+// Config returns the response sent to the requests of an Ingress rule
+// with a body larger than the size allowed by the proxy-body-size annotation
+type Config struct {
+	// ErrorBody is the body of the 413 response
+	ErrorBody string `json:"errorBody"`
+	// ErrorContentType is the Content-Type of the 413 response
+	ErrorContentType string `json:"errorContentType"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.ErrorBody != c2.ErrorBody {
+		return false
+	}
+	if c1.ErrorContentType != c2.ErrorContentType {
+		return false
+	}
+
+	return true
+}
+
+type bodySize struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the response to requests with a body too large
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return bodySize{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to customize the response to requests with a body too large
+func (a bodySize) Parse(ing *extensions.Ingress) (interface{}, error) {
+	body, err := parser.GetStringAnnotation("proxy-body-size-error-body", ing)
+	if err != nil || body == "" {
+		return &Config{}, nil
+	}
+
+	contentType, err := parser.GetStringAnnotation("proxy-body-size-error-content-type", ing)
+	if err != nil {
+		contentType = defaultErrorContentType
+	}
+	if !contentTypeRegexp.MatchString(contentType) {
+		return nil, ing_errors.NewInvalidAnnotationContent("proxy-body-size-error-content-type", contentType)
+	}
+
+	return &Config{
+		ErrorBody:        body,
+		ErrorContentType: contentType,
+	}, nil
+}
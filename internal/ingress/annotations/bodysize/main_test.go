@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bodysize
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	body := parser.GetAnnotationWithPrefix("proxy-body-size-error-body")
+	contentType := parser.GetAnnotationWithPrefix("proxy-body-size-error-content-type")
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, false},
+		{map[string]string{contentType: "application/json"}, &Config{}, false},
+		{map[string]string{body: "upload limit exceeded"}, &Config{"upload limit exceeded", "text/plain"}, false},
+		{map[string]string{body: `{"error":"too large"}`, contentType: "application/json; charset=utf-8"}, &Config{`{"error":"too large"}`, "application/json; charset=utf-8"}, false},
+		{map[string]string{body: "too large", contentType: `text/plain"; evil`}, nil, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("expected an error with annotations %v", testCase.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error with annotations %v: %v", testCase.annotations, err)
+			continue
+		}
+
+		config := result.(*Config)
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %v", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
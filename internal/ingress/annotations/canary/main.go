@@ -73,6 +73,10 @@ func (c canary) Parse(ing *extensions.Ingress) (interface{}, error) {
 		config.Cookie = ""
 	}
 
+	if config.Weight < 0 || config.Weight > 100 {
+		return nil, errors.NewInvalidAnnotationContent("canary-weight", config.Weight)
+	}
+
 	if !config.Enabled && (config.Weight > 0 || len(config.Header) > 0 || len(config.HeaderValue) > 0 || len(config.Cookie) > 0) {
 		return nil, errors.NewInvalidAnnotationConfiguration("canary", "configured but not enabled")
 	}
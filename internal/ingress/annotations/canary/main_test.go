@@ -111,6 +111,9 @@ func TestAnnotations(t *testing.T) {
 		{"canary enabled and no weight", true, 0, "", "", false},
 		{"canary enabled by header", true, 20, "X-Canary", "", false},
 		{"canary enabled by cookie", true, 20, "", "canary_enabled", false},
+		{"canary enabled and all requests", true, 100, "", "", false},
+		{"canary enabled and negative weight", true, -1, "", "", true},
+		{"canary enabled and weight too large", true, 101, "", "", true},
 	}
 
 	for _, test := range tests {
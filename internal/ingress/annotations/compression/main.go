@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compression
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type compression struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new compression annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return compression{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to disable the gzip and brotli compression of the responses,
+// for backends that compress the responses by themselves
+func (c compression) Parse(ing *extensions.Ingress) (interface{}, error) {
+	return parser.GetBoolAnnotation("disable-compression", ing)
+}
@@ -18,8 +18,10 @@ package cors
 
 import (
 	"regexp"
+	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
@@ -37,6 +39,8 @@ var (
 	// that could cause the Response to contain some internal value/variable (like returning $pid, $upstream_addr, etc)
 	// Origin must contain a http/s Origin (including or not the port) or the value '*'
 	corsOriginRegex = regexp.MustCompile(`^(https?://[A-Za-z0-9\-\.]*(:[0-9]+)?|\*)?$`)
+	// Origin with a wildcard subdomain (https://*.example.com)
+	corsWildcardOriginRegex = regexp.MustCompile(`^https?://\*\.[A-Za-z0-9\-\.]+(:[0-9]+)?$`)
 	// Method must contain valid methods list (PUT, GET, POST, BLA)
 	// May contain or not spaces between each verb
 	corsMethodsRegex = regexp.MustCompile(`^([A-Za-z]+,?\s?)+$`)
@@ -56,6 +60,7 @@ type Config struct {
 	CorsAllowMethods     string `json:"corsAllowMethods"`
 	CorsAllowHeaders     string `json:"corsAllowHeaders"`
 	CorsAllowCredentials bool   `json:"corsAllowCredentials"`
+	CorsExposeHeaders    string `json:"corsExposeHeaders"`
 	CorsMaxAge           int    `json:"corsMaxAge"`
 }
 
@@ -87,6 +92,9 @@ func (c1 *Config) Equal(c2 *Config) bool {
 	if c1.CorsAllowOrigin != c2.CorsAllowOrigin {
 		return false
 	}
+	if c1.CorsExposeHeaders != c2.CorsExposeHeaders {
+		return false
+	}
 	if c1.CorsEnabled != c2.CorsEnabled {
 		return false
 	}
@@ -106,8 +114,10 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 	}
 
 	config.CorsAllowOrigin, err = parser.GetStringAnnotation("cors-allow-origin", ing)
-	if err != nil || !corsOriginRegex.MatchString(config.CorsAllowOrigin) {
+	if err != nil {
 		config.CorsAllowOrigin = "*"
+	} else {
+		config.CorsAllowOrigin = parseOrigins(config.CorsAllowOrigin, ing)
 	}
 
 	config.CorsAllowHeaders, err = parser.GetStringAnnotation("cors-allow-headers", ing)
@@ -125,11 +135,45 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 		config.CorsAllowCredentials = true
 	}
 
+	config.CorsExposeHeaders, err = parser.GetStringAnnotation("cors-expose-headers", ing)
+	if err != nil || !corsHeadersRegex.MatchString(config.CorsExposeHeaders) {
+		config.CorsExposeHeaders = ""
+	}
+
 	config.CorsMaxAge, err = parser.GetIntAnnotation("cors-max-age", ing)
-	if err != nil {
+	if err != nil || config.CorsMaxAge < 0 {
 		config.CorsMaxAge = defaultCorsMaxAge
 	}
 
 	return config, nil
 
 }
+
+// parseOrigins returns the valid origins of a comma separated list
+// of origins, or '*' if the list contains '*' or no valid origin
+func parseOrigins(value string, ing *extensions.Ingress) string {
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+
+		if origin == "*" {
+			return "*"
+		}
+
+		if !corsOriginRegex.MatchString(origin) && !corsWildcardOriginRegex.MatchString(origin) {
+			klog.Warningf("Ignoring invalid CORS origin %v in Ingress %v/%v", origin, ing.Namespace, ing.Name)
+			continue
+		}
+
+		origins = append(origins, origin)
+	}
+
+	if len(origins) == 0 {
+		return "*"
+	}
+
+	return strings.Join(origins, ", ")
+}
@@ -159,3 +159,69 @@ func TestIngressCorsConfigInvalid(t *testing.T) {
 		t.Errorf("expected %v but returned %v", defaultCorsMaxAge, nginxCors.CorsMaxAge)
 	}
 }
+
+func TestIngressCorsConfigOrigins(t *testing.T) {
+	testCases := []struct {
+		origin   string
+		expected string
+	}{
+		{"https://origin123.test.com:4443", "https://origin123.test.com:4443"},
+		{"https://a.test.com,http://b.test.com", "https://a.test.com, http://b.test.com"},
+		{"https://*.test.com, https://a.test.com:4443", "https://*.test.com, https://a.test.com:4443"},
+		{"https://a.test.com, *", "*"},
+		{"https://a.test.com, $nginx_version", "https://a.test.com"},
+		{"$nginx_version, a.test.com", "*"},
+		{"https://*test.com", "*"},
+	}
+
+	for _, tc := range testCases {
+		ing := buildIngress()
+
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+		data[parser.GetAnnotationWithPrefix("cors-allow-origin")] = tc.origin
+		ing.SetAnnotations(data)
+
+		corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err != nil {
+			t.Errorf("error parsing annotations: %v", err)
+		}
+
+		nginxCors := corst.(*Config)
+		if nginxCors.CorsAllowOrigin != tc.expected {
+			t.Errorf("expected %v but returned %v for origin %v", tc.expected, nginxCors.CorsAllowOrigin, tc.origin)
+		}
+	}
+}
+
+func TestIngressCorsExposeHeaders(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+	data[parser.GetAnnotationWithPrefix("cors-expose-headers")] = "X-Request-ID, X-Total-Count"
+	data[parser.GetAnnotationWithPrefix("cors-max-age")] = "-1"
+	ing.SetAnnotations(data)
+
+	corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("error parsing annotations: %v", err)
+	}
+
+	nginxCors := corst.(*Config)
+	if nginxCors.CorsExposeHeaders != "X-Request-ID, X-Total-Count" {
+		t.Errorf("expected %v but returned %v", "X-Request-ID, X-Total-Count", nginxCors.CorsExposeHeaders)
+	}
+
+	if nginxCors.CorsMaxAge != defaultCorsMaxAge {
+		t.Errorf("expected %v but returned %v", defaultCorsMaxAge, nginxCors.CorsMaxAge)
+	}
+
+	data[parser.GetAnnotationWithPrefix("cors-expose-headers")] = "$upstream_addr"
+	ing.SetAnnotations(data)
+
+	corst, _ = NewParser(&resolver.Mock{}).Parse(ing)
+	if exposeHeaders := corst.(*Config).CorsExposeHeaders; exposeHeaders != "" {
+		t.Errorf("expected no headers but returned %v", exposeHeaders)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customheaders
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var validHeaderName = regexp.MustCompile(`^[A-Za-z0-9\-_]+$`)
+
+// Config contains the custom headers of an Ingress rule
+type Config struct {
+	// ProxySetHeaders contains the headers sent to the upstream servers
+	ProxySetHeaders map[string]string `json:"proxySetHeaders,omitempty"`
+	// Headers contains the headers added to the responses
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type customHeaders struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new custom headers annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return customHeaders{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to read the custom headers from ConfigMaps
+func (a customHeaders) Parse(ing *extensions.Ingress) (interface{}, error) {
+	var err error
+	config := &Config{}
+
+	config.ProxySetHeaders, err = a.readHeaders("proxy-set-headers", ing)
+	if err != nil {
+		return config, err
+	}
+
+	config.Headers, err = a.readHeaders("custom-headers", ing)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// readHeaders returns the headers contained in the ConfigMap referenced
+// by an annotation. The ConfigMap must be in the namespace of the Ingress
+func (a customHeaders) readHeaders(name string, ing *extensions.Ingress) (map[string]string, error) {
+	cmName, err := parser.GetStringAnnotation(name, ing)
+	if err != nil {
+		return nil, nil
+	}
+
+	cmNs, cmn, err := cache.SplitMetaNamespaceKey(cmName)
+	if err != nil {
+		return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid ConfigMap %q in annotation %v", cmName, name))
+	}
+
+	if cmNs == "" {
+		cmNs = ing.Namespace
+	}
+
+	if cmNs != ing.Namespace {
+		return nil, ing_errors.NewLocationDenied("the ConfigMap with custom headers must be in the namespace of the Ingress")
+	}
+
+	key := fmt.Sprintf("%v/%v", cmNs, cmn)
+	cm, err := a.r.GetConfigMap(key)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading ConfigMap %v", key),
+		}
+	}
+
+	for header, value := range cm.Data {
+		if err := ValidateHeader(header, value); err != nil {
+			return nil, ing_errors.NewLocationDenied(fmt.Sprintf("%v in ConfigMap %v", err, key))
+		}
+	}
+
+	return cm.Data, nil
+}
+
+// ValidateHeader checks the name and the value of a header can be used
+// in the NGINX configuration
+func ValidateHeader(name, value string) error {
+	if !validHeaderName.MatchString(name) {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+
+	if strings.ContainsAny(value, "\"\\\n\r") {
+		return fmt.Errorf("invalid value of header %q", name)
+	}
+
+	return nil
+}
+
+// Equal tests for equality between two custom headers Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.ProxySetHeaders) != len(c2.ProxySetHeaders) {
+		return false
+	}
+	if len(c1.ProxySetHeaders) != 0 && !reflect.DeepEqual(c1.ProxySetHeaders, c2.ProxySetHeaders) {
+		return false
+	}
+	if len(c1.Headers) != len(c2.Headers) {
+		return false
+	}
+
+	return len(c1.Headers) == 0 || reflect.DeepEqual(c1.Headers, c2.Headers)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customheaders
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+// GetConfigMap mocks the GetConfigMap call from the customheaders package
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	switch name {
+	case "default/proxy-headers":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"X-Team":       "payments",
+				"X-Request-Id": "$req_id",
+			},
+		}, nil
+	case "default/response-headers":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"X-Frame-Options": "DENY",
+			},
+		}, nil
+	case "default/invalid-name":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"X-Team; return 200": "payments",
+			},
+		}, nil
+	case "default/invalid-value":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"X-Team": "payments\"; return 200; \"",
+			},
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no configmap with name %v", name)
+}
+
+func TestParse(t *testing.T) {
+	proxySetHeaders := parser.GetAnnotationWithPrefix("proxy-set-headers")
+	customHeaders := parser.GetAnnotationWithPrefix("custom-headers")
+
+	ap := NewParser(mockConfigMap{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	expectedProxySetHeaders := map[string]string{
+		"X-Team":       "payments",
+		"X-Request-Id": "$req_id",
+	}
+	expectedHeaders := map[string]string{
+		"X-Frame-Options": "DENY",
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, false},
+		{map[string]string{proxySetHeaders: "proxy-headers"}, &Config{ProxySetHeaders: expectedProxySetHeaders}, false},
+		{map[string]string{customHeaders: "default/response-headers"}, &Config{Headers: expectedHeaders}, false},
+		{map[string]string{proxySetHeaders: "proxy-headers", customHeaders: "response-headers"}, &Config{ProxySetHeaders: expectedProxySetHeaders, Headers: expectedHeaders}, false},
+		{map[string]string{proxySetHeaders: "other/proxy-headers"}, &Config{}, true},
+		{map[string]string{customHeaders: "missing"}, &Config{}, true},
+		{map[string]string{proxySetHeaders: "invalid-name"}, &Config{}, true},
+		{map[string]string{customHeaders: "invalid-value"}, &Config{}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expectErr != (err != nil) {
+			t.Errorf("expected error %v but returned %v, annotations: %s", testCase.expectErr, err, testCase.annotations)
+		}
+
+		p, _ := i.(*Config)
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"X-Content-Type-Options", "nosniff", true},
+		{"X_Custom", "", true},
+		{"X Content", "nosniff", false},
+		{"X-Header;", "value", false},
+		{"X-Header", `value"; more_set_headers "X-Other: 1`, false},
+		{"X-Header", "value\r\nX-Other: 1", false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateHeader(tc.name, tc.value)
+		if tc.valid && err != nil {
+			t.Errorf("expected header %q: %q to be valid but returned %v", tc.name, tc.value, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected header %q: %q to be invalid", tc.name, tc.value)
+		}
+	}
+}
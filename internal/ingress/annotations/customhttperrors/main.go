@@ -23,9 +23,16 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// isValidCode checks the status code can be intercepted by NGINX
+// http://nginx.org/en/docs/http/ngx_http_core_module.html#error_page
+func isValidCode(code int) bool {
+	return code >= 300 && code <= 599
+}
+
 type customhttperrors struct {
 	r resolver.Resolver
 }
@@ -45,11 +52,22 @@ func (e customhttperrors) Parse(ing *extensions.Ingress) (interface{}, error) {
 
 	cSplit := strings.Split(c, ",")
 	var codes []int
+	seen := make(map[int]bool)
 	for _, i := range cSplit {
-		num, err := strconv.Atoi(i)
+		num, err := strconv.Atoi(strings.TrimSpace(i))
 		if err != nil {
 			return nil, err
 		}
+
+		if !isValidCode(num) {
+			return nil, ing_errors.NewInvalidAnnotationContent("custom-http-errors", num)
+		}
+
+		if seen[num] {
+			continue
+		}
+		seen[num] = true
+
 		codes = append(codes, num)
 	}
 
@@ -25,6 +25,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -89,3 +90,39 @@ func TestParseAnnotations(t *testing.T) {
 		t.Errorf("expected %v but got %v", expected, val)
 	}
 }
+
+func TestParseAnnotationsWithSpacesAndDuplicates(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("custom-http-errors")] = "404, 503,404 "
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error parsing ingress with custom-http-errors: %v", err)
+	}
+
+	expected := []int{404, 503}
+	if !reflect.DeepEqual(expected, i) {
+		t.Errorf("expected %v but got %v", expected, i)
+	}
+}
+
+func TestParseAnnotationsWithInvalidCodes(t *testing.T) {
+	ing := buildIngress()
+
+	for _, codes := range []string{"200,404", "404,600", "0"} {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("custom-http-errors")] = codes
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if !errors.IsInvalidContent(err) {
+			t.Errorf("expected an invalid content error parsing %v but got %v", codes, err)
+		}
+		if i != nil {
+			t.Errorf("expected %v but got %v", nil, i)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fastcgi
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	validIndex = regexp.MustCompile(`^[\w.-]+$`)
+	validParam = regexp.MustCompile(`^\w+$`)
+)
+
+// Config describes the FastCGI configuration of an Ingress rule
+type Config struct {
+	// Index is the file name appended to URIs ending with a slash
+	Index string `json:"index"`
+	// Params contains the fastcgi_param directives read from a ConfigMap
+	Params map[string]string `json:"params"`
+}
+
+type fastcgi struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new FastCGI annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return fastcgi{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure the FastCGI backends
+func (a fastcgi) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	protocol, err := parser.GetStringAnnotation("backend-protocol", ing)
+	if err != nil || strings.ToUpper(strings.TrimSpace(protocol)) != "FCGI" {
+		return config, nil
+	}
+
+	index, err := parser.GetStringAnnotation("fastcgi-index", ing)
+	if err == nil {
+		if !validIndex.MatchString(index) {
+			return config, ing_errors.NewInvalidAnnotationContent("fastcgi-index", index)
+		}
+		config.Index = index
+	}
+
+	cmName, err := parser.GetStringAnnotation("fastcgi-params-configmap", ing)
+	if err != nil {
+		return config, nil
+	}
+
+	cmNs, cmn, err := cache.SplitMetaNamespaceKey(cmName)
+	if err != nil {
+		return nil, ing_errors.NewInvalidAnnotationContent("fastcgi-params-configmap", cmName)
+	}
+
+	if cmNs == "" {
+		cmNs = ing.Namespace
+	}
+
+	if cmNs != ing.Namespace {
+		return config, ing_errors.NewLocationDenied("the ConfigMap with FastCGI params must be in the namespace of the Ingress")
+	}
+
+	key := fmt.Sprintf("%v/%v", cmNs, cmn)
+	cm, err := a.r.GetConfigMap(key)
+	if err != nil {
+		return config, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading ConfigMap %v", key),
+		}
+	}
+
+	for param := range cm.Data {
+		if !validParam.MatchString(param) {
+			return config, ing_errors.NewLocationDenied(fmt.Sprintf("invalid FastCGI param %q in ConfigMap %v", param, key))
+		}
+	}
+
+	config.Params = cm.Data
+
+	return config, nil
+}
+
+// Equal tests for equality between two FastCGI Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Index != c2.Index {
+		return false
+	}
+	if len(c1.Params) != len(c2.Params) {
+		return false
+	}
+
+	return len(c1.Params) == 0 || reflect.DeepEqual(c1.Params, c2.Params)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fastcgi
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+// GetConfigMap mocks the GetConfigMap call from the fastcgi package
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	switch name {
+	case "default/fcgi-params":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"SCRIPT_FILENAME": "/example/index.php",
+				"HTTP_PROXY":      "",
+			},
+		}, nil
+	case "default/invalid-params":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"SCRIPT_FILENAME /tmp; return 200": "",
+			},
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no configmap with name %v", name)
+}
+
+func TestParse(t *testing.T) {
+	protocol := parser.GetAnnotationWithPrefix("backend-protocol")
+	index := parser.GetAnnotationWithPrefix("fastcgi-index")
+	params := parser.GetAnnotationWithPrefix("fastcgi-params-configmap")
+
+	ap := NewParser(mockConfigMap{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	expectedParams := map[string]string{
+		"SCRIPT_FILENAME": "/example/index.php",
+		"HTTP_PROXY":      "",
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, false},
+		{map[string]string{index: "index.php"}, &Config{}, false},
+		{map[string]string{protocol: "HTTP", index: "index.php"}, &Config{}, false},
+		{map[string]string{protocol: "FCGI", index: "index.php"}, &Config{Index: "index.php"}, false},
+		{map[string]string{protocol: "fcgi", index: "index.php; return 200"}, &Config{}, true},
+		{map[string]string{protocol: "FCGI", params: "fcgi-params"}, &Config{Params: expectedParams}, false},
+		{map[string]string{protocol: "FCGI", params: "default/fcgi-params", index: "app.php"}, &Config{Index: "app.php", Params: expectedParams}, false},
+		{map[string]string{protocol: "FCGI", params: "other/fcgi-params"}, &Config{}, true},
+		{map[string]string{protocol: "FCGI", params: "missing"}, &Config{}, true},
+		{map[string]string{protocol: "FCGI", params: "invalid-params"}, &Config{}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expectErr != (err != nil) {
+			t.Errorf("expected error %v but returned %v, annotations: %s", testCase.expectErr, err, testCase.annotations)
+		}
+
+		p, _ := i.(*Config)
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package globalratelimit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/net"
+)
+
+const defaultKey = "$remote_addr"
+
+var keyRegexp = regexp.MustCompile(`^[A-Za-z0-9_$.:/-]+$`)
+
+// Config returns the global rate limit configuration for an Ingress rule.
+// The requests are counted in a store shared by all the replicas of
+// the controller, so the limit does not depend on the number of replicas
+type Config struct {
+	// Namespace groups the counters of the Ingress in the shared store
+	Namespace string `json:"namespace"`
+	// Limit is the number of requests allowed in a window
+	Limit int `json:"limit"`
+	// WindowSize is the duration of the window in seconds
+	WindowSize int `json:"window-size"`
+	// Key is the NGINX variable, text or combination of both used to count the requests
+	Key string `json:"key"`
+	// IgnoredCIDRs contains the client addresses the limit is not applied to
+	IgnoredCIDRs []string `json:"ignored-cidrs"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Namespace != c2.Namespace {
+		return false
+	}
+	if c1.Limit != c2.Limit {
+		return false
+	}
+	if c1.WindowSize != c2.WindowSize {
+		return false
+	}
+	if c1.Key != c2.Key {
+		return false
+	}
+	if len(c1.IgnoredCIDRs) != len(c2.IgnoredCIDRs) {
+		return false
+	}
+	for i := range c1.IgnoredCIDRs {
+		if c1.IgnoredCIDRs[i] != c2.IgnoredCIDRs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type globalratelimit struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new global rate limit annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return globalratelimit{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to limit the requests across all the replicas
+func (a globalratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
+	limit, _ := parser.GetIntAnnotation("global-rate-limit", ing)
+	if limit <= 0 {
+		return &Config{}, nil
+	}
+
+	window, err := parser.GetStringAnnotation("global-rate-limit-window", ing)
+	if err != nil {
+		return nil, ing_errors.NewInvalidAnnotationConfiguration("global-rate-limit-window", "the window is required")
+	}
+
+	windowSize, err := time.ParseDuration(window)
+	if err != nil || windowSize < time.Second {
+		return nil, ing_errors.NewInvalidAnnotationContent("global-rate-limit-window", window)
+	}
+
+	key, err := parser.GetStringAnnotation("global-rate-limit-key", ing)
+	if err != nil {
+		key = defaultKey
+	}
+	if !keyRegexp.MatchString(key) {
+		return nil, ing_errors.NewInvalidAnnotationContent("global-rate-limit-key", key)
+	}
+
+	val, _ := parser.GetStringAnnotation("global-rate-limit-ignored-cidrs", ing)
+	cidrs, err := parseCIDRs(val)
+	if err != nil {
+		return nil, ing_errors.NewInvalidAnnotationContent("global-rate-limit-ignored-cidrs", val)
+	}
+
+	return &Config{
+		Namespace:    fmt.Sprintf("%v_%v", ing.GetNamespace(), ing.GetName()),
+		Limit:        limit,
+		WindowSize:   int(windowSize.Seconds()),
+		Key:          key,
+		IgnoredCIDRs: cidrs,
+	}, nil
+}
+
+func parseCIDRs(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+
+	ipnets, ips, err := net.ParseIPNets(strings.Split(s, ",")...)
+	if err != nil {
+		return nil, err
+	}
+
+	cidrs := []string{}
+	for k := range ipnets {
+		cidrs = append(cidrs, k)
+	}
+	for k := range ips {
+		cidrs = append(cidrs, k)
+	}
+
+	sort.Strings(cidrs)
+
+	return cidrs, nil
+}
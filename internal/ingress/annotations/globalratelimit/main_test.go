@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package globalratelimit
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	limit := parser.GetAnnotationWithPrefix("global-rate-limit")
+	window := parser.GetAnnotationWithPrefix("global-rate-limit-window")
+	key := parser.GetAnnotationWithPrefix("global-rate-limit-key")
+	cidrs := parser.GetAnnotationWithPrefix("global-rate-limit-ignored-cidrs")
+
+	testCases := map[string]struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		"without annotations": {nil, &Config{}, false},
+		"without limit":       {map[string]string{window: "1m"}, &Config{}, false},
+		"with defaults": {
+			map[string]string{limit: "100", window: "1m"},
+			&Config{Namespace: "default_foo", Limit: 100, WindowSize: 60, Key: "$remote_addr", IgnoredCIDRs: []string{}},
+			false,
+		},
+		"with key and ignored cidrs": {
+			map[string]string{limit: "10", window: "2s", key: "$http_x_api_key", cidrs: "10.0.0.0/8, 192.168.0.1"},
+			&Config{Namespace: "default_foo", Limit: 10, WindowSize: 2, Key: "$http_x_api_key", IgnoredCIDRs: []string{"10.0.0.0/8", "192.168.0.1"}},
+			false,
+		},
+		"without window":       {map[string]string{limit: "100"}, nil, true},
+		"invalid window":       {map[string]string{limit: "100", window: "10ms"}, nil, true},
+		"invalid key":          {map[string]string{limit: "100", window: "1m", key: `$remote_addr";`}, nil, true},
+		"invalid ignored cidr": {map[string]string{limit: "100", window: "1m", cidrs: "10.0.0.0/33"}, nil, true},
+	}
+
+	for name, tc := range testCases {
+		ing := buildIngress()
+		ing.SetAnnotations(tc.annotations)
+
+		result, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hsts
+
+import (
+	"strconv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// Config returns the HTTP Strict Transport Security configuration for an Ingress rule
+type Config struct {
+	Enabled           bool   `json:"enabled"`
+	MaxAge            string `json:"maxAge"`
+	IncludeSubdomains bool   `json:"includeSubdomains"`
+	Preload           bool   `json:"preload"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.MaxAge != c2.MaxAge {
+		return false
+	}
+	if c1.IncludeSubdomains != c2.IncludeSubdomains {
+		return false
+	}
+	if c1.Preload != c2.Preload {
+		return false
+	}
+
+	return true
+}
+
+type hsts struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new HSTS annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return hsts{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure the Strict-Transport-Security header.
+// Annotations not defined in the Ingress use the values of the configuration ConfigMap.
+func (h hsts) Parse(ing *extensions.Ingress) (interface{}, error) {
+	defBackend := h.r.GetDefaultBackend()
+	config := &Config{}
+
+	var err error
+
+	config.Enabled, err = parser.GetBoolAnnotation("hsts", ing)
+	if err != nil {
+		config.Enabled = defBackend.HSTS
+	}
+
+	config.MaxAge, err = parser.GetStringAnnotation("hsts-max-age", ing)
+	if err != nil || !isValidMaxAge(config.MaxAge) {
+		config.MaxAge = defBackend.HSTSMaxAge
+	}
+
+	config.IncludeSubdomains, err = parser.GetBoolAnnotation("hsts-include-subdomains", ing)
+	if err != nil {
+		config.IncludeSubdomains = defBackend.HSTSIncludeSubdomains
+	}
+
+	config.Preload, err = parser.GetBoolAnnotation("hsts-preload", ing)
+	if err != nil {
+		config.Preload = defBackend.HSTSPreload
+	}
+
+	return config, nil
+}
+
+// isValidMaxAge checks the max-age directive is a number of seconds
+func isValidMaxAge(maxAge string) bool {
+	_, err := strconv.ParseUint(maxAge, 10, 32)
+	return err == nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hsts
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		HSTS:                  true,
+		HSTSMaxAge:            "15724800",
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           false,
+	}
+}
+
+func TestParse(t *testing.T) {
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	testCases := map[string]struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		"without annotations": {nil, &Config{true, "15724800", true, false}},
+		"with all the annotations": {map[string]string{
+			parser.GetAnnotationWithPrefix("hsts"):                    "true",
+			parser.GetAnnotationWithPrefix("hsts-max-age"):            "63072000",
+			parser.GetAnnotationWithPrefix("hsts-include-subdomains"): "false",
+			parser.GetAnnotationWithPrefix("hsts-preload"):            "true",
+		}, &Config{true, "63072000", false, true}},
+		"disabled": {map[string]string{
+			parser.GetAnnotationWithPrefix("hsts"): "false",
+		}, &Config{false, "15724800", true, false}},
+		"with invalid max age": {map[string]string{
+			parser.GetAnnotationWithPrefix("hsts-max-age"): "1y; preload",
+		}, &Config{true, "15724800", true, false}},
+		"with negative max age": {map[string]string{
+			parser.GetAnnotationWithPrefix("hsts-max-age"): "-1",
+		}, &Config{true, "15724800", true, false}},
+	}
+
+	for name, tc := range testCases {
+		ing.SetAnnotations(tc.annotations)
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+		}
+
+		config, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("%v: expected a *Config but returned %T", name, i)
+		}
+		if !config.Equal(tc.expected) {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, config)
+		}
+	}
+}
@@ -35,5 +35,10 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // Parse parses the annotations contained in the ingress rule
 // used to add http2 push preload to the server
 func (h2pp http2PushPreload) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetBoolAnnotation("http2-push-preload", ing)
+	val, err := parser.GetBoolAnnotation("http2-push-preload", ing)
+	if err != nil {
+		return h2pp.r.GetDefaultBackend().HTTP2PushPreload, nil
+	}
+
+	return val, nil
 }
@@ -23,6 +23,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -60,3 +61,35 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{HTTP2PushPreload: true}
+}
+
+func TestParseWithDefaultConfig(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("http2-push-preload")
+	ap := NewParser(mockBackend{})
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	result, _ := ap.Parse(ing)
+	if result != true {
+		t.Errorf("expected true from the default configuration but returned %v", result)
+	}
+
+	ing.SetAnnotations(map[string]string{annotation: "false"})
+	result, _ = ap.Parse(ing)
+	if result != false {
+		t.Errorf("expected the annotation to override the default configuration but returned %v", result)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/net"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
@@ -77,6 +78,8 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // rule used to limit access to certain client addresses or networks.
 // Multiple ranges can specified using commas as separator
 // e.g. `18.0.0.0/8,56.0.0.0/8`
+// Invalid entries are ignored and reported in the returned error. The
+// location is denied when none of the entries is valid.
 func (a ipwhitelist) Parse(ing *extensions.Ingress) (interface{}, error) {
 	defBackend := a.r.GetDefaultBackend()
 	sort.Strings(defBackend.WhitelistSourceRange)
@@ -87,23 +90,41 @@ func (a ipwhitelist) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, nil
 	}
 
-	values := strings.Split(val, ",")
-	ipnets, ips, err := net.ParseIPNets(values...)
-	if err != nil && len(ips) == 0 {
-		return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, ing_errors.LocationDenied{
-			Reason: errors.Wrap(err, "the annotation does not contain a valid IP address or network"),
+	cidrs := sets.NewString()
+	invalid := []string{}
+	var parseErr error
+	for _, value := range strings.Split(val, ",") {
+		ipnets, ips, err := net.ParseIPNets(value)
+		if err != nil {
+			invalid = append(invalid, strings.TrimSpace(value))
+			if parseErr == nil {
+				parseErr = err
+			}
+			continue
+		}
+
+		for k := range ipnets {
+			cidrs.Insert(k)
+		}
+		for k := range ips {
+			cidrs.Insert(k)
 		}
 	}
 
-	cidrs := []string{}
-	for k := range ipnets {
-		cidrs = append(cidrs, k)
+	if cidrs.Len() == 0 {
+		return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, ing_errors.LocationDenied{
+			Reason: errors.Wrap(parseErr, "the annotation does not contain a valid IP address or network"),
+		}
 	}
-	for k := range ips {
-		cidrs = append(cidrs, k)
+
+	if defBackend.MergeWhitelistSourceRange {
+		cidrs.Insert(defBackend.WhitelistSourceRange...)
 	}
 
-	sort.Strings(cidrs)
+	sr := &SourceRange{CIDR: cidrs.List()}
+	if len(invalid) > 0 {
+		return sr, ing_errors.NewInvalidAnnotationContent("whitelist-source-range", strings.Join(invalid, ","))
+	}
 
-	return &SourceRange{cidrs}, nil
+	return sr, nil
 }
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -91,6 +92,11 @@ func TestParseAnnotations(t *testing.T) {
 			expectCidr: []string{"1.1.1.1/32", "2.2.2.2/32", "3.3.3.0/24"},
 			expectErr:  false,
 		},
+		"test parse ipv6": {
+			net:        "::1, 2001:db8::/32",
+			expectCidr: []string{"2001:db8::/32", "::1"},
+			expectErr:  false,
+		},
 	}
 
 	for testName, test := range tests {
@@ -191,6 +197,71 @@ func TestParseAnnotationsWithDefaultConfig(t *testing.T) {
 	}
 }
 
+// Test that the invalid entries are reported without discarding the valid ones
+func TestParseAnnotationsWithInvalidEntries(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("whitelist-source-range")] = "10.0.0.0/24, ww,1.1.1.1,10.0.0.0/33"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if !ing_errors.IsInvalidContent(err) {
+		t.Fatalf("expected an invalid content error but %v returned", err)
+	}
+	expectedErr := "the annotation whitelist-source-range does not contain a valid value (ww,10.0.0.0/33)"
+	if err.Error() != expectedErr {
+		t.Errorf("expected error %v but %v returned", expectedErr, err)
+	}
+
+	sr, ok := i.(*SourceRange)
+	if !ok {
+		t.Fatalf("expected a SourceRange type")
+	}
+	expectCidr := []string{"1.1.1.1", "10.0.0.0/24"}
+	if !strsEquals(sr.CIDR, expectCidr) {
+		t.Errorf("expected %v CIDR but %v returned", expectCidr, sr.CIDR)
+	}
+}
+
+type mergeMockBackend struct {
+	resolver.Mock
+}
+
+// GetDefaultBackend returns the backend that must be used as default
+func (m mergeMockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		WhitelistSourceRange:      []string{"4.4.4.0/24", "1.2.3.4/32"},
+		MergeWhitelistSourceRange: true,
+	}
+}
+
+// Test that the whitelist set on the Backend is merged with the annotation
+func TestParseAnnotationsMergeDefaultConfig(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("whitelist-source-range")] = "10.0.0.0/24,4.4.4.0/24"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mergeMockBackend{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectCidr := []string{"1.2.3.4/32", "10.0.0.0/24", "4.4.4.0/24"}
+	if sr := i.(*SourceRange); !strsEquals(sr.CIDR, expectCidr) {
+		t.Errorf("expected %v CIDR but %v returned", expectCidr, sr.CIDR)
+	}
+
+	ing.SetAnnotations(map[string]string{})
+	i, _ = NewParser(mergeMockBackend{}).Parse(ing)
+	expectCidr = []string{"1.2.3.4/32", "4.4.4.0/24"}
+	if sr := i.(*SourceRange); !strsEquals(sr.CIDR, expectCidr) {
+		t.Errorf("expected %v CIDR but %v returned", expectCidr, sr.CIDR)
+	}
+}
+
 func strsEquals(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
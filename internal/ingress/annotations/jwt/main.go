@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	defaultJWKSCacheDuration = 3600
+	defaultErrorContentType  = "text/plain"
+)
+
+var (
+	issuerRegexp      = regexp.MustCompile(`^[^"\\\s]+$`)
+	audienceRegexp    = regexp.MustCompile(`^[A-Za-z0-9_.:/~-]+$`)
+	contentTypeRegexp = regexp.MustCompile(`^[A-Za-z0-9_.+/-]+(;\s*[A-Za-z0-9_.-]+=[A-Za-z0-9_.-]+)*$`)
+)
+
+// Config returns the JWT validation configuration for an Ingress rule
+type Config struct {
+	// JWKSURL is the URL of the JSON Web Key Set used to verify the signature of the tokens
+	JWKSURL string `json:"jwksUrl"`
+	// JWKSCacheDuration is the number of seconds the keys are cached
+	JWKSCacheDuration int `json:"jwksCacheDuration"`
+	// Issuer is the expected value of the iss claim
+	Issuer string `json:"issuer"`
+	// Audiences contains the accepted values of the aud claim
+	Audiences        []string             `json:"audiences,omitempty"`
+	ErrorBody        string               `json:"errorBody"`
+	ErrorContentType string               `json:"errorContentType"`
+	ClaimHeaders     []parser.ClaimHeader `json:"claimHeaders,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.JWKSURL != c2.JWKSURL {
+		return false
+	}
+	if c1.JWKSCacheDuration != c2.JWKSCacheDuration {
+		return false
+	}
+	if c1.Issuer != c2.Issuer {
+		return false
+	}
+	if len(c1.Audiences) != len(c2.Audiences) {
+		return false
+	}
+	for i := range c1.Audiences {
+		if c1.Audiences[i] != c2.Audiences[i] {
+			return false
+		}
+	}
+	if c1.ErrorBody != c2.ErrorBody {
+		return false
+	}
+	if c1.ErrorContentType != c2.ErrorContentType {
+		return false
+	}
+	if len(c1.ClaimHeaders) != len(c2.ClaimHeaders) {
+		return false
+	}
+	for i := range c1.ClaimHeaders {
+		if c1.ClaimHeaders[i] != c2.ClaimHeaders[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type jwt struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new JWT validation annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return jwt{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// validate the JSON Web Token sent in the Authorization header
+func (j jwt) Parse(ing *extensions.Ingress) (interface{}, error) {
+	jwksURL, err := parser.GetStringAnnotation("jwt-jwks-url", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(jwksURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.ContainsAny(jwksURL, `"\`) {
+		return nil, ing_errors.NewLocationDenied("invalid JWKS URL")
+	}
+
+	cacheDuration, err := parser.GetIntAnnotation("jwt-jwks-cache-duration", ing)
+	if err != nil || cacheDuration <= 0 {
+		cacheDuration = defaultJWKSCacheDuration
+	}
+
+	issuer, _ := parser.GetStringAnnotation("jwt-issuer", ing)
+	if issuer != "" && !issuerRegexp.MatchString(issuer) {
+		return nil, ing_errors.NewLocationDenied("invalid JWT issuer")
+	}
+
+	audiences := []string{}
+	astr, _ := parser.GetStringAnnotation("jwt-audience", ing)
+	for _, audience := range strings.Split(astr, ",") {
+		audience = strings.TrimSpace(audience)
+		if audience == "" {
+			continue
+		}
+		if !audienceRegexp.MatchString(audience) {
+			return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid JWT audience %q", audience))
+		}
+		audiences = append(audiences, audience)
+	}
+
+	errorBody, _ := parser.GetStringAnnotation("jwt-error-body", ing)
+
+	contentType, err := parser.GetStringAnnotation("jwt-error-content-type", ing)
+	if err != nil {
+		contentType = defaultErrorContentType
+	}
+	if !contentTypeRegexp.MatchString(contentType) {
+		return nil, ing_errors.NewLocationDenied("invalid JWT error content type")
+	}
+
+	claimHeaders, err := parser.GetClaimHeadersAnnotation("jwt-claim-headers", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		JWKSURL:           jwksURL,
+		JWKSCacheDuration: cacheDuration,
+		Issuer:            issuer,
+		Audiences:         audiences,
+		ErrorBody:         errorBody,
+		ErrorContentType:  contentType,
+		ClaimHeaders:      claimHeaders,
+	}, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+}
+
+func TestWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress())
+	if err == nil {
+		t.Error("expected error with ingress without annotations")
+	}
+}
+
+func TestParse(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{
+		parser.GetAnnotationWithPrefix("jwt-jwks-url"):            "https://auth.example.com/.well-known/jwks.json",
+		parser.GetAnnotationWithPrefix("jwt-jwks-cache-duration"): "600",
+		parser.GetAnnotationWithPrefix("jwt-issuer"):              "https://auth.example.com/",
+		parser.GetAnnotationWithPrefix("jwt-audience"):            "api, https://api.example.com",
+		parser.GetAnnotationWithPrefix("jwt-error-body"):          `{"error":"unauthorized"}`,
+		parser.GetAnnotationWithPrefix("jwt-error-content-type"):  "application/json; charset=utf-8",
+		parser.GetAnnotationWithPrefix("jwt-claim-headers"):       "X-Auth-User:sub, X-Auth-Scope:scope",
+	}
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	expected := &Config{
+		JWKSURL:           "https://auth.example.com/.well-known/jwks.json",
+		JWKSCacheDuration: 600,
+		Issuer:            "https://auth.example.com/",
+		Audiences:         []string{"api", "https://api.example.com"},
+		ErrorBody:         `{"error":"unauthorized"}`,
+		ErrorContentType:  "application/json; charset=utf-8",
+		ClaimHeaders: []parser.ClaimHeader{
+			{Header: "X-Auth-User", Claim: "sub"},
+			{Header: "X-Auth-Scope", Claim: "scope"},
+		},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %v but returned %v", expected, cfg)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	ing := buildIngress()
+	ing.SetAnnotations(map[string]string{
+		parser.GetAnnotationWithPrefix("jwt-jwks-url"): "https://auth.example.com/.well-known/jwks.json",
+	})
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := i.(*Config)
+	if cfg.JWKSCacheDuration != defaultJWKSCacheDuration {
+		t.Errorf("expected %v as cache duration but returned %v", defaultJWKSCacheDuration, cfg.JWKSCacheDuration)
+	}
+	if cfg.ErrorContentType != defaultErrorContentType {
+		t.Errorf("expected %v as error content type but returned %v", defaultErrorContentType, cfg.ErrorContentType)
+	}
+	if cfg.Issuer != "" || len(cfg.Audiences) != 0 || len(cfg.ClaimHeaders) != 0 {
+		t.Errorf("unexpected configuration %v", cfg)
+	}
+}
+
+func TestInvalidAnnotations(t *testing.T) {
+	testCases := map[string]map[string]string{
+		"invalid jwks url":     {"jwt-jwks-url": "ftp://auth.example.com/jwks.json"},
+		"quotes in url":        {"jwt-jwks-url": `https://auth.example.com/"`},
+		"invalid issuer":       {"jwt-issuer": `https://auth.example.com/"`},
+		"invalid audience":     {"jwt-audience": "api, my api"},
+		"invalid content type": {"jwt-error-content-type": `application/json"`},
+		"invalid claim header": {"jwt-claim-headers": "X-Auth-User"},
+		"invalid header name":  {"jwt-claim-headers": "X Auth:sub"},
+		"invalid claim name":   {"jwt-claim-headers": `X-Auth:"sub"`},
+	}
+
+	for name, tc := range testCases {
+		ing := buildIngress()
+		data := map[string]string{
+			parser.GetAnnotationWithPrefix("jwt-jwks-url"): "https://auth.example.com/.well-known/jwks.json",
+		}
+		for k, v := range tc {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
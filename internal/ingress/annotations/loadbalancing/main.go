@@ -18,11 +18,16 @@ package loadbalancing
 
 import (
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// ValidAlgorithms contains the algorithms implemented by the Lua balancer
+var ValidAlgorithms = sets.NewString("round_robin", "ewma", "ip_hash")
+
 type loadbalancing struct {
 	r resolver.Resolver
 }
@@ -33,8 +38,20 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 }
 
 // Parse parses the annotations contained in the ingress rule
-// used to indicate if the location/s contains a fragment of
-// configuration to be included inside the paths of the rules
+// used to indicate the load balancing algorithm of the backends.
+// Without the annotation the global algorithm of the configmap is used
 func (a loadbalancing) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetStringAnnotation("load-balance", ing)
+	defBackend := a.r.GetDefaultBackend()
+
+	val, err := parser.GetStringAnnotation("load-balance", ing)
+	if err != nil {
+		return defBackend.LoadBalancing, nil
+	}
+
+	if !ValidAlgorithms.Has(val) {
+		klog.Warningf("Ingress %v/%v: %v is not a valid load balancing algorithm. Using the default %q", ing.Namespace, ing.Name, val, defBackend.LoadBalancing)
+		return defBackend.LoadBalancing, nil
+	}
+
+	return val, nil
 }
@@ -23,6 +23,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -38,7 +39,10 @@ func TestParse(t *testing.T) {
 		annotations map[string]string
 		expected    string
 	}{
+		{map[string]string{annotation: "ewma"}, "ewma"},
+		{map[string]string{annotation: "round_robin"}, "round_robin"},
 		{map[string]string{annotation: "ip_hash"}, "ip_hash"},
+		{map[string]string{annotation: "least_conn"}, ""},
 		{map[string]string{}, ""},
 		{nil, ""},
 	}
@@ -59,3 +63,41 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{LoadBalancing: "ewma"}
+}
+
+func TestParseWithGlobalAlgorithm(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("load-balance")
+	ap := NewParser(mockBackend{})
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    string
+	}{
+		{map[string]string{annotation: "round_robin"}, "round_robin"},
+		{map[string]string{annotation: "invalid"}, "ewma"},
+		{map[string]string{}, "ewma"},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, _ := ap.Parse(ing)
+		if result != testCase.expected {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, result, testCase.annotations)
+		}
+	}
+}
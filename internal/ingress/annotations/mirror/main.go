@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mirror
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// targetRegex matches an HTTP(S) URL, optionally containing NGINX variables,
+// without characters that could alter the NGINX configuration
+var targetRegex = regexp.MustCompile(`^https?://[^\s;{}"'\\]+$`)
+
+// Config returns the mirror configuration for an Ingress rule
+type Config struct {
+	// Source is the internal location that sends the copy of the requests
+	Source string `json:"source"`
+	// Target is the URL receiving the copy of the requests
+	Target string `json:"target"`
+	// RequestBody indicates if the body of the requests is mirrored
+	RequestBody bool `json:"requestBody"`
+	// Percentage of the requests mirrored to the target
+	Percentage int `json:"percentage"`
+}
+
+type mirror struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new mirror annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return mirror{r}
+}
+
+// Parse parses the annotations contained in the ingress
+// rule used to mirror the requests to a different target
+func (a mirror) Parse(ing *extensions.Ingress) (interface{}, error) {
+	target, err := parser.GetStringAnnotation("mirror-target", ing)
+	if err != nil {
+		return &Config{}, err
+	}
+
+	if !targetRegex.MatchString(target) {
+		return &Config{}, ing_errors.NewInvalidAnnotationContent("mirror-target", target)
+	}
+
+	// the URI of the mirror subrequest is the internal location,
+	// send the original URI when the target does not define one
+	if !strings.ContainsAny(target[strings.Index(target, "://")+3:], "/$") {
+		target = target + "$request_uri"
+	}
+
+	// the slash is not valid in the names, so the source
+	// of each Ingress is unique
+	config := &Config{
+		Source:      fmt.Sprintf("/_mirror/%v/%v", ing.Namespace, ing.Name),
+		Target:      target,
+		RequestBody: true,
+		Percentage:  100,
+	}
+
+	requestBody, err := parser.GetBoolAnnotation("mirror-request-body", ing)
+	if err == nil {
+		config.RequestBody = requestBody
+	}
+
+	percentage, err := parser.GetIntAnnotation("mirror-percentage", ing)
+	if err == nil {
+		if percentage < 0 || percentage > 100 {
+			return &Config{}, ing_errors.NewInvalidAnnotationContent("mirror-percentage", percentage)
+		}
+		config.Percentage = percentage
+	}
+
+	return config, nil
+}
+
+// Equal tests for equality between two mirror Config types
+func (m1 *Config) Equal(m2 *Config) bool {
+	if m1 == m2 {
+		return true
+	}
+	if m1 == nil || m2 == nil {
+		return false
+	}
+	if m1.Source != m2.Source {
+		return false
+	}
+	if m1.Target != m2.Target {
+		return false
+	}
+	if m1.RequestBody != m2.RequestBody {
+		return false
+	}
+	if m1.Percentage != m2.Percentage {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mirror
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	target := parser.GetAnnotationWithPrefix("mirror-target")
+	requestBody := parser.GetAnnotationWithPrefix("mirror-request-body")
+	percentage := parser.GetAnnotationWithPrefix("mirror-percentage")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, true},
+		{map[string]string{requestBody: "false"}, &Config{}, true},
+		{map[string]string{target: "https://test.env.com$request_uri"}, &Config{
+			Source:      "/_mirror/default/foo",
+			Target:      "https://test.env.com$request_uri",
+			RequestBody: true,
+			Percentage:  100,
+		}, false},
+		{map[string]string{target: "http://test-svc.test.svc.cluster.local", requestBody: "false", percentage: "10"}, &Config{
+			Source:      "/_mirror/default/foo",
+			Target:      "http://test-svc.test.svc.cluster.local$request_uri",
+			RequestBody: false,
+			Percentage:  10,
+		}, false},
+		{map[string]string{target: "http://test.env.com/mirror"}, &Config{
+			Source:      "/_mirror/default/foo",
+			Target:      "http://test.env.com/mirror",
+			RequestBody: true,
+			Percentage:  100,
+		}, false},
+		{map[string]string{target: "test.env.com"}, &Config{}, true},
+		{map[string]string{target: "http://test.env.com; return 200"}, &Config{}, true},
+		{map[string]string{target: "http://test.env.com", percentage: "101"}, &Config{}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expectErr != (err != nil) {
+			t.Errorf("expected error %v but returned %v, annotations: %s", testCase.expectErr, err, testCase.annotations)
+		}
+
+		p, _ := i.(*Config)
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
+
+func TestSourceIsUnique(t *testing.T) {
+	ap := NewParser(&resolver.Mock{})
+	annotations := map[string]string{parser.GetAnnotationWithPrefix("mirror-target"): "http://test.env.com"}
+
+	sources := map[string]bool{}
+	for _, name := range [][]string{{"a-b", "c"}, {"a", "b-c"}} {
+		ing := &extensions.Ingress{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace:   name[0],
+				Name:        name[1],
+				Annotations: annotations,
+			},
+		}
+
+		i, err := ap.Parse(ing)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		source := i.(*Config).Source
+		if sources[source] {
+			t.Errorf("expected a unique source for Ingress %v/%v but %v is already used", name[0], name[1], source)
+		}
+		sources[source] = true
+	}
+}
@@ -17,17 +17,32 @@ limitations under the License.
 package modsecurity
 
 import (
+	"fmt"
+
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
+
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// valid values of the SecRuleEngine directive
+var modes = map[string]bool{
+	"On":            true,
+	"DetectionOnly": true,
+	"Off":           true,
+}
+
 // Config contains ModSecurity Configuration items
 type Config struct {
 	Enable        bool   `json:"enable-modsecurity"`
 	OWASPRules    bool   `json:"enable-owasp-core-rules"`
 	TransactionID string `json:"modsecurity-transaction-id"`
 	Snippet       string `json:"modsecurity-snippet"`
+	// Mode overrides the SecRuleEngine directive of the rules, to
+	// block the requests (On) or only log them (DetectionOnly)
+	Mode string `json:"modsecurity-mode"`
 }
 
 // Equal tests for equality between two Config types
@@ -50,6 +65,9 @@ func (modsec1 *Config) Equal(modsec2 *Config) bool {
 	if modsec1.Snippet != modsec2.Snippet {
 		return false
 	}
+	if modsec1.Mode != modsec2.Mode {
+		return false
+	}
 
 	return true
 }
@@ -88,6 +106,23 @@ func (a modSecurity) Parse(ing *extensions.Ingress) (interface{}, error) {
 	if err != nil {
 		config.Snippet = ""
 	}
+	if config.Snippet != "" {
+		// the rules are rendered as the argument of modsecurity_rules, the
+		// directives after a closing quote are part of the configuration
+		if err := snippet.Validate(fmt.Sprintf("modsecurity_rules '%v';", config.Snippet)); err != nil {
+			klog.Warningf("Ignoring annotation modsecurity-snippet of Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+			config.Snippet = ""
+		}
+	}
+
+	config.Mode, err = parser.GetStringAnnotation("modsecurity-mode", ing)
+	if err != nil {
+		config.Mode = ""
+	}
+	if config.Mode != "" && !modes[config.Mode] {
+		klog.Warningf("Invalid ModSecurity mode %q in Ingress %v/%v, using the mode of the rules", config.Mode, ing.Namespace, ing.Name)
+		config.Mode = ""
+	}
 
 	return config, nil
 }
@@ -23,6 +23,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -31,6 +32,7 @@ func TestParse(t *testing.T) {
 	owasp := parser.GetAnnotationWithPrefix("enable-owasp-core-rules")
 	transID := parser.GetAnnotationWithPrefix("modsecurity-transaction-id")
 	snippet := parser.GetAnnotationWithPrefix("modsecurity-snippet")
+	mode := parser.GetAnnotationWithPrefix("modsecurity-mode")
 
 	ap := NewParser(&resolver.Mock{})
 	if ap == nil {
@@ -41,22 +43,26 @@ func TestParse(t *testing.T) {
 		annotations map[string]string
 		expected    Config
 	}{
-		{map[string]string{enable: "true"}, Config{true, false, "", ""}},
-		{map[string]string{enable: "false"}, Config{false, false, "", ""}},
-		{map[string]string{enable: ""}, Config{false, false, "", ""}},
+		{map[string]string{enable: "true"}, Config{true, false, "", "", ""}},
+		{map[string]string{enable: "false"}, Config{false, false, "", "", ""}},
+		{map[string]string{enable: ""}, Config{false, false, "", "", ""}},
 
-		{map[string]string{owasp: "true"}, Config{false, true, "", ""}},
-		{map[string]string{owasp: "false"}, Config{false, false, "", ""}},
-		{map[string]string{owasp: ""}, Config{false, false, "", ""}},
+		{map[string]string{owasp: "true"}, Config{false, true, "", "", ""}},
+		{map[string]string{owasp: "false"}, Config{false, false, "", "", ""}},
+		{map[string]string{owasp: ""}, Config{false, false, "", "", ""}},
 
-		{map[string]string{transID: "ok"}, Config{false, false, "ok", ""}},
-		{map[string]string{transID: ""}, Config{false, false, "", ""}},
+		{map[string]string{transID: "ok"}, Config{false, false, "ok", "", ""}},
+		{map[string]string{transID: ""}, Config{false, false, "", "", ""}},
 
-		{map[string]string{snippet: "ModSecurity Rule"}, Config{false, false, "", "ModSecurity Rule"}},
-		{map[string]string{snippet: ""}, Config{false, false, "", ""}},
+		{map[string]string{snippet: "ModSecurity Rule"}, Config{false, false, "", "ModSecurity Rule", ""}},
+		{map[string]string{snippet: ""}, Config{false, false, "", "", ""}},
 
-		{map[string]string{}, Config{false, false, "", ""}},
-		{nil, Config{false, false, "", ""}},
+		{map[string]string{mode: "On"}, Config{false, false, "", "", "On"}},
+		{map[string]string{mode: "DetectionOnly"}, Config{false, false, "", "", "DetectionOnly"}},
+		{map[string]string{mode: "on; SecRule"}, Config{false, false, "", "", ""}},
+
+		{map[string]string{}, Config{false, false, "", "", ""}},
+		{nil, Config{false, false, "", "", ""}},
 	}
 
 	ing := &extensions.Ingress{
@@ -76,3 +82,35 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBlockedSnippet(t *testing.T) {
+	snippet.BlockedDirectives = []string{"include"}
+	defer func() {
+		snippet.BlockedDirectives = []string{}
+	}()
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	testCases := map[string]string{
+		"SecRuleEngine On":                          "SecRuleEngine On",
+		"SecRuleEngine On'; include /etc/passwd; #": "",
+	}
+
+	ap := NewParser(&resolver.Mock{})
+	for value, expected := range testCases {
+		ing.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("modsecurity-snippet"): value,
+		})
+
+		result, _ := ap.Parse(ing)
+		if config := result.(*Config); config.Snippet != expected {
+			t.Errorf("expected the snippet %q but returned %q", expected, config.Snippet)
+		}
+	}
+}
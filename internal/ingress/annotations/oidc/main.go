@@ -0,0 +1,254 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	defaultScope             = "openid"
+	defaultRedirectPath      = "/oauth2/callback"
+	defaultSessionCookieName = "session"
+	defaultSessionLifetime   = 3600
+)
+
+var (
+	pathRegexp       = regexp.MustCompile(`^/[A-Za-z0-9_.~/-]*$`)
+	scopeRegexp      = regexp.MustCompile(`^[A-Za-z0-9_.:/ -]+$`)
+	cookieNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Config returns the OpenID Connect authentication configuration for an Ingress rule
+type Config struct {
+	// DiscoveryURL is the URL of the OpenID Provider configuration document
+	DiscoveryURL string `json:"discoveryUrl"`
+	// Secret is the namespace/name of the Secret with the client credentials
+	Secret string `json:"secret"`
+	// File contains the path to the file with the client credentials
+	File string `json:"file"`
+	// FileSHA contains the sha1 of the file with the client credentials
+	FileSHA           string               `json:"fileSha"`
+	Scope             string               `json:"scope"`
+	RedirectPath      string               `json:"redirectPath"`
+	LogoutPath        string               `json:"logoutPath"`
+	SessionCookieName string               `json:"sessionCookieName"`
+	SessionLifetime   int                  `json:"sessionLifetime"`
+	ClaimHeaders      []parser.ClaimHeader `json:"claimHeaders,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.DiscoveryURL != c2.DiscoveryURL {
+		return false
+	}
+	if c1.Secret != c2.Secret {
+		return false
+	}
+	if c1.File != c2.File {
+		return false
+	}
+	if c1.FileSHA != c2.FileSHA {
+		return false
+	}
+	if c1.Scope != c2.Scope {
+		return false
+	}
+	if c1.RedirectPath != c2.RedirectPath {
+		return false
+	}
+	if c1.LogoutPath != c2.LogoutPath {
+		return false
+	}
+	if c1.SessionCookieName != c2.SessionCookieName {
+		return false
+	}
+	if c1.SessionLifetime != c2.SessionLifetime {
+		return false
+	}
+	if len(c1.ClaimHeaders) != len(c2.ClaimHeaders) {
+		return false
+	}
+	for i := range c1.ClaimHeaders {
+		if c1.ClaimHeaders[i] != c2.ClaimHeaders[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// credentials contains the client credentials used by the
+// Lua module to authenticate against the OpenID Provider
+type credentials struct {
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	SessionSecret string `json:"session_secret"`
+}
+
+type oidc struct {
+	r             resolver.Resolver
+	authDirectory string
+}
+
+// NewParser creates a new OpenID Connect authentication annotation parser
+func NewParser(authDirectory string, r resolver.Resolver) parser.IngressAnnotation {
+	return oidc{r, authDirectory}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// authenticate the requests with an OpenID Provider. The client credentials
+// are written to a file, so they are not included in the NGINX configuration
+func (o oidc) Parse(ing *extensions.Ingress) (interface{}, error) {
+	discoveryURL, err := parser.GetStringAnnotation("oidc-discovery-url", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(discoveryURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.ContainsAny(discoveryURL, `"\`) {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect discovery URL")
+	}
+
+	s, err := parser.GetStringAnnotation("oidc-secret", ing)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrap(err, "error reading secret name from annotation"),
+		}
+	}
+
+	name := fmt.Sprintf("%v/%v", ing.Namespace, s)
+
+	secret, err := o.r.GetSecret(name)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading secret %v", name),
+		}
+	}
+
+	creds := credentials{
+		ClientID:      string(secret.Data["client-id"]),
+		ClientSecret:  string(secret.Data["client-secret"]),
+		SessionSecret: string(secret.Data["session-secret"]),
+	}
+	if creds.ClientID == "" || creds.ClientSecret == "" {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Errorf("the secret %v does not contain the keys client-id and client-secret", name),
+		}
+	}
+	if creds.SessionSecret == "" {
+		// the session cookies must be readable by every NGINX worker and replica
+		sum := sha256.Sum256([]byte(name + creds.ClientSecret))
+		creds.SessionSecret = hex.EncodeToString(sum[:])
+	}
+
+	scope, err := parser.GetStringAnnotation("oidc-scope", ing)
+	if err != nil {
+		scope = defaultScope
+	}
+	if !scopeRegexp.MatchString(scope) {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect scope")
+	}
+
+	redirectPath, err := parser.GetStringAnnotation("oidc-redirect-path", ing)
+	if err != nil {
+		redirectPath = defaultRedirectPath
+	}
+	if !pathRegexp.MatchString(redirectPath) {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect redirect path")
+	}
+
+	logoutPath, _ := parser.GetStringAnnotation("oidc-logout-path", ing)
+	if logoutPath != "" && !pathRegexp.MatchString(logoutPath) {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect logout path")
+	}
+
+	cookieName, err := parser.GetStringAnnotation("oidc-session-cookie-name", ing)
+	if err != nil {
+		cookieName = defaultSessionCookieName
+	}
+	if !cookieNameRegexp.MatchString(cookieName) {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect session cookie name")
+	}
+
+	lifetime, err := parser.GetIntAnnotation("oidc-session-lifetime", ing)
+	if err != nil || lifetime <= 0 {
+		lifetime = defaultSessionLifetime
+	}
+
+	claimHeaders, err := parser.GetClaimHeadersAnnotation("oidc-claim-headers", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	credsFile := fmt.Sprintf("%v/%v-%v-oidc.json", o.authDirectory, ing.GetNamespace(), ing.GetName())
+	err = dumpCredentials(credsFile, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		DiscoveryURL:      discoveryURL,
+		Secret:            name,
+		File:              credsFile,
+		FileSHA:           file.SHA1(credsFile),
+		Scope:             scope,
+		RedirectPath:      redirectPath,
+		LogoutPath:        logoutPath,
+		SessionCookieName: cookieName,
+		SessionLifetime:   lifetime,
+		ClaimHeaders:      claimHeaders,
+	}, nil
+}
+
+// dumpCredentials writes the client credentials into a JSON file
+func dumpCredentials(filename string, creds credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filename, data, file.ReadWriteByUser)
+	if err != nil {
+		return ing_errors.LocationDenied{
+			Reason: errors.Wrap(err, "unexpected error creating OpenID Connect credentials file"),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+}
+
+type mockSecret struct {
+	resolver.Mock
+}
+
+func (m mockSecret) GetSecret(name string) (*api.Secret, error) {
+	switch name {
+	case "default/oidc-client":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: api.NamespaceDefault, Name: "oidc-client"},
+			Data: map[string][]byte{
+				"client-id":      []byte("id"),
+				"client-secret":  []byte("secret"),
+				"session-secret": []byte("session"),
+			},
+		}, nil
+	case "default/incomplete":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: api.NamespaceDefault, Name: "incomplete"},
+			Data:       map[string][]byte{"client-id": []byte("id")},
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no secret with name %v", name)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "oidc")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporal directory: %v", err)
+	}
+	return dir
+}
+
+func TestWithoutAnnotations(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewParser(dir, &mockSecret{}).Parse(buildIngress())
+	if err == nil {
+		t.Error("expected error with ingress without annotations")
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	ing := buildIngress()
+	data := map[string]string{
+		parser.GetAnnotationWithPrefix("oidc-discovery-url"):       "https://accounts.example.com/.well-known/openid-configuration",
+		parser.GetAnnotationWithPrefix("oidc-secret"):              "oidc-client",
+		parser.GetAnnotationWithPrefix("oidc-scope"):               "openid email",
+		parser.GetAnnotationWithPrefix("oidc-session-cookie-name"): "app_session",
+		parser.GetAnnotationWithPrefix("oidc-session-lifetime"):    "600",
+		parser.GetAnnotationWithPrefix("oidc-claim-headers"):       "X-Auth-User:sub, X-Auth-Roles:https://example.com/roles",
+	}
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(dir, &mockSecret{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	expected := &Config{
+		DiscoveryURL:      "https://accounts.example.com/.well-known/openid-configuration",
+		Secret:            "default/oidc-client",
+		File:              dir + "/default-foo-oidc.json",
+		FileSHA:           cfg.FileSHA,
+		Scope:             "openid email",
+		RedirectPath:      defaultRedirectPath,
+		SessionCookieName: "app_session",
+		SessionLifetime:   600,
+		ClaimHeaders: []parser.ClaimHeader{
+			{Header: "X-Auth-User", Claim: "sub"},
+			{Header: "X-Auth-Roles", Claim: "https://example.com/roles"},
+		},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %v but returned %v", expected, cfg)
+	}
+	if cfg.FileSHA == "" {
+		t.Errorf("expected the sha of the credentials file")
+	}
+
+	content, err := ioutil.ReadFile(cfg.File)
+	if err != nil {
+		t.Fatalf("unexpected error reading credentials file: %v", err)
+	}
+
+	creds := credentials{}
+	err = json.Unmarshal(content, &creds)
+	if err != nil {
+		t.Fatalf("unexpected error parsing credentials file: %v", err)
+	}
+	if creds != (credentials{ClientID: "id", ClientSecret: "secret", SessionSecret: "session"}) {
+		t.Errorf("unexpected credentials %v", creds)
+	}
+}
+
+func TestInvalidAnnotations(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	valid := map[string]string{
+		"oidc-discovery-url": "https://accounts.example.com/.well-known/openid-configuration",
+		"oidc-secret":        "oidc-client",
+	}
+
+	testCases := map[string]map[string]string{
+		"invalid discovery url":  {"oidc-discovery-url": "ftp://accounts.example.com"},
+		"quotes in url":          {"oidc-discovery-url": `https://accounts.example.com/"`},
+		"missing secret":         {"oidc-secret": "missing"},
+		"incomplete secret":      {"oidc-secret": "incomplete"},
+		"invalid scope":          {"oidc-scope": `openid"`},
+		"invalid redirect path":  {"oidc-redirect-path": "callback"},
+		"invalid logout path":    {"oidc-logout-path": `/logout"`},
+		"invalid cookie name":    {"oidc-session-cookie-name": "session;"},
+		"invalid claim header":   {"oidc-claim-headers": "X-Auth-User"},
+		"invalid header name":    {"oidc-claim-headers": "X Auth:sub"},
+		"invalid claim name":     {"oidc-claim-headers": `X-Auth:"sub"`},
+		"cross namespace secret": {"oidc-secret": "other/oidc-client"},
+	}
+
+	for name, tc := range testCases {
+		ing := buildIngress()
+		data := map[string]string{}
+		for k, v := range valid {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		for k, v := range tc {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(dir, &mockSecret{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
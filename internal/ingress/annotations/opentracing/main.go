@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opentracing
+
+import (
+	"math"
+	"strconv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	enableOpentracing     = "enable-opentracing"
+	opentracingSampleRate = "opentracing-sample-rate"
+)
+
+// Config describes the tracing of the requests of the locations of an Ingress
+type Config struct {
+	// Set indicates the Ingress overrides the global enable-opentracing setting
+	Set bool `json:"set"`
+	// Enabled indicates if the requests are traced. Only used when Set is true
+	Enabled bool `json:"enabled"`
+	// SampleRate is the ratio of the requests sampled, between 0 and 1.
+	// The zero value keeps the sampling configured in the tracer
+	SampleRate float64 `json:"sampleRate,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Set != c2.Set {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.SampleRate != c2.SampleRate {
+		return false
+	}
+
+	return true
+}
+
+type opentracing struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new opentracing annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return opentracing{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to enable or disable the tracing of the requests
+func (o opentracing) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	enabled, err := parser.GetBoolAnnotation(enableOpentracing, ing)
+	if err != nil && !ing_errors.IsMissingAnnotations(err) {
+		return config, err
+	}
+	if err == nil {
+		config.Set = true
+		config.Enabled = enabled
+	}
+
+	val, err := parser.GetStringAnnotation(opentracingSampleRate, ing)
+	if err != nil {
+		if ing_errors.IsMissingAnnotations(err) {
+			return config, nil
+		}
+		return config, err
+	}
+
+	// NGINX splits the requests with a precision of 0.01%
+	rate, err := strconv.ParseFloat(val, 64)
+	if err != nil || math.IsNaN(rate) || rate > 1 || math.Round(rate*10000) < 1 {
+		return config, ing_errors.NewInvalidAnnotationContent(opentracingSampleRate, val)
+	}
+
+	config.SampleRate = rate
+
+	return config, nil
+}
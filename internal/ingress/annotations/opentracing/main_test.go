@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opentracing
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix(enableOpentracing)
+	sampleRate := parser.GetAnnotationWithPrefix(opentracingSampleRate)
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    Config
+		expectErr   bool
+	}{
+		{nil, Config{}, false},
+		{map[string]string{enable: "true"}, Config{Set: true, Enabled: true}, false},
+		{map[string]string{enable: "false"}, Config{Set: true, Enabled: false}, false},
+		{map[string]string{enable: "true", sampleRate: "0.1"}, Config{Set: true, Enabled: true, SampleRate: 0.1}, false},
+		{map[string]string{sampleRate: "1"}, Config{SampleRate: 1}, false},
+		{map[string]string{enable: "true", sampleRate: "0"}, Config{Set: true, Enabled: true}, true},
+		{map[string]string{sampleRate: "0.00001"}, Config{}, true},
+		{map[string]string{sampleRate: "1.5"}, Config{}, true},
+		{map[string]string{sampleRate: "NaN"}, Config{}, true},
+		{map[string]string{sampleRate: "often"}, Config{}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, tc := range testCases {
+		ing.SetAnnotations(tc.annotations)
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if tc.expectErr && err == nil {
+			t.Errorf("expected an error parsing %v", tc.annotations)
+		}
+		if !tc.expectErr && err != nil {
+			t.Errorf("unexpected error parsing %v: %v", tc.annotations, err)
+		}
+
+		cfg, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but %T was returned", i)
+		}
+		if !cfg.Equal(&tc.expected) {
+			t.Errorf("expected %+v but returned %+v, annotations: %v", tc.expected, *cfg, tc.annotations)
+		}
+	}
+}
@@ -18,7 +18,9 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
@@ -28,8 +30,18 @@ import (
 var (
 	// AnnotationsPrefix defines the common prefix used in the nginx ingress controller
 	AnnotationsPrefix = "nginx.ingress.kubernetes.io"
+
+	headerRegexp = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	claimRegexp  = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
 )
 
+// ClaimHeader defines a request header sent to the upstream
+// with the value of a claim of a token
+type ClaimHeader struct {
+	Header string `json:"header"`
+	Claim  string `json:"claim"`
+}
+
 // IngressAnnotation has a method to parse annotations located in Ingress
 type IngressAnnotation interface {
 	Parse(ing *extensions.Ingress) (interface{}, error)
@@ -115,6 +127,32 @@ func GetIntAnnotation(name string, ing *extensions.Ingress) (int, error) {
 	return ingAnnotations(ing.GetAnnotations()).parseInt(v)
 }
 
+// GetClaimHeadersAnnotation extracts a comma separated list of header:claim
+// pairs from an Ingress annotation. The list is empty without the annotation
+func GetClaimHeadersAnnotation(name string, ing *extensions.Ingress) ([]ClaimHeader, error) {
+	claimHeaders := []ClaimHeader{}
+	hstr, _ := GetStringAnnotation(name, ing)
+	for _, entry := range strings.Split(hstr, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.NewLocationDenied(fmt.Sprintf("invalid claim header %q", entry))
+		}
+
+		ch := ClaimHeader{Header: strings.TrimSpace(parts[0]), Claim: strings.TrimSpace(parts[1])}
+		if !headerRegexp.MatchString(ch.Header) || !claimRegexp.MatchString(ch.Claim) {
+			return nil, errors.NewLocationDenied(fmt.Sprintf("invalid claim header %q", entry))
+		}
+		claimHeaders = append(claimHeaders, ch)
+	}
+
+	return claimHeaders, nil
+}
+
 // GetAnnotationWithPrefix returns the prefix of ingress annotations
 func GetAnnotationWithPrefix(suffix string) string {
 	return fmt.Sprintf("%v/%v", AnnotationsPrefix, suffix)
@@ -17,6 +17,7 @@ limitations under the License.
 package parser
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -154,3 +155,44 @@ func TestGetIntAnnotation(t *testing.T) {
 		delete(data, test.field)
 	}
 }
+
+func TestGetClaimHeadersAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		name   string
+		value  string
+		exp    []ClaimHeader
+		expErr bool
+	}{
+		{"empty", "", []ClaimHeader{}, false},
+		{"valid", " X-Auth-User: sub ,, X-Auth-Roles:https://example.com/roles", []ClaimHeader{
+			{Header: "X-Auth-User", Claim: "sub"},
+			{Header: "X-Auth-Roles", Claim: "https://example.com/roles"},
+		}, false},
+		{"missing claim", "X-Auth-User", nil, true},
+		{"invalid header", "X Auth:sub", nil, true},
+		{"invalid claim", "X-Auth-User:sub;", nil, true},
+	}
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	for _, test := range tests {
+		data[GetAnnotationWithPrefix("claim-headers")] = test.value
+
+		chs, err := GetClaimHeadersAnnotation("claim-headers", ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(chs, test.exp) {
+			t.Errorf("%v: expected %v but %v was returned", test.name, test.exp, chs)
+		}
+	}
+}
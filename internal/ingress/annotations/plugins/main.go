@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// NameRegexp matches the valid names of a Lua plugin. The name is used
+// to locate the module plugins.<name>.main in the Lua package path
+var NameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+type plugins struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the Lua plugins enabled in an Ingress rule
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return plugins{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to enable Lua plugins in its locations
+func (a plugins) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetStringAnnotation("plugins", ing)
+	if err != nil {
+		return []string{}, nil
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !NameRegexp.MatchString(name) {
+			return []string{}, ing_errors.NewInvalidAnnotationContent("plugins", val)
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
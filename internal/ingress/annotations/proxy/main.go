@@ -17,28 +17,79 @@ limitations under the License.
 package proxy
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// validNextUpstream contains the cases in which a request can be passed to
+// the next upstream server
+// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream
+var validNextUpstream = map[string]bool{
+	"error":          true,
+	"timeout":        true,
+	"invalid_header": true,
+	"http_500":       true,
+	"http_502":       true,
+	"http_503":       true,
+	"http_504":       true,
+	"http_403":       true,
+	"http_404":       true,
+	"http_429":       true,
+	"non_idempotent": true,
+	"off":            true,
+}
+
+// isValidNextUpstream checks the value only contains known cases and
+// "off" is not combined with any other case
+func isValidNextUpstream(value string) bool {
+	cases := strings.Fields(value)
+	if len(cases) == 0 {
+		return false
+	}
+
+	for _, c := range cases {
+		if !validNextUpstream[c] {
+			return false
+		}
+
+		if c == "off" && len(cases) > 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SizeRegex matches the sizes accepted by the NGINX directives,
+// refer to http://nginx.org/en/docs/syntax.html
+var SizeRegex = regexp.MustCompile(`^[0-9]+[kKmM]?$`)
+
 // Config returns the proxy timeout to use in the upstream server/s
 type Config struct {
-	BodySize          string `json:"bodySize"`
-	ConnectTimeout    int    `json:"connectTimeout"`
-	SendTimeout       int    `json:"sendTimeout"`
-	ReadTimeout       int    `json:"readTimeout"`
-	BuffersNumber     int    `json:"buffersNumber"`
-	BufferSize        string `json:"bufferSize"`
-	CookieDomain      string `json:"cookieDomain"`
-	CookiePath        string `json:"cookiePath"`
-	NextUpstream      string `json:"nextUpstream"`
-	NextUpstreamTries int    `json:"nextUpstreamTries"`
-	ProxyRedirectFrom string `json:"proxyRedirectFrom"`
-	ProxyRedirectTo   string `json:"proxyRedirectTo"`
-	RequestBuffering  string `json:"requestBuffering"`
-	ProxyBuffering    string `json:"proxyBuffering"`
+	BodySize            string `json:"bodySize"`
+	ConnectTimeout      int    `json:"connectTimeout"`
+	SendTimeout         int    `json:"sendTimeout"`
+	ReadTimeout         int    `json:"readTimeout"`
+	BuffersNumber       int    `json:"buffersNumber"`
+	BufferSize          string `json:"bufferSize"`
+	CookieDomain        string `json:"cookieDomain"`
+	CookiePath          string `json:"cookiePath"`
+	NextUpstream        string `json:"nextUpstream"`
+	NextUpstreamTries   int    `json:"nextUpstreamTries"`
+	NextUpstreamTimeout int    `json:"nextUpstreamTimeout"`
+	ProxyRedirectFrom   string `json:"proxyRedirectFrom"`
+	ProxyRedirectTo     string `json:"proxyRedirectTo"`
+	RequestBuffering    string `json:"requestBuffering"`
+	ProxyBuffering      string `json:"proxyBuffering"`
+	MaxTempFileSize     string `json:"maxTempFileSize"`
 }
 
 // Equal tests for equality between two Configuration types
@@ -79,6 +130,9 @@ func (l1 *Config) Equal(l2 *Config) bool {
 	if l1.NextUpstreamTries != l2.NextUpstreamTries {
 		return false
 	}
+	if l1.NextUpstreamTimeout != l2.NextUpstreamTimeout {
+		return false
+	}
 	if l1.RequestBuffering != l2.RequestBuffering {
 		return false
 	}
@@ -91,6 +145,9 @@ func (l1 *Config) Equal(l2 *Config) bool {
 	if l1.ProxyBuffering != l2.ProxyBuffering {
 		return false
 	}
+	if l1.MaxTempFileSize != l2.MaxTempFileSize {
+		return false
+	}
 
 	return true
 }
@@ -104,37 +161,91 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return proxy{r}
 }
 
+// parseTimeout returns the value of a proxy timeout annotation. The default
+// timeout is used when the annotation is not a number of seconds between 1
+// and the maximum configured in the proxy-max-timeout setting
+func parseTimeout(name string, ing *extensions.Ingress, def, max int) (int, error) {
+	timeout, err := parser.GetIntAnnotation(name, ing)
+	if err != nil {
+		if ing_errors.IsMissingAnnotations(err) {
+			return def, nil
+		}
+
+		return def, err
+	}
+
+	if timeout < 1 || (max > 0 && timeout > max) {
+		return def, ing_errors.NewInvalidAnnotationContent(name, fmt.Sprintf("%v, must be between 1 and %v seconds", timeout, max))
+	}
+
+	return timeout, nil
+}
+
+// parseSize returns the value of a proxy buffer size annotation or
+// the default size when the annotation is not a valid NGINX size
+func parseSize(name string, ing *extensions.Ingress, def string) (string, error) {
+	size, err := parser.GetStringAnnotation(name, ing)
+	if err != nil {
+		if ing_errors.IsMissingAnnotations(err) {
+			return def, nil
+		}
+
+		return def, err
+	}
+
+	if !SizeRegex.MatchString(size) {
+		return def, ing_errors.NewInvalidAnnotationContent(name, size)
+	}
+
+	return size, nil
+}
+
 // ParseAnnotations parses the annotations contained in the ingress
-// rule used to configure upstream check parameters
+// rule used to configure upstream check parameters. Invalid timeouts and
+// buffer settings are replaced with the default values and reported in
+// the returned error
 func (a proxy) Parse(ing *extensions.Ingress) (interface{}, error) {
 	defBackend := a.r.GetDefaultBackend()
 	config := &Config{}
 
-	var err error
+	var err, invalidErr error
 
-	config.ConnectTimeout, err = parser.GetIntAnnotation("proxy-connect-timeout", ing)
+	config.ConnectTimeout, err = parseTimeout("proxy-connect-timeout", ing, defBackend.ProxyConnectTimeout, defBackend.ProxyMaxTimeout)
 	if err != nil {
-		config.ConnectTimeout = defBackend.ProxyConnectTimeout
+		invalidErr = err
 	}
 
-	config.SendTimeout, err = parser.GetIntAnnotation("proxy-send-timeout", ing)
-	if err != nil {
-		config.SendTimeout = defBackend.ProxySendTimeout
+	config.SendTimeout, err = parseTimeout("proxy-send-timeout", ing, defBackend.ProxySendTimeout, defBackend.ProxyMaxTimeout)
+	if err != nil && invalidErr == nil {
+		invalidErr = err
 	}
 
-	config.ReadTimeout, err = parser.GetIntAnnotation("proxy-read-timeout", ing)
-	if err != nil {
-		config.ReadTimeout = defBackend.ProxyReadTimeout
+	config.ReadTimeout, err = parseTimeout("proxy-read-timeout", ing, defBackend.ProxyReadTimeout, defBackend.ProxyMaxTimeout)
+	if err != nil && invalidErr == nil {
+		invalidErr = err
 	}
 
 	config.BuffersNumber, err = parser.GetIntAnnotation("proxy-buffers-number", ing)
 	if err != nil {
 		config.BuffersNumber = defBackend.ProxyBuffersNumber
+		if !ing_errors.IsMissingAnnotations(err) && invalidErr == nil {
+			invalidErr = err
+		}
+	} else if config.BuffersNumber < 1 {
+		config.BuffersNumber = defBackend.ProxyBuffersNumber
+		if invalidErr == nil {
+			invalidErr = ing_errors.NewInvalidAnnotationContent("proxy-buffers-number", config.BuffersNumber)
+		}
 	}
 
-	config.BufferSize, err = parser.GetStringAnnotation("proxy-buffer-size", ing)
-	if err != nil {
-		config.BufferSize = defBackend.ProxyBufferSize
+	config.BufferSize, err = parseSize("proxy-buffer-size", ing, defBackend.ProxyBufferSize)
+	if err != nil && invalidErr == nil {
+		invalidErr = err
+	}
+
+	config.MaxTempFileSize, err = parseSize("proxy-max-temp-file-size", ing, defBackend.ProxyMaxTempFileSize)
+	if err != nil && invalidErr == nil {
+		invalidErr = err
 	}
 
 	config.CookiePath, err = parser.GetStringAnnotation("proxy-cookie-path", ing)
@@ -155,11 +266,25 @@ func (a proxy) Parse(ing *extensions.Ingress) (interface{}, error) {
 	config.NextUpstream, err = parser.GetStringAnnotation("proxy-next-upstream", ing)
 	if err != nil {
 		config.NextUpstream = defBackend.ProxyNextUpstream
+	} else if !isValidNextUpstream(config.NextUpstream) {
+		klog.Warningf("%v is not a valid value for the annotation proxy-next-upstream. Using %v as default", config.NextUpstream, defBackend.ProxyNextUpstream)
+		config.NextUpstream = defBackend.ProxyNextUpstream
 	}
 
 	config.NextUpstreamTries, err = parser.GetIntAnnotation("proxy-next-upstream-tries", ing)
 	if err != nil {
 		config.NextUpstreamTries = defBackend.ProxyNextUpstreamTries
+	} else if config.NextUpstreamTries < 0 {
+		klog.Warningf("%v is not a valid value for the annotation proxy-next-upstream-tries. Using %v as default", config.NextUpstreamTries, defBackend.ProxyNextUpstreamTries)
+		config.NextUpstreamTries = defBackend.ProxyNextUpstreamTries
+	}
+
+	config.NextUpstreamTimeout, err = parser.GetIntAnnotation("proxy-next-upstream-timeout", ing)
+	if err != nil {
+		config.NextUpstreamTimeout = defBackend.ProxyNextUpstreamTimeout
+	} else if config.NextUpstreamTimeout < 0 {
+		klog.Warningf("%v is not a valid value for the annotation proxy-next-upstream-timeout. Using %v as default", config.NextUpstreamTimeout, defBackend.ProxyNextUpstreamTimeout)
+		config.NextUpstreamTimeout = defBackend.ProxyNextUpstreamTimeout
 	}
 
 	config.RequestBuffering, err = parser.GetStringAnnotation("proxy-request-buffering", ing)
@@ -180,7 +305,12 @@ func (a proxy) Parse(ing *extensions.Ingress) (interface{}, error) {
 	config.ProxyBuffering, err = parser.GetStringAnnotation("proxy-buffering", ing)
 	if err != nil {
 		config.ProxyBuffering = defBackend.ProxyBuffering
+	} else if config.ProxyBuffering != "on" && config.ProxyBuffering != "off" {
+		if invalidErr == nil {
+			invalidErr = ing_errors.NewInvalidAnnotationContent("proxy-buffering", config.ProxyBuffering)
+		}
+		config.ProxyBuffering = defBackend.ProxyBuffering
 	}
 
-	return config, nil
+	return config, invalidErr
 }
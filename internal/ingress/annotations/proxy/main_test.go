@@ -26,6 +26,7 @@ import (
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -70,16 +71,19 @@ type mockBackend struct {
 
 func (m mockBackend) GetDefaultBackend() defaults.Backend {
 	return defaults.Backend{
-		ProxyConnectTimeout:    10,
-		ProxySendTimeout:       15,
-		ProxyReadTimeout:       20,
-		ProxyBuffersNumber:     4,
-		ProxyBufferSize:        "10k",
-		ProxyBodySize:          "3k",
-		ProxyNextUpstream:      "error",
-		ProxyNextUpstreamTries: 3,
-		ProxyRequestBuffering:  "on",
-		ProxyBuffering:         "off",
+		ProxyConnectTimeout:      10,
+		ProxySendTimeout:         15,
+		ProxyReadTimeout:         20,
+		ProxyMaxTimeout:          120,
+		ProxyBuffersNumber:       4,
+		ProxyBufferSize:          "10k",
+		ProxyMaxTempFileSize:     "1024m",
+		ProxyBodySize:            "3k",
+		ProxyNextUpstream:        "error",
+		ProxyNextUpstreamTries:   3,
+		ProxyNextUpstreamTimeout: 30,
+		ProxyRequestBuffering:    "on",
+		ProxyBuffering:           "off",
 	}
 }
 
@@ -95,6 +99,7 @@ func TestProxy(t *testing.T) {
 	data[parser.GetAnnotationWithPrefix("proxy-body-size")] = "2k"
 	data[parser.GetAnnotationWithPrefix("proxy-next-upstream")] = "off"
 	data[parser.GetAnnotationWithPrefix("proxy-next-upstream-tries")] = "3"
+	data[parser.GetAnnotationWithPrefix("proxy-next-upstream-timeout")] = "5"
 	data[parser.GetAnnotationWithPrefix("proxy-request-buffering")] = "off"
 	data[parser.GetAnnotationWithPrefix("proxy-buffering")] = "on"
 	ing.SetAnnotations(data)
@@ -131,6 +136,9 @@ func TestProxy(t *testing.T) {
 	if p.NextUpstreamTries != 3 {
 		t.Errorf("expected 3 as next-upstream-tries but returned %v", p.NextUpstreamTries)
 	}
+	if p.NextUpstreamTimeout != 5 {
+		t.Errorf("expected 5 as next-upstream-timeout but returned %v", p.NextUpstreamTimeout)
+	}
 	if p.RequestBuffering != "off" {
 		t.Errorf("expected off as request-buffering but returned %v", p.RequestBuffering)
 	}
@@ -177,7 +185,154 @@ func TestProxyWithNoAnnotation(t *testing.T) {
 	if p.NextUpstreamTries != 3 {
 		t.Errorf("expected 3 as next-upstream-tries but returned %v", p.NextUpstreamTries)
 	}
+	if p.NextUpstreamTimeout != 30 {
+		t.Errorf("expected 30 as next-upstream-timeout but returned %v", p.NextUpstreamTimeout)
+	}
 	if p.RequestBuffering != "on" {
 		t.Errorf("expected on as request-buffering but returned %v", p.RequestBuffering)
 	}
 }
+
+func TestProxyNextUpstream(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		nextUpstream    string
+		tries           string
+		timeout         string
+		expected        string
+		expectedTries   int
+		expectedTimeout int
+	}{
+		{"error timeout http_502", "5", "10", "error timeout http_502", 5, 10},
+		{"error timeout non_idempotent", "0", "0", "error timeout non_idempotent", 0, 0},
+		{"off", "1", "", "off", 1, 30},
+		{"error off", "", "", "error", 3, 30},
+		{"error http_418", "-1", "-5", "error", 3, 30},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("proxy-next-upstream")] = test.nextUpstream
+		if test.tries != "" {
+			data[parser.GetAnnotationWithPrefix("proxy-next-upstream-tries")] = test.tries
+		}
+		if test.timeout != "" {
+			data[parser.GetAnnotationWithPrefix("proxy-next-upstream-timeout")] = test.timeout
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if err != nil {
+			t.Fatalf("unexpected error parsing a valid")
+		}
+		p := i.(*Config)
+		if p.NextUpstream != test.expected {
+			t.Errorf("expected %v as next-upstream but returned %v", test.expected, p.NextUpstream)
+		}
+		if p.NextUpstreamTries != test.expectedTries {
+			t.Errorf("expected %v as next-upstream-tries but returned %v", test.expectedTries, p.NextUpstreamTries)
+		}
+		if p.NextUpstreamTimeout != test.expectedTimeout {
+			t.Errorf("expected %v as next-upstream-timeout but returned %v", test.expectedTimeout, p.NextUpstreamTimeout)
+		}
+	}
+}
+
+func TestProxyInvalidTimeouts(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		annotations map[string]string
+		connect     int
+		send        int
+		read        int
+		invalid     bool
+	}{
+		{map[string]string{"proxy-connect-timeout": "1", "proxy-send-timeout": "120", "proxy-read-timeout": "60"}, 1, 120, 60, false},
+		{map[string]string{"proxy-connect-timeout": "5s"}, 10, 15, 20, true},
+		{map[string]string{"proxy-send-timeout": "0"}, 10, 15, 20, true},
+		{map[string]string{"proxy-read-timeout": "-1"}, 10, 15, 20, true},
+		{map[string]string{"proxy-read-timeout": "121", "proxy-send-timeout": "30"}, 10, 30, 20, true},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{}
+		for name, value := range test.annotations {
+			data[parser.GetAnnotationWithPrefix(name)] = value
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if test.invalid && !errors.IsInvalidContent(err) {
+			t.Errorf("expected an invalid content error for %v but returned %v", test.annotations, err)
+		}
+		if !test.invalid && err != nil {
+			t.Errorf("unexpected error parsing %v: %v", test.annotations, err)
+		}
+
+		p, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type")
+		}
+		if p.ConnectTimeout != test.connect {
+			t.Errorf("expected %v as connect-timeout but returned %v", test.connect, p.ConnectTimeout)
+		}
+		if p.SendTimeout != test.send {
+			t.Errorf("expected %v as send-timeout but returned %v", test.send, p.SendTimeout)
+		}
+		if p.ReadTimeout != test.read {
+			t.Errorf("expected %v as read-timeout but returned %v", test.read, p.ReadTimeout)
+		}
+	}
+}
+
+func TestProxyBuffering(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		annotations     map[string]string
+		buffering       string
+		buffersNumber   int
+		bufferSize      string
+		maxTempFileSize string
+		invalid         bool
+	}{
+		{map[string]string{}, "off", 4, "10k", "1024m", false},
+		{map[string]string{"proxy-buffering": "on", "proxy-buffers-number": "8", "proxy-buffer-size": "16k", "proxy-max-temp-file-size": "0"}, "on", 8, "16k", "0", false},
+		{map[string]string{"proxy-buffering": "yes"}, "off", 4, "10k", "1024m", true},
+		{map[string]string{"proxy-buffers-number": "0"}, "off", 4, "10k", "1024m", true},
+		{map[string]string{"proxy-buffer-size": "8 k"}, "off", 4, "10k", "1024m", true},
+		{map[string]string{"proxy-max-temp-file-size": "2g; return 200"}, "off", 4, "10k", "1024m", true},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{}
+		for name, value := range test.annotations {
+			data[parser.GetAnnotationWithPrefix(name)] = value
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if test.invalid && !errors.IsInvalidContent(err) {
+			t.Errorf("expected an invalid content error for %v but returned %v", test.annotations, err)
+		}
+		if !test.invalid && err != nil {
+			t.Errorf("unexpected error parsing %v: %v", test.annotations, err)
+		}
+
+		p := i.(*Config)
+		if p.ProxyBuffering != test.buffering {
+			t.Errorf("expected %v as proxy-buffering but returned %v", test.buffering, p.ProxyBuffering)
+		}
+		if p.BuffersNumber != test.buffersNumber {
+			t.Errorf("expected %v as proxy-buffers-number but returned %v", test.buffersNumber, p.BuffersNumber)
+		}
+		if p.BufferSize != test.bufferSize {
+			t.Errorf("expected %v as proxy-buffer-size but returned %v", test.bufferSize, p.BufferSize)
+		}
+		if p.MaxTempFileSize != test.maxTempFileSize {
+			t.Errorf("expected %v as proxy-max-temp-file-size but returned %v", test.maxTempFileSize, p.MaxTempFileSize)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyprotocol
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/net/proxyproto"
+)
+
+type proxyProtocol struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new PROXY protocol annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxyProtocol{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to send the PROXY protocol header to SSL passthrough upstreams
+func (pp proxyProtocol) Parse(ing *extensions.Ingress) (interface{}, error) {
+	version, err := parser.GetStringAnnotation("ssl-passthrough-proxy-protocol", ing)
+	if err != nil {
+		return "", err
+	}
+
+	switch version {
+	case proxyproto.V1, proxyproto.V2:
+		return version, nil
+	default:
+		return "", ing_errors.NewInvalidAnnotationContent("ssl-passthrough-proxy-protocol", version)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyssl
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	defaultProxySSLVerify      = "off"
+	defaultProxySSLVerifyDepth = 1
+)
+
+var (
+	proxySSLVerifyRegex = regexp.MustCompile(`^(on|off)$`)
+	proxySSLNameRegex   = regexp.MustCompile(`^[A-Za-z0-9\-.]+$`)
+)
+
+// Config contains the client certificate presented to the upstream
+// servers and the verification of their certificates
+type Config struct {
+	resolver.AuthSSLCert
+	Verify      string `json:"verify"`
+	VerifyDepth int    `json:"verifyDepth"`
+	ServerName  string `json:"serverName,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if !(&c1.AuthSSLCert).Equal(&c2.AuthSSLCert) {
+		return false
+	}
+	if c1.Verify != c2.Verify {
+		return false
+	}
+	if c1.VerifyDepth != c2.VerifyDepth {
+		return false
+	}
+
+	return c1.ServerName == c2.ServerName
+}
+
+type proxySSL struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new TLS upstream annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxySSL{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure TLS connections to the upstream servers
+func (a proxySSL) Parse(ing *extensions.Ingress) (interface{}, error) {
+	var err error
+	config := &Config{}
+
+	secret, err := parser.GetStringAnnotation("proxy-ssl-secret", ing)
+	if err != nil {
+		return &Config{}, err
+	}
+
+	ns, name, err := cache.SplitMetaNamespaceKey(secret)
+	if err != nil || name == "" {
+		return &Config{}, ing_errors.NewLocationDenied(fmt.Sprintf("invalid Secret %q in annotation proxy-ssl-secret", secret))
+	}
+
+	if ns == "" {
+		ns = ing.Namespace
+	}
+
+	if ns != ing.Namespace {
+		return &Config{}, ing_errors.NewLocationDenied("the Secret with the upstream client certificate must be in the namespace of the Ingress")
+	}
+
+	cert, err := a.r.GetAuthCertificate(fmt.Sprintf("%v/%v", ns, name))
+	if err != nil {
+		return &Config{}, ing_errors.LocationDenied{
+			Reason: errors.Wrap(err, "error obtaining certificate"),
+		}
+	}
+	config.AuthSSLCert = *cert
+
+	config.Verify, err = parser.GetStringAnnotation("proxy-ssl-verify", ing)
+	if err != nil || !proxySSLVerifyRegex.MatchString(config.Verify) {
+		config.Verify = defaultProxySSLVerify
+	}
+
+	if config.Verify == "on" && config.CAFileName == "" {
+		return &Config{}, ing_errors.NewLocationDenied(fmt.Sprintf("the Secret %v/%v does not contain a ca.crt to verify the upstream servers", ns, name))
+	}
+
+	config.VerifyDepth, err = parser.GetIntAnnotation("proxy-ssl-verify-depth", ing)
+	if err != nil || config.VerifyDepth <= 0 {
+		config.VerifyDepth = defaultProxySSLVerifyDepth
+	}
+
+	config.ServerName, err = parser.GetStringAnnotation("proxy-ssl-name", ing)
+	if err != nil || !proxySSLNameRegex.MatchString(config.ServerName) {
+		config.ServerName = ""
+	}
+
+	return config, nil
+}
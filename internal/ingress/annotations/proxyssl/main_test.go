@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyssl
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockSecret struct {
+	resolver.Mock
+}
+
+// GetAuthCertificate mocks the GetAuthCertificate call from the proxyssl package
+func (m mockSecret) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error) {
+	switch name {
+	case "default/upstream-tls":
+		return &resolver.AuthSSLCert{
+			Secret:      "default/upstream-tls",
+			CAFileName:  "/ssl/default-upstream-tls.pem",
+			PemFileName: "/ssl/default-upstream-tls.pem",
+			PemSHA:      "abc",
+		}, nil
+	case "other/client-cert":
+		return &resolver.AuthSSLCert{
+			Secret:      "other/client-cert",
+			PemFileName: "/ssl/other-client-cert.pem",
+			PemSHA:      "def",
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no secret with name %v", name)
+}
+
+func TestParse(t *testing.T) {
+	secret := parser.GetAnnotationWithPrefix("proxy-ssl-secret")
+	verify := parser.GetAnnotationWithPrefix("proxy-ssl-verify")
+	verifyDepth := parser.GetAnnotationWithPrefix("proxy-ssl-verify-depth")
+	name := parser.GetAnnotationWithPrefix("proxy-ssl-name")
+
+	upstreamTLS := resolver.AuthSSLCert{
+		Secret:      "default/upstream-tls",
+		CAFileName:  "/ssl/default-upstream-tls.pem",
+		PemFileName: "/ssl/default-upstream-tls.pem",
+		PemSHA:      "abc",
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, true},
+		{map[string]string{secret: "upstream-tls"}, &Config{AuthSSLCert: upstreamTLS, Verify: "off", VerifyDepth: 1}, false},
+		{map[string]string{secret: "default/upstream-tls", verify: "on", verifyDepth: "2", name: "app.internal"},
+			&Config{AuthSSLCert: upstreamTLS, Verify: "on", VerifyDepth: 2, ServerName: "app.internal"}, false},
+		{map[string]string{secret: "upstream-tls", verify: "yes", verifyDepth: "-1", name: "app; internal"},
+			&Config{AuthSSLCert: upstreamTLS, Verify: "off", VerifyDepth: 1}, false},
+		{map[string]string{secret: "other/client-cert"}, &Config{}, true},
+		{map[string]string{secret: "missing"}, &Config{}, true},
+		{map[string]string{secret: "a/b/c"}, &Config{}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := NewParser(mockSecret{}).Parse(ing)
+		if testCase.expectErr != (err != nil) {
+			t.Errorf("expected error %v but returned %v, annotations: %s", testCase.expectErr, err, testCase.annotations)
+		}
+
+		if err != nil && !errors.IsMissingAnnotations(err) && !errors.IsLocationDenied(err) {
+			t.Errorf("expected a LocationDenied error but returned %v, annotations: %s", err, testCase.annotations)
+		}
+
+		p, _ := i.(*Config)
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
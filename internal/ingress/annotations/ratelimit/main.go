@@ -19,12 +19,14 @@ package ratelimit
 import (
 	"encoding/base64"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 	"k8s.io/ingress-nginx/internal/net"
 )
@@ -38,6 +40,8 @@ const (
 	defSharedSize = 5
 )
 
+var headerRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
 // Config returns rate limit configuration for an Ingress rule limiting the
 // number of connections per IP address and/or connections per second.
 // If you both annotations are specified in a single Ingress rule, RPS limits
@@ -118,6 +122,9 @@ type Zone struct {
 	Burst int    `json:"burst"`
 	// SharedSize amount of shared memory for the zone
 	SharedSize int `json:"sharedSize"`
+	// Key is the variable used as the key of the zone instead of the
+	// client address. Only used by the connections limit
+	Key string `json:"key"`
 }
 
 // Equal tests for equality between two Zone types
@@ -140,6 +147,9 @@ func (z1 *Zone) Equal(z2 *Zone) bool {
 	if z1.SharedSize != z2.SharedSize {
 		return false
 	}
+	if z1.Key != z2.Key {
+		return false
+	}
 
 	return true
 }
@@ -168,7 +178,20 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 
 	rpm, _ := parser.GetIntAnnotation("limit-rpm", ing)
 	rps, _ := parser.GetIntAnnotation("limit-rps", ing)
-	conn, _ := parser.GetIntAnnotation("limit-connections", ing)
+	conn, err := parser.GetIntAnnotation("limit-connections", ing)
+	if err != nil {
+		conn = defBackend.LimitConnections
+	}
+
+	connKey := ""
+	header, _ := parser.GetStringAnnotation("limit-connections-header", ing)
+	if header != "" {
+		if !headerRegex.MatchString(header) {
+			return nil, ing_errors.NewInvalidAnnotationContent("limit-connections-header", header)
+		}
+
+		connKey = fmt.Sprintf("$http_%v", strings.Replace(strings.ToLower(header), "-", "_", -1))
+	}
 
 	val, _ := parser.GetStringAnnotation("limit-whitelist", ing)
 
@@ -195,6 +218,7 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 			Limit:      conn,
 			Burst:      conn * defBurst,
 			SharedSize: defSharedSize,
+			Key:        connKey,
 		},
 		RPS: Zone{
 			Name:       fmt.Sprintf("%v_rps", zoneName),
@@ -77,6 +77,16 @@ func (m mockBackend) GetDefaultBackend() defaults.Backend {
 	}
 }
 
+type mockBackendWithConnections struct {
+	resolver.Mock
+}
+
+func (m mockBackendWithConnections) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		LimitConnections: 100,
+	}
+}
+
 func TestWithoutAnnotations(t *testing.T) {
 	ing := buildIngress()
 	_, err := NewParser(mockBackend{}).Parse(ing)
@@ -149,3 +159,53 @@ func TestRateLimiting(t *testing.T) {
 		t.Errorf("expected 10 in limit by limitrate but %v was returend", rateLimit.LimitRate)
 	}
 }
+
+func TestConnectionsLimit(t *testing.T) {
+	ing := buildIngress()
+
+	i, err := NewParser(mockBackendWithConnections{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	rateLimit := i.(*Config)
+	if rateLimit.Connections.Limit != 100 {
+		t.Errorf("expected the default limit of 100 connections but %v was returned", rateLimit.Connections.Limit)
+	}
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("limit-connections")] = "0"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(mockBackendWithConnections{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	rateLimit = i.(*Config)
+	if rateLimit.Connections.Limit != 0 {
+		t.Errorf("expected no connections limit but %v was returned", rateLimit.Connections.Limit)
+	}
+
+	data[parser.GetAnnotationWithPrefix("limit-connections")] = "5"
+	data[parser.GetAnnotationWithPrefix("limit-connections-header")] = "X-Client-ID"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(mockBackendWithConnections{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	rateLimit = i.(*Config)
+	if rateLimit.Connections.Limit != 5 {
+		t.Errorf("expected 5 connections but %v was returned", rateLimit.Connections.Limit)
+	}
+	if rateLimit.Connections.Key != "$http_x_client_id" {
+		t.Errorf("expected $http_x_client_id as key but %v was returned", rateLimit.Connections.Key)
+	}
+
+	data[parser.GetAnnotationWithPrefix("limit-connections-header")] = "X-Client-ID;"
+	ing.SetAnnotations(data)
+
+	_, err = NewParser(mockBackendWithConnections{}).Parse(ing)
+	if err == nil {
+		t.Errorf("expected an error with an invalid header name")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
@@ -30,6 +31,15 @@ import (
 
 const defaultPermanentRedirectCode = http.StatusMovedPermanently
 
+// validRedirectCodes contains the status codes used to redirect a request
+var validRedirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
 // Config returns the redirect configuration for an Ingress rule
 type Config struct {
 	URL       string `json:"url"`
@@ -75,12 +85,22 @@ func (r redirect) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return nil, err
 	}
 
+	if pr != "" {
+		if err := isValidURL(pr); err != nil {
+			return nil, err
+		}
+	}
+
 	prc, err := parser.GetIntAnnotation("permanent-redirect-code", ing)
 	if err != nil && !errors.IsMissingAnnotations(err) {
 		return nil, err
 	}
 
-	if prc < http.StatusMultipleChoices || prc > http.StatusPermanentRedirect {
+	if err == nil && !validRedirectCodes[prc] {
+		klog.Warningf("%v is not a valid redirect code in Ingress %v/%v. Using %v as default", prc, ing.Namespace, ing.Name, defaultPermanentRedirectCode)
+	}
+
+	if !validRedirectCodes[prc] {
 		prc = defaultPermanentRedirectCode
 	}
 
@@ -115,15 +135,25 @@ func (r1 *Config) Equal(r2 *Config) bool {
 	return true
 }
 
+// isValidURL checks the redirect is an absolute http or https URL
+// without characters that would alter the NGINX configuration
 func isValidURL(s string) error {
+	if strings.ContainsAny(s, " \t\n;{}\"'") {
+		return errors.Errorf("the URL contains invalid characters (%v)", s)
+	}
+
 	u, err := url.Parse(s)
 	if err != nil {
 		return err
 	}
 
-	if !strings.HasPrefix(u.Scheme, "http") {
+	if u.Scheme != "http" && u.Scheme != "https" {
 		return errors.Errorf("only http and https are valid protocols (%v)", u.Scheme)
 	}
 
+	if u.Host == "" {
+		return errors.Errorf("the URL must be absolute (%v)", s)
+	}
+
 	return nil
 }
@@ -155,3 +155,29 @@ func TestIsValidURL(t *testing.T) {
 		t.Errorf("expected nil but got %v", err)
 	}
 }
+
+func TestInvalidRedirectURL(t *testing.T) {
+	rp := NewParser(resolver.Mock{})
+
+	for _, annotation := range []string{"permanent-redirect", "temporal-redirect"} {
+		for _, u := range []string{
+			"/relative/path",
+			"ftp://example.com",
+			"http://",
+			"https://example.com; return 200",
+			"https://example.com/{path}",
+			"https://example.com/\"quoted\"",
+		} {
+			ing := new(extensions.Ingress)
+
+			data := make(map[string]string, 1)
+			data[parser.GetAnnotationWithPrefix(annotation)] = u
+			ing.SetAnnotations(data)
+
+			i, err := rp.Parse(ing)
+			if err == nil {
+				t.Errorf("Expected an error parsing %v %v but returned %v", annotation, u, i)
+			}
+		}
+	}
+}
@@ -17,9 +17,16 @@ limitations under the License.
 package rewrite
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -31,6 +38,12 @@ type Config struct {
 	SSLRedirect bool `json:"sslRedirect"`
 	// ForceSSLRedirect indicates if the location section is accessible SSL only
 	ForceSSLRedirect bool `json:"forceSSLRedirect"`
+	// SSLRedirectCode is the HTTP status code of the redirect to HTTPS.
+	// Zero means the global http-redirect-code is used
+	SSLRedirectCode int `json:"sslRedirectCode"`
+	// NoSSLRedirectPaths contains the paths of the Ingress rules excluded
+	// from the redirect to HTTPS
+	NoSSLRedirectPaths []string `json:"noSSLRedirectPaths,omitempty"`
 	// AppRoot defines the Application Root that the Controller must redirect if it's in '/' context
 	AppRoot string `json:"appRoot"`
 	// UseRegex indicates whether or not the locations use regex paths
@@ -54,6 +67,12 @@ func (r1 *Config) Equal(r2 *Config) bool {
 	if r1.ForceSSLRedirect != r2.ForceSSLRedirect {
 		return false
 	}
+	if r1.SSLRedirectCode != r2.SSLRedirectCode {
+		return false
+	}
+	if !sets.NewString(r1.NoSSLRedirectPaths...).Equal(sets.NewString(r2.NoSSLRedirectPaths...)) {
+		return false
+	}
 	if r1.AppRoot != r2.AppRoot {
 		return false
 	}
@@ -64,6 +83,49 @@ func (r1 *Config) Equal(r2 *Config) bool {
 	return true
 }
 
+// appRootRegex matches the absolute paths accepted as application root.
+// Whitespace, quotes, semicolons and braces would alter the NGINX configuration
+var appRootRegex = regexp.MustCompile(`^/[^\s;{}"'\\]*$`)
+
+// validRedirectCodes contains the HTTP status codes accepted in ssl-redirect-code
+var validRedirectCodes = sets.NewInt(301, 302, 307, 308)
+
+// captureGroupRegex matches the references to capture groups in the rewrite target
+var captureGroupRegex = regexp.MustCompile(`\$([1-9])`)
+
+// checkCaptureGroups returns an error if the rewrite target references a
+// capture group not defined in one of the paths of the Ingress rules
+func checkCaptureGroups(ing *extensions.Ingress, target string) error {
+	refs := captureGroupRegex.FindAllStringSubmatch(target, -1)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			re, err := regexp.Compile(path.Path)
+			if err != nil {
+				// NGINX uses PCRE, a superset of the syntax supported by Go
+				continue
+			}
+
+			for _, ref := range refs {
+				group, _ := strconv.Atoi(ref[1])
+				if group > re.NumSubexp() {
+					return ing_errors.NewInvalidAnnotationContent("rewrite-target",
+						fmt.Sprintf("%v references the capture group %v not defined in the path %v", target, ref[0], path.Path))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 type rewrite struct {
 	r resolver.Resolver
 }
@@ -90,8 +152,62 @@ func (a rewrite) Parse(ing *extensions.Ingress) (interface{}, error) {
 		config.ForceSSLRedirect = a.r.GetDefaultBackend().ForceSSLRedirect
 	}
 
-	config.AppRoot, _ = parser.GetStringAnnotation("app-root", ing)
+	var invalidErr error
+
+	config.SSLRedirectCode, err = parser.GetIntAnnotation("ssl-redirect-code", ing)
+	if ing_errors.IsInvalidContent(err) {
+		invalidErr = err
+	} else if err == nil && !validRedirectCodes.Has(config.SSLRedirectCode) {
+		invalidErr = ing_errors.NewInvalidAnnotationContent("ssl-redirect-code", config.SSLRedirectCode)
+		config.SSLRedirectCode = 0
+	}
+
+	noSSLRedirectPaths, err := parser.GetStringAnnotation("no-ssl-redirect-paths", ing)
+	if err == nil {
+		config.NoSSLRedirectPaths, err = parseNoSSLRedirectPaths(noSSLRedirectPaths)
+		if err != nil {
+			invalidErr = err
+		}
+	}
+
 	config.UseRegex, _ = parser.GetBoolAnnotation("use-regex", ing)
 
-	return config, nil
+	// the target is kept, the warning is reported as an event of the Ingress
+	if err := checkCaptureGroups(ing, config.Target); err != nil {
+		invalidErr = err
+	}
+
+	config.AppRoot, err = parser.GetStringAnnotation("app-root", ing)
+	if err == nil && !appRootRegex.MatchString(config.AppRoot) {
+		appRoot := config.AppRoot
+		config.AppRoot = ""
+		return config, ing_errors.NewInvalidAnnotationContent("app-root", appRoot)
+	}
+
+	return config, invalidErr
+}
+
+// parseNoSSLRedirectPaths returns the absolute paths contained in a comma
+// separated list, and an error naming the entries that are not
+func parseNoSSLRedirectPaths(value string) ([]string, error) {
+	var paths, invalid []string
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if !appRootRegex.MatchString(path) {
+			invalid = append(invalid, path)
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	if len(invalid) > 0 {
+		return paths, ing_errors.NewInvalidAnnotationContent("no-ssl-redirect-paths", strings.Join(invalid, ","))
+	}
+
+	return paths, nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package rewrite
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -26,6 +27,7 @@ import (
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -162,6 +164,64 @@ func TestForceSSLRedirect(t *testing.T) {
 		t.Errorf("Expected true but returned false")
 	}
 }
+
+func TestSSLRedirectCode(t *testing.T) {
+	ing := buildIngress()
+
+	testCases := []struct {
+		value     string
+		expected  int
+		expectErr bool
+	}{
+		{"301", 301, false},
+		{"308", 308, false},
+		{"200", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tc := range testCases {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("ssl-redirect-code")] = tc.value
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{redirect: true}).Parse(ing)
+		if tc.expectErr != errors.IsInvalidContent(err) {
+			t.Errorf("%v: expected invalid content error %v but returned %v", tc.value, tc.expectErr, err)
+		}
+
+		redirect, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a Redirect type")
+		}
+		if redirect.SSLRedirectCode != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", tc.value, tc.expected, redirect.SSLRedirectCode)
+		}
+	}
+}
+
+func TestNoSSLRedirectPaths(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("no-ssl-redirect-paths")] = "/healthz, /ping,,metrics,/a;b"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockBackend{redirect: true}).Parse(ing)
+	if !errors.IsInvalidContent(err) {
+		t.Errorf("expected an invalid content error but returned %v", err)
+	}
+
+	redirect, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Redirect type")
+	}
+
+	expected := []string{"/healthz", "/ping"}
+	if !reflect.DeepEqual(redirect.NoSSLRedirectPaths, expected) {
+		t.Errorf("expected %v but returned %v", expected, redirect.NoSSLRedirectPaths)
+	}
+}
+
 func TestAppRoot(t *testing.T) {
 	ing := buildIngress()
 
@@ -179,6 +239,29 @@ func TestAppRoot(t *testing.T) {
 	}
 }
 
+func TestInvalidAppRoot(t *testing.T) {
+	ing := buildIngress()
+
+	for _, appRoot := range []string{"app1", "/app1; return 200", "/app 1", "/app1{"} {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("app-root")] = appRoot
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{redirect: true}).Parse(ing)
+		if !errors.IsInvalidContent(err) {
+			t.Errorf("expected an invalid content error for %q but returned %v", appRoot, err)
+		}
+
+		redirect, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a App Context")
+		}
+		if redirect.AppRoot != "" {
+			t.Errorf("expected an empty AppRoot for %q but returned %v", appRoot, redirect.AppRoot)
+		}
+	}
+}
+
 func TestUseRegex(t *testing.T) {
 	ing := buildIngress()
 
@@ -195,3 +278,31 @@ func TestUseRegex(t *testing.T) {
 		t.Errorf("Unexpected value got in UseRegex")
 	}
 }
+
+func TestCaptureGroups(t *testing.T) {
+	ing := buildIngress()
+	ing.Spec.Rules[0].HTTP.Paths[0].Path = "/foo/(.*)"
+
+	testCases := map[string]bool{
+		"/$1":    true,
+		"/$1/$2": false,
+		"/bar":   true,
+	}
+
+	for target, valid := range testCases {
+		ing.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("rewrite-target"): target,
+		})
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if valid && err != nil {
+			t.Errorf("expected the target %v to be valid but returned %v", target, err)
+		}
+		if !valid && !errors.IsInvalidContent(err) {
+			t.Errorf("expected an invalid content error for the target %v but returned %v", target, err)
+		}
+		if redirect := i.(*Config); redirect.Target != target {
+			t.Errorf("expected the target %v to be kept but returned %v", target, redirect.Target)
+		}
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package satisfy
 
 import (
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -32,13 +35,18 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return satisfy{r}
 }
 
-// Parse parses annotation contained in the ingress
+// Parse parses annotation contained in the ingress.
+// Valid values are "any" and "all", matching the NGINX satisfy directive.
 func (s satisfy) Parse(ing *extensions.Ingress) (interface{}, error) {
 	satisfy, err := parser.GetStringAnnotation("satisfy", ing)
+	if err != nil {
+		return "", nil
+	}
 
-	if err != nil || (satisfy != "any" && satisfy != "all") {
-		satisfy = ""
+	value := strings.ToLower(strings.TrimSpace(satisfy))
+	if value != "any" && value != "all" {
+		return "", ing_errors.NewInvalidAnnotationContent("satisfy", satisfy)
 	}
 
-	return satisfy, nil
+	return value, nil
 }
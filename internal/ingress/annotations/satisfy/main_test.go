@@ -66,22 +66,28 @@ func buildIngress() *extensions.Ingress {
 func TestSatisfyParser(t *testing.T) {
 	ing := buildIngress()
 
-	data := map[string]string{
-		"any":     "any",
-		"all":     "all",
-		"invalid": "",
-		"":        "",
+	data := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{"any", "any", false},
+		{"all", "all", false},
+		{" ANY ", "any", false},
+		{"invalid", "", true},
+		{"", "", false},
 	}
 
 	annotations := map[string]string{}
 
-	for input, expected := range data {
-		annotations[parser.GetAnnotationWithPrefix("satisfy")] = input
+	for _, tc := range data {
+		expected := tc.expected
+		annotations[parser.GetAnnotationWithPrefix("satisfy")] = tc.input
 		ing.SetAnnotations(annotations)
 
 		satisfyt, err := NewParser(&resolver.Mock{}).Parse(ing)
-		if err != nil {
-			t.Errorf("error parsing annotations: %v", err)
+		if tc.expectErr != (err != nil) {
+			t.Errorf("%q: expected error %v but returned %v", tc.input, tc.expectErr, err)
 		}
 
 		val, ok := satisfyt.(string)
@@ -32,6 +32,13 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return serviceUpstream{r}
 }
 
+// Parse parses the annotations contained in the ingress to use
+// the Service ClusterIP as the upstream instead of the Endpoints
 func (s serviceUpstream) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetBoolAnnotation("service-upstream", ing)
+	val, err := parser.GetBoolAnnotation("service-upstream", ing)
+	if err != nil {
+		return s.r.GetDefaultBackend().ServiceUpstream, nil
+	}
+
+	return val, nil
 }
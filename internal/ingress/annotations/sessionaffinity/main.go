@@ -49,11 +49,25 @@ const (
 
 	// This is used to control the cookie path when use-regex is set to true
 	annotationAffinityCookiePath = "session-cookie-path"
+
+	// This is used to set the Secure attribute of the cookie even for plain HTTP requests
+	annotationAffinityCookieSecure = "session-cookie-secure"
+
+	// This is used to control the SameSite attribute of the cookie
+	annotationAffinityCookieSameSite = "session-cookie-samesite"
+
+	// This is used to control the domain of the cookie
+	annotationAffinityCookieDomain = "session-cookie-domain"
+
+	// This is used to send the session to a new endpoint when the endpoint of the cookie fails
+	annotationAffinityCookieChangeOnFailure = "session-cookie-change-on-failure"
 )
 
 var (
-	affinityCookieHashRegex    = regexp.MustCompile(`^(index|md5|sha1)$`)
-	affinityCookieExpiresRegex = regexp.MustCompile(`(^0|-?[1-9]\d*$)`)
+	affinityCookieHashRegex     = regexp.MustCompile(`^(index|md5|sha1)$`)
+	affinityCookieExpiresRegex  = regexp.MustCompile(`(^0|-?[1-9]\d*$)`)
+	affinityCookieSameSiteRegex = regexp.MustCompile(`^(None|Lax|Strict)$`)
+	affinityCookieDomainRegex   = regexp.MustCompile(`^\.?[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
 )
 
 // Config describes the per ingress session affinity config
@@ -75,6 +89,14 @@ type Cookie struct {
 	MaxAge string `json:"maxage"`
 	// The path that a cookie will be set on
 	Path string `json:"path"`
+	// Secure sets the Secure attribute of the cookie even for plain HTTP requests
+	Secure bool `json:"secure"`
+	// SameSite is the value of the SameSite attribute of the cookie
+	SameSite string `json:"samesite"`
+	// Domain is the domain that a cookie will be set on
+	Domain string `json:"domain"`
+	// ChangeOnFailure moves the session to a new endpoint when the endpoint of the cookie fails
+	ChangeOnFailure bool `json:"changeonfailure"`
 }
 
 // cookieAffinityParse gets the annotation values related to Cookie Affinity
@@ -113,6 +135,28 @@ func (a affinity) cookieAffinityParse(ing *extensions.Ingress) *Cookie {
 		klog.V(3).Infof("Invalid or no annotation value found in Ingress %v: %v. Ignoring it", ing.Name, annotationAffinityCookieMaxAge)
 	}
 
+	cookie.Secure, err = parser.GetBoolAnnotation(annotationAffinityCookieSecure, ing)
+	if err != nil {
+		cookie.Secure = false
+	}
+
+	cookie.SameSite, err = parser.GetStringAnnotation(annotationAffinityCookieSameSite, ing)
+	if err != nil || !affinityCookieSameSiteRegex.MatchString(cookie.SameSite) {
+		klog.V(3).Infof("Invalid or no annotation value found in Ingress %v: %v. Ignoring it", ing.Name, annotationAffinityCookieSameSite)
+		cookie.SameSite = ""
+	}
+
+	cookie.Domain, err = parser.GetStringAnnotation(annotationAffinityCookieDomain, ing)
+	if err != nil || !affinityCookieDomainRegex.MatchString(cookie.Domain) {
+		klog.V(3).Infof("Invalid or no annotation value found in Ingress %v: %v. Ignoring it", ing.Name, annotationAffinityCookieDomain)
+		cookie.Domain = ""
+	}
+
+	cookie.ChangeOnFailure, err = parser.GetBoolAnnotation(annotationAffinityCookieChangeOnFailure, ing)
+	if err != nil {
+		cookie.ChangeOnFailure = false
+	}
+
 	return cookie
 }
 
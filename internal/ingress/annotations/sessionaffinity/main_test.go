@@ -72,6 +72,10 @@ func TestIngressAffinityCookieConfig(t *testing.T) {
 	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieExpires)] = "4500"
 	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieMaxAge)] = "3000"
 	data[parser.GetAnnotationWithPrefix(annotationAffinityCookiePath)] = "/foo"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieSecure)] = "true"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieSameSite)] = "Strict"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieDomain)] = ".foo.bar.com"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieChangeOnFailure)] = "true"
 	ing.SetAnnotations(data)
 
 	affin, _ := NewParser(&resolver.Mock{}).Parse(ing)
@@ -103,4 +107,48 @@ func TestIngressAffinityCookieConfig(t *testing.T) {
 	if nginxAffinity.Cookie.Path != "/foo" {
 		t.Errorf("expected /foo as session-cookie-path but returned %v", nginxAffinity.Cookie.Path)
 	}
+
+	if !nginxAffinity.Cookie.Secure {
+		t.Errorf("expected true as session-cookie-secure but returned %v", nginxAffinity.Cookie.Secure)
+	}
+
+	if nginxAffinity.Cookie.SameSite != "Strict" {
+		t.Errorf("expected Strict as session-cookie-samesite but returned %v", nginxAffinity.Cookie.SameSite)
+	}
+
+	if nginxAffinity.Cookie.Domain != ".foo.bar.com" {
+		t.Errorf("expected .foo.bar.com as session-cookie-domain but returned %v", nginxAffinity.Cookie.Domain)
+	}
+
+	if !nginxAffinity.Cookie.ChangeOnFailure {
+		t.Errorf("expected true as session-cookie-change-on-failure but returned %v", nginxAffinity.Cookie.ChangeOnFailure)
+	}
+}
+
+func TestIngressAffinityCookieInvalidAttributes(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix(annotationAffinityType)] = "cookie"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieSameSite)] = "strict; HttpOnly"
+	data[parser.GetAnnotationWithPrefix(annotationAffinityCookieDomain)] = "foo.bar.com; Secure"
+	ing.SetAnnotations(data)
+
+	affin, _ := NewParser(&resolver.Mock{}).Parse(ing)
+	nginxAffinity, ok := affin.(*Config)
+	if !ok {
+		t.Errorf("expected a Config type")
+	}
+
+	if nginxAffinity.Cookie.SameSite != "" {
+		t.Errorf("expected an empty session-cookie-samesite but returned %v", nginxAffinity.Cookie.SameSite)
+	}
+
+	if nginxAffinity.Cookie.Domain != "" {
+		t.Errorf("expected an empty session-cookie-domain but returned %v", nginxAffinity.Cookie.Domain)
+	}
+
+	if nginxAffinity.Cookie.Secure || nginxAffinity.Cookie.ChangeOnFailure {
+		t.Errorf("expected session-cookie-secure and session-cookie-change-on-failure to be disabled by default")
+	}
 }
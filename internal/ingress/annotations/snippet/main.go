@@ -17,12 +17,27 @@ limitations under the License.
 package snippet
 
 import (
+	"fmt"
+	"path"
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+var (
+	// Disabled ignores the annotations containing configuration snippets
+	Disabled = false
+
+	// BlockedDirectives contains the NGINX directives not allowed in the
+	// snippets. Patterns like *_by_lua* match groups of directives
+	BlockedDirectives = []string{}
+)
+
 type snippet struct {
 	r resolver.Resolver
 }
@@ -36,5 +51,130 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // used to indicate if the location/s contains a fragment of
 // configuration to be included inside the paths of the rules
 func (a snippet) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetStringAnnotation("configuration-snippet", ing)
+	return Read("configuration-snippet", ing)
+}
+
+// Read returns the configuration snippet contained in an annotation.
+// The snippet is ignored if snippets are disabled or if it contains
+// a blocked directive
+func Read(name string, ing *extensions.Ingress) (string, error) {
+	value, err := parser.GetStringAnnotation(name, ing)
+	if err != nil {
+		return value, err
+	}
+
+	if Disabled {
+		klog.Warningf("Ignoring annotation %v of Ingress %v/%v: snippet annotations are disabled", name, ing.Namespace, ing.Name)
+		return "", ing_errors.NewInvalidAnnotationContent(name, "snippet annotations are disabled")
+	}
+
+	if directive := blockedDirective(value); directive != "" {
+		klog.Warningf("Ignoring annotation %v of Ingress %v/%v: the directive %v is not allowed", name, ing.Namespace, ing.Name, directive)
+		return "", ing_errors.NewInvalidAnnotationContent(name, fmt.Sprintf("the directive %v is not allowed", directive))
+	}
+
+	return value, nil
+}
+
+// Validate checks a configuration snippet not defined in an annotation,
+// like the snippets of the StreamService ports, is allowed
+func Validate(value string) error {
+	if Disabled {
+		return fmt.Errorf("snippets are disabled")
+	}
+
+	if directive := blockedDirective(value); directive != "" {
+		return fmt.Errorf("the directive %v is not allowed", directive)
+	}
+
+	return nil
+}
+
+// blockedDirective returns the first directive of the snippet
+// matching a pattern in BlockedDirectives
+func blockedDirective(value string) string {
+	if len(BlockedDirectives) == 0 {
+		return ""
+	}
+
+	for _, directive := range directives(value) {
+		for _, pattern := range BlockedDirectives {
+			if matched, _ := path.Match(pattern, directive); matched {
+				return directive
+			}
+		}
+	}
+
+	return ""
+}
+
+// directives returns the names of the directives of a configuration
+// snippet, which are the first words of each statement and block.
+// Names can be quoted and contain escaped characters like in NGINX.
+// As in ngx_conf_read_token, quotes and comments are only special at
+// the beginning of a word, so a quote inside a word does not hide the
+// statements that follow it
+func directives(value string) []string {
+	names := []string{}
+
+	var name strings.Builder
+	var quote rune
+	inName := true
+	inComment := false
+	escaped := false
+	// quotes and comments start at the beginning of a word
+	wordStart := true
+
+	endName := func() {
+		if name.Len() > 0 {
+			names = append(names, name.String())
+			name.Reset()
+		}
+	}
+
+	for _, c := range value {
+		isWordStart := wordStart
+		wordStart = false
+
+		switch {
+		case inComment:
+			inComment = c != '\n'
+			wordStart = !inComment
+		case escaped:
+			escaped = false
+			if inName {
+				name.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if inName {
+				name.WriteRune(c)
+			}
+		case (c == '"' || c == '\'') && isWordStart:
+			quote = c
+		case c == '#' && isWordStart:
+			inComment = true
+		case c == ';' || c == '{' || c == '}':
+			endName()
+			inName = true
+			wordStart = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if name.Len() > 0 {
+				endName()
+				inName = false
+			}
+			wordStart = true
+		default:
+			if inName {
+				name.WriteRune(c)
+			}
+		}
+	}
+
+	endName()
+
+	return names
 }
@@ -17,12 +17,14 @@ limitations under the License.
 package snippet
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -60,3 +62,100 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestDirectives(t *testing.T) {
+	testCases := map[string][]string{
+		`more_set_headers "Request-Id: $req_id";`:                    {"more_set_headers"},
+		"# content_by_lua_block\nproxy_set_header X-A 'b;c';":        {"proxy_set_header"},
+		`set $a a#b; content_by_lua_block { ngx.say("ok") }`:         {"set", "content_by_lua_block", `ngx.say("ok")`},
+		"set $a b\"c;\ncontent_by_lua_block { ngx.say(1) }\n#\";":    {"set", "content_by_lua_block", "ngx.say(1)"},
+		`set $a b'c; access_by_lua_block {} '`:                       {"set", "access_by_lua_block"},
+		`location /a { "content_by_lua_block" {} }`:                  {"location", "content_by_lua_block"},
+		`if ($uri = /x) { return 403; } content_by\_lua_block {}`:    {"if", "return", "content_by_lua_block"},
+		"proxy_pass http://upstream;\n\tload_module /tmp/module.so;": {"proxy_pass", "load_module"},
+		`more_set_headers "X-Snippet: include /etc/passwd;";`:        {"more_set_headers"},
+		`"access_by_lua" 'ngx.exit(403)';`:                           {"access_by_lua"},
+	}
+
+	for snippet, expected := range testCases {
+		actual := directives(snippet)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %v but returned %v for snippet %v", expected, actual, snippet)
+		}
+	}
+}
+
+func TestBlockedDirectives(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("configuration-snippet")
+
+	defer func() {
+		Disabled = false
+		BlockedDirectives = []string{}
+	}()
+	BlockedDirectives = []string{"*_by_lua*", "load_module"}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	testCases := []struct {
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{`more_set_headers "Request-Id: $req_id";`, `more_set_headers "Request-Id: $req_id";`, false},
+		{`content_by_lua_block { ngx.say("ok") }`, "", true},
+		{`"access_by_lua_file" /tmp/script.lua;`, "", true},
+		{"load_module /tmp/module.so;", "", true},
+		{"set $a b\"c;\ncontent_by_lua_block { ngx.say(1) }\n#\";", "", true},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(map[string]string{annotation: testCase.value})
+		result, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if testCase.expectErr != errors.IsInvalidContent(err) {
+			t.Errorf("expected error %v but returned %v for snippet %v", testCase.expectErr, err, testCase.value)
+		}
+		if result != testCase.expected {
+			t.Errorf("expected %v but returned %v for snippet %v", testCase.expected, result, testCase.value)
+		}
+	}
+
+	Disabled = true
+	ing.SetAnnotations(map[string]string{annotation: `more_set_headers "Request-Id: $req_id";`})
+	result, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if !errors.IsInvalidContent(err) {
+		t.Errorf("expected an invalid content error but returned %v", err)
+	}
+	if result != "" {
+		t.Errorf("expected an empty snippet but returned %v", result)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defer func() {
+		Disabled = false
+		BlockedDirectives = []string{}
+	}()
+
+	if err := Validate("limit_conn tcp_conn 10;"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	BlockedDirectives = []string{"*_by_lua*"}
+	if err := Validate("preread_by_lua_block { ngx.exit(403) }"); err == nil {
+		t.Errorf("expected an error validating a snippet with a blocked directive")
+	}
+	if err := Validate("set $a b\"c;\npreread_by_lua_block { ngx.exit(403) }\n#\";"); err == nil {
+		t.Errorf("expected an error validating a snippet with a blocked directive after a quote inside a word")
+	}
+
+	Disabled = true
+	if err := Validate("limit_conn tcp_conn 10;"); err == nil {
+		t.Errorf("expected an error validating a snippet when snippets are disabled")
+	}
+}
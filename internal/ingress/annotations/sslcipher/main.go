@@ -17,12 +17,19 @@ limitations under the License.
 package sslcipher
 
 import (
+	"regexp"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// list of ciphers in the OpenSSL format
+// https://www.openssl.org/docs/man1.1.1/man1/ciphers.html
+var cipherListRegex = regexp.MustCompile(`^[A-Za-z0-9!+@=._:,\- ]+$`)
+
 type sslCipher struct {
 	r resolver.Resolver
 }
@@ -35,5 +42,14 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // Parse parses the annotations contained in the ingress rule
 // used to add ssl-ciphers to the server name
 func (sc sslCipher) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetStringAnnotation("ssl-ciphers", ing)
+	val, err := parser.GetStringAnnotation("ssl-ciphers", ing)
+	if err != nil {
+		return "", err
+	}
+
+	if !cipherListRegex.MatchString(val) {
+		return "", ing_errors.NewInvalidAnnotationContent("ssl-ciphers", val)
+	}
+
+	return val, nil
 }
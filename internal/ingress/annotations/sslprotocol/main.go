@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sslprotocol
+
+import (
+	"fmt"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// protocols supported by the ssl_protocols directive
+// https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_protocols
+var validProtocols = map[string]bool{
+	"SSLv2":   true,
+	"SSLv3":   true,
+	"TLSv1":   true,
+	"TLSv1.1": true,
+	"TLSv1.2": true,
+	"TLSv1.3": true,
+}
+
+type sslProtocol struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new sslProtocol annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return sslProtocol{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure the SSL protocols enabled in the server name
+func (sp sslProtocol) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetStringAnnotation("ssl-protocols", ing)
+	if err != nil {
+		return "", err
+	}
+
+	protocols := strings.Fields(val)
+	if len(protocols) == 0 {
+		return "", ing_errors.NewInvalidAnnotationContent("ssl-protocols", val)
+	}
+
+	for _, protocol := range protocols {
+		if !validProtocols[protocol] {
+			return "", ing_errors.NewInvalidAnnotationConfiguration("ssl-protocols",
+				fmt.Sprintf("unsupported protocol %v", protocol))
+		}
+	}
+
+	return strings.Join(protocols, " "), nil
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamsnippet
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type streamSnippet struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new stream snippet annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return streamSnippet{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to indicate if the TCP stream service of the Ingress contains
+// a fragment of configuration to be included inside its server
+func (a streamSnippet) Parse(ing *extensions.Ingress) (interface{}, error) {
+	return snippet.Read("stream-snippet", ing)
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tcpport
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type tcpPort struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new TCP port annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return tcpPort{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to expose the backend as a raw TCP stream service in
+// the port indicated in the annotation instead of a HTTP server
+func (a tcpPort) Parse(ing *extensions.Ingress) (interface{}, error) {
+	port, err := parser.GetIntAnnotation("tcp-port", ing)
+	if err != nil {
+		return 0, err
+	}
+
+	if port < 1 || port > 65535 {
+		return 0, ing_errors.NewInvalidAnnotationContent("tcp-port", port)
+	}
+
+	return port, nil
+}
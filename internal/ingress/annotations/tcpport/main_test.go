@@ -0,0 +1,68 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tcpport
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("tcp-port")
+	ap := NewParser(&resolver.Mock{})
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    int
+		expectErr   bool
+	}{
+		{map[string]string{annotation: "5432"}, 5432, false},
+		{map[string]string{annotation: "1883"}, 1883, false},
+		{map[string]string{annotation: "0"}, 0, true},
+		{map[string]string{annotation: "70000"}, 0, true},
+		{map[string]string{annotation: "mqtt"}, 0, true},
+		{map[string]string{}, 0, true},
+		{nil, 0, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := ap.Parse(ing)
+		if testCase.expectErr && err == nil {
+			t.Errorf("expected an error with annotations %v", testCase.annotations)
+		}
+		if !testCase.expectErr && err != nil {
+			t.Errorf("unexpected error with annotations %v: %v", testCase.annotations, err)
+		}
+		if result != testCase.expected {
+			t.Errorf("expected %v but returned %v, annotations: %v", testCase.expected, result, testCase.annotations)
+		}
+	}
+}
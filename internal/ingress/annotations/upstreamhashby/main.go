@@ -45,7 +45,7 @@ func (a upstreamhashby) Parse(ing *extensions.Ingress) (interface{}, error) {
 	upstreamHashBySubset, _ := parser.GetBoolAnnotation("upstream-hash-by-subset", ing)
 	upstreamHashbySubsetSize, _ := parser.GetIntAnnotation("upstream-hash-by-subset-size", ing)
 
-	if upstreamHashbySubsetSize == 0 {
+	if upstreamHashbySubsetSize <= 0 {
 		upstreamHashbySubsetSize = 3
 	}
 
@@ -65,3 +65,40 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSubsetSize(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("upstream-hash-by-subset-size")
+	ap := NewParser(&resolver.Mock{})
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    int
+	}{
+		{map[string]string{annotation: "5"}, 5},
+		{map[string]string{annotation: "0"}, 3},
+		{map[string]string{annotation: "-2"}, 3},
+		{map[string]string{annotation: "invalid"}, 3},
+		{map[string]string{}, 3},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, _ := ap.Parse(ing)
+		uc, ok := result.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type")
+		}
+
+		if uc.UpstreamHashBySubsetSize != testCase.expected {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, uc.UpstreamHashBySubsetSize, testCase.annotations)
+		}
+	}
+}
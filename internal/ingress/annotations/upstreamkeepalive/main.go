@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upstreamkeepalive
+
+import (
+	"fmt"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	keepaliveConnections = "upstream-keepalive-connections"
+	keepaliveTimeout     = "upstream-keepalive-timeout"
+	keepaliveRequests    = "upstream-keepalive-requests"
+)
+
+// Config describes the cache of keepalive connections to the upstream
+// servers used by the locations of an Ingress
+type Config struct {
+	// Override indicates the configuration differs from the one defined
+	// in the configuration ConfigMap and requires a dedicated upstream
+	Override bool `json:"override"`
+	// Connections is the maximum number of idle keepalive connections
+	// preserved in the cache of each worker process. Zero disables it
+	Connections int `json:"connections"`
+	// Timeout is the time, in seconds, an idle connection stays open
+	Timeout int `json:"timeout"`
+	// Requests is the maximum number of requests sent through one connection
+	Requests int `json:"requests"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Override != c2.Override {
+		return false
+	}
+	if c1.Connections != c2.Connections {
+		return false
+	}
+	if c1.Timeout != c2.Timeout {
+		return false
+	}
+	if c1.Requests != c2.Requests {
+		return false
+	}
+
+	return true
+}
+
+// UpstreamName returns the name of the upstream block used to proxy
+// the requests of the locations with this configuration
+func (c Config) UpstreamName() string {
+	if !c.Override {
+		return "upstream_balancer"
+	}
+
+	return fmt.Sprintf("upstream_balancer_keepalive_%v_%v_%v", c.Connections, c.Timeout, c.Requests)
+}
+
+type upstreamKeepalive struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new upstream keepalive annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return upstreamKeepalive{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// configure the keepalive connections to the upstream servers. Missing
+// or invalid values are replaced with the ones of the configuration
+// ConfigMap and the invalid ones are reported in the returned error
+func (a upstreamKeepalive) Parse(ing *extensions.Ingress) (interface{}, error) {
+	defBackend := a.r.GetDefaultBackend()
+
+	var invalidErr error
+
+	parse := func(name string, def, min int) int {
+		val, err := parser.GetIntAnnotation(name, ing)
+		if err != nil {
+			if !ing_errors.IsMissingAnnotations(err) && invalidErr == nil {
+				invalidErr = err
+			}
+			return def
+		}
+
+		if val < min {
+			if invalidErr == nil {
+				invalidErr = ing_errors.NewInvalidAnnotationContent(name, val)
+			}
+			return def
+		}
+
+		return val
+	}
+
+	config := &Config{
+		Connections: parse(keepaliveConnections, defBackend.UpstreamKeepaliveConnections, 0),
+		Timeout:     parse(keepaliveTimeout, defBackend.UpstreamKeepaliveTimeout, 1),
+		Requests:    parse(keepaliveRequests, defBackend.UpstreamKeepaliveRequests, 1),
+	}
+
+	config.Override = config.Connections != defBackend.UpstreamKeepaliveConnections ||
+		config.Timeout != defBackend.UpstreamKeepaliveTimeout ||
+		config.Requests != defBackend.UpstreamKeepaliveRequests
+
+	return config, invalidErr
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upstreamkeepalive
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		UpstreamKeepaliveConnections: 32,
+		UpstreamKeepaliveTimeout:     60,
+		UpstreamKeepaliveRequests:    100,
+	}
+}
+
+func TestParse(t *testing.T) {
+	connections := parser.GetAnnotationWithPrefix(keepaliveConnections)
+	timeout := parser.GetAnnotationWithPrefix(keepaliveTimeout)
+	requests := parser.GetAnnotationWithPrefix(keepaliveRequests)
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    Config
+		expectErr   bool
+	}{
+		{nil, Config{false, 32, 60, 100}, false},
+		{map[string]string{connections: "32", timeout: "60"}, Config{false, 32, 60, 100}, false},
+		{map[string]string{connections: "8"}, Config{true, 8, 60, 100}, false},
+		{map[string]string{connections: "0"}, Config{true, 0, 60, 100}, false},
+		{map[string]string{timeout: "30", requests: "1000"}, Config{true, 32, 30, 1000}, false},
+		{map[string]string{connections: "-1"}, Config{false, 32, 60, 100}, true},
+		{map[string]string{timeout: "0", requests: "10"}, Config{true, 32, 60, 10}, true},
+		{map[string]string{requests: "many"}, Config{false, 32, 60, 100}, true},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, tc := range testCases {
+		ing.SetAnnotations(tc.annotations)
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if tc.expectErr && err == nil {
+			t.Errorf("expected an error parsing %v", tc.annotations)
+		}
+		if !tc.expectErr && err != nil {
+			t.Errorf("unexpected error parsing %v: %v", tc.annotations, err)
+		}
+
+		cfg, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type but %T was returned", i)
+		}
+		if !cfg.Equal(&tc.expected) {
+			t.Errorf("expected %+v but returned %+v, annotations: %v", tc.expected, *cfg, tc.annotations)
+		}
+	}
+}
+
+func TestUpstreamName(t *testing.T) {
+	if name := (Config{Connections: 32, Timeout: 60, Requests: 100}).UpstreamName(); name != "upstream_balancer" {
+		t.Errorf("expected upstream_balancer but returned %v", name)
+	}
+
+	if name := (Config{Override: true, Connections: 8, Timeout: 30, Requests: 1000}).UpstreamName(); name != "upstream_balancer_keepalive_8_30_1000" {
+		t.Errorf("expected upstream_balancer_keepalive_8_30_1000 but returned %v", name)
+	}
+}
@@ -17,12 +17,20 @@ limitations under the License.
 package upstreamvhost
 
 import (
+	"regexp"
+
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// vhostRegex matches host names and IP addresses with an optional port.
+// NGINX variables like $namespace can be part of the host name
+var vhostRegex = regexp.MustCompile(`^(([A-Za-z0-9\-._]|\$[A-Za-z_]+|\$\{[A-Za-z_]+\})+|\[[0-9A-Fa-f:.]+\])(:[0-9]{1,5})?$`)
+
 type upstreamVhost struct {
 	r resolver.Resolver
 }
@@ -33,8 +41,17 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 }
 
 // Parse parses the annotations contained in the ingress rule
-// used to indicate if the location/s contains a fragment of
-// configuration to be included inside the paths of the rules
+// used to override the Host header sent to the upstream servers
 func (a upstreamVhost) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetStringAnnotation("upstream-vhost", ing)
+	vhost, err := parser.GetStringAnnotation("upstream-vhost", ing)
+	if err != nil {
+		return vhost, err
+	}
+
+	if !vhostRegex.MatchString(vhost) {
+		klog.Warningf("Ignoring invalid upstream-vhost %q in Ingress %v/%v", vhost, ing.Namespace, ing.Name)
+		return "", ing_errors.NewInvalidAnnotationContent("upstream-vhost", vhost)
+	}
+
+	return vhost, nil
 }
@@ -23,6 +23,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -53,3 +54,37 @@ func TestParse(t *testing.T) {
 		t.Errorf("expected %v but got %v", "ok.com", vhost)
 	}
 }
+
+func TestParseInvalidVhost(t *testing.T) {
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	testCases := map[string]bool{
+		"internal.example.local":                   true,
+		"internal.example.local:8080":              true,
+		"10.0.0.1":                                 true,
+		"[fd00::1]:8080":                           true,
+		"$service_name.$namespace.svc":             true,
+		"ok.com\"; proxy_pass http://evil.com; \"": false,
+		"ok.com/path":                              false,
+		"ok.com:port":                              false,
+		"ok .com":                                  false,
+	}
+
+	for vhost, valid := range testCases {
+		ing.SetAnnotations(map[string]string{parser.GetAnnotationWithPrefix("upstream-vhost"): vhost})
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if valid && (err != nil || i != vhost) {
+			t.Errorf("expected %v to be valid but returned %v (%v)", vhost, i, err)
+		}
+		if !valid && (!errors.IsInvalidContent(err) || i != "") {
+			t.Errorf("expected %v to be invalid but returned %v (%v)", vhost, i, err)
+		}
+	}
+}
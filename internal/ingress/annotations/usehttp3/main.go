@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usehttp3
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type useHTTP3 struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new use-http3 annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return useHTTP3{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to enable or disable the advertisement of HTTP/3 in the server
+func (u useHTTP3) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetBoolAnnotation("use-http3", ing)
+	if err != nil {
+		return u.r.GetDefaultBackend().UseHTTP3, nil
+	}
+
+	return val, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// Config returns the WebSocket configuration for an Ingress rule
+type Config struct {
+	// Upgrade forces the Upgrade and Connection headers of WebSocket
+	// handshakes to be sent to the upstream, even when the Connection
+	// header is overridden with the connection-proxy-header annotation
+	Upgrade bool `json:"upgrade"`
+	// ReadTimeout and SendTimeout (in seconds) replace the proxy timeouts
+	// of the location for upgraded connections. Zero keeps the proxy timeouts
+	ReadTimeout int `json:"readTimeout"`
+	SendTimeout int `json:"sendTimeout"`
+}
+
+type websocket struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new WebSocket annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return websocket{r}
+}
+
+// Parse parses the annotations contained in the ingress
+// rule used to configure the proxy of WebSocket connections
+func (a websocket) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	upgrade, err := parser.GetBoolAnnotation("websocket-upgrade", ing)
+	if err == nil {
+		config.Upgrade = upgrade
+	}
+
+	rt, err := parser.GetIntAnnotation("websocket-read-timeout", ing)
+	if err == nil {
+		if rt <= 0 {
+			return &Config{}, ing_errors.NewInvalidAnnotationContent("websocket-read-timeout", rt)
+		}
+		config.ReadTimeout = rt
+	}
+
+	st, err := parser.GetIntAnnotation("websocket-send-timeout", ing)
+	if err == nil {
+		if st <= 0 {
+			return &Config{}, ing_errors.NewInvalidAnnotationContent("websocket-send-timeout", st)
+		}
+		config.SendTimeout = st
+	}
+
+	return config, nil
+}
+
+// HasTimeouts returns true if the proxy timeouts of upgraded connections are overridden
+func (c *Config) HasTimeouts() bool {
+	return c.ReadTimeout > 0 || c.SendTimeout > 0
+}
+
+// Equal tests for equality between two WebSocket Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Upgrade != c2.Upgrade {
+		return false
+	}
+	if c1.ReadTimeout != c2.ReadTimeout {
+		return false
+	}
+	if c1.SendTimeout != c2.SendTimeout {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	upgrade := parser.GetAnnotationWithPrefix("websocket-upgrade")
+	readTimeout := parser.GetAnnotationWithPrefix("websocket-read-timeout")
+	sendTimeout := parser.GetAnnotationWithPrefix("websocket-send-timeout")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+		expectErr   bool
+	}{
+		{nil, &Config{}, false},
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{upgrade: "true"}, &Config{Upgrade: true}, false},
+		{map[string]string{readTimeout: "3600", sendTimeout: "600"}, &Config{ReadTimeout: 3600, SendTimeout: 600}, false},
+		{map[string]string{upgrade: "true", readTimeout: "3600"}, &Config{Upgrade: true, ReadTimeout: 3600}, false},
+		{map[string]string{readTimeout: "0"}, &Config{}, true},
+		{map[string]string{sendTimeout: "-1"}, &Config{}, true},
+		{map[string]string{readTimeout: "one hour"}, &Config{}, false},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expectErr != (err != nil) {
+			t.Errorf("expected error %v but returned %v, annotations: %s", testCase.expectErr, err, testCase.annotations)
+		}
+
+		p, _ := i.(*Config)
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
@@ -17,12 +17,41 @@ limitations under the License.
 package xforwardedprefix
 
 import (
+	"regexp"
+	"strconv"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+var validPrefix = regexp.MustCompile(`^/[A-Za-z0-9\-._~/%]*$`)
+
+// Config contains the X-Forwarded-Prefix header sent to the upstream servers
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Prefix is the value of the header. The prefix is derived from
+	// the path of the location when it is empty
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+
+	return c1.Prefix == c2.Prefix
+}
+
 type xforwardedprefix struct {
 	r resolver.Resolver
 }
@@ -33,7 +62,21 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 }
 
 // Parse parses the annotations contained in the ingress rule
-// used to add an x-forwarded-prefix header to the request
+// used to add an x-forwarded-prefix header to the request.
+// The annotation contains a boolean or the value of the header
 func (cbbs xforwardedprefix) Parse(ing *extensions.Ingress) (interface{}, error) {
-	return parser.GetBoolAnnotation("x-forwarded-prefix", ing)
+	value, err := parser.GetStringAnnotation("x-forwarded-prefix", ing)
+	if err != nil || value == "" {
+		return &Config{}, nil
+	}
+
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		return &Config{Enabled: enabled}, nil
+	}
+
+	if !validPrefix.MatchString(value) {
+		return &Config{}, ing_errors.NewInvalidAnnotationContent("x-forwarded-prefix", value)
+	}
+
+	return &Config{Enabled: true, Prefix: value}, nil
 }
@@ -35,13 +35,17 @@ func TestParse(t *testing.T) {
 
 	testCases := []struct {
 		annotations map[string]string
-		expected    bool
+		expected    *Config
 	}{
-		{map[string]string{annotation: "true"}, true},
-		{map[string]string{annotation: "1"}, true},
-		{map[string]string{annotation: ""}, false},
-		{map[string]string{}, false},
-		{nil, false},
+		{map[string]string{annotation: "true"}, &Config{Enabled: true}},
+		{map[string]string{annotation: "1"}, &Config{Enabled: true}},
+		{map[string]string{annotation: "false"}, &Config{}},
+		{map[string]string{annotation: "/api/v1"}, &Config{Enabled: true, Prefix: "/api/v1"}},
+		{map[string]string{annotation: "/api\"; more_set_headers \"a"}, &Config{}},
+		{map[string]string{annotation: "api"}, &Config{}},
+		{map[string]string{annotation: ""}, &Config{}},
+		{map[string]string{}, &Config{}},
+		{nil, &Config{}},
 	}
 
 	ing := &extensions.Ingress{
@@ -54,8 +58,9 @@ func TestParse(t *testing.T) {
 
 	for _, testCase := range testCases {
 		ing.SetAnnotations(testCase.annotations)
-		result, _ := ap.Parse(ing)
-		if result != testCase.expected {
+		i, _ := ap.Parse(ing)
+		result := i.(*Config)
+		if !result.Equal(testCase.expected) {
 			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, result, testCase.annotations)
 		}
 	}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+const (
+	// acmeChallengeUpstreamName is the name of the upstream
+	// receiving the ACME HTTP-01 challenges
+	acmeChallengeUpstreamName = "upstream-acme-challenge"
+)
+
+var (
+	// tokens are base64url encoded without padding
+	// https://tools.ietf.org/html/rfc8555#section-8.3
+	acmeTokenRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// key authorizations are the token and the thumbprint of the account key separated by a dot
+	acmeKeyAuthorizationRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
+)
+
+// getACMEChallengeUpstream returns the upstream of the Service configured to
+// receive the ACME HTTP-01 challenges, in the form "namespace/name:port"
+func (n *NGINXController) getACMEChallengeUpstream() *ingress.Backend {
+	svcPort := n.store.GetBackendConfiguration().ACMEChallengeService
+	if svcPort == "" {
+		return nil
+	}
+
+	upstream := &ingress.Backend{
+		Name: acmeChallengeUpstreamName,
+	}
+
+	svcKey, port, err := parseACMEChallengeService(svcPort)
+	if err != nil {
+		klog.Warningf("Invalid ACME challenge Service: %v", err)
+		return upstream
+	}
+
+	svc, err := n.store.GetService(svcKey)
+	if err != nil {
+		klog.Warningf("Error getting ACME challenge Service %q: %v", svcKey, err)
+		return upstream
+	}
+
+	endps, err := n.serviceEndpoints(svcKey, port)
+	if err != nil {
+		klog.Warningf("Error obtaining Endpoints for ACME challenge Service %q: %v", svcKey, err)
+		return upstream
+	}
+
+	upstream.Service = svc
+	upstream.Port = intstr.Parse(port)
+	upstream.Endpoints = endps
+
+	return upstream
+}
+
+// parseACMEChallengeService returns the Service key and
+// the port of a reference in the form namespace/name:port
+func parseACMEChallengeService(svcPort string) (string, string, error) {
+	i := strings.LastIndex(svcPort, ":")
+	if i < 0 || i == len(svcPort)-1 {
+		return "", "", fmt.Errorf("invalid format (namespace/name:port) found in '%v'", svcPort)
+	}
+
+	svcKey, port := svcPort[:i], svcPort[i+1:]
+	ns, name, err := k8s.ParseNameNS(svcKey)
+	if err != nil {
+		return "", "", err
+	}
+	if ns == "" || name == "" {
+		return "", "", fmt.Errorf("invalid format (namespace/name:port) found in '%v'", svcPort)
+	}
+
+	return svcKey, port, nil
+}
+
+// getACMEChallenges returns the responses to the ACME HTTP-01 challenges
+// contained in the configured Secret, indexed by token
+func (n *NGINXController) getACMEChallenges() map[string]string {
+	secrKey := n.store.GetBackendConfiguration().ACMEChallengeSecret
+	if secrKey == "" {
+		return nil
+	}
+
+	secret, err := n.store.GetSecret(secrKey)
+	if err != nil {
+		klog.Warningf("Error getting ACME challenge Secret %q: %v", secrKey, err)
+		return nil
+	}
+
+	return parseACMEChallenges(secrKey, secret.Data)
+}
+
+// parseACMEChallenges returns the valid challenges of the data of a
+// Secret. Only valid tokens and key authorizations are returned,
+// because they are rendered in the configuration file of NGINX.
+func parseACMEChallenges(secrKey string, data map[string][]byte) map[string]string {
+	challenges := make(map[string]string, len(data))
+	for token, keyAuth := range data {
+		keyAuthorization := strings.TrimSpace(string(keyAuth))
+		if !acmeTokenRegex.MatchString(token) || !acmeKeyAuthorizationRegex.MatchString(keyAuthorization) {
+			klog.Warningf("Ignoring invalid ACME challenge %q in Secret %q", token, secrKey)
+			continue
+		}
+
+		challenges[token] = keyAuthorization
+	}
+
+	return challenges
+}
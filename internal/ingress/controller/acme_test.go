@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+)
+
+func TestParseACMEChallengeService(t *testing.T) {
+	testCases := map[string]struct {
+		svcPort        string
+		expectedSvcKey string
+		expectedPort   string
+		expectErr      bool
+	}{
+		"numeric port":        {"cert-manager/solver:8089", "cert-manager/solver", "8089", false},
+		"named port":          {"cert-manager/solver:http", "cert-manager/solver", "http", false},
+		"without port":        {"cert-manager/solver", "", "", true},
+		"with empty port":     {"cert-manager/solver:", "", "", true},
+		"without namespace":   {"solver:8089", "", "", true},
+		"with empty name":     {"cert-manager/:8089", "", "", true},
+		"with too many parts": {"a/b/c:8089", "", "", true},
+	}
+
+	for name, tc := range testCases {
+		svcKey, port, err := parseACMEChallengeService(tc.svcPort)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%v: expected error %v but returned %v", name, tc.expectErr, err)
+		}
+		if svcKey != tc.expectedSvcKey || port != tc.expectedPort {
+			t.Errorf("%v: expected %v and %v but returned %v and %v", name, tc.expectedSvcKey, tc.expectedPort, svcKey, port)
+		}
+	}
+}
+
+func TestParseACMEChallenges(t *testing.T) {
+	data := map[string][]byte{
+		"LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0": []byte("LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0.9jg46WB3rR_AHD-EBXdN7cBkH1WOu0tA3M9fm21mqTI\n"),
+		"invalid;token":  []byte("token.thumbprint"),
+		"invalidKeyAuth": []byte("token.thumbprint\";return 302 http://example.com"),
+		"missingDot":     []byte("thumbprint"),
+	}
+
+	challenges := parseACMEChallenges("default/acme", data)
+	if len(challenges) != 1 {
+		t.Fatalf("expected one challenge but returned %v", challenges)
+	}
+
+	expected := "LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0.9jg46WB3rR_AHD-EBXdN7cBkH1WOu0tA3M9fm21mqTI"
+	if challenges["LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"] != expected {
+		t.Errorf("expected %v but returned %v", expected, challenges)
+	}
+}
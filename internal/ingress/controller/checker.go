@@ -21,7 +21,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
+	ps "github.com/mitchellh/go-ps"
 	"github.com/ncabatoff/process-exporter/proc"
 	"github.com/pkg/errors"
 	"k8s.io/klog"
@@ -30,7 +32,7 @@ import (
 )
 
 // Name returns the healthcheck name
-func (n NGINXController) Name() string {
+func (n *NGINXController) Name() string {
 	return "nginx-ingress-controller"
 }
 
@@ -72,5 +74,59 @@ func (n *NGINXController) Check(_ *http.Request) error {
 		return errors.Wrapf(err, "unexpected error reading the nginx PID from %v", nginx.PID)
 	}
 	_, err = fs.NewProc(pid)
-	return err
+	if err != nil || !n.cfg.DeepHealthCheck {
+		return err
+	}
+
+	return n.deepCheck(pid)
+}
+
+// deepCheck verifies the NGINX master process has workers, the dynamic
+// configuration endpoint responds and, with DeepHealthCheckReload, the last
+// reload succeeded
+func (n *NGINXController) deepCheck(pid int) error {
+	workers, err := countChildProcesses(pid)
+	if err != nil {
+		return errors.Wrap(err, "unexpected error reading the nginx worker processes")
+	}
+
+	if workers == 0 {
+		klog.Errorf("healthcheck error: no worker processes of the nginx master process %v", pid)
+		return fmt.Errorf("nginx worker processes not running")
+	}
+
+	if n.cfg.DeepHealthCheckReload && atomic.LoadInt32(&n.reloadFailed) == 1 {
+		klog.Errorf("healthcheck error: the last reload of nginx failed")
+		return fmt.Errorf("last reload of nginx failed")
+	}
+
+	statusCode, _, err := nginx.NewGetStatusRequest("/configuration/ping")
+	if err != nil {
+		klog.Errorf("healthcheck error: %v", err)
+		return err
+	}
+
+	if statusCode != 200 {
+		klog.Errorf("healthcheck error: %v", statusCode)
+		return fmt.Errorf("dynamic configuration endpoint not available")
+	}
+
+	return nil
+}
+
+// countChildProcesses returns the number of running processes whose parent is pid
+func countChildProcesses(pid int) (int, error) {
+	processes, err := ps.Processes()
+	if err != nil {
+		return 0, err
+	}
+
+	children := 0
+	for _, p := range processes {
+		if p.PPid() == pid {
+			children++
+		}
+	}
+
+	return children, nil
 }
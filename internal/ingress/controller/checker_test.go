@@ -23,8 +23,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/kubernetes/pkg/util/filesystem"
 
@@ -115,6 +119,71 @@ func TestNginxCheck(t *testing.T) {
 	})
 }
 
+func TestNginxDeepCheck(t *testing.T) {
+	listener, err := net.Listen("unix", nginx.StatusSocket)
+	if err != nil {
+		t.Fatalf("crating unix listener: %s", err)
+	}
+	defer listener.Close()
+	defer os.Remove(nginx.StatusSocket)
+
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+		},
+	}
+	defer server.Close()
+	server.Start()
+
+	n := &NGINXController{
+		cfg: &Configuration{
+			DeepHealthCheck: true,
+		},
+	}
+
+	// dummy master process without workers
+	single := exec.Command("sleep", "3600")
+	single.Start()
+	defer single.Process.Kill()
+	go single.Wait()
+
+	if err := n.deepCheck(single.Process.Pid); err == nil {
+		t.Error("expected an error without worker processes but none returned")
+	}
+
+	// dummy master process with a worker
+	master := exec.Command("sh", "-c", "sleep 3600 & wait")
+	master.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	master.Start()
+	defer syscall.Kill(-master.Process.Pid, syscall.SIGKILL)
+	go master.Wait()
+
+	err = wait.Poll(50*time.Millisecond, 5*time.Second, func() (bool, error) {
+		workers, err := countChildProcesses(master.Process.Pid)
+		return workers > 0, err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error waiting for the worker process: %v", err)
+	}
+
+	if err := n.deepCheck(master.Process.Pid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	atomic.StoreInt32(&n.reloadFailed, 1)
+	if err := n.deepCheck(master.Process.Pid); err != nil {
+		t.Errorf("unexpected error after a failed reload without deep-health-check-reload: %v", err)
+	}
+
+	n.cfg.DeepHealthCheckReload = true
+	if err := n.deepCheck(master.Process.Pid); err == nil {
+		t.Error("expected an error after a failed reload but none returned")
+	}
+}
+
 func callHealthz(expErr bool, mux *http.ServeMux) error {
 	req, err := http.NewRequest("GET", "/healthz", nil)
 	if err != nil {
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// configurationChecksum returns the checksum of the configuration applied to
+// NGINX: the data of the configuration ConfigMap and the servers, backends
+// and streams resulting from the objects of the cluster. Values depending on
+// the node, like the resolvers or the number of CPUs, are not included so
+// the replicas of the controller return the same checksum once they
+// converged on the same configuration.
+func configurationChecksum(configMap map[string]string, pcfg *ingress.Configuration) string {
+	cfg := *pcfg
+	// the checksum is only set when NGINX is reloaded
+	cfg.ConfigurationChecksum = ""
+
+	buf, err := json.Marshal(struct {
+		ConfigMap     map[string]string     `json:"configMap"`
+		Configuration ingress.Configuration `json:"configuration"`
+	}{configMap, cfg})
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(buf))
+}
+
+// updateConfigurationChecksum updates the checksum of the applied configuration
+func (n *NGINXController) updateConfigurationChecksum(pcfg *ingress.Configuration) {
+	var data map[string]string
+	if n.cfg.ConfigMapName != "" {
+		cm, err := n.store.GetConfigMap(n.cfg.ConfigMapName)
+		if err == nil {
+			data = cm.Data
+		}
+	}
+
+	checksum := configurationChecksum(data, pcfg)
+
+	n.runningConfigLock.Lock()
+	n.configurationChecksum = checksum
+	n.runningConfigLock.Unlock()
+
+	n.metricCollector.SetConfigurationChecksum(checksum)
+}
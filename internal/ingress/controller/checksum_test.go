@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func newChecksumTestConfiguration(endpoint string) *ingress.Configuration {
+	return &ingress.Configuration{
+		Backends: []*ingress.Backend{
+			{
+				Name:      "default-demo-80",
+				Endpoints: []ingress.Endpoint{{Address: endpoint, Port: "8080"}},
+			},
+		},
+		Servers: []*ingress.Server{
+			{Hostname: "demo.example.com"},
+		},
+	}
+}
+
+func TestConfigurationChecksum(t *testing.T) {
+	configMap := map[string]string{"proxy-body-size": "8m"}
+	checksum := configurationChecksum(configMap, newChecksumTestConfiguration("10.0.0.1"))
+
+	if checksum == "" {
+		t.Fatalf("expected a checksum but an empty one was returned")
+	}
+
+	reloaded := newChecksumTestConfiguration("10.0.0.1")
+	reloaded.ConfigurationChecksum = "1234"
+	if c := configurationChecksum(map[string]string{"proxy-body-size": "8m"}, reloaded); c != checksum {
+		t.Errorf("expected the checksum %v of the same configuration but %v was returned", checksum, c)
+	}
+
+	if c := configurationChecksum(map[string]string{"proxy-body-size": "16m"}, newChecksumTestConfiguration("10.0.0.1")); c == checksum {
+		t.Errorf("expected a different checksum after a change in the ConfigMap")
+	}
+
+	if c := configurationChecksum(configMap, newChecksumTestConfiguration("10.0.0.2")); c == checksum {
+		t.Errorf("expected a different checksum after a change in the endpoints")
+	}
+}
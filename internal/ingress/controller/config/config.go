@@ -26,6 +26,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/runtime"
 )
@@ -81,6 +82,27 @@ const (
 	defaultLimitConnZoneVariable = "$binary_remote_addr"
 )
 
+// Limits of the batches of statistics sent by the Lua monitor of every
+// NGINX worker to the collector of the Prometheus metrics
+const (
+	// DefaultFlushInterval is the interval, in milliseconds, at which
+	// the batches are sent
+	DefaultFlushInterval = 1000
+	// MinFlushInterval is the shortest interval between two batches of
+	// the same worker
+	MinFlushInterval = 100
+	// MaxFlushInterval is the longest interval between two batches of
+	// the same worker, to keep the metrics up to date
+	MaxFlushInterval = 60000
+
+	// DefaultMaxBatchSize is the number of records of a batch, the
+	// requests processed once the batch is full are not reported
+	DefaultMaxBatchSize = 10000
+	// MaxBatchSize is the largest batch accepted by the collector, the
+	// records beyond it are dropped
+	MaxBatchSize = 100000
+)
+
 // Configuration represents the content of nginx.conf file
 type Configuration struct {
 	defaults.Backend `json:",squash"`
@@ -190,22 +212,10 @@ type Configuration struct {
 	// and the need of establishing a new connection.
 	HTTP2MaxRequests int `json:"http2-max-requests,omitempty"`
 
-	// Enables or disables the header HSTS in servers running SSL
-	HSTS bool `json:"hsts,omitempty"`
-
-	// Enables or disables the use of HSTS in all the subdomains of the servername
-	// Default: true
-	HSTSIncludeSubdomains bool `json:"hsts-include-subdomains,omitempty"`
-
-	// HTTP Strict Transport Security (often abbreviated as HSTS) is a security feature (HTTP header)
-	// that tell browsers that it should only be communicated with using HTTPS, instead of using HTTP.
-	// https://developer.mozilla.org/en-US/docs/Web/Security/HTTP_strict_transport_security
-	// max-age is the time, in seconds, that the browser should remember that this site is only to be
-	// accessed using HTTPS.
-	HSTSMaxAge string `json:"hsts-max-age,omitempty"`
-
-	// Enables or disables the preload attribute in HSTS feature
-	HSTSPreload bool `json:"hsts-preload,omitempty"`
+	// HTTP3AltSvcMaxAge is the time, in seconds, that clients should remember
+	// that HTTP/3 is available in the hosts advertising it in the Alt-Svc header
+	// Default: 86400
+	HTTP3AltSvcMaxAge int `json:"http3-alt-svc-max-age,omitempty"`
 
 	// Time during which a keep-alive client connection will stay open on the server side.
 	// The zero value disables keep-alive client connections
@@ -226,6 +236,11 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_log_module.html#log_format
 	LogFormatEscapeJSON bool `json:"log-format-escape-json,omitempty"`
 
+	// Disable escaping of the variables in the upstream log_format.
+	// Ignored if LogFormatEscapeJSON is enabled
+	// http://nginx.org/en/docs/http/ngx_http_log_module.html#log_format
+	LogFormatEscapeNone bool `json:"log-format-escape-none,omitempty"`
+
 	// Customize upstream log_format
 	// http://nginx.org/en/docs/http/ngx_http_log_module.html#log_format
 	LogFormatUpstream string `json:"log-format-upstream,omitempty"`
@@ -258,8 +273,15 @@ type Configuration struct {
 	NginxStatusIpv4Whitelist []string `json:"nginx-status-ipv4-whitelist,omitempty"`
 	NginxStatusIpv6Whitelist []string `json:"nginx-status-ipv6-whitelist,omitempty"`
 
-	// If UseProxyProtocol is enabled ProxyRealIPCIDR defines the default the IP/network address
-	// of your external load balancer
+	// NginxStatusListen contains the IP:port addresses of a dedicated server
+	// exposing the /nginx_status endpoint instead of the "_" server, like
+	// 127.0.0.1:10246 to only expose it to the processes of the pod
+	// By default this is empty
+	NginxStatusListen []string `json:"nginx-status-listen,omitempty"`
+
+	// ProxyRealIPCIDR defines the IP/network addresses of the trusted proxies, usually your
+	// external load balancers, when UseForwardedHeaders or UseProxyProtocol are enabled
+	// http://nginx.org/en/docs/http/ngx_http_realip_module.html#set_real_ip_from
 	ProxyRealIPCIDR []string `json:"proxy-real-ip-cidr,omitempty"`
 
 	// Sets the name of the configmap that contains the headers to pass to the backend
@@ -338,6 +360,21 @@ type Configuration struct {
 	// https://www.igvita.com/2013/12/16/optimizing-nginx-tls-time-to-first-byte/
 	SSLBufferSize string `json:"ssl-buffer-size,omitempty"`
 
+	// Enables stapling of OCSP responses fetched and cached by the controller.
+	// Responses are refreshed halfway through their validity period.
+	// http://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_stapling
+	// By default this is disabled
+	EnableOCSP bool `json:"enable-ocsp"`
+
+	// ACMEChallengeService defines the Service, in the form "namespace/name:port", receiving
+	// the ACME HTTP-01 challenges sent to /.well-known/acme-challenge/ in all the servers
+	ACMEChallengeService string `json:"acme-challenge-service"`
+
+	// ACMEChallengeSecret defines the Secret, in the form "namespace/name", containing the
+	// responses to the ACME HTTP-01 challenges. The keys of the Secret are the tokens
+	// of the challenges and the values the key authorizations returned to the ACME server.
+	ACMEChallengeSecret string `json:"acme-challenge-secret"`
+
 	// Enables or disables the use of the PROXY protocol to receive client connection
 	// (real IP address) information passed through proxy servers and load balancers
 	// such as HAproxy and Amazon Elastic Load Balancer (ELB).
@@ -361,6 +398,16 @@ type Configuration struct {
 	// By default this is disabled
 	UseGeoIP2 bool `json:"use-geoip2,omitempty"`
 
+	// MaxmindEditionIDs contains the editions of the GeoIP2 databases
+	// loaded by NGINX and downloaded with the license key
+	// Default: GeoLite2-City,GeoLite2-ASN
+	MaxmindEditionIDs []string `json:"maxmind-edition-ids"`
+
+	// MaxmindRefreshInterval sets how often the GeoIP2 databases are downloaded
+	// again. The zero value only downloads the missing databases
+	// Default: 24h
+	MaxmindRefreshInterval time.Duration `json:"maxmind-refresh-interval"`
+
 	// Enables or disables the use of the NGINX Brotli Module for compression
 	// https://github.com/google/ngx_brotli
 	EnableBrotli bool `json:"enable-brotli,omitempty"`
@@ -371,10 +418,9 @@ type Configuration struct {
 	// MIME Types that will be compressed on-the-fly using Brotli module
 	BrotliTypes string `json:"brotli-types,omitempty"`
 
-	// Enables or disables the HTTP/2 support in secure connections
-	// http://nginx.org/en/docs/http/ngx_http_v2_module.html
-	// Default: true
-	UseHTTP2 bool `json:"use-http2,omitempty"`
+	// Minimum length of a response, as read from the Content-Length header,
+	// that will be compressed using Brotli module
+	BrotliMinLength int `json:"brotli-min-length,omitempty"`
 
 	// gzip Compression Level that will be used
 	GzipLevel int `json:"gzip-level,omitempty"`
@@ -383,6 +429,21 @@ type Configuration struct {
 	// Responses with the “text/html” type are always compressed if UseGzip is enabled
 	GzipTypes string `json:"gzip-types,omitempty"`
 
+	// Minimum length of a response, as read from the Content-Length header,
+	// that will be gzipped
+	// http://nginx.org/en/docs/http/ngx_http_gzip_module.html#gzip_min_length
+	GzipMinLength int `json:"gzip-min-length,omitempty"`
+
+	// Enables or disables gzipping of responses for proxied requests
+	// depending on the request and response
+	// http://nginx.org/en/docs/http/ngx_http_gzip_module.html#gzip_proxied
+	GzipProxied string `json:"gzip-proxied,omitempty"`
+
+	// Disables gzipping of responses for requests with the User-Agent
+	// header fields matching any of the specified regular expressions
+	// http://nginx.org/en/docs/http/ngx_http_gzip_module.html#gzip_disable
+	GzipDisable string `json:"gzip-disable,omitempty"`
+
 	// Defines the number of worker processes. By default auto means number of available CPU cores
 	// http://nginx.org/en/docs/ngx_core_module.html#worker_processes
 	WorkerProcesses string `json:"worker-processes,omitempty"`
@@ -399,22 +460,6 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_map_module.html#variables_hash_max_size
 	VariablesHashMaxSize int `json:"variables-hash-max-size,omitempty"`
 
-	// Activates the cache for connections to upstream servers.
-	// The connections parameter sets the maximum number of idle keepalive connections to
-	// upstream servers that are preserved in the cache of each worker process. When this
-	// number is exceeded, the least recently used connections are closed.
-	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive
-	UpstreamKeepaliveConnections int `json:"upstream-keepalive-connections,omitempty"`
-
-	// Sets a timeout during which an idle keepalive connection to an upstream server will stay open.
-	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_timeout
-	UpstreamKeepaliveTimeout int `json:"upstream-keepalive-timeout,omitempty"`
-
-	// Sets the maximum number of requests that can be served through one keepalive connection.
-	// After the maximum number of requests is made, the connection is closed.
-	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_requests
-	UpstreamKeepaliveRequests int `json:"upstream-keepalive-requests,omitempty"`
-
 	// Sets the maximum size of the variables hash table.
 	// http://nginx.org/en/docs/http/ngx_http_map_module.html#variables_hash_max_size
 	LimitConnZoneVariable string `json:"limit-conn-zone-variable,omitempty"`
@@ -522,6 +567,9 @@ type Configuration struct {
 	// LocationSnippet adds custom configuration to all the locations in the nginx configuration
 	LocationSnippet string `json:"location-snippet"`
 
+	// StreamSnippet adds custom configuration to the stream section of the nginx configuration
+	StreamSnippet string `json:"stream-snippet"`
+
 	// HTTPRedirectCode sets the HTTP status code to be used in redirects.
 	// Supported codes are 301,302,307 and 308
 	// Default: 308
@@ -533,6 +581,13 @@ type Configuration struct {
 	// Default: true
 	ReusePort bool `json:"reuse-port"`
 
+	// BacklogSize sets the maximum length of the queue of pending connections
+	// of the default server listening sockets. The zero value uses the value
+	// of net.core.somaxconn, which is also the upper limit enforced by the kernel
+	// http://nginx.org/en/docs/http/ngx_http_core_module.html#listen
+	// Default: 0
+	BacklogSize int `json:"backlog-size"`
+
 	// HideHeaders sets additional header that will not be passed from the upstream
 	// server to the client response
 	// Default: empty
@@ -548,6 +603,47 @@ type Configuration struct {
 	// Default: 503
 	LimitConnStatusCode int `json:"limit-conn-status-code"`
 
+	// GlobalRateLimitBackend is the shared store used to enforce the global
+	// rate limits across all the replicas of the controller. Valid values
+	// are memcached and redis. Empty disables the global rate limits
+	GlobalRateLimitBackend string `json:"global-rate-limit-backend"`
+
+	// GlobalRateLimitHost is the address of the shared store
+	GlobalRateLimitHost string `json:"global-rate-limit-host"`
+
+	// GlobalRateLimitPort is the port of the shared store
+	// Default: 11211 for memcached and 6379 for redis
+	GlobalRateLimitPort int `json:"global-rate-limit-port"`
+
+	// GlobalRateLimitConnectTimeout is the timeout in milliseconds
+	// of the operations with the shared store
+	GlobalRateLimitConnectTimeout int `json:"global-rate-limit-connect-timeout"`
+
+	// GlobalRateLimitMaxIdleTimeout is the time in milliseconds an idle
+	// connection to the shared store is kept in the connection pool
+	GlobalRateLimitMaxIdleTimeout int `json:"global-rate-limit-max-idle-timeout"`
+
+	// GlobalRateLimitPoolSize is the maximum number of idle connections
+	// to the shared store kept in the pool of every NGINX worker
+	GlobalRateLimitPoolSize int `json:"global-rate-limit-pool-size"`
+
+	// GlobalRateLimitStatusCode Sets the status code to return in response
+	// to requests rejected by a global rate limit
+	// Default: 429
+	GlobalRateLimitStatusCode int `json:"global-rate-limit-status-code"`
+
+	// MetricsFlushInterval is the interval in milliseconds at which every
+	// NGINX worker sends the statistics of its requests to the collector
+	// of the Prometheus metrics
+	// Default: 1000
+	MetricsFlushInterval int `json:"metrics-flush-interval"`
+
+	// MetricsMaxBatchSize is the maximum number of requests reported by
+	// every NGINX worker in each interval. Requests exceeding it are not
+	// part of the Prometheus metrics
+	// Default: 10000
+	MetricsMaxBatchSize int `json:"metrics-max-batch-size"`
+
 	// EnableSyslog enables the configuration for remote logging in NGINX
 	EnableSyslog bool `json:"enable-syslog"`
 	// SyslogHost FQDN or IP address where the logs should be sent
@@ -563,6 +659,11 @@ type Configuration struct {
 	// should not get authenticated
 	NoAuthLocations string `json:"no-auth-locations"`
 
+	// GlobalExternalAuth configures an external authentication service
+	// for all the locations not defining their own, unless disabled with
+	// the annotation enable-global-auth
+	GlobalExternalAuth authreq.Config `json:"global-external-auth"`
+
 	// DisableLuaRestyWAF disables lua-resty-waf globally regardless
 	// of whether there's an ingress that has enabled the WAF using annotation
 	DisableLuaRestyWAF bool `json:"disable-lua-resty-waf"`
@@ -583,6 +684,28 @@ type Configuration struct {
 
 	// Block all requests with given Referer headers
 	BlockReferers []string `json:"block-referers"`
+
+	// Plugins is the list of Lua plugins that run in every location.
+	// A plugin named foo is loaded from /etc/nginx/lua/plugins/foo/main.lua
+	// and can be enabled in a single Ingress rule using the plugins annotation
+	Plugins []string `json:"plugins"`
+
+	// LuaSharedDicts contains the size, in kilobytes, of the Lua shared
+	// dictionaries replacing the default ones or defining new dictionaries
+	// https://github.com/openresty/lua-nginx-module#lua_shared_dict
+	LuaSharedDicts map[string]int `json:"lua-shared-dicts"`
+}
+
+// defaultLuaSharedDicts contains the default size, in kilobytes, of the
+// Lua shared dictionaries used by the controller
+var defaultLuaSharedDicts = map[string]int{
+	"configuration_data":         5120,
+	"certificate_data":           16384,
+	"ocsp_response_data":         5120,
+	"discovery":                  1024,
+	"jwks":                       1024,
+	"waf_storage":                65536,
+	"tcp_udp_configuration_data": 5120,
 }
 
 // NewDefault returns the default nginx configuration
@@ -608,8 +731,10 @@ func NewDefault() Configuration {
 		BlockCIDRs:                       defBlockEntity,
 		BlockUserAgents:                  defBlockEntity,
 		BlockReferers:                    defBlockEntity,
+		Plugins:                          []string{},
 		BrotliLevel:                      4,
 		BrotliTypes:                      brotliTypes,
+		BrotliMinLength:                  20,
 		ClientHeaderBufferSize:           "1k",
 		ClientHeaderTimeout:              60,
 		ClientBodyBufferSize:             "8k",
@@ -626,17 +751,17 @@ func NewDefault() Configuration {
 		HTTP2MaxHeaderSize:               "16k",
 		HTTP2MaxRequests:                 1000,
 		HTTPRedirectCode:                 308,
-		HSTS:                             true,
-		HSTSIncludeSubdomains:            true,
-		HSTSMaxAge:                       hstsMaxAge,
-		HSTSPreload:                      false,
+		HTTP3AltSvcMaxAge:                86400,
 		IgnoreInvalidHeaders:             true,
 		GzipLevel:                        5,
 		GzipTypes:                        gzipTypes,
+		GzipMinLength:                    256,
+		GzipProxied:                      "any",
 		KeepAlive:                        75,
 		KeepAliveRequests:                100,
 		LargeClientHeaderBuffers:         "4 8k",
 		LogFormatEscapeJSON:              false,
+		LogFormatEscapeNone:              false,
 		LogFormatStream:                  logFormatStream,
 		LogFormatUpstream:                logFormatUpstream,
 		EnableMultiAccept:                true,
@@ -665,19 +790,22 @@ func NewDefault() Configuration {
 		UseGzip:                          true,
 		UseGeoIP:                         true,
 		UseGeoIP2:                        false,
+		MaxmindEditionIDs:                []string{"GeoLite2-City", "GeoLite2-ASN"},
+		MaxmindRefreshInterval:           24 * time.Hour,
 		WorkerProcesses:                  strconv.Itoa(runtime.NumCPU()),
 		WorkerShutdownTimeout:            "10s",
 		VariablesHashBucketSize:          128,
 		VariablesHashMaxSize:             2048,
-		UseHTTP2:                         true,
 		ProxyStreamTimeout:               "600s",
 		Backend: defaults.Backend{
 			ProxyBodySize:          bodySize,
 			ProxyConnectTimeout:    5,
 			ProxyReadTimeout:       60,
 			ProxySendTimeout:       60,
+			ProxyMaxTimeout:        3600,
 			ProxyBuffersNumber:     4,
 			ProxyBufferSize:        "4k",
+			ProxyMaxTempFileSize:   "1024m",
 			ProxyCookieDomain:      "off",
 			ProxyCookiePath:        "off",
 			ProxyNextUpstream:      "error timeout",
@@ -691,11 +819,18 @@ func NewDefault() Configuration {
 			SkipAccessLogURLs:      []string{},
 			LimitRate:              0,
 			LimitRateAfter:         0,
+			LimitConnections:       0,
 			ProxyBuffering:         "off",
+			UseHTTP2:               true,
+			HSTS:                   true,
+			HSTSIncludeSubdomains:  true,
+			HSTSMaxAge:             hstsMaxAge,
+			HSTSPreload:            false,
+
+			UpstreamKeepaliveConnections: 32,
+			UpstreamKeepaliveTimeout:     60,
+			UpstreamKeepaliveRequests:    100,
 		},
-		UpstreamKeepaliveConnections: 32,
-		UpstreamKeepaliveTimeout:     60,
-		UpstreamKeepaliveRequests:    100,
 		LimitConnZoneVariable:        defaultLimitConnZoneVariable,
 		BindAddressIpv4:              defBindAddress,
 		BindAddressIpv6:              defBindAddress,
@@ -714,6 +849,19 @@ func NewDefault() Configuration {
 		SyslogPort:                   514,
 		NoTLSRedirectLocations:       "/.well-known/acme-challenge",
 		NoAuthLocations:              "/.well-known/acme-challenge",
+
+		GlobalRateLimitConnectTimeout: 50,
+		GlobalRateLimitMaxIdleTimeout: 10000,
+		GlobalRateLimitPoolSize:       50,
+		GlobalRateLimitStatusCode:     429,
+
+		MetricsFlushInterval: DefaultFlushInterval,
+		MetricsMaxBatchSize:  DefaultMaxBatchSize,
+	}
+
+	cfg.LuaSharedDicts = make(map[string]int, len(defaultLuaSharedDicts))
+	for name, size := range defaultLuaSharedDicts {
+		cfg.LuaSharedDicts[name] = size
 	}
 
 	if klog.V(5) {
@@ -723,6 +871,16 @@ func NewDefault() Configuration {
 	return cfg
 }
 
+// LuaSharedDictSize returns the size, in kilobytes, of a Lua shared
+// dictionary, or the default size if it is not configured
+func (cfg Configuration) LuaSharedDictSize(name string) int {
+	if size, ok := cfg.LuaSharedDicts[name]; ok {
+		return size
+	}
+
+	return defaultLuaSharedDicts[name]
+}
+
 // BuildLogFormatUpstream format the log_format upstream using
 // proxy_protocol_addr as remote client address if UseProxyProtocol
 // is enabled.
@@ -747,6 +905,7 @@ type TemplateConfig struct {
 	HealthzURI                 string
 	Cfg                        Configuration
 	IsIPV6Enabled              bool
+	IsHTTP3Enabled             bool
 	IsSSLPassthroughEnabled    bool
 	NginxStatusIpv4Whitelist   []string
 	NginxStatusIpv6Whitelist   []string
@@ -755,6 +914,14 @@ type TemplateConfig struct {
 	PublishService             *apiv1.Service
 	DynamicCertificatesEnabled bool
 	EnableMetrics              bool
+	EnableIPAccess             bool
+	SSLSessionTicketKeys       []string
+	GeoIP2Databases            []string
+	GeoIP2MissingDatabases     []string
+	ACMEChallenges             map[string]string
+
+	// ServerBlocks contains the servers rendered with the SERVER template
+	ServerBlocks map[*ingress.Server]string `json:"-"`
 
 	PID          string
 	StatusSocket string
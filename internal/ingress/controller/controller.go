@@ -17,11 +17,14 @@ limitations under the License.
 package controller
 
 import (
+	"crypto/x509"
 	"fmt"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mitchellh/hashstructure"
@@ -29,15 +32,23 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 
+	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
 	"k8s.io/ingress-nginx/internal/k8s"
+	ing_net "k8s.io/ingress-nginx/internal/net"
 )
 
 const (
@@ -51,6 +62,9 @@ type Configuration struct {
 	APIServerHost  string
 	KubeConfigFile string
 	Client         clientset.Interface
+	// StreamServiceClient is only set when StreamServices are enabled
+	// +optional
+	StreamServiceClient streamservice.Interface
 
 	ResyncPeriod time.Duration
 
@@ -65,10 +79,22 @@ type Configuration struct {
 	TCPConfigMapName string
 	// +optional
 	UDPConfigMapName string
+	// +optional
+	IPAccessConfigMapName string
 
 	HealthCheckTimeout    time.Duration
 	DefaultSSLCertificate string
 
+	// DeepHealthCheck extends the health check to the NGINX workers
+	// and the dynamic configuration endpoint
+	DeepHealthCheck bool
+	// DeepHealthCheckReload extends the deep health check to the last reload
+	DeepHealthCheckReload bool
+
+	// PublishNotReadyEndpoints includes the not ready addresses of the
+	// Services with publishNotReadyAddresses in the upstreams
+	PublishNotReadyEndpoints bool
+
 	// +optional
 	PublishService       string
 	PublishStatusAddress string
@@ -80,12 +106,27 @@ type Configuration struct {
 
 	ListenPorts *ngx_config.ListenPorts
 
+	// HealthCheckHost is the address of the healthz, metrics and profiling
+	// endpoints. All the addresses are used when it is empty
+	HealthCheckHost string
+
 	EnableSSLPassthrough bool
 
+	EnableStreamServices bool
+
 	EnableProfiling bool
 
+	// DryRun renders and checks the NGINX configuration and exits
+	DryRun bool
+	// DryRunConfigDir contains the manifests used in place of the
+	// objects of the API server in the dry-run mode
+	DryRunConfigDir string
+
 	EnableMetrics  bool
 	MetricsPerHost bool
+	// MetricsMaxHosts limits the number of hosts used as label of the
+	// metrics (no limit when zero)
+	MetricsMaxHosts int
 
 	EnableSSLChainCompletion bool
 
@@ -94,13 +135,36 @@ type Configuration struct {
 
 	SyncRateLimit float32
 
+	// SyncQueueBaseDelay, SyncQueueMaxDelay and SyncQueueBurst configure the
+	// rate limiter of the syncs requeued after a failure
+	SyncQueueBaseDelay time.Duration
+	SyncQueueMaxDelay  time.Duration
+	SyncQueueBurst     int
+
 	DynamicCertificatesEnabled bool
 
 	DisableCatchAll bool
+
+	// +optional
+	IngressLabelSelector string
+
+	ValidationWebhook         string
+	ValidationWebhookCertPath string
+	ValidationWebhookKeyPath  string
+
+	ConfigDriftCheckPeriod time.Duration
+
+	// +optional
+	SSLSessionTicketKeySecret         string
+	SSLSessionTicketKeyRotationPeriod time.Duration
+	DisableSSLSessionTickets          bool
+
+	// +optional
+	MaxmindLicenseKeySecret string
 }
 
 // GetPublishService returns the Service used to set the load-balancer status of Ingresses.
-func (n NGINXController) GetPublishService() *apiv1.Service {
+func (n *NGINXController) GetPublishService() *apiv1.Service {
 	s, err := n.store.GetService(n.cfg.PublishService)
 	if err != nil {
 		return nil
@@ -112,63 +176,60 @@ func (n NGINXController) GetPublishService() *apiv1.Service {
 // syncIngress collects all the pieces required to assemble the NGINX
 // configuration file and passes the resulting data structures to the backend
 // (OnUpdate) when a reload is deemed necessary.
-func (n *NGINXController) syncIngress(interface{}) error {
+func (n *NGINXController) syncIngress(interface{}) (err error) {
 	n.syncRateLimiter.Accept()
 
 	if n.syncQueue.IsShuttingDown() {
 		return nil
 	}
 
-	ings := n.store.ListIngresses()
-
-	upstreams, servers := n.getBackendServers(ings)
-	var passUpstreams []*ingress.SSLPassthroughBackend
-
-	hosts := sets.NewString()
+	if n.reloadChangedTemplate() {
+		// the ingress configuration did not change, only the template
+		atomic.StoreInt32(&n.forceSync, 1)
+	}
 
-	for _, server := range servers {
-		if !hosts.Has(server.Hostname) {
-			hosts.Insert(server.Hostname)
-		}
-		if server.Alias != "" && !hosts.Has(server.Alias) {
-			hosts.Insert(server.Alias)
-		}
+	ings := n.store.ListIngresses()
+	hosts, servers, pcfg, streamServiceStates := n.getConfiguration(ings)
 
-		if !server.SSLPassthrough {
-			continue
-		}
+	n.updateOrphanedIngresses(ings, pcfg.Backends)
+	n.reportInvalidIPAccess()
 
-		for _, loc := range server.Locations {
-			if loc.Path != rootLocation {
-				klog.Warningf("Ignoring SSL Passthrough for location %q in server %q", loc.Path, server.Hostname)
-				continue
+	if n.isLeader() && n.cfg.StreamServiceClient != nil {
+		// the ports of the StreamServices are exposed only once NGINX
+		// is reloaded and configured
+		defer func() {
+			if err != nil {
+				for _, state := range streamServiceStates {
+					state.configurationFailed = true
+				}
 			}
-			passUpstreams = append(passUpstreams, &ingress.SSLPassthroughBackend{
-				Backend:  loc.Backend,
-				Hostname: server.Hostname,
-				Service:  loc.Service,
-				Port:     loc.Port,
-			})
-			break
-		}
+			n.updateStreamServiceStatus(streamServiceStates)
+		}()
 	}
 
-	pcfg := &ingress.Configuration{
-		Backends:              upstreams,
-		Servers:               servers,
-		TCPEndpoints:          n.getStreamServices(n.cfg.TCPConfigMapName, apiv1.ProtocolTCP),
-		UDPEndpoints:          n.getStreamServices(n.cfg.UDPConfigMapName, apiv1.ProtocolUDP),
-		PassthroughBackends:   passUpstreams,
-		BackendConfigChecksum: n.store.GetBackendConfiguration().Checksum,
-		ControllerPodsCount:   n.store.GetRunningControllerPodsCount(),
+	forceSync := atomic.CompareAndSwapInt32(&n.forceSync, 1, 0)
+
+	if n.cfg.EnableSSLPassthrough {
+		n.updatePassthroughServers(pcfg.PassthroughBackends)
 	}
 
-	if n.runningConfig.Equal(pcfg) {
+	if !forceSync && n.runningConfig.Equal(pcfg) {
 		klog.V(3).Infof("No configuration change detected, skipping backend reload.")
 		return nil
 	}
 
-	if !n.IsDynamicConfigurationEnough(pcfg) {
+	reload := forceSync || !n.IsDynamicConfigurationEnough(pcfg)
+	if !reload {
+		// the checksum rendered in the configuration file is the one of the last reload
+		pcfg.ConfigurationChecksum = n.runningConfig.ConfigurationChecksum
+
+		if !n.isConfigurationFileUnchanged(pcfg) {
+			klog.Warningf("The NGINX configuration file changed although only dynamic changes were detected.")
+			reload = true
+		}
+	}
+
+	if reload {
 		klog.Infof("Configuration changes detected, backend reload required.")
 
 		hash, _ := hashstructure.Hash(pcfg, &hashstructure.HashOptions{
@@ -179,14 +240,18 @@ func (n *NGINXController) syncIngress(interface{}) error {
 
 		err := n.OnUpdate(*pcfg)
 		if err != nil {
+			atomic.StoreInt32(&n.reloadFailed, 1)
 			n.metricCollector.IncReloadErrorCount()
 			n.metricCollector.ConfigSuccess(hash, false)
+			n.metricCollector.ConfigApplied(false)
 			klog.Errorf("Unexpected failure reloading the backend:\n%v", err)
 			return err
 		}
 
 		n.metricCollector.SetHosts(hosts)
 
+		atomic.StoreInt32(&n.reloadFailed, 0)
+
 		klog.Infof("Backend successfully reloaded.")
 		n.metricCollector.ConfigSuccess(hash, true)
 		n.metricCollector.IncReloadCount()
@@ -208,8 +273,20 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		Jitter:   0.1,
 	}
 
-	err := wait.ExponentialBackoff(retry, func() (bool, error) {
-		err := configureDynamically(pcfg, n.cfg.DynamicCertificatesEnabled)
+	// a rotation of certificates only requires the update of the
+	// certificates served by Lua
+	certificatesOnly := !reload && n.cfg.DynamicCertificatesEnabled && onlyCertificatesChanged(n.runningConfig, pcfg)
+
+	// the backends applied and their checksum are updated atomically for
+	// the drift check
+	n.appliedLock.Lock()
+	err = wait.ExponentialBackoff(retry, func() (bool, error) {
+		var err error
+		if certificatesOnly {
+			err = configureCertificates(pcfg)
+		} else {
+			err = configureDynamically(pcfg, n.cfg.DynamicCertificatesEnabled)
+		}
 		if err == nil {
 			klog.V(2).Infof("Dynamic reconfiguration succeeded.")
 			return true, nil
@@ -218,20 +295,268 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		klog.Warningf("Dynamic reconfiguration failed: %v", err)
 		return false, err
 	})
+	if err == nil {
+		n.setAppliedBackends()
+	}
+	n.appliedLock.Unlock()
+
 	if err != nil {
 		klog.Errorf("Unexpected failure reconfiguring NGINX:\n%v", err)
+		n.metricCollector.ConfigApplied(false)
 		return err
 	}
 
+	if certificatesOnly {
+		klog.Infof("SSL certificates updated without a backend reload.")
+		n.metricCollector.IncReloadAvoidedCount("ssl-certificate")
+	}
+
 	ri := getRemovedIngresses(n.runningConfig, pcfg)
 	re := getRemovedHosts(n.runningConfig, pcfg)
 	n.metricCollector.RemoveMetrics(ri, re)
 
+	n.runningConfigLock.Lock()
 	n.runningConfig = pcfg
+	n.runningConfigLock.Unlock()
+
+	n.updateConfigurationChecksum(pcfg)
+
+	n.metricCollector.ConfigApplied(true)
 
 	return nil
 }
 
+// CheckIngress returns an error in case the provided ingress, when added
+// to the current configuration, generates an invalid configuration
+func (n *NGINXController) CheckIngress(ing *extensions.Ingress) error {
+	if n == nil {
+		return fmt.Errorf("cannot check ingress on a nil ingress controller")
+	}
+
+	if ing == nil {
+		// no ingress to add, no state change
+		return nil
+	}
+
+	if !class.IsValid(ing) {
+		klog.V(3).Infof("Ignoring Ingress %v/%v: it does not belong to this controller", ing.Namespace, ing.Name)
+		return nil
+	}
+
+	if n.cfg.Namespace != "" && ing.Namespace != n.cfg.Namespace {
+		klog.V(3).Infof("Ignoring Ingress %v/%v: namespace is not watched", ing.Namespace, ing.Name)
+		return nil
+	}
+
+	if n.cfg.IngressLabelSelector != "" {
+		selector, err := labels.Parse(n.cfg.IngressLabelSelector)
+		if err != nil {
+			return err
+		}
+
+		if !selector.Matches(labels.Set(ing.Labels)) {
+			klog.V(3).Infof("Ignoring Ingress %v/%v: it does not match the label selector", ing.Namespace, ing.Name)
+			return nil
+		}
+	}
+
+	// replace the current version of the Ingress (if any) with the candidate
+	ings := []*ingress.Ingress{}
+	for _, i := range n.store.ListIngresses() {
+		if i.Namespace == ing.Namespace && i.Name == ing.Name {
+			continue
+		}
+		ings = append(ings, i)
+	}
+
+	ings = append(ings, &ingress.Ingress{
+		Ingress:           *ing,
+		ParsedAnnotations: annotations.NewAnnotationExtractor(n.store).Extract(ing),
+	})
+
+	_, _, pcfg, _ := n.getConfiguration(ings)
+
+	cfg := n.store.GetBackendConfiguration()
+	cfg.Resolver = n.resolver
+
+	content, err := n.generateTemplate(cfg, *pcfg)
+	if err != nil {
+		return err
+	}
+
+	// the certificates in use must not be modified by an Ingress
+	// that could be rejected
+	staged := stagedCertificates{}
+	if n.cfg.DynamicCertificatesEnabled {
+		staged, err = stageStreamCertificates(pcfg.TCPEndpoints)
+		if err != nil {
+			return err
+		}
+		defer staged.discard()
+	}
+
+	return n.testTemplate(staged.replace(content))
+}
+
+// getConfiguration returns the hosts, servers and NGINX configuration
+// resulting from the provided list of Ingresses, and the state of the ports
+// of the StreamServices.
+func (n *NGINXController) getConfiguration(ingresses []*ingress.Ingress) (sets.String, []*ingress.Server, *ingress.Configuration, map[string]*streamServiceState) {
+	ingresses, streamIngresses := splitStreamIngresses(ingresses)
+
+	upstreams, servers := n.getBackendServers(ingresses)
+	if upstream := n.getACMEChallengeUpstream(); upstream != nil {
+		upstreams = append(upstreams, upstream)
+	}
+
+	var passUpstreams []*ingress.SSLPassthroughBackend
+
+	hosts := sets.NewString()
+
+	for _, server := range servers {
+		if !hosts.Has(server.Hostname) {
+			hosts.Insert(server.Hostname)
+		}
+		if server.Alias != "" && !hosts.Has(server.Alias) {
+			hosts.Insert(server.Alias)
+		}
+
+		if !server.SSLPassthrough {
+			continue
+		}
+
+		for _, loc := range server.Locations {
+			if loc.Path != rootLocation {
+				klog.Warningf("Ignoring SSL Passthrough for location %q in server %q", loc.Path, server.Hostname)
+				continue
+			}
+			passUpstreams = append(passUpstreams, &ingress.SSLPassthroughBackend{
+				Backend:       loc.Backend,
+				Hostname:      server.Hostname,
+				Service:       loc.Service,
+				Port:          loc.Port,
+				ProxyProtocol: server.SSLPassthroughProxyProtocol,
+			})
+			break
+		}
+	}
+
+	tcpEndpoints := n.getStreamServices(n.cfg.TCPConfigMapName, apiv1.ProtocolTCP)
+	udpEndpoints := n.getStreamServices(n.cfg.UDPConfigMapName, apiv1.ProtocolUDP)
+
+	ssTCPEndpoints, ssUDPEndpoints, streamServiceStates := n.getStreamServiceEndpoints(n.store.ListStreamServices(), tcpEndpoints, udpEndpoints)
+	tcpEndpoints = append(tcpEndpoints, ssTCPEndpoints...)
+	udpEndpoints = append(udpEndpoints, ssUDPEndpoints...)
+
+	tcpEndpoints = append(tcpEndpoints, n.getIngressStreamServices(streamIngresses, tcpEndpoints)...)
+
+	// Keep upstream order sorted to reduce unnecessary nginx config reloads.
+	sort.SliceStable(tcpEndpoints, func(i, j int) bool {
+		return tcpEndpoints[i].Port < tcpEndpoints[j].Port
+	})
+	sort.SliceStable(udpEndpoints, func(i, j int) bool {
+		return udpEndpoints[i].Port < udpEndpoints[j].Port
+	})
+
+	pcfg := &ingress.Configuration{
+		Backends:              upstreams,
+		Servers:               servers,
+		TCPEndpoints:          tcpEndpoints,
+		UDPEndpoints:          udpEndpoints,
+		PassthroughBackends:   passUpstreams,
+		BackendConfigChecksum: n.store.GetBackendConfiguration().Checksum,
+		ControllerPodsCount:   n.store.GetRunningControllerPodsCount(),
+
+		SSLSessionTicketKeysChecksum: n.sessionTicketKeys.Checksum(),
+		ACMEChallenges:               n.getACMEChallenges(),
+		IPAccess:                     n.getIPAccess(),
+	}
+
+	return hosts, servers, pcfg, streamServiceStates
+}
+
+// getIPAccess returns the CIDRs denied and allowed to access every server,
+// read from the deny and allow keys of the IP access ConfigMap
+func (n *NGINXController) getIPAccess() ingress.IPAccess {
+	ipAccess := ingress.IPAccess{
+		Denied:  []string{},
+		Allowed: []string{},
+	}
+
+	if n.cfg.IPAccessConfigMapName == "" {
+		return ipAccess
+	}
+
+	configmap, err := n.store.GetConfigMap(n.cfg.IPAccessConfigMapName)
+	if err != nil {
+		klog.Errorf("Error getting ConfigMap %q: %v", n.cfg.IPAccessConfigMapName, err)
+		return ipAccess
+	}
+
+	ipAccess.Denied, _ = parseIPAccessList(configmap.Data["deny"])
+	ipAccess.Allowed, _ = parseIPAccessList(configmap.Data["allow"])
+
+	return ipAccess
+}
+
+// reportInvalidIPAccess emits a Warning Event on the IP access ConfigMap
+// when the entries ignored in its lists change
+func (n *NGINXController) reportInvalidIPAccess() {
+	if n.cfg.IPAccessConfigMapName == "" {
+		return
+	}
+
+	configmap, err := n.store.GetConfigMap(n.cfg.IPAccessConfigMapName)
+	if err != nil {
+		return
+	}
+
+	_, denied := parseIPAccessList(configmap.Data["deny"])
+	_, allowed := parseIPAccessList(configmap.Data["allow"])
+
+	invalid := strings.Join(append(denied, allowed...), ", ")
+	if invalid == n.invalidIPAccess {
+		return
+	}
+
+	n.invalidIPAccess = invalid
+	if invalid == "" {
+		return
+	}
+
+	klog.Warningf("Ignoring the invalid entries %v of the IP access ConfigMap %q", invalid, n.cfg.IPAccessConfigMapName)
+	n.recorder.Eventf(configmap, apiv1.EventTypeWarning, "IPACCESS",
+		"Ignoring the invalid entries %v of the IP access lists", invalid)
+}
+
+// parseIPAccessList returns the sorted addresses and CIDRs of a comma
+// separated list and the invalid entries skipped
+func parseIPAccessList(list string) ([]string, []string) {
+	cidrs := sets.NewString()
+	invalid := []string{}
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		ipnets, ips, err := ing_net.ParseIPNets(value)
+		if err != nil {
+			invalid = append(invalid, value)
+			continue
+		}
+
+		for cidr := range ipnets {
+			cidrs.Insert(cidr)
+		}
+		for addr := range ips {
+			cidrs.Insert(addr)
+		}
+	}
+
+	return cidrs.List(), invalid
+}
+
 func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Protocol) []ingress.L4Service {
 	if configmapName == "" {
 		return []ingress.L4Service{}
@@ -249,14 +574,7 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 	}
 	var svcs []ingress.L4Service
 	var svcProxyProtocol ingress.ProxyProtocol
-	rp := []int{
-		n.cfg.ListenPorts.HTTP,
-		n.cfg.ListenPorts.HTTPS,
-		n.cfg.ListenPorts.SSLProxy,
-		n.cfg.ListenPorts.Health,
-		n.cfg.ListenPorts.Default,
-	}
-	reserverdPorts := sets.NewInt(rp...)
+	reserverdPorts := n.reservedPorts()
 	// svcRef format: <(str)namespace>/<(str)service>:<(intstr)port>[:<("PROXY")decode>:<("PROXY")encode>]
 	for port, svcRef := range configmap.Data {
 		externalPort, err := strconv.Atoi(port)
@@ -286,6 +604,14 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 				svcProxyProtocol.Encode = true
 			}
 		}
+		var udpOptions ingress.L4Backend
+		if proto == apiv1.ProtocolUDP {
+			err = parseUDPOptions(nsSvcPort[2:], &udpOptions)
+			if err != nil {
+				klog.Warningf("Invalid Service reference %q for %v port %d: %v", svcRef, proto, externalPort, err)
+				continue
+			}
+		}
 		svcNs, svcName, err := k8s.ParseNameNS(nsName)
 		if err != nil {
 			klog.Warningf("%v", err)
@@ -304,7 +630,7 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 			for _, sp := range svc.Spec.Ports {
 				if sp.Name == svcPort {
 					if sp.Protocol == proto {
-						endps = getEndpoints(svc, &sp, proto, n.store.GetServiceEndpoints)
+						endps = getEndpoints(svc, &sp, proto, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 						break
 					}
 				}
@@ -314,7 +640,7 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 			for _, sp := range svc.Spec.Ports {
 				if sp.Port == int32(targetPort) {
 					if sp.Protocol == proto {
-						endps = getEndpoints(svc, &sp, proto, n.store.GetServiceEndpoints)
+						endps = getEndpoints(svc, &sp, proto, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 						break
 					}
 				}
@@ -334,6 +660,10 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 				Port:          intstr.FromString(svcPort),
 				Protocol:      proto,
 				ProxyProtocol: svcProxyProtocol,
+
+				ProxyResponses:     udpOptions.ProxyResponses,
+				ProxyTimeout:       udpOptions.ProxyTimeout,
+				SessionPersistence: udpOptions.SessionPersistence,
 			},
 			Endpoints: endps,
 			Service:   svc,
@@ -346,6 +676,202 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 	return svcs
 }
 
+var nginxTimeRegex = regexp.MustCompile(`^[1-9]\d*(ms|s|m|h|d)?$`)
+
+// parseUDPOptions parses the options of an UDP service defined in the
+// UDP services ConfigMap after the port, with the format <key>=<value>:
+//   - persistence=client-ip sends the datagrams of a client to the same endpoint
+//   - responses=<number> datagrams expected in response to a client datagram
+//   - timeout=<time> closes the session after the time without activity
+func parseUDPOptions(options []string, backend *ingress.L4Backend) error {
+	for _, option := range options {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid option %q", option)
+		}
+
+		key, value := kv[0], kv[1]
+		switch key {
+		case "persistence":
+			if value != "client-ip" {
+				return fmt.Errorf("invalid persistence %q, only client-ip is supported", value)
+			}
+			backend.SessionPersistence = true
+		case "responses":
+			responses, err := strconv.Atoi(value)
+			if err != nil || responses < 0 {
+				return fmt.Errorf("invalid number of responses %q", value)
+			}
+			backend.ProxyResponses = value
+		case "timeout":
+			if !nginxTimeRegex.MatchString(value) {
+				return fmt.Errorf("invalid timeout %q", value)
+			}
+			backend.ProxyTimeout = value
+		default:
+			return fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	return nil
+}
+
+// reservedPorts returns the ports used by the Ingress controller that
+// cannot be exposed as stream services.
+func (n *NGINXController) reservedPorts() sets.Int {
+	return sets.NewInt(
+		n.cfg.ListenPorts.HTTP,
+		n.cfg.ListenPorts.HTTPS,
+		n.cfg.ListenPorts.SSLProxy,
+		n.cfg.ListenPorts.Health,
+		n.cfg.ListenPorts.Default,
+	)
+}
+
+// splitStreamIngresses separates the Ingresses exposed in a raw TCP port
+// using the tcp-port annotation from the ones configuring HTTP servers.
+func splitStreamIngresses(ingresses []*ingress.Ingress) ([]*ingress.Ingress, []*ingress.Ingress) {
+	var httpIngresses, streamIngresses []*ingress.Ingress
+	for _, ing := range ingresses {
+		if ing.ParsedAnnotations != nil && ing.ParsedAnnotations.TCPPort != 0 {
+			streamIngresses = append(streamIngresses, ing)
+			continue
+		}
+		httpIngresses = append(httpIngresses, ing)
+	}
+
+	return httpIngresses, streamIngresses
+}
+
+// getIngressStreamServices returns the TCP stream services defined by
+// Ingresses with the tcp-port annotation. The TLS connections are terminated
+// by NGINX using the first Secret of the Ingress TLS section, if any.
+// Ports reserved by the controller or already used by the TCP services
+// ConfigMap are skipped.
+func (n *NGINXController) getIngressStreamServices(ingresses []*ingress.Ingress, configured []ingress.L4Service) []ingress.L4Service {
+	usedPorts := n.reservedPorts()
+	for _, svc := range configured {
+		usedPorts.Insert(svc.Port)
+	}
+
+	// sort Ingresses using the CreationTimestamp field so the oldest
+	// Ingress keeps the port in case of conflicts
+	sort.SliceStable(ingresses, func(i, j int) bool {
+		ir := ingresses[i].CreationTimestamp
+		jr := ingresses[j].CreationTimestamp
+		return ir.Before(&jr)
+	})
+
+	var svcs []ingress.L4Service
+	for _, ing := range ingresses {
+		ingKey := k8s.MetaNamespaceKey(ing)
+		externalPort := ing.ParsedAnnotations.TCPPort
+
+		if usedPorts.Has(externalPort) {
+			klog.Warningf("Port %d cannot be used for the TCP stream service of Ingress %q. It is already in use.", externalPort, ingKey)
+			continue
+		}
+
+		backend := ing.Spec.Backend
+		if backend == nil {
+			for _, rule := range ing.Spec.Rules {
+				if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+					backend = &rule.HTTP.Paths[0].Backend
+					break
+				}
+			}
+		}
+		if backend == nil {
+			klog.Warningf("Ingress %q does not define a backend for the TCP stream service in port %d", ingKey, externalPort)
+			continue
+		}
+
+		svcKey := fmt.Sprintf("%v/%v", ing.Namespace, backend.ServiceName)
+		svc, err := n.store.GetService(svcKey)
+		if err != nil {
+			klog.Warningf("Error getting Service %q: %v", svcKey, err)
+			continue
+		}
+
+		endps := n.getServicePortEndpoints(svc, backend.ServicePort, apiv1.ProtocolTCP)
+		// stream services cannot contain empty upstreams and there is
+		// no default backend equivalent
+		if len(endps) == 0 {
+			klog.Warningf("Service %q does not have any active Endpoint for TCP port %v", svcKey, backend.ServicePort.String())
+			continue
+		}
+
+		var sslCert *ingress.SSLCert
+		if len(ing.Spec.TLS) > 0 && ing.Spec.TLS[0].SecretName != "" {
+			secrKey := fmt.Sprintf("%v/%v", ing.Namespace, ing.Spec.TLS[0].SecretName)
+			sslCert, err = n.getStreamSSLCert(secrKey)
+			if err != nil {
+				klog.Warningf("Error getting SSL certificate %q for the TCP stream service of Ingress %q: %v", secrKey, ingKey, err)
+				continue
+			}
+		}
+
+		usedPorts.Insert(externalPort)
+		svcs = append(svcs, ingress.L4Service{
+			Port: externalPort,
+			Backend: ingress.L4Backend{
+				Name:      backend.ServiceName,
+				Namespace: ing.Namespace,
+				Port:      backend.ServicePort,
+				Protocol:  apiv1.ProtocolTCP,
+			},
+			Endpoints: endps,
+			Service:   svc,
+			SSLCert:   sslCert,
+			Snippet:   ing.ParsedAnnotations.StreamSnippet,
+		})
+	}
+
+	return svcs
+}
+
+// getServicePortEndpoints returns the endpoints of the port of a Service,
+// referenced by number or name, with the given protocol.
+func (n *NGINXController) getServicePortEndpoints(svc *apiv1.Service, port intstr.IntOrString, proto apiv1.Protocol) []ingress.Endpoint {
+	for _, sp := range svc.Spec.Ports {
+		if sp.Protocol != proto {
+			continue
+		}
+		if (port.Type == intstr.Int && sp.Port == port.IntVal) ||
+			(port.Type == intstr.String && sp.Name == port.StrVal) {
+			return getEndpoints(svc, &sp, proto, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
+		}
+	}
+
+	return nil
+}
+
+// getStreamSSLCert returns a copy of the certificate of the Secret secrKey
+// to be used by TCP stream services terminating TLS.
+func (n *NGINXController) getStreamSSLCert(secrKey string) (*ingress.SSLCert, error) {
+	cert, err := n.store.GetLocalSSLCert(secrKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sslCert := *cert
+	if n.cfg.DynamicCertificatesEnabled {
+		// NGINX stream servers cannot load certificates using Lua,
+		// the certificate is written to disk before the reload
+		sslCert.PemFileName = streamPemFileName(secrKey)
+		sslCert.PemSHA = file.SHA1Content([]byte(sslCert.PemCertKey))
+	}
+
+	return &sslCert, nil
+}
+
+// streamPemFileName returns the path of the PEM file used by the
+// TCP stream services terminating TLS with the Secret secrKey. The
+// name is a hash of the key, unique for each namespace and name.
+func streamPemFileName(secrKey string) string {
+	return fmt.Sprintf("%v/stream-%v.pem", file.DefaultSSLDirectory, file.SHA1Content([]byte(secrKey)))
+}
+
 // getDefaultUpstream returns the upstream associated with the default backend.
 // Configures the upstream to return HTTP code 503 in case of error.
 func (n *NGINXController) getDefaultUpstream() *ingress.Backend {
@@ -366,7 +892,7 @@ func (n *NGINXController) getDefaultUpstream() *ingress.Backend {
 		return upstream
 	}
 
-	endps := getEndpoints(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+	endps := getEndpoints(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 	if len(endps) == 0 {
 		klog.Warningf("Service %q does not have any active Endpoint", svcKey)
 		endps = []ingress.Endpoint{n.DefaultEndpoint()}
@@ -467,24 +993,41 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						loc.ConfigurationSnippet = anns.ConfigurationSnippet
 						loc.CorsConfig = anns.CorsConfig
 						loc.ExternalAuth = anns.ExternalAuth
+						loc.EnableGlobalAuth = anns.EnableGlobalAuth
 						loc.HTTP2PushPreload = anns.HTTP2PushPreload
 						loc.Proxy = anns.Proxy
 						loc.RateLimit = anns.RateLimit
 						loc.Redirect = anns.Redirect
 						loc.Rewrite = anns.Rewrite
 						loc.UpstreamVhost = anns.UpstreamVhost
+						loc.UpstreamKeepalive = anns.UpstreamKeepalive
 						loc.Whitelist = anns.Whitelist
 						loc.Denied = anns.Denied
 						loc.XForwardedPrefix = anns.XForwardedPrefix
 						loc.UsePortInRedirects = anns.UsePortInRedirects
 						loc.Connection = anns.Connection
 						loc.Logs = anns.Logs
+						loc.Opentracing = anns.Opentracing
 						loc.LuaRestyWAF = anns.LuaRestyWAF
 						loc.InfluxDB = anns.InfluxDB
 						loc.DefaultBackend = anns.DefaultBackend
 						loc.BackendProtocol = anns.BackendProtocol
 						loc.CustomHTTPErrors = anns.CustomHTTPErrors
 						loc.ModSecurity = anns.ModSecurity
+						loc.Plugins = anns.Plugins
+						loc.BodySizeError = anns.BodySizeError
+						loc.BypassIPAccess = anns.BypassIPAccess
+						loc.GlobalRateLimit = anns.GlobalRateLimit
+						loc.JWT = anns.JWT
+						loc.OIDC = anns.OIDC
+						loc.HSTS = anns.HSTS
+						loc.FastCGI = anns.FastCGI
+						loc.CustomHeaders = anns.CustomHeaders
+						loc.ProxySSL = anns.ProxySSL
+						loc.Mirror = anns.Mirror
+						loc.WebSocket = anns.WebSocket
+						loc.GRPCWeb = anns.GRPCWeb
+						loc.DisableCompression = anns.DisableCompression
 						loc.Satisfy = anns.Satisfy
 
 						if loc.Redirect.FromToWWW {
@@ -511,23 +1054,41 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						ConfigurationSnippet: anns.ConfigurationSnippet,
 						CorsConfig:           anns.CorsConfig,
 						ExternalAuth:         anns.ExternalAuth,
+						EnableGlobalAuth:     anns.EnableGlobalAuth,
 						Proxy:                anns.Proxy,
 						RateLimit:            anns.RateLimit,
 						Redirect:             anns.Redirect,
 						Rewrite:              anns.Rewrite,
 						UpstreamVhost:        anns.UpstreamVhost,
+						UpstreamKeepalive:    anns.UpstreamKeepalive,
 						Whitelist:            anns.Whitelist,
 						Denied:               anns.Denied,
 						XForwardedPrefix:     anns.XForwardedPrefix,
 						UsePortInRedirects:   anns.UsePortInRedirects,
 						Connection:           anns.Connection,
 						Logs:                 anns.Logs,
+						Opentracing:          anns.Opentracing,
 						LuaRestyWAF:          anns.LuaRestyWAF,
 						InfluxDB:             anns.InfluxDB,
 						DefaultBackend:       anns.DefaultBackend,
 						BackendProtocol:      anns.BackendProtocol,
 						CustomHTTPErrors:     anns.CustomHTTPErrors,
 						ModSecurity:          anns.ModSecurity,
+						Plugins:              anns.Plugins,
+						BodySizeError:        anns.BodySizeError,
+						BypassIPAccess:       anns.BypassIPAccess,
+						GlobalRateLimit:      anns.GlobalRateLimit,
+						JWT:                  anns.JWT,
+						OIDC:                 anns.OIDC,
+						HSTS:                 anns.HSTS,
+						FastCGI:              anns.FastCGI,
+						CustomHeaders:        anns.CustomHeaders,
+						ProxySSL:             anns.ProxySSL,
+						Mirror:               anns.Mirror,
+						WebSocket:            anns.WebSocket,
+						GRPCWeb:              anns.GRPCWeb,
+						DisableCompression:   anns.DisableCompression,
+						HTTP2PushPreload:     anns.HTTP2PushPreload,
 						Satisfy:              anns.Satisfy,
 					}
 
@@ -552,6 +1113,10 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 					ups.SessionAffinity.CookieSessionAffinity.Expires = anns.SessionAffinity.Cookie.Expires
 					ups.SessionAffinity.CookieSessionAffinity.MaxAge = anns.SessionAffinity.Cookie.MaxAge
 					ups.SessionAffinity.CookieSessionAffinity.Path = cookiePath
+					ups.SessionAffinity.CookieSessionAffinity.Secure = anns.SessionAffinity.Cookie.Secure
+					ups.SessionAffinity.CookieSessionAffinity.SameSite = anns.SessionAffinity.Cookie.SameSite
+					ups.SessionAffinity.CookieSessionAffinity.Domain = anns.SessionAffinity.Cookie.Domain
+					ups.SessionAffinity.CookieSessionAffinity.ChangeOnFailure = anns.SessionAffinity.Cookie.ChangeOnFailure
 
 					locs := ups.SessionAffinity.CookieSessionAffinity.Locations
 					if _, ok := locs[host]; !ok {
@@ -584,7 +1149,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 			for _, location := range server.Locations {
 				if shouldCreateUpstreamForLocationDefaultBackend(upstream, location) {
 					sp := location.DefaultBackend.Spec.Ports[0]
-					endps := getEndpoints(location.DefaultBackend, &sp, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+					endps := getEndpoints(location.DefaultBackend, &sp, apiv1.ProtocolTCP, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 					if len(endps) > 0 {
 
 						name := fmt.Sprintf("custom-default-backend-%v", location.DefaultBackend.GetName())
@@ -600,6 +1165,11 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 							klog.V(3).Infof("Upstream %q has no active Endpoint, so using custom default backend for location %q in server %q (Service \"%v/%v\")",
 								upstream.Name, location.Path, server.Hostname, location.DefaultBackend.Namespace, location.DefaultBackend.Name)
 
+							location.Backend = name
+						} else if location.IsDefBackend {
+							klog.V(3).Infof("Using custom default backend for the paths without a rule in server %q (Service \"%v/%v\")",
+								server.Hostname, location.DefaultBackend.Namespace, location.DefaultBackend.Name)
+
 							location.Backend = name
 						}
 					}
@@ -626,13 +1196,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 
 	aServers := make([]*ingress.Server, 0, len(servers))
 	for _, value := range servers {
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return value.Locations[i].Path > value.Locations[j].Path
-		})
-
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return len(value.Locations[i].Path) > len(value.Locations[j].Path)
-		})
+		sortLocations(value.Locations)
 		aServers = append(aServers, value)
 	}
 
@@ -841,7 +1405,7 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 			servicePort.TargetPort.String() == backendPort ||
 			servicePort.Name == backendPort {
 
-			endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+			endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 			if len(endps) == 0 {
 				klog.Warningf("Service %q does not have any active Endpoint.", svcKey)
 			}
@@ -864,7 +1428,30 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 			Port:       int32(externalPort),
 			TargetPort: intstr.FromString(backendPort),
 		}
-		endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+		endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
+		if len(endps) == 0 {
+			klog.Warningf("Service %q does not have any active Endpoint.", svcKey)
+			return upstreams, nil
+		}
+
+		upstreams = append(upstreams, endps...)
+		return upstreams, nil
+	}
+
+	// Ingress with a headless Service and no port defined for that Service
+	if len(svc.Spec.Ports) == 0 && svc.Spec.ClusterIP == apiv1.ClusterIPNone {
+		port, err := strconv.Atoi(backendPort)
+		if err != nil {
+			klog.Warningf("Only numeric ports are allowed in headless Services without ports: %q is not a valid port number.", backendPort)
+			return upstreams, nil
+		}
+
+		servicePort := apiv1.ServicePort{
+			Protocol:   apiv1.ProtocolTCP,
+			Port:       int32(port),
+			TargetPort: intstr.FromInt(port),
+		}
+		endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.cfg.PublishNotReadyEndpoints, n.store.GetServiceEndpoints)
 		if len(endps) == 0 {
 			klog.Warningf("Service %q does not have any active Endpoint.", svcKey)
 			return upstreams, nil
@@ -889,19 +1476,21 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 
 	bdef := n.store.GetDefaultBackend()
 	ngxProxy := proxy.Config{
-		BodySize:          bdef.ProxyBodySize,
-		ConnectTimeout:    bdef.ProxyConnectTimeout,
-		SendTimeout:       bdef.ProxySendTimeout,
-		ReadTimeout:       bdef.ProxyReadTimeout,
-		BuffersNumber:     bdef.ProxyBuffersNumber,
-		BufferSize:        bdef.ProxyBufferSize,
-		CookieDomain:      bdef.ProxyCookieDomain,
-		CookiePath:        bdef.ProxyCookiePath,
-		NextUpstream:      bdef.ProxyNextUpstream,
-		NextUpstreamTries: bdef.ProxyNextUpstreamTries,
-		RequestBuffering:  bdef.ProxyRequestBuffering,
-		ProxyRedirectFrom: bdef.ProxyRedirectFrom,
-		ProxyBuffering:    bdef.ProxyBuffering,
+		BodySize:            bdef.ProxyBodySize,
+		ConnectTimeout:      bdef.ProxyConnectTimeout,
+		SendTimeout:         bdef.ProxySendTimeout,
+		ReadTimeout:         bdef.ProxyReadTimeout,
+		BuffersNumber:       bdef.ProxyBuffersNumber,
+		BufferSize:          bdef.ProxyBufferSize,
+		CookieDomain:        bdef.ProxyCookieDomain,
+		CookiePath:          bdef.ProxyCookiePath,
+		NextUpstream:        bdef.ProxyNextUpstream,
+		NextUpstreamTries:   bdef.ProxyNextUpstreamTries,
+		NextUpstreamTimeout: bdef.ProxyNextUpstreamTimeout,
+		RequestBuffering:    bdef.ProxyRequestBuffering,
+		ProxyRedirectFrom:   bdef.ProxyRedirectFrom,
+		ProxyBuffering:      bdef.ProxyBuffering,
+		MaxTempFileSize:     bdef.ProxyMaxTempFileSize,
 	}
 
 	// generated on Start() with createDefaultSSLCertificate()
@@ -918,6 +1507,8 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 	// initialize default server and root location
 	servers[defServerName] = &ingress.Server{
 		Hostname: defServerName,
+		UseHTTP2: n.store.GetBackendConfiguration().UseHTTP2,
+		UseHTTP3: n.store.GetBackendConfiguration().UseHTTP3,
 		SSLCert: ingress.SSLCert{
 			PemFileName: defaultPemFileName,
 			PemSHA:      defaultPemSHA,
@@ -933,6 +1524,12 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 					Access:  n.store.GetBackendConfiguration().EnableAccessLogForDefaultBackend,
 					Rewrite: false,
 				},
+				HSTS: hsts.Config{
+					Enabled:           bdef.HSTS,
+					MaxAge:            bdef.HSTSMaxAge,
+					IncludeSubdomains: bdef.HSTSIncludeSubdomains,
+					Preload:           bdef.HSTSPreload,
+				},
 			},
 		}}
 
@@ -969,23 +1566,40 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 
 					// customize using Ingress annotations
 					defLoc.Logs = anns.Logs
+					defLoc.Opentracing = anns.Opentracing
 					defLoc.BasicDigestAuth = anns.BasicDigestAuth
 					defLoc.ClientBodyBufferSize = anns.ClientBodyBufferSize
 					defLoc.ConfigurationSnippet = anns.ConfigurationSnippet
 					defLoc.CorsConfig = anns.CorsConfig
 					defLoc.ExternalAuth = anns.ExternalAuth
+					defLoc.EnableGlobalAuth = anns.EnableGlobalAuth
 					defLoc.Proxy = anns.Proxy
 					defLoc.RateLimit = anns.RateLimit
 					// TODO: Redirect and rewrite can affect the catch all behavior, skip for now
 					// defLoc.Redirect = anns.Redirect
 					// defLoc.Rewrite = anns.Rewrite
 					defLoc.UpstreamVhost = anns.UpstreamVhost
+					defLoc.UpstreamKeepalive = anns.UpstreamKeepalive
 					defLoc.Whitelist = anns.Whitelist
 					defLoc.Denied = anns.Denied
 					defLoc.LuaRestyWAF = anns.LuaRestyWAF
 					defLoc.InfluxDB = anns.InfluxDB
 					defLoc.BackendProtocol = anns.BackendProtocol
 					defLoc.ModSecurity = anns.ModSecurity
+					defLoc.Plugins = anns.Plugins
+					defLoc.BodySizeError = anns.BodySizeError
+					defLoc.BypassIPAccess = anns.BypassIPAccess
+					defLoc.GlobalRateLimit = anns.GlobalRateLimit
+					defLoc.JWT = anns.JWT
+					defLoc.OIDC = anns.OIDC
+					defLoc.HSTS = anns.HSTS
+					defLoc.FastCGI = anns.FastCGI
+					defLoc.CustomHeaders = anns.CustomHeaders
+					defLoc.ProxySSL = anns.ProxySSL
+					defLoc.Mirror = anns.Mirror
+					defLoc.WebSocket = anns.WebSocket
+					defLoc.GRPCWeb = anns.GRPCWeb
+					defLoc.DisableCompression = anns.DisableCompression
 				} else {
 					klog.V(3).Infof("Ingress %q defines both a backend and rules. Using its backend as default upstream for all its rules.",
 						ingKey)
@@ -1016,27 +1630,48 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 						ConfigurationSnippet: anns.ConfigurationSnippet,
 						CorsConfig:           anns.CorsConfig,
 						ExternalAuth:         anns.ExternalAuth,
+						EnableGlobalAuth:     anns.EnableGlobalAuth,
 						Proxy:                anns.Proxy,
 						RateLimit:            anns.RateLimit,
 						Redirect:             anns.Redirect,
 						Rewrite:              anns.Rewrite,
 						UpstreamVhost:        anns.UpstreamVhost,
+						UpstreamKeepalive:    anns.UpstreamKeepalive,
 						Whitelist:            anns.Whitelist,
 						Denied:               anns.Denied,
 						XForwardedPrefix:     anns.XForwardedPrefix,
 						UsePortInRedirects:   anns.UsePortInRedirects,
 						Connection:           anns.Connection,
 						Logs:                 anns.Logs,
+						Opentracing:          anns.Opentracing,
 						LuaRestyWAF:          anns.LuaRestyWAF,
 						InfluxDB:             anns.InfluxDB,
 						DefaultBackend:       anns.DefaultBackend,
 						BackendProtocol:      anns.BackendProtocol,
 						CustomHTTPErrors:     anns.CustomHTTPErrors,
 						ModSecurity:          anns.ModSecurity,
+						Plugins:              anns.Plugins,
+						BodySizeError:        anns.BodySizeError,
+						BypassIPAccess:       anns.BypassIPAccess,
+						GlobalRateLimit:      anns.GlobalRateLimit,
+						JWT:                  anns.JWT,
+						OIDC:                 anns.OIDC,
+						HSTS:                 anns.HSTS,
+						FastCGI:              anns.FastCGI,
+						CustomHeaders:        anns.CustomHeaders,
+						ProxySSL:             anns.ProxySSL,
+						Mirror:               anns.Mirror,
+						WebSocket:            anns.WebSocket,
+						GRPCWeb:              anns.GRPCWeb,
+						DisableCompression:   anns.DisableCompression,
 					},
 				},
-				SSLPassthrough: anns.SSLPassthrough,
-				SSLCiphers:     anns.SSLCiphers,
+				SSLPassthrough:              anns.SSLPassthrough,
+				SSLPassthroughProxyProtocol: anns.ProxyProtocol,
+				SSLCiphers:                  anns.SSLCiphers,
+				SSLProtocols:                anns.SSLProtocols,
+				UseHTTP2:                    n.store.GetBackendConfiguration().UseHTTP2,
+				UseHTTP3:                    n.store.GetBackendConfiguration().UseHTTP3,
 			}
 		}
 	}
@@ -1078,11 +1713,21 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				}
 			}
 
+			// any Ingress that overrides the global HTTP/3 setting configures the server
+			if anns.UseHTTP3 != n.store.GetBackendConfiguration().UseHTTP3 {
+				servers[host].UseHTTP3 = anns.UseHTTP3
+			}
+
 			// only add SSL ciphers if the server does not have them previously configured
 			if servers[host].SSLCiphers == "" && anns.SSLCiphers != "" {
 				servers[host].SSLCiphers = anns.SSLCiphers
 			}
 
+			// only add SSL protocols if the server does not have them previously configured
+			if servers[host].SSLProtocols == "" && anns.SSLProtocols != "" {
+				servers[host].SSLProtocols = anns.SSLProtocols
+			}
+
 			// only add a certificate if the server does not have one previously configured
 			if servers[host].SSLCert.PemFileName != "" {
 				continue
@@ -1139,6 +1784,29 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 			if cert.ExpireTime.Before(time.Now().Add(240 * time.Hour)) {
 				klog.Warningf("SSL certificate for server %q is about to expire (%v)", host, cert.ExpireTime)
 			}
+
+			// a second TLS section for the same host can provide a certificate
+			// with a different key type, served to clients that support it
+			additionalCert := extractAdditionalSSLCert(host, tlsSecretName, cert, ing, n.store.GetLocalSSLCert)
+			if additionalCert == nil {
+				continue
+			}
+
+			if n.cfg.DynamicCertificatesEnabled {
+				additionalCert.PemFileName = defaultPemFileName
+				additionalCert.PemSHA = defaultPemSHA
+			}
+
+			if cert.Certificate.PublicKeyAlgorithm == x509.ECDSA {
+				servers[host].SSLCert = *additionalCert
+				servers[host].SSLCertECDSA = *cert
+			} else {
+				servers[host].SSLCertECDSA = *additionalCert
+			}
+
+			if additionalCert.ExpireTime.Before(time.Now().Add(240 * time.Hour)) {
+				klog.Warningf("SSL certificate for server %q is about to expire (%v)", host, additionalCert.ExpireTime)
+			}
 		}
 	}
 
@@ -1302,6 +1970,67 @@ func extractTLSSecretName(host string, ing *ingress.Ingress,
 	return ""
 }
 
+// extractAdditionalSSLCert returns the certificate of a TLS section of the Ingress,
+// other than the one of the secret tlsSecretName, listing the host name and using
+// a key type different from the one of cert, so an RSA and an ECDSA certificate
+// can be configured for the same server.
+func extractAdditionalSSLCert(host, tlsSecretName string, cert *ingress.SSLCert, ing *ingress.Ingress,
+	getLocalSSLCert func(string) (*ingress.SSLCert, error)) *ingress.SSLCert {
+
+	if ing == nil || cert == nil || cert.Certificate == nil {
+		return nil
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		if tls.SecretName == "" || tls.SecretName == tlsSecretName {
+			continue
+		}
+
+		if !sets.NewString(tls.Hosts...).Has(host) {
+			continue
+		}
+
+		secrKey := fmt.Sprintf("%v/%v", ing.Namespace, tls.SecretName)
+
+		additionalCert, err := getLocalSSLCert(secrKey)
+		if err != nil {
+			klog.Warningf("Error getting SSL certificate %q: %v", secrKey, err)
+			continue
+		}
+
+		if additionalCert == nil || additionalCert.Certificate == nil {
+			continue
+		}
+
+		if !isDualCertificatePair(cert.Certificate, additionalCert.Certificate) {
+			klog.Warningf("Ignoring SSL certificate %q for server %q: only an RSA and an ECDSA certificate can be configured for the same host",
+				secrKey, host)
+			continue
+		}
+
+		err = additionalCert.Certificate.VerifyHostname(host)
+		if err != nil {
+			err = verifyHostname(host, additionalCert.Certificate)
+		}
+		if err != nil {
+			klog.Warningf("SSL certificate %q does not contain a Common Name or Subject Alternative Name for server %q: %v",
+				secrKey, host, err)
+			continue
+		}
+
+		klog.V(3).Infof("Found additional SSL certificate for host %q: %q", host, secrKey)
+		return additionalCert
+	}
+
+	return nil
+}
+
+// isDualCertificatePair checks if one of the certificates uses an RSA key and the other an ECDSA key
+func isDualCertificatePair(c1, c2 *x509.Certificate) bool {
+	return (c1.PublicKeyAlgorithm == x509.RSA && c2.PublicKeyAlgorithm == x509.ECDSA) ||
+		(c1.PublicKeyAlgorithm == x509.ECDSA && c2.PublicKeyAlgorithm == x509.RSA)
+}
+
 // getRemovedHosts returns a list of the hostsnames
 // that are not associated anymore to the NGINX configuration.
 func getRemovedHosts(rucfg, newcfg *ingress.Configuration) []string {
@@ -1356,9 +2085,53 @@ func getRemovedIngresses(rucfg, newcfg *ingress.Configuration) []string {
 	return oldIngresses.Difference(newIngresses).List()
 }
 
-// checks conditions for whether or not an upstream should be created for a custom default backend
+// checks conditions for whether or not an upstream should be created for a custom default backend.
+// The location of the paths without a rule in the Ingress always uses the custom default backend
 func shouldCreateUpstreamForLocationDefaultBackend(upstream *ingress.Backend, location *ingress.Location) bool {
 	return (upstream.Name == location.Backend) &&
-		(len(upstream.Endpoints) == 0 || len(location.CustomHTTPErrors) != 0) &&
-		location.DefaultBackend != nil
+		(len(upstream.Endpoints) == 0 || len(location.CustomHTTPErrors) != 0 || location.IsDefBackend) &&
+		location.DefaultBackend != nil &&
+		len(location.DefaultBackend.Spec.Ports) != 0
+}
+
+// sortLocations sorts the locations of a server from the longest path to the
+// shortest one, the order used by NGINX to evaluate the regular expressions.
+// Locations with paths of the same length place the prefix paths before the
+// paths of Ingresses using regular expressions. In servers where no path is
+// rendered as a regular expression, the prefix paths are placed first and
+// the root path, which matches every request, last.
+func sortLocations(locations []*ingress.Location) {
+	enforceRegex := ngx_template.EnforceRegex(locations)
+
+	sort.SliceStable(locations, func(i, j int) bool {
+		if !enforceRegex {
+			ti, tj := locationType(locations[i]), locationType(locations[j])
+			if ti != tj {
+				return ti < tj
+			}
+		}
+
+		if len(locations[i].Path) != len(locations[j].Path) {
+			return len(locations[i].Path) > len(locations[j].Path)
+		}
+
+		if locations[i].Rewrite.UseRegex != locations[j].Rewrite.UseRegex {
+			return !locations[i].Rewrite.UseRegex
+		}
+
+		return locations[i].Path > locations[j].Path
+	})
+}
+
+// locationType returns the position of the location type in the order of
+// the locations of a server
+func locationType(location *ingress.Location) int {
+	switch {
+	case location.Path == rootLocation:
+		return 2
+	case location.Rewrite.UseRegex:
+		return 1
+	default:
+		return 0
+	}
 }
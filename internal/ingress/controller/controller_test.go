@@ -21,6 +21,8 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"testing"
@@ -31,10 +33,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	"k8s.io/ingress-nginx/internal/k8s"
@@ -598,6 +602,108 @@ func TestExtractTLSSecretName(t *testing.T) {
 	}
 }
 
+func TestExtractAdditionalSSLCert(t *testing.T) {
+	rsaCert := &ingress.SSLCert{
+		Certificate: fakeX509CertWithKeyType([]string{"foo.bar"}, x509.RSA),
+	}
+	ecdsaCert := &ingress.SSLCert{
+		Certificate: fakeX509CertWithKeyType([]string{"foo.bar"}, x509.ECDSA),
+	}
+	otherHostECDSACert := &ingress.SSLCert{
+		Certificate: fakeX509CertWithKeyType([]string{"example.com"}, x509.ECDSA),
+	}
+
+	newIngress := func(tls ...extensions.IngressTLS) *ingress.Ingress {
+		return &ingress.Ingress{
+			Ingress: extensions.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: extensions.IngressSpec{
+					TLS: tls,
+				},
+			},
+		}
+	}
+
+	certs := map[string]*ingress.SSLCert{
+		"default/rsa":        rsaCert,
+		"default/ecdsa":      ecdsaCert,
+		"default/other-host": otherHostECDSACert,
+	}
+	getLocalSSLCert := func(name string) (*ingress.SSLCert, error) {
+		cert, ok := certs[name]
+		if !ok {
+			return nil, fmt.Errorf("certificate %v not found", name)
+		}
+		return cert, nil
+	}
+
+	testCases := map[string]struct {
+		ingress      *ingress.Ingress
+		secretName   string
+		cert         *ingress.SSLCert
+		expectedCert *ingress.SSLCert
+	}{
+		"single tls section": {
+			newIngress(extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"}),
+			"rsa", rsaCert, nil,
+		},
+		"rsa and ecdsa certificates": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "ecdsa"},
+			),
+			"rsa", rsaCert, ecdsaCert,
+		},
+		"ecdsa and rsa certificates": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "ecdsa"},
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+			),
+			"ecdsa", ecdsaCert, rsaCert,
+		},
+		"same key type": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+			),
+			"rsa", rsaCert, nil,
+		},
+		"second tls section for another host": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+				extensions.IngressTLS{Hosts: []string{"example.com"}, SecretName: "ecdsa"},
+			),
+			"rsa", rsaCert, nil,
+		},
+		"certificate not valid for the host": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "other-host"},
+			),
+			"rsa", rsaCert, nil,
+		},
+		"missing secret": {
+			newIngress(
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "rsa"},
+				extensions.IngressTLS{Hosts: []string{"foo.bar"}, SecretName: "missing"},
+			),
+			"rsa", rsaCert, nil,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			cert := extractAdditionalSSLCert("foo.bar", tc.secretName, tc.cert, tc.ingress, getLocalSSLCert)
+			if cert != tc.expectedCert {
+				t.Errorf("Expected additional certificate %v but returned %v", tc.expectedCert, cert)
+			}
+		})
+	}
+}
+
 func TestGetBackendServers(t *testing.T) {
 	ctl := newNGINXController(t)
 
@@ -910,13 +1016,16 @@ func newNGINXController(t *testing.T) *NGINXController {
 		fmt.Sprintf("%v/tcp", ns),
 		fmt.Sprintf("%v/udp", ns),
 		"",
+		"",
 		10*time.Minute,
 		clientSet,
+		nil,
 		fs,
 		channels.NewRingChannel(10),
 		false,
 		pod,
-		false)
+		false,
+		"")
 
 	config := &Configuration{
 		ListenPorts: &ngx_config.ListenPorts{
@@ -940,3 +1049,251 @@ func fakeX509Cert(dnsNames []string) *x509.Certificate {
 		},
 	}
 }
+
+func fakeX509CertWithKeyType(dnsNames []string, keyType x509.PublicKeyAlgorithm) *x509.Certificate {
+	cert := fakeX509Cert(dnsNames)
+	cert.PublicKeyAlgorithm = keyType
+	return cert
+}
+
+func TestParseIPAccessList(t *testing.T) {
+	testCases := map[string]struct {
+		list            string
+		expected        []string
+		expectedInvalid []string
+	}{
+		"empty list":        {"", []string{}, []string{}},
+		"CIDRs and address": {"192.168.0.0/16, 10.0.0.1,10.0.0.0/8", []string{"10.0.0.0/8", "10.0.0.1", "192.168.0.0/16"}, []string{}},
+		"invalid entries":   {"10.0.0.0/8,invalid,10.0.0.0/33", []string{"10.0.0.0/8"}, []string{"invalid", "10.0.0.0/33"}},
+		"IPv6 entries":      {"2001:db8::/32,2001:db8::1", []string{"2001:db8::/32", "2001:db8::1"}, []string{}},
+		"duplicated CIDRs":  {"10.1.0.0/8,10.0.0.0/8", []string{"10.0.0.0/8"}, []string{}},
+	}
+
+	for name, tc := range testCases {
+		cidrs, invalid := parseIPAccessList(tc.list)
+		if !reflect.DeepEqual(cidrs, tc.expected) {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, cidrs)
+		}
+		if !reflect.DeepEqual(invalid, tc.expectedInvalid) {
+			t.Errorf("%v: expected the invalid entries %v but returned %v", name, tc.expectedInvalid, invalid)
+		}
+	}
+}
+
+func TestReportInvalidIPAccess(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "config"},
+		Data:       map[string]string{"deny": "10.0.0.0/8,2001:db8::/32,invalid"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{
+		cfg:      &Configuration{IPAccessConfigMapName: "default/config"},
+		store:    &fakeIPAccessStore{configmap: configmap},
+		recorder: recorder,
+	}
+
+	n.reportInvalidIPAccess()
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event for the invalid entry but %v were recorded", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "invalid") || strings.Contains(event, "2001:db8::/32") {
+		t.Errorf("expected an event about the invalid entry only but got %q", event)
+	}
+
+	n.reportInvalidIPAccess()
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event for entries already reported but %v were recorded", len(recorder.Events))
+	}
+
+	configmap.Data["allow"] = "10.0.0.0/33"
+	n.reportInvalidIPAccess()
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected one event for the new invalid entry but %v were recorded", len(recorder.Events))
+	}
+}
+
+// fakeIPAccessStore returns the same ConfigMap for any name
+type fakeIPAccessStore struct {
+	store.Storer
+	configmap *v1.ConfigMap
+}
+
+func (s *fakeIPAccessStore) GetConfigMap(string) (*v1.ConfigMap, error) {
+	return s.configmap, nil
+}
+
+func TestSplitStreamIngresses(t *testing.T) {
+	httpIng := &ingress.Ingress{
+		Ingress:           extensions.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "http"}},
+		ParsedAnnotations: &annotations.Ingress{},
+	}
+	streamIng := &ingress.Ingress{
+		Ingress:           extensions.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "stream"}},
+		ParsedAnnotations: &annotations.Ingress{TCPPort: 5432},
+	}
+
+	httpIngs, streamIngs := splitStreamIngresses([]*ingress.Ingress{httpIng, streamIng})
+	if len(httpIngs) != 1 || httpIngs[0] != httpIng {
+		t.Errorf("expected only the Ingress %q to configure HTTP servers but got %v", httpIng.Name, httpIngs)
+	}
+	if len(streamIngs) != 1 || streamIngs[0] != streamIng {
+		t.Errorf("expected only the Ingress %q to configure stream services but got %v", streamIng.Name, streamIngs)
+	}
+}
+
+func TestParseUDPOptions(t *testing.T) {
+	testCases := map[string]struct {
+		options   []string
+		expected  ingress.L4Backend
+		expectErr bool
+	}{
+		"no options":  {[]string{}, ingress.L4Backend{}, false},
+		"persistence": {[]string{"persistence=client-ip"}, ingress.L4Backend{SessionPersistence: true}, false},
+		"all options": {
+			[]string{"persistence=client-ip", "responses=0", "timeout=30s"},
+			ingress.L4Backend{SessionPersistence: true, ProxyResponses: "0", ProxyTimeout: "30s"},
+			false,
+		},
+		"invalid persistence": {[]string{"persistence=cookie"}, ingress.L4Backend{}, true},
+		"invalid responses":   {[]string{"responses=-1"}, ingress.L4Backend{}, true},
+		"invalid timeout":     {[]string{"timeout=30x"}, ingress.L4Backend{}, true},
+		"unknown option":      {[]string{"foo=bar"}, ingress.L4Backend{}, true},
+		"PROXY field":         {[]string{"PROXY"}, ingress.L4Backend{}, true},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			backend := ingress.L4Backend{}
+			err := parseUDPOptions(tc.options, &backend)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error parsing %v", tc.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing %v: %v", tc.options, err)
+			}
+			if !(&backend).Equal(&tc.expected) {
+				t.Errorf("expected %+v but returned %+v", tc.expected, backend)
+			}
+		})
+	}
+}
+
+func TestShouldCreateUpstreamForLocationDefaultBackend(t *testing.T) {
+	customBackend := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "custom-backend"},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{Port: 80}},
+		},
+	}
+	endpoints := []ingress.Endpoint{{Address: "10.0.0.1", Port: "8080"}}
+
+	testCases := map[string]struct {
+		upstream *ingress.Backend
+		location *ingress.Location
+		expected bool
+	}{
+		"without a custom default backend": {
+			&ingress.Backend{Name: "upstream-default-backend", Endpoints: endpoints},
+			&ingress.Location{Backend: "upstream-default-backend", IsDefBackend: true},
+			false,
+		},
+		"path without a rule": {
+			&ingress.Backend{Name: "upstream-default-backend", Endpoints: endpoints},
+			&ingress.Location{Backend: "upstream-default-backend", IsDefBackend: true, DefaultBackend: customBackend},
+			true,
+		},
+		"upstream with endpoints": {
+			&ingress.Backend{Name: "default-app-80", Endpoints: endpoints},
+			&ingress.Location{Backend: "default-app-80", DefaultBackend: customBackend},
+			false,
+		},
+		"upstream without endpoints": {
+			&ingress.Backend{Name: "default-app-80"},
+			&ingress.Location{Backend: "default-app-80", DefaultBackend: customBackend},
+			true,
+		},
+		"custom error pages": {
+			&ingress.Backend{Name: "default-app-80", Endpoints: endpoints},
+			&ingress.Location{Backend: "default-app-80", DefaultBackend: customBackend, CustomHTTPErrors: []int{404}},
+			true,
+		},
+		"custom default backend without ports": {
+			&ingress.Backend{Name: "default-app-80"},
+			&ingress.Location{Backend: "default-app-80", DefaultBackend: &v1.Service{}},
+			false,
+		},
+		"location of a different upstream": {
+			&ingress.Backend{Name: "default-other-80"},
+			&ingress.Location{Backend: "default-app-80", DefaultBackend: customBackend},
+			false,
+		},
+	}
+
+	for name, tc := range testCases {
+		result := shouldCreateUpstreamForLocationDefaultBackend(tc.upstream, tc.location)
+		if result != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, result)
+		}
+	}
+}
+
+func TestSortLocations(t *testing.T) {
+	locations := []*ingress.Location{
+		{Path: "/"},
+		{Path: "/api/(.*)", Rewrite: rewrite.Config{UseRegex: true}},
+		{Path: "/api"},
+		{Path: "/api/v1/.*", Rewrite: rewrite.Config{UseRegex: true}},
+		{Path: "/api/users"},
+		{Path: "/web"},
+	}
+
+	sortLocations(locations)
+
+	expected := []string{"/api/users", "/api/v1/.*", "/api/(.*)", "/web", "/api", "/"}
+	paths := []string{}
+	for _, location := range locations {
+		paths = append(paths, location.Path)
+	}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v but returned %v", expected, paths)
+	}
+
+	locations = []*ingress.Location{
+		{Path: "/"},
+		{Path: "/api"},
+		{Path: "/api/users"},
+		{Path: "/web"},
+	}
+
+	sortLocations(locations)
+
+	expected = []string{"/api/users", "/web", "/api", "/"}
+	paths = []string{}
+	for _, location := range locations {
+		paths = append(paths, location.Path)
+	}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v but returned %v", expected, paths)
+	}
+}
+
+func TestStreamPemFileName(t *testing.T) {
+	names := map[string]string{}
+	for _, key := range []string{"a-b/c", "a/b-c", "a/b", "a/b-c"} {
+		name := streamPemFileName(key)
+		if other, ok := names[name]; ok && other != key {
+			t.Errorf("expected a unique file for the Secret %v but %v is used by %v", key, name, other)
+		}
+		names[name] = key
+	}
+
+	if streamPemFileName("a/b") != streamPemFileName("a/b") {
+		t.Errorf("expected the same file for the same Secret")
+	}
+}
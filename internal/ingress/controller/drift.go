@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"sync/atomic"
+
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+const (
+	driftSourceFile     = "file"
+	driftSourceBackends = "backends"
+)
+
+// appliedState contains the checksums of the NGINX configuration file and
+// of the dynamic backends applied by the last successful synchronization.
+type appliedState struct {
+	config   string
+	backends string
+}
+
+// setAppliedConfig records the checksum of the NGINX configuration file
+// successfully reloaded. The caller must hold appliedLock.
+func (n *NGINXController) setAppliedConfig(content []byte) {
+	n.applied.config = checksum(content)
+}
+
+// setAppliedBackends records the checksum of the dynamic backends
+// successfully applied. The caller must hold appliedLock.
+func (n *NGINXController) setAppliedBackends() {
+	statusCode, body, err := nginx.NewGetStatusRequest("/configuration/backends")
+	if err != nil || statusCode != http.StatusOK {
+		klog.Warningf("Unable to read the dynamic configuration of backends (status %v): %v", statusCode, err)
+		return
+	}
+
+	n.applied.backends = checksum(body)
+}
+
+// checkConfigurationDrift compares the NGINX configuration file in path and
+// dynamic backends against the state applied by the controller. In case of
+// divergence (e.g. manual changes inside the pod) a synchronization that
+// re-applies the desired configuration is forced.
+func (n *NGINXController) checkConfigurationDrift(path string) {
+	sources := n.configurationDrift(path)
+	if len(sources) == 0 {
+		return
+	}
+
+	for _, source := range sources {
+		n.metricCollector.IncConfigDriftCount(source)
+	}
+
+	klog.Infof("Re-applying the desired configuration")
+	atomic.StoreInt32(&n.forceSync, 1)
+	n.syncQueue.EnqueueTask(task.GetDummyObject("configuration-drift"))
+}
+
+// configurationDrift returns the sources of the running configuration
+// that differ from the state applied by the controller. The lock is held
+// during the comparison, a synchronization cannot apply a new state in
+// the middle of it.
+func (n *NGINXController) configurationDrift(path string) []string {
+	n.appliedLock.Lock()
+	defer n.appliedLock.Unlock()
+
+	sources := []string{}
+
+	if n.applied.config != "" && file.SHA1(path) != n.applied.config {
+		klog.Warningf("Configuration drift detected: %v differs from the configuration applied by the controller", path)
+		sources = append(sources, driftSourceFile)
+	}
+
+	if n.applied.backends != "" {
+		statusCode, body, err := nginx.NewGetStatusRequest("/configuration/backends")
+		if err != nil || statusCode != http.StatusOK {
+			klog.Warningf("Unable to read the dynamic configuration of backends (status %v): %v", statusCode, err)
+		} else if checksum(body) != n.applied.backends {
+			klog.Warningf("Configuration drift detected: dynamic backends differ from the ones applied by the controller")
+			sources = append(sources, driftSourceBackends)
+		}
+	}
+
+	return sources
+}
+
+func checksum(b []byte) string {
+	hasher := sha1.New()
+	hasher.Write(b)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
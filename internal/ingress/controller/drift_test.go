@@ -0,0 +1,266 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// driftCollector records the configuration drifts reported to the metrics
+type driftCollector struct {
+	metric.DummyCollector
+
+	lock    sync.Mutex
+	sources []string
+}
+
+func (c *driftCollector) IncConfigDriftCount(source string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sources = append(c.sources, source)
+}
+
+func TestConfigurationDrift(t *testing.T) {
+	listener, err := net.Listen("unix", nginx.StatusSocket)
+	if err != nil {
+		t.Fatalf("creating unix listener: %s", err)
+	}
+	defer listener.Close()
+	defer os.Remove(nginx.StatusSocket)
+
+	var lock sync.Mutex
+	backends := []byte(`[{"name":"default-app-80"}]`)
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+				w.WriteHeader(http.StatusOK)
+				w.Write(backends)
+			}),
+		},
+	}
+	defer server.Close()
+	server.Start()
+
+	cfg, err := ioutil.TempFile("", "nginx-conf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(cfg.Name())
+	cfg.Close()
+
+	content := []byte("events {}")
+	err = ioutil.WriteFile(cfg.Name(), content, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := &NGINXController{
+		appliedLock: &sync.Mutex{},
+	}
+
+	if sources := n.configurationDrift(cfg.Name()); len(sources) != 0 {
+		t.Errorf("expected no drift before the first synchronization but %v returned", sources)
+	}
+
+	n.appliedLock.Lock()
+	n.setAppliedConfig(content)
+	n.setAppliedBackends()
+	n.appliedLock.Unlock()
+
+	if sources := n.configurationDrift(cfg.Name()); len(sources) != 0 {
+		t.Errorf("expected no drift after applying the configuration but %v returned", sources)
+	}
+
+	err = ioutil.WriteFile(cfg.Name(), []byte("events { worker_connections 1; }"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock.Lock()
+	backends = []byte(`[]`)
+	lock.Unlock()
+
+	expected := []string{driftSourceFile, driftSourceBackends}
+	if sources := n.configurationDrift(cfg.Name()); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("expected drift of %v but %v returned", expected, sources)
+	}
+}
+
+func TestConfigurationDriftWaitsForSync(t *testing.T) {
+	cfg, err := ioutil.TempFile("", "nginx-conf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(cfg.Name())
+	cfg.Close()
+
+	n := &NGINXController{
+		appliedLock: &sync.Mutex{},
+	}
+	n.setAppliedConfig([]byte("events {}"))
+
+	// a synchronization writing a new configuration file holds the lock
+	// until the checksum of the new file is recorded
+	n.appliedLock.Lock()
+	err = ioutil.WriteFile(cfg.Name(), []byte("events { worker_connections 1; }"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan []string)
+	go func() {
+		done <- n.configurationDrift(cfg.Name())
+	}()
+
+	n.setAppliedConfig([]byte("events { worker_connections 1; }"))
+	n.appliedLock.Unlock()
+
+	if sources := <-done; len(sources) != 0 {
+		t.Errorf("expected no drift during a synchronization but %v returned", sources)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	cfg, err := ioutil.TempFile("", "nginx-conf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(cfg.Name())
+
+	content := []byte("events {}")
+	if _, err := cfg.Write(content); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Close()
+
+	// the applied configuration is compared with the checksum of the file
+	if checksum(content) != file.SHA1(cfg.Name()) {
+		t.Errorf("expected the checksum of the content %v to be the one of the file %v", checksum(content), file.SHA1(cfg.Name()))
+	}
+
+	if checksum(content) == checksum([]byte("events { worker_connections 1; }")) {
+		t.Errorf("expected different checksums for different contents")
+	}
+}
+
+func TestCheckConfigurationDrift(t *testing.T) {
+	listener, err := net.Listen("unix", nginx.StatusSocket)
+	if err != nil {
+		t.Fatalf("creating unix listener: %s", err)
+	}
+	defer listener.Close()
+	defer os.Remove(nginx.StatusSocket)
+
+	backends := []byte(`[{"name":"default-app-80"}]`)
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write(backends)
+			}),
+		},
+	}
+	defer server.Close()
+	server.Start()
+
+	cfg, err := ioutil.TempFile("", "nginx-conf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(cfg.Name())
+	cfg.Close()
+
+	content := []byte("events {}")
+	err = ioutil.WriteFile(cfg.Name(), content, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		applied  appliedState
+		expected []string
+	}{
+		{"no drift", appliedState{config: checksum(content), backends: checksum(backends)}, nil},
+		{"configuration file", appliedState{config: checksum([]byte("events { worker_connections 1; }"))}, []string{driftSourceFile}},
+		{"dynamic backends", appliedState{backends: checksum([]byte(`[]`))}, []string{driftSourceBackends}},
+		{"both", appliedState{config: checksum([]byte("")), backends: checksum([]byte(`[]`))}, []string{driftSourceFile, driftSourceBackends}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			synced := make(chan interface{}, 1)
+			collector := &driftCollector{}
+			n := &NGINXController{
+				appliedLock:     &sync.Mutex{},
+				applied:         tc.applied,
+				metricCollector: collector,
+				syncQueue: task.NewTaskQueue(func(obj interface{}) error {
+					synced <- obj
+					return nil
+				}),
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			go n.syncQueue.Run(time.Second, stopCh)
+			defer n.syncQueue.Shutdown()
+
+			n.checkConfigurationDrift(cfg.Name())
+
+			collector.lock.Lock()
+			sources := collector.sources
+			collector.lock.Unlock()
+			if !reflect.DeepEqual(sources, tc.expected) {
+				t.Errorf("expected the drift of %v to be counted but %v returned", tc.expected, sources)
+			}
+
+			forceSync := atomic.LoadInt32(&n.forceSync) == 1
+			if forceSync != (len(tc.expected) > 0) {
+				t.Errorf("expected the next synchronization to be forced %v but it is %v", len(tc.expected) > 0, forceSync)
+			}
+
+			select {
+			case <-synced:
+				if len(tc.expected) == 0 {
+					t.Errorf("expected no synchronization without drift")
+				}
+			case <-time.After(500 * time.Millisecond):
+				if len(tc.expected) > 0 {
+					t.Errorf("expected a synchronization re-applying the configuration")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io"
+	"time"
+)
+
+// dryRunQuietPeriod is the time without changes in the store required to
+// consider all the objects have been processed
+const dryRunQuietPeriod = 1 * time.Second
+
+// DryRun renders the NGINX configuration resulting from the objects of the
+// store, writes it to w and checks it with "nginx -t". NGINX is not started
+// nor reloaded, and the load-balancer status of the Ingresses is not updated.
+func (n *NGINXController) DryRun(w io.Writer) error {
+	defer close(n.stopCh)
+
+	n.store.Run(n.stopCh)
+
+	// the handlers of the informers process the objects after the caches
+	// are synced, wait until the store stops sending events
+	for quiet := false; !quiet; {
+		select {
+		case <-n.updateCh.Out():
+		case <-time.After(dryRunQuietPeriod):
+			quiet = true
+		}
+	}
+
+	ings := n.store.ListIngresses()
+	_, _, pcfg, _ := n.getConfiguration(ings)
+
+	cfg := n.store.GetBackendConfiguration()
+	cfg.Resolver = n.resolver
+
+	content, err := n.generateTemplate(cfg, *pcfg)
+	if err != nil {
+		return err
+	}
+
+	// the certificates of the stream services are staged and discarded,
+	// they are never written to the ones in use
+	staged := stagedCertificates{}
+	if n.cfg.DynamicCertificatesEnabled {
+		staged, err = stageStreamCertificates(pcfg.TCPEndpoints)
+		if err != nil {
+			return err
+		}
+		defer staged.discard()
+	}
+
+	_, err = w.Write(content)
+	if err != nil {
+		return err
+	}
+
+	return n.testTemplate(staged.replace(content))
+}
@@ -21,17 +21,21 @@ import (
 	"net"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"k8s.io/klog"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/k8s"
 )
 
 // getEndpoints returns a list of Endpoint structs for a given service/target port combination.
-func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Protocol,
+// The not ready addresses are only included when publishNotReady is enabled
+// and the Service publishes them.
+func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Protocol, publishNotReady bool,
 	getServiceEndpoints func(string) (*corev1.Endpoints, error)) []ingress.Endpoint {
 
 	upsServers := []ingress.Endpoint{}
@@ -50,18 +54,24 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 	if s.Spec.Type == corev1.ServiceTypeExternalName {
 		klog.V(3).Infof("Ingress using Service %q of type ExternalName.", svcKey)
 
+		// the target port of ExternalName Services defaults to the Service port
 		targetPort := port.TargetPort.IntValue()
+		if targetPort <= 0 {
+			targetPort = int(port.Port)
+		}
+
 		if targetPort <= 0 {
 			klog.Errorf("ExternalName Service %q has an invalid port (%v)", svcKey, targetPort)
 			return upsServers
 		}
 
-		if net.ParseIP(s.Spec.ExternalName) == nil {
-			_, err := net.LookupHost(s.Spec.ExternalName)
-			if err != nil {
-				klog.Errorf("Error resolving host %q: %v", s.Spec.ExternalName, err)
-				return upsServers
-			}
+		// the name is not resolved per request but by the Lua balancer every
+		// time it syncs the backends, so only the format is validated here.
+		// Fully qualified names can end with a dot
+		externalName := strings.TrimSuffix(s.Spec.ExternalName, ".")
+		if net.ParseIP(externalName) == nil && len(validation.IsDNS1123Subdomain(externalName)) > 0 {
+			klog.Errorf("ExternalName Service %q has an invalid external name (%v)", svcKey, s.Spec.ExternalName)
+			return upsServers
 		}
 
 		return append(upsServers, ingress.Endpoint{
@@ -78,7 +88,25 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 	}
 
 	for _, ss := range ep.Subsets {
-		for _, epPort := range ss.Ports {
+		ports := ss.Ports
+		// headless Services without port definitions do not define ports in
+		// the Endpoints either, the port used in the Ingress is used instead
+		if len(ports) == 0 && s.Spec.ClusterIP == corev1.ClusterIPNone && len(s.Spec.Ports) == 0 {
+			ports = []corev1.EndpointPort{{Port: int32(port.TargetPort.IntValue()), Protocol: proto}}
+		}
+
+		addresses := ss.Addresses
+		// The Endpoints do not tell terminating Pods apart from the ones
+		// starting or failing the readiness probe, so the not ready
+		// addresses are only used when both the Service and the
+		// controller explicitly allow it
+		if publishNotReady && s.Spec.PublishNotReadyAddresses {
+			addresses = make([]corev1.EndpointAddress, 0, len(ss.Addresses)+len(ss.NotReadyAddresses))
+			addresses = append(addresses, ss.Addresses...)
+			addresses = append(addresses, ss.NotReadyAddresses...)
+		}
+
+		for _, epPort := range ports {
 
 			if !reflect.DeepEqual(epPort.Protocol, proto) {
 				continue
@@ -97,7 +125,7 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 				continue
 			}
 
-			for _, epAddress := range ss.Addresses {
+			for _, epAddress := range addresses {
 				ep := net.JoinHostPort(epAddress.IP, strconv.Itoa(int(targetPort)))
 				if _, exists := processedUpstreamServers[ep]; exists {
 					continue
@@ -108,7 +108,7 @@ func TestGetEndpoints(t *testing.T) {
 			},
 		},
 		{
-			"a service type ServiceTypeExternalName with an invalid ExternalName value should return one endpoint",
+			"a service type ServiceTypeExternalName with a name that does not resolve yet should return one endpoint",
 			&corev1.Service{
 				Spec: corev1.ServiceSpec{
 					Type:         corev1.ServiceTypeExternalName,
@@ -129,8 +129,95 @@ func TestGetEndpoints(t *testing.T) {
 			func(string) (*corev1.Endpoints, error) {
 				return &corev1.Endpoints{}, nil
 			},
+			[]ingress.Endpoint{
+				{
+					Address: "foo.bar",
+					Port:    "80",
+				},
+			},
+		},
+		{
+			"a service type ServiceTypeExternalName with a fully qualified name should return one endpoint",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "foo.bar.",
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "default",
+							TargetPort: intstr.FromInt(80),
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name:       "default",
+				TargetPort: intstr.FromInt(80),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "foo.bar.",
+					Port:    "80",
+				},
+			},
+		},
+		{
+			"a service type ServiceTypeExternalName with an invalid ExternalName value should return 0 endpoint",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "foo_bar!",
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "default",
+							TargetPort: intstr.FromInt(80),
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name:       "default",
+				TargetPort: intstr.FromInt(80),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{}, nil
+			},
 			[]ingress.Endpoint{},
 		},
+		{
+			"a service type ServiceTypeExternalName without target port should use the service port",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "foo.bar",
+					Ports: []corev1.ServicePort{
+						{
+							Name: "default",
+							Port: 8080,
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name: "default",
+				Port: 8080,
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "foo.bar",
+					Port:    "8080",
+				},
+			},
+		},
 		{
 			"should return no endpoint when there is an error searching for endpoints",
 			&corev1.Service{
@@ -381,13 +468,245 @@ func TestGetEndpoints(t *testing.T) {
 				},
 			},
 		},
+		{
+			"should return one endpoint per pod when a named target port differs across pods",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeClusterIP,
+					ClusterIP: "1.1.1.1",
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							Port:       80,
+							TargetPort: intstr.FromString("web"),
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromString("web"),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+							Ports: []corev1.EndpointPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, Port: 8080},
+							},
+						},
+						{
+							Addresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+							Ports: []corev1.EndpointPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, Port: 9090},
+							},
+						},
+					},
+				}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "10.0.0.1",
+					Port:    "8080",
+				},
+				{
+					Address: "10.0.0.2",
+					Port:    "9090",
+				},
+			},
+		},
+		{
+			"should use the Ingress port for headless services without ports",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeClusterIP,
+					ClusterIP: corev1.ClusterIPNone,
+				},
+			},
+			&corev1.ServicePort{
+				Protocol:   corev1.ProtocolTCP,
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+						},
+					},
+				}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "10.0.0.1",
+					Port:    "8080",
+				},
+				{
+					Address: "10.0.0.2",
+					Port:    "8080",
+				},
+			},
+		},
+		{
+			"should ignore not ready addresses when the service does not publish them",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeClusterIP,
+					ClusterIP: "1.1.1.1",
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "default",
+							TargetPort: intstr.FromInt(80),
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name:       "default",
+				TargetPort: intstr.FromInt(80),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+							NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+							Ports: []corev1.EndpointPort{
+								{Name: "default", Protocol: corev1.ProtocolTCP, Port: 80},
+							},
+						},
+					},
+				}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "10.0.0.1",
+					Port:    "80",
+				},
+			},
+		},
+		{
+			"should include not ready addresses of terminating pods when the service publishes them",
+			&corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:                     corev1.ServiceTypeClusterIP,
+					ClusterIP:                "1.1.1.1",
+					PublishNotReadyAddresses: true,
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "default",
+							TargetPort: intstr.FromInt(80),
+						},
+					},
+				},
+			},
+			&corev1.ServicePort{
+				Name:       "default",
+				TargetPort: intstr.FromInt(80),
+			},
+			corev1.ProtocolTCP,
+			func(string) (*corev1.Endpoints, error) {
+				return &corev1.Endpoints{
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+							NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+							Ports: []corev1.EndpointPort{
+								{Name: "default", Protocol: corev1.ProtocolTCP, Port: 80},
+							},
+						},
+					},
+				}, nil
+			},
+			[]ingress.Endpoint{
+				{
+					Address: "10.0.0.1",
+					Port:    "80",
+				},
+				{
+					Address: "10.0.0.2",
+					Port:    "80",
+				},
+			},
+		},
 	}
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			result := getEndpoints(testCase.svc, testCase.port, testCase.proto, testCase.fn)
+			// the combinations of the flag --publish-not-ready-endpoints
+			// are tested in TestGetEndpointsNotReadyAddresses
+			result := getEndpoints(testCase.svc, testCase.port, testCase.proto, true, testCase.fn)
 			if len(testCase.result) != len(result) {
-				t.Errorf("Expected %d Endpoints but got %d", len(testCase.result), len(result))
+				t.Fatalf("Expected %d Endpoints but got %d", len(testCase.result), len(result))
+			}
+
+			for i := range result {
+				if result[i].Address != testCase.result[i].Address || result[i].Port != testCase.result[i].Port {
+					t.Errorf("Expected Endpoint %v:%v but got %v:%v", testCase.result[i].Address, testCase.result[i].Port, result[i].Address, result[i].Port)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEndpointsNotReadyAddresses(t *testing.T) {
+	endpoints := func(string) (*corev1.Endpoints, error) {
+		return &corev1.Endpoints{
+			Subsets: []corev1.EndpointSubset{
+				{
+					Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+					NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+					Ports: []corev1.EndpointPort{
+						{Name: "default", Protocol: corev1.ProtocolTCP, Port: 80},
+					},
+				},
+			},
+		}, nil
+	}
+
+	tests := []struct {
+		name                     string
+		publishNotReadyAddresses bool
+		publishNotReady          bool
+		result                   []string
+	}{
+		{"should ignore not ready addresses by default", false, false, []string{"10.0.0.1"}},
+		{"should ignore not ready addresses published by the service unless enabled", true, false, []string{"10.0.0.1"}},
+		{"should ignore not ready addresses not published by the service", false, true, []string{"10.0.0.1"}},
+		{"should include not ready addresses published by the service when enabled", true, true, []string{"10.0.0.1", "10.0.0.2"}},
+	}
+
+	port := corev1.ServicePort{
+		Name:       "default",
+		TargetPort: intstr.FromInt(80),
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			svc := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:                     corev1.ServiceTypeClusterIP,
+					ClusterIP:                "1.1.1.1",
+					PublishNotReadyAddresses: testCase.publishNotReadyAddresses,
+					Ports:                    []corev1.ServicePort{port},
+				},
+			}
+
+			result := getEndpoints(svc, &port, corev1.ProtocolTCP, testCase.publishNotReady, endpoints)
+			if len(testCase.result) != len(result) {
+				t.Fatalf("Expected %d Endpoints but got %d", len(testCase.result), len(result))
+			}
+
+			for i := range result {
+				if result[i].Address != testCase.result[i] {
+					t.Errorf("Expected Endpoint %v but got %v", testCase.result[i], result[i].Address)
+				}
 			}
 		})
 	}
@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+const (
+	// maxmindURL is the permalink used to download the GeoIP2 databases
+	// https://dev.maxmind.com/geoip/geoipupdate/#Direct_Downloads
+	maxmindURL = "https://download.maxmind.com/app/geoip_download?license_key=%v&edition_id=%v&suffix=tar.gz"
+
+	geoIP2SyncPeriod = time.Minute
+
+	// time to wait before downloading a database again after an error,
+	// to avoid exhausting the daily download limit of the license key
+	geoIP2RetryPeriod = 10 * time.Minute
+
+	// maxmindLicenseKeyField is the key of the Secret containing the license key
+	maxmindLicenseKeyField = "license-key"
+)
+
+// geoIP2Directory contains the GeoIP2 databases loaded by NGINX
+var geoIP2Directory = "/etc/nginx/geoip"
+
+// geoIP2Databases downloads the GeoIP2 databases from MaxMind and
+// keeps them up to date
+type geoIP2Databases struct {
+	url       string
+	directory string
+	client    *http.Client
+
+	// time of the last failed download of each edition
+	failures map[string]time.Time
+}
+
+func newGeoIP2Databases() *geoIP2Databases {
+	return &geoIP2Databases{
+		url:       maxmindURL,
+		directory: geoIP2Directory,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		failures:  map[string]time.Time{},
+	}
+}
+
+func (g *geoIP2Databases) path(edition string) string {
+	return filepath.Join(g.directory, edition+".mmdb")
+}
+
+// Available returns the editions with a database on disk and the
+// ones still missing, NGINX cannot load them yet
+func (g *geoIP2Databases) Available(editions []string) ([]string, []string) {
+	if g == nil {
+		return nil, editions
+	}
+
+	var available, missing []string
+	for _, edition := range editions {
+		if _, err := os.Stat(g.path(edition)); err == nil {
+			available = append(available, edition)
+		} else {
+			missing = append(missing, edition)
+		}
+	}
+
+	return available, missing
+}
+
+// sync downloads the databases of the editions that are missing or were
+// downloaded more than one refresh interval ago. A zero refresh interval
+// only downloads the missing databases. Returns true if any database changed.
+func (g *geoIP2Databases) sync(licenseKey string, editions []string, refresh time.Duration, now time.Time) bool {
+	changed := false
+	for _, edition := range editions {
+		if !g.needsDownload(edition, refresh, now) {
+			continue
+		}
+
+		klog.Infof("Downloading GeoIP2 database %v", edition)
+		err := g.download(licenseKey, edition)
+		if err != nil {
+			klog.Errorf("Error downloading GeoIP2 database %v: %v", edition, err)
+			g.failures[edition] = now
+			continue
+		}
+
+		delete(g.failures, edition)
+		changed = true
+	}
+
+	return changed
+}
+
+func (g *geoIP2Databases) needsDownload(edition string, refresh time.Duration, now time.Time) bool {
+	if failed, ok := g.failures[edition]; ok && now.Before(failed.Add(geoIP2RetryPeriod)) {
+		return false
+	}
+
+	info, err := os.Stat(g.path(edition))
+	if err != nil {
+		return true
+	}
+
+	return refresh > 0 && !now.Before(info.ModTime().Add(refresh))
+}
+
+// download extracts the database of an edition from the archive
+// provided by MaxMind and replaces the one on disk
+func (g *geoIP2Databases) download(licenseKey, edition string) error {
+	resp, err := g.client.Get(fmt.Sprintf(g.url, url.QueryEscape(licenseKey), url.QueryEscape(edition)))
+	if err != nil {
+		// do not log the URL, it contains the license key
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	name := edition + ".mmdb"
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%v not found in the archive", name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == name {
+			return g.write(edition, tr)
+		}
+	}
+}
+
+// write replaces the database of an edition atomically, so NGINX
+// never reads a partial file
+func (g *geoIP2Databases) write(edition string, r io.Reader) error {
+	tmp, err := ioutil.TempFile(g.directory, edition)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), g.path(edition))
+}
+
+// syncGeoIP2Databases downloads the configured GeoIP2 databases and
+// reloads NGINX when any of them changed
+func (n *NGINXController) syncGeoIP2Databases() {
+	cfg := n.store.GetBackendConfiguration()
+	if !cfg.UseGeoIP2 || n.cfg.MaxmindLicenseKeySecret == "" {
+		return
+	}
+
+	licenseKey, err := n.maxmindLicenseKey()
+	if err != nil {
+		klog.Errorf("Error reading the MaxMind license key: %v", err)
+		return
+	}
+
+	if n.geoIP2.sync(licenseKey, cfg.MaxmindEditionIDs, cfg.MaxmindRefreshInterval, time.Now()) {
+		klog.Infof("GeoIP2 databases changed")
+		atomic.StoreInt32(&n.forceSync, 1)
+		n.syncQueue.EnqueueTask(task.GetDummyObject("geoip2-databases"))
+	}
+}
+
+// maxmindLicenseKey reads the MaxMind license key from the Secret
+// defined with the flag --maxmind-license-key-secret
+func (n *NGINXController) maxmindLicenseKey() (string, error) {
+	ns, name, err := k8s.ParseNameNS(n.cfg.MaxmindLicenseKeySecret)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := n.cfg.Client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	licenseKey := strings.TrimSpace(string(secret.Data[maxmindLicenseKeyField]))
+	if licenseKey == "" {
+		return "", fmt.Errorf("the key %v of the Secret %v is empty", maxmindLicenseKeyField, n.cfg.MaxmindLicenseKeySecret)
+	}
+
+	return licenseKey, nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func geoIP2Archive(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = tw.Write(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func newTestGeoIP2Databases(t *testing.T, handler http.HandlerFunc) (*geoIP2Databases, func()) {
+	dir, err := ioutil.TempDir("", "geoip2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(handler)
+
+	g := newGeoIP2Databases()
+	g.url = server.URL + "/?license_key=%v&edition_id=%v"
+	g.directory = dir
+
+	return g, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGeoIP2DatabasesSync(t *testing.T) {
+	requests := 0
+	g, cleanup := newTestGeoIP2Databases(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("license_key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		edition := r.URL.Query().Get("edition_id")
+		w.Write(geoIP2Archive(t, edition+"_20190101/"+edition+".mmdb", []byte(edition)))
+	})
+	defer cleanup()
+
+	editions := []string{"GeoLite2-City", "GeoLite2-ASN"}
+	now := time.Now()
+
+	if available, missing := g.Available(editions); len(available) != 0 || !reflect.DeepEqual(missing, editions) {
+		t.Errorf("expected no databases but got %v, missing %v", available, missing)
+	}
+
+	if !g.sync("secret", editions, 24*time.Hour, now) {
+		t.Errorf("expected databases to change")
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 downloads but got %v", requests)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(g.directory, "GeoLite2-City.mmdb"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != "GeoLite2-City" {
+		t.Errorf("unexpected content of the database: %q", content)
+	}
+
+	if available, missing := g.Available(editions); !reflect.DeepEqual(available, editions) || len(missing) != 0 {
+		t.Errorf("expected %v but got %v, missing %v", editions, available, missing)
+	}
+
+	if g.sync("secret", editions, 24*time.Hour, now.Add(time.Hour)) {
+		t.Errorf("expected databases not to change before the refresh interval")
+	}
+	if g.sync("secret", editions, 0, now.Add(48*time.Hour)) {
+		t.Errorf("expected databases not to be refreshed with a zero refresh interval")
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 downloads but got %v", requests)
+	}
+
+	if !g.sync("secret", editions, 24*time.Hour, now.Add(25*time.Hour)) {
+		t.Errorf("expected databases to be refreshed")
+	}
+	if requests != 4 {
+		t.Errorf("expected 4 downloads but got %v", requests)
+	}
+}
+
+func TestGeoIP2DatabasesSyncError(t *testing.T) {
+	requests := 0
+	g, cleanup := newTestGeoIP2Databases(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	defer cleanup()
+
+	editions := []string{"GeoLite2-Country"}
+	now := time.Now()
+
+	if g.sync("invalid", editions, 24*time.Hour, now) {
+		t.Errorf("expected databases not to change")
+	}
+	if g.sync("invalid", editions, 24*time.Hour, now.Add(time.Minute)) {
+		t.Errorf("expected databases not to change")
+	}
+	if requests != 1 {
+		t.Errorf("expected the download not to be retried before %v but got %v requests", geoIP2RetryPeriod, requests)
+	}
+
+	g.sync("invalid", editions, 24*time.Hour, now.Add(geoIP2RetryPeriod))
+	if requests != 2 {
+		t.Errorf("expected the download to be retried but got %v requests", requests)
+	}
+
+	if available, _ := g.Available(editions); len(available) != 0 {
+		t.Errorf("expected no databases but got %v", available)
+	}
+}
+
+func TestGeoIP2DatabasesMissingFile(t *testing.T) {
+	g, cleanup := newTestGeoIP2Databases(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(geoIP2Archive(t, "README.txt", []byte("readme")))
+	})
+	defer cleanup()
+
+	err := g.download("secret", "GeoLite2-City")
+	if err == nil {
+		t.Errorf("expected an error downloading an archive without the database")
+	}
+
+	files, err := ioutil.ReadDir(g.directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected no files but got %v", len(files))
+	}
+}
+
+func TestMaxmindLicenseKey(t *testing.T) {
+	client := fake.NewSimpleClientset(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "maxmind"},
+		Data:       map[string][]byte{maxmindLicenseKeyField: []byte("secret\n")},
+	}, &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "empty"},
+	})
+
+	testCases := map[string]struct {
+		secret     string
+		licenseKey string
+		err        bool
+	}{
+		"license key":       {"default/maxmind", "secret", false},
+		"empty Secret":      {"default/empty", "", true},
+		"missing Secret":    {"default/missing", "", true},
+		"invalid reference": {"maxmind", "", true},
+	}
+
+	for name, tc := range testCases {
+		n := &NGINXController{cfg: &Configuration{Client: client, MaxmindLicenseKeySecret: tc.secret}}
+		licenseKey, err := n.maxmindLicenseKey()
+		if (err != nil) != tc.err {
+			t.Errorf("%v: expected error %v but returned %v", name, tc.err, err)
+		}
+		if licenseKey != tc.licenseKey {
+			t.Errorf("%v: expected the license key %q but returned %q", name, tc.licenseKey, licenseKey)
+		}
+	}
+}
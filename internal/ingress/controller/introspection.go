@@ -0,0 +1,202 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+// redactedValue replaces the sensitive values in the introspection output
+const redactedValue = "<redacted>"
+
+// introspectionState is the read-only view of the running state of the
+// controller exposed by the introspection server
+type introspectionState struct {
+	Backends      []*ingress.Backend       `json:"backends"`
+	Servers       []*ingress.Server        `json:"servers"`
+	Certificates  []certificateInfo        `json:"certificates"`
+	Configuration ngx_config.Configuration `json:"configuration"`
+	NginxConf     string                   `json:"nginxConf"`
+	Checksum      string                   `json:"checksum"`
+}
+
+// certificateInfo describes a SSL certificate used by a server without
+// its private key
+type certificateInfo struct {
+	Hostname    string    `json:"hostname"`
+	Secret      string    `json:"secret"`
+	PemFileName string    `json:"pemFileName"`
+	PemSHA      string    `json:"pemSha"`
+	CN          []string  `json:"cn"`
+	ExpireTime  time.Time `json:"expires"`
+}
+
+// newIntrospectionHandler returns the handler of the introspection server.
+// It exposes the whole state in /state and each section in /state/<section>
+func newIntrospectionHandler(n *NGINXController) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		writeIntrospection(w, r, n.introspectionState())
+	})
+	mux.HandleFunc("/state/", func(w http.ResponseWriter, r *http.Request) {
+		state := n.introspectionState()
+
+		var section interface{}
+		switch strings.TrimPrefix(r.URL.Path, "/state/") {
+		case "backends":
+			section = state.Backends
+		case "servers":
+			section = state.Servers
+		case "certificates":
+			section = state.Certificates
+		case "configuration":
+			section = state.Configuration
+		case "nginx.conf":
+			section = state.NginxConf
+		case "checksum":
+			section = state.Checksum
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		writeIntrospection(w, r, section)
+	})
+
+	return mux
+}
+
+func writeIntrospection(w http.ResponseWriter, r *http.Request, data interface{}) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// startIntrospectionServer serves the introspection handler in a unix
+// socket, only reachable from the pod of the controller
+func (n *NGINXController) startIntrospectionServer() {
+	err := os.Remove(nginx.IntrospectionSocket)
+	if err != nil && !os.IsNotExist(err) {
+		klog.Warningf("Error removing the introspection socket %v: %v", nginx.IntrospectionSocket, err)
+	}
+
+	listener, err := net.Listen("unix", nginx.IntrospectionSocket)
+	if err != nil {
+		klog.Warningf("Error listening in the introspection socket %v: %v", nginx.IntrospectionSocket, err)
+		return
+	}
+
+	err = os.Chmod(nginx.IntrospectionSocket, 0600)
+	if err != nil {
+		klog.Warningf("Error changing the permissions of the introspection socket %v: %v", nginx.IntrospectionSocket, err)
+	}
+
+	err = n.introspectionServer.Serve(listener)
+	if err != nil && err != http.ErrServerClosed {
+		klog.Warningf("Unexpected error in the introspection server: %v", err)
+	}
+}
+
+// introspectionState returns the running state of the controller without
+// the private keys of the certificates and other secrets
+func (n *NGINXController) introspectionState() introspectionState {
+	n.runningConfigLock.RLock()
+	running := n.runningConfig
+	checksum := n.configurationChecksum
+	n.runningConfigLock.RUnlock()
+
+	state := introspectionState{
+		Backends:     running.Backends,
+		Servers:      make([]*ingress.Server, 0, len(running.Servers)),
+		Certificates: []certificateInfo{},
+		Checksum:     checksum,
+	}
+
+	for _, server := range running.Servers {
+		redacted := *server
+		redacted.SSLCert = redactCertificate(server.SSLCert)
+		redacted.SSLCertECDSA = redactCertificate(server.SSLCertECDSA)
+		state.Servers = append(state.Servers, &redacted)
+
+		for _, cert := range []ingress.SSLCert{server.SSLCert, server.SSLCertECDSA} {
+			if cert.PemFileName == "" {
+				continue
+			}
+
+			state.Certificates = append(state.Certificates, certificateInfo{
+				Hostname:    server.Hostname,
+				Secret:      k8s.MetaNamespaceKey(&cert.ObjectMeta),
+				PemFileName: cert.PemFileName,
+				PemSHA:      cert.PemSHA,
+				CN:          cert.CN,
+				ExpireTime:  cert.ExpireTime,
+			})
+		}
+	}
+
+	sort.SliceStable(state.Certificates, func(i, j int) bool {
+		return state.Certificates[i].Hostname < state.Certificates[j].Hostname
+	})
+
+	if n.store != nil {
+		state.Configuration = n.store.GetBackendConfiguration()
+	}
+
+	conf, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		klog.Warningf("Error reading %v: %v", cfgPath, err)
+	}
+	state.NginxConf = string(conf)
+
+	return state
+}
+
+// redactCertificate returns a copy of the certificate without the private
+// key. The parsed certificate is also removed as the certificates section
+// contains its details
+func redactCertificate(cert ingress.SSLCert) ingress.SSLCert {
+	if cert.PemCertKey != "" {
+		cert.PemCertKey = redactedValue
+	}
+	cert.Certificate = nil
+
+	return cert
+}
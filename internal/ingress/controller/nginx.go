@@ -28,18 +28,20 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"text/template"
 	"time"
 
-	proxyproto "github.com/armon/go-proxyproto"
 	"github.com/eapache/channels"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -47,9 +49,11 @@ import (
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/util/filesystem"
 
+	adm_controller "k8s.io/ingress-nginx/internal/admission/controller"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/process"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
@@ -59,6 +63,7 @@ import (
 	"k8s.io/ingress-nginx/internal/k8s"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/net/dns"
+	"k8s.io/ingress-nginx/internal/net/proxyproto"
 	"k8s.io/ingress-nginx/internal/net/ssl"
 	"k8s.io/ingress-nginx/internal/nginx"
 	"k8s.io/ingress-nginx/internal/task"
@@ -87,7 +92,8 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 	}
 
 	n := &NGINXController{
-		isIPV6Enabled: ing_net.IsIPv6Enabled(),
+		isIPV6Enabled:    ing_net.IsIPv6Enabled(),
+		isHTTP3Supported: isHTTP3Supported(),
 
 		resolver:        h,
 		cfg:             config,
@@ -100,17 +106,34 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 		stopCh:   make(chan struct{}),
 		updateCh: channels.NewRingChannel(1024),
 
-		stopLock: &sync.Mutex{},
+		stopLock:    &sync.Mutex{},
+		appliedLock: &sync.Mutex{},
 
 		fileSystem: fs,
 
-		runningConfig: new(ingress.Configuration),
+		runningConfig:     new(ingress.Configuration),
+		runningConfigLock: &sync.RWMutex{},
 
-		Proxy: &TCPProxy{},
+		templateLock: &sync.RWMutex{},
+
+		orphaned: newOrphanedIngresses(),
+
+		Proxy: &TCPProxy{MetricCollector: mc},
 
 		metricCollector: mc,
 	}
 
+	n.introspectionServer = &http.Server{
+		Handler: newIntrospectionHandler(n),
+	}
+
+	if n.cfg.ValidationWebhook != "" {
+		n.validationWebhookServer = &http.Server{
+			Addr:    config.ValidationWebhook,
+			Handler: adm_controller.NewAdmissionControllerServer(&adm_controller.IngressAdmission{Checker: n}),
+		}
+	}
+
 	pod, err := k8s.GetPodDetails(config.Client)
 	if err != nil {
 		klog.Fatalf("unexpected error obtaining pod information: %v", err)
@@ -122,16 +145,30 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 		config.ConfigMapName,
 		config.TCPConfigMapName,
 		config.UDPConfigMapName,
+		config.IPAccessConfigMapName,
 		config.DefaultSSLCertificate,
 		config.ResyncPeriod,
 		config.Client,
+		config.StreamServiceClient,
 		fs,
 		n.updateCh,
 		config.DynamicCertificatesEnabled,
 		pod,
-		config.DisableCatchAll)
+		config.DisableCatchAll,
+		config.IngressLabelSelector)
 
-	n.syncQueue = task.NewTaskQueue(n.syncIngress)
+	if config.SSLSessionTicketKeySecret != "" {
+		n.sessionTicketKeys, err = newSessionTicketKeys(config.Client, config.SSLSessionTicketKeySecret,
+			config.SSLSessionTicketKeyRotationPeriod, fs)
+		if err != nil {
+			klog.Fatalf("unexpected error reading TLS session ticket keys Secret: %v", err)
+		}
+	}
+
+	n.geoIP2 = newGeoIP2Databases()
+
+	n.syncQueue = task.NewRateLimitedTaskQueue(n.syncIngress,
+		task.NewRateLimiter(config.SyncQueueBaseDelay, config.SyncQueueMaxDelay, config.SyncQueueBurst))
 	if config.UpdateStatus {
 		n.syncStatus = status.NewStatusSyncer(status.Config{
 			Client:                 config.Client,
@@ -149,19 +186,7 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 	}
 
 	onTemplateChange := func() {
-		template, err := ngx_template.NewTemplate(tmplPath, fs)
-		if err != nil {
-			// this error is different from the rest because it must be clear why nginx is not working
-			klog.Errorf(`
--------------------------------------------------------------------------------
-Error loading new template: %v
--------------------------------------------------------------------------------
-`, err)
-			return
-		}
-
-		n.t = template
-		klog.Info("New NGINX configuration template loaded.")
+		atomic.StoreInt32(&n.templateChanged, 1)
 		n.syncQueue.EnqueueTask(task.GetDummyObject("template-change"))
 	}
 
@@ -239,12 +264,27 @@ type NGINXController struct {
 	// runningConfig contains the running configuration in the Backend
 	runningConfig *ingress.Configuration
 
+	// runningConfigLock protects the updates of runningConfig and
+	// configurationChecksum against the readers outside of the
+	// synchronization loop
+	runningConfigLock *sync.RWMutex
+
+	// configurationChecksum is the checksum of the applied configuration
+	configurationChecksum string
+
 	t *ngx_template.Template
 
+	// templateLock protects the replacement of the template t against the
+	// readers outside of the synchronization loop, like the admission webhook
+	templateLock *sync.RWMutex
+
 	resolver []net.IP
 
 	isIPV6Enabled bool
 
+	// isHTTP3Supported indicates the NGINX binary contains the HTTP/3 module
+	isHTTP3Supported bool
+
 	isShuttingDown bool
 
 	Proxy *TCPProxy
@@ -253,7 +293,38 @@ type NGINXController struct {
 
 	fileSystem filesystem.Filesystem
 
+	// sessionTicketKeys is only set when the TLS session ticket
+	// keys are read from a Secret
+	sessionTicketKeys *sessionTicketKeys
+
+	geoIP2 *geoIP2Databases
+
 	metricCollector metric.Collector
+
+	validationWebhookServer *http.Server
+
+	introspectionServer *http.Server
+
+	// forceSync is set to 1 when the next synchronization must reload
+	// NGINX even if the configuration did not change
+	forceSync int32
+
+	// reloadFailed is set to 1 when the last reload of NGINX failed
+	reloadFailed int32
+
+	// templateChanged is set to 1 when the next synchronization must read
+	// the template again
+	templateChanged int32
+
+	// orphaned contains the Ingresses routing traffic to empty Services
+	orphaned *orphanedIngresses
+
+	// invalidIPAccess contains the invalid entries of the IP access lists
+	// last reported, only accessed by the synchronization
+	invalidIPAccess string
+
+	appliedLock *sync.Mutex
+	applied     appliedState
 }
 
 // Start starts a new NGINX master process running in the foreground.
@@ -282,10 +353,36 @@ func (n *NGINXController) Start() {
 	klog.Info("Starting NGINX process")
 	n.start(cmd)
 
+	go n.startIntrospectionServer()
+
+	go wait.Until(n.reportOrphanedIngresses, orphanedIngressCheckPeriod, n.stopCh)
+
+	if n.validationWebhookServer != nil {
+		klog.Infof("Starting validation webhook on %s with keys %s %s", n.validationWebhookServer.Addr, n.cfg.ValidationWebhookCertPath, n.cfg.ValidationWebhookKeyPath)
+		go func() {
+			err := n.validationWebhookServer.ListenAndServeTLS(n.cfg.ValidationWebhookCertPath, n.cfg.ValidationWebhookKeyPath)
+			if err != nil && err != http.ErrServerClosed {
+				klog.Fatalf("Unexpected error starting the validation webhook: %v", err)
+			}
+		}()
+	}
+
 	go n.syncQueue.Run(time.Second, n.stopCh)
 	// force initial sync
 	n.syncQueue.EnqueueTask(task.GetDummyObject("initial-sync"))
 
+	if n.cfg.ConfigDriftCheckPeriod > 0 {
+		go wait.Until(func() {
+			n.checkConfigurationDrift(cfgPath)
+		}, n.cfg.ConfigDriftCheckPeriod, n.stopCh)
+	}
+
+	if n.sessionTicketKeys != nil {
+		go wait.Until(n.syncSessionTicketKeys, sessionTicketKeysSyncPeriod, n.stopCh)
+	}
+
+	go wait.Until(n.syncGeoIP2Databases, geoIP2SyncPeriod, n.stopCh)
+
 	// In case of error the temporal configuration file will
 	// be available up to five minutes after the error
 	go func() {
@@ -344,6 +441,12 @@ func (n *NGINXController) Start() {
 	}
 }
 
+// isLeader returns if the controller is the leader of the status updates.
+// Without update of the status there is no leader election.
+func (n *NGINXController) isLeader() bool {
+	return n.syncStatus != nil && n.syncStatus.IsLeader()
+}
+
 // Stop gracefully stops the NGINX master process.
 func (n *NGINXController) Stop() error {
 	n.isShuttingDown = true
@@ -362,6 +465,21 @@ func (n *NGINXController) Stop() error {
 		n.syncStatus.Shutdown()
 	}
 
+	if n.validationWebhookServer != nil {
+		klog.Info("Stopping admission controller")
+		err := n.validationWebhookServer.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if n.introspectionServer != nil {
+		err := n.introspectionServer.Close()
+		if err != nil {
+			klog.Warningf("Unexpected error stopping the introspection server: %v", err)
+		}
+	}
+
 	// send stop signal to NGINX
 	klog.Info("Stopping NGINX process")
 	cmd := nginxExecCommand("-s", "quit")
@@ -400,7 +518,7 @@ func (n *NGINXController) start(cmd *exec.Cmd) {
 }
 
 // DefaultEndpoint returns the default endpoint to be use as default server that returns 404.
-func (n NGINXController) DefaultEndpoint() ingress.Endpoint {
+func (n *NGINXController) DefaultEndpoint() ingress.Endpoint {
 	return ingress.Endpoint{
 		Address: "127.0.0.1",
 		Port:    fmt.Sprintf("%v", n.cfg.ListenPorts.Default),
@@ -408,9 +526,69 @@ func (n NGINXController) DefaultEndpoint() ingress.Endpoint {
 	}
 }
 
+// reloadTemplate reads the template from disk and replaces the current one
+// if the configuration it renders is valid. Returns true if it was replaced.
+func (n *NGINXController) reloadTemplate() bool {
+	template, err := ngx_template.NewTemplate(tmplPath, n.fileSystem)
+	if err != nil {
+		// this error is different from the rest because it must be clear why nginx is not working
+		klog.Errorf(`
+-------------------------------------------------------------------------------
+Error loading new template: %v
+-------------------------------------------------------------------------------
+`, err)
+		return false
+	}
+
+	err = n.validateTemplate(template)
+	if err != nil {
+		klog.Errorf(`
+-------------------------------------------------------------------------------
+Error validating new template, keeping the previous one: %v
+-------------------------------------------------------------------------------
+`, err)
+		return false
+	}
+
+	n.templateLock.Lock()
+	n.t = template
+	n.templateLock.Unlock()
+
+	klog.Info("New NGINX configuration template loaded.")
+
+	return true
+}
+
+// reloadChangedTemplate reads the template again if it changed since the
+// last synchronization. Returns true if the template was replaced.
+// It must only be called from the synchronization loop.
+func (n *NGINXController) reloadChangedTemplate() bool {
+	if !atomic.CompareAndSwapInt32(&n.templateChanged, 1, 0) {
+		return false
+	}
+
+	return n.reloadTemplate()
+}
+
+// Resync reads the configuration ConfigMap again and forces a validated
+// synchronization of the NGINX configuration, even if the resulting
+// configuration did not change. The template is read again by the
+// synchronization.
+func (n *NGINXController) Resync() {
+	atomic.StoreInt32(&n.templateChanged, 1)
+
+	err := n.store.ReloadConfigMap()
+	if err != nil {
+		klog.Errorf("Error reading the configuration ConfigMap %v: %v", n.cfg.ConfigMapName, err)
+	}
+
+	atomic.StoreInt32(&n.forceSync, 1)
+	n.syncQueue.EnqueueTask(task.GetDummyObject("resync"))
+}
+
 // testTemplate checks if the NGINX configuration inside the byte array is valid
 // running the command "nginx -t" using a temporal file.
-func (n NGINXController) testTemplate(cfg []byte) error {
+func (n *NGINXController) testTemplate(cfg []byte) error {
 	if len(cfg) == 0 {
 		return fmt.Errorf("invalid NGINX configuration (empty)")
 	}
@@ -440,51 +618,36 @@ Error: %v
 	return nil
 }
 
-// OnUpdate is called by the synchronization loop whenever configuration
-// changes were detected. The received backend Configuration is merged with the
-// configuration ConfigMap before generating the final configuration file.
-// Returns nil in case the backend was successfully reloaded.
-func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
+// validateTemplate renders the running configuration using the template t
+// and checks the result with "nginx -t" before t replaces the current one.
+func (n *NGINXController) validateTemplate(t *ngx_template.Template) error {
 	cfg := n.store.GetBackendConfiguration()
 	cfg.Resolver = n.resolver
 
-	if n.cfg.EnableSSLPassthrough {
-		servers := []*TCPServer{}
-		for _, pb := range ingressCfg.PassthroughBackends {
-			svc := pb.Service
-			if svc == nil {
-				klog.Warningf("Missing Service for SSL Passthrough backend %q", pb.Backend)
-				continue
-			}
-			port, err := strconv.Atoi(pb.Port.String())
-			if err != nil {
-				for _, sp := range svc.Spec.Ports {
-					if sp.Name == pb.Port.String() {
-						port = int(sp.Port)
-						break
-					}
-				}
-			} else {
-				for _, sp := range svc.Spec.Ports {
-					if sp.Port == int32(port) {
-						port = int(sp.Port)
-						break
-					}
-				}
-			}
-
-			// TODO: Allow PassthroughBackends to specify they support proxy-protocol
-			servers = append(servers, &TCPServer{
-				Hostname:      pb.Hostname,
-				IP:            svc.Spec.ClusterIP,
-				Port:          port,
-				ProxyProtocol: false,
-			})
-		}
+	n.runningConfigLock.RLock()
+	runningConfig := *n.runningConfig
+	n.runningConfigLock.RUnlock()
 
-		n.Proxy.ServerList = servers
+	content, err := n.renderTemplate(t, cfg, runningConfig)
+	if err != nil {
+		return err
 	}
 
+	return n.testTemplate(content)
+}
+
+// generateTemplate returns the nginx configuration file content
+func (n *NGINXController) generateTemplate(cfg ngx_config.Configuration, ingressCfg ingress.Configuration) ([]byte, error) {
+	n.templateLock.RLock()
+	t := n.t
+	n.templateLock.RUnlock()
+
+	return n.renderTemplate(t, cfg, ingressCfg)
+}
+
+// renderTemplate returns the nginx configuration file content rendered
+// using the template t
+func (n *NGINXController) renderTemplate(t *ngx_template.Template, cfg ngx_config.Configuration, ingressCfg ingress.Configuration) ([]byte, error) {
 	// NGINX cannot resize the hash tables used to store server names. For
 	// this reason we check if the current size is correct for the host
 	// names defined in the Ingress rules and adjust the value if
@@ -552,9 +715,17 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		cmap, err := n.store.GetConfigMap(cfg.AddHeaders)
 		if err != nil {
 			klog.Warningf("Error reading ConfigMap %q from local store: %v", cfg.AddHeaders, err)
+			cmap = &apiv1.ConfigMap{}
 		}
 
-		addHeaders = cmap.Data
+		for header, value := range cmap.Data {
+			if err := customheaders.ValidateHeader(header, value); err != nil {
+				klog.Warningf("Ignoring header from ConfigMap %q: %v", cfg.AddHeaders, err)
+				continue
+			}
+
+			addHeaders[header] = value
+		}
 	}
 
 	sslDHParam := ""
@@ -581,10 +752,23 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 	cfg.SSLDHParam = sslDHParam
 
+	if n.cfg.DisableSSLSessionTickets {
+		cfg.SSLSessionTickets = false
+	}
+
+	var geoIP2Databases, geoIP2MissingDatabases []string
+	if cfg.UseGeoIP2 {
+		geoIP2Databases, geoIP2MissingDatabases = n.geoIP2.Available(cfg.MaxmindEditionIDs)
+	}
+
+	if cfg.UseHTTP3 && !n.isHTTP3Supported {
+		klog.Warningf("HTTP/3 is enabled in the configuration but NGINX was built without the HTTP/3 module, ignoring it")
+	}
+
 	tc := ngx_config.TemplateConfig{
 		ProxySetHeaders:            setHeaders,
 		AddHeaders:                 addHeaders,
-		BacklogSize:                sysctlSomaxconn(),
+		BacklogSize:                listenBacklog(cfg.BacklogSize, sysctlSomaxconn()),
 		Backends:                   ingressCfg.Backends,
 		PassthroughBackends:        ingressCfg.PassthroughBackends,
 		Servers:                    ingressCfg.Servers,
@@ -592,6 +776,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		UDPBackends:                ingressCfg.UDPEndpoints,
 		Cfg:                        cfg,
 		IsIPV6Enabled:              n.isIPV6Enabled && !cfg.DisableIpv6,
+		IsHTTP3Enabled:             cfg.UseHTTP3 && n.isHTTP3Supported,
 		NginxStatusIpv4Whitelist:   cfg.NginxStatusIpv4Whitelist,
 		NginxStatusIpv6Whitelist:   cfg.NginxStatusIpv6Whitelist,
 		RedirectServers:            buildRedirects(ingressCfg.Servers),
@@ -599,7 +784,12 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		ListenPorts:                n.cfg.ListenPorts,
 		PublishService:             n.GetPublishService(),
 		DynamicCertificatesEnabled: n.cfg.DynamicCertificatesEnabled,
+		EnableIPAccess:             n.cfg.IPAccessConfigMapName != "",
 		EnableMetrics:              n.cfg.EnableMetrics,
+		SSLSessionTicketKeys:       n.sessionTicketKeys.Files(),
+		GeoIP2Databases:            geoIP2Databases,
+		GeoIP2MissingDatabases:     geoIP2MissingDatabases,
+		ACMEChallenges:             ingressCfg.ACMEChallenges,
 
 		HealthzURI:   nginx.HealthPath,
 		PID:          nginx.PID,
@@ -610,19 +800,46 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 	tc.Cfg.Checksum = ingressCfg.ConfigurationChecksum
 
-	content, err := n.t.Write(tc)
+	return t.Write(tc)
+}
+
+// OnUpdate is called by the synchronization loop whenever configuration
+// changes were detected. The received backend Configuration is merged with the
+// configuration ConfigMap before generating the final configuration file.
+// Returns nil in case the backend was successfully reloaded.
+func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
+	cfg := n.store.GetBackendConfiguration()
+	cfg.Resolver = n.resolver
+
+	content, err := n.generateTemplate(cfg, ingressCfg)
 	if err != nil {
 		return err
 	}
 
-	if cfg.EnableOpentracing {
+	if ngx_template.ShouldLoadOpentracingModule(cfg, ingressCfg.Servers) {
 		err := createOpentracingCfg(cfg)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = n.testTemplate(content)
+	staged := stagedCertificates{}
+	if n.cfg.DynamicCertificatesEnabled {
+		staged, err = stageStreamCertificates(ingressCfg.TCPEndpoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = n.testTemplate(staged.replace(content))
+	if err != nil {
+		staged.discard()
+		// the running configuration is kept untouched
+		n.recordInvalidConfiguration(content, err)
+		return err
+	}
+
+	err = staged.commit()
 	if err != nil {
 		return err
 	}
@@ -653,6 +870,11 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		}
 	}
 
+	// the drift check must not compare the new file against the
+	// checksum of the previous one
+	n.appliedLock.Lock()
+	defer n.appliedLock.Unlock()
+
 	err = ioutil.WriteFile(cfgPath, content, file.ReadWriteByUser)
 	if err != nil {
 		return err
@@ -663,9 +885,237 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		return fmt.Errorf("%v\n%v", err, string(o))
 	}
 
+	n.setAppliedConfig(content)
+
+	return nil
+}
+
+// stagedCertificates maps the path of the certificates of the TCP stream
+// services to the temporal file containing the new content.
+type stagedCertificates map[string]string
+
+// stageStreamCertificates writes to temporal files the certificates of the
+// TCP stream services terminating TLS that changed. Stream servers cannot
+// use the certificates configured dynamically in Lua. The certificates in
+// use are not modified until the staged ones are committed.
+func stageStreamCertificates(services []ingress.L4Service) (stagedCertificates, error) {
+	staged := stagedCertificates{}
+	for _, svc := range services {
+		if svc.SSLCert == nil || svc.SSLCert.PemCertKey == "" {
+			continue
+		}
+
+		if _, ok := staged[svc.SSLCert.PemFileName]; ok {
+			continue
+		}
+
+		if file.SHA1(svc.SSLCert.PemFileName) == svc.SSLCert.PemSHA {
+			continue
+		}
+
+		tmp, err := ioutil.TempFile(filepath.Dir(svc.SSLCert.PemFileName), filepath.Base(svc.SSLCert.PemFileName))
+		if err != nil {
+			staged.discard()
+			return nil, fmt.Errorf("could not create temporal pem file for %v: %v", svc.SSLCert.PemFileName, err)
+		}
+		tmp.Close()
+
+		staged[svc.SSLCert.PemFileName] = tmp.Name()
+
+		err = ioutil.WriteFile(tmp.Name(), []byte(svc.SSLCert.PemCertKey), file.ReadWriteByUser)
+		if err != nil {
+			staged.discard()
+			return nil, fmt.Errorf("could not write pem file %v: %v", tmp.Name(), err)
+		}
+	}
+
+	return staged, nil
+}
+
+// replace returns a copy of the NGINX configuration referencing the staged
+// certificates instead of the ones in use, to check it with "nginx -t".
+func (s stagedCertificates) replace(content []byte) []byte {
+	for path, tmp := range s {
+		content = bytes.Replace(content, []byte(path), []byte(tmp), -1)
+	}
+
+	return content
+}
+
+// commit replaces the certificates in use with the staged ones.
+func (s stagedCertificates) commit() error {
+	for path, tmp := range s {
+		err := os.Rename(tmp, path)
+		if err != nil {
+			s.discard()
+			return fmt.Errorf("could not write pem file %v: %v", path, err)
+		}
+		delete(s, path)
+	}
+
 	return nil
 }
 
+// discard removes the staged certificates.
+func (s stagedCertificates) discard() {
+	for path, tmp := range s {
+		os.Remove(tmp)
+		delete(s, path)
+	}
+}
+
+var (
+	nginxTestErrorLine = regexp.MustCompile(`in \S+:(\d+)`)
+	quotedValue        = regexp.MustCompile(`"(.*)"`)
+
+	snippetStart = regexp.MustCompile(`^# Custom code snippet configured in the configuration configmap \((\S+)\)$`)
+	snippetEnd   = regexp.MustCompile(`^# End of custom code snippet \((\S+)\)$`)
+)
+
+// recordInvalidConfiguration emits a warning Event on the Ingress or the
+// configuration ConfigMap that generated the section of the configuration
+// rejected by "nginx -t".
+func (n *NGINXController) recordInvalidConfiguration(content []byte, testErr error) {
+	if key, ok := snippetForConfigError(content, testErr); ok {
+		cmap, err := n.store.GetConfigMap(n.cfg.ConfigMapName)
+		if err != nil {
+			klog.Warningf("Invalid NGINX configuration in the %v key of the ConfigMap %q", key, n.cfg.ConfigMapName)
+			return
+		}
+
+		n.recorder.Eventf(cmap, apiv1.EventTypeWarning, "RELOAD",
+			"Invalid NGINX configuration in the %v key, keeping the previous one: %v", key, strings.TrimSpace(testErr.Error()))
+		return
+	}
+
+	namespace, name, ok := ingressForConfigError(content, testErr)
+	if !ok {
+		klog.Warningf("Unable to determine which Ingress generated the invalid NGINX configuration")
+		return
+	}
+
+	for _, ing := range n.store.ListIngresses() {
+		if ing.Namespace != namespace || ing.Name != name {
+			continue
+		}
+
+		n.recorder.Eventf(&ing.Ingress, apiv1.EventTypeWarning, "RELOAD",
+			"Invalid NGINX configuration, keeping the previous one: %v", strings.TrimSpace(testErr.Error()))
+		return
+	}
+}
+
+// ingressForConfigError returns the namespace and name of the Ingress that
+// owns the location reported in the output of "nginx -t".
+func ingressForConfigError(content []byte, testErr error) (string, string, bool) {
+	lines, line, ok := configErrorLine(content, testErr)
+	if !ok {
+		return "", "", false
+	}
+
+	start, ok := enclosingLocation(lines, line-1)
+	if !ok {
+		return "", "", false
+	}
+
+	// locations start setting the namespace and the name of the Ingress
+	var namespace, name string
+	depth := 1
+	for i := start + 1; i < len(lines) && depth > 0; i++ {
+		l := strings.TrimSpace(lines[i])
+		if depth == 1 {
+			if v := quotedValue.FindStringSubmatch(l); len(v) == 2 {
+				switch {
+				case strings.HasPrefix(l, "set $namespace"):
+					namespace = v[1]
+				case strings.HasPrefix(l, "set $ingress_name"):
+					name = v[1]
+				}
+			}
+
+			if namespace != "" && name != "" {
+				return namespace, name, true
+			}
+		}
+
+		depth += strings.Count(l, "{") - strings.Count(l, "}")
+	}
+
+	return "", "", false
+}
+
+// enclosingLocation returns the index of the line opening the location
+// block that contains the given line of the configuration.
+func enclosingLocation(lines []string, line int) (int, bool) {
+	if strings.HasPrefix(strings.TrimSpace(lines[line]), "location ") {
+		return line, true
+	}
+
+	depth := 0
+	for i := line - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		depth += strings.Count(l, "}") - strings.Count(l, "{")
+		if depth >= 0 {
+			continue
+		}
+
+		// the line opens a block containing the given one
+		switch {
+		case strings.HasPrefix(l, "location "):
+			return i, true
+		case strings.HasPrefix(l, "server ") || strings.HasPrefix(l, "server{"):
+			return 0, false
+		}
+
+		depth = 0
+	}
+
+	return 0, false
+}
+
+// snippetForConfigError returns the key of the configuration ConfigMap
+// containing the snippet reported in the output of "nginx -t".
+func snippetForConfigError(content []byte, testErr error) (string, bool) {
+	lines, line, ok := configErrorLine(content, testErr)
+	if !ok {
+		return "", false
+	}
+
+	for i := line - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		if snippetEnd.MatchString(l) {
+			return "", false
+		}
+
+		if m := snippetStart.FindStringSubmatch(l); len(m) == 2 {
+			return m[1], true
+		}
+	}
+
+	return "", false
+}
+
+// configErrorLine returns the lines of the configuration and the line
+// number reported in the output of "nginx -t".
+func configErrorLine(content []byte, testErr error) ([]string, int, bool) {
+	m := nginxTestErrorLine.FindStringSubmatch(testErr.Error())
+	if len(m) != 2 {
+		return nil, 0, false
+	}
+
+	line, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, 0, false
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return nil, 0, false
+	}
+
+	return lines, line, true
+}
+
 // nginxHashBucketSize computes the correct NGINX hash_bucket_size for a hash
 // with the given longest key.
 func nginxHashBucketSize(longestString int) int {
@@ -691,19 +1141,55 @@ func nextPowerOf2(v int) int {
 	return v
 }
 
+// updatePassthroughServers replaces the servers of the SSL Passthrough
+// proxy. The proxy runs in the controller and does not require a reload.
+func (n *NGINXController) updatePassthroughServers(backends []*ingress.SSLPassthroughBackend) {
+	servers := []*TCPServer{}
+	for _, pb := range backends {
+		svc := pb.Service
+		if svc == nil {
+			klog.Warningf("Missing Service for SSL Passthrough backend %q", pb.Backend)
+			continue
+		}
+		port, err := strconv.Atoi(pb.Port.String())
+		if err != nil {
+			for _, sp := range svc.Spec.Ports {
+				if sp.Name == pb.Port.String() {
+					port = int(sp.Port)
+					break
+				}
+			}
+		} else {
+			for _, sp := range svc.Spec.Ports {
+				if sp.Port == int32(port) {
+					port = int(sp.Port)
+					break
+				}
+			}
+		}
+
+		servers = append(servers, &TCPServer{
+			Hostname:      pb.Hostname,
+			IP:            svc.Spec.ClusterIP,
+			Port:          port,
+			ProxyProtocol: pb.ProxyProtocol,
+		})
+	}
+
+	n.Proxy.SetServers(servers)
+}
+
 func (n *NGINXController) setupSSLProxy() {
 	cfg := n.store.GetBackendConfiguration()
 	sslPort := n.cfg.ListenPorts.HTTPS
 	proxyPort := n.cfg.ListenPorts.SSLProxy
 
 	klog.Info("Starting TLS proxy for SSL Passthrough")
-	n.Proxy = &TCPProxy{
-		Default: &TCPServer{
-			Hostname:      "localhost",
-			IP:            "127.0.0.1",
-			Port:          proxyPort,
-			ProxyProtocol: true,
-		},
+	n.Proxy.Default = &TCPServer{
+		Hostname:      "localhost",
+		IP:            "127.0.0.1",
+		Port:          proxyPort,
+		ProxyProtocol: proxyproto.V1,
 	}
 
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", sslPort))
@@ -721,7 +1207,7 @@ func (n *NGINXController) setupSSLProxy() {
 
 			if n.store.GetBackendConfiguration().UseProxyProtocol {
 				// wrap the listener in order to decode Proxy
-				// Protocol before handling the connection
+				// Protocol (v1 or v2) before handling the connection
 				conn, err = proxyList.Accept()
 			} else {
 				conn, err = listener.Accept()
@@ -744,32 +1230,70 @@ func clearCertificates(config *ingress.Configuration) {
 	var clearedServers []*ingress.Server
 	for _, server := range config.Servers {
 		copyOfServer := *server
-		copyOfServer.SSLCert = ingress.SSLCert{PemFileName: copyOfServer.SSLCert.PemFileName}
+		// with dynamic certificates the file is always the default certificate,
+		// a change in its content requires a reload
+		copyOfServer.SSLCert = renderedCertificate(copyOfServer.SSLCert)
+		copyOfServer.SSLCertECDSA = renderedCertificate(copyOfServer.SSLCertECDSA)
 		clearedServers = append(clearedServers, &copyOfServer)
 	}
 	config.Servers = clearedServers
 }
 
+// renderedCertificate returns the fields of a certificate rendered in the template
+func renderedCertificate(cert ingress.SSLCert) ingress.SSLCert {
+	return ingress.SSLCert{
+		PemFileName:          cert.PemFileName,
+		PemSHA:               cert.PemSHA,
+		FullChainPemFileName: cert.FullChainPemFileName,
+		OCSPFileName:         cert.OCSPFileName,
+	}
+}
+
+// onlyCertificatesChanged returns whether the certificates served dynamically
+// are the only difference between two configurations
+func onlyCertificatesChanged(c1, c2 *ingress.Configuration) bool {
+	copyOfC1 := *c1
+	copyOfC2 := *c2
+
+	clearCertificates(&copyOfC1)
+	clearCertificates(&copyOfC2)
+
+	return !c1.Equal(c2) && copyOfC1.Equal(&copyOfC2)
+}
+
 // Helper function to clear endpoints from the ingress configuration since they should be ignored when
 // checking if the new configuration changes can be applied dynamically.
+// The Service exposed in a port is selected by Lua, only the port, the
+// PROXY protocol configuration and the certificate are rendered in the template
 func clearL4serviceEndpoints(config *ingress.Configuration) {
 	var clearedTCPL4Services []ingress.L4Service
 	var clearedUDPL4Services []ingress.L4Service
 	for _, service := range config.TCPEndpoints {
 		copyofService := ingress.L4Service{
-			Port:      service.Port,
-			Backend:   service.Backend,
+			Port: service.Port,
+			Backend: ingress.L4Backend{
+				Protocol:      service.Backend.Protocol,
+				ProxyProtocol: service.Backend.ProxyProtocol,
+			},
 			Endpoints: []ingress.Endpoint{},
 			Service:   nil,
+			SSLCert:   service.SSLCert,
+			Snippet:   service.Snippet,
 		}
 		clearedTCPL4Services = append(clearedTCPL4Services, copyofService)
 	}
 	for _, service := range config.UDPEndpoints {
 		copyofService := ingress.L4Service{
-			Port:      service.Port,
-			Backend:   service.Backend,
+			Port: service.Port,
+			Backend: ingress.L4Backend{
+				Protocol:       service.Backend.Protocol,
+				ProxyProtocol:  service.Backend.ProxyProtocol,
+				ProxyResponses: service.Backend.ProxyResponses,
+				ProxyTimeout:   service.Backend.ProxyTimeout,
+			},
 			Endpoints: []ingress.Endpoint{},
 			Service:   nil,
+			Snippet:   service.Snippet,
 		}
 		clearedUDPL4Services = append(clearedUDPL4Services, copyofService)
 	}
@@ -777,14 +1301,32 @@ func clearL4serviceEndpoints(config *ingress.Configuration) {
 	config.UDPEndpoints = clearedUDPL4Services
 }
 
+// Helper function to clear the backends from the ingress configuration since they are configured
+// dynamically. Only the SSL Passthrough flag is rendered in the template, in the proxy_pass
+// directive of the locations using the backend.
+func clearBackends(config *ingress.Configuration) {
+	clearedBackends := []*ingress.Backend{}
+	for _, backend := range config.Backends {
+		if !backend.SSLPassthrough {
+			continue
+		}
+
+		clearedBackends = append(clearedBackends, &ingress.Backend{
+			Name:           backend.Name,
+			SSLPassthrough: backend.SSLPassthrough,
+		})
+	}
+	config.Backends = clearedBackends
+}
+
 // IsDynamicConfigurationEnough returns whether a Configuration can be
 // dynamically applied, without reloading the backend.
 func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configuration) bool {
 	copyOfRunningConfig := *n.runningConfig
 	copyOfPcfg := *pcfg
 
-	copyOfRunningConfig.Backends = []*ingress.Backend{}
-	copyOfPcfg.Backends = []*ingress.Backend{}
+	clearBackends(&copyOfRunningConfig)
+	clearBackends(&copyOfPcfg)
 
 	clearL4serviceEndpoints(&copyOfRunningConfig)
 	clearL4serviceEndpoints(&copyOfPcfg)
@@ -792,6 +1334,14 @@ func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configurati
 	copyOfRunningConfig.ControllerPodsCount = 0
 	copyOfPcfg.ControllerPodsCount = 0
 
+	// the global IP access lists are updated without a reload
+	copyOfRunningConfig.IPAccess = ingress.IPAccess{}
+	copyOfPcfg.IPAccess = ingress.IPAccess{}
+
+	// the SSL Passthrough proxy is updated without a reload
+	copyOfRunningConfig.PassthroughBackends = []*ingress.SSLPassthroughBackend{}
+	copyOfPcfg.PassthroughBackends = []*ingress.SSLPassthroughBackend{}
+
 	if n.cfg.DynamicCertificatesEnabled {
 		clearCertificates(&copyOfRunningConfig)
 		clearCertificates(&copyOfPcfg)
@@ -800,6 +1350,31 @@ func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configurati
 	return copyOfRunningConfig.Equal(&copyOfPcfg)
 }
 
+// isConfigurationFileUnchanged returns whether the NGINX configuration file
+// rendered for a Configuration is byte-identical to the one applied by the
+// last reload. This guarantees the changes IsDynamicConfigurationEnough
+// considers dynamic are applied entirely without reloading NGINX.
+func (n *NGINXController) isConfigurationFileUnchanged(pcfg *ingress.Configuration) bool {
+	n.appliedLock.Lock()
+	applied := n.applied.config
+	n.appliedLock.Unlock()
+
+	if applied == "" {
+		return false
+	}
+
+	cfg := n.store.GetBackendConfiguration()
+	cfg.Resolver = n.resolver
+
+	content, err := n.generateTemplate(cfg, *pcfg)
+	if err != nil {
+		klog.Warningf("Unexpected error generating the NGINX configuration: %v", err)
+		return false
+	}
+
+	return checksum(content) == applied
+}
+
 // configureDynamically encodes new Backends in JSON format and POSTs the
 // payload to an internal HTTP endpoint handled by Lua.
 func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnabled bool) error {
@@ -866,12 +1441,16 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 		}
 
 		key := fmt.Sprintf("udp-%v-%v-%v", ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
-		streams = append(streams, ingress.Backend{
+		backend := ingress.Backend{
 			Name:      key,
 			Endpoints: ep.Endpoints,
 			Port:      intstr.FromInt(ep.Port),
 			Service:   service,
-		})
+		}
+		if ep.Backend.SessionPersistence {
+			backend.UpstreamHashBy = ingress.UpstreamHashByConfig{UpstreamHashBy: "$remote_addr"}
+		}
+		streams = append(streams, backend)
 	}
 
 	err = updateStreamConfiguration(streams)
@@ -890,6 +1469,15 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 		return fmt.Errorf("unexpected error code: %d", statusCode)
 	}
 
+	statusCode, _, err = nginx.NewPostStatusRequest("/configuration/ip-access", "application/json", pcfg.IPAccess)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
+	}
+
 	if isDynamicCertificatesEnabled {
 		err = configureCertificates(pcfg)
 		if err != nil {
@@ -933,7 +1521,11 @@ func configureCertificates(pcfg *ingress.Configuration) error {
 		servers = append(servers, &ingress.Server{
 			Hostname: server.Hostname,
 			SSLCert: ingress.SSLCert{
-				PemCertKey: server.SSLCert.PemCertKey,
+				PemCertKey:   server.SSLCert.PemCertKey,
+				OCSPResponse: server.SSLCert.OCSPResponse,
+			},
+			SSLCertECDSA: ingress.SSLCert{
+				PemCertKey: server.SSLCertECDSA.PemCertKey,
 			},
 		})
 	}
@@ -1055,6 +1647,10 @@ func buildRedirects(servers []*ingress.Server) []*redirect {
 		}
 
 		to := srv.Hostname
+		if strings.HasPrefix(to, "*.") {
+			klog.Warningf("Skipping creation of redirection to the wildcard server %q", to)
+			continue
+		}
 
 		var from string
 		if strings.HasPrefix(to, "www.") {
@@ -1090,7 +1686,7 @@ func buildRedirects(servers []*ingress.Server) []*redirect {
 			if ssl.IsValidHostname(from, srv.SSLCert.CN) {
 				r.SSLCert = srv.SSLCert
 			} else {
-				klog.Warningf("the server %v has SSL configured but the SSL certificate does not contains a CN for %v. Redirects will not work for HTTPS to HTTPS", from, to)
+				klog.Warningf("the server %v has SSL configured but the SSL certificate does not contain a CN or SAN for %v. Redirects will not work for HTTPS to HTTPS", to, from)
 			}
 		}
 
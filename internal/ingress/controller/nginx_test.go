@@ -17,22 +17,30 @@ limitations under the License.
 package controller
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/internal/task"
 )
 
 func TestIsDynamicConfigurationEnough(t *testing.T) {
@@ -141,6 +149,34 @@ func TestIsDynamicConfigurationEnough(t *testing.T) {
 		t.Errorf("Expected to be dynamically configurable when backend and SSLCert changes")
 	}
 
+	newServers[0].SSLCertECDSA.PemCertKey = "new-fake-ecdsa-certificate"
+	newConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  newServers,
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when only SSLCertECDSA changes")
+	}
+	newServers[0].SSLCertECDSA.PemCertKey = ""
+
+	newServers[0].SSLCert.PemFileName = "/etc/ingress-controller/ssl/default-fake-certificate.pem"
+	newServers[0].SSLCert.PemSHA = "new-default-certificate-sha"
+
+	newConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  newServers,
+	}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the default certificate changes")
+	}
+
+	newServers[0].SSLCert.PemFileName = ""
+	newServers[0].SSLCert.PemSHA = ""
+	newConfig = &ingress.Configuration{
+		Backends: []*ingress.Backend{{Name: "a-backend-8080"}},
+		Servers:  newServers,
+	}
+
 	if !n.runningConfig.Equal(commonConfig) {
 		t.Errorf("Expected running config to not change")
 	}
@@ -148,6 +184,347 @@ func TestIsDynamicConfigurationEnough(t *testing.T) {
 	if !newConfig.Equal(&ingress.Configuration{Backends: []*ingress.Backend{{Name: "a-backend-8080"}}, Servers: newServers}) {
 		t.Errorf("Expected new config to not change")
 	}
+
+	tcpService := ingress.L4Service{
+		Port: 9000,
+		Backend: ingress.L4Backend{
+			Name:      "tcp-svc",
+			Namespace: "default",
+			Port:      intstr.FromInt(8080),
+			Protocol:  "TCP",
+		},
+		Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "8080"}},
+	}
+	n.runningConfig = &ingress.Configuration{
+		Backends:     backends,
+		Servers:      newServers,
+		TCPEndpoints: []ingress.L4Service{tcpService},
+	}
+
+	newTCPService := tcpService
+	newTCPService.Backend.Name = "another-tcp-svc"
+	newTCPService.Endpoints = []ingress.Endpoint{{Address: "10.0.0.2", Port: "8080"}}
+	newConfig = &ingress.Configuration{
+		Backends:     backends,
+		Servers:      newServers,
+		TCPEndpoints: []ingress.L4Service{newTCPService},
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when the Service exposed in a TCP port changes")
+	}
+
+	newTCPService.Backend.ProxyProtocol.Decode = true
+	newConfig.TCPEndpoints = []ingress.L4Service{newTCPService}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the PROXY protocol of a TCP port changes")
+	}
+
+	newTCPService.Backend.ProxyProtocol.Decode = false
+	newTCPService.SSLCert = &ingress.SSLCert{PemFileName: "/etc/ingress-controller/ssl/stream-default-tls.pem", PemSHA: "1"}
+	newConfig.TCPEndpoints = []ingress.L4Service{newTCPService}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the certificate of a TCP port changes")
+	}
+
+	newTCPService.SSLCert = nil
+	newTCPService.Port = 9001
+	newConfig.TCPEndpoints = []ingress.L4Service{newTCPService}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when a new TCP port is exposed")
+	}
+
+	newTCPService.Port = 9000
+	newTCPService.Snippet = "proxy_download_rate 1k;"
+	newConfig.TCPEndpoints = []ingress.L4Service{newTCPService}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the snippet of a TCP port changes")
+	}
+
+	newConfig = &ingress.Configuration{
+		Backends:     []*ingress.Backend{{Name: "fakenamespace-myapp-80", SSLPassthrough: true}},
+		Servers:      newServers,
+		TCPEndpoints: []ingress.L4Service{tcpService},
+	}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the SSL Passthrough flag of a backend changes")
+	}
+
+	udpService := ingress.L4Service{
+		Port: 53,
+		Backend: ingress.L4Backend{
+			Name:      "dns",
+			Namespace: "kube-system",
+			Port:      intstr.FromInt(53),
+			Protocol:  "UDP",
+		},
+		Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "53"}},
+	}
+	n.runningConfig = &ingress.Configuration{
+		Backends:     backends,
+		Servers:      newServers,
+		UDPEndpoints: []ingress.L4Service{udpService},
+	}
+
+	newUDPService := udpService
+	newUDPService.Backend.SessionPersistence = true
+	newConfig = &ingress.Configuration{
+		Backends:     backends,
+		Servers:      newServers,
+		UDPEndpoints: []ingress.L4Service{newUDPService},
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when the session persistence of an UDP port changes")
+	}
+
+	newUDPService.Backend.ProxyTimeout = "30s"
+	newConfig.UDPEndpoints = []ingress.L4Service{newUDPService}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the proxy timeout of an UDP port changes")
+	}
+
+	n.runningConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  newServers,
+		PassthroughBackends: []*ingress.SSLPassthroughBackend{
+			{Backend: "a-backend-8080", Hostname: "myapp.fake", Port: intstr.FromInt(8080)},
+		},
+	}
+	newConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  newServers,
+		PassthroughBackends: []*ingress.SSLPassthroughBackend{
+			{Backend: "a-backend-8443", Hostname: "myapp.fake", Port: intstr.FromInt(8443)},
+		},
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when only SSL Passthrough backends change")
+	}
+
+	newConfig = &ingress.Configuration{
+		Backends: backends,
+		Servers:  newServers,
+		PassthroughBackends: []*ingress.SSLPassthroughBackend{
+			{Backend: "a-backend-8443", Hostname: "myapp.fake", Port: intstr.FromInt(8443)},
+		},
+		IPAccess: ingress.IPAccess{Denied: []string{"10.0.0.0/8"}},
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when only the IP access lists change")
+	}
+}
+
+func TestOnlyCertificatesChanged(t *testing.T) {
+	newConfig := func(cert string) *ingress.Configuration {
+		return &ingress.Configuration{
+			Backends: []*ingress.Backend{{Name: "fakenamespace-myapp-80"}},
+			Servers: []*ingress.Server{{
+				Hostname: "myapp.fake",
+				SSLCert: ingress.SSLCert{
+					PemFileName: "/etc/ingress-controller/ssl/default-fake-certificate.pem",
+					PemSHA:      "default-certificate-sha",
+					PemCertKey:  cert,
+				},
+			}},
+		}
+	}
+
+	running := newConfig("fake-certificate")
+
+	if onlyCertificatesChanged(running, newConfig("fake-certificate")) {
+		t.Errorf("Expected no certificate change when the configurations are equal")
+	}
+
+	pcfg := newConfig("new-fake-certificate")
+	if !onlyCertificatesChanged(running, pcfg) {
+		t.Errorf("Expected only a certificate change when the PEM of a server changes")
+	}
+
+	pcfg.Servers[0].SSLCertECDSA.PemCertKey = "new-fake-ecdsa-certificate"
+	if !onlyCertificatesChanged(running, pcfg) {
+		t.Errorf("Expected only a certificate change when the ECDSA PEM of a server changes")
+	}
+
+	pcfg.Backends = []*ingress.Backend{{Name: "fakenamespace-myapp-8080"}}
+	if onlyCertificatesChanged(running, pcfg) {
+		t.Errorf("Expected more than a certificate change when the backends change")
+	}
+
+	pcfg = newConfig("new-fake-certificate")
+	pcfg.Servers[0].SSLCert.PemSHA = "new-default-certificate-sha"
+	if onlyCertificatesChanged(running, pcfg) {
+		t.Errorf("Expected more than a certificate change when the default certificate changes")
+	}
+
+	if !running.Equal(newConfig("fake-certificate")) {
+		t.Errorf("Expected running config to not change")
+	}
+}
+
+func TestIsConfigurationFileUnchanged(t *testing.T) {
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := newNGINXController(t)
+	n.appliedLock = &sync.Mutex{}
+	n.templateLock = &sync.RWMutex{}
+	n.t, err = ngx_template.NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newConfig := func(address string) *ingress.Configuration {
+		return &ingress.Configuration{
+			Backends: []*ingress.Backend{{
+				Name:      "fakenamespace-myapp-80",
+				Endpoints: []ingress.Endpoint{{Address: address, Port: "8080"}},
+			}},
+			Servers: []*ingress.Server{{
+				Hostname: "myapp.fake",
+				Locations: []*ingress.Location{{
+					Path:    "/",
+					Backend: "fakenamespace-myapp-80",
+				}},
+			}},
+			ConfigurationChecksum: "1",
+		}
+	}
+
+	pcfg := newConfig("10.0.0.1")
+	if n.isConfigurationFileUnchanged(pcfg) {
+		t.Errorf("Expected the configuration file to be changed when no configuration was applied")
+	}
+
+	content, err := n.generateTemplate(n.store.GetBackendConfiguration(), *pcfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n.setAppliedConfig(content)
+
+	if !n.isConfigurationFileUnchanged(newConfig("10.0.0.2")) {
+		t.Errorf("Expected the configuration file to be unchanged when only the endpoints change")
+	}
+
+	pcfg = newConfig("10.0.0.1")
+	pcfg.Backends[0].SSLPassthrough = true
+	if n.isConfigurationFileUnchanged(pcfg) {
+		t.Errorf("Expected the configuration file to be changed when the SSL Passthrough flag of a backend changes")
+	}
+}
+
+func TestReloadTemplate(t *testing.T) {
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := newNGINXController(t)
+	n.fileSystem = fs
+	n.runningConfig = &ingress.Configuration{}
+	n.runningConfigLock = &sync.RWMutex{}
+	n.templateLock = &sync.RWMutex{}
+	n.t, err = ngx_template.NewTemplate(tmplPath, fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer func(cmd func(string) *exec.Cmd) {
+		nginxTestCommand = cmd
+	}(nginxTestCommand)
+
+	current := n.t
+
+	// the configuration rejected by nginx -t is kept for inspection
+	defer func() {
+		files, _ := filepath.Glob(filepath.Join(os.TempDir(), tempNginxPattern+"*"))
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}()
+
+	// the configuration rendered by the new template is rejected by NGINX
+	nginxTestCommand = func(string) *exec.Cmd {
+		return exec.Command("false")
+	}
+	if n.reloadTemplate() {
+		t.Errorf("expected the template to be rejected when nginx -t fails")
+	}
+	if n.t != current {
+		t.Errorf("expected the previous template to be kept when nginx -t fails")
+	}
+
+	nginxTestCommand = func(string) *exec.Cmd {
+		return exec.Command("true")
+	}
+	if !n.reloadTemplate() {
+		t.Errorf("expected the template to be accepted when nginx -t succeeds")
+	}
+	if n.t == current {
+		t.Errorf("expected the template to be replaced when nginx -t succeeds")
+	}
+
+	// templates with syntax errors are rejected before running nginx -t
+	current = n.t
+	f, err := fs.Create(tmplPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Write([]byte("{{ if }}"))
+	f.Close()
+
+	if n.reloadTemplate() {
+		t.Errorf("expected an invalid template to be rejected")
+	}
+	if n.t != current {
+		t.Errorf("expected the previous template to be kept when the new one is invalid")
+	}
+}
+
+func TestResyncReloadsTemplateInSyncLoop(t *testing.T) {
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := newNGINXController(t)
+	n.fileSystem = fs
+	n.runningConfig = &ingress.Configuration{}
+	n.runningConfigLock = &sync.RWMutex{}
+	n.templateLock = &sync.RWMutex{}
+	n.syncQueue = task.NewTaskQueue(func(interface{}) error { return nil })
+	n.t, err = ngx_template.NewTemplate(tmplPath, fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer func(cmd func(string) *exec.Cmd) {
+		nginxTestCommand = cmd
+	}(nginxTestCommand)
+	nginxTestCommand = func(string) *exec.Cmd {
+		return exec.Command("true")
+	}
+
+	current := n.t
+
+	n.Resync()
+	if n.t != current {
+		t.Errorf("expected the template to be replaced by the synchronization, not by Resync")
+	}
+	if atomic.LoadInt32(&n.forceSync) != 1 {
+		t.Errorf("expected Resync to force the next synchronization")
+	}
+
+	if !n.reloadChangedTemplate() {
+		t.Errorf("expected the synchronization to read the template again after Resync")
+	}
+	if n.t == current {
+		t.Errorf("expected the template to be replaced by the synchronization")
+	}
+
+	if n.reloadChangedTemplate() {
+		t.Errorf("expected the template to be read only once per Resync")
+	}
 }
 
 func TestConfigureDynamically(t *testing.T) {
@@ -198,6 +575,12 @@ func TestConfigureDynamically(t *testing.T) {
 							t.Errorf("controllerPodsCount should be present in JSON content: %v", body)
 						}
 					}
+				case "/configuration/ip-access":
+					{
+						if !strings.Contains(body, "10.0.0.0/8") {
+							t.Errorf("denied CIDRs should be present in JSON content: %v", body)
+						}
+					}
 				default:
 					t.Errorf("unknown request to %s", r.URL.Path)
 				}
@@ -241,6 +624,10 @@ func TestConfigureDynamically(t *testing.T) {
 		Backends:            backends,
 		Servers:             servers,
 		ControllerPodsCount: 2,
+		IPAccess: ingress.IPAccess{
+			Denied:  []string{"10.0.0.0/8"},
+			Allowed: []string{},
+		},
 	}
 
 	err = configureDynamically(commonConfig, false)
@@ -416,6 +803,115 @@ func TestNextPowerOf2(t *testing.T) {
 	}
 }
 
+func TestIngressForConfigError(t *testing.T) {
+	content := []byte(`http {
+    ## start server foo.bar
+    server {
+        server_name foo.bar ;
+        bad_server_directive;
+
+        location / {
+            set $namespace      "default";
+            set $ingress_name   "demo";
+            set $service_name   "demo-svc";
+
+            unknown_directive on;
+        }
+
+        location /other {
+            set $namespace      "other";
+            set $ingress_name   "other-demo";
+            set $service_name   "other-svc";
+
+            if ($request_method = POST) {
+                unknown_if_directive on;
+            }
+        }
+
+        location ~* "^/bad[" {
+            set $namespace      "default";
+            set $ingress_name   "bad-regex";
+        }
+    }
+    ## end server foo.bar
+}
+`)
+
+	testCases := []struct {
+		name      string
+		err       error
+		namespace string
+		ingress   string
+		found     bool
+	}{
+		{"location directive", fmt.Errorf(`nginx: [emerg] unknown directive "unknown_directive" in /tmp/nginx-cfg123:12`), "default", "demo", true},
+		{"location line of the second Ingress", fmt.Errorf(`nginx: [emerg] duplicate location "/other" in /tmp/nginx-cfg123:15`), "other", "other-demo", true},
+		{"directive nested in a location", fmt.Errorf(`nginx: [emerg] unknown directive "unknown_if_directive" in /tmp/nginx-cfg123:21`), "other", "other-demo", true},
+		{"location regex", fmt.Errorf(`nginx: [emerg] pcre_compile() failed: missing ] in /tmp/nginx-cfg123:25`), "default", "bad-regex", true},
+		{"server directive", fmt.Errorf(`nginx: [emerg] unknown directive "bad_server_directive" in /tmp/nginx-cfg123:5`), "", "", false},
+		{"line out of range", fmt.Errorf(`nginx: [emerg] unexpected end of file in /tmp/nginx-cfg123:100`), "", "", false},
+		{"no line number", fmt.Errorf(`nginx: [emerg] invalid configuration`), "", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespace, name, found := ingressForConfigError(content, tc.err)
+			if found != tc.found {
+				t.Fatalf("expected found %v but returned %v", tc.found, found)
+			}
+			if namespace != tc.namespace || name != tc.ingress {
+				t.Errorf("expected Ingress %v/%v but returned %v/%v", tc.namespace, tc.ingress, namespace, name)
+			}
+		})
+	}
+}
+
+func TestSnippetForConfigError(t *testing.T) {
+	content := []byte(`http {
+    # Custom code snippet configured in the configuration configmap (http-snippet)
+    unknown_http_directive on;
+    # End of custom code snippet (http-snippet)
+
+    server {
+        location / {
+            set $namespace      "default";
+            set $ingress_name   "demo";
+
+            # Custom code snippet configured in the configuration configmap (location-snippet)
+            unknown_location_directive on;
+            # End of custom code snippet (location-snippet)
+            unknown_directive on;
+        }
+    }
+}
+`)
+
+	testCases := []struct {
+		name  string
+		err   error
+		key   string
+		found bool
+	}{
+		{"http snippet", fmt.Errorf(`nginx: [emerg] unknown directive "unknown_http_directive" in /tmp/nginx-cfg123:3`), "http-snippet", true},
+		{"location snippet", fmt.Errorf(`nginx: [emerg] unknown directive "unknown_location_directive" in /tmp/nginx-cfg123:12`), "location-snippet", true},
+		{"after snippet", fmt.Errorf(`nginx: [emerg] unknown directive "unknown_directive" in /tmp/nginx-cfg123:14`), "", false},
+		{"line out of range", fmt.Errorf(`nginx: [emerg] unexpected end of file in /tmp/nginx-cfg123:100`), "", false},
+		{"no line number", fmt.Errorf(`nginx: [emerg] invalid configuration`), "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, found := snippetForConfigError(content, tc.err)
+			if found != tc.found {
+				t.Fatalf("expected found %v but returned %v", tc.found, found)
+			}
+			if key != tc.key {
+				t.Errorf("expected key %q but returned %q", tc.key, key)
+			}
+		})
+	}
+}
+
 func TestCleanTempNginxCfg(t *testing.T) {
 	err := cleanTempNginxCfg()
 	if err != nil {
@@ -470,3 +966,108 @@ func TestCleanTempNginxCfg(t *testing.T) {
 		t.Errorf("expected one file but %d were found", len(files))
 	}
 }
+
+func TestStageStreamCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stream-certificates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pem := filepath.Join(dir, "stream-default-db.pem")
+	err = ioutil.WriteFile(pem, []byte("old"), file.ReadWriteByUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := []ingress.L4Service{
+		{
+			SSLCert: &ingress.SSLCert{
+				PemFileName: pem,
+				PemSHA:      file.SHA1(pem) + "-new",
+				PemCertKey:  "new",
+			},
+		},
+	}
+
+	staged, err := stageStreamCertificates(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged) != 1 {
+		t.Fatalf("expected one staged certificate but %v were returned", len(staged))
+	}
+
+	content := staged.replace([]byte(fmt.Sprintf("ssl_certificate %v;", pem)))
+	if strings.Contains(string(content), pem+";") {
+		t.Errorf("expected the configuration to reference the staged certificate: %s", content)
+	}
+
+	staged.discard()
+
+	b, _ := ioutil.ReadFile(pem)
+	if string(b) != "old" {
+		t.Errorf("expected the certificate in use to be untouched after discarding but it contains %q", b)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("expected the staged certificate to be removed but %v files were found", len(files))
+	}
+
+	staged, err = stageStreamCertificates(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = staged.commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ = ioutil.ReadFile(pem)
+	if string(b) != "new" {
+		t.Errorf("expected the certificate in use to be replaced after committing but it contains %q", b)
+	}
+
+	files, _ = ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("expected no staged certificate after committing but %v files were found", len(files))
+	}
+}
+
+func TestBuildRedirects(t *testing.T) {
+	cert := ingress.SSLCert{
+		PemFileName: "/etc/ingress-controller/ssl/default-example.pem",
+		PemSHA:      "sha",
+		CN:          []string{"example.com", "www.example.com"},
+	}
+
+	servers := []*ingress.Server{
+		{Hostname: "example.com", RedirectFromToWWW: true, SSLCert: cert},
+		{Hostname: "www.foo.com", RedirectFromToWWW: true, SSLCert: cert},
+		{Hostname: "bar.com", RedirectFromToWWW: true},
+		{Hostname: "www.bar.com"},
+		{Hostname: "*.example.com", RedirectFromToWWW: true},
+		{Hostname: "baz.com"},
+	}
+
+	redirects := buildRedirects(servers)
+	if len(redirects) != 2 {
+		t.Fatalf("expected 2 redirects but returned %v", len(redirects))
+	}
+
+	if redirects[0].From != "www.example.com" || redirects[0].To != "example.com" {
+		t.Errorf("expected a redirect from www.example.com to example.com but returned %v to %v", redirects[0].From, redirects[0].To)
+	}
+	if redirects[0].SSLCert.PemFileName != cert.PemFileName {
+		t.Errorf("expected the certificate of example.com in the redirect but returned %v", redirects[0].SSLCert.PemFileName)
+	}
+
+	if redirects[1].From != "foo.com" || redirects[1].To != "www.foo.com" {
+		t.Errorf("expected a redirect from foo.com to www.foo.com but returned %v to %v", redirects[1].From, redirects[1].To)
+	}
+	if redirects[1].SSLCert.PemFileName != "" {
+		t.Errorf("expected no certificate in the redirect because foo.com is not included in the certificate")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+)
+
+const (
+	// orphanNoService is the type of the Ingresses routing traffic to a Service that does not exist
+	orphanNoService = "no-service"
+	// orphanNoEndpoint is the type of the Ingresses routing traffic to a Service without active Endpoints
+	orphanNoEndpoint = "no-endpoint"
+
+	// orphanedIngressCheckPeriod is the period at which the Events of the orphaned Ingresses are checked
+	orphanedIngressCheckPeriod = 1 * time.Minute
+	// orphanedIngressEventPeriod is the period at which the Events of an orphaned Ingress are repeated
+	orphanedIngressEventPeriod = 30 * time.Minute
+)
+
+// orphanedIngress contains an orphaned Ingress and the time of its last Event
+type orphanedIngress struct {
+	ing       *ingress.Ingress
+	lastEvent time.Time
+}
+
+// orphanedIngresses contains the Ingresses routing traffic to a Service
+// that does not exist or does not have any active Endpoint
+type orphanedIngresses struct {
+	lock    *sync.Mutex
+	entries map[collectors.OrphanedIngress]*orphanedIngress
+}
+
+func newOrphanedIngresses() *orphanedIngresses {
+	return &orphanedIngresses{
+		lock:    &sync.Mutex{},
+		entries: map[collectors.OrphanedIngress]*orphanedIngress{},
+	}
+}
+
+// getOrphanedIngresses returns the Ingresses with at least one backend
+// without a Service or without active Endpoints
+func getOrphanedIngresses(ingresses []*ingress.Ingress, backends []*ingress.Backend) map[collectors.OrphanedIngress]*ingress.Ingress {
+	upstreams := make(map[string]*ingress.Backend, len(backends))
+	for _, backend := range backends {
+		upstreams[backend.Name] = backend
+	}
+
+	orphaned := map[collectors.OrphanedIngress]*ingress.Ingress{}
+	for _, ing := range ingresses {
+		names := []string{}
+		if ing.Spec.Backend != nil {
+			names = append(names, upstreamName(ing.Namespace, ing.Spec.Backend.ServiceName, ing.Spec.Backend.ServicePort))
+		}
+
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for _, path := range rule.HTTP.Paths {
+				names = append(names, upstreamName(ing.Namespace, path.Backend.ServiceName, path.Backend.ServicePort))
+			}
+		}
+
+		for _, name := range names {
+			upstream, ok := upstreams[name]
+			if !ok {
+				continue
+			}
+
+			orphanType := ""
+			if upstream.Service == nil {
+				orphanType = orphanNoService
+			} else if len(upstream.Endpoints) == 0 {
+				orphanType = orphanNoEndpoint
+			} else {
+				continue
+			}
+
+			orphaned[collectors.OrphanedIngress{
+				Namespace: ing.Namespace,
+				Ingress:   ing.Name,
+				Type:      orphanType,
+			}] = ing
+		}
+	}
+
+	return orphaned
+}
+
+// updateOrphanedIngresses updates the metric of the orphaned Ingresses and
+// reports an Event for the Ingresses that were not orphaned before
+func (n *NGINXController) updateOrphanedIngresses(ingresses []*ingress.Ingress, backends []*ingress.Backend) {
+	orphaned := getOrphanedIngresses(ingresses, backends)
+
+	metrics := make([]collectors.OrphanedIngress, 0, len(orphaned))
+	for key := range orphaned {
+		metrics = append(metrics, key)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Namespace != metrics[j].Namespace {
+			return metrics[i].Namespace < metrics[j].Namespace
+		}
+		if metrics[i].Ingress != metrics[j].Ingress {
+			return metrics[i].Ingress < metrics[j].Ingress
+		}
+		return metrics[i].Type < metrics[j].Type
+	})
+	n.metricCollector.SetOrphanedIngresses(metrics)
+
+	n.orphaned.lock.Lock()
+	defer n.orphaned.lock.Unlock()
+
+	entries := make(map[collectors.OrphanedIngress]*orphanedIngress, len(orphaned))
+	for _, key := range metrics {
+		entry, ok := n.orphaned.entries[key]
+		if !ok {
+			entry = &orphanedIngress{}
+			n.recordOrphanedIngress(key, orphaned[key])
+			entry.lastEvent = time.Now()
+		}
+
+		entry.ing = orphaned[key]
+		entries[key] = entry
+	}
+
+	n.orphaned.entries = entries
+}
+
+// reportOrphanedIngresses repeats the Events of the Ingresses that are
+// still orphaned after orphanedIngressEventPeriod
+func (n *NGINXController) reportOrphanedIngresses() {
+	n.orphaned.lock.Lock()
+	defer n.orphaned.lock.Unlock()
+
+	for key, entry := range n.orphaned.entries {
+		if time.Since(entry.lastEvent) < orphanedIngressEventPeriod {
+			continue
+		}
+
+		n.recordOrphanedIngress(key, entry.ing)
+		entry.lastEvent = time.Now()
+	}
+}
+
+func (n *NGINXController) recordOrphanedIngress(key collectors.OrphanedIngress, ing *ingress.Ingress) {
+	reason := "Service without active Endpoints"
+	if key.Type == orphanNoService {
+		reason = "Service not found"
+	}
+
+	klog.Warningf("Ingress %v/%v is routing traffic to the default backend: %v", key.Namespace, key.Ingress, reason)
+	n.recorder.Eventf(&ing.Ingress, apiv1.EventTypeWarning, "ORPHANED",
+		"The traffic of at least one path is routed to the default backend: %v", reason)
+}
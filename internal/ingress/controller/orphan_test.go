@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+)
+
+func newOrphanTestIngress(name string, services ...string) *ingress.Ingress {
+	paths := []extensions.HTTPIngressPath{}
+	for _, service := range services {
+		paths = append(paths, extensions.HTTPIngressPath{
+			Path: "/" + service,
+			Backend: extensions.IngressBackend{
+				ServiceName: service,
+				ServicePort: intstr.FromInt(80),
+			},
+		})
+	}
+
+	return &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: extensions.IngressSpec{
+				Rules: []extensions.IngressRule{
+					{
+						Host: "example.com",
+						IngressRuleValue: extensions.IngressRuleValue{
+							HTTP: &extensions.HTTPIngressRuleValue{
+								Paths: paths,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newOrphanTestBackends() []*ingress.Backend {
+	return []*ingress.Backend{
+		{
+			Name:      "default-ready-80",
+			Service:   &apiv1.Service{},
+			Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "8080"}},
+		},
+		{
+			Name:    "default-empty-80",
+			Service: &apiv1.Service{},
+		},
+		{
+			Name: "default-missing-80",
+		},
+	}
+}
+
+func TestGetOrphanedIngresses(t *testing.T) {
+	ings := []*ingress.Ingress{
+		newOrphanTestIngress("ready", "ready"),
+		newOrphanTestIngress("empty", "ready", "empty", "empty"),
+		newOrphanTestIngress("both", "missing", "empty"),
+		newOrphanTestIngress("unknown", "unknown"),
+	}
+
+	orphaned := getOrphanedIngresses(ings, newOrphanTestBackends())
+
+	expected := map[collectors.OrphanedIngress]*ingress.Ingress{
+		{Namespace: "default", Ingress: "empty", Type: orphanNoEndpoint}: ings[1],
+		{Namespace: "default", Ingress: "both", Type: orphanNoService}:   ings[2],
+		{Namespace: "default", Ingress: "both", Type: orphanNoEndpoint}:  ings[2],
+	}
+	if !reflect.DeepEqual(orphaned, expected) {
+		t.Errorf("expected %v but returned %v", expected, orphaned)
+	}
+}
+
+func TestUpdateOrphanedIngresses(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{
+		recorder:        recorder,
+		metricCollector: metric.DummyCollector{},
+		orphaned:        newOrphanedIngresses(),
+	}
+
+	ings := []*ingress.Ingress{newOrphanTestIngress("empty", "empty")}
+	backends := newOrphanTestBackends()
+
+	n.updateOrphanedIngresses(ings, backends)
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event for a new orphaned Ingress but %v were recorded", len(recorder.Events))
+	}
+	<-recorder.Events
+
+	n.updateOrphanedIngresses(ings, backends)
+	n.reportOrphanedIngresses()
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event for an Ingress already reported but %v were recorded", len(recorder.Events))
+	}
+
+	for _, entry := range n.orphaned.entries {
+		entry.lastEvent = entry.lastEvent.Add(-orphanedIngressEventPeriod)
+	}
+	n.reportOrphanedIngresses()
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected the event to be repeated after %v but %v were recorded", orphanedIngressEventPeriod, len(recorder.Events))
+	}
+
+	n.updateOrphanedIngresses([]*ingress.Ingress{}, backends)
+	if len(n.orphaned.entries) != 0 {
+		t.Errorf("expected no orphaned Ingresses but %v were returned", len(n.orphaned.entries))
+	}
+}
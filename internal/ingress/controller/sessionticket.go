@@ -0,0 +1,309 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+const (
+	// sessionTicketKeysRotatedAnnotation contains the time of the last
+	// rotation of the keys in the Secret, in RFC3339 format
+	sessionTicketKeysRotatedAnnotation = "nginx.ingress.kubernetes.io/ssl-session-ticket-keys-rotated-at"
+
+	// size of the generated keys, AES256 keys for tickets
+	// https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_session_ticket_key
+	sessionTicketKeySize = 80
+
+	sessionTicketKeysSyncPeriod = 30 * time.Second
+)
+
+// Keys of the Secret containing the session ticket keys, in the order they are
+// configured in NGINX. The current key encrypts new tickets and all of them
+// decrypt tickets. The next key is distributed before it is used, so all the
+// replicas can decrypt the tickets issued by the first one rotating the keys.
+var sessionTicketKeyNames = []string{"current.key", "next.key", "previous.key"}
+
+// sessionTicketKeys keeps the TLS session ticket keys of a Secret shared
+// by all the replicas of the controller in files read by NGINX
+type sessionTicketKeys struct {
+	client         clientset.Interface
+	namespace      string
+	name           string
+	rotationPeriod time.Duration
+	fs             file.Filesystem
+
+	mu       sync.RWMutex
+	files    []string
+	checksum string
+}
+
+func newSessionTicketKeys(client clientset.Interface, secret string, rotationPeriod time.Duration, fs file.Filesystem) (*sessionTicketKeys, error) {
+	ns, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sessionTicketKeys{
+		client:         client,
+		namespace:      ns,
+		name:           name,
+		rotationPeriod: rotationPeriod,
+		fs:             fs,
+	}, nil
+}
+
+// Files returns the files with the keys, the first one is
+// used to encrypt tickets
+func (k *sessionTicketKeys) Files() []string {
+	if k == nil {
+		return nil
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.files
+}
+
+// Checksum returns the checksum of the content of the keys
+func (k *sessionTicketKeys) Checksum() string {
+	if k == nil {
+		return ""
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.checksum
+}
+
+// sync reads the keys from the Secret, rotating them when it is
+// required, and writes them to disk. Returns true if the keys changed.
+func (k *sessionTicketKeys) sync() (bool, error) {
+	secret, err := k.client.CoreV1().Secrets(k.namespace).Get(k.name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) && k.rotationPeriod > 0 {
+		secret, err = k.create()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if k.rotationPeriod > 0 && needsSessionTicketKeyRotation(secret, k.rotationPeriod, time.Now()) {
+		secret, err = k.rotate(secret)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var files []string
+	hash := sha1.New()
+	for _, name := range sessionTicketKeyNames {
+		key, ok := secret.Data[name]
+		if !ok {
+			continue
+		}
+
+		if len(key) != 48 && len(key) != 80 {
+			klog.Warningf("Ignoring key %q of Secret %v/%v: session ticket keys must contain either 48 or 80 bytes", name, k.namespace, k.name)
+			continue
+		}
+
+		fileName := sessionTicketKeyFile(name)
+		err := k.writeKey(fileName, key)
+		if err != nil {
+			return false, err
+		}
+
+		files = append(files, fileName)
+		hash.Write(key)
+	}
+
+	if len(files) == 0 || files[0] != sessionTicketKeyFile(sessionTicketKeyNames[0]) {
+		return false, fmt.Errorf("Secret %v/%v does not contain a valid %q session ticket key", k.namespace, k.name, sessionTicketKeyNames[0])
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if checksum == k.checksum {
+		return false, nil
+	}
+
+	k.files = files
+	k.checksum = checksum
+
+	return true, nil
+}
+
+func sessionTicketKeyFile(name string) string {
+	return fmt.Sprintf("%v/ticket-%v", file.DefaultSSLDirectory, name)
+}
+
+func (k *sessionTicketKeys) writeKey(fileName string, key []byte) error {
+	f, err := k.fs.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(key)
+	return err
+}
+
+// create creates the Secret with new keys. When another replica
+// creates it first the Secret of the replica is returned.
+func (k *sessionTicketKeys) create() (*apiv1.Secret, error) {
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: k.namespace,
+			Name:      k.name,
+		},
+		Data: map[string][]byte{},
+	}
+
+	for _, name := range sessionTicketKeyNames[:2] {
+		key, err := newSessionTicketKey()
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[name] = key
+	}
+
+	setSessionTicketKeysRotated(secret, time.Now())
+
+	klog.Infof("Creating Secret %v/%v with TLS session ticket keys", k.namespace, k.name)
+	created, err := k.client.CoreV1().Secrets(k.namespace).Create(secret)
+	if k8sErrors.IsAlreadyExists(err) {
+		return k.client.CoreV1().Secrets(k.namespace).Get(k.name, metav1.GetOptions{})
+	}
+
+	return created, err
+}
+
+// rotate updates the keys of the Secret. The update fails if
+// another replica already updated the Secret, in that case the
+// Secret of the replica is returned.
+func (k *sessionTicketKeys) rotate(secret *apiv1.Secret) (*apiv1.Secret, error) {
+	rotated := secret.DeepCopy()
+	err := rotateSessionTicketKeys(rotated, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Rotating TLS session ticket keys of Secret %v/%v", k.namespace, k.name)
+	updated, err := k.client.CoreV1().Secrets(k.namespace).Update(rotated)
+	if k8sErrors.IsConflict(err) {
+		return k.client.CoreV1().Secrets(k.namespace).Get(k.name, metav1.GetOptions{})
+	}
+
+	return updated, err
+}
+
+// needsSessionTicketKeyRotation returns true if the keys of the
+// Secret were rotated more than one rotation period ago
+func needsSessionTicketKeyRotation(secret *apiv1.Secret, period time.Duration, now time.Time) bool {
+	rotated, err := time.Parse(time.RFC3339, secret.Annotations[sessionTicketKeysRotatedAnnotation])
+	if err != nil {
+		return true
+	}
+
+	return !now.Before(rotated.Add(period))
+}
+
+// rotateSessionTicketKeys replaces the previous key with the current one,
+// the current key with the next one and generates a new next key
+func rotateSessionTicketKeys(secret *apiv1.Secret, now time.Time) error {
+	key, err := newSessionTicketKey()
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	current, next, previous := sessionTicketKeyNames[0], sessionTicketKeyNames[1], sessionTicketKeyNames[2]
+
+	if _, ok := secret.Data[current]; ok {
+		secret.Data[previous] = secret.Data[current]
+	}
+	if _, ok := secret.Data[next]; ok {
+		secret.Data[current] = secret.Data[next]
+	} else {
+		// keys provided without a next key, the new key is used at once
+		secret.Data[current] = key
+		key, err = newSessionTicketKey()
+		if err != nil {
+			return err
+		}
+	}
+	secret.Data[next] = key
+
+	setSessionTicketKeysRotated(secret, now)
+
+	return nil
+}
+
+func setSessionTicketKeysRotated(secret *apiv1.Secret, now time.Time) {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[sessionTicketKeysRotatedAnnotation] = now.UTC().Format(time.RFC3339)
+}
+
+func newSessionTicketKey() ([]byte, error) {
+	key := make([]byte, sessionTicketKeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, fmt.Errorf("generating session ticket key: %v", err)
+	}
+
+	return key, nil
+}
+
+// syncSessionTicketKeys synchronizes the session ticket keys and
+// triggers an update of the configuration when they change
+func (n *NGINXController) syncSessionTicketKeys() {
+	changed, err := n.sessionTicketKeys.sync()
+	if err != nil {
+		klog.Errorf("Error synchronizing TLS session ticket keys: %v", err)
+		return
+	}
+
+	if changed {
+		klog.Infof("TLS session ticket keys changed")
+		n.syncQueue.EnqueueTask(task.GetDummyObject("session-ticket-keys"))
+	}
+}
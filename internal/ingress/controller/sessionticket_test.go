@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/file"
+)
+
+func newTestSessionTicketKeys(t *testing.T, rotationPeriod time.Duration, objects ...*apiv1.Secret) *sessionTicketKeys {
+	client := fake.NewSimpleClientset()
+	for _, secret := range objects {
+		_, err := client.CoreV1().Secrets(secret.Namespace).Create(secret)
+		if err != nil {
+			t.Fatalf("unexpected error creating Secret: %v", err)
+		}
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error creating filesystem: %v", err)
+	}
+
+	keys, err := newSessionTicketKeys(client, "default/tickets", rotationPeriod, fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return keys
+}
+
+func newTestSessionTicketKey(t *testing.T) []byte {
+	key, err := newSessionTicketKey()
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	return key
+}
+
+func TestNeedsSessionTicketKeyRotation(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		annotation string
+		expected   bool
+	}{
+		"without annotation": {"", true},
+		"invalid annotation": {"yesterday", true},
+		"recent rotation":    {"2019-03-01T11:30:00Z", false},
+		"due rotation":       {"2019-03-01T11:00:00Z", true},
+	}
+
+	for name, tc := range testCases {
+		secret := &apiv1.Secret{}
+		if tc.annotation != "" {
+			secret.Annotations = map[string]string{sessionTicketKeysRotatedAnnotation: tc.annotation}
+		}
+
+		if needs := needsSessionTicketKeyRotation(secret, time.Hour, now); needs != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, needs)
+		}
+	}
+}
+
+func TestRotateSessionTicketKeys(t *testing.T) {
+	current, next := newTestSessionTicketKey(t), newTestSessionTicketKey(t)
+	secret := &apiv1.Secret{
+		Data: map[string][]byte{
+			"current.key": current,
+			"next.key":    next,
+		},
+	}
+
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := rotateSessionTicketKeys(secret, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(secret.Data["previous.key"], current) {
+		t.Errorf("expected the current key to become the previous key")
+	}
+	if !bytes.Equal(secret.Data["current.key"], next) {
+		t.Errorf("expected the next key to become the current key")
+	}
+	if len(secret.Data["next.key"]) != sessionTicketKeySize || bytes.Equal(secret.Data["next.key"], next) {
+		t.Errorf("expected a new next key")
+	}
+	if secret.Annotations[sessionTicketKeysRotatedAnnotation] != "2019-03-01T12:00:00Z" {
+		t.Errorf("unexpected rotation time %v", secret.Annotations[sessionTicketKeysRotatedAnnotation])
+	}
+}
+
+func TestSessionTicketKeysSync(t *testing.T) {
+	keys := newTestSessionTicketKeys(t, 0, &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tickets"},
+		Data: map[string][]byte{
+			"current.key":  newTestSessionTicketKey(t),
+			"previous.key": bytes.Repeat([]byte("a"), 48),
+			"next.key":     []byte("invalid"),
+		},
+	})
+
+	changed, err := keys.sync()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected the keys to change")
+	}
+
+	expected := []string{sessionTicketKeyFile("current.key"), sessionTicketKeyFile("previous.key")}
+	files := keys.Files()
+	if len(files) != len(expected) || files[0] != expected[0] || files[1] != expected[1] {
+		t.Errorf("expected files %v but returned %v", expected, files)
+	}
+	if keys.Checksum() == "" {
+		t.Errorf("expected a checksum")
+	}
+
+	changed, err = keys.sync()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Errorf("expected the keys not to change")
+	}
+}
+
+func TestSessionTicketKeysSyncWithoutCurrentKey(t *testing.T) {
+	keys := newTestSessionTicketKeys(t, 0, &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tickets"},
+		Data: map[string][]byte{
+			"next.key": newTestSessionTicketKey(t),
+		},
+	})
+
+	_, err := keys.sync()
+	if err == nil {
+		t.Errorf("expected an error without a current key")
+	}
+	if keys.Files() != nil {
+		t.Errorf("expected no files")
+	}
+}
+
+func TestSessionTicketKeysSyncRotation(t *testing.T) {
+	keys := newTestSessionTicketKeys(t, time.Hour)
+
+	changed, err := keys.sync()
+	if err != nil {
+		t.Fatalf("unexpected error creating the Secret: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected the keys to change")
+	}
+
+	secret, err := keys.client.CoreV1().Secrets("default").Get("tickets", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secret.Data["current.key"]) != sessionTicketKeySize || len(secret.Data["next.key"]) != sessionTicketKeySize {
+		t.Errorf("expected the Secret to contain a current and a next key")
+	}
+
+	checksum := keys.Checksum()
+	current := secret.Data["current.key"]
+
+	secret.Annotations[sessionTicketKeysRotatedAnnotation] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	_, err = keys.client.CoreV1().Secrets("default").Update(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changed, err = keys.sync()
+	if err != nil {
+		t.Fatalf("unexpected error rotating the keys: %v", err)
+	}
+	if !changed || keys.Checksum() == checksum {
+		t.Errorf("expected the keys to change after the rotation")
+	}
+
+	secret, err = keys.client.CoreV1().Secrets("default").Get("tickets", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(secret.Data["previous.key"], current) {
+		t.Errorf("expected the current key to become the previous key")
+	}
+	if len(keys.Files()) != 3 {
+		t.Errorf("expected three keys but returned %v", keys.Files())
+	}
+}
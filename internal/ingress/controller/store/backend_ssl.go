@@ -19,6 +19,7 @@ package store
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/imdario/mergo"
 	"k8s.io/klog"
@@ -46,13 +47,24 @@ func (s *k8sStore) syncSecret(key string) {
 	if err != nil {
 		if !isErrSecretForAuth(err) {
 			klog.Warningf("Error obtaining X.509 certificate: %v", err)
+			s.recordCertificateIssues(key, []string{err.Error()}, nil)
 		}
 		return
 	}
 
+	s.checkCertificate(key, cert)
+
 	// create certificates and add or update the item in the store
 	cur, err := s.GetLocalSSLCert(key)
 	if err == nil {
+		if cur.PemSHA == cert.PemSHA && cur.PemCertKey == cert.PemCertKey {
+			// the certificate did not change, keep the OCSP response
+			cert.OCSPResponse = cur.OCSPResponse
+			cert.OCSPFileName = cur.OCSPFileName
+			cert.OCSPRefreshTime = cur.OCSPRefreshTime
+			cert.OCSPExpireTime = cur.OCSPExpireTime
+		}
+
 		if cur.Equal(cert) {
 			// no need to update
 			return
@@ -75,7 +87,7 @@ func (s *k8sStore) syncSecret(key string) {
 // getPemCertificate receives a secret, and creates a ingress.SSLCert as return.
 // It parses the secret and verifies if it's a keypair, or a 'ca.crt' secret only.
 func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error) {
-	secret, err := s.listers.Secret.ByKey(secretName)
+	secret, err := s.GetSecret(secretName)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +95,7 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 	cert, okcert := secret.Data[apiv1.TLSCertKey]
 	key, okkey := secret.Data[apiv1.TLSPrivateKeyKey]
 	ca := secret.Data["ca.crt"]
+	crl := secret.Data["ca.crl"]
 
 	auth := secret.Data["auth"]
 
@@ -98,7 +111,9 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 			return nil, fmt.Errorf("key 'tls.key' missing from Secret %q", secretName)
 		}
 
-		if s.isDynamicCertificatesEnabled {
+		// the default certificate is always written to disk because NGINX
+		// uses it when there is no certificate for the requested hostname
+		if s.isDynamicCertificatesEnabled && secretName != s.defaultSSLCertificate {
 			sslCert, err = ssl.CreateSSLCert(nsSecName, cert, key, ca)
 			if err != nil {
 				return nil, fmt.Errorf("unexpected error creating SSL Cert: %v", err)
@@ -137,12 +152,107 @@ func (s *k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error
 		return nil, fmt.Errorf("secret %q contains no keypair or CA certificate", secretName)
 	}
 
+	if ca != nil && crl != nil {
+		err = ssl.ConfigureCRL(nsSecName, crl, sslCert, s.filesystem)
+		if err != nil {
+			return nil, err
+		}
+
+		klog.V(3).Infof("Configuring Secret %q for TLS authentication with a CRL", secretName)
+	}
+
 	sslCert.Name = secret.Name
 	sslCert.Namespace = secret.Namespace
 
 	return sslCert, nil
 }
 
+// checkCertificate verifies the certificate chain of a TLS Secret and that the
+// certificate is valid for the hosts it is configured for in the Ingresses
+// referencing it. Each issue is reported as a Warning event on those Ingresses,
+// because NGINX uses the default certificate for hosts without a valid one.
+func (s *k8sStore) checkCertificate(key string, cert *ingress.SSLCert) {
+	secret, err := s.GetSecret(key)
+	if err != nil {
+		return
+	}
+
+	chain, ok := secret.Data[apiv1.TLSCertKey]
+	if !ok {
+		// CA certificate used for authentication
+		return
+	}
+
+	issues := ssl.VerifyCertificateChain(chain, time.Now())
+	s.recordCertificateIssues(key, issues, cert)
+}
+
+// recordCertificateIssues logs the issues found loading the certificate of the
+// Secret key and emits them as Warning events on the Ingresses referencing it.
+// When cert is not nil, the hosts of the TLS sections using the Secret are also
+// checked against the names contained in the certificate. The issues are only
+// reported when they differ from the ones of the previous synchronization, the
+// caller must hold syncSecretMu.
+func (s *k8sStore) recordCertificateIssues(key string, issues []string, cert *ingress.SSLCert) {
+	previous := s.certificateIssues[key]
+	reported := map[string]string{}
+	if len(issues) > 0 {
+		reported[""] = strings.Join(issues, "\n")
+	}
+
+	if reported[""] != previous[""] {
+		for _, issue := range issues {
+			klog.Warningf("Invalid SSL certificate in Secret %q: %v", key, issue)
+		}
+	}
+
+	_, secretName, _ := k8s.ParseNameNS(key)
+
+	for _, ingKey := range s.secretIngressMap.Reference(key) {
+		ing, err := s.listers.Ingress.ByKey(ingKey)
+		if err != nil {
+			continue
+		}
+
+		ingIssues := append([]string{}, issues...)
+		if cert != nil {
+			for _, tls := range ing.Spec.TLS {
+				if tls.SecretName != secretName {
+					continue
+				}
+
+				for _, host := range tls.Hosts {
+					if !ssl.IsValidHostname(host, cert.CN) {
+						ingIssues = append(ingIssues, fmt.Sprintf("host %q is not included in the certificate (names: %v)", host, cert.CN))
+					}
+				}
+			}
+		}
+
+		if len(ingIssues) == 0 {
+			continue
+		}
+
+		// a new Ingress with the name of a deleted one has a different UID
+		state := strings.Join(ingIssues, "\n")
+		reported[string(ing.UID)] = state
+		if previous[string(ing.UID)] == state {
+			continue
+		}
+
+		for _, issue := range ingIssues {
+			s.recorder.Eventf(ing, apiv1.EventTypeWarning, "CERTIFICATE", "Secret %v: %v", key, issue)
+		}
+	}
+
+	if len(reported) == 0 {
+		delete(s.certificateIssues, key)
+		return
+	}
+
+	s.certificateIssues[key] = reported
+}
+
 func (s *k8sStore) checkSSLChainIssues() {
 	for _, item := range s.ListLocalSSLCerts() {
 		secrKey := k8s.MetaNamespaceKey(item)
@@ -194,6 +304,136 @@ func (s *k8sStore) checkSSLChainIssues() {
 	}
 }
 
+// updateOCSPResponses requests the OCSP responses of the local SSL certificates
+// that do not have one or that should be refreshed. Responses are removed
+// once they expire or when OCSP stapling is disabled.
+func (s *k8sStore) updateOCSPResponses() {
+	enabled := s.GetBackendConfiguration().EnableOCSP
+
+	for _, item := range s.ListLocalSSLCerts() {
+		secrKey := k8s.MetaNamespaceKey(item)
+		cert, err := s.GetLocalSSLCert(secrKey)
+		if err != nil || cert.Certificate == nil {
+			continue
+		}
+
+		now := time.Now()
+
+		if !enabled || len(cert.Certificate.OCSPServer) == 0 {
+			if len(cert.OCSPResponse) > 0 {
+				s.setOCSPResponse(secrKey, cert, nil)
+			}
+			continue
+		}
+
+		if len(cert.OCSPResponse) > 0 && now.Before(cert.OCSPRefreshTime) {
+			continue
+		}
+
+		resp, err := s.fetchOCSPResponse(cert)
+		if err != nil {
+			klog.Warningf("Error obtaining OCSP response for Secret %q: %v", secrKey, err)
+			if len(cert.OCSPResponse) > 0 && !cert.OCSPExpireTime.IsZero() && now.After(cert.OCSPExpireTime) {
+				s.setOCSPResponse(secrKey, cert, nil)
+			}
+			continue
+		}
+
+		if resp.Status != ssl.OCSPGood {
+			klog.Warningf("OCSP responder returned status %v for Secret %q, the response will not be stapled", resp.Status, secrKey)
+			if len(cert.OCSPResponse) > 0 {
+				s.setOCSPResponse(secrKey, cert, nil)
+			}
+			continue
+		}
+
+		s.setOCSPResponse(secrKey, cert, resp)
+	}
+}
+
+// fetchOCSPResponse requests the OCSP response of a certificate
+// using the issuer included in the certificate chain
+func (s *k8sStore) fetchOCSPResponse(cert *ingress.SSLCert) (*ssl.OCSPResponse, error) {
+	chain := []byte(cert.PemCertKey)
+
+	var files []string
+	if len(chain) == 0 && cert.PemFileName != "" {
+		files = append(files, cert.PemFileName)
+	}
+	if cert.FullChainPemFileName != "" {
+		files = append(files, cert.FullChainPemFileName)
+	}
+
+	for _, name := range files {
+		data, err := s.filesystem.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(append(chain, '\n'), data...)
+	}
+
+	issuer, err := ssl.OCSPIssuer(cert.Certificate, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssl.FetchOCSPResponse(cert.Certificate, issuer)
+}
+
+// setOCSPResponse updates the OCSP response of a local SSL certificate,
+// unless the certificate changed while the response was requested
+func (s *k8sStore) setOCSPResponse(secrKey string, cert *ingress.SSLCert, resp *ssl.OCSPResponse) {
+	s.syncSecretMu.Lock()
+	defer s.syncSecretMu.Unlock()
+
+	current, err := s.GetLocalSSLCert(secrKey)
+	if err != nil || current.PemSHA != cert.PemSHA || current.PemCertKey != cert.PemCertKey {
+		return
+	}
+
+	dst := *current
+	dst.OCSPResponse = nil
+	dst.OCSPFileName = ""
+	dst.OCSPRefreshTime = time.Time{}
+	dst.OCSPExpireTime = time.Time{}
+
+	if resp != nil {
+		if !s.isDynamicCertificatesEnabled {
+			ocspFileName := fmt.Sprintf("%v/%v-%v.ocsp", file.DefaultSSLDirectory, current.Namespace, current.Name)
+			err := writeFile(s.filesystem, ocspFileName, resp.Raw)
+			if err != nil {
+				klog.Errorf("Error writing OCSP response for Secret %q: %v", secrKey, err)
+				return
+			}
+			dst.OCSPFileName = ocspFileName
+		}
+
+		dst.OCSPResponse = resp.Raw
+		dst.OCSPRefreshTime = resp.RefreshTime()
+		dst.OCSPExpireTime = resp.NextUpdate
+
+		klog.Infof("Updating OCSP response of SSL certificate %q (next update %v)", secrKey, resp.NextUpdate)
+	} else {
+		klog.Infof("Removing OCSP response of SSL certificate %q", secrKey)
+	}
+
+	s.sslStore.Update(secrKey, &dst)
+	// this update must trigger an update
+	// (like an update event from a change in Ingress)
+	s.sendDummyEvent()
+}
+
+func writeFile(fs file.Filesystem, name string, data []byte) error {
+	f, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	return err
+}
+
 // sendDummyEvent sends a dummy event to trigger an update
 // This is used in when a secret change
 func (s *k8sStore) sendDummyEvent() {
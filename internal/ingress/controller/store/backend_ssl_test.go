@@ -18,11 +18,19 @@ package store
 
 import (
 	"encoding/base64"
+	"strings"
+	"testing"
 
 	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	testclient "k8s.io/client-go/kubernetes/fake"
 	cache_client "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
 )
 
 const (
@@ -221,3 +229,141 @@ func TestGetPemCertificate(t *testing.T) {
 	}
 }
 */
+
+func TestGetPemCertificateDefaultSSLCertificate(t *testing.T) {
+	crt, key, _, err := buildCrtKeyAndCA()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := &k8sStore{
+		listers:                      &Lister{},
+		filesystem:                   fs,
+		isDynamicCertificatesEnabled: true,
+		defaultSSLCertificate:        "ingress-nginx/default-tls",
+	}
+	s.listers.Secret.Store = cache_client.NewStore(cache_client.MetaNamespaceKeyFunc)
+	s.listers.DefaultSSLCertificate.Store = cache_client.NewStore(cache_client.MetaNamespaceKeyFunc)
+
+	for _, secrKey := range []string{"default/foo-tls", s.defaultSSLCertificate} {
+		ns, name, _ := k8s.ParseNameNS(secrKey)
+		secret := &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Data:       map[string][]byte{apiv1.TLSCertKey: crt, apiv1.TLSPrivateKeyKey: key},
+		}
+		if secrKey == s.defaultSSLCertificate {
+			s.listers.DefaultSSLCertificate.Add(secret)
+		} else {
+			s.listers.Secret.Add(secret)
+		}
+	}
+
+	sslCert, err := s.getPemCertificate("default/foo-tls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sslCert.PemFileName != "" || sslCert.PemCertKey == "" {
+		t.Errorf("expected a certificate served dynamically but file %q was returned", sslCert.PemFileName)
+	}
+
+	sslCert, err = s.getPemCertificate(s.defaultSSLCertificate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sslCert.PemFileName == "" {
+		t.Errorf("expected the default certificate to be written to disk")
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	crt, key, _, err := buildCrtKeyAndCA()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	s := &k8sStore{
+		listers:          &Lister{},
+		secretIngressMap:  NewObjectRefMap(),
+		recorder:          recorder,
+		certificateIssues: map[string]map[string]string{},
+	}
+	s.listers.Secret.Store = cache_client.NewStore(cache_client.MetaNamespaceKeyFunc)
+	s.listers.Ingress.Store = cache_client.NewStore(cache_client.MetaNamespaceKeyFunc)
+
+	s.listers.Secret.Add(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo-tls"},
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       crt,
+			apiv1.TLSPrivateKeyKey: key,
+		},
+	})
+	s.listers.Ingress.Add(&extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "1"},
+		Spec: extensions.IngressSpec{
+			TLS: []extensions.IngressTLS{
+				{Hosts: []string{"nginxsvc", "foo.bar"}, SecretName: "foo-tls"},
+			},
+		},
+	})
+	s.secretIngressMap.Insert("default/foo", "default/foo-tls")
+
+	s.checkCertificate("default/foo-tls", &ingress.SSLCert{CN: []string{"nginxsvc"}})
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning CERTIFICATE") || !strings.Contains(event, `host "foo.bar"`) {
+			t.Errorf("expected a warning about host foo.bar but got %q", event)
+		}
+	default:
+		t.Fatalf("expected an event about the host not included in the certificate")
+	}
+
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event %q", event)
+	default:
+	}
+
+	// the issues are only reported again when they change
+	s.checkCertificate("default/foo-tls", &ingress.SSLCert{CN: []string{"nginxsvc"}})
+
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event for an issue already reported %q", event)
+	default:
+	}
+
+	s.recordCertificateIssues("default/foo-tls", []string{"invalid certificate"}, nil)
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Secret default/foo-tls: invalid certificate") {
+			t.Errorf("expected a warning about the invalid certificate but got %q", event)
+		}
+	default:
+		t.Fatalf("expected an event about the invalid certificate")
+	}
+
+	// an issue fixed and introduced again is reported again
+	s.checkCertificate("default/foo-tls", &ingress.SSLCert{CN: []string{"nginxsvc", "foo.bar"}})
+	if _, ok := s.certificateIssues["default/foo-tls"]; ok {
+		t.Errorf("expected no issue for a valid certificate")
+	}
+
+	s.checkCertificate("default/foo-tls", &ingress.SSLCert{CN: []string{"nginxsvc"}})
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, `host "foo.bar"`) {
+			t.Errorf("expected a warning about host foo.bar but got %q", event)
+		}
+	default:
+		t.Fatalf("expected the issue to be reported again")
+	}
+}
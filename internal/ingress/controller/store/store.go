@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"reflect"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -53,6 +55,7 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
 	"k8s.io/ingress-nginx/internal/k8s"
 )
 
@@ -77,6 +80,9 @@ type Storer interface {
 	// ListIngresses returns a list of all Ingresses in the store.
 	ListIngresses() []*ingress.Ingress
 
+	// ListStreamServices returns a list of all StreamServices in the store.
+	ListStreamServices() []*streamservice.StreamService
+
 	// GetRunningControllerPodsCount returns the number of Running ingress-nginx controller Pods.
 	GetRunningControllerPodsCount() int
 
@@ -89,6 +95,7 @@ type Storer interface {
 	// GetAuthCertificate resolves a given secret name into an SSL certificate.
 	// The secret must contain 3 keys named:
 	//   ca.crt: contains the certificate chain used for authentication
+	//   ca.crl: (optional) contains the revocation list used for authentication
 	GetAuthCertificate(string) (*resolver.AuthSSLCert, error)
 
 	// GetDefaultBackend returns the default backend configuration
@@ -96,6 +103,10 @@ type Storer interface {
 
 	// Run initiates the synchronization of the controllers
 	Run(stopCh chan struct{})
+
+	// ReloadConfigMap reads the configuration ConfigMap from the API server
+	// and parses the Ingresses again
+	ReloadConfigMap() error
 }
 
 // EventType type of event associated with an informer
@@ -126,6 +137,11 @@ type Informer struct {
 	Secret    cache.SharedIndexInformer
 	ConfigMap cache.SharedIndexInformer
 	Pod       cache.SharedIndexInformer
+	// StreamService is only set when StreamServices are enabled
+	StreamService cache.SharedIndexInformer
+	// DefaultSSLCertificate is only set when the secret with the default
+	// SSL certificate is not located in the watched namespace
+	DefaultSSLCertificate cache.SharedIndexInformer
 }
 
 // Lister contains object listers (stores).
@@ -137,6 +153,8 @@ type Lister struct {
 	ConfigMap             ConfigMapLister
 	IngressWithAnnotation IngressWithAnnotationsLister
 	Pod                   PodLister
+	StreamService         StreamServiceLister
+	DefaultSSLCertificate SecretLister
 }
 
 // NotExistsError is returned when an object does not exist in a local store.
@@ -155,14 +173,24 @@ func (i *Informer) Run(stopCh chan struct{}) {
 	go i.ConfigMap.Run(stopCh)
 	go i.Pod.Run(stopCh)
 
-	// wait for all involved caches to be synced before processing items
-	// from the queue
-	if !cache.WaitForCacheSync(stopCh,
+	cacheSyncs := []cache.InformerSynced{
 		i.Endpoint.HasSynced,
 		i.Service.HasSynced,
 		i.Secret.HasSynced,
 		i.ConfigMap.HasSynced,
-	) {
+	}
+	if i.StreamService != nil {
+		go i.StreamService.Run(stopCh)
+		cacheSyncs = append(cacheSyncs, i.StreamService.HasSynced)
+	}
+	if i.DefaultSSLCertificate != nil {
+		go i.DefaultSSLCertificate.Run(stopCh)
+		cacheSyncs = append(cacheSyncs, i.DefaultSSLCertificate.HasSynced)
+	}
+
+	// wait for all involved caches to be synced before processing items
+	// from the queue
+	if !cache.WaitForCacheSync(stopCh, cacheSyncs...) {
 		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 	}
 
@@ -207,6 +235,14 @@ type k8sStore struct {
 	// secret in the annotations.
 	secretIngressMap ObjectRefMap
 
+	// configmapIngressMap contains information about which ingress
+	// references a configmap in the annotations.
+	configmapIngressMap ObjectRefMap
+
+	// secretStreamServiceMap contains information about which
+	// StreamService references a secret in the TLS configuration.
+	secretStreamServiceMap ObjectRefMap
+
 	filesystem file.Filesystem
 
 	// updateCh
@@ -215,6 +251,17 @@ type k8sStore struct {
 	// syncSecretMu protects against simultaneous invocations of syncSecret
 	syncSecretMu *sync.Mutex
 
+	// certificateIssues contains the issues of the TLS Secrets last reported,
+	// by Secret and Ingress UID, to emit events only when they change
+	certificateIssues map[string]map[string]string
+
+	// annotationWarningsMu protects against simultaneous read/write of annotationWarnings
+	annotationWarningsMu *sync.Mutex
+
+	// annotationWarnings contains the invalid annotations last reported, by
+	// Ingress, to emit events only when they change
+	annotationWarnings map[string]string
+
 	// backendConfigMu protects against simultaneous read/write of backendConfig
 	backendConfigMu *sync.RWMutex
 
@@ -223,18 +270,28 @@ type k8sStore struct {
 	isDynamicCertificatesEnabled bool
 
 	pod *k8s.PodInfo
+
+	// recorder emits events on the objects watched by the store
+	recorder record.EventRecorder
+
+	// client and configmap are used to read the configuration ConfigMap
+	// from the API server in ReloadConfigMap
+	client    clientset.Interface
+	configmap string
 }
 
 // New creates a new object store to be used in the ingress controller
 func New(checkOCSP bool,
-	namespace, configmap, tcp, udp, defaultSSLCertificate string,
+	namespace, configmap, tcp, udp, ipAccess, defaultSSLCertificate string,
 	resyncPeriod time.Duration,
 	client clientset.Interface,
+	streamServiceClient streamservice.Interface,
 	fs file.Filesystem,
 	updateCh *channels.RingChannel,
 	isDynamicCertificatesEnabled bool,
 	pod *k8s.PodInfo,
-	disableCatchAll bool) Storer {
+	disableCatchAll bool,
+	ingressLabelSelector string) Storer {
 
 	store := &k8sStore{
 		isOCSPCheckEnabled:           checkOCSP,
@@ -245,11 +302,18 @@ func New(checkOCSP bool,
 		updateCh:                     updateCh,
 		backendConfig:                ngx_config.NewDefault(),
 		syncSecretMu:                 &sync.Mutex{},
+		certificateIssues:            map[string]map[string]string{},
+		annotationWarningsMu:         &sync.Mutex{},
+		annotationWarnings:           map[string]string{},
 		backendConfigMu:              &sync.RWMutex{},
 		secretIngressMap:             NewObjectRefMap(),
+		configmapIngressMap:          NewObjectRefMap(),
+		secretStreamServiceMap:       NewObjectRefMap(),
 		defaultSSLCertificate:        defaultSSLCertificate,
 		isDynamicCertificatesEnabled: isDynamicCertificatesEnabled,
 		pod:                          pod,
+		client:                       client,
+		configmap:                    configmap,
 	}
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -260,6 +324,7 @@ func New(checkOCSP bool,
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
 		Component: "nginx-ingress-controller",
 	})
+	store.recorder = recorder
 
 	// k8sStore fulfills resolver.Resolver interface
 	store.annotations = annotations.NewAnnotationExtractor(store)
@@ -271,7 +336,15 @@ func New(checkOCSP bool,
 		informers.WithNamespace(namespace),
 		informers.WithTweakListOptions(func(*metav1.ListOptions) {}))
 
-	store.informers.Ingress = infFactory.Extensions().V1beta1().Ingresses().Informer()
+	// Ingresses use a dedicated factory so the label selector is only
+	// applied to them and not to the rest of the watched objects
+	ingFactory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = ingressLabelSelector
+		}))
+
+	store.informers.Ingress = ingFactory.Extensions().V1beta1().Ingresses().Informer()
 	store.listers.Ingress.Store = store.informers.Ingress.GetStore()
 
 	store.informers.Endpoint = infFactory.Core().V1().Endpoints().Informer()
@@ -304,6 +377,29 @@ func New(checkOCSP bool,
 	)
 	store.listers.Pod.Store = store.informers.Pod.GetStore()
 
+	// the secret with the default SSL certificate must be watched to
+	// apply changes even when it is not located in the watched namespace
+	defNs, defName, err := k8s.ParseNameNS(defaultSSLCertificate)
+	if err == nil && namespace != corev1.NamespaceAll && defNs != namespace {
+		fieldSelector := fields.OneTermEqualSelector("metadata.name", defName).String()
+		store.informers.DefaultSSLCertificate = cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+					options.FieldSelector = fieldSelector
+					return client.CoreV1().Secrets(defNs).List(options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					options.FieldSelector = fieldSelector
+					return client.CoreV1().Secrets(defNs).Watch(options)
+				},
+			},
+			&corev1.Secret{},
+			resyncPeriod,
+			cache.Indexers{},
+		)
+		store.listers.DefaultSSLCertificate.Store = store.informers.DefaultSSLCertificate.GetStore()
+	}
+
 	ingDeleteHandler := func(obj interface{}) {
 		ing, ok := obj.(*extensions.Ingress)
 		if !ok {
@@ -333,6 +429,11 @@ func New(checkOCSP bool,
 
 		key := k8s.MetaNamespaceKey(ing)
 		store.secretIngressMap.Delete(key)
+		store.configmapIngressMap.Delete(key)
+
+		store.annotationWarningsMu.Lock()
+		delete(store.annotationWarnings, key)
+		store.annotationWarningsMu.Unlock()
 
 		updateCh.In() <- Event{
 			Type: DeleteEvent,
@@ -356,6 +457,7 @@ func New(checkOCSP bool,
 
 			store.syncIngress(ing)
 			store.updateSecretIngressMap(ing)
+			store.updateConfigMapIngressMap(ing)
 			store.syncSecrets(ing)
 
 			updateCh.In() <- Event{
@@ -396,6 +498,7 @@ func New(checkOCSP bool,
 
 			store.syncIngress(curIng)
 			store.updateSecretIngressMap(curIng)
+			store.updateConfigMapIngressMap(curIng)
 			store.syncSecrets(curIng)
 
 			updateCh.In() <- Event{
@@ -414,6 +517,23 @@ func New(checkOCSP bool,
 				store.syncSecret(store.defaultSSLCertificate)
 			}
 
+			if store.GetBackendConfiguration().ACMEChallengeSecret == key {
+				klog.Infof("secret %v with the responses to ACME challenges was added", key)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
+
+			if sss := store.secretStreamServiceMap.Reference(key); len(sss) > 0 {
+				klog.Infof("secret %v was added and it is used in StreamServices %v", key, sss)
+				store.syncSecret(key)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
+
 			// find references in ingresses and update local ssl certs
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 				klog.Infof("secret %v was added and it is used in ingress annotations. Parsing...", key)
@@ -441,6 +561,23 @@ func New(checkOCSP bool,
 					store.syncSecret(store.defaultSSLCertificate)
 				}
 
+				if store.GetBackendConfiguration().ACMEChallengeSecret == key {
+					klog.Infof("secret %v with the responses to ACME challenges was updated", key)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+
+				if sss := store.secretStreamServiceMap.Reference(key); len(sss) > 0 {
+					klog.Infof("secret %v was updated and it is used in StreamServices %v", key, sss)
+					store.syncSecret(key)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+
 				// find references in ingresses and update local ssl certs
 				if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 					klog.Infof("secret %v was updated and it is used in ingress annotations. Parsing...", key)
@@ -480,6 +617,34 @@ func New(checkOCSP bool,
 
 			key := k8s.MetaNamespaceKey(sec)
 
+			store.syncSecretMu.Lock()
+			delete(store.certificateIssues, key)
+			store.syncSecretMu.Unlock()
+
+			if store.defaultSSLCertificate == key {
+				klog.Warningf("secret %v with the default SSL certificate was deleted, using the self-signed certificate", key)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+
+			if store.GetBackendConfiguration().ACMEChallengeSecret == key {
+				klog.Infof("secret %v with the responses to ACME challenges was deleted", key)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+
+			if sss := store.secretStreamServiceMap.Reference(key); len(sss) > 0 {
+				klog.Infof("secret %v was deleted and it is used in StreamServices %v", key, sss)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+
 			// find references in ingresses
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 				klog.Infof("secret %v was deleted and it is used in ingress annotations. Parsing...", key)
@@ -529,7 +694,7 @@ func New(checkOCSP bool,
 			cm := obj.(*corev1.ConfigMap)
 			key := k8s.MetaNamespaceKey(cm)
 			// updates to configuration configmaps can trigger an update
-			if key == configmap || key == tcp || key == udp {
+			if key == configmap || key == tcp || key == udp || key == ipAccess {
 				recorder.Eventf(cm, corev1.EventTypeNormal, "CREATE", fmt.Sprintf("ConfigMap %v", key))
 				if key == configmap {
 					store.setConfig(cm)
@@ -538,12 +703,33 @@ func New(checkOCSP bool,
 					Type: ConfigurationEvent,
 					Obj:  obj,
 				}
+				return
+			}
+
+			// find references in ingresses
+			if ings := store.configmapIngressMap.Reference(key); len(ings) > 0 {
+				klog.Infof("configmap %v was added and it is used in ingress annotations. Parsing...", key)
+				store.syncReferencingIngresses(ings)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
 			}
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			if !reflect.DeepEqual(old, cur) {
 				cm := cur.(*corev1.ConfigMap)
 				key := k8s.MetaNamespaceKey(cm)
+				// the global IP access lists are applied without parsing the ingresses
+				if key == ipAccess {
+					recorder.Eventf(cm, corev1.EventTypeNormal, "UPDATE", "ConfigMap %v", key)
+					updateCh.In() <- Event{
+						Type: ConfigurationEvent,
+						Obj:  cur,
+					}
+					return
+				}
+
 				// updates to configuration configmaps can trigger an update
 				if key == configmap || key == tcp || key == udp {
 					recorder.Eventf(cm, corev1.EventTypeNormal, "UPDATE", fmt.Sprintf("ConfigMap %v", key))
@@ -551,21 +737,61 @@ func New(checkOCSP bool,
 						store.setConfig(cm)
 					}
 
-					ings := store.listers.IngressWithAnnotation.List()
-					for _, ingKey := range ings {
-						key := k8s.MetaNamespaceKey(ingKey)
-						ing, err := store.getIngress(key)
-						if err != nil {
-							klog.Errorf("could not find Ingress %v in local store: %v", key, err)
-							continue
-						}
-						store.syncIngress(ing)
-					}
+					store.syncIngresses()
 
 					updateCh.In() <- Event{
 						Type: ConfigurationEvent,
 						Obj:  cur,
 					}
+					return
+				}
+
+				// find references in ingresses
+				if ings := store.configmapIngressMap.Reference(key); len(ings) > 0 {
+					klog.Infof("configmap %v was updated and it is used in ingress annotations. Parsing...", key)
+					store.syncReferencingIngresses(ings)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				// If we reached here it means the configmap was deleted but its final state is unrecorded.
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					klog.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				cm, ok = tombstone.Obj.(*corev1.ConfigMap)
+				if !ok {
+					klog.Errorf("Tombstone contained object that is not a ConfigMap: %#v", obj)
+					return
+				}
+			}
+
+			key := k8s.MetaNamespaceKey(cm)
+
+			// the stream services and the global IP access lists are removed
+			if key == tcp || key == udp || key == ipAccess {
+				recorder.Eventf(cm, corev1.EventTypeNormal, "DELETE", "ConfigMap %v", key)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  obj,
+				}
+				return
+			}
+
+			// find references in ingresses
+			if ings := store.configmapIngressMap.Reference(key); len(ings) > 0 {
+				klog.Infof("configmap %v was deleted and it is used in ingress annotations. Parsing...", key)
+				store.syncReferencingIngresses(ings)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
 				}
 			}
 		},
@@ -602,10 +828,78 @@ func New(checkOCSP bool,
 	store.informers.Ingress.AddEventHandler(ingEventHandler)
 	store.informers.Endpoint.AddEventHandler(epEventHandler)
 	store.informers.Secret.AddEventHandler(secrEventHandler)
+	if store.informers.DefaultSSLCertificate != nil {
+		store.informers.DefaultSSLCertificate.AddEventHandler(secrEventHandler)
+	}
 	store.informers.ConfigMap.AddEventHandler(cmEventHandler)
 	store.informers.Service.AddEventHandler(cache.ResourceEventHandlerFuncs{})
 	store.informers.Pod.AddEventHandler(podEventHandler)
 
+	if streamServiceClient != nil {
+		store.informers.StreamService = cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+					return streamServiceClient.List(namespace, options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return streamServiceClient.Watch(namespace, options)
+				},
+			},
+			&streamservice.StreamService{},
+			resyncPeriod,
+			cache.Indexers{},
+		)
+		store.listers.StreamService.Store = store.informers.StreamService.GetStore()
+
+		store.informers.StreamService.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				ss := obj.(*streamservice.StreamService)
+				store.updateSecretStreamServiceMap(ss)
+				store.syncStreamServiceSecrets(ss)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			},
+			UpdateFunc: func(old, cur interface{}) {
+				oldSS := old.(*streamservice.StreamService)
+				curSS := cur.(*streamservice.StreamService)
+				// status updates are written by the controller
+				if reflect.DeepEqual(oldSS.Spec, curSS.Spec) {
+					return
+				}
+
+				store.updateSecretStreamServiceMap(curSS)
+				store.syncStreamServiceSecrets(curSS)
+				updateCh.In() <- Event{
+					Type: UpdateEvent,
+					Obj:  cur,
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				ss, ok := obj.(*streamservice.StreamService)
+				if !ok {
+					tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+					if !ok {
+						klog.Errorf("couldn't get object from tombstone %#v", obj)
+						return
+					}
+					ss, ok = tombstone.Obj.(*streamservice.StreamService)
+					if !ok {
+						klog.Errorf("Tombstone contained object that is not a StreamService: %#v", obj)
+						return
+					}
+				}
+
+				store.secretStreamServiceMap.Delete(k8s.MetaNamespaceKey(ss))
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			},
+		})
+	}
+
 	// do not wait for informers to read the configmap configuration
 	ns, name, _ := k8s.ParseNameNS(configmap)
 	cm, err := client.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
@@ -623,6 +917,37 @@ func isCatchAllIngress(spec extensions.IngressSpec) bool {
 	return spec.Backend != nil && len(spec.Rules) == 0
 }
 
+// recordAnnotationWarnings emits an event for each invalid annotation of an
+// Ingress. The Ingress is synchronized again on every change of the Secrets
+// and ConfigMaps it references, so the warnings are only reported when they
+// differ from the ones of the previous synchronization or the Ingress is
+// recreated.
+func (s *k8sStore) recordAnnotationWarnings(ing *extensions.Ingress, warnings []string) {
+	key := k8s.MetaNamespaceKey(ing)
+	reported := ""
+	if len(warnings) > 0 {
+		reported = fmt.Sprintf("%v\n%v", ing.UID, strings.Join(warnings, "\n"))
+	}
+
+	s.annotationWarningsMu.Lock()
+	defer s.annotationWarningsMu.Unlock()
+
+	if reported == s.annotationWarnings[key] {
+		return
+	}
+
+	for _, warning := range warnings {
+		s.recorder.Event(ing, corev1.EventTypeWarning, "ANNOTATION", warning)
+	}
+
+	if reported == "" {
+		delete(s.annotationWarnings, key)
+		return
+	}
+
+	s.annotationWarnings[key] = reported
+}
+
 // syncIngress parses ingress annotations converting the value of the
 // annotation to a go struct
 func (s *k8sStore) syncIngress(ing *extensions.Ingress) {
@@ -646,15 +971,47 @@ func (s *k8sStore) syncIngress(ing *extensions.Ingress) {
 		}
 	}
 
+	anns := s.annotations.Extract(ing)
+	s.recordAnnotationWarnings(ing, anns.Warnings)
+
 	err := s.listers.IngressWithAnnotation.Update(&ingress.Ingress{
 		Ingress:           *copyIng,
-		ParsedAnnotations: s.annotations.Extract(ing),
+		ParsedAnnotations: anns,
 	})
 	if err != nil {
 		klog.Error(err)
 	}
 }
 
+// updateSecretStreamServiceMap takes a StreamService and updates all Secret
+// objects it references in secretStreamServiceMap.
+func (s *k8sStore) updateSecretStreamServiceMap(ss *streamservice.StreamService) {
+	key := k8s.MetaNamespaceKey(ss)
+	klog.V(3).Infof("updating references to secrets for StreamService %v", key)
+
+	// delete all existing references first
+	s.secretStreamServiceMap.Delete(key)
+
+	var refSecrets []string
+	for _, port := range ss.Spec.Ports {
+		if port.TLS != nil && port.TLS.SecretName != "" {
+			refSecrets = append(refSecrets, fmt.Sprintf("%v/%v", ss.Namespace, port.TLS.SecretName))
+		}
+	}
+
+	// populate map with all secret references
+	s.secretStreamServiceMap.Insert(key, refSecrets...)
+}
+
+// syncStreamServiceSecrets synchronizes data from all Secrets referenced by
+// the given StreamService with the local store and file system.
+func (s *k8sStore) syncStreamServiceSecrets(ss *streamservice.StreamService) {
+	key := k8s.MetaNamespaceKey(ss)
+	for _, secrKey := range s.secretStreamServiceMap.ReferencedBy(key) {
+		s.syncSecret(secrKey)
+	}
+}
+
 // updateSecretIngressMap takes an Ingress and updates all Secret objects it
 // references in secretIngressMap.
 func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
@@ -681,22 +1038,66 @@ func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
 	secretAnnotations := []string{
 		"auth-secret",
 		"auth-tls-secret",
+		"oidc-secret",
+		"proxy-ssl-secret",
 	}
 	for _, ann := range secretAnnotations {
-		secrKey, err := objectRefAnnotationNsKey(ann, ing)
+		secrKeys, err := objectRefAnnotationNsKeys(ann, ing)
 		if err != nil && !errors.IsMissingAnnotations(err) {
 			klog.Errorf("error reading secret reference in annotation %q: %s", ann, err)
 			continue
 		}
-		if secrKey != "" {
-			refSecrets = append(refSecrets, secrKey)
-		}
+		refSecrets = append(refSecrets, secrKeys...)
 	}
 
 	// populate map with all secret references
 	s.secretIngressMap.Insert(key, refSecrets...)
 }
 
+// updateConfigMapIngressMap takes an Ingress and updates all ConfigMap objects
+// it references in configmapIngressMap.
+func (s *k8sStore) updateConfigMapIngressMap(ing *extensions.Ingress) {
+	key := k8s.MetaNamespaceKey(ing)
+	klog.V(3).Infof("updating references to configmaps for ingress %v", key)
+
+	// delete all existing references first
+	s.configmapIngressMap.Delete(key)
+
+	configmapAnnotations := []string{
+		"fastcgi-params-configmap",
+		"proxy-set-headers",
+		"custom-headers",
+	}
+
+	var refConfigMaps []string
+	for _, ann := range configmapAnnotations {
+		cmKey, err := objectRefAnnotationNsKey(ann, ing)
+		if err != nil && !errors.IsMissingAnnotations(err) {
+			klog.Errorf("error reading configmap reference in annotation %q: %s", ann, err)
+			continue
+		}
+		if cmKey != "" {
+			refConfigMaps = append(refConfigMaps, cmKey)
+		}
+	}
+
+	// populate map with all configmap references
+	s.configmapIngressMap.Insert(key, refConfigMaps...)
+}
+
+// syncReferencingIngresses parses again the annotations of the given
+// Ingresses after a change in an object they reference
+func (s *k8sStore) syncReferencingIngresses(ings []string) {
+	for _, ingKey := range ings {
+		ing, err := s.getIngress(ingKey)
+		if err != nil {
+			klog.Errorf("could not find Ingress %v in local store", ingKey)
+			continue
+		}
+		s.syncIngress(ing)
+	}
+}
+
 // objectRefAnnotationNsKey returns an object reference formatted as a
 // 'namespace/name' key from the given annotation name.
 func objectRefAnnotationNsKey(ann string, ing *extensions.Ingress) (string, error) {
@@ -705,7 +1106,35 @@ func objectRefAnnotationNsKey(ann string, ing *extensions.Ingress) (string, erro
 		return "", err
 	}
 
-	secrNs, secrName, err := cache.SplitMetaNamespaceKey(annValue)
+	return objectRefNsKey(annValue, ing)
+}
+
+// objectRefAnnotationNsKeys returns the keys of the objects referenced
+// by an annotation containing a comma separated list of references.
+func objectRefAnnotationNsKeys(ann string, ing *extensions.Ingress) ([]string, error) {
+	annValue, err := parser.GetStringAnnotation(ann, ing)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, ref := range strings.Split(annValue, ",") {
+		key, err := objectRefNsKey(strings.TrimSpace(ref), ing)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// objectRefNsKey returns the key of an object referenced in the
+// "namespace/name" or "name" formats, relative to the Ingress namespace.
+func objectRefNsKey(ref string, ing *extensions.Ingress) (string, error) {
+	secrNs, secrName, err := cache.SplitMetaNamespaceKey(ref)
 	if secrName == "" {
 		return "", err
 	}
@@ -713,7 +1142,7 @@ func objectRefAnnotationNsKey(ann string, ing *extensions.Ingress) (string, erro
 	if secrNs == "" {
 		return fmt.Sprintf("%v/%v", ing.Namespace, secrName), nil
 	}
-	return annValue, nil
+	return ref, nil
 }
 
 // syncSecrets synchronizes data from all Secrets referenced by the given
@@ -727,6 +1156,10 @@ func (s *k8sStore) syncSecrets(ing *extensions.Ingress) {
 
 // GetSecret returns the Secret matching key.
 func (s *k8sStore) GetSecret(key string) (*corev1.Secret, error) {
+	if key == s.defaultSSLCertificate && s.listers.DefaultSSLCertificate.Store != nil {
+		return s.listers.DefaultSSLCertificate.ByKey(key)
+	}
+
 	return s.listers.Secret.ByKey(key)
 }
 
@@ -776,6 +1209,33 @@ func (s *k8sStore) ListIngresses() []*ingress.Ingress {
 	return ingresses
 }
 
+// ListStreamServices returns the list of StreamServices sorted using the
+// CreationTimestamp field, or nil if StreamServices are not enabled
+func (s *k8sStore) ListStreamServices() []*streamservice.StreamService {
+	if s.listers.StreamService.Store == nil {
+		return nil
+	}
+
+	var streamServices []*streamservice.StreamService
+	for _, item := range s.listers.StreamService.List() {
+		streamServices = append(streamServices, item.(*streamservice.StreamService))
+	}
+
+	// the oldest StreamService keeps a port in conflict, the name is used
+	// when both were created in the same second
+	sort.SliceStable(streamServices, func(i, j int) bool {
+		ir := streamServices[i].CreationTimestamp
+		jr := streamServices[j].CreationTimestamp
+		if !ir.Equal(&jr) {
+			return ir.Before(&jr)
+		}
+
+		return k8s.MetaNamespaceKey(streamServices[i]) < k8s.MetaNamespaceKey(streamServices[j])
+	})
+
+	return streamServices
+}
+
 // GetLocalSSLCert returns the local copy of a SSLCert
 func (s *k8sStore) GetLocalSSLCert(key string) (*ingress.SSLCert, error) {
 	return s.sslStore.ByKey(key)
@@ -802,10 +1262,19 @@ func (s *k8sStore) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error
 		return nil, err
 	}
 
+	// the PEM file contains a key only if the Secret contains one
+	pemFileName := ""
+	if secret, err := s.GetSecret(name); err == nil && len(secret.Data[corev1.TLSPrivateKeyKey]) > 0 {
+		pemFileName = cert.PemFileName
+	}
+
 	return &resolver.AuthSSLCert{
-		Secret:     name,
-		CAFileName: cert.CAFileName,
-		PemSHA:     cert.PemSHA,
+		Secret:      name,
+		CAFileName:  cert.CAFileName,
+		PemFileName: pemFileName,
+		PemSHA:      cert.PemSHA,
+		CRLFileName: cert.CRLFileName,
+		CRLSHA:      cert.CRLSHA,
 	}, nil
 }
 
@@ -857,6 +1326,42 @@ func (s *k8sStore) setConfig(cmap *corev1.ConfigMap) {
 	s.writeSSLSessionTicketKey(cmap, "/etc/nginx/tickets.key")
 }
 
+// syncIngresses parses the annotations of all the Ingresses again
+func (s *k8sStore) syncIngresses() {
+	for _, item := range s.listers.IngressWithAnnotation.List() {
+		key := k8s.MetaNamespaceKey(item)
+		ing, err := s.getIngress(key)
+		if err != nil {
+			klog.Errorf("could not find Ingress %v in local store: %v", key, err)
+			continue
+		}
+		s.syncIngress(ing)
+	}
+}
+
+// ReloadConfigMap reads the configuration ConfigMap from the API server,
+// bypassing the cache of the informer, and parses the Ingresses again
+func (s *k8sStore) ReloadConfigMap() error {
+	if s.configmap == "" {
+		return nil
+	}
+
+	ns, name, err := k8s.ParseNameNS(s.configmap)
+	if err != nil {
+		return err
+	}
+
+	cm, err := s.client.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	s.setConfig(cm)
+	s.syncIngresses()
+
+	return nil
+}
+
 // Run initiates the synchronization of the informers and the initial
 // synchronization of the secrets.
 func (s *k8sStore) Run(stopCh chan struct{}) {
@@ -866,6 +1371,8 @@ func (s *k8sStore) Run(stopCh chan struct{}) {
 	if s.isOCSPCheckEnabled {
 		go wait.Until(s.checkSSLChainIssues, 60*time.Second, stopCh)
 	}
+
+	go wait.Until(s.updateOCSPResponses, 60*time.Second, stopCh)
 }
 
 // GetRunningControllerPodsCount returns the number of Running ingress-nginx controller Pods
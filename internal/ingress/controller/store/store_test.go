@@ -19,6 +19,7 @@ package store
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -28,8 +29,10 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	"encoding/base64"
 	"io/ioutil"
@@ -40,6 +43,7 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/test/e2e/framework"
 )
@@ -77,13 +81,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -159,13 +166,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -313,13 +323,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -422,13 +435,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -513,13 +529,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -585,6 +604,105 @@ func TestStore(t *testing.T) {
 
 	})
 
+	t.Run("should receive events from configmap referenced from ingress", func(t *testing.T) {
+		ns := createNamespace(clientSet, t)
+		defer deleteNamespace(ns, clientSet, t)
+		cm := createConfigMap(clientSet, ns, t)
+		defer deleteConfigMap(cm, ns, clientSet, t)
+
+		stopCh := make(chan struct{})
+		updateCh := channels.NewRingChannel(1024)
+
+		var del uint64
+
+		go func(ch *channels.RingChannel) {
+			for {
+				evt, ok := <-ch.Out()
+				if !ok {
+					return
+				}
+
+				e := evt.(Event)
+				if e.Obj == nil {
+					continue
+				}
+				if e.Type == DeleteEvent {
+					atomic.AddUint64(&del, 1)
+				}
+			}
+		}(updateCh)
+
+		fs := newFS(t)
+		storer := New(true,
+			ns,
+			fmt.Sprintf("%v/config", ns),
+			fmt.Sprintf("%v/tcp", ns),
+			fmt.Sprintf("%v/udp", ns),
+			"",
+			"",
+			10*time.Minute,
+			clientSet,
+			nil,
+			fs,
+			updateCh,
+			false,
+			pod,
+			false,
+			"")
+
+		storer.Run(stopCh)
+
+		ingressName := "ingress-with-configmap"
+		configMapName := "headers"
+
+		_, err := clientSet.CoreV1().ConfigMaps(ns).Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:     configMapName,
+				SelfLink: fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", ns, configMapName),
+			},
+			Data: map[string]string{"X-Foo": "bar"},
+		})
+		if err != nil {
+			t.Errorf("error creating configmap: %v", err)
+		}
+
+		ing := ensureIngress(&extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ingressName,
+				Namespace: ns,
+				SelfLink:  fmt.Sprintf("/apis/extensions/v1beta1/namespaces/%s/ingresses/%s", ns, ingressName),
+				Annotations: map[string]string{
+					parser.GetAnnotationWithPrefix("proxy-set-headers"): configMapName,
+				},
+			},
+			Spec: extensions.IngressSpec{
+				Backend: &extensions.IngressBackend{
+					ServiceName: "http-svc",
+					ServicePort: intstr.FromInt(80),
+				},
+			},
+		}, clientSet, t)
+		defer deleteIngress(ing, clientSet, t)
+
+		err = framework.WaitForIngressInNamespace(clientSet, ns, ingressName)
+		if err != nil {
+			t.Errorf("error waiting for ingress: %v", err)
+		}
+
+		time.Sleep(1 * time.Second)
+
+		err = clientSet.CoreV1().ConfigMaps(ns).Delete(configMapName, &metav1.DeleteOptions{})
+		if err != nil {
+			t.Errorf("error deleting configmap: %v", err)
+		}
+
+		time.Sleep(1 * time.Second)
+
+		if atomic.LoadUint64(&del) != 1 {
+			t.Errorf("expected 1 events of type Delete but %v occurred", del)
+		}
+	})
+
 	t.Run("should create an ingress with a secret which does not exist", func(t *testing.T) {
 		ns := createNamespace(clientSet, t)
 		defer deleteNamespace(ns, clientSet, t)
@@ -627,13 +745,16 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
+			nil,
 			fs,
 			updateCh,
 			false,
 			pod,
-			false)
+			false,
+			"")
 
 		storer.Run(stopCh)
 
@@ -732,6 +853,90 @@ func TestStore(t *testing.T) {
 		})
 	})
 
+	t.Run("should only store the ingresses matching the label selector", func(t *testing.T) {
+		ns := createNamespace(clientSet, t)
+		defer deleteNamespace(ns, clientSet, t)
+		cm := createConfigMap(clientSet, ns, t)
+		defer deleteConfigMap(cm, ns, clientSet, t)
+
+		for name, labels := range map[string]map[string]string{
+			"selected":   {"ingress-controller": "public"},
+			"unselected": {"ingress-controller": "private"},
+			"unlabeled":  nil,
+		} {
+			ing := ensureIngress(&extensions.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: ns,
+					Labels:    labels,
+					SelfLink:  fmt.Sprintf("/apis/extensions/v1beta1/namespaces/%s/ingresses/%s", ns, name),
+				},
+				Spec: extensions.IngressSpec{
+					Rules: []extensions.IngressRule{
+						{
+							Host: name,
+							IngressRuleValue: extensions.IngressRuleValue{
+								HTTP: &extensions.HTTPIngressRuleValue{
+									Paths: []extensions.HTTPIngressPath{
+										{
+											Path: "/",
+											Backend: extensions.IngressBackend{
+												ServiceName: "http-svc",
+												ServicePort: intstr.FromInt(80),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}, clientSet, t)
+			defer deleteIngress(ing, clientSet, t)
+		}
+
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		updateCh := channels.NewRingChannel(1024)
+
+		go func(ch *channels.RingChannel) {
+			for {
+				<-ch.Out()
+			}
+		}(updateCh)
+
+		fs := newFS(t)
+		storer := New(true,
+			ns,
+			fmt.Sprintf("%v/config", ns),
+			fmt.Sprintf("%v/tcp", ns),
+			fmt.Sprintf("%v/udp", ns),
+			"",
+			"",
+			10*time.Minute,
+			clientSet,
+			nil,
+			fs,
+			updateCh,
+			false,
+			pod,
+			false,
+			"ingress-controller=public")
+
+		storer.Run(stopCh)
+
+		// take into account the delay of the ingress annotations extraction
+		time.Sleep(1 * time.Second)
+
+		ingresses := storer.ListIngresses()
+		if len(ingresses) != 1 {
+			t.Fatalf("expected 1 Ingress but %v returned", len(ingresses))
+		}
+		if ingresses[0].Name != "selected" {
+			t.Errorf("expected the Ingress selected but %v returned", ingresses[0].Name)
+		}
+	})
+
 	// test add ingress with secret it doesn't exists and then add secret
 	// check secret is generated on fs
 	// check ocsp
@@ -868,13 +1073,14 @@ func newStore(t *testing.T) *k8sStore {
 			IngressWithAnnotation: IngressWithAnnotationsLister{cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)},
 			Pod:                   PodLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
 		},
-		sslStore:         NewSSLCertTracker(),
-		filesystem:       fs,
-		updateCh:         channels.NewRingChannel(10),
-		syncSecretMu:     new(sync.Mutex),
-		backendConfigMu:  new(sync.RWMutex),
-		secretIngressMap: NewObjectRefMap(),
-		pod:              pod,
+		sslStore:            NewSSLCertTracker(),
+		filesystem:          fs,
+		updateCh:            channels.NewRingChannel(10),
+		syncSecretMu:        new(sync.Mutex),
+		backendConfigMu:     new(sync.RWMutex),
+		secretIngressMap:    NewObjectRefMap(),
+		configmapIngressMap: NewObjectRefMap(),
+		pod:                 pod,
 	}
 }
 
@@ -928,6 +1134,19 @@ func TestUpdateSecretIngressMap(t *testing.T) {
 		}
 	})
 
+	t.Run("with annotation containing a list of secrets", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		ing.ObjectMeta.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("auth-secret"): "auth, otherns/users",
+		})
+		s.listers.Ingress.Update(ing)
+		s.updateSecretIngressMap(ing)
+
+		if l := s.secretIngressMap.Len(); !(l == 2 && s.secretIngressMap.Has("testns/auth") && s.secretIngressMap.Has("otherns/users")) {
+			t.Errorf("Expected \"testns/auth\" and \"otherns/users\" to be the only referenced Secrets (got %d)", l)
+		}
+	})
+
 	t.Run("with annotation in invalid format", func(t *testing.T) {
 		ing := ingTpl.DeepCopy()
 		ing.ObjectMeta.SetAnnotations(map[string]string{
@@ -942,6 +1161,41 @@ func TestUpdateSecretIngressMap(t *testing.T) {
 	})
 }
 
+func TestUpdateConfigMapIngressMap(t *testing.T) {
+	s := newStore(t)
+
+	ingTpl := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+		},
+	}
+	s.listers.Ingress.Add(ingTpl)
+
+	t.Run("with annotation in simple name format", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		ing.ObjectMeta.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("fastcgi-params-configmap"): "fcgi-params",
+		})
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configmapIngressMap.Len(); !(l == 1 && s.configmapIngressMap.Has("testns/fcgi-params")) {
+			t.Errorf("Expected \"testns/fcgi-params\" to be the only referenced ConfigMap (got %d)", l)
+		}
+	})
+
+	t.Run("without annotation", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configmapIngressMap.Len(); l != 0 {
+			t.Errorf("Expected 0 referenced ConfigMap (got %d)", l)
+		}
+	})
+}
+
 func TestListIngresses(t *testing.T) {
 	s := newStore(t)
 
@@ -1144,3 +1398,111 @@ func TestGetRunningControllerPodsCount(t *testing.T) {
 		t.Errorf("Expected 1 controller Pods but got %v", s)
 	}
 }
+
+func TestReloadConfigMap(t *testing.T) {
+	s := newStore(t)
+
+	err := s.ReloadConfigMap()
+	if err != nil {
+		t.Errorf("unexpected error without a configuration ConfigMap: %v", err)
+	}
+
+	s.configmap = "default/config"
+	s.client = fake.NewSimpleClientset()
+
+	err = s.ReloadConfigMap()
+	if err == nil {
+		t.Errorf("expected an error reading a ConfigMap that does not exist")
+	}
+
+	s.client = fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: v1.NamespaceDefault,
+		},
+		Data: map[string]string{
+			"proxy-body-size": "8m",
+		},
+	})
+
+	err = s.ReloadConfigMap()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.GetBackendConfiguration().ProxyBodySize != "8m" {
+		t.Errorf("expected 8m as proxy-body-size but returned %v", s.GetBackendConfiguration().ProxyBodySize)
+	}
+}
+
+func TestListStreamServices(t *testing.T) {
+	s := &k8sStore{
+		listers: &Lister{
+			StreamService: StreamServiceLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
+		},
+	}
+
+	created := metav1.NewTime(time.Now().Truncate(time.Second))
+	older := metav1.NewTime(created.Add(-time.Minute))
+
+	for _, ss := range []*streamservice.StreamService{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "db", CreationTimestamp: created}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "mqtt", CreationTimestamp: created}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "db", CreationTimestamp: created}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "c", Name: "dns", CreationTimestamp: older}},
+	} {
+		s.listers.StreamService.Add(ss)
+	}
+
+	// the order must not depend on the order of the informer
+	expected := []string{"c/dns", "a/db", "a/mqtt", "b/db"}
+	for i := 0; i < 10; i++ {
+		keys := []string{}
+		for _, ss := range s.ListStreamServices() {
+			keys = append(keys, k8s.MetaNamespaceKey(ss))
+		}
+
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected StreamServices %v but returned %v", expected, keys)
+		}
+	}
+}
+
+func TestRecordAnnotationWarnings(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	s := &k8sStore{
+		recorder:             recorder,
+		annotationWarningsMu: &sync.Mutex{},
+		annotationWarnings:   map[string]string{},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "1"},
+	}
+
+	testCases := []struct {
+		name     string
+		uid      string
+		warnings []string
+		events   int
+	}{
+		{"new warnings", "1", []string{"invalid proxy-read-timeout"}, 1},
+		{"same warnings", "1", []string{"invalid proxy-read-timeout"}, 0},
+		{"different warnings", "1", []string{"invalid proxy-read-timeout", "invalid proxy-send-timeout"}, 2},
+		{"recreated Ingress", "2", []string{"invalid proxy-read-timeout", "invalid proxy-send-timeout"}, 2},
+		{"warnings fixed", "2", nil, 0},
+		{"warnings introduced again", "2", []string{"invalid proxy-read-timeout"}, 1},
+	}
+
+	for _, tc := range testCases {
+		ing.UID = types.UID(tc.uid)
+		s.recordAnnotationWarnings(ing, tc.warnings)
+
+		if len(recorder.Events) != tc.events {
+			t.Errorf("%v: expected %v events but %v were emitted", tc.name, tc.events, len(recorder.Events))
+		}
+		for len(recorder.Events) > 0 {
+			<-recorder.Events
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// streamServiceState contains the ports of a StreamService that
+// could not be exposed, because they are already in use or because
+// of an invalid configuration, and whether the NGINX configuration
+// exposing them failed
+type streamServiceState struct {
+	conflicts []string
+	errors    []string
+
+	configurationFailed bool
+}
+
+// getStreamServiceEndpoints returns the TCP and UDP services defined by
+// StreamServices, skipping the ports reserved by the controller or already
+// used by the ConfigMaps. When two StreamServices use the same port, the
+// oldest one keeps it.
+func (n *NGINXController) getStreamServiceEndpoints(streamServices []*streamservice.StreamService,
+	tcpConfigured, udpConfigured []ingress.L4Service) ([]ingress.L4Service, []ingress.L4Service, map[string]*streamServiceState) {
+
+	usedPorts := map[apiv1.Protocol]sets.Int{
+		apiv1.ProtocolTCP: n.reservedPorts(),
+		apiv1.ProtocolUDP: n.reservedPorts(),
+	}
+	for _, svc := range tcpConfigured {
+		usedPorts[apiv1.ProtocolTCP].Insert(svc.Port)
+	}
+	for _, svc := range udpConfigured {
+		usedPorts[apiv1.ProtocolUDP].Insert(svc.Port)
+	}
+
+	var tcpSvcs, udpSvcs []ingress.L4Service
+	states := make(map[string]*streamServiceState)
+
+	for _, ss := range streamServices {
+		ssKey := k8s.MetaNamespaceKey(ss)
+		state := &streamServiceState{}
+		states[ssKey] = state
+
+		for _, port := range ss.Spec.Ports {
+			err := streamservice.ValidatePort(port)
+			if err != nil {
+				klog.Warningf("Invalid port in StreamService %q: %v", ssKey, err)
+				state.errors = append(state.errors, err.Error())
+				continue
+			}
+
+			proto := streamservice.Protocol(port)
+			if usedPorts[proto].Has(port.Port) {
+				klog.Warningf("%v port %d of StreamService %q is already in use", proto, port.Port, ssKey)
+				state.conflicts = append(state.conflicts, fmt.Sprintf("%v/%v", port.Port, proto))
+				continue
+			}
+
+			svc, err := n.getStreamServicePort(ss, port)
+			if err != nil {
+				klog.Warningf("Error exposing %v port %d of StreamService %q: %v", proto, port.Port, ssKey, err)
+				state.errors = append(state.errors, err.Error())
+				continue
+			}
+
+			usedPorts[proto].Insert(port.Port)
+			if proto == apiv1.ProtocolTCP {
+				tcpSvcs = append(tcpSvcs, *svc)
+			} else {
+				udpSvcs = append(udpSvcs, *svc)
+			}
+		}
+	}
+
+	return tcpSvcs, udpSvcs, states
+}
+
+// getStreamServicePort returns the stream service exposed
+// in a valid port of a StreamService
+func (n *NGINXController) getStreamServicePort(ss *streamservice.StreamService, port streamservice.StreamPort) (*ingress.L4Service, error) {
+	proto := streamservice.Protocol(port)
+
+	svcKey := fmt.Sprintf("%v/%v", ss.Namespace, port.Backend.ServiceName)
+	svc, err := n.store.GetService(svcKey)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Service %q: %v", svcKey, err)
+	}
+
+	// stream services cannot contain empty upstreams and there is
+	// no default backend equivalent
+	endps := n.getServicePortEndpoints(svc, port.Backend.ServicePort, proto)
+	if len(endps) == 0 {
+		return nil, fmt.Errorf("Service %q does not have any active Endpoint for %v port %v", svcKey, proto, port.Backend.ServicePort.String())
+	}
+
+	var sslCert *ingress.SSLCert
+	if port.TLS != nil {
+		secrKey := fmt.Sprintf("%v/%v", ss.Namespace, port.TLS.SecretName)
+		sslCert, err = n.getStreamSSLCert(secrKey)
+		if err != nil {
+			return nil, fmt.Errorf("error getting SSL certificate %q: %v", secrKey, err)
+		}
+	}
+
+	return &ingress.L4Service{
+		Port: port.Port,
+		Backend: ingress.L4Backend{
+			Name:      port.Backend.ServiceName,
+			Namespace: ss.Namespace,
+			Port:      port.Backend.ServicePort,
+			Protocol:  proto,
+			ProxyProtocol: ingress.ProxyProtocol{
+				Decode: port.ProxyProtocol.Decode,
+				Encode: port.ProxyProtocol.Encode,
+			},
+		},
+		Endpoints: endps,
+		Service:   svc,
+		SSLCert:   sslCert,
+		Snippet:   port.Snippet,
+	}, nil
+}
+
+// updateStreamServiceStatus updates the conditions of the StreamServices
+// with the state of their ports. Only changes in the conditions are written,
+// and only by the leader of the status updates.
+func (n *NGINXController) updateStreamServiceStatus(states map[string]*streamServiceState) {
+	for _, ss := range n.store.ListStreamServices() {
+		state, ok := states[k8s.MetaNamespaceKey(ss)]
+		if !ok {
+			continue
+		}
+
+		newSS := ss.DeepCopy()
+		changed := newSS.Status.SetCondition(state.readyCondition())
+		changed = newSS.Status.SetCondition(state.conflictCondition()) || changed
+		if !changed {
+			continue
+		}
+
+		_, err := n.cfg.StreamServiceClient.UpdateStatus(newSS)
+		if err != nil {
+			klog.Warningf("Error updating status of StreamService %v/%v: %v", ss.Namespace, ss.Name, err)
+		}
+	}
+}
+
+func (s *streamServiceState) readyCondition() streamservice.StreamServiceCondition {
+	c := streamservice.StreamServiceCondition{
+		Type:               streamservice.StreamServiceReady,
+		Status:             apiv1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "PortsExposed",
+		Message:            "All the ports are exposed",
+	}
+
+	if len(s.errors) > 0 || len(s.conflicts) > 0 {
+		c.Status = apiv1.ConditionFalse
+		c.Reason = "PortsNotExposed"
+		c.Message = strings.Join(s.errors, "; ")
+		if len(s.conflicts) > 0 {
+			if c.Message != "" {
+				c.Message += "; "
+			}
+			c.Message += fmt.Sprintf("ports already in use: %v", strings.Join(s.conflicts, ", "))
+		}
+	}
+
+	if s.configurationFailed {
+		c.Status = apiv1.ConditionFalse
+		c.Reason = "ConfigurationFailed"
+		c.Message = "The NGINX configuration exposing the ports could not be applied"
+	}
+
+	return c
+}
+
+func (s *streamServiceState) conflictCondition() streamservice.StreamServiceCondition {
+	c := streamservice.StreamServiceCondition{
+		Type:               streamservice.StreamServiceConflict,
+		Status:             apiv1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "NoConflicts",
+	}
+
+	if len(s.conflicts) > 0 {
+		c.Status = apiv1.ConditionTrue
+		c.Reason = "PortInUse"
+		c.Message = fmt.Sprintf("ports already used by the controller, the ConfigMaps or older StreamServices: %v", strings.Join(s.conflicts, ", "))
+	}
+
+	return c
+}
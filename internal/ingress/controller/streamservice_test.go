@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/streamservice"
+)
+
+func TestGetStreamServiceEndpoints(t *testing.T) {
+	ctl := newNGINXController(t)
+
+	backend := extensions.IngressBackend{ServiceName: "missing", ServicePort: intstr.FromInt(5432)}
+	ss := &streamservice.StreamService{
+		ObjectMeta: metav1.ObjectMeta{Name: "databases", Namespace: "default"},
+		Spec: streamservice.StreamServiceSpec{
+			Ports: []streamservice.StreamPort{
+				// reserved by the controller
+				{Port: 80, Backend: backend},
+				// used by the TCP services ConfigMap
+				{Port: 9000, Backend: backend},
+				// UDP ports are independent
+				{Port: 9000, Protocol: apiv1.ProtocolUDP, Backend: backend},
+				// invalid configuration
+				{Port: 5432, Protocol: apiv1.ProtocolUDP, Backend: backend, TLS: &streamservice.StreamTLS{SecretName: "tls"}},
+			},
+		},
+	}
+
+	tcp, udp, states := ctl.getStreamServiceEndpoints([]*streamservice.StreamService{ss}, []ingress.L4Service{{Port: 9000}}, nil)
+	if len(tcp) != 0 || len(udp) != 0 {
+		t.Errorf("expected no stream services but got %v TCP and %v UDP", len(tcp), len(udp))
+	}
+
+	state, ok := states["default/databases"]
+	if !ok {
+		t.Fatalf("expected the state of the StreamService to be returned")
+	}
+
+	expectedConflicts := []string{"80/TCP", "9000/TCP"}
+	if len(state.conflicts) != len(expectedConflicts) {
+		t.Fatalf("expected conflicts %v but got %v", expectedConflicts, state.conflicts)
+	}
+	for i, c := range expectedConflicts {
+		if state.conflicts[i] != c {
+			t.Errorf("expected conflicts %v but got %v", expectedConflicts, state.conflicts)
+		}
+	}
+
+	// the missing Service and the invalid TLS configuration
+	if len(state.errors) != 2 {
+		t.Errorf("expected 2 errors but got %v", state.errors)
+	}
+
+	ready := state.readyCondition()
+	if ready.Status != apiv1.ConditionFalse || ready.Reason != "PortsNotExposed" {
+		t.Errorf("expected the StreamService to not be ready but got %+v", ready)
+	}
+	conflict := state.conflictCondition()
+	if conflict.Status != apiv1.ConditionTrue || conflict.Reason != "PortInUse" {
+		t.Errorf("expected the StreamService to have a conflict but got %+v", conflict)
+	}
+}
+
+func TestStreamServiceStateConditions(t *testing.T) {
+	state := &streamServiceState{}
+
+	ready := state.readyCondition()
+	if ready.Status != apiv1.ConditionTrue {
+		t.Errorf("expected the StreamService to be ready but got %+v", ready)
+	}
+	conflict := state.conflictCondition()
+	if conflict.Status != apiv1.ConditionFalse {
+		t.Errorf("expected the StreamService to not have conflicts but got %+v", conflict)
+	}
+
+	state.configurationFailed = true
+	ready = state.readyCondition()
+	if ready.Status != apiv1.ConditionFalse || ready.Reason != "ConfigurationFailed" {
+		t.Errorf("expected the StreamService to not be ready after a failed configuration but got %+v", ready)
+	}
+}
@@ -17,61 +17,120 @@ limitations under the License.
 package controller
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
+	"sync"
+	"time"
 
 	"k8s.io/klog"
 
 	"github.com/paultag/sniff/parser"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/net/proxyproto"
+)
+
+const (
+	tlsRecordHeaderLength  = 5
+	tlsRecordTypeHandshake = 0x16
+	// maximum length of a TLS record payload (2^14) plus
+	// the expansion allowed for compressed or encrypted records
+	tlsMaxRecordLength = 16384 + 2048
+
+	// defaultHandshakeTimeout is the maximum time to receive
+	// the TLS ClientHello when the TCPProxy does not define one
+	defaultHandshakeTimeout = 10 * time.Second
+
+	// defaultServerLabel is the host label used in the metrics
+	// of connections handled by the default server (NGINX)
+	defaultServerLabel = "_"
 )
 
 // TCPServer describes a server that works in passthrough mode.
 type TCPServer struct {
-	Hostname      string
-	IP            string
-	Port          int
-	ProxyProtocol bool
+	Hostname string
+	IP       string
+	Port     int
+	// ProxyProtocol is the version of the PROXY protocol
+	// header sent to the server. Empty disables it
+	ProxyProtocol string
 }
 
 // TCPProxy describes the passthrough servers and a default as catch all.
+// The passthrough servers can be replaced at any time using SetServers
+// while the proxy is handling connections.
 type TCPProxy struct {
-	ServerList []*TCPServer
-	Default    *TCPServer
+	Default *TCPServer
+
+	// HandshakeTimeout is the maximum time to receive the TLS ClientHello
+	HandshakeTimeout time.Duration
+
+	MetricCollector metric.Collector
+
+	mu      sync.RWMutex
+	servers map[string]*TCPServer
+}
+
+// SetServers replaces the passthrough servers
+func (p *TCPProxy) SetServers(servers []*TCPServer) {
+	m := make(map[string]*TCPServer, len(servers))
+	for _, s := range servers {
+		m[s.Hostname] = s
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.servers = m
 }
 
 // Get returns the TCPServer to use for a given host.
 func (p *TCPProxy) Get(host string) *TCPServer {
-	if p.ServerList == nil {
-		return p.Default
-	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	for _, s := range p.ServerList {
-		if s.Hostname == host {
-			return s
-		}
+	if s, ok := p.servers[host]; ok {
+		return s
 	}
 
 	return p.Default
 }
 
-// Handle reads enough information from the connection to extract the hostname
+// Handle reads the TLS ClientHello from the connection to extract the hostname
 // and open a connection to the passthrough server.
 func (p *TCPProxy) Handle(conn net.Conn) {
 	defer conn.Close()
-	data := make([]byte, 4096)
 
-	length, err := conn.Read(data)
+	timeout := p.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	data, err := readClientHello(conn)
+	conn.SetReadDeadline(time.Time{})
 	if err != nil {
-		klog.V(4).Infof("Error reading the first 4k of the connection: %v", err)
-		return
+		p.metricCollector().IncSSLPassthroughHandshakeErrors()
+		if len(data) == 0 || err == io.EOF || err == io.ErrUnexpectedEOF || isTimeout(err) {
+			klog.V(4).Infof("Error reading TLS ClientHello from %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+
+		// not a TLS handshake, let NGINX answer the client
+		klog.V(4).Infof("Invalid TLS ClientHello from %v: %v", conn.RemoteAddr(), err)
 	}
 
 	proxy := p.Default
-	hostname, err := parser.GetHostname(data[:])
+	label := defaultServerLabel
+	hostname, err := parser.GetHostname(data)
 	if err == nil {
 		klog.V(4).Infof("Parsed hostname from TLS Client Hello: %s", hostname)
 		proxy = p.Get(hostname)
+		if proxy != p.Default {
+			label = hostname
+		}
 	}
 
 	if proxy == nil {
@@ -81,51 +140,107 @@ func (p *TCPProxy) Handle(conn net.Conn) {
 
 	clientConn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", proxy.IP, proxy.Port))
 	if err != nil {
+		klog.V(4).Infof("Error connecting to passthrough server %v:%v: %v", proxy.IP, proxy.Port, err)
 		return
 	}
 	defer clientConn.Close()
 
-	if proxy.ProxyProtocol {
+	p.metricCollector().IncSSLPassthroughConnections(label)
+
+	if proxy.ProxyProtocol != "" {
 		// write out the Proxy Protocol header
 		localAddr := conn.LocalAddr().(*net.TCPAddr)
 		remoteAddr := conn.RemoteAddr().(*net.TCPAddr)
-		protocol := "UNKNOWN"
-		if remoteAddr.IP.To4() != nil {
-			protocol = "TCP4"
-		} else if remoteAddr.IP.To16() != nil {
-			protocol = "TCP6"
+
+		var proxyProtocolHeader []byte
+		if proxy.ProxyProtocol == proxyproto.V2 {
+			proxyProtocolHeader = proxyproto.EncodeV2(remoteAddr, localAddr)
+		} else {
+			proxyProtocolHeader = proxyproto.EncodeV1(remoteAddr, localAddr)
 		}
-		proxyProtocolHeader := fmt.Sprintf("PROXY %s %s %s %d %d\r\n", protocol, remoteAddr.IP.String(), localAddr.IP.String(), remoteAddr.Port, localAddr.Port)
-		klog.V(4).Infof("Writing Proxy Protocol header: %s", proxyProtocolHeader)
-		_, err = fmt.Fprintf(clientConn, proxyProtocolHeader)
-	}
-	if err != nil {
-		klog.Errorf("Error writing Proxy Protocol header: %v", err)
-		clientConn.Close()
-	} else {
-		_, err = clientConn.Write(data[:length])
+
+		klog.V(4).Infof("Writing Proxy Protocol %v header: %q", proxy.ProxyProtocol, proxyProtocolHeader)
+		_, err = clientConn.Write(proxyProtocolHeader)
 		if err != nil {
-			klog.Errorf("Error writing the first 4k of proxy data: %v", err)
-			clientConn.Close()
+			klog.Errorf("Error writing Proxy Protocol header: %v", err)
+			return
 		}
 	}
 
-	pipe(clientConn, conn)
+	_, err = clientConn.Write(data)
+	if err != nil {
+		klog.Errorf("Error writing TLS ClientHello to passthrough server: %v", err)
+		return
+	}
+
+	sent, received := pipe(clientConn, conn)
+	p.metricCollector().AddSSLPassthroughBytes(label, sent+int64(len(data)), received)
 }
 
-func pipe(client, server net.Conn) {
-	doCopy := func(s, c net.Conn, cancel chan<- bool) {
-		io.Copy(s, c)
-		cancel <- true
+func (p *TCPProxy) metricCollector() metric.Collector {
+	if p.MetricCollector == nil {
+		return metric.DummyCollector{}
 	}
 
-	cancel := make(chan bool, 2)
+	return p.MetricCollector
+}
 
-	go doCopy(server, client, cancel)
-	go doCopy(client, server, cancel)
+// readClientHello reads the first TLS record of the connection, which
+// contains the ClientHello message. In case of error the bytes read
+// so far are returned.
+func readClientHello(r io.Reader) ([]byte, error) {
+	header := make([]byte, tlsRecordHeaderLength)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return header[:n], err
+	}
 
-	select {
-	case <-cancel:
-		return
+	if header[0] != tlsRecordTypeHandshake {
+		return header, fmt.Errorf("unexpected TLS record type 0x%x", header[0])
 	}
+
+	length := int(binary.BigEndian.Uint16(header[3:5]))
+	if length == 0 || length > tlsMaxRecordLength {
+		return header, fmt.Errorf("invalid TLS record length %v", length)
+	}
+
+	data := make([]byte, tlsRecordHeaderLength+length)
+	copy(data, header)
+
+	n, err = io.ReadFull(r, data[tlsRecordHeaderLength:])
+	if err != nil {
+		return data[:tlsRecordHeaderLength+n], err
+	}
+
+	return data, nil
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// pipe copies data in both directions until one of the connections
+// is closed and returns the number of bytes sent to and received
+// from the passthrough server
+func pipe(upstream, downstream net.Conn) (sent, received int64) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		received, _ = io.Copy(downstream, upstream)
+		// unblock the copy in the other direction
+		downstream.SetReadDeadline(time.Now())
+	}()
+
+	go func() {
+		defer wg.Done()
+		sent, _ = io.Copy(upstream, downstream)
+		upstream.SetReadDeadline(time.Now())
+	}()
+
+	wg.Wait()
+
+	return sent, received
 }
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paultag/sniff/parser"
+)
+
+// clientHello returns the first TLS record sent by a client
+// connecting to the given server name
+func clientHello(t *testing.T, serverName string) []byte {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		conn := tls.Client(client, &tls.Config{ServerName: serverName})
+		conn.Handshake()
+		client.Close()
+	}()
+
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, err := readClientHello(server)
+	if err != nil {
+		t.Fatalf("unexpected error reading the TLS ClientHello: %v", err)
+	}
+
+	return data
+}
+
+func TestReadClientHello(t *testing.T) {
+	data := clientHello(t, "passthrough.example.com")
+
+	hostname, err := parser.GetHostname(data)
+	if err != nil {
+		t.Fatalf("unexpected error parsing the TLS ClientHello: %v", err)
+	}
+	if hostname != "passthrough.example.com" {
+		t.Errorf("expected passthrough.example.com but returned %v", hostname)
+	}
+
+	// the record can arrive in several packets
+	r := io.MultiReader(bytes.NewReader(data[:3]), bytes.NewReader(data[3:10]), bytes.NewReader(data[10:]))
+	read, err := readClientHello(r)
+	if err != nil {
+		t.Fatalf("unexpected error reading a fragmented TLS ClientHello: %v", err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Errorf("expected the complete TLS ClientHello to be returned")
+	}
+
+	invalid := map[string][]byte{
+		"not a handshake": []byte("GET / HTTP/1.1\r\n\r\n"),
+		"empty record":    {0x16, 0x03, 0x01, 0x00, 0x00},
+		"truncated":       data[:len(data)-1],
+	}
+	for name, d := range invalid {
+		if _, err := readClientHello(bytes.NewReader(d)); err == nil {
+			t.Errorf("%v: expected an error reading the TLS ClientHello", name)
+		}
+	}
+}
+
+func TestTCPProxyGet(t *testing.T) {
+	def := &TCPServer{Hostname: "localhost"}
+	p := &TCPProxy{Default: def}
+
+	if s := p.Get("foo.bar"); s != def {
+		t.Errorf("expected the default server without passthrough servers")
+	}
+
+	foo := &TCPServer{Hostname: "foo.bar", IP: "10.0.0.1", Port: 443}
+	p.SetServers([]*TCPServer{foo})
+	if s := p.Get("foo.bar"); s != foo {
+		t.Errorf("expected the foo.bar server but returned %v", s)
+	}
+	if s := p.Get("bar.foo"); s != def {
+		t.Errorf("expected the default server for an unknown host")
+	}
+
+	p.SetServers(nil)
+	if s := p.Get("foo.bar"); s != def {
+		t.Errorf("expected the default server after removing the passthrough servers")
+	}
+}
+
+func TestTCPProxyHandle(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	defer backend.Close()
+
+	addr := backend.Addr().(*net.TCPAddr)
+	p := &TCPProxy{HandshakeTimeout: 5 * time.Second}
+	p.SetServers([]*TCPServer{{Hostname: "passthrough.example.com", IP: addr.IP.String(), Port: addr.Port}})
+
+	hello := clientHello(t, "passthrough.example.com")
+
+	server, client := net.Pipe()
+	go p.Handle(server)
+	go func() {
+		client.Write(hello)
+		client.Write([]byte("payload"))
+		client.Close()
+	}()
+
+	conn, err := backend.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error accepting the proxied connection: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("unexpected error reading the proxied connection: %v", err)
+	}
+	if !bytes.Equal(data, append(hello, []byte("payload")...)) {
+		t.Errorf("expected the TLS ClientHello and the payload to be proxied")
+	}
+}
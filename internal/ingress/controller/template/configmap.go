@@ -19,6 +19,7 @@ package template
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,9 @@ import (
 	"github.com/mitchellh/mapstructure"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/loadbalancing"
+	plugin_annotation "k8s.io/ingress-nginx/internal/ingress/annotations/plugins"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/runtime"
@@ -48,14 +52,78 @@ const (
 	hideHeaders              = "hide-headers"
 	nginxStatusIpv4Whitelist = "nginx-status-ipv4-whitelist"
 	nginxStatusIpv6Whitelist = "nginx-status-ipv6-whitelist"
+	nginxStatusListen        = "nginx-status-listen"
 	proxyHeaderTimeout       = "proxy-protocol-header-timeout"
 	workerProcesses          = "worker-processes"
+	workerCPUAffinity        = "worker-cpu-affinity"
+	globalRateLimitBackend   = "global-rate-limit-backend"
+	plugins                  = "plugins"
+	loadBalanceAlgorithm     = "load-balance"
+	gzipProxied              = "gzip-proxied"
+	luaSharedDicts           = "lua-shared-dicts"
+	maxmindEditionIDs        = "maxmind-edition-ids"
+	maxmindRefreshInterval   = "maxmind-refresh-interval"
+
+	globalAuthURL             = "global-auth-url"
+	globalAuthMethod          = "global-auth-method"
+	globalAuthSignin          = "global-auth-signin"
+	globalAuthResponseHeaders = "global-auth-response-headers"
+	globalAuthRequestRedirect = "global-auth-request-redirect"
+	globalAuthSnippet         = "global-auth-snippet"
 )
 
 var (
 	validRedirectCodes = sets.NewInt([]int{301, 302, 307, 308}...)
+
+	validGlobalRateLimitBackends = sets.NewString("memcached", "redis")
+
+	validGzipProxied = sets.NewString("off", "expired", "no-cache", "no-store", "private", "no_last_modified", "no_etag", "auth", "any")
+
+	// GeoIP2 editions supported by the template and the kind of the database
+	maxmindEditions = map[string]string{
+		"GeoLite2-City":    "city",
+		"GeoIP2-City":      "city",
+		"GeoLite2-Country": "country",
+		"GeoIP2-Country":   "country",
+		"GeoLite2-ASN":     "asn",
+	}
+
+	// variables defined by every kind of GeoIP2 database and the path of
+	// their value in the database
+	maxmindVariables = map[string][]geoIP2Variable{
+		"city": {
+			{"geoip2_city_country_code", "country iso_code"},
+			{"geoip2_city_country_name", "country names en"},
+			{"geoip2_city_continent_code", "continent code"},
+			{"geoip2_city", "city names en"},
+			{"geoip2_postal_code", "postal code"},
+			{"geoip2_dma_code", "location metro_code"},
+			{"geoip2_latitude", "location latitude"},
+			{"geoip2_longitude", "location longitude"},
+			{"geoip2_region_code", "subdivisions 0 iso_code"},
+			{"geoip2_region_name", "subdivisions 0 names en"},
+		},
+		"country": {
+			{"geoip2_country_code", "country iso_code"},
+			{"geoip2_country_name", "country names en"},
+			{"geoip2_continent_code", "continent code"},
+		},
+		"asn": {
+			{"geoip2_asn", "autonomous_system_number"},
+			{"geoip2_org", "autonomous_system_organization"},
+		},
+	}
+
+	luaSharedDictNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	luaSharedDictSizeRegexp = regexp.MustCompile(`^([0-9]+)([kKmM]?)$`)
+
+	forwardedForHeaderRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
 )
 
+// denyAllSourceRange is an address no client can use, to deny the access
+// to all the clients when no entry of whitelist-source-range is valid
+const denyAllSourceRange = "0.0.0.0/32"
+
 // ReadConfig obtains the configuration defined by the user merged with the defaults.
 func ReadConfig(src map[string]string) config.Configuration {
 	conf := map[string]string{}
@@ -68,7 +136,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 	errors := make([]int, 0)
 	skipUrls := make([]string, 0)
 	whiteList := make([]string, 0)
-	proxyList := make([]string, 0)
+	proxyList := to.ProxyRealIPCIDR
 	hideHeadersList := make([]string, 0)
 
 	bindAddressIpv4List := make([]string, 0)
@@ -78,12 +146,16 @@ func ReadConfig(src map[string]string) config.Configuration {
 	blockUserAgentList := make([]string, 0)
 	blockRefererList := make([]string, 0)
 
+	pluginList := make([]string, 0)
+
 	if val, ok := conf[customHTTPErrors]; ok {
 		delete(conf, customHTTPErrors)
 		for _, i := range strings.Split(val, ",") {
-			j, err := strconv.Atoi(i)
+			j, err := strconv.Atoi(strings.TrimSpace(i))
 			if err != nil {
 				klog.Warningf("%v is not a valid http code: %v", i, err)
+			} else if j < 300 || j > 599 {
+				klog.Warningf("%v is not a valid http code: the code must be between 300 and 599", j)
 			} else {
 				errors = append(errors, j)
 			}
@@ -99,13 +171,29 @@ func ReadConfig(src map[string]string) config.Configuration {
 	}
 	if val, ok := conf[whitelistSourceRange]; ok {
 		delete(conf, whitelistSourceRange)
-		whiteList = append(whiteList, strings.Split(val, ",")...)
+		for _, cidr := range parseAddressList(whitelistSourceRange, val) {
+			if cidr == "unix:" {
+				klog.Warningf("Ignoring invalid %v entry %q", whitelistSourceRange, cidr)
+				continue
+			}
+			whiteList = append(whiteList, cidr)
+		}
+		if len(whiteList) == 0 && strings.TrimSpace(val) != "" {
+			// an empty list allows every client, which is not what the
+			// user asked for
+			klog.Errorf("No valid entry in %v %q, denying access to all the clients", whitelistSourceRange, val)
+			whiteList = append(whiteList, denyAllSourceRange)
+		}
 	}
 	if val, ok := conf[proxyRealIPCIDR]; ok {
 		delete(conf, proxyRealIPCIDR)
-		proxyList = append(proxyList, strings.Split(val, ",")...)
-	} else {
-		proxyList = append(proxyList, "0.0.0.0/0")
+		proxyList = parseAddressList(proxyRealIPCIDR, val)
+		if len(proxyList) == 0 {
+			// falling back to the default would trust any client to
+			// provide its own address
+			klog.Errorf("No valid entry in %v %q, no proxy is trusted to provide the client address", proxyRealIPCIDR, val)
+			proxyList = []string{}
+		}
 	}
 	if val, ok := conf[bindAddress]; ok {
 		delete(conf, bindAddress)
@@ -125,15 +213,29 @@ func ReadConfig(src map[string]string) config.Configuration {
 
 	if val, ok := conf[blockCIDRs]; ok {
 		delete(conf, blockCIDRs)
-		blockCIDRList = strings.Split(val, ",")
+		blockCIDRList = parseAddressList(blockCIDRs, val)
 	}
 	if val, ok := conf[blockUserAgents]; ok {
 		delete(conf, blockUserAgents)
-		blockUserAgentList = strings.Split(val, ",")
+		blockUserAgentList = parseBlockList(val)
 	}
 	if val, ok := conf[blockReferers]; ok {
 		delete(conf, blockReferers)
-		blockRefererList = strings.Split(val, ",")
+		blockRefererList = parseBlockList(val)
+	}
+	if val, ok := conf[plugins]; ok {
+		delete(conf, plugins)
+		for _, i := range strings.Split(val, ",") {
+			i = strings.TrimSpace(i)
+			if i == "" {
+				continue
+			}
+			if !plugin_annotation.NameRegexp.MatchString(i) {
+				klog.Warningf("%v is not a valid name for a Lua plugin", i)
+				continue
+			}
+			pluginList = append(pluginList, i)
+		}
 	}
 
 	if val, ok := conf[httpRedirectCode]; ok {
@@ -161,6 +263,26 @@ func ReadConfig(src map[string]string) config.Configuration {
 		}
 	}
 
+	if val, ok := conf[maxmindEditionIDs]; ok {
+		delete(conf, maxmindEditionIDs)
+		editions, err := parseMaxmindEditionIDs(val)
+		if err != nil {
+			klog.Warningf("Invalid maxmind-edition-ids %q: %v. Using the default.", val, err)
+		} else {
+			to.MaxmindEditionIDs = editions
+		}
+	}
+
+	if val, ok := conf[maxmindRefreshInterval]; ok {
+		delete(conf, maxmindRefreshInterval)
+		duration, err := time.ParseDuration(val)
+		if err != nil || duration < 0 {
+			klog.Warningf("%q is not a valid maxmind-refresh-interval. Using the default %v", val, to.MaxmindRefreshInterval)
+		} else {
+			to.MaxmindRefreshInterval = duration
+		}
+	}
+
 	streamResponses := 1
 	if val, ok := conf[proxyStreamResponses]; ok {
 		delete(conf, proxyStreamResponses)
@@ -174,19 +296,24 @@ func ReadConfig(src map[string]string) config.Configuration {
 
 	// Nginx Status whitelist
 	if val, ok := conf[nginxStatusIpv4Whitelist]; ok {
-		whitelist := make([]string, 0)
-		whitelist = append(whitelist, strings.Split(val, ",")...)
-		to.NginxStatusIpv4Whitelist = whitelist
+		to.NginxStatusIpv4Whitelist = parseAddressList(nginxStatusIpv4Whitelist, val)
 
 		delete(conf, nginxStatusIpv4Whitelist)
 	}
 	if val, ok := conf[nginxStatusIpv6Whitelist]; ok {
-		whitelist := make([]string, 0)
-		whitelist = append(whitelist, strings.Split(val, ",")...)
-		to.NginxStatusIpv6Whitelist = whitelist
+		to.NginxStatusIpv6Whitelist = parseAddressList(nginxStatusIpv6Whitelist, val)
 
 		delete(conf, nginxStatusIpv6Whitelist)
 	}
+	if val, ok := conf[nginxStatusListen]; ok {
+		delete(conf, nginxStatusListen)
+		addresses, err := parseListenAddresses(val)
+		if err != nil {
+			klog.Warningf("Invalid nginx-status-listen %q: %v. Using the default.", val, err)
+		} else {
+			to.NginxStatusListen = addresses
+		}
+	}
 
 	if val, ok := conf[workerProcesses]; ok {
 		to.WorkerProcesses = val
@@ -198,6 +325,68 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, workerProcesses)
 	}
 
+	if val, ok := conf[workerCPUAffinity]; ok {
+		to.WorkerCPUAffinity = val
+
+		// NGINX binds the workers to the first CPUs of the node, including
+		// the ones the container is not allowed to run on
+		if val == "auto" {
+			if mask := runtime.CPUAffinityMask(); mask != "" {
+				to.WorkerCPUAffinity = fmt.Sprintf("auto %v", mask)
+			}
+		}
+
+		delete(conf, workerCPUAffinity)
+	}
+
+	if val, ok := conf[globalRateLimitBackend]; ok {
+		delete(conf, globalRateLimitBackend)
+		if val == "" || validGlobalRateLimitBackends.Has(val) {
+			to.GlobalRateLimitBackend = val
+		} else {
+			klog.Warningf("%v is not a valid backend for global rate limits. Global rate limits are disabled.", val)
+		}
+	}
+
+	if val, ok := conf[loadBalanceAlgorithm]; ok {
+		delete(conf, loadBalanceAlgorithm)
+		if loadbalancing.ValidAlgorithms.Has(val) {
+			to.LoadBalancing = val
+		} else {
+			klog.Warningf("%v is not a valid load balancing algorithm. Using the default %q", val, to.LoadBalancing)
+		}
+	}
+
+	if val, ok := conf[gzipProxied]; ok {
+		delete(conf, gzipProxied)
+		values := strings.Fields(val)
+		if len(values) > 0 && validGzipProxied.HasAll(values...) {
+			to.GzipProxied = strings.Join(values, " ")
+		} else {
+			klog.Warningf("%v is not a valid value for gzip-proxied. Using the default %q", val, to.GzipProxied)
+		}
+	}
+
+	if val, ok := conf[luaSharedDicts]; ok {
+		delete(conf, luaSharedDicts)
+		for _, dict := range strings.Split(val, ",") {
+			dict = strings.TrimSpace(dict)
+			if dict == "" {
+				continue
+			}
+
+			name, size, err := parseLuaSharedDict(dict)
+			if err != nil {
+				klog.Warningf("Ignoring Lua shared dictionary %q: %v", dict, err)
+				continue
+			}
+
+			to.LuaSharedDicts[name] = size
+		}
+	}
+
+	to.GlobalExternalAuth = parseGlobalExternalAuth(conf)
+
 	to.CustomHTTPErrors = filterErrors(errors)
 	to.SkipAccessLogURLs = skipUrls
 	to.WhitelistSourceRange = whiteList
@@ -208,6 +397,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 	to.BlockUserAgents = blockUserAgentList
 	to.BlockReferers = blockRefererList
 	to.HideHeaders = hideHeadersList
+	to.Plugins = pluginList
 	to.ProxyStreamResponses = streamResponses
 	to.DisableIpv6DNS = !ing_net.IsIPv6Enabled()
 
@@ -227,6 +417,12 @@ func ReadConfig(src map[string]string) config.Configuration {
 		klog.Warningf("unexpected error merging defaults: %v", err)
 	}
 
+	filterCompression(&to)
+	filterConnections(&to)
+	filterMetrics(&to)
+	filterRealIP(&to)
+	filterLogFormats(&to)
+
 	hash, err := hashstructure.Hash(to, &hashstructure.HashOptions{
 		TagName: "json",
 	})
@@ -239,6 +435,318 @@ func ReadConfig(src map[string]string) config.Configuration {
 	return to
 }
 
+// parseLuaSharedDict parses the definition of a Lua shared dictionary,
+// "name:size", and returns the name and the size in kilobytes. The size
+// is in megabytes unless it ends with the unit k or m.
+func parseLuaSharedDict(dict string) (string, int, error) {
+	parts := strings.SplitN(dict, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("expected name:size")
+	}
+
+	name := strings.TrimSpace(parts[0])
+	if !luaSharedDictNameRegexp.MatchString(name) {
+		return "", 0, fmt.Errorf("invalid name %q", name)
+	}
+
+	m := luaSharedDictSizeRegexp.FindStringSubmatch(strings.TrimSpace(parts[1]))
+	if m == nil {
+		return "", 0, fmt.Errorf("invalid size %q", parts[1])
+	}
+
+	size, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid size %q: %v", parts[1], err)
+	}
+
+	if m[2] != "k" && m[2] != "K" {
+		size = size * 1024
+	}
+
+	// NGINX rejects shared memory zones smaller than 8k
+	if size < 8 {
+		return "", 0, fmt.Errorf("the size must be at least 8k")
+	}
+
+	return name, size, nil
+}
+
+// parseAddressList parses a comma separated list of addresses, networks
+// or "unix:", skipping the invalid ones.
+func parseAddressList(name, val string) []string {
+	addresses := []string{}
+	for _, spec := range strings.Split(val, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		if spec != "unix:" {
+			if _, _, err := ing_net.ParseIPNets(spec); err != nil {
+				klog.Warningf("Ignoring invalid %v entry %q: %v", name, spec, err)
+				continue
+			}
+		}
+
+		addresses = append(addresses, spec)
+	}
+
+	return addresses
+}
+
+// parseBlockList parses a comma separated list of User-Agent or Referer
+// patterns. The quotes around a pattern are removed because the patterns
+// are always quoted in the configuration
+func parseBlockList(val string) []string {
+	patterns := []string{}
+	for _, pattern := range strings.Split(val, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if len(pattern) > 1 && strings.HasPrefix(pattern, `"`) && strings.HasSuffix(pattern, `"`) {
+			pattern = pattern[1 : len(pattern)-1]
+		}
+		if pattern == "" {
+			continue
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+// parseGlobalExternalAuth reads the configuration of the global external
+// authentication. Invalid optional values are ignored, and an invalid URL
+// disables the global authentication
+func parseGlobalExternalAuth(conf map[string]string) authreq.Config {
+	cfg := authreq.Config{}
+
+	urlString := conf[globalAuthURL]
+	method := conf[globalAuthMethod]
+	signin := conf[globalAuthSignin]
+	headers := conf[globalAuthResponseHeaders]
+	requestRedirect := conf[globalAuthRequestRedirect]
+	authSnippet := conf[globalAuthSnippet]
+	for _, key := range []string{globalAuthURL, globalAuthMethod, globalAuthSignin, globalAuthResponseHeaders, globalAuthRequestRedirect, globalAuthSnippet} {
+		delete(conf, key)
+	}
+
+	if urlString == "" {
+		return cfg
+	}
+
+	authURL, err := authreq.ParseURL(urlString)
+	if err != nil {
+		klog.Warningf("Invalid %v %q, global external authentication is disabled: %v", globalAuthURL, urlString, err)
+		return cfg
+	}
+	cfg.URL = urlString
+	cfg.Host = authURL.Hostname()
+
+	if method != "" {
+		if authreq.ValidMethod(method) {
+			cfg.Method = method
+		} else {
+			klog.Warningf("Ignoring invalid %v %q", globalAuthMethod, method)
+		}
+	}
+
+	if signin != "" {
+		if _, err := authreq.ParseURL(signin); err == nil {
+			cfg.SigninURL = signin
+		} else {
+			klog.Warningf("Ignoring invalid %v %q: %v", globalAuthSignin, signin, err)
+		}
+	}
+
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !authreq.ValidHeader(header) {
+			klog.Warningf("Ignoring invalid header %q in %v", header, globalAuthResponseHeaders)
+			continue
+		}
+		cfg.ResponseHeaders = append(cfg.ResponseHeaders, header)
+	}
+
+	if requestRedirect != "" {
+		if strings.ContainsAny(requestRedirect, " \t\r\n;'\"{}") {
+			klog.Warningf("Ignoring invalid %v %q", globalAuthRequestRedirect, requestRedirect)
+		} else {
+			cfg.RequestRedirect = requestRedirect
+		}
+	}
+
+	cfg.AuthSnippet = authSnippet
+
+	return cfg
+}
+
+// parseListenAddresses parses a comma separated list of IP:port addresses
+func parseListenAddresses(val string) ([]string, error) {
+	addresses := []string{}
+	for _, address := range strings.Split(val, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("%q is not an IP address", host)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("%q is not a valid port", port)
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses, nil
+}
+
+// parseMaxmindEditionIDs parses a comma separated list of GeoIP2 editions.
+// Only one edition of each kind is allowed, as they define the same variables.
+func parseMaxmindEditionIDs(val string) ([]string, error) {
+	editions := []string{}
+	kinds := sets.NewString()
+	for _, edition := range strings.Split(val, ",") {
+		edition = strings.TrimSpace(edition)
+		if edition == "" {
+			continue
+		}
+
+		kind, ok := maxmindEditions[edition]
+		if !ok {
+			return nil, fmt.Errorf("unsupported edition %v", edition)
+		}
+		if kinds.Has(kind) {
+			return nil, fmt.Errorf("only one %v edition can be used", kind)
+		}
+
+		kinds.Insert(kind)
+		editions = append(editions, edition)
+	}
+
+	return editions, nil
+}
+
+// filterCompression replaces the gzip and brotli settings NGINX would
+// reject with the default values.
+func filterCompression(cfg *config.Configuration) {
+	def := config.NewDefault()
+
+	if cfg.GzipLevel < 1 || cfg.GzipLevel > 9 {
+		klog.Warningf("%v is not a valid gzip compression level (1-9). Using the default %v", cfg.GzipLevel, def.GzipLevel)
+		cfg.GzipLevel = def.GzipLevel
+	}
+
+	if cfg.BrotliLevel < 0 || cfg.BrotliLevel > 11 {
+		klog.Warningf("%v is not a valid brotli compression level (0-11). Using the default %v", cfg.BrotliLevel, def.BrotliLevel)
+		cfg.BrotliLevel = def.BrotliLevel
+	}
+
+	// a trailing backslash escapes the closing quote of the directive
+	if strings.ContainsAny(cfg.GzipDisable, "\"\r\n") || strings.HasSuffix(cfg.GzipDisable, `\`) {
+		klog.Warningf("%q is not a valid value for gzip-disable. Using the default %q", cfg.GzipDisable, def.GzipDisable)
+		cfg.GzipDisable = def.GzipDisable
+	}
+}
+
+// filterConnections replaces the listen and client keepalive settings NGINX
+// would reject with the default values.
+func filterConnections(cfg *config.Configuration) {
+	def := config.NewDefault()
+
+	if cfg.BacklogSize < 0 {
+		klog.Warningf("%v is not a valid backlog size. Using the value of net.core.somaxconn", cfg.BacklogSize)
+		cfg.BacklogSize = def.BacklogSize
+	}
+
+	if cfg.KeepAlive < 0 {
+		klog.Warningf("%v is not a valid keep-alive timeout. Using the default %v", cfg.KeepAlive, def.KeepAlive)
+		cfg.KeepAlive = def.KeepAlive
+	}
+
+	if cfg.KeepAliveRequests < 1 {
+		klog.Warningf("%v is not a valid number of keep-alive requests. Using the default %v", cfg.KeepAliveRequests, def.KeepAliveRequests)
+		cfg.KeepAliveRequests = def.KeepAliveRequests
+	}
+}
+
+// filterMetrics keeps the configuration of the Lua monitor within the
+// limits of the batches preferred by the collector of the metrics
+func filterMetrics(cfg *config.Configuration) {
+	if cfg.MetricsFlushInterval < config.MinFlushInterval || cfg.MetricsFlushInterval > config.MaxFlushInterval {
+		klog.Warningf("%v is not a valid metrics flush interval, it must be between %v and %v milliseconds. Using the default %v",
+			cfg.MetricsFlushInterval, config.MinFlushInterval, config.MaxFlushInterval, config.DefaultFlushInterval)
+		cfg.MetricsFlushInterval = config.DefaultFlushInterval
+	}
+
+	if cfg.MetricsMaxBatchSize < 1 || cfg.MetricsMaxBatchSize > config.MaxBatchSize {
+		klog.Warningf("%v is not a valid metrics batch size, it must be between 1 and %v. Using the default %v",
+			cfg.MetricsMaxBatchSize, config.MaxBatchSize, config.DefaultMaxBatchSize)
+		cfg.MetricsMaxBatchSize = config.DefaultMaxBatchSize
+	}
+}
+
+// filterRealIP replaces the header NGINX would reject as the source of the
+// client address with the default one, and warns when any client is trusted
+// to provide it.
+func filterRealIP(cfg *config.Configuration) {
+	def := config.NewDefault()
+
+	if !forwardedForHeaderRegexp.MatchString(cfg.ForwardedForHeader) {
+		klog.Warningf("%q is not a valid forwarded-for-header. Using the default %v", cfg.ForwardedForHeader, def.ForwardedForHeader)
+		cfg.ForwardedForHeader = def.ForwardedForHeader
+	}
+
+	if cfg.UseForwardedHeaders && !cfg.UseProxyProtocol {
+		for _, cidr := range cfg.ProxyRealIPCIDR {
+			if cidr == "0.0.0.0/0" || cidr == "::/0" {
+				klog.Warningf("use-forwarded-headers is enabled and proxy-real-ip-cidr contains %v: any client can set its own address using the %v header", cidr, cfg.ForwardedForHeader)
+				break
+			}
+		}
+	}
+}
+
+// filterLogFormats replaces the log formats NGINX would reject with the
+// default ones. The variables declared in the snippets of the ConfigMap
+// can be used in the log formats.
+func filterLogFormats(cfg *config.Configuration) {
+	def := config.NewDefault()
+
+	if cfg.LogFormatUpstream != def.LogFormatUpstream {
+		unknown, err := checkLogFormatUpstream(cfg.LogFormatUpstream, cfg.HTTPSnippet, cfg.ServerSnippet, cfg.LocationSnippet)
+		if err != nil {
+			klog.Warningf("Invalid log-format-upstream %q: %v. Using the default.", cfg.LogFormatUpstream, err)
+			cfg.LogFormatUpstream = def.LogFormatUpstream
+		} else if len(unknown) > 0 {
+			klog.Warningf("log-format-upstream references variables not declared by the controller or the ConfigMap snippets: %v. NGINX rejects the configuration if they are not declared in an annotation snippet.", strings.Join(unknown, ", "))
+		}
+	}
+
+	if cfg.LogFormatStream != def.LogFormatStream {
+		unknown, err := checkLogFormatStream(cfg.LogFormatStream, cfg.StreamSnippet)
+		if err != nil {
+			klog.Warningf("Invalid log-format-stream %q: %v. Using the default.", cfg.LogFormatStream, err)
+			cfg.LogFormatStream = def.LogFormatStream
+		} else if len(unknown) > 0 {
+			klog.Warningf("log-format-stream references variables not declared by the controller or the stream-snippet: %v. NGINX rejects the configuration if they are not declared.", strings.Join(unknown, ", "))
+		}
+	}
+
+	if cfg.LogFormatEscapeJSON && cfg.LogFormatEscapeNone {
+		klog.Warning("log-format-escape-json and log-format-escape-none are both enabled. Using JSON escaping.")
+		cfg.LogFormatEscapeNone = false
+	}
+}
+
 func filterErrors(codes []int) []int {
 	var fa []int
 	for _, code := range codes {
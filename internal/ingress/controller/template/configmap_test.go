@@ -25,7 +25,9 @@ import (
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/mitchellh/hashstructure"
 
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/runtime"
 )
 
 func TestFilterErrors(t *testing.T) {
@@ -51,6 +53,442 @@ func TestProxyTimeoutParsing(t *testing.T) {
 	}
 }
 
+func TestGlobalRateLimitBackendParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect string
+	}{
+		"memcached":       {"memcached", "memcached"},
+		"redis":           {"redis", "redis"},
+		"invalid backend": {"cassandra", ""},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"global-rate-limit-backend": tc.input})
+		if cfg.GlobalRateLimitBackend != tc.expect {
+			t.Errorf("Testing %v. Expected %q but got %q", n, tc.expect, cfg.GlobalRateLimitBackend)
+		}
+	}
+}
+
+func TestLoadBalanceParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect string
+	}{
+		"round robin":       {"round_robin", "round_robin"},
+		"ewma":              {"ewma", "ewma"},
+		"ip hash":           {"ip_hash", "ip_hash"},
+		"invalid algorithm": {"least_conn", ""},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"load-balance": tc.input})
+		if cfg.LoadBalancing != tc.expect {
+			t.Errorf("Testing %v. Expected %q but got %q", n, tc.expect, cfg.LoadBalancing)
+		}
+	}
+}
+
+func TestWorkerCPUAffinityParsing(t *testing.T) {
+	cfg := ReadConfig(map[string]string{"worker-cpu-affinity": "0001 0010"})
+	if cfg.WorkerCPUAffinity != "0001 0010" {
+		t.Errorf("expected %q but got %q", "0001 0010", cfg.WorkerCPUAffinity)
+	}
+
+	cfg = ReadConfig(map[string]string{"worker-cpu-affinity": "auto"})
+	if cfg.WorkerCPUAffinity != "auto "+runtime.CPUAffinityMask() {
+		t.Errorf("expected the automatic binding restricted to %q but got %q", runtime.CPUAffinityMask(), cfg.WorkerCPUAffinity)
+	}
+}
+
+func TestCompressionParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input       map[string]string
+		gzipLevel   int
+		brotliLevel int
+		gzipProxied string
+	}{
+		"defaults":             {map[string]string{}, 5, 4, "any"},
+		"valid levels":         {map[string]string{"gzip-level": "9", "brotli-level": "0"}, 9, 0, "any"},
+		"invalid levels":       {map[string]string{"gzip-level": "0", "brotli-level": "12"}, 5, 4, "any"},
+		"valid gzip proxied":   {map[string]string{"gzip-proxied": "expired  no-cache auth"}, 5, 4, "expired no-cache auth"},
+		"invalid gzip proxied": {map[string]string{"gzip-proxied": "any; include /etc/passwd"}, 5, 4, "any"},
+		"empty gzip proxied":   {map[string]string{"gzip-proxied": ""}, 5, 4, "any"},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if cfg.GzipLevel != tc.gzipLevel {
+			t.Errorf("Testing %v. Expected gzip level %v but got %v", n, tc.gzipLevel, cfg.GzipLevel)
+		}
+		if cfg.BrotliLevel != tc.brotliLevel {
+			t.Errorf("Testing %v. Expected brotli level %v but got %v", n, tc.brotliLevel, cfg.BrotliLevel)
+		}
+		if cfg.GzipProxied != tc.gzipProxied {
+			t.Errorf("Testing %v. Expected gzip proxied %q but got %q", n, tc.gzipProxied, cfg.GzipProxied)
+		}
+	}
+}
+
+func TestGzipDisableParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect string
+	}{
+		"msie6":              {"msie6", "msie6"},
+		"regex":              {`MSIE [4-6]\.`, `MSIE [4-6]\.`},
+		"injection":          {`msie6"; include /etc/passwd; #`, ""},
+		"trailing backslash": {`msie6\`, ""},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"gzip-disable": tc.input})
+		if cfg.GzipDisable != tc.expect {
+			t.Errorf("Testing %v. Expected %q but got %q", n, tc.expect, cfg.GzipDisable)
+		}
+	}
+}
+
+func TestConnectionsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input             map[string]string
+		reusePort         bool
+		backlogSize       int
+		keepAlive         int
+		keepAliveRequests int
+	}{
+		"defaults":        {map[string]string{}, true, 0, 75, 100},
+		"valid values":    {map[string]string{"reuse-port": "false", "backlog-size": "4096", "keep-alive": "0", "keep-alive-requests": "10000"}, false, 4096, 0, 10000},
+		"negative values": {map[string]string{"backlog-size": "-1", "keep-alive": "-5", "keep-alive-requests": "-1"}, true, 0, 75, 100},
+		"zero requests":   {map[string]string{"keep-alive-requests": "0"}, true, 0, 75, 100},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if cfg.ReusePort != tc.reusePort {
+			t.Errorf("Testing %v. Expected reuse-port %v but got %v", n, tc.reusePort, cfg.ReusePort)
+		}
+		if cfg.BacklogSize != tc.backlogSize {
+			t.Errorf("Testing %v. Expected backlog size %v but got %v", n, tc.backlogSize, cfg.BacklogSize)
+		}
+		if cfg.KeepAlive != tc.keepAlive {
+			t.Errorf("Testing %v. Expected keep-alive %v but got %v", n, tc.keepAlive, cfg.KeepAlive)
+		}
+		if cfg.KeepAliveRequests != tc.keepAliveRequests {
+			t.Errorf("Testing %v. Expected keep-alive-requests %v but got %v", n, tc.keepAliveRequests, cfg.KeepAliveRequests)
+		}
+	}
+}
+
+func TestMetricsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input         map[string]string
+		flushInterval int
+		maxBatchSize  int
+	}{
+		"defaults":           {map[string]string{}, 1000, 10000},
+		"valid values":       {map[string]string{"metrics-flush-interval": "5000", "metrics-max-batch-size": "50000"}, 5000, 50000},
+		"too short interval": {map[string]string{"metrics-flush-interval": "10"}, 1000, 10000},
+		"too long interval":  {map[string]string{"metrics-flush-interval": "3600000"}, 1000, 10000},
+		"empty batches":      {map[string]string{"metrics-max-batch-size": "0"}, 1000, 10000},
+		"too large batches":  {map[string]string{"metrics-max-batch-size": "1000000"}, 1000, 10000},
+		"non numeric values": {map[string]string{"metrics-flush-interval": "1s"}, 1000, 10000},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if cfg.MetricsFlushInterval != tc.flushInterval {
+			t.Errorf("Testing %v. Expected metrics-flush-interval %v but got %v", n, tc.flushInterval, cfg.MetricsFlushInterval)
+		}
+		if cfg.MetricsMaxBatchSize != tc.maxBatchSize {
+			t.Errorf("Testing %v. Expected metrics-max-batch-size %v but got %v", n, tc.maxBatchSize, cfg.MetricsMaxBatchSize)
+		}
+	}
+}
+
+func TestRealIPParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input              map[string]string
+		trusted            []string
+		forwardedForHeader string
+	}{
+		"defaults":       {map[string]string{}, []string{"0.0.0.0/0"}, "X-Forwarded-For"},
+		"valid values":   {map[string]string{"proxy-real-ip-cidr": "10.0.0.0/8, 192.168.1.1,2001:db8::/32,unix:", "forwarded-for-header": "CF-Connecting-IP"}, []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "unix:"}, "CF-Connecting-IP"},
+		"invalid cidrs":  {map[string]string{"proxy-real-ip-cidr": "10.0.0.0/8,10.0.0.0/33;\nreturn 200"}, []string{"10.0.0.0/8"}, "X-Forwarded-For"},
+		"no valid cidr":  {map[string]string{"proxy-real-ip-cidr": "invalid"}, []string{}, "X-Forwarded-For"},
+		"invalid header": {map[string]string{"forwarded-for-header": "X-Forwarded-For; return 200"}, []string{"0.0.0.0/0"}, "X-Forwarded-For"},
+		"proxy protocol": {map[string]string{"forwarded-for-header": "proxy_protocol"}, []string{"0.0.0.0/0"}, "proxy_protocol"},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if !reflect.DeepEqual(cfg.ProxyRealIPCIDR, tc.trusted) {
+			t.Errorf("Testing %v. Expected trusted addresses %v but got %v", n, tc.trusted, cfg.ProxyRealIPCIDR)
+		}
+		if cfg.ForwardedForHeader != tc.forwardedForHeader {
+			t.Errorf("Testing %v. Expected forwarded-for-header %q but got %q", n, tc.forwardedForHeader, cfg.ForwardedForHeader)
+		}
+	}
+}
+
+func TestWhitelistSourceRangeParsing(t *testing.T) {
+	cfg := ReadConfig(map[string]string{
+		"whitelist-source-range":       "10.0.0.0/8, 2001:db8::/32,unix:,10.0.0.0/33",
+		"merge-whitelist-source-range": "true",
+	})
+
+	expected := []string{"10.0.0.0/8", "2001:db8::/32"}
+	if !reflect.DeepEqual(cfg.WhitelistSourceRange, expected) {
+		t.Errorf("Expected whitelist-source-range %v but got %v", expected, cfg.WhitelistSourceRange)
+	}
+	if !cfg.MergeWhitelistSourceRange {
+		t.Errorf("Expected merge-whitelist-source-range to be enabled")
+	}
+
+	cfg = ReadConfig(map[string]string{
+		"whitelist-source-range": "10.0.0.0/33,unix:",
+	})
+
+	expected = []string{"0.0.0.0/32"}
+	if !reflect.DeepEqual(cfg.WhitelistSourceRange, expected) {
+		t.Errorf("Expected whitelist-source-range %v denying all the clients but got %v", expected, cfg.WhitelistSourceRange)
+	}
+
+	cfg = ReadConfig(map[string]string{
+		"whitelist-source-range": "",
+	})
+
+	if len(cfg.WhitelistSourceRange) != 0 {
+		t.Errorf("Expected empty whitelist-source-range but got %v", cfg.WhitelistSourceRange)
+	}
+}
+
+func TestBlockListsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input      map[string]string
+		cidrs      []string
+		userAgents []string
+		referers   []string
+	}{
+		"defaults": {map[string]string{}, []string{}, []string{}, []string{}},
+		"valid values": {map[string]string{
+			"block-cidrs":       "10.0.0.0/8, 192.168.1.1,2001:db8::/32",
+			"block-user-agents": `~*bot, "Mozilla/5.0 (compatible; Googlebot/2.1)",`,
+			"block-referers":    "~*spam\\.example\\.com$",
+		}, []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}, []string{"~*bot", "Mozilla/5.0 (compatible; Googlebot/2.1)"}, []string{"~*spam\\.example\\.com$"}},
+		"invalid cidrs": {map[string]string{"block-cidrs": "10.0.0.0/8,all;\nreturn 200,,"}, []string{"10.0.0.0/8"}, []string{}, []string{}},
+		"empty entries": {map[string]string{"block-user-agents": ", ,\"\"", "block-referers": ","}, []string{}, []string{}, []string{}},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if !reflect.DeepEqual(cfg.BlockCIDRs, tc.cidrs) {
+			t.Errorf("Testing %v. Expected block-cidrs %v but got %v", n, tc.cidrs, cfg.BlockCIDRs)
+		}
+		if !reflect.DeepEqual(cfg.BlockUserAgents, tc.userAgents) {
+			t.Errorf("Testing %v. Expected block-user-agents %v but got %v", n, tc.userAgents, cfg.BlockUserAgents)
+		}
+		if !reflect.DeepEqual(cfg.BlockReferers, tc.referers) {
+			t.Errorf("Testing %v. Expected block-referers %v but got %v", n, tc.referers, cfg.BlockReferers)
+		}
+	}
+}
+
+func TestGlobalExternalAuthParsing(t *testing.T) {
+	authURL := "https://auth.example.com/oauth2/auth"
+
+	testCases := map[string]struct {
+		input    map[string]string
+		expected authreq.Config
+	}{
+		"defaults": {map[string]string{}, authreq.Config{}},
+		"valid values": {map[string]string{
+			"global-auth-url":              authURL,
+			"global-auth-method":           "GET",
+			"global-auth-signin":           "https://auth.example.com/oauth2/start",
+			"global-auth-response-headers": "X-Auth-User, X-Auth-Email",
+			"global-auth-request-redirect": "https://app.example.com/",
+			"global-auth-snippet":          "proxy_set_header Foo-Header 42;",
+		}, authreq.Config{
+			URL:             authURL,
+			Host:            "auth.example.com",
+			Method:          "GET",
+			SigninURL:       "https://auth.example.com/oauth2/start",
+			ResponseHeaders: []string{"X-Auth-User", "X-Auth-Email"},
+			RequestRedirect: "https://app.example.com/",
+			AuthSnippet:     "proxy_set_header Foo-Header 42;",
+		}},
+		"invalid url": {map[string]string{
+			"global-auth-url":    "auth.example.com/oauth2/auth",
+			"global-auth-method": "GET",
+		}, authreq.Config{}},
+		"invalid optional values": {map[string]string{
+			"global-auth-url":              authURL,
+			"global-auth-method":           "FOO",
+			"global-auth-signin":           "/oauth2/start",
+			"global-auth-response-headers": "X-Auth-User,X-Auth Email",
+			"global-auth-request-redirect": "https://app.example.com/; return 200",
+		}, authreq.Config{
+			URL:             authURL,
+			Host:            "auth.example.com",
+			ResponseHeaders: []string{"X-Auth-User"},
+		}},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if !reflect.DeepEqual(cfg.GlobalExternalAuth, tc.expected) {
+			t.Errorf("Testing %v. Expected global external authentication %+v but got %+v", n, tc.expected, cfg.GlobalExternalAuth)
+		}
+	}
+}
+
+func TestNginxStatusParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  map[string]string
+		ipv4   []string
+		ipv6   []string
+		listen []string
+	}{
+		"defaults":       {map[string]string{}, []string{"127.0.0.1"}, []string{"::1"}, nil},
+		"valid values":   {map[string]string{"nginx-status-ipv4-whitelist": "127.0.0.1, 10.0.0.0/8", "nginx-status-ipv6-whitelist": "::1", "nginx-status-listen": "127.0.0.1:10246,[::1]:10246"}, []string{"127.0.0.1", "10.0.0.0/8"}, []string{"::1"}, []string{"127.0.0.1:10246", "[::1]:10246"}},
+		"invalid allow":  {map[string]string{"nginx-status-ipv4-whitelist": "127.0.0.1;return 200"}, []string{}, []string{"::1"}, nil},
+		"invalid host":   {map[string]string{"nginx-status-listen": "localhost:10246"}, []string{"127.0.0.1"}, []string{"::1"}, nil},
+		"invalid port":   {map[string]string{"nginx-status-listen": "127.0.0.1:0"}, []string{"127.0.0.1"}, []string{"::1"}, nil},
+		"missing port":   {map[string]string{"nginx-status-listen": "127.0.0.1"}, []string{"127.0.0.1"}, []string{"::1"}, nil},
+		"injected value": {map[string]string{"nginx-status-listen": "127.0.0.1:10246;return 200"}, []string{"127.0.0.1"}, []string{"::1"}, nil},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if !reflect.DeepEqual(cfg.NginxStatusIpv4Whitelist, tc.ipv4) {
+			t.Errorf("Testing %v. Expected IPv4 allowlist %v but got %v", n, tc.ipv4, cfg.NginxStatusIpv4Whitelist)
+		}
+		if !reflect.DeepEqual(cfg.NginxStatusIpv6Whitelist, tc.ipv6) {
+			t.Errorf("Testing %v. Expected IPv6 allowlist %v but got %v", n, tc.ipv6, cfg.NginxStatusIpv6Whitelist)
+		}
+		if !reflect.DeepEqual(cfg.NginxStatusListen, tc.listen) {
+			t.Errorf("Testing %v. Expected listen addresses %v but got %v", n, tc.listen, cfg.NginxStatusListen)
+		}
+	}
+}
+
+func TestMaxmindParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input    map[string]string
+		editions []string
+		refresh  time.Duration
+	}{
+		"defaults":            {map[string]string{}, []string{"GeoLite2-City", "GeoLite2-ASN"}, 24 * time.Hour},
+		"valid values":        {map[string]string{"maxmind-edition-ids": "GeoIP2-Country, GeoLite2-ASN", "maxmind-refresh-interval": "168h"}, []string{"GeoIP2-Country", "GeoLite2-ASN"}, 168 * time.Hour},
+		"no refresh":          {map[string]string{"maxmind-refresh-interval": "0"}, []string{"GeoLite2-City", "GeoLite2-ASN"}, 0},
+		"unsupported edition": {map[string]string{"maxmind-edition-ids": "GeoLite2-City,GeoIP2-Enterprise"}, []string{"GeoLite2-City", "GeoLite2-ASN"}, 24 * time.Hour},
+		"same kind":           {map[string]string{"maxmind-edition-ids": "GeoLite2-City,GeoIP2-City"}, []string{"GeoLite2-City", "GeoLite2-ASN"}, 24 * time.Hour},
+		"invalid refresh":     {map[string]string{"maxmind-refresh-interval": "-1h"}, []string{"GeoLite2-City", "GeoLite2-ASN"}, 24 * time.Hour},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(tc.input)
+		if !reflect.DeepEqual(cfg.MaxmindEditionIDs, tc.editions) {
+			t.Errorf("Testing %v. Expected editions %v but got %v", n, tc.editions, cfg.MaxmindEditionIDs)
+		}
+		if cfg.MaxmindRefreshInterval != tc.refresh {
+			t.Errorf("Testing %v. Expected refresh interval %v but got %v", n, tc.refresh, cfg.MaxmindRefreshInterval)
+		}
+	}
+}
+
+func TestLogFormatParsing(t *testing.T) {
+	def := config.NewDefault()
+
+	cfg := ReadConfig(map[string]string{
+		"log-format-upstream": `$remote_addr "$request" $status`,
+		"log-format-stream":   `$remote_addr $status`,
+	})
+	if cfg.LogFormatUpstream != `$remote_addr "$request" $status` {
+		t.Errorf("unexpected log-format-upstream %q", cfg.LogFormatUpstream)
+	}
+	if cfg.LogFormatStream != `$remote_addr $status` {
+		t.Errorf("unexpected log-format-stream %q", cfg.LogFormatStream)
+	}
+
+	cfg = ReadConfig(map[string]string{
+		"log-format-upstream": `$remote_addr $missing`,
+		"log-format-stream":   `$remote_addr $request`,
+	})
+	if cfg.LogFormatUpstream != `$remote_addr $missing` {
+		t.Errorf("expected log-format-upstream with an unknown variable to be kept but got %q", cfg.LogFormatUpstream)
+	}
+	if cfg.LogFormatStream != `$remote_addr $request` {
+		t.Errorf("expected log-format-stream with an unknown variable to be kept but got %q", cfg.LogFormatStream)
+	}
+
+	cfg = ReadConfig(map[string]string{
+		"log-format-upstream":    `$remote_addr'; include /etc/passwd; '`,
+		"log-format-stream":      `$remote_addr; include /etc/passwd`,
+		"log-format-escape-json": "true",
+		"log-format-escape-none": "true",
+	})
+	if cfg.LogFormatUpstream != def.LogFormatUpstream {
+		t.Errorf("expected the default log-format-upstream but got %q", cfg.LogFormatUpstream)
+	}
+	if cfg.LogFormatStream != def.LogFormatStream {
+		t.Errorf("expected the default log-format-stream but got %q", cfg.LogFormatStream)
+	}
+	if !cfg.LogFormatEscapeJSON || cfg.LogFormatEscapeNone {
+		t.Errorf("expected JSON escaping to take precedence")
+	}
+}
+
+func TestLuaSharedDictsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect map[string]int
+	}{
+		"megabytes":       {"configuration_data:100M, certificate_data:50", map[string]int{"configuration_data": 102400, "certificate_data": 51200}},
+		"kilobytes":       {"ocsp_response_data: 512k", map[string]int{"ocsp_response_data": 512}},
+		"new dictionary":  {"plugin_cache:10m", map[string]int{"plugin_cache": 10240}},
+		"invalid size":    {"configuration_data:100G", map[string]int{"configuration_data": 5120}},
+		"too small":       {"configuration_data:4k", map[string]int{"configuration_data": 5120}},
+		"invalid name":    {"configuration data:10M,:10M", map[string]int{"configuration_data": 5120}},
+		"missing size":    {"configuration_data", map[string]int{"configuration_data": 5120}},
+		"empty item list": {",,", map[string]int{"certificate_data": 16384}},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"lua-shared-dicts": tc.input})
+		for name, size := range tc.expect {
+			if cfg.LuaSharedDicts[name] != size {
+				t.Errorf("Testing %v. Expected size %v for %v but got %v", n, size, name, cfg.LuaSharedDicts[name])
+			}
+		}
+	}
+}
+
+func TestPluginsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect []string
+	}{
+		"single plugin":   {"billing", []string{"billing"}},
+		"many plugins":    {"billing, custom_auth", []string{"billing", "custom_auth"}},
+		"invalid plugins": {"billing,../../tmp/foo,bar.main", []string{"billing"}},
+		"empty list":      {"", []string{}},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"plugins": tc.input})
+		if !reflect.DeepEqual(cfg.Plugins, tc.expect) {
+			t.Errorf("Testing %v. Expected %v but got %v", n, tc.expect, cfg.Plugins)
+		}
+	}
+}
+
+func TestCustomHTTPErrorsParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect []int
+	}{
+		"single code":   {"404", []int{404}},
+		"many codes":    {"404, 503", []int{404, 503}},
+		"invalid codes": {"200,404,600,abc", []int{404}},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"custom-http-errors": tc.input})
+		if !reflect.DeepEqual(cfg.CustomHTTPErrors, tc.expect) {
+			t.Errorf("Testing %v. Expected %v but got %v", n, tc.expect, cfg.CustomHTTPErrors)
+		}
+	}
+}
+
 func TestMergeConfigMapToStruct(t *testing.T) {
 	conf := map[string]string{
 		"custom-http-errors":            "300,400,demo",
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// logFormatVariable matches the variables referenced in a log format,
+// written as $name or ${name}
+var logFormatVariable = regexp.MustCompile(`\$(?:\{([A-Za-z0-9_]*)(\}?)|([A-Za-z0-9_]*))`)
+
+// setVariable and mapVariable match the variables declared in a
+// configuration snippet, by the first argument of set like directives or
+// the last argument of map like blocks
+var (
+	setVariable = regexp.MustCompile(`(?:^|[\s;{}])(?:set|perl_set|js_set)\s+\$([A-Za-z0-9_]+)`)
+	mapVariable = regexp.MustCompile(`(?:^|[\s;{}])(?:map|geo|split_clients)\s[^;{]*\$([A-Za-z0-9_]+)\s*\{`)
+)
+
+// httpLogVariables contains the variables known to be available in the log
+// format of the http context. The list includes the variables of the NGINX
+// modules built in the controller image and the variables defined in the
+// configuration template. Other variables, like the ones declared in the
+// snippets of the annotations, are checked by NGINX before the reload.
+var httpLogVariables = sets.NewString(
+	// ngx_http_core_module
+	"args", "binary_remote_addr", "body_bytes_sent", "bytes_sent",
+	"connection", "connection_requests", "connection_time",
+	"content_length", "content_type", "cookie", "document_root",
+	"document_uri", "host", "hostname", "https", "is_args", "limit_rate",
+	"msec", "nginx_version", "pid", "pipe", "proxy_protocol_addr",
+	"proxy_protocol_port", "proxy_protocol_server_addr",
+	"proxy_protocol_server_port", "query_string", "realpath_root",
+	"remote_addr", "remote_port", "remote_user", "request",
+	"request_body", "request_body_file", "request_completion",
+	"request_filename", "request_id", "request_length", "request_method",
+	"request_time", "request_uri", "scheme", "server_addr", "server_name",
+	"server_port", "server_protocol", "status", "tcpinfo_rtt",
+	"tcpinfo_rttvar", "tcpinfo_snd_cwnd", "tcpinfo_rcv_space",
+	"time_iso8601", "time_local", "uri",
+	// ngx_http_upstream_module
+	"upstream_addr", "upstream_bytes_received", "upstream_bytes_sent",
+	"upstream_cache_status", "upstream_connect_time",
+	"upstream_header_time", "upstream_queue_time",
+	"upstream_response_length", "upstream_response_time",
+	"upstream_status",
+	// ngx_http_proxy_module, ngx_http_realip_module and others
+	"proxy_add_x_forwarded_for", "proxy_host", "proxy_port",
+	"realip_remote_addr", "realip_remote_port", "gzip_ratio",
+	"limit_conn_status", "limit_req_status", "http2", "http3",
+	"invalid_referer", "connections_active", "connections_reading",
+	"connections_writing", "connections_waiting", "date_local",
+	"date_gmt", "fastcgi_script_name", "fastcgi_path_info",
+	// nginx.tmpl
+	"auth_cookie", "best_http_host", "full_x_forwarded_for",
+	"ingress_name", "location_path", "namespace", "pass_access_scheme",
+	"pass_port", "pass_server_port", "proxy_alternative_upstream_name",
+	"proxy_upstream_name", "redirect_to_https", "req_id", "service_name",
+	"service_port", "the_real_ip", "this_host", "cors", "cors_origin",
+	"cors_vary", "global_rate_limit_exceeded",
+	"global_rate_limit_key", "connection_upgrade", "loggable",
+	"connection_upgrade_keepalive", "proxy_connection_header",
+	"cache_key", "tmp_cache_key", "target", "block_ua", "block_ref",
+	"block_cidr", "websocket_read_timeout", "websocket_send_timeout",
+	"websocket_connect_timeout",
+)
+
+// httpLogVariablePrefixes contains the prefixes of the variables created
+// from the name of headers, cookies or arguments, or by modules with
+// variables depending on the configuration.
+var httpLogVariablePrefixes = []string{
+	"arg_", "cookie_", "http_", "sent_http_", "sent_trailer_",
+	"upstream_cookie_", "upstream_http_", "upstream_trailer_",
+	"ssl_", "geoip_", "geoip2_", "opentracing_context_",
+}
+
+// streamLogVariables contains the variables that can be used in the log
+// format of the stream context.
+var streamLogVariables = sets.NewString(
+	// ngx_stream_core_module
+	"binary_remote_addr", "bytes_received", "bytes_sent", "connection",
+	"hostname", "msec", "nginx_version", "pid", "protocol",
+	"proxy_protocol_addr", "proxy_protocol_port",
+	"proxy_protocol_server_addr", "proxy_protocol_server_port",
+	"remote_addr", "remote_port", "server_addr", "server_port",
+	"session_time", "status", "time_iso8601", "time_local",
+	// ngx_stream_upstream_module
+	"upstream_addr", "upstream_bytes_received", "upstream_bytes_sent",
+	"upstream_connect_time", "upstream_first_byte_time",
+	"upstream_session_time",
+)
+
+// streamLogVariablePrefixes contains the prefixes of the variables of the
+// stream context defined by modules.
+var streamLogVariablePrefixes = []string{"ssl_"}
+
+// snippetVariables returns the variables declared in configuration
+// snippets, which can be used in the log formats like the variables of
+// the template.
+func snippetVariables(snippets ...string) sets.String {
+	variables := sets.NewString()
+	for _, snippet := range snippets {
+		for _, re := range []*regexp.Regexp{setVariable, mapVariable} {
+			for _, m := range re.FindAllStringSubmatch(snippet, -1) {
+				variables.Insert(m[1])
+			}
+		}
+	}
+
+	return variables
+}
+
+// checkLogFormatVariables returns an error if the log format contains an
+// invalid variable reference, and the variables that are not in the list
+// of known variables and do not start with one of the prefixes. Unknown
+// variables are not an error: NGINX rejects the configuration before the
+// reload if they are not declared anywhere.
+func checkLogFormatVariables(format string, variables sets.String, prefixes []string) ([]string, error) {
+	unknown := []string{}
+	for _, m := range logFormatVariable.FindAllStringSubmatch(format, -1) {
+		name := m[1] + m[3]
+		if name == "" || (strings.HasPrefix(m[0], "${") && m[2] == "") {
+			return nil, fmt.Errorf("invalid variable %q", m[0])
+		}
+
+		if variables.Has(name) || hasAnyPrefix(name, prefixes) {
+			continue
+		}
+
+		unknown = append(unknown, "$"+name)
+	}
+
+	return unknown, nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) && len(s) > len(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkLogFormatUpstream validates the format of the upstreaminfo log,
+// rendered between single quotes in the configuration. The variables
+// declared in the snippets of the http context are accepted.
+func checkLogFormatUpstream(format string, snippets ...string) ([]string, error) {
+	if strings.ContainsAny(format, "'\r\n") {
+		return nil, fmt.Errorf("the format cannot contain single quotes or line breaks")
+	}
+
+	return checkLogFormatVariables(format, httpLogVariables.Union(snippetVariables(snippets...)), httpLogVariablePrefixes)
+}
+
+// checkLogFormatStream validates the format of the stream log, rendered
+// without quotes in the configuration. The variables declared in the
+// snippets of the stream context are accepted.
+func checkLogFormatStream(format string, snippets ...string) ([]string, error) {
+	if strings.ContainsAny(format, ";{}\r\n") {
+		return nil, fmt.Errorf("the format cannot contain ';', '{', '}' or line breaks")
+	}
+
+	return checkLogFormatVariables(format, streamLogVariables.Union(snippetVariables(snippets...)), streamLogVariablePrefixes)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckLogFormatUpstream(t *testing.T) {
+	testCases := []struct {
+		format  string
+		valid   bool
+		unknown []string
+	}{
+		{`$remote_addr - $remote_user [$time_local] "$request" $status`, true, nil},
+		{`{"time": "$time_iso8601", "host": "$host", "upstream": "$proxy_upstream_name"}`, true, nil},
+		{`$http_x_forwarded_for $sent_http_content_type $cookie_session $arg_page`, true, nil},
+		{`${request_time}s $upstream_response_time`, true, nil},
+		{`$geoip2_city_country_code $ssl_protocol`, true, nil},
+		{`$remote_addr $unknown_variable`, true, []string{"$unknown_variable"}},
+		{`$remote_addr $http_`, true, []string{"$http_"}},
+		{`$ $remote_addr`, false, nil},
+		{`${remote_addr`, false, nil},
+		{`$remote_addr'; include /etc/passwd; '`, false, nil},
+		{"$remote_addr\n$status", false, nil},
+		{`$remote_addr $cors_origin $global_rate_limit_exceeded`, true, nil},
+		{`$proxy_connection_header $cache_key $block_ua $block_ref $websocket_read_timeout`, true, nil},
+		{`$date_local $date_gmt $fastcgi_script_name`, true, nil},
+	}
+
+	for _, tc := range testCases {
+		unknown, err := checkLogFormatUpstream(tc.format)
+		if tc.valid && err != nil {
+			t.Errorf("expected %q to be valid but returned %v", tc.format, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected %q to be invalid", tc.format)
+		}
+		if len(unknown) != len(tc.unknown) || (len(unknown) > 0 && !reflect.DeepEqual(unknown, tc.unknown)) {
+			t.Errorf("expected the unknown variables %v in %q but returned %v", tc.unknown, tc.format, unknown)
+		}
+	}
+}
+
+func TestCheckLogFormatUpstreamSnippets(t *testing.T) {
+	snippet := `
+map $http_user_agent $is_bot {
+    default 0;
+    ~*bot   1;
+}
+geo $remote_addr $office { default 0; 10.0.0.0/8 1; }
+set $tenant $http_x_tenant;`
+
+	for _, variable := range []string{"is_bot", "office", "tenant"} {
+		unknown, err := checkLogFormatUpstream("$remote_addr $"+variable, snippet)
+		if err != nil || len(unknown) > 0 {
+			t.Errorf("expected the variable %v declared in the snippet to be known but returned %v, %v", variable, unknown, err)
+		}
+	}
+
+	if unknown, _ := checkLogFormatUpstream("$remote_addr $is_bot"); len(unknown) != 1 {
+		t.Errorf("expected the variable $is_bot to be unknown without the snippet")
+	}
+}
+
+func TestCheckLogFormatStream(t *testing.T) {
+	testCases := []struct {
+		format string
+		valid  bool
+	}{
+		{`[$time_local] $protocol $status $bytes_sent $bytes_received $session_time`, true},
+		{`$remote_addr $upstream_addr $ssl_preread_server_name`, true},
+		{`$remote_addr; include /etc/passwd`, false},
+		{`$remote_addr {`, false},
+	}
+
+	if unknown, err := checkLogFormatStream(`$remote_addr $request_uri`); err != nil || len(unknown) != 1 {
+		t.Errorf("expected $request_uri to be unknown in the stream log format but returned %v, %v", unknown, err)
+	}
+
+	for _, tc := range testCases {
+		_, err := checkLogFormatStream(tc.format)
+		if tc.valid && err != nil {
+			t.Errorf("expected %q to be valid but returned %v", tc.format, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected %q to be invalid", tc.format)
+		}
+	}
+}
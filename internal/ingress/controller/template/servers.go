@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"runtime"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/klog"
+)
+
+// renderedServer contains a server rendered with the SERVER template and the
+// checksum of the data used to render it
+type renderedServer struct {
+	checksum [sha256.Size]byte
+	content  string
+}
+
+// serverBlock returns a server rendered with the SERVER template
+func serverBlock(all config.TemplateConfig, server *ingress.Server) string {
+	block, ok := all.ServerBlocks[server]
+	if !ok {
+		klog.Errorf("server %v was not rendered", server.Hostname)
+	}
+
+	return block
+}
+
+// renderServerBlocks renders the servers of the configuration with the
+// SERVER template in parallel. The servers which data did not change since
+// the previous call are not rendered again.
+func (t *Template) renderServerBlocks(conf *config.TemplateConfig) error {
+	global, err := serverGlobalData(*conf)
+	if err != nil {
+		return err
+	}
+
+	passthrough := sets.NewString()
+	for _, backend := range conf.Backends {
+		if backend.SSLPassthrough {
+			passthrough.Insert(backend.Name)
+		}
+	}
+
+	t.serversLock.Lock()
+	defer t.serversLock.Unlock()
+
+	servers := make([]renderedServer, len(conf.Servers))
+	errs := make([]error, len(conf.Servers))
+
+	workers := runtime.NumCPU()
+	if workers > len(conf.Servers) {
+		workers = len(conf.Servers)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				servers[idx], errs[idx] = t.renderServer(*conf, global, passthrough, conf.Servers[idx])
+			}
+		}()
+	}
+
+	for idx := range conf.Servers {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	rendered := make(map[string]renderedServer, len(conf.Servers))
+	conf.ServerBlocks = make(map[*ingress.Server]string, len(conf.Servers))
+	for idx, server := range conf.Servers {
+		if errs[idx] != nil {
+			return errs[idx]
+		}
+
+		rendered[server.Hostname] = servers[idx]
+		conf.ServerBlocks[server] = servers[idx].content
+	}
+
+	// only the servers of the last configuration are kept
+	t.servers = rendered
+
+	return nil
+}
+
+// renderServer renders a server with the SERVER template, unless the server
+// rendered by the previous call used the same data
+func (t *Template) renderServer(conf config.TemplateConfig, global []byte, passthrough sets.String, server *ingress.Server) (renderedServer, error) {
+	data, err := json.Marshal(server)
+	if err != nil {
+		return renderedServer{}, err
+	}
+
+	hasher := sha256.New()
+	hasher.Write(global)
+	hasher.Write(data)
+	// the proxy_pass of the locations depends on the SSL Passthrough flag of the backend
+	for _, location := range server.Locations {
+		if passthrough.Has(location.Backend) {
+			hasher.Write([]byte(location.Backend))
+		}
+	}
+
+	var checksum [sha256.Size]byte
+	copy(checksum[:], hasher.Sum(nil))
+
+	if previous, ok := t.servers[server.Hostname]; ok && previous.checksum == checksum {
+		return previous, nil
+	}
+
+	buf := t.bp.Get()
+	defer t.bp.Put(buf)
+
+	err = t.tmpl.ExecuteTemplate(buf, "SERVER", struct{ First, Second interface{} }{conf, server})
+	if err != nil {
+		return renderedServer{}, err
+	}
+
+	return renderedServer{
+		checksum: checksum,
+		content:  buf.String(),
+	}, nil
+}
+
+// serverGlobalData returns the data of the configuration available to the
+// SERVER template, without the fields only used outside of it. These fields
+// change often and would require rendering every server again.
+func serverGlobalData(conf config.TemplateConfig) ([]byte, error) {
+	conf.Servers = nil
+	conf.RedirectServers = nil
+	conf.Backends = nil
+	conf.PassthroughBackends = nil
+	conf.TCPBackends = nil
+	conf.UDPBackends = nil
+	conf.PublishService = nil
+
+	// the checksum of the configuration (Cfg.Checksum) is not marshaled
+	return json.Marshal(conf)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func newServersTemplateConfig(servers int) config.TemplateConfig {
+	conf := config.TemplateConfig{
+		Cfg:         config.NewDefault(),
+		ListenPorts: &config.ListenPorts{HTTP: 80, HTTPS: 443},
+	}
+
+	for i := 0; i < servers; i++ {
+		backend := fmt.Sprintf("default-app%v-80", i)
+		conf.Backends = append(conf.Backends, &ingress.Backend{
+			Name:      backend,
+			Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "8080"}},
+		})
+		conf.Servers = append(conf.Servers, &ingress.Server{
+			Hostname: fmt.Sprintf("app%v.example.com", i),
+			Locations: []*ingress.Location{{
+				Path:    "/",
+				Backend: backend,
+			}},
+		})
+	}
+
+	return conf
+}
+
+func newServersTemplate(t testing.TB) *Template {
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	return ngxTpl
+}
+
+func TestRenderServerBlocks(t *testing.T) {
+	ngxTpl := newServersTemplate(t)
+	if !ngxTpl.renderServers {
+		t.Fatalf("expected the servers to be rendered with the serverBlock function")
+	}
+
+	conf := newServersTemplateConfig(10)
+	if _, err := ngxTpl.Write(conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := ngxTpl.servers
+	if len(previous) != 10 {
+		t.Fatalf("expected 10 rendered servers but %v returned", len(previous))
+	}
+
+	// change a server, the SSL Passthrough flag of the backend of another one
+	// and the endpoints of the rest
+	conf = newServersTemplateConfig(10)
+	conf.Servers[0].Locations[0].Path = "/app"
+	conf.Backends[1].SSLPassthrough = true
+	for _, backend := range conf.Backends[2:] {
+		backend.Endpoints = []ingress.Endpoint{{Address: "10.0.0.2", Port: "8080"}}
+	}
+
+	content, err := ngxTpl.Write(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content = append([]byte{}, content...)
+
+	for i, server := range conf.Servers {
+		changed := ngxTpl.servers[server.Hostname].checksum != previous[server.Hostname].checksum
+		if changed != (i < 2) {
+			t.Errorf("unexpected rendering of server %v (changed: %v)", server.Hostname, changed)
+		}
+	}
+
+	expected, err := newServersTemplate(t).Write(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(content) != string(expected) {
+		t.Errorf("expected the configuration rendered with the cached servers to be equal to the one rendered without them")
+	}
+
+	conf = newServersTemplateConfig(5)
+	if _, err := ngxTpl.Write(conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ngxTpl.servers) != 5 {
+		t.Errorf("expected the servers removed from the configuration to be removed from the cache")
+	}
+}
+
+func BenchmarkTemplateServers(b *testing.B) {
+	conf := newServersTemplateConfig(1000)
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			ngxTpl := newServersTemplate(b)
+			b.StartTimer()
+
+			if _, err := ngxTpl.Write(conf); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		ngxTpl := newServersTemplate(b)
+		if _, err := ngxTpl.Write(conf); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			conf.Servers[i%len(conf.Servers)].Locations[0].Path = fmt.Sprintf("/%v", i)
+			if _, err := ngxTpl.Write(conf); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
 	"net/url"
@@ -30,6 +31,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	text_template "text/template"
 	"time"
 
@@ -39,7 +41,10 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamkeepalive"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/klog"
@@ -56,6 +61,14 @@ type Template struct {
 	tmpl *text_template.Template
 	//fw   watch.FileWatcher
 	bp *BufferPool
+
+	// renderServers is true when the template renders the servers
+	// with the serverBlock function instead of the SERVER template
+	renderServers bool
+
+	serversLock sync.Mutex
+	// servers contains the server blocks rendered by the last Write
+	servers map[string]renderedServer
 }
 
 //NewTemplate returns a new Template instance or an
@@ -72,8 +85,10 @@ func NewTemplate(file string, fs file.Filesystem) (*Template, error) {
 	}
 
 	return &Template{
-		tmpl: tmpl,
-		bp:   NewBufferPool(defBufferSize),
+		tmpl:          tmpl,
+		bp:            NewBufferPool(defBufferSize),
+		renderServers: tmpl.Lookup("SERVER") != nil && strings.Contains(string(data), "serverBlock"),
+		servers:       map[string]renderedServer{},
 	}, nil
 }
 
@@ -94,6 +109,13 @@ func (t *Template) Write(conf config.TemplateConfig) ([]byte, error) {
 		klog.Infof("NGINX configuration: %v", string(b))
 	}
 
+	if t.renderServers {
+		err := t.renderServerBlocks(&conf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err := t.tmpl.Execute(tmplBuf, conf)
 	if err != nil {
 		return nil, err
@@ -126,6 +148,9 @@ var (
 		"buildLuaSharedDictionaries": buildLuaSharedDictionaries,
 		"buildLocation":              buildLocation,
 		"buildAuthLocation":          buildAuthLocation,
+		"buildBodySizeErrorLocation": buildBodySizeErrorLocation,
+		"buildNginxString":           buildNginxString,
+		"buildMapKey":                buildMapKey,
 		"buildAuthResponseHeaders":   buildAuthResponseHeaders,
 		"buildProxyPass":             buildProxyPass,
 		"filterRateLimits":           filterRateLimits,
@@ -133,9 +158,18 @@ var (
 		"buildRateLimit":             buildRateLimit,
 		"buildResolversForLua":       buildResolversForLua,
 		"buildResolvers":             buildResolvers,
+		"shouldConfigureOIDC":        shouldConfigureOIDC,
+		"shouldConfigureJWT":         shouldConfigureJWT,
+		"shouldApplyGlobalRateLimit": shouldApplyGlobalRateLimit,
+		"buildPlugins":               buildPlugins,
+		"buildLocationPlugins":       buildLocationPlugins,
+		"buildLuaString":             buildLuaString,
 		"buildUpstreamName":          buildUpstreamName,
 		"isLocationInLocationList":   isLocationInLocationList,
 		"isLocationAllowed":          isLocationAllowed,
+		"isSSLRedirectExcluded":      isSSLRedirectExcluded,
+		"buildSSLRedirectCode":       buildSSLRedirectCode,
+		"shouldApplyGlobalAuth":      shouldApplyGlobalAuth,
 		"buildLogFormatUpstream":     buildLogFormatUpstream,
 		"buildDenyVariable":          buildDenyVariable,
 		"getenv":                     os.Getenv,
@@ -151,6 +185,7 @@ var (
 		"serverConfig": func(all config.TemplateConfig, server *ingress.Server) interface{} {
 			return struct{ First, Second interface{} }{all, server}
 		},
+		"serverBlock":                        serverBlock,
 		"isValidByteSize":                    isValidByteSize,
 		"buildForwardedFor":                  buildForwardedFor,
 		"buildAuthSignURL":                   buildAuthSignURL,
@@ -162,6 +197,18 @@ var (
 		"buildCustomErrorDeps":               buildCustomErrorDeps,
 		"opentracingPropagateContext":        opentracingPropagateContext,
 		"buildCustomErrorLocationsPerServer": buildCustomErrorLocationsPerServer,
+		"buildMirrorLocations":               buildMirrorLocations,
+		"enableGRPCWeb":                      enableGRPCWeb,
+		"shouldConfigureACMEChallenge":       shouldConfigureACMEChallenge,
+		"buildCorsOriginRegex":               buildCorsOriginRegex,
+		"corsReflectsOrigin":                 corsReflectsOrigin,
+		"buildProxySSL":                      buildProxySSL,
+		"filterUpstreamKeepalives":           filterUpstreamKeepalives,
+		"mergeHeaders":                       mergeHeaders,
+		"geoIP2Variables":                    geoIP2Variables,
+		"luaSharedDictSize":                  luaSharedDictSize,
+		"shouldLoadOpentracingModule":        shouldLoadOpentracingModule,
+		"buildOpentracingForLocation":        buildOpentracingForLocation,
 	}
 )
 
@@ -201,19 +248,71 @@ func shouldConfigureLuaRestyWAF(disableLuaRestyWAF bool, mode string) bool {
 	return false
 }
 
-func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
+// luaSharedDictsNotInHTTP contains the Lua shared dictionaries that are
+// not always defined in the http block
+var luaSharedDictsNotInHTTP = sets.NewString("discovery", "jwks", "waf_storage", "tcp_udp_configuration_data")
+
+// luaSharedDictSize returns the size of a Lua shared dictionary in the
+// format of the lua_shared_dict directive
+func luaSharedDictSize(c interface{}, name string) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	size := cfg.LuaSharedDictSize(name)
+	if size%1024 == 0 {
+		return fmt.Sprintf("%dM", size/1024)
+	}
+
+	return fmt.Sprintf("%dk", size)
+}
+
+func buildLuaSharedDictionaries(c interface{}, s interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
 	servers, ok := s.([]*ingress.Server)
 	if !ok {
 		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
 		return ""
 	}
 
+	dict := func(name string) string {
+		return fmt.Sprintf("lua_shared_dict %v %v", name, luaSharedDictSize(cfg, name))
+	}
+
 	out := []string{
-		"lua_shared_dict configuration_data 5M",
-		"lua_shared_dict certificate_data 16M",
+		dict("configuration_data"),
+		dict("certificate_data"),
+		dict("ocsp_response_data"),
 	}
 
-	if !disableLuaRestyWAF {
+	// dictionaries defined in the configuration ConfigMap, used by plugins
+	custom := sets.NewString()
+	for name := range cfg.LuaSharedDicts {
+		if !luaSharedDictsNotInHTTP.Has(name) {
+			custom.Insert(name)
+		}
+	}
+	custom.Delete("configuration_data", "certificate_data", "ocsp_response_data")
+	for _, name := range custom.List() {
+		out = append(out, dict(name))
+	}
+
+	configureOIDC := shouldConfigureOIDC(servers)
+	if configureOIDC {
+		out = append(out, dict("discovery"))
+	}
+	if configureOIDC || shouldConfigureJWT(servers) {
+		out = append(out, dict("jwks"))
+	}
+
+	if !cfg.DisableLuaRestyWAF {
 		luaRestyWAFEnabled := func() bool {
 			for _, server := range servers {
 				for _, location := range server.Locations {
@@ -225,13 +324,155 @@ func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
 			return false
 		}()
 		if luaRestyWAFEnabled {
-			out = append(out, "lua_shared_dict waf_storage 64M")
+			out = append(out, dict("waf_storage"))
 		}
 	}
 
 	return strings.Join(out, ";\n\r") + ";"
 }
 
+// shouldConfigureOIDC returns true if a location uses OpenID Connect authentication
+func shouldConfigureOIDC(s interface{}) bool {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.OIDC.DiscoveryURL != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// shouldConfigureJWT returns true if a location validates JSON Web Tokens
+func shouldConfigureJWT(s interface{}) bool {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.JWT.JWKSURL != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// shouldApplyGlobalRateLimit returns true if the location defines a global
+// rate limit and a shared store is configured to enforce it
+func shouldApplyGlobalRateLimit(c interface{}, l interface{}) bool {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return false
+	}
+
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return false
+	}
+
+	return cfg.GlobalRateLimitBackend != "" && location.GlobalRateLimit.Limit > 0
+}
+
+// buildPlugins returns the names of the Lua plugins enabled globally
+// or in at least one location. Every plugin is loaded once when NGINX starts
+func buildPlugins(c interface{}, s interface{}) []string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return []string{}
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return []string{}
+	}
+
+	seen := sets.NewString()
+	plugins := appendPlugins([]string{}, seen, cfg.Plugins)
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			plugins = appendPlugins(plugins, seen, location.Plugins)
+		}
+	}
+
+	return plugins
+}
+
+// buildLocationPlugins returns the names of the Lua plugins that run in
+// a location, the ones enabled globally first and then the ones enabled
+// using the plugins annotation
+func buildLocationPlugins(c interface{}, l interface{}) []string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return []string{}
+	}
+
+	location, ok := l.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return []string{}
+	}
+
+	seen := sets.NewString()
+	plugins := appendPlugins([]string{}, seen, cfg.Plugins)
+	return appendPlugins(plugins, seen, location.Plugins)
+}
+
+// appendPlugins appends the names not seen before keeping the order,
+// the order of the list is the order in which the plugins run
+func appendPlugins(plugins []string, seen sets.String, names []string) []string {
+	for _, name := range names {
+		if seen.Has(name) {
+			continue
+		}
+		seen.Insert(name)
+		plugins = append(plugins, name)
+	}
+
+	return plugins
+}
+
+// buildLuaString returns the input as a double quoted Lua string literal.
+// Quotes, backslashes, braces and non printable characters are escaped
+// using decimal escape sequences, so the value cannot break the Lua block
+func buildLuaString(input interface{}) string {
+	str, ok := input.(string)
+	if !ok {
+		klog.Errorf("expected a 'string' type but %T was returned", input)
+		return `""`
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c == '"' || c == '\\' || c == '{' || c == '}' || c < ' ' || c > '~' {
+			fmt.Fprintf(&buf, "\\%03d", c)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
 func buildResolversForLua(res interface{}, disableIpv6 interface{}) string {
 	nss, ok := res.([]net.IP)
 	if !ok {
@@ -317,6 +558,12 @@ func enforceRegexModifier(input interface{}) bool {
 		return false
 	}
 
+	return EnforceRegex(locations)
+}
+
+// EnforceRegex returns true if the paths of all the locations of a server
+// are rendered as regular expressions
+func EnforceRegex(locations []*ingress.Location) bool {
 	for _, location := range locations {
 		if needsRewrite(location) || location.Rewrite.UseRegex {
 			return true
@@ -341,14 +588,14 @@ func buildLocation(input interface{}, enforceRegex bool) string {
 	return path
 }
 
-func buildAuthLocation(input interface{}) string {
+func buildAuthLocation(input interface{}, globalExternalAuthURL string) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
 		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return ""
 	}
 
-	if location.ExternalAuth.URL == "" {
+	if location.ExternalAuth.URL == "" && !shouldApplyGlobalAuth(location, globalExternalAuthURL) {
 		return ""
 	}
 
@@ -358,19 +605,73 @@ func buildAuthLocation(input interface{}) string {
 	return fmt.Sprintf("/_external-auth-%v", str)
 }
 
-func buildAuthResponseHeaders(input interface{}) []string {
+// buildBodySizeErrorLocation returns the name of the location returning
+// the custom response to the requests with a body too large
+func buildBodySizeErrorLocation(input interface{}) string {
 	location, ok := input.(*ingress.Location)
-	res := []string{}
 	if !ok {
 		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
-		return res
+		return ""
+	}
+
+	if location.BodySizeError.ErrorBody == "" {
+		return ""
+	}
+
+	str := base64.URLEncoding.EncodeToString([]byte(location.Path))
+	// removes "=" after encoding
+	str = strings.Replace(str, "=", "", -1)
+	return fmt.Sprintf("@body-size-error-%v", str)
+}
+
+// buildNginxString returns the input as a double quoted NGINX string.
+// Quotes and backslashes are escaped and the $ character is replaced
+// with ${literal_dollar} to avoid the evaluation of variables
+func buildNginxString(input interface{}) string {
+	str, ok := input.(string)
+	if !ok {
+		klog.Errorf("expected a 'string' type but %T was returned", input)
+		return `""`
+	}
+
+	str = strings.Replace(str, `\`, `\\`, -1)
+	str = strings.Replace(str, `"`, `\"`, -1)
+
+	return fmt.Sprintf(`"%v"`, escapeLiteralDollar(str))
+}
+
+// buildMapKey returns a quoted source value of a map. Unlike buildNginxString
+// the dollar signs are kept, because the source values cannot contain
+// variables and regular expressions use them as anchors.
+func buildMapKey(input interface{}) string {
+	str, ok := input.(string)
+	if !ok {
+		klog.Errorf("expected a 'string' type but %T was returned", input)
+		return `""`
 	}
 
-	if len(location.ExternalAuth.ResponseHeaders) == 0 {
-		return res
+	str = strings.Replace(str, `\`, `\\`, -1)
+	str = strings.Replace(str, `"`, `\"`, -1)
+
+	return fmt.Sprintf(`"%v"`, str)
+}
+
+// shouldApplyGlobalAuth returns true when the location uses the global
+// external authentication: it is configured, enabled in the location and
+// the location does not define its own authentication service
+func shouldApplyGlobalAuth(input interface{}, globalExternalAuthURL string) bool {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return false
 	}
 
-	for i, h := range location.ExternalAuth.ResponseHeaders {
+	return location.ExternalAuth.URL == "" && globalExternalAuthURL != "" && location.EnableGlobalAuth
+}
+
+func buildAuthResponseHeaders(headers []string) []string {
+	res := []string{}
+	for i, h := range headers {
 		hvar := strings.ToLower(h)
 		hvar = strings.NewReplacer("-", "_").Replace(hvar)
 		res = append(res, fmt.Sprintf("auth_request_set $authHeader%v $upstream_http_%v;", i, hvar))
@@ -423,9 +724,12 @@ func buildProxyPass(host string, b interface{}, loc interface{}) string {
 	case "AJP":
 		proto = ""
 		proxyPass = "ajp_pass"
+	case "FCGI":
+		proto = ""
+		proxyPass = "fastcgi_pass"
 	}
 
-	upstreamName := "upstream_balancer"
+	upstreamName := location.UpstreamKeepalive.UpstreamName()
 
 	for _, backend := range backends {
 		if backend.Name == location.Backend {
@@ -452,8 +756,12 @@ func buildProxyPass(host string, b interface{}, loc interface{}) string {
 	if len(location.Rewrite.Target) > 0 {
 		var xForwardedPrefix string
 
-		if location.XForwardedPrefix {
-			xForwardedPrefix = fmt.Sprintf("proxy_set_header X-Forwarded-Prefix \"%s\";\n", path)
+		if location.XForwardedPrefix.Enabled {
+			prefix := location.XForwardedPrefix.Prefix
+			if prefix == "" {
+				prefix = forwardedPrefix(path)
+			}
+			xForwardedPrefix = fmt.Sprintf("proxy_set_header X-Forwarded-Prefix \"%s\";\n", prefix)
 		}
 
 		return fmt.Sprintf(`
@@ -465,6 +773,29 @@ rewrite "(?i)%s" %s break;
 	return defProxyPass
 }
 
+// forwardedPrefix returns the prefix of the path of a location removed
+// by a rewrite, which is the path before the first regular expression
+func forwardedPrefix(path string) string {
+	prefix := path
+	for i, c := range path {
+		if strings.ContainsRune(`()[]{}*+?|^$\`, c) || (c == '.' && i+1 < len(path) && strings.ContainsRune("*+?", rune(path[i+1]))) {
+			prefix = path[:i]
+			// the quantifiers apply to the previous character
+			if strings.ContainsRune("*+?{", c) && len(prefix) > 0 {
+				prefix = prefix[:len(prefix)-1]
+			}
+			break
+		}
+	}
+
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return "/"
+	}
+
+	return prefix
+}
+
 // TODO: Needs Unit Tests
 func filterRateLimits(input interface{}) []ratelimit.Config {
 	ratelimits := []ratelimit.Config{}
@@ -486,6 +817,67 @@ func filterRateLimits(input interface{}) []ratelimit.Config {
 	return ratelimits
 }
 
+// geoIP2Variable is a variable defined by a GeoIP2 database
+type geoIP2Variable struct {
+	Name string
+	Path string
+}
+
+// geoIP2Variables returns the variables defined by the database of a GeoIP2 edition
+func geoIP2Variables(edition string) []geoIP2Variable {
+	return maxmindVariables[maxmindEditions[edition]]
+}
+
+// mergeHeaders returns the headers added to the responses of a location,
+// the global ones and the ones of the Ingress. The headers of the Ingress
+// replace the global headers with the same name, compared case-insensitively
+func mergeHeaders(global, local map[string]string) map[string]string {
+	headers := make(map[string]string, len(global)+len(local))
+	for name, value := range global {
+		headers[name] = value
+	}
+
+	for name, value := range local {
+		for g := range global {
+			if strings.EqualFold(g, name) {
+				delete(headers, g)
+			}
+		}
+
+		headers[name] = value
+	}
+
+	return headers
+}
+
+// filterUpstreamKeepalives returns the distinct keepalive configurations
+// of the locations that require a dedicated upstream block
+func filterUpstreamKeepalives(input interface{}) []upstreamkeepalive.Config {
+	keepalives := []upstreamkeepalive.Config{}
+	found := sets.String{}
+
+	servers, ok := input.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		return keepalives
+	}
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			name := loc.UpstreamKeepalive.UpstreamName()
+			if loc.UpstreamKeepalive.Override && !found.Has(name) {
+				found.Insert(name)
+				keepalives = append(keepalives, loc.UpstreamKeepalive)
+			}
+		}
+	}
+
+	sort.Slice(keepalives, func(i, j int) bool {
+		return keepalives[i].UpstreamName() < keepalives[j].UpstreamName()
+	})
+
+	return keepalives
+}
+
 // TODO: Needs Unit Tests
 // buildRateLimitZones produces an array of limit_conn_zone in order to allow
 // rate limiting of request. Each Ingress rule could have up to three zones, one
@@ -503,7 +895,13 @@ func buildRateLimitZones(input interface{}) []string {
 	for _, server := range servers {
 		for _, loc := range server.Locations {
 			if loc.RateLimit.Connections.Limit > 0 {
-				zone := fmt.Sprintf("limit_conn_zone $limit_%s zone=%v:%vm;",
+				variable := "limit"
+				if loc.RateLimit.Connections.Key != "" {
+					variable = "limit_conn"
+				}
+
+				zone := fmt.Sprintf("limit_conn_zone $%v_%s zone=%v:%vm;",
+					variable,
 					loc.RateLimit.ID,
 					loc.RateLimit.Connections.Name,
 					loc.RateLimit.Connections.SharedSize)
@@ -605,6 +1003,40 @@ func isLocationInLocationList(location interface{}, rawLocationList string) bool
 	return false
 }
 
+// isSSLRedirectExcluded checks if the path of the location is excluded
+// from the redirect to HTTPS with the no-ssl-redirect-paths annotation
+func isSSLRedirectExcluded(input interface{}) bool {
+	loc, ok := input.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return false
+	}
+
+	for _, path := range loc.Rewrite.NoSSLRedirectPaths {
+		if path == loc.Path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildSSLRedirectCode returns the HTTP status code of the redirect to
+// HTTPS of a location, falling back to the global http-redirect-code
+func buildSSLRedirectCode(defaultCode int, input interface{}) int {
+	loc, ok := input.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return defaultCode
+	}
+
+	if loc.Rewrite.SSLRedirectCode != 0 {
+		return loc.Rewrite.SSLRedirectCode
+	}
+
+	return defaultCode
+}
+
 func isLocationAllowed(input interface{}) bool {
 	loc, ok := input.(*ingress.Location)
 	if !ok {
@@ -616,7 +1048,8 @@ func isLocationAllowed(input interface{}) bool {
 }
 
 var (
-	denyPathSlugMap = map[string]string{}
+	denyPathSlugMapLock = &sync.Mutex{}
+	denyPathSlugMap     = map[string]string{}
 )
 
 // buildDenyVariable returns a nginx variable for a location in a
@@ -631,6 +1064,10 @@ func buildDenyVariable(a interface{}) string {
 		return ""
 	}
 
+	// servers are rendered in parallel
+	denyPathSlugMapLock.Lock()
+	defer denyPathSlugMapLock.Unlock()
+
 	if _, ok := denyPathSlugMap[l]; !ok {
 		denyPathSlugMap[l] = randomString()
 	}
@@ -682,7 +1119,7 @@ func buildNextUpstream(i, r interface{}) string {
 // refer to http://nginx.org/en/docs/syntax.html
 // Nginx differentiates between size and offset
 // offset directives support gigabytes in addition
-var nginxSizeRegex = regexp.MustCompile("^[0-9]+[kKmM]{0,1}$")
+// to the sizes of proxy.SizeRegex
 var nginxOffsetRegex = regexp.MustCompile("^[0-9]+[kKmMgG]{0,1}$")
 
 // isValidByteSize validates size units valid in nginx
@@ -704,7 +1141,7 @@ func isValidByteSize(input interface{}, isOffset bool) bool {
 		return nginxOffsetRegex.MatchString(s)
 	}
 
-	return nginxSizeRegex.MatchString(s)
+	return proxy.SizeRegex.MatchString(s)
 }
 
 type ingressInformation struct {
@@ -821,14 +1258,60 @@ func randomString() string {
 	return string(b)
 }
 
-func buildOpentracing(input interface{}) string {
-	cfg, ok := input.(config.Configuration)
+// ShouldLoadOpentracingModule returns true if the requests are traced
+// globally or in any location
+func ShouldLoadOpentracingModule(cfg config.Configuration, servers []*ingress.Server) bool {
+	if cfg.EnableOpentracing {
+		return true
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.Opentracing.Set && location.Opentracing.Enabled {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func shouldLoadOpentracingModule(c interface{}, s interface{}) bool {
+	cfg, ok := c.(config.Configuration)
 	if !ok {
-		klog.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return false
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	return ShouldLoadOpentracingModule(cfg, servers)
+}
+
+// opentracingSamplingVariable returns the variable containing the sampling
+// priority of the requests of the locations with a sample rate
+func opentracingSamplingVariable(rate float64) string {
+	return fmt.Sprintf("$opentracing_sampling_priority_%v", int(math.Round(rate*10000)))
+}
+
+func buildOpentracing(c interface{}, s interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		klog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		klog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
 		return ""
 	}
 
-	if !cfg.EnableOpentracing {
+	if !ShouldLoadOpentracingModule(cfg, servers) {
 		return ""
 	}
 
@@ -843,6 +1326,61 @@ func buildOpentracing(input interface{}) string {
 
 	buf.WriteString("\r\n")
 
+	// the sampling priority tag overrides the sampling of the tracer
+	rates := []int{}
+	found := sets.NewInt()
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			rate := int(math.Round(location.Opentracing.SampleRate * 10000))
+			if rate > 0 && !found.Has(rate) {
+				found.Insert(rate)
+				rates = append(rates, rate)
+			}
+		}
+	}
+
+	sort.Ints(rates)
+	for _, rate := range rates {
+		buf.WriteString(fmt.Sprintf("split_clients \"$request_id\" %v {\r\n", opentracingSamplingVariable(float64(rate)/10000)))
+		buf.WriteString(fmt.Sprintf("    %v.%02d%% 1;\r\n", rate/100, rate%100))
+		buf.WriteString("    * 0;\r\n")
+		buf.WriteString("}\r\n")
+	}
+
+	return buf.String()
+}
+
+// buildOpentracingForLocation returns the directives enabling or
+// disabling the tracing of the requests of a location
+func buildOpentracingForLocation(isOTEnabled bool, loc interface{}) string {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", loc)
+		return ""
+	}
+
+	if location.Opentracing.Set {
+		if !location.Opentracing.Enabled {
+			if isOTEnabled {
+				return "opentracing off;"
+			}
+			return ""
+		}
+	} else if !isOTEnabled {
+		return ""
+	}
+
+	buf := bytes.NewBufferString("")
+	if !isOTEnabled {
+		buf.WriteString("opentracing on;\n")
+	}
+
+	buf.WriteString(fmt.Sprintf("%v;\n", opentracingPropagateContext(location)))
+
+	if location.Opentracing.SampleRate > 0 {
+		buf.WriteString(fmt.Sprintf("opentracing_tag sampling.priority %v;\n", opentracingSamplingVariable(location.Opentracing.SampleRate)))
+	}
+
 	return buf.String()
 }
 
@@ -883,6 +1421,48 @@ func proxySetHeader(loc interface{}) string {
 	return "proxy_set_header"
 }
 
+// buildProxySSL returns the directives configuring the client certificate
+// presented to the upstream servers and the verification of their certificates
+func buildProxySSL(loc interface{}) string {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		return ""
+	}
+
+	if location.ProxySSL.Secret == "" {
+		return ""
+	}
+
+	prefix := "proxy"
+	if location.BackendProtocol == "GRPC" || location.BackendProtocol == "GRPCS" {
+		prefix = "grpc"
+	}
+
+	out := []string{}
+	if location.ProxySSL.PemFileName != "" {
+		out = append(out,
+			fmt.Sprintf("%v_ssl_certificate %v;", prefix, location.ProxySSL.PemFileName),
+			fmt.Sprintf("%v_ssl_certificate_key %v;", prefix, location.ProxySSL.PemFileName))
+	}
+
+	if location.ProxySSL.CAFileName != "" {
+		out = append(out, fmt.Sprintf("%v_ssl_trusted_certificate %v;", prefix, location.ProxySSL.CAFileName))
+	}
+
+	out = append(out,
+		fmt.Sprintf("%v_ssl_verify %v;", prefix, location.ProxySSL.Verify),
+		fmt.Sprintf("%v_ssl_verify_depth %v;", prefix, location.ProxySSL.VerifyDepth))
+
+	if location.ProxySSL.ServerName != "" {
+		out = append(out,
+			fmt.Sprintf("%v_ssl_name %v;", prefix, location.ProxySSL.ServerName),
+			fmt.Sprintf("%v_ssl_server_name on;", prefix))
+	}
+
+	return strings.Join(out, "\n")
+}
+
 // buildCustomErrorDeps is a utility function returning a struct wrapper with
 // the data required to build the 'CUSTOM_ERRORS' template
 func buildCustomErrorDeps(upstreamName string, errorCodes []int, enableMetrics bool) interface{} {
@@ -953,6 +1533,30 @@ func buildCustomErrorLocationsPerServer(input interface{}) interface{} {
 	return errorLocations
 }
 
+// buildMirrorLocations returns the mirror configurations of the locations of
+// a server block, deduplicated by internal location, to create the internal
+// locations sending the copy of the requests
+func buildMirrorLocations(input interface{}) []mirror.Config {
+	locations, ok := input.([]*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Location' type but %T was returned", input)
+		return []mirror.Config{}
+	}
+
+	mirrors := []mirror.Config{}
+	found := sets.NewString()
+	for _, loc := range locations {
+		if loc.Mirror.Source == "" || found.Has(loc.Mirror.Source) {
+			continue
+		}
+
+		found.Insert(loc.Mirror.Source)
+		mirrors = append(mirrors, loc.Mirror)
+	}
+
+	return mirrors
+}
+
 func opentracingPropagateContext(loc interface{}) string {
 	location, ok := loc.(*ingress.Location)
 	if !ok {
@@ -966,3 +1570,74 @@ func opentracingPropagateContext(loc interface{}) string {
 
 	return "opentracing_propagate_context"
 }
+
+// enableGRPCWeb returns true if gRPC-Web requests to the location must be
+// translated into gRPC. This is only possible with a gRPC backend.
+func enableGRPCWeb(loc interface{}) bool {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		return false
+	}
+
+	if !location.GRPCWeb {
+		return false
+	}
+
+	return location.BackendProtocol == "GRPC" || location.BackendProtocol == "GRPCS"
+}
+
+// acmeChallengePath is the path of the ACME HTTP-01 challenges
+// https://tools.ietf.org/html/rfc8555#section-8.3
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// shouldConfigureACMEChallenge returns true if the ACME HTTP-01 challenge location
+// must be added to the server, which is when no Ingress defines the same path
+func shouldConfigureACMEChallenge(s interface{}) bool {
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, location := range server.Locations {
+		if location.Path == acmeChallengePath {
+			return false
+		}
+	}
+
+	return true
+}
+
+// corsReflectsOrigin returns true when the Access-Control-Allow-Origin header
+// contains the Origin of the request instead of a wildcard, so the responses
+// depend on the Origin header.
+func corsReflectsOrigin(corsOrigin string) bool {
+	return corsOrigin != "" && corsOrigin != "*"
+}
+
+// buildCorsOriginRegex returns the configuration that checks if the Origin
+// of the request is one of the allowed CORS origins. Wildcard subdomains
+// like https://*.example.com match any subdomain of the domain
+func buildCorsOriginRegex(corsOrigin string) string {
+	if !corsReflectsOrigin(corsOrigin) {
+		return "set $cors_origin '*';\nset $cors 'true';"
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(corsOrigin, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+
+		origin = regexp.QuoteMeta(origin)
+		origins = append(origins, strings.Replace(origin, `\*\.`, `[A-Za-z0-9\-]+\.`, 1))
+	}
+
+	return fmt.Sprintf(`set $cors '';
+if ($http_origin ~* "^(%v)$") {
+    set $cors_origin $http_origin;
+    set $cors 'true';
+}`, strings.Join(origins, "|"))
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -33,11 +34,21 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/bodysize"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/globalratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwt"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/oidc"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentracing"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamkeepalive"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/xforwardedprefix"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 var (
@@ -165,9 +176,10 @@ proxy_pass http://upstream_balancer;`,
 )
 
 func TestBuildLuaSharedDictionaries(t *testing.T) {
+	cfg := config.NewDefault()
 	invalidType := &ingress.Ingress{}
 	expected := ""
-	actual := buildLuaSharedDictionaries(invalidType, true)
+	actual := buildLuaSharedDictionaries(cfg, invalidType)
 
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -184,7 +196,7 @@ func TestBuildLuaSharedDictionaries(t *testing.T) {
 		},
 	}
 
-	config := buildLuaSharedDictionaries(servers, false)
+	config := buildLuaSharedDictionaries(cfg, servers)
 	if !strings.Contains(config, "lua_shared_dict configuration_data") {
 		t.Errorf("expected to include 'configuration_data' but got %s", config)
 	}
@@ -193,10 +205,237 @@ func TestBuildLuaSharedDictionaries(t *testing.T) {
 	}
 
 	servers[1].Locations[0].LuaRestyWAF = luarestywaf.Config{Mode: "ACTIVE"}
-	config = buildLuaSharedDictionaries(servers, false)
+	config = buildLuaSharedDictionaries(cfg, servers)
 	if !strings.Contains(config, "lua_shared_dict waf_storage") {
 		t.Errorf("expected to configure 'waf_storage', but got %s", config)
 	}
+	if strings.Contains(config, "lua_shared_dict discovery") {
+		t.Errorf("expected to not include 'discovery' but got %s", config)
+	}
+
+	servers[0].Locations[0].OIDC = oidc.Config{DiscoveryURL: "https://accounts.example.com"}
+	config = buildLuaSharedDictionaries(cfg, servers)
+	if !strings.Contains(config, "lua_shared_dict discovery") || !strings.Contains(config, "lua_shared_dict jwks") {
+		t.Errorf("expected to configure 'discovery' and 'jwks', but got %s", config)
+	}
+
+	servers[0].Locations[0].OIDC = oidc.Config{}
+	servers[1].Locations[0].JWT = jwt.Config{JWKSURL: "https://auth.example.com/jwks.json"}
+	config = buildLuaSharedDictionaries(cfg, servers)
+	if strings.Contains(config, "lua_shared_dict discovery") || !strings.Contains(config, "lua_shared_dict jwks") {
+		t.Errorf("expected to configure only 'jwks', but got %s", config)
+	}
+
+	cfg.DisableLuaRestyWAF = true
+	config = buildLuaSharedDictionaries(cfg, servers)
+	if strings.Contains(config, "waf_storage") {
+		t.Errorf("expected to not include 'waf_storage' when lua-resty-waf is disabled but got %s", config)
+	}
+
+	cfg.LuaSharedDicts["configuration_data"] = 102400
+	cfg.LuaSharedDicts["certificate_data"] = 50
+	cfg.LuaSharedDicts["plugin_cache"] = 10240
+	config = buildLuaSharedDictionaries(cfg, servers)
+	for _, dict := range []string{
+		"lua_shared_dict configuration_data 100M",
+		"lua_shared_dict certificate_data 50k",
+		"lua_shared_dict plugin_cache 10M",
+	} {
+		if !strings.Contains(config, dict) {
+			t.Errorf("expected to include '%v' but got %s", dict, config)
+		}
+	}
+	if strings.Contains(config, "tcp_udp_configuration_data") {
+		t.Errorf("expected to not include 'tcp_udp_configuration_data' but got %s", config)
+	}
+}
+
+func TestLuaSharedDictSize(t *testing.T) {
+	if size := luaSharedDictSize(config.Configuration{}, "tcp_udp_configuration_data"); size != "5M" {
+		t.Errorf("expected the default size 5M but got %v", size)
+	}
+
+	if size := luaSharedDictSize(&ingress.Ingress{}, "configuration_data"); size != "" {
+		t.Errorf("expected no size with an invalid type but got %v", size)
+	}
+}
+
+func TestShouldConfigureOIDC(t *testing.T) {
+	if shouldConfigureOIDC(&ingress.Ingress{}) {
+		t.Errorf("expected false with an invalid type")
+	}
+
+	servers := []*ingress.Server{
+		{Hostname: "foo.bar", Locations: []*ingress.Location{{Path: "/"}}},
+	}
+	if shouldConfigureOIDC(servers) {
+		t.Errorf("expected false without OpenID Connect authentication")
+	}
+
+	servers[0].Locations[0].OIDC = oidc.Config{DiscoveryURL: "https://accounts.example.com"}
+	if !shouldConfigureOIDC(servers) {
+		t.Errorf("expected true with OpenID Connect authentication")
+	}
+}
+
+func TestShouldConfigureJWT(t *testing.T) {
+	if shouldConfigureJWT(&ingress.Ingress{}) {
+		t.Errorf("expected false with an invalid type")
+	}
+
+	servers := []*ingress.Server{
+		{Hostname: "foo.bar", Locations: []*ingress.Location{{Path: "/"}}},
+	}
+	if shouldConfigureJWT(servers) {
+		t.Errorf("expected false without JWT validation")
+	}
+
+	servers[0].Locations[0].JWT = jwt.Config{JWKSURL: "https://auth.example.com/jwks.json"}
+	if !shouldConfigureJWT(servers) {
+		t.Errorf("expected true with JWT validation")
+	}
+}
+
+func TestShouldApplyGlobalRateLimit(t *testing.T) {
+	cfg := config.NewDefault()
+	location := &ingress.Location{Path: "/"}
+
+	if shouldApplyGlobalRateLimit("invalid", location) {
+		t.Errorf("expected false with an invalid configuration type")
+	}
+	if shouldApplyGlobalRateLimit(cfg, "invalid") {
+		t.Errorf("expected false with an invalid location type")
+	}
+	if shouldApplyGlobalRateLimit(cfg, location) {
+		t.Errorf("expected false without global rate limit")
+	}
+
+	location.GlobalRateLimit = globalratelimit.Config{Limit: 10, WindowSize: 60}
+	if shouldApplyGlobalRateLimit(cfg, location) {
+		t.Errorf("expected false without a shared store")
+	}
+
+	cfg.GlobalRateLimitBackend = "memcached"
+	if !shouldApplyGlobalRateLimit(cfg, location) {
+		t.Errorf("expected true with a global rate limit and a shared store")
+	}
+}
+
+func TestBuildPlugins(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.Plugins = []string{"billing"}
+
+	servers := []*ingress.Server{
+		{
+			Hostname: "foo.bar",
+			Locations: []*ingress.Location{
+				{Path: "/", Plugins: []string{"custom_auth", "billing"}},
+				{Path: "/api", Plugins: []string{"rewrite_headers"}},
+			},
+		},
+		{
+			Hostname:  "bar.foo",
+			Locations: []*ingress.Location{{Path: "/"}},
+		},
+	}
+
+	expected := []string{"billing", "custom_auth", "rewrite_headers"}
+	actual := buildPlugins(cfg, servers)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v but returned %v", expected, actual)
+	}
+
+	if len(buildPlugins("invalid", servers)) != 0 {
+		t.Errorf("expected no plugins with an invalid configuration type")
+	}
+	if len(buildPlugins(cfg, "invalid")) != 0 {
+		t.Errorf("expected no plugins with an invalid servers type")
+	}
+}
+
+func TestBuildLocationPlugins(t *testing.T) {
+	cfg := config.NewDefault()
+	location := &ingress.Location{Path: "/"}
+
+	if len(buildLocationPlugins(cfg, location)) != 0 {
+		t.Errorf("expected no plugins by default")
+	}
+
+	cfg.Plugins = []string{"billing"}
+	location.Plugins = []string{"custom_auth", "billing"}
+
+	expected := []string{"billing", "custom_auth"}
+	actual := buildLocationPlugins(cfg, location)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v but returned %v", expected, actual)
+	}
+
+	if len(buildLocationPlugins("invalid", location)) != 0 {
+		t.Errorf("expected no plugins with an invalid configuration type")
+	}
+	if len(buildLocationPlugins(cfg, "invalid")) != 0 {
+		t.Errorf("expected no plugins with an invalid location type")
+	}
+}
+
+func TestBuildLuaString(t *testing.T) {
+	cases := map[string]struct {
+		input    interface{}
+		expected string
+	}{
+		"invalid type": {1, `""`},
+		"empty":        {"", `""`},
+		"plain text":   {"unauthorized request", `"unauthorized request"`},
+		"json":         {`{"error":"unauthorized"}`, `"\123\034error\034:\034unauthorized\034\125"`},
+		"backslash":    {`a\b`, `"a\092b"`},
+		"new line":     {"a\nb", `"a\010b"`},
+	}
+
+	for name, tc := range cases {
+		actual := buildLuaString(tc.input)
+		if actual != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildNginxString(t *testing.T) {
+	cases := map[string]struct {
+		input    interface{}
+		expected string
+	}{
+		"invalid type": {1, `""`},
+		"plain text":   {"upload limit exceeded", `"upload limit exceeded"`},
+		"json":         {`{"error":"too large"}`, `"{\"error\":\"too large\"}"`},
+		"backslash":    {`a\b`, `"a\\b"`},
+		"variable":     {"$host", `"${literal_dollar}host"`},
+	}
+
+	for name, tc := range cases {
+		actual := buildNginxString(tc.input)
+		if actual != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildMapKey(t *testing.T) {
+	cases := map[string]struct {
+		input    interface{}
+		expected string
+	}{
+		"invalid type": {1, `""`},
+		"plain text":   {"Mozilla/5.0 (compatible; Googlebot/2.1)", `"Mozilla/5.0 (compatible; Googlebot/2.1)"`},
+		"regex":        {`~*^.*\.example\.com$`, `"~*^.*\\.example\\.com$"`},
+		"quotes":       {`a"b`, `"a\"b"`},
+	}
+
+	for name, tc := range cases {
+		actual := buildMapKey(tc.input)
+		if actual != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, actual)
+		}
+	}
 }
 
 func TestFormatIP(t *testing.T) {
@@ -249,7 +488,7 @@ func TestBuildProxyPass(t *testing.T) {
 			Path:             tc.Path,
 			Rewrite:          rewrite.Config{Target: tc.Target},
 			Backend:          defaultBackend,
-			XForwardedPrefix: tc.XForwardedPrefix,
+			XForwardedPrefix: xforwardedprefix.Config{Enabled: tc.XForwardedPrefix},
 		}
 
 		if tc.SecureBackend {
@@ -280,10 +519,145 @@ func TestBuildProxyPass(t *testing.T) {
 	}
 }
 
+func TestBuildProxyPassUpstreamKeepalive(t *testing.T) {
+	loc := &ingress.Location{
+		Path:              "/",
+		Backend:           "upstream-name",
+		UpstreamKeepalive: upstreamkeepalive.Config{Override: true, Connections: 8, Timeout: 30, Requests: 1000},
+	}
+
+	expected := "proxy_pass http://upstream_balancer_keepalive_8_30_1000;"
+	pp := buildProxyPass("example.com", []*ingress.Backend{{Name: "upstream-name"}}, loc)
+	if pp != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, pp)
+	}
+}
+
+func TestMergeHeaders(t *testing.T) {
+	global := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+	}
+	local := map[string]string{
+		"x-frame-options": "SAMEORIGIN",
+		"X-Custom":        "value",
+	}
+
+	expected := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"x-frame-options":        "SAMEORIGIN",
+		"X-Custom":               "value",
+	}
+
+	headers := mergeHeaders(global, local)
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected %v but returned %v", expected, headers)
+	}
+
+	if len(global) != 2 {
+		t.Errorf("expected the global headers to be left untouched")
+	}
+
+	if headers := mergeHeaders(nil, nil); len(headers) != 0 {
+		t.Errorf("expected no headers but returned %v", headers)
+	}
+}
+
+func TestFilterUpstreamKeepalives(t *testing.T) {
+	override := upstreamkeepalive.Config{Override: true, Connections: 8, Timeout: 30, Requests: 1000}
+	disabled := upstreamkeepalive.Config{Override: true, Connections: 0, Timeout: 60, Requests: 100}
+
+	servers := []*ingress.Server{
+		{
+			Hostname: "foo.bar",
+			Locations: []*ingress.Location{
+				{Path: "/", UpstreamKeepalive: override},
+				{Path: "/global"},
+			},
+		},
+		{
+			Hostname: "bar.baz",
+			Locations: []*ingress.Location{
+				{Path: "/", UpstreamKeepalive: override},
+				{Path: "/nokeepalive", UpstreamKeepalive: disabled},
+			},
+		},
+	}
+
+	expected := []upstreamkeepalive.Config{disabled, override}
+	keepalives := filterUpstreamKeepalives(servers)
+	if !reflect.DeepEqual(keepalives, expected) {
+		t.Errorf("expected %+v but returned %+v", expected, keepalives)
+	}
+
+	if len(filterUpstreamKeepalives(nil)) != 0 {
+		t.Errorf("expected no keepalive configurations for an invalid input")
+	}
+}
+
+func TestBuildProxyPassXForwardedPrefix(t *testing.T) {
+	defaultBackend := "upstream-name"
+	backends := []*ingress.Backend{{Name: defaultBackend}}
+
+	testCases := map[string]struct {
+		path     string
+		config   xforwardedprefix.Config
+		expected string
+	}{
+		"prefix of a regular expression": {"/api(/|$)(.*)", xforwardedprefix.Config{Enabled: true}, "/api"},
+		"optional trailing slash":        {"/something/?(.*)", xforwardedprefix.Config{Enabled: true}, "/something"},
+		"dot in the path":                {"/v1.0/(.*)", xforwardedprefix.Config{Enabled: true}, "/v1.0"},
+		"regular expression in the root": {"/(.*)", xforwardedprefix.Config{Enabled: true}, "/"},
+		"custom prefix":                  {"/api(/|$)(.*)", xforwardedprefix.Config{Enabled: true, Prefix: "/public/api"}, "/public/api"},
+	}
+
+	for name, tc := range testCases {
+		loc := &ingress.Location{
+			Path:             tc.path,
+			Rewrite:          rewrite.Config{Target: "/$2"},
+			Backend:          defaultBackend,
+			XForwardedPrefix: tc.config,
+		}
+
+		expected := fmt.Sprintf("proxy_set_header X-Forwarded-Prefix \"%s\";", tc.expected)
+		if pp := buildProxyPass("example.com", backends, loc); !strings.Contains(pp, expected) {
+			t.Errorf("%s: expected '%v' in \n'%v'", name, expected, pp)
+		}
+	}
+}
+
+func TestBuildProxyPassWithBackendProtocol(t *testing.T) {
+	defaultBackend := "upstream-name"
+	backends := []*ingress.Backend{{Name: defaultBackend}}
+
+	tests := map[string]string{
+		"HTTP":  "proxy_pass http://upstream_balancer;",
+		"HTTPS": "proxy_pass https://upstream_balancer;",
+		"GRPC":  "grpc_pass grpc://upstream_balancer;",
+		"GRPCS": "grpc_pass grpcs://upstream_balancer;",
+		"AJP":   "ajp_pass upstream_balancer;",
+		"FCGI":  "fastcgi_pass upstream_balancer;",
+	}
+
+	for protocol, expected := range tests {
+		loc := &ingress.Location{
+			Path:            "/",
+			Rewrite:         rewrite.Config{Target: "/"},
+			Backend:         defaultBackend,
+			BackendProtocol: protocol,
+		}
+
+		pp := buildProxyPass("example.com", backends, loc)
+		if pp != expected {
+			t.Errorf("%s: expected '%v' but returned '%v'", protocol, expected, pp)
+		}
+	}
+}
+
 func TestBuildAuthLocation(t *testing.T) {
 	invalidType := &ingress.Ingress{}
 	expected := ""
-	actual := buildAuthLocation(invalidType)
+	actual := buildAuthLocation(invalidType, "")
 
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -298,7 +672,7 @@ func TestBuildAuthLocation(t *testing.T) {
 		Path: "/cat",
 	}
 
-	str := buildAuthLocation(loc)
+	str := buildAuthLocation(loc, "")
 
 	encodedAuthURL := strings.Replace(base64.URLEncoding.EncodeToString([]byte(loc.Path)), "=", "", -1)
 	expected = fmt.Sprintf("/_external-auth-%v", encodedAuthURL)
@@ -306,21 +680,67 @@ func TestBuildAuthLocation(t *testing.T) {
 	if str != expected {
 		t.Errorf("Expected \n'%v'\nbut returned \n'%v'", expected, str)
 	}
+
+	globalAuthURL := "https://auth.example.com/oauth2/auth"
+	loc = &ingress.Location{Path: "/cat", EnableGlobalAuth: true}
+	if str := buildAuthLocation(loc, globalAuthURL); str != expected {
+		t.Errorf("Expected \n'%v'\nbut returned \n'%v'", expected, str)
+	}
+
+	loc.EnableGlobalAuth = false
+	if str := buildAuthLocation(loc, globalAuthURL); str != "" {
+		t.Errorf("Expected an empty location but returned '%v'", str)
+	}
+}
+
+func TestShouldApplyGlobalAuth(t *testing.T) {
+	globalAuthURL := "https://auth.example.com/oauth2/auth"
+
+	testCases := map[string]struct {
+		loc           *ingress.Location
+		globalAuthURL string
+		expected      bool
+	}{
+		"global auth not configured": {&ingress.Location{EnableGlobalAuth: true}, "", false},
+		"global auth enabled":        {&ingress.Location{EnableGlobalAuth: true}, globalAuthURL, true},
+		"global auth disabled":       {&ingress.Location{EnableGlobalAuth: false}, globalAuthURL, false},
+		"location auth": {&ingress.Location{
+			EnableGlobalAuth: true,
+			ExternalAuth:     authreq.Config{URL: "https://auth.example.com/other"},
+		}, globalAuthURL, false},
+	}
+
+	for title, tc := range testCases {
+		if actual := shouldApplyGlobalAuth(tc.loc, tc.globalAuthURL); actual != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", title, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildBodySizeErrorLocation(t *testing.T) {
+	loc := &ingress.Location{Path: "/upload"}
+	if actual := buildBodySizeErrorLocation(loc); actual != "" {
+		t.Errorf("Expected an empty location name but returned '%v'", actual)
+	}
+
+	loc.BodySizeError = bodysize.Config{ErrorBody: "upload limit exceeded", ErrorContentType: "text/plain"}
+
+	encodedPath := strings.Replace(base64.URLEncoding.EncodeToString([]byte(loc.Path)), "=", "", -1)
+	expected := fmt.Sprintf("@body-size-error-%v", encodedPath)
+	if actual := buildBodySizeErrorLocation(loc); actual != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
 }
 
 func TestBuildAuthResponseHeaders(t *testing.T) {
-	invalidType := &ingress.Ingress{}
 	expected := []string{}
-	actual := buildAuthResponseHeaders(invalidType)
+	actual := buildAuthResponseHeaders(nil)
 
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
 
-	loc := &ingress.Location{
-		ExternalAuth: authreq.Config{ResponseHeaders: []string{"h1", "H-With-Caps-And-Dashes"}},
-	}
-	headers := buildAuthResponseHeaders(loc)
+	headers := buildAuthResponseHeaders([]string{"h1", "H-With-Caps-And-Dashes"})
 	expected = []string{
 		"auth_request_set $authHeader0 $upstream_http_h1;",
 		"proxy_set_header 'h1' $authHeader0;",
@@ -378,6 +798,172 @@ func TestTemplateWithData(t *testing.T) {
 	if !strings.Contains(string(rt), "listen 2.2.2.2") {
 		t.Errorf("invalid NGINX template, expected IPV4 listen address not present")
 	}
+
+	dat.Cfg.UseGeoIP2 = true
+	dat.GeoIP2Databases = []string{"GeoLite2-Country", "GeoLite2-ASN"}
+	dat.GeoIP2MissingDatabases = []string{"GeoLite2-City"}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), "geoip2 /etc/nginx/geoip/GeoLite2-Country.mmdb") || !strings.Contains(string(rt), "geoip2 /etc/nginx/geoip/GeoLite2-ASN.mmdb") {
+		t.Errorf("invalid NGINX template, expected GeoIP2 databases not present")
+	}
+
+	if !strings.Contains(string(rt), "$geoip2_asn source=$the_real_ip autonomous_system_number;") {
+		t.Errorf("invalid NGINX template, expected GeoIP2 variables not present")
+	}
+
+	if strings.Contains(string(rt), "GeoLite2-City.mmdb") {
+		t.Errorf("invalid NGINX template, unexpected GeoIP2 database not available on disk")
+	}
+
+	if !strings.Contains(string(rt), `map $remote_addr $geoip2_city_country_code { default ""; }`) {
+		t.Errorf("invalid NGINX template, expected empty variables of the GeoIP2 database not available on disk")
+	}
+
+	dat.Cfg.NginxStatusListen = []string{"127.0.0.1:10246"}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), "listen 127.0.0.1:10246;") {
+		t.Errorf("invalid NGINX template, expected dedicated status server not present")
+	}
+
+	if strings.Count(string(rt), "location /nginx_status") != 1 {
+		t.Errorf("invalid NGINX template, expected /nginx_status only in the dedicated status server")
+	}
+
+	dat.Cfg.StreamSnippet = "limit_conn_zone $binary_remote_addr zone=tcp_conn:10m;"
+	dat.TCPBackends = []ingress.L4Service{{Port: 5432, Snippet: "limit_conn tcp_conn 10;"}}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), dat.Cfg.StreamSnippet) || !strings.Contains(string(rt), dat.TCPBackends[0].Snippet) {
+		t.Errorf("invalid NGINX template, expected stream snippets not present")
+	}
+
+	dat.Cfg.GlobalExternalAuth = authreq.Config{URL: "https://auth.example.com/oauth2/auth", Host: "auth.example.com"}
+	for _, server := range dat.Servers {
+		for _, location := range server.Locations {
+			location.EnableGlobalAuth = true
+		}
+	}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), "set $target https://auth.example.com/oauth2/auth;") {
+		t.Errorf("invalid NGINX template, expected global external authentication not present")
+	}
+
+	location := dat.Servers[0].Locations[0]
+	location.Whitelist.CIDR = []string{"10.0.0.0/8"}
+	location.Satisfy = "any"
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), "allow 10.0.0.0/8;") || !strings.Contains(string(rt), "satisfy any;") {
+		t.Errorf("invalid NGINX template, expected whitelist to take part in satisfy any")
+	}
+
+	dat.Cfg.BlockCIDRs = []string{"192.168.0.0/16"}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), "deny 192.168.0.0/16;") || !strings.Contains(string(rt), "192.168.0.0/16 1;") {
+		t.Errorf("invalid NGINX template, expected block-cidrs not present")
+	}
+
+	if !strings.Contains(string(rt), "if ($block_cidr) {") {
+		t.Errorf("invalid NGINX template, expected block-cidrs to be enforced outside satisfy any")
+	}
+
+	sslListen := regexp.MustCompile(`(?m)^\s*listen .*\bssl\b.*;$`)
+	for _, useHTTP2 := range []bool{false, true} {
+		for _, server := range dat.Servers {
+			server.SSLCert.PemFileName = "/etc/ingress-controller/ssl/default-fake-certificate.pem"
+			server.UseHTTP2 = useHTTP2
+		}
+		rt, err = ngxTpl.Write(dat)
+		if err != nil {
+			t.Errorf("invalid NGINX template: %v", err)
+		}
+
+		listens := sslListen.FindAllString(string(rt), -1)
+		if len(listens) == 0 {
+			t.Errorf("invalid NGINX template, expected ssl listen lines not present")
+		}
+		for _, listen := range listens {
+			if strings.HasSuffix(listen, " http2;") != useHTTP2 {
+				t.Errorf("invalid NGINX template, expected http2 %v in the listen line %q", useHTTP2, strings.TrimSpace(listen))
+			}
+		}
+	}
+
+	dat.Servers[0].SSLProtocols = "TLSv1.3"
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	if !strings.Contains(string(rt), `require("certificate").check_protocols("TLSv1.3")`) {
+		t.Errorf("invalid NGINX template, expected the protocols of the server to be checked in the handshake")
+	}
+
+	dat.AddHeaders = map[string]string{"X-Content-Type-Options": "nosniff"}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	header := strings.Index(string(rt), `more_set_headers "X-Content-Type-Options: nosniff";`)
+	if header == -1 || header > strings.Index(string(rt), "## start server") {
+		t.Errorf("invalid NGINX template, expected the global headers in the http block")
+	}
+
+	dat.Cfg.LimitConnZoneVariable = "$binary_remote_addr"
+	dat.Servers[0].Locations[0].RateLimit = ratelimit.Config{
+		Connections: ratelimit.Zone{Name: "default_header_conn", Limit: 10, SharedSize: 5, Key: "$http_x_client_id"},
+		Name:        "default_header",
+		ID:          "header",
+	}
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	connKey := regexp.MustCompile(`map \$http_x_client_id \$limit_conn_key_header {\s+"" \$binary_remote_addr;\s+default \$http_x_client_id;\s+}`)
+	if !connKey.MatchString(string(rt)) {
+		t.Errorf("invalid NGINX template, expected the connections without the header to be limited by client address")
+	}
+	if !strings.Contains(string(rt), "0 $limit_conn_key_header;") {
+		t.Errorf("invalid NGINX template, expected the connections limit key to exclude the whitelist")
+	}
+	dat.Servers[0].Locations[0].RateLimit = ratelimit.Config{}
+
+	dat.EnableMetrics = true
+	dat.Cfg.EnableModsecurity = true
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Errorf("invalid NGINX template: %v", err)
+	}
+
+	for _, marker := range []string{"monitor.rewrite()", "monitor.access()", "modsecurity = true,", "access_checks = true,"} {
+		if !strings.Contains(string(rt), marker) {
+			t.Errorf("invalid NGINX template, expected %q to report the requests blocked by ModSecurity", marker)
+		}
+	}
 }
 
 func BenchmarkTemplateWithData(b *testing.B) {
@@ -476,6 +1062,41 @@ func TestIsLocationAllowed(t *testing.T) {
 	}
 }
 
+func TestIsSSLRedirectExcluded(t *testing.T) {
+	if isSSLRedirectExcluded(&ingress.Ingress{}) {
+		t.Errorf("Expected '%v' but returned '%v'", false, true)
+	}
+
+	loc := &ingress.Location{
+		Path:    "/healthz",
+		Rewrite: rewrite.Config{NoSSLRedirectPaths: []string{"/ping", "/healthz"}},
+	}
+	if !isSSLRedirectExcluded(loc) {
+		t.Errorf("Expected '%v' but returned '%v'", true, false)
+	}
+
+	loc.Path = "/healthz/live"
+	if isSSLRedirectExcluded(loc) {
+		t.Errorf("Expected '%v' but returned '%v'", false, true)
+	}
+}
+
+func TestBuildSSLRedirectCode(t *testing.T) {
+	if code := buildSSLRedirectCode(308, &ingress.Ingress{}); code != 308 {
+		t.Errorf("Expected '%v' but returned '%v'", 308, code)
+	}
+
+	loc := &ingress.Location{}
+	if code := buildSSLRedirectCode(308, loc); code != 308 {
+		t.Errorf("Expected '%v' but returned '%v'", 308, code)
+	}
+
+	loc.Rewrite.SSLRedirectCode = 301
+	if code := buildSSLRedirectCode(308, loc); code != 301 {
+		t.Errorf("Expected '%v' but returned '%v'", 301, code)
+	}
+}
+
 func TestBuildForwardedFor(t *testing.T) {
 	invalidType := &ingress.Ingress{}
 	expected := ""
@@ -670,6 +1291,35 @@ func TestBuildRateLimitZones(t *testing.T) {
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
+
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{
+					RateLimit: ratelimit.Config{
+						ID:          "ip",
+						Connections: ratelimit.Zone{Name: "default_ip_conn", Limit: 10, SharedSize: 5},
+					},
+				},
+				{
+					RateLimit: ratelimit.Config{
+						ID:          "header",
+						Connections: ratelimit.Zone{Name: "default_header_conn", Limit: 10, SharedSize: 5, Key: "$http_x_client_id"},
+					},
+				},
+			},
+		},
+	}
+
+	expected = []string{
+		"limit_conn_zone $limit_conn_header zone=default_header_conn:5m;",
+		"limit_conn_zone $limit_ip zone=default_ip_conn:5m;",
+	}
+	actual = buildRateLimitZones(servers)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
 }
 
 // TODO: Needs more tests
@@ -754,7 +1404,7 @@ func TestBuildUpstreamName(t *testing.T) {
 			Path:             tc.Path,
 			Rewrite:          rewrite.Config{Target: tc.Target},
 			Backend:          defaultBackend,
-			XForwardedPrefix: tc.XForwardedPrefix,
+			XForwardedPrefix: xforwardedprefix.Config{Enabled: tc.XForwardedPrefix},
 		}
 
 		if tc.SecureBackend {
@@ -829,6 +1479,24 @@ func TestOpentracingPropagateContext(t *testing.T) {
 	}
 }
 
+func TestEnableGRPCWeb(t *testing.T) {
+	tests := map[interface{}]bool{
+		&ingress.Location{BackendProtocol: "GRPC"}:                 false,
+		&ingress.Location{BackendProtocol: "HTTP", GRPCWeb: true}:  false,
+		&ingress.Location{BackendProtocol: "HTTPS", GRPCWeb: true}: false,
+		&ingress.Location{BackendProtocol: "GRPC", GRPCWeb: true}:  true,
+		&ingress.Location{BackendProtocol: "GRPCS", GRPCWeb: true}: true,
+		"not a location": false,
+	}
+
+	for loc, expected := range tests {
+		actual := enableGRPCWeb(loc)
+		if actual != expected {
+			t.Errorf("Expected %v but returned %v for %v", expected, actual, loc)
+		}
+	}
+}
+
 func TestGetIngressInformation(t *testing.T) {
 	validIngress := &ingress.Ingress{}
 	invalidIngress := "wrongtype"
@@ -1053,7 +1721,7 @@ func TestBuildInfluxDB(t *testing.T) {
 func TestBuildOpenTracing(t *testing.T) {
 	invalidType := &ingress.Ingress{}
 	expected := ""
-	actual := buildOpentracing(invalidType)
+	actual := buildOpentracing(invalidType, []*ingress.Server{})
 
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -1064,7 +1732,7 @@ func TestBuildOpenTracing(t *testing.T) {
 		JaegerCollectorHost: "jaeger-host.com",
 	}
 	expected = "opentracing_load_tracer /usr/local/lib/libjaegertracing_plugin.so /etc/nginx/opentracing.json;\r\n"
-	actual = buildOpentracing(cfgJaeger)
+	actual = buildOpentracing(cfgJaeger, []*ingress.Server{})
 
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -1075,7 +1743,7 @@ func TestBuildOpenTracing(t *testing.T) {
 		ZipkinCollectorHost: "zipkin-host.com",
 	}
 	expected = "opentracing_load_tracer /usr/local/lib/libzipkin_opentracing.so /etc/nginx/opentracing.json;\r\n"
-	actual = buildOpentracing(cfgZipkin)
+	actual = buildOpentracing(cfgZipkin, []*ingress.Server{})
 
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -1086,12 +1754,87 @@ func TestBuildOpenTracing(t *testing.T) {
 		DatadogCollectorHost: "datadog-host.com",
 	}
 	expected = "opentracing_load_tracer /usr/local/lib/libdd_opentracing.so /etc/nginx/opentracing.json;\r\n"
-	actual = buildOpentracing(cfgDatadog)
+	actual = buildOpentracing(cfgDatadog, []*ingress.Server{})
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{Path: "/a", Opentracing: opentracing.Config{Set: true, Enabled: true, SampleRate: 0.1}},
+				{Path: "/b", Opentracing: opentracing.Config{SampleRate: 0.005}},
+				{Path: "/c", Opentracing: opentracing.Config{SampleRate: 0.1}},
+			},
+		},
+	}
+	cfgJaeger.EnableOpentracing = false
+	expected = "opentracing_load_tracer /usr/local/lib/libjaegertracing_plugin.so /etc/nginx/opentracing.json;\r\n" +
+		"split_clients \"$request_id\" $opentracing_sampling_priority_50 {\r\n    0.50% 1;\r\n    * 0;\r\n}\r\n" +
+		"split_clients \"$request_id\" $opentracing_sampling_priority_1000 {\r\n    10.00% 1;\r\n    * 0;\r\n}\r\n"
+	actual = buildOpentracing(cfgJaeger, servers)
 
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
+}
+
+func TestShouldLoadOpentracingModule(t *testing.T) {
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{Path: "/", Opentracing: opentracing.Config{Set: true, Enabled: false}},
+			},
+		},
+	}
+
+	if !shouldLoadOpentracingModule(config.Configuration{EnableOpentracing: true}, servers) {
+		t.Errorf("Expected the module to be loaded when tracing is enabled globally")
+	}
+	if shouldLoadOpentracingModule(config.Configuration{}, servers) {
+		t.Errorf("Expected the module not to be loaded when tracing is disabled")
+	}
+
+	servers[0].Locations = append(servers[0].Locations, &ingress.Location{Path: "/traced", Opentracing: opentracing.Config{Set: true, Enabled: true}})
+	if !shouldLoadOpentracingModule(config.Configuration{}, servers) {
+		t.Errorf("Expected the module to be loaded when tracing is enabled in a location")
+	}
+
+	if shouldLoadOpentracingModule("not a configuration", servers) {
+		t.Errorf("Expected the module not to be loaded with an invalid configuration")
+	}
+}
+
+func TestBuildOpentracingForLocation(t *testing.T) {
+	testCases := []struct {
+		description string
+		global      bool
+		config      opentracing.Config
+		protocol    string
+		expected    string
+	}{
+		{"globally disabled", false, opentracing.Config{}, "HTTP", ""},
+		{"globally enabled", true, opentracing.Config{}, "HTTP", "opentracing_propagate_context;\n"},
+		{"globally enabled with gRPC", true, opentracing.Config{}, "GRPC", "opentracing_grpc_propagate_context;\n"},
+		{"opt out", true, opentracing.Config{Set: true, Enabled: false}, "HTTP", "opentracing off;"},
+		{"opt out globally disabled", false, opentracing.Config{Set: true, Enabled: false}, "HTTP", ""},
+		{"opt in", false, opentracing.Config{Set: true, Enabled: true}, "HTTP", "opentracing on;\nopentracing_propagate_context;\n"},
+		{"sample rate", true, opentracing.Config{SampleRate: 0.25}, "HTTP", "opentracing_propagate_context;\nopentracing_tag sampling.priority $opentracing_sampling_priority_2500;\n"},
+		{"sample rate globally disabled", false, opentracing.Config{SampleRate: 0.25}, "HTTP", ""},
+	}
+
+	for _, tc := range testCases {
+		location := &ingress.Location{BackendProtocol: tc.protocol, Opentracing: tc.config}
+		actual := buildOpentracingForLocation(tc.global, location)
+		if actual != tc.expected {
+			t.Errorf("%v: expected %q but returned %q", tc.description, tc.expected, actual)
+		}
+	}
 
+	if actual := buildOpentracingForLocation(true, "not a location"); actual != "" {
+		t.Errorf("Expected an empty string but returned %q", actual)
+	}
 }
 
 func TestEnforceRegexModifier(t *testing.T) {
@@ -1128,3 +1871,117 @@ func TestStripLocationModifer(t *testing.T) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
 }
+
+func TestBuildMirrorLocations(t *testing.T) {
+	foo := mirror.Config{Source: "/_mirror/default/foo", Target: "http://test.env.com$request_uri", RequestBody: true, Percentage: 100}
+	bar := mirror.Config{Source: "/_mirror/default/bar", Target: "http://test.env.com/bar", Percentage: 10}
+
+	locations := []*ingress.Location{
+		{Path: "/foo", Mirror: foo},
+		{Path: "/foo/api", Mirror: foo},
+		{Path: "/"},
+		{Path: "/bar", Mirror: bar},
+	}
+
+	expected := []mirror.Config{foo, bar}
+	if mirrors := buildMirrorLocations(locations); !reflect.DeepEqual(mirrors, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, mirrors)
+	}
+
+	if mirrors := buildMirrorLocations(&ingress.Server{}); len(mirrors) != 0 {
+		t.Errorf("Expected no mirror locations with an invalid type but returned '%v'", mirrors)
+	}
+}
+
+func TestShouldConfigureACMEChallenge(t *testing.T) {
+	tests := map[string]struct {
+		server   interface{}
+		expected bool
+	}{
+		"without locations": {&ingress.Server{}, true},
+		"with other locations": {&ingress.Server{Locations: []*ingress.Location{
+			{Path: "/"},
+			{Path: "/.well-known"},
+		}}, true},
+		"with a challenge location": {&ingress.Server{Locations: []*ingress.Location{
+			{Path: "/"},
+			{Path: "/.well-known/acme-challenge/"},
+		}}, false},
+		"not a server": {"not a server", false},
+	}
+
+	for name, tc := range tests {
+		if actual := shouldConfigureACMEChallenge(tc.server); actual != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildCorsOriginRegex(t *testing.T) {
+	testCases := map[string]struct {
+		origin   string
+		expected string
+		reflects bool
+	}{
+		"any origin": {"*", "set $cors_origin '*';\nset $cors 'true';", false},
+		"list of origins": {"https://a.test.com, http://b.test.com:8080", `set $cors '';
+if ($http_origin ~* "^(https://a\.test\.com|http://b\.test\.com:8080)$") {
+    set $cors_origin $http_origin;
+    set $cors 'true';
+}`, true},
+		"wildcard subdomain": {"https://*.test.com", `set $cors '';
+if ($http_origin ~* "^(https://[A-Za-z0-9\-]+\.test\.com)$") {
+    set $cors_origin $http_origin;
+    set $cors 'true';
+}`, true},
+	}
+
+	for name, tc := range testCases {
+		if actual := buildCorsOriginRegex(tc.origin); actual != tc.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", name, tc.expected, actual)
+		}
+		if actual := corsReflectsOrigin(tc.origin); actual != tc.reflects {
+			t.Errorf("%v: expected the origin to be reflected %v but returned %v", name, tc.reflects, actual)
+		}
+	}
+}
+
+func TestBuildProxySSL(t *testing.T) {
+	config := proxyssl.Config{
+		AuthSSLCert: resolver.AuthSSLCert{
+			Secret:      "default/upstream-tls",
+			CAFileName:  "/etc/ingress-controller/ssl/ca-default-upstream-tls.pem",
+			PemFileName: "/etc/ingress-controller/ssl/default-upstream-tls.pem",
+		},
+		Verify:      "on",
+		VerifyDepth: 2,
+		ServerName:  "app.internal",
+	}
+
+	testCases := map[string]struct {
+		location *ingress.Location
+		expected string
+	}{
+		"without secret": {&ingress.Location{}, ""},
+		"https backend": {&ingress.Location{BackendProtocol: "HTTPS", ProxySSL: config}, `proxy_ssl_certificate /etc/ingress-controller/ssl/default-upstream-tls.pem;
+proxy_ssl_certificate_key /etc/ingress-controller/ssl/default-upstream-tls.pem;
+proxy_ssl_trusted_certificate /etc/ingress-controller/ssl/ca-default-upstream-tls.pem;
+proxy_ssl_verify on;
+proxy_ssl_verify_depth 2;
+proxy_ssl_name app.internal;
+proxy_ssl_server_name on;`},
+		"grpcs backend without client certificate": {&ingress.Location{BackendProtocol: "GRPCS", ProxySSL: proxyssl.Config{
+			AuthSSLCert: resolver.AuthSSLCert{Secret: "default/ca", CAFileName: "/etc/ingress-controller/ssl/ca-default-ca.pem"},
+			Verify:      "on",
+			VerifyDepth: 1,
+		}}, `grpc_ssl_trusted_certificate /etc/ingress-controller/ssl/ca-default-ca.pem;
+grpc_ssl_verify on;
+grpc_ssl_verify_depth 1;`},
+	}
+
+	for name, tc := range testCases {
+		if actual := buildProxySSL(tc.location); actual != tc.expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", name, tc.expected, actual)
+		}
+	}
+}
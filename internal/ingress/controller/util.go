@@ -19,6 +19,7 @@ package controller
 import (
 	"os"
 	"os/exec"
+	"strings"
 	"syscall"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -65,6 +66,22 @@ func sysctlSomaxconn() int {
 	return maxConns
 }
 
+// listenBacklog returns the backlog of the listening sockets. A size of zero
+// uses somaxconn and larger values are capped to it, as the kernel would
+// silently truncate them anyway.
+func listenBacklog(size, somaxconn int) int {
+	if size <= 0 {
+		return somaxconn
+	}
+
+	if size > somaxconn {
+		klog.Warningf("backlog-size %v is greater than net.core.somaxconn (%v). Using %v", size, somaxconn, somaxconn)
+		return somaxconn
+	}
+
+	return size
+}
+
 // rlimitMaxNumFiles returns hard limit for RLIMIT_NOFILE
 func rlimitMaxNumFiles() int {
 	var rLimit syscall.Rlimit
@@ -108,6 +125,24 @@ func nginxExecCommand(args ...string) *exec.Cmd {
 	return exec.Command("authbind", cmdArgs...)
 }
 
-func nginxTestCommand(cfg string) *exec.Cmd {
+// nginxTestCommand returns the command checking the NGINX configuration
+// file cfg. It is a variable to allow the tests to replace NGINX.
+var nginxTestCommand = func(cfg string) *exec.Cmd {
 	return exec.Command(defBinary, "-c", cfg, "-t")
 }
+
+// isHTTP3Supported checks if the NGINX binary was built with the HTTP/3 module
+func isHTTP3Supported() bool {
+	out, err := exec.Command(defBinary, "-V").CombinedOutput()
+	if err != nil {
+		klog.Warningf("Error reading NGINX build information: %v", err)
+		return false
+	}
+
+	return hasHTTP3Module(string(out))
+}
+
+// hasHTTP3Module checks if the output of nginx -V contains the HTTP/3 module
+func hasHTTP3Module(buildInfo string) bool {
+	return strings.Contains(buildInfo, "--with-http_v3_module")
+}
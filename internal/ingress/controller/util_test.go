@@ -33,3 +33,36 @@ func TestSysctlSomaxconn(t *testing.T) {
 		t.Errorf("returned %v but expected >= 511", i)
 	}
 }
+
+func TestListenBacklog(t *testing.T) {
+	testCases := []struct {
+		size      int
+		somaxconn int
+		expected  int
+	}{
+		{0, 511, 511},
+		{-1, 4096, 4096},
+		{1024, 4096, 1024},
+		{8192, 4096, 4096},
+	}
+	for _, tc := range testCases {
+		b := listenBacklog(tc.size, tc.somaxconn)
+		if b != tc.expected {
+			t.Errorf("listenBacklog(%v, %v) returned %v but expected %v", tc.size, tc.somaxconn, b, tc.expected)
+		}
+	}
+}
+
+func TestHasHTTP3Module(t *testing.T) {
+	testCases := map[string]bool{
+		"nginx version: nginx/1.15.8\nconfigure arguments: --with-http_v2_module --with-http_ssl_module":                       false,
+		"nginx version: nginx/1.25.3\nconfigure arguments: --with-http_v2_module --with-http_v3_module --with-http_ssl_module": true,
+		"": false,
+	}
+
+	for buildInfo, expected := range testCases {
+		if hasHTTP3Module(buildInfo) != expected {
+			t.Errorf("expected %v for %q", expected, buildInfo)
+		}
+	}
+}
@@ -50,6 +50,10 @@ type Backend struct {
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_send_timeout
 	ProxySendTimeout int `json:"proxy-send-timeout"`
 
+	// Maximum value in seconds accepted in the proxy timeout annotations.
+	// Ingress rules with higher values use the default timeouts instead.
+	ProxyMaxTimeout int `json:"proxy-max-timeout"`
+
 	// Sets the number of the buffers used for reading a response from the proxied server
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffers
 	ProxyBuffersNumber int `json:"proxy-buffers-number"`
@@ -59,6 +63,11 @@ type Backend struct {
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffer_size)
 	ProxyBufferSize string `json:"proxy-buffer-size"`
 
+	// Limits the size of the temporary file used to buffer the responses that
+	// do not fit into the proxy buffers. Zero disables the temporary files.
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_max_temp_file_size
+	ProxyMaxTempFileSize string `json:"proxy-max-temp-file-size"`
+
 	// Sets a text that should be changed in the path attribute of the “Set-Cookie” header fields of
 	// a proxied server response.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_cookie_path
@@ -77,6 +86,11 @@ type Backend struct {
 	// https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream_tries
 	ProxyNextUpstreamTries int `json:"proxy-next-upstream-tries"`
 
+	// Limits the time during which a request can be passed to the next server.
+	// A value of 0 turns off this limitation.
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream_timeout
+	ProxyNextUpstreamTimeout int `json:"proxy-next-upstream-timeout"`
+
 	// Sets the original text that should be changed in the "Location" and "Refresh" header fields of a proxied server response.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_redirect
 	// Default: off
@@ -125,13 +139,40 @@ type Backend struct {
 	// Default 3
 	UpstreamHashBySubsetSize int `json:"upstream-hash-by-subset-size"`
 
+	// Activates the cache for connections to upstream servers.
+	// The connections parameter sets the maximum number of idle keepalive connections to
+	// upstream servers that are preserved in the cache of each worker process. When this
+	// number is exceeded, the least recently used connections are closed.
+	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive
+	UpstreamKeepaliveConnections int `json:"upstream-keepalive-connections,omitempty"`
+
+	// Sets a timeout during which an idle keepalive connection to an upstream server will stay open.
+	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_timeout
+	UpstreamKeepaliveTimeout int `json:"upstream-keepalive-timeout,omitempty"`
+
+	// Sets the maximum number of requests that can be served through one keepalive connection.
+	// After the maximum number of requests is made, the connection is closed.
+	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_requests
+	UpstreamKeepaliveRequests int `json:"upstream-keepalive-requests,omitempty"`
+
 	// Let's us choose a load balancing algorithm per ingress
 	LoadBalancing string `json:"load-balance"`
 
+	// Uses the Service ClusterIP and port as the single upstream server instead of
+	// the individual Endpoints, leaving the load balancing to kube-proxy
+	// Default: false
+	ServiceUpstream bool `json:"service-upstream"`
+
 	// WhitelistSourceRange allows limiting access to certain client addresses
 	// http://nginx.org/en/docs/http/ngx_http_access_module.html
 	WhitelistSourceRange []string `json:"whitelist-source-range,-"`
 
+	// MergeWhitelistSourceRange adds the addresses of WhitelistSourceRange
+	// to the ones of the whitelist-source-range annotation, instead of
+	// using them only when the annotation is not present
+	// Default: false
+	MergeWhitelistSourceRange bool `json:"merge-whitelist-source-range"`
+
 	// Limits the rate of response transmission to a client.
 	// The rate is specified in bytes per second. The zero value disables rate limiting.
 	// The limit is set per a request, and so if a client simultaneously opens two connections,
@@ -143,7 +184,47 @@ type Backend struct {
 	// http://nginx.org/en/docs/http/ngx_http_core_module.html#limit_rate_after
 	LimitRateAfter int `json:"limit-rate-after"`
 
+	// Limits the number of concurrent connections from a single client address
+	// to the Ingress rules without the limit-connections annotation.
+	// The zero value disables the limit.
+	// http://nginx.org/en/docs/http/ngx_http_limit_conn_module.html#limit_conn
+	LimitConnections int `json:"limit-connections"`
+
 	// Enables or disables buffering of responses from the proxied server.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffering
 	ProxyBuffering string `json:"proxy-buffering"`
+
+	// Enables or disables the HTTP/2 support in secure connections
+	// http://nginx.org/en/docs/http/ngx_http_v2_module.html
+	// Default: true
+	UseHTTP2 bool `json:"use-http2,omitempty"`
+
+	// Enables or disables QUIC listeners and the advertisement of HTTP/3
+	// using the Alt-Svc header. Requires NGINX to be built with the HTTP/3 module
+	// http://nginx.org/en/docs/http/ngx_http_v3_module.html
+	// Default: false
+	UseHTTP3 bool `json:"use-http3"`
+
+	// Enables automatic conversion of preload links specified in the "Link"
+	// response header fields into push requests
+	// http://nginx.org/en/docs/http/ngx_http_v2_module.html#http2_push_preload
+	// Default: false
+	HTTP2PushPreload bool `json:"http2-push-preload"`
+
+	// Enables or disables the header HSTS in servers running SSL
+	HSTS bool `json:"hsts,omitempty"`
+
+	// Enables or disables the use of HSTS in all the subdomains of the servername
+	// Default: true
+	HSTSIncludeSubdomains bool `json:"hsts-include-subdomains,omitempty"`
+
+	// HTTP Strict Transport Security (often abbreviated as HSTS) is a security feature (HTTP header)
+	// that tell browsers that it should only be communicated with using HTTPS, instead of using HTTP.
+	// https://developer.mozilla.org/en-US/docs/Web/Security/HTTP_strict_transport_security
+	// max-age is the time, in seconds, that the browser should remember that this site is only to be
+	// accessed using HTTPS.
+	HSTSMaxAge string `json:"hsts-max-age,omitempty"`
+
+	// Enables or disables the preload attribute in HSTS feature
+	HSTSPreload bool `json:"hsts-preload,omitempty"`
 }
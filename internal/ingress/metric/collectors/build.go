@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+	"k8s.io/ingress-nginx/version"
+)
+
+// NewBuildInfo creates a new prometheus collector exposing the release of
+// the controller and the version and modules of NGINX as labels of a gauge
+// set to 1. The NGINX labels are empty when its build information is nil.
+func NewBuildInfo(pod, namespace, class string, ngx *nginx.BuildInfo) prometheus.Collector {
+	ngxVersion := ""
+	ngxModules := ""
+	if ngx != nil {
+		ngxVersion = ngx.Version
+		ngxModules = strings.Join(ngx.Modules, ",")
+	}
+
+	buildInfo := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: PrometheusNamespace,
+			Name:      "build_info",
+			Help:      `Release of the controller and version and modules of NGINX`,
+			ConstLabels: prometheus.Labels{
+				"controller_namespace": namespace,
+				"controller_class":     class,
+				"controller_pod":       pod,
+				"release":              version.RELEASE,
+				"build":                version.COMMIT,
+				"repository":           version.REPO,
+				"nginx_version":        ngxVersion,
+				"nginx_modules":        ngxModules,
+			},
+		},
+	)
+	buildInfo.Set(1)
+
+	return buildInfo
+}
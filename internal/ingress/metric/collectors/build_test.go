@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/nginx"
+)
+
+func TestBuildInfo(t *testing.T) {
+	const metadata = `
+		# HELP nginx_ingress_controller_build_info Release of the controller and version and modules of NGINX
+		# TYPE nginx_ingress_controller_build_info gauge
+	`
+
+	cases := []struct {
+		name string
+		ngx  *nginx.BuildInfo
+		want string
+	}{
+		{
+			name: "should expose the NGINX version and modules",
+			ngx: &nginx.BuildInfo{
+				Version: "openresty/1.15.8.1",
+				Modules: []string{"http_ssl_module", "stream"},
+			},
+			want: metadata + `
+				nginx_ingress_controller_build_info{build="UNKNOWN",controller_class="nginx",controller_namespace="default",controller_pod="pod",nginx_modules="http_ssl_module,stream",nginx_version="openresty/1.15.8.1",release="UNKNOWN",repository="UNKNOWN"} 1
+			`,
+		},
+		{
+			name: "should expose empty NGINX labels without build information",
+			want: metadata + `
+				nginx_ingress_controller_build_info{build="UNKNOWN",controller_class="nginx",controller_namespace="default",controller_pod="pod",nginx_modules="",nginx_version="",release="UNKNOWN",repository="UNKNOWN"} 1
+			`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bi := NewBuildInfo("pod", "default", "nginx", c.ngx)
+			reg := prometheus.NewPedanticRegistry()
+			if err := reg.Register(bi); err != nil {
+				t.Errorf("registering collector failed: %s", err)
+			}
+
+			if err := GatherAndCompare(bi, c.want, []string{"nginx_ingress_controller_build_info"}, reg); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+
+			reg.Unregister(bi)
+		})
+	}
+}
@@ -32,6 +32,15 @@ var (
 	sslLabelHost = []string{"namespace", "class", "host"}
 )
 
+// OrphanedIngress describes an Ingress routing traffic to a Service that
+// does not exist or does not have any active Endpoint
+type OrphanedIngress struct {
+	Namespace string
+	Ingress   string
+	// Type is no-service or no-endpoint
+	Type string
+}
+
 // Controller defines base metrics about the ingress controller
 type Controller struct {
 	prometheus.Collector
@@ -39,10 +48,15 @@ type Controller struct {
 	configHash        prometheus.Gauge
 	configSuccess     prometheus.Gauge
 	configSuccessTime prometheus.Gauge
+	configApplied     prometheus.Gauge
 
 	reloadOperation       *prometheus.CounterVec
 	reloadOperationErrors *prometheus.CounterVec
+	configDrift           *prometheus.CounterVec
+	reloadsAvoided        *prometheus.CounterVec
 	sslExpireTime         *prometheus.GaugeVec
+	orphanedIngress       *prometheus.GaugeVec
+	configChecksum        *prometheus.GaugeVec
 
 	constLabels prometheus.Labels
 	labels      prometheus.Labels
@@ -87,6 +101,13 @@ func NewController(pod, namespace, class string) *Controller {
 				Help:        "Timestamp of the last successful configuration reload.",
 				ConstLabels: constLabels,
 			}),
+		configApplied: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "config_last_apply_successful",
+				Help:        "Whether the last attempt to apply a new configuration was successful",
+				ConstLabels: constLabels,
+			}),
 		reloadOperation: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: PrometheusNamespace,
@@ -103,6 +124,24 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			operation,
 		),
+		configDrift: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "config_drift_total",
+				Help:        `Cumulative number of divergences detected between the applied and the running configuration`,
+				ConstLabels: constLabels,
+			},
+			[]string{"source"},
+		),
+		reloadsAvoided: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "reloads_avoided_total",
+				Help:        `Cumulative number of configuration changes applied dynamically that used to require a reload`,
+				ConstLabels: constLabels,
+			},
+			[]string{"reason"},
+		),
 		sslExpireTime: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: PrometheusNamespace,
@@ -112,6 +151,24 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			sslLabelHost,
 		),
+		orphanedIngress: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "orphaned_ingress",
+				Help:        `Ingresses routing traffic to a Service that does not exist (type no-service) or does not have any active Endpoint (type no-endpoint)`,
+				ConstLabels: constLabels,
+			},
+			[]string{"namespace", "ingress", "type"},
+		),
+		configChecksum: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "config_checksum",
+				Help:        `Checksum of the applied configuration, equal in the replicas running the same configuration`,
+				ConstLabels: constLabels,
+			},
+			[]string{"checksum"},
+		),
 	}
 
 	return cm
@@ -127,6 +184,30 @@ func (cm *Controller) IncReloadErrorCount() {
 	cm.reloadOperationErrors.With(cm.constLabels).Inc()
 }
 
+// IncConfigDriftCount increment the configuration drift counter
+func (cm *Controller) IncConfigDriftCount(source string) {
+	cm.configDrift.WithLabelValues(source).Inc()
+}
+
+// IncReloadAvoidedCount increment the counter of avoided reloads
+func (cm *Controller) IncReloadAvoidedCount(reason string) {
+	cm.reloadsAvoided.WithLabelValues(reason).Inc()
+}
+
+// SetOrphanedIngresses replaces the Ingresses reported as orphaned
+func (cm *Controller) SetOrphanedIngresses(orphaned []OrphanedIngress) {
+	cm.orphanedIngress.Reset()
+	for _, o := range orphaned {
+		cm.orphanedIngress.WithLabelValues(o.Namespace, o.Ingress, o.Type).Set(1)
+	}
+}
+
+// SetConfigurationChecksum replaces the checksum of the applied configuration
+func (cm *Controller) SetConfigurationChecksum(checksum string) {
+	cm.configChecksum.Reset()
+	cm.configChecksum.WithLabelValues(checksum).Set(1)
+}
+
 // ConfigSuccess set a boolean flag according to the output of the controller configuration reload
 func (cm *Controller) ConfigSuccess(hash uint64, success bool) {
 	if success {
@@ -142,14 +223,30 @@ func (cm *Controller) ConfigSuccess(hash uint64, success bool) {
 	cm.configHash.Set(0)
 }
 
+// ConfigApplied set a boolean flag according to the result of the last
+// attempt to apply a new configuration (test, reload and dynamic update)
+func (cm *Controller) ConfigApplied(success bool) {
+	if success {
+		cm.configApplied.Set(1)
+		return
+	}
+
+	cm.configApplied.Set(0)
+}
+
 // Describe implements prometheus.Collector
 func (cm Controller) Describe(ch chan<- *prometheus.Desc) {
 	cm.configHash.Describe(ch)
 	cm.configSuccess.Describe(ch)
 	cm.configSuccessTime.Describe(ch)
+	cm.configApplied.Describe(ch)
 	cm.reloadOperation.Describe(ch)
 	cm.reloadOperationErrors.Describe(ch)
+	cm.configDrift.Describe(ch)
+	cm.reloadsAvoided.Describe(ch)
 	cm.sslExpireTime.Describe(ch)
+	cm.orphanedIngress.Describe(ch)
+	cm.configChecksum.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -157,9 +254,14 @@ func (cm Controller) Collect(ch chan<- prometheus.Metric) {
 	cm.configHash.Collect(ch)
 	cm.configSuccess.Collect(ch)
 	cm.configSuccessTime.Collect(ch)
+	cm.configApplied.Collect(ch)
 	cm.reloadOperation.Collect(ch)
 	cm.reloadOperationErrors.Collect(ch)
+	cm.configDrift.Collect(ch)
+	cm.reloadsAvoided.Collect(ch)
 	cm.sslExpireTime.Collect(ch)
+	cm.orphanedIngress.Collect(ch)
+	cm.configChecksum.Collect(ch)
 }
 
 // SetSSLExpireTime sets the expiration time of SSL Certificates
@@ -100,6 +100,51 @@ func TestControllerCounters(t *testing.T) {
 			`,
 			metrics: []string{"nginx_ingress_controller_ssl_expire_time_seconds"},
 		},
+		{
+			name: "should replace the orphaned Ingresses",
+			test: func(cm *Controller) {
+				cm.SetOrphanedIngresses([]OrphanedIngress{
+					{Namespace: "default", Ingress: "old", Type: "no-service"},
+				})
+				cm.SetOrphanedIngresses([]OrphanedIngress{
+					{Namespace: "default", Ingress: "demo", Type: "no-endpoint"},
+					{Namespace: "default", Ingress: "demo", Type: "no-service"},
+				})
+			},
+			want: `
+				# HELP nginx_ingress_controller_orphaned_ingress Ingresses routing traffic to a Service that does not exist (type no-service) or does not have any active Endpoint (type no-endpoint)
+				# TYPE nginx_ingress_controller_orphaned_ingress gauge
+				nginx_ingress_controller_orphaned_ingress{controller_class="nginx",controller_namespace="default",controller_pod="pod",ingress="demo",namespace="default",type="no-endpoint"} 1
+				nginx_ingress_controller_orphaned_ingress{controller_class="nginx",controller_namespace="default",controller_pod="pod",ingress="demo",namespace="default",type="no-service"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_orphaned_ingress"},
+		},
+		{
+			name: "should count the avoided reloads",
+			test: func(cm *Controller) {
+				cm.IncReloadAvoidedCount("ssl-certificate")
+				cm.IncReloadAvoidedCount("ssl-certificate")
+			},
+			want: `
+				# HELP nginx_ingress_controller_reloads_avoided_total Cumulative number of configuration changes applied dynamically that used to require a reload
+				# TYPE nginx_ingress_controller_reloads_avoided_total counter
+				nginx_ingress_controller_reloads_avoided_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="ssl-certificate"} 2
+			`,
+			metrics: []string{"nginx_ingress_controller_reloads_avoided_total"},
+		},
+		{
+			name: "should replace the configuration checksum",
+			test: func(cm *Controller) {
+				cm.SetConfigurationChecksum("0000000000000001")
+				cm.SetConfigurationChecksum("2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881")
+			},
+			want: `
+				# HELP nginx_ingress_controller_config_checksum Checksum of the applied configuration, equal in the replicas running the same configuration
+				# TYPE nginx_ingress_controller_config_checksum gauge
+				nginx_ingress_controller_config_checksum{checksum="2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881",controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_config_checksum"},
+		},
 	}
 
 	for _, c := range cases {
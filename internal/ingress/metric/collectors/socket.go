@@ -17,13 +17,12 @@ limitations under the License.
 package collectors
 
 import (
-	"fmt"
+	"bytes"
 	"io"
-	"io/ioutil"
 	"net"
 	"os"
+	"sync"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
@@ -53,6 +52,11 @@ type socketData struct {
 	Ingress   string `json:"ingress"`
 	Service   string `json:"service"`
 	Path      string `json:"path"`
+
+	ModSecurityBlocked      bool `json:"modsecurityBlocked"`
+	GlobalRateLimitExceeded bool `json:"globalRateLimitExceeded"`
+	RequestBodyTooLarge     bool `json:"requestBodyTooLarge"`
+	UpstreamRetried         bool `json:"upstreamRetried"`
 }
 
 // SocketCollector stores prometheus metrics and ingress meta-data
@@ -71,15 +75,218 @@ type SocketCollector struct {
 
 	requests *prometheus.CounterVec
 
+	modSecurityBlocked *prometheus.CounterVec
+
+	globalRateLimitExceeded *prometheus.CounterVec
+
+	requestBodyTooLarge *prometheus.CounterVec
+
+	upstreamRetried *prometheus.CounterVec
+
 	listener net.Listener
 
-	metricMapping map[string]interface{}
+	requestTags []string
+
+	// ingresses indexes the label sets of the metrics of each ingress
+	ingresses *ingressIndex
 
 	hosts sets.String
 
 	metricsPerHost bool
+
+	// values interns the label values of the observations
+	values *labelValues
+}
+
+// maxInternedValues is the number of label values interned before the
+// table is reset, to release the values not used anymore
+const maxInternedValues = 65536
+
+// labelValues keeps a single copy of the label values of the observations,
+// shared by the series of every metric, and limits the number of hosts
+// used as label.
+type labelValues struct {
+	sync.Mutex
+
+	values map[string]string
+
+	// hosts contains the hosts used as label, up to maxHosts (no limit when zero)
+	hosts    map[string]struct{}
+	maxHosts int
+}
+
+func newLabelValues(maxHosts int) *labelValues {
+	return &labelValues{
+		values:   make(map[string]string),
+		hosts:    make(map[string]struct{}),
+		maxHosts: maxHosts,
+	}
+}
+
+// value returns the shared copy of a label value. The caller must hold the lock.
+func (lv *labelValues) value(b []byte) string {
+	if v, ok := lv.values[string(b)]; ok {
+		return v
+	}
+
+	if len(lv.values) >= maxInternedValues {
+		lv.values = make(map[string]string)
+	}
+
+	s := string(b)
+	lv.values[s] = s
+	return s
+}
+
+// host returns the value of the host label of an observation, empty once
+// the limit of hosts used as label is reached
+func (lv *labelValues) host(host string) string {
+	lv.Lock()
+	defer lv.Unlock()
+
+	if _, ok := lv.hosts[host]; ok {
+		return host
+	}
+
+	if lv.maxHosts > 0 && len(lv.hosts) >= lv.maxHosts {
+		klog.V(3).Infof("reached the limit of %v hosts, recording metrics for host %v without the host label", lv.maxHosts, host)
+		return ""
+	}
+
+	lv.hosts[host] = struct{}{}
+	return host
+}
+
+// setHosts releases the hosts not served anymore
+func (lv *labelValues) setHosts(hosts sets.String) {
+	lv.Lock()
+	defer lv.Unlock()
+
+	for host := range lv.hosts {
+		if !hosts.Has(host) {
+			delete(lv.hosts, host)
+		}
+	}
+}
+
+// ingressIndex contains the label sets observed for the metrics of each
+// ingress (namespace/name), to delete them directly from the vectors when
+// the ingress is removed instead of gathering every series in the registry.
+type ingressIndex struct {
+	sync.Mutex
+
+	series map[string]*ingressSeries
+
+	// key is the buffer used to build the keys of the maps
+	key []byte
+}
+
+// ingressSeries contains the label sets observed for the metrics of an
+// ingress, indexed by the values of the labels
+type ingressSeries struct {
+	// labels of the metrics using requestTags
+	request map[string]prometheus.Labels
+	// labels of the metrics per ingress and status
+	collector map[string]prometheus.Labels
+	// labels of the metrics per ingress and service
+	upstream map[string]prometheus.Labels
+}
+
+func newIngressIndex() *ingressIndex {
+	return &ingressIndex{
+		series: make(map[string]*ingressSeries),
+	}
+}
+
+// add records the label sets used by the metrics of an ingress. The label
+// sets are copied the first time they are observed, the maps can be reused.
+func (ii *ingressIndex) add(namespace, ingress string, requestTags []string, requestLabels prometheus.Labels, collectorKey string, collectorLabels prometheus.Labels, upstreamKey string, upstreamLabels prometheus.Labels) {
+	ii.Lock()
+	defer ii.Unlock()
+
+	ii.key = append(append(append(ii.key[:0], namespace...), '/'), ingress...)
+	is, ok := ii.series[string(ii.key)]
+	if !ok {
+		is = &ingressSeries{
+			request:   make(map[string]prometheus.Labels),
+			collector: make(map[string]prometheus.Labels),
+			upstream:  make(map[string]prometheus.Labels),
+		}
+		ii.series[string(ii.key)] = is
+	}
+
+	ii.key = appendLabelsKey(ii.key[:0], requestLabels, requestTags)
+	if _, ok := is.request[string(ii.key)]; !ok {
+		is.request[string(ii.key)] = copyLabels(requestLabels)
+	}
+	if _, ok := is.collector[collectorKey]; !ok {
+		is.collector[collectorKey] = copyLabels(collectorLabels)
+	}
+	if _, ok := is.upstream[upstreamKey]; !ok {
+		is.upstream[upstreamKey] = copyLabels(upstreamLabels)
+	}
+}
+
+// remove deletes an ingress from the index and returns its label sets
+func (ii *ingressIndex) remove(ingress string) (*ingressSeries, bool) {
+	ii.Lock()
+	defer ii.Unlock()
+
+	is, ok := ii.series[ingress]
+	if ok {
+		delete(ii.series, ingress)
+	}
+
+	return is, ok
+}
+
+// appendLabelsKey appends to key the values of the labels, in the order of
+// names, to be used as key in a map
+func appendLabelsKey(key []byte, labels prometheus.Labels, names []string) []byte {
+	for i, name := range names {
+		if i > 0 {
+			key = append(key, '\xff')
+		}
+		key = append(key, labels[name]...)
+	}
+
+	return key
+}
+
+func copyLabels(labels prometheus.Labels) prometheus.Labels {
+	c := make(prometheus.Labels, len(labels))
+	for name, value := range labels {
+		c[name] = value
+	}
+
+	return c
+}
+
+// statsLabels contains the label maps reused by the observations of a batch
+type statsLabels struct {
+	request   prometheus.Labels
+	collector prometheus.Labels
+	latency   prometheus.Labels
 }
 
+var (
+	labelsPool = sync.Pool{
+		New: func() interface{} {
+			return &statsLabels{
+				request:   make(prometheus.Labels, len(requestTags)+1),
+				collector: make(prometheus.Labels, 3),
+				latency:   make(prometheus.Labels, 3),
+			}
+		},
+	}
+
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
 var (
 	requestTags = []string{
 		"status",
@@ -93,16 +300,21 @@ var (
 	}
 )
 
+// SocketPath is the path of the unix socket receiving the statistics
+// sent by the Lua monitor
+const SocketPath = "/tmp/prometheus-nginx.socket"
+
 // NewSocketCollector creates a new SocketCollector instance using
-// the ingress watch namespace and class used by the controller
-func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*SocketCollector, error) {
-	socket := "/tmp/prometheus-nginx.socket"
-	listener, err := net.Listen("unix", socket)
+// the ingress watch namespace and class used by the controller.
+// When metricsPerHost is set, maxHosts limits the number of hosts used as
+// label (no limit when zero).
+func NewSocketCollector(pod, namespace, class string, metricsPerHost bool, maxHosts int) (*SocketCollector, error) {
+	listener, err := net.Listen("unix", SocketPath)
 	if err != nil {
 		return nil, err
 	}
 
-	err = os.Chmod(socket, 0777)
+	err = os.Chmod(SocketPath, 0777)
 	if err != nil {
 		return nil, err
 	}
@@ -121,8 +333,14 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 	sc := &SocketCollector{
 		listener: listener,
 
+		requestTags: requestTags,
+
+		ingresses: newIngressIndex(),
+
 		metricsPerHost: metricsPerHost,
 
+		values: newLabelValues(maxHosts),
+
 		responseTime: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:        "response_duration_seconds",
@@ -172,6 +390,46 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 			[]string{"ingress", "namespace", "status"},
 		),
 
+		modSecurityBlocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "modsecurity_blocked_requests",
+				Help:        "The total number of client requests blocked by ModSecurity.",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
+		globalRateLimitExceeded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "global_rate_limit_exceeded_requests",
+				Help:        "The total number of client requests rejected by a global rate limit.",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
+		requestBodyTooLarge: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "request_body_too_large_requests",
+				Help:        "The total number of client requests rejected because of the size of the body.",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
+		upstreamRetried: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "upstream_retried_requests",
+				Help:        "The total number of client requests retried in a different upstream server.",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
 		bytesSent: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:        "bytes_sent",
@@ -194,62 +452,57 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 		),
 	}
 
-	sc.metricMapping = map[string]interface{}{
-		prometheus.BuildFQName(PrometheusNamespace, "", "request_duration_seconds"): sc.requestTime,
-		prometheus.BuildFQName(PrometheusNamespace, "", "request_size"):             sc.requestLength,
-
-		prometheus.BuildFQName(PrometheusNamespace, "", "response_duration_seconds"): sc.responseTime,
-		prometheus.BuildFQName(PrometheusNamespace, "", "response_size"):             sc.responseLength,
-
-		prometheus.BuildFQName(PrometheusNamespace, "", "bytes_sent"): sc.bytesSent,
-
-		prometheus.BuildFQName(PrometheusNamespace, "", "ingress_upstream_latency_seconds"): sc.upstreamLatency,
-	}
-
 	return sc, nil
 }
 
 func (sc *SocketCollector) handleMessage(msg []byte) {
-	klog.V(5).Infof("msg: %v", string(msg))
+	if klog.V(5) {
+		klog.Infof("msg: %v", string(msg))
+	}
 
-	// Unmarshal bytes
-	var statsBatch []socketData
-	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(msg, &statsBatch)
+	decoder, err := decodeStats(msg, sc.values)
 	if err != nil {
 		klog.Errorf("Unexpected error deserializing JSON payload: %v. Payload:\n%v", err, string(msg))
 		return
 	}
+	defer releaseDecoder(decoder)
+
+	labels := labelsPool.Get().(*statsLabels)
+	defer labelsPool.Put(labels)
 
-	for _, stats := range statsBatch {
+	for i := range decoder.batch {
+		stats := &decoder.batch[i]
 		if !sc.hosts.Has(stats.Host) {
 			klog.V(3).Infof("skiping metric for host %v that is not being served", stats.Host)
 			continue
 		}
 
 		// Note these must match the order in requestTags at the top
-		requestLabels := prometheus.Labels{
-			"status":    stats.Status,
-			"method":    stats.Method,
-			"path":      stats.Path,
-			"namespace": stats.Namespace,
-			"ingress":   stats.Ingress,
-			"service":   stats.Service,
-		}
+		requestLabels := labels.request
+		requestLabels["status"] = stats.Status
+		requestLabels["method"] = stats.Method
+		requestLabels["path"] = stats.Path
+		requestLabels["namespace"] = stats.Namespace
+		requestLabels["ingress"] = stats.Ingress
+		requestLabels["service"] = stats.Service
 		if sc.metricsPerHost {
-			requestLabels["host"] = stats.Host
+			requestLabels["host"] = sc.values.host(stats.Host)
 		}
 
-		collectorLabels := prometheus.Labels{
-			"namespace": stats.Namespace,
-			"ingress":   stats.Ingress,
-			"status":    stats.Status,
-		}
+		collectorLabels := labels.collector
+		collectorLabels["namespace"] = stats.Namespace
+		collectorLabels["ingress"] = stats.Ingress
+		collectorLabels["status"] = stats.Status
 
-		latencyLabels := prometheus.Labels{
-			"namespace": stats.Namespace,
-			"ingress":   stats.Ingress,
-			"service":   stats.Service,
-		}
+		latencyLabels := labels.latency
+		latencyLabels["namespace"] = stats.Namespace
+		latencyLabels["ingress"] = stats.Ingress
+		latencyLabels["service"] = stats.Service
+
+		sc.ingresses.add(stats.Namespace, stats.Ingress,
+			sc.requestTags, requestLabels,
+			stats.Status, collectorLabels,
+			stats.Service, latencyLabels)
 
 		requestsMetric, err := sc.requests.GetMetricWith(collectorLabels)
 		if err != nil {
@@ -258,6 +511,42 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			requestsMetric.Inc()
 		}
 
+		if stats.ModSecurityBlocked {
+			blockedMetric, err := sc.modSecurityBlocked.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching ModSecurity blocked requests metric: %v", err)
+			} else {
+				blockedMetric.Inc()
+			}
+		}
+
+		if stats.GlobalRateLimitExceeded {
+			exceededMetric, err := sc.globalRateLimitExceeded.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching global rate limit exceeded requests metric: %v", err)
+			} else {
+				exceededMetric.Inc()
+			}
+		}
+
+		if stats.RequestBodyTooLarge {
+			tooLargeMetric, err := sc.requestBodyTooLarge.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching request body too large metric: %v", err)
+			} else {
+				tooLargeMetric.Inc()
+			}
+		}
+
+		if stats.UpstreamRetried {
+			retriedMetric, err := sc.upstreamRetried.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching upstream retried requests metric: %v", err)
+			} else {
+				retriedMetric.Inc()
+			}
+		}
+
 		if stats.Latency != -1 {
 			latencyMetric, err := sc.upstreamLatency.GetMetricWith(latencyLabels)
 			if err != nil {
@@ -332,66 +621,44 @@ func (sc *SocketCollector) Stop() {
 // RemoveMetrics deletes prometheus metrics from prometheus for ingresses and
 // host that are not available anymore.
 // Ref: https://godoc.org/github.com/prometheus/client_golang/prometheus#CounterVec.Delete
-func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus.Gatherer) {
-	mfs, err := registry.Gather()
-	if err != nil {
-		klog.Errorf("Error gathering metrics: %v", err)
-		return
-	}
-
-	// 1. remove metrics of removed ingresses
+func (sc *SocketCollector) RemoveMetrics(ingresses []string) {
 	klog.V(2).Infof("removing ingresses %v from metrics", ingresses)
-	for _, mf := range mfs {
-		metricName := mf.GetName()
-		metric, ok := sc.metricMapping[metricName]
+	for _, ingKey := range ingresses {
+		is, ok := sc.ingresses.remove(ingKey)
 		if !ok {
 			continue
 		}
 
-		toRemove := sets.NewString(ingresses...)
-		for _, m := range mf.GetMetric() {
-			labels := make(map[string]string, len(m.GetLabel()))
-			for _, labelPair := range m.GetLabel() {
-				labels[*labelPair.Name] = *labelPair.Value
-			}
-
-			// remove labels that are constant
-			deleteConstants(labels)
-
-			ns, ok := labels["namespace"]
-			if !ok {
-				continue
-			}
-			ing, ok := labels["ingress"]
-			if !ok {
-				continue
-			}
-
-			ingKey := fmt.Sprintf("%v/%v", ns, ing)
-			if !toRemove.Has(ingKey) {
-				continue
+		klog.V(2).Infof("Removing prometheus metrics for ingress %v", ingKey)
+		for _, labels := range is.request {
+			for _, h := range []*prometheus.HistogramVec{
+				sc.requestTime,
+				sc.requestLength,
+				sc.responseTime,
+				sc.responseLength,
+				sc.bytesSent,
+			} {
+				h.Delete(labels)
 			}
+		}
 
-			klog.V(2).Infof("Removing prometheus metric from histogram %v for ingress %v", metricName, ingKey)
+		for _, labels := range is.collector {
+			sc.requests.Delete(labels)
+		}
 
-			h, ok := metric.(*prometheus.HistogramVec)
-			if ok {
-				removed := h.Delete(labels)
-				if !removed {
-					klog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
-				}
+		for _, labels := range is.upstream {
+			for _, c := range []*prometheus.CounterVec{
+				sc.modSecurityBlocked,
+				sc.globalRateLimitExceeded,
+				sc.requestBodyTooLarge,
+				sc.upstreamRetried,
+			} {
+				c.Delete(labels)
 			}
 
-			s, ok := metric.(*prometheus.SummaryVec)
-			if ok {
-				removed := s.Delete(labels)
-				if !removed {
-					klog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
-				}
-			}
+			sc.upstreamLatency.Delete(labels)
 		}
 	}
-
 }
 
 // Describe implements prometheus.Collector
@@ -401,6 +668,11 @@ func (sc SocketCollector) Describe(ch chan<- *prometheus.Desc) {
 
 	sc.requests.Describe(ch)
 
+	sc.modSecurityBlocked.Describe(ch)
+	sc.globalRateLimitExceeded.Describe(ch)
+	sc.requestBodyTooLarge.Describe(ch)
+	sc.upstreamRetried.Describe(ch)
+
 	sc.upstreamLatency.Describe(ch)
 
 	sc.responseTime.Describe(ch)
@@ -416,6 +688,11 @@ func (sc SocketCollector) Collect(ch chan<- prometheus.Metric) {
 
 	sc.requests.Collect(ch)
 
+	sc.modSecurityBlocked.Collect(ch)
+	sc.globalRateLimitExceeded.Collect(ch)
+	sc.requestBodyTooLarge.Collect(ch)
+	sc.upstreamRetried.Collect(ch)
+
 	sc.upstreamLatency.Collect(ch)
 
 	sc.responseTime.Collect(ch)
@@ -428,17 +705,24 @@ func (sc SocketCollector) Collect(ch chan<- prometheus.Metric) {
 // This set of hostnames is used to filter the metrics to be exposed
 func (sc *SocketCollector) SetHosts(hosts sets.String) {
 	sc.hosts = hosts
+	sc.values.setHosts(hosts)
 }
 
 // handleMessages process the content received in a network connection
+// The content is read in a pooled buffer, fn must not retain it.
 func handleMessages(conn io.ReadCloser, fn func([]byte)) {
 	defer conn.Close()
-	data, err := ioutil.ReadAll(conn)
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+
+	buf.Reset()
+	_, err := buf.ReadFrom(conn)
 	if err != nil {
 		return
 	}
 
-	fn(data)
+	fn(buf.Bytes())
 }
 
 func deleteConstants(labels prometheus.Labels) {
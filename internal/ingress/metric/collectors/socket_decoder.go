@@ -0,0 +1,428 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+// statsDecoder decodes the batches of statistics sent by the Lua monitor, a
+// JSON array of flat objects. The batch and the buffer of unescaped strings
+// are reused, and the strings are interned, so decoding a record does not
+// allocate memory once the decoder is warm.
+type statsDecoder struct {
+	data []byte
+	pos  int
+
+	// scratch contains the last string read, unescaped
+	scratch []byte
+
+	batch []socketData
+}
+
+var decoderPool = sync.Pool{
+	New: func() interface{} {
+		return &statsDecoder{}
+	},
+}
+
+// decodeStats decodes a batch of statistics, interning the strings with
+// values. The batch is valid until the decoder is returned with releaseDecoder.
+func decodeStats(data []byte, values *labelValues) (*statsDecoder, error) {
+	d := decoderPool.Get().(*statsDecoder)
+	d.data = data
+	d.pos = 0
+
+	values.Lock()
+	err := d.decode(values)
+	values.Unlock()
+	if err != nil {
+		releaseDecoder(d)
+		return nil, err
+	}
+
+	if len(d.batch) > config.MaxBatchSize {
+		klog.Warningf("Dropping %v records of a batch larger than %v", len(d.batch)-config.MaxBatchSize, config.MaxBatchSize)
+		for i := config.MaxBatchSize; i < len(d.batch); i++ {
+			d.batch[i] = socketData{}
+		}
+		d.batch = d.batch[:config.MaxBatchSize]
+	}
+
+	return d, nil
+}
+
+// releaseDecoder returns a decoder to the pool once its batch is processed
+func releaseDecoder(d *statsDecoder) {
+	// values of a previous batch must not leak in fields absent from the next one
+	for i := range d.batch {
+		d.batch[i] = socketData{}
+	}
+
+	d.batch = d.batch[:0]
+	d.data = nil
+	decoderPool.Put(d)
+}
+
+func (d *statsDecoder) decode(values *labelValues) error {
+	if err := d.expect('['); err != nil {
+		return err
+	}
+
+	if !d.consume(']') {
+		for {
+			d.batch = append(d.batch, socketData{})
+			if err := d.decodeObject(&d.batch[len(d.batch)-1], values); err != nil {
+				return err
+			}
+
+			if d.consume(',') {
+				continue
+			}
+			if err := d.expect(']'); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	d.skipWhitespace()
+	if d.pos != len(d.data) {
+		return d.errorf("unexpected data after the batch")
+	}
+
+	return nil
+}
+
+func (d *statsDecoder) decodeObject(stats *socketData, values *labelValues) error {
+	if err := d.expect('{'); err != nil {
+		return err
+	}
+
+	if d.consume('}') {
+		return nil
+	}
+
+	for {
+		if err := d.readString(); err != nil {
+			return err
+		}
+		if err := d.expect(':'); err != nil {
+			return err
+		}
+
+		var err error
+		switch string(d.scratch) {
+		case "host":
+			stats.Host, err = d.readLabel(values)
+		case "status":
+			stats.Status, err = d.readLabel(values)
+		case "method":
+			stats.Method, err = d.readLabel(values)
+		case "namespace":
+			stats.Namespace, err = d.readLabel(values)
+		case "ingress":
+			stats.Ingress, err = d.readLabel(values)
+		case "service":
+			stats.Service, err = d.readLabel(values)
+		case "path":
+			stats.Path, err = d.readLabel(values)
+		case "responseLength":
+			stats.ResponseLength, err = d.readNumber()
+		case "requestLength":
+			stats.RequestLength, err = d.readNumber()
+		case "requestTime":
+			stats.RequestTime, err = d.readNumber()
+		case "upstreamLatency":
+			stats.Latency, err = d.readNumber()
+		case "upstreamResponseLength":
+			stats.upstream.ResponseLength, err = d.readNumber()
+		case "upstreamResponseTime":
+			stats.upstream.ResponseTime, err = d.readNumber()
+		case "modsecurityBlocked":
+			stats.ModSecurityBlocked, err = d.readBool()
+		case "globalRateLimitExceeded":
+			stats.GlobalRateLimitExceeded, err = d.readBool()
+		case "requestBodyTooLarge":
+			stats.RequestBodyTooLarge, err = d.readBool()
+		case "upstreamRetried":
+			stats.UpstreamRetried, err = d.readBool()
+		default:
+			err = d.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+
+		if d.consume(',') {
+			continue
+		}
+		return d.expect('}')
+	}
+}
+
+// readLabel reads a string (or null) and returns its interned copy
+func (d *statsDecoder) readLabel(values *labelValues) (string, error) {
+	if d.consumeLiteral("null") {
+		return "", nil
+	}
+
+	if err := d.readString(); err != nil {
+		return "", err
+	}
+
+	return values.value(d.scratch), nil
+}
+
+// readString reads a string into scratch
+func (d *statsDecoder) readString() error {
+	if err := d.expect('"'); err != nil {
+		return err
+	}
+
+	d.scratch = d.scratch[:0]
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		d.pos++
+
+		switch {
+		case c == '"':
+			return nil
+		case c == '\\':
+			if err := d.readEscape(); err != nil {
+				return err
+			}
+		case c < ' ':
+			return d.errorf("invalid control character %q in string", c)
+		default:
+			d.scratch = append(d.scratch, c)
+		}
+	}
+
+	return d.errorf("unexpected end of string")
+}
+
+func (d *statsDecoder) readEscape() error {
+	if d.pos >= len(d.data) {
+		return d.errorf("unexpected end of string")
+	}
+
+	c := d.data[d.pos]
+	d.pos++
+
+	switch c {
+	case '"', '\\', '/':
+		d.scratch = append(d.scratch, c)
+	case 'b':
+		d.scratch = append(d.scratch, '\b')
+	case 'f':
+		d.scratch = append(d.scratch, '\f')
+	case 'n':
+		d.scratch = append(d.scratch, '\n')
+	case 'r':
+		d.scratch = append(d.scratch, '\r')
+	case 't':
+		d.scratch = append(d.scratch, '\t')
+	case 'u':
+		r, err := d.readRune()
+		if err != nil {
+			return err
+		}
+
+		if utf16.IsSurrogate(r) {
+			r2 := utf8.RuneError
+			if d.pos+1 < len(d.data) && d.data[d.pos] == '\\' && d.data[d.pos+1] == 'u' {
+				d.pos += 2
+				r2, err = d.readRune()
+				if err != nil {
+					return err
+				}
+			}
+			r = utf16.DecodeRune(r, r2)
+		}
+
+		var buf [utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], r)
+		d.scratch = append(d.scratch, buf[:n]...)
+	default:
+		return d.errorf("invalid escape character %q", c)
+	}
+
+	return nil
+}
+
+// readRune reads the four hexadecimal digits of an \u escape
+func (d *statsDecoder) readRune() (rune, error) {
+	if d.pos+4 > len(d.data) {
+		return 0, d.errorf("unexpected end of string")
+	}
+
+	var r rune
+	for _, c := range d.data[d.pos : d.pos+4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, d.errorf("invalid unicode escape")
+		}
+		r = r*16 + rune(c)
+	}
+	d.pos += 4
+
+	return r, nil
+}
+
+func (d *statsDecoder) readNumber() (float64, error) {
+	d.skipWhitespace()
+	if d.consumeLiteral("null") {
+		return 0, nil
+	}
+
+	start := d.pos
+	for d.pos < len(d.data) && isNumberChar(d.data[d.pos]) {
+		d.pos++
+	}
+	if start == d.pos {
+		return 0, d.errorf("expected a number")
+	}
+
+	n, err := strconv.ParseFloat(string(d.data[start:d.pos]), 64)
+	if err != nil {
+		return 0, d.errorf("invalid number %q", d.data[start:d.pos])
+	}
+
+	return n, nil
+}
+
+func (d *statsDecoder) readBool() (bool, error) {
+	switch {
+	case d.consumeLiteral("true"):
+		return true, nil
+	case d.consumeLiteral("false"), d.consumeLiteral("null"):
+		return false, nil
+	}
+
+	return false, d.errorf("expected a boolean")
+}
+
+// skipValue skips the value of a field not used by the collector
+func (d *statsDecoder) skipValue() error {
+	d.skipWhitespace()
+	if d.pos >= len(d.data) {
+		return d.errorf("unexpected end of data")
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == '"':
+		return d.readString()
+	case c == '{' || c == '[':
+		return d.skipComposite()
+	case c == 't' || c == 'f' || c == 'n':
+		if d.consumeLiteral("true") || d.consumeLiteral("false") || d.consumeLiteral("null") {
+			return nil
+		}
+		return d.errorf("invalid literal")
+	default:
+		_, err := d.readNumber()
+		return err
+	}
+}
+
+// skipComposite skips an object or an array, tracking the nesting level
+func (d *statsDecoder) skipComposite() error {
+	depth := 0
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case '"':
+			if err := d.readString(); err != nil {
+				return err
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+		d.pos++
+
+		if depth == 0 {
+			return nil
+		}
+	}
+
+	return d.errorf("unexpected end of data")
+}
+
+func (d *statsDecoder) skipWhitespace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips the next character when it is c
+func (d *statsDecoder) consume(c byte) bool {
+	d.skipWhitespace()
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+
+	return false
+}
+
+func (d *statsDecoder) consumeLiteral(literal string) bool {
+	d.skipWhitespace()
+	if len(d.data)-d.pos >= len(literal) && string(d.data[d.pos:d.pos+len(literal)]) == literal {
+		d.pos += len(literal)
+		return true
+	}
+
+	return false
+}
+
+func (d *statsDecoder) expect(c byte) error {
+	if !d.consume(c) {
+		return d.errorf("expected %q", c)
+	}
+
+	return nil
+}
+
+func (d *statsDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %v: %v", d.pos, fmt.Sprintf(format, args...))
+}
+
+func isNumberChar(c byte) bool {
+	return ('0' <= c && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/test/metrics-load/synthetic"
+)
+
+func TestDecodeStats(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		want    []socketData
+		wantErr bool
+	}{
+		{
+			name: "empty batch",
+			data: ` [ ] `,
+		},
+		{
+			name: "records with unknown fields",
+			data: `[{
+				"host":"testshop.com",
+				"status":"200",
+				"bytesSent":150.0,
+				"method":"GET",
+				"path":"\/admin",
+				"requestLength":300.0,
+				"requestTime":60.0,
+				"responseLength":-1,
+				"upstreamName":"test-upstream",
+				"upstreamLatency":0.01,
+				"upstreamResponseTime":200,
+				"upstreamResponseLength":1e3,
+				"upstreamStatus":"220",
+				"upstreamAddrs":["1.1.1.1:8080",{"weight":null}],
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"modsecurityBlocked":true,
+				"upstreamRetried":null
+			},{"host":"tést😀.com","status":null,"requestBodyTooLarge":false,"globalRateLimitExceeded":true}]`,
+			want: []socketData{
+				{
+					Host:           "testshop.com",
+					Status:         "200",
+					Method:         "GET",
+					Path:           "/admin",
+					RequestLength:  300,
+					RequestTime:    60,
+					ResponseLength: -1,
+					upstream: upstream{
+						Latency:        0.01,
+						ResponseTime:   200,
+						ResponseLength: 1000,
+					},
+					Namespace:          "test-app-production",
+					Ingress:            "web-yml",
+					Service:            "test-app",
+					ModSecurityBlocked: true,
+				},
+				{
+					Host:                    "tést😀.com",
+					GlobalRateLimitExceeded: true,
+				},
+			},
+		},
+		{
+			name:    "not an array",
+			data:    `{"host":"testshop.com"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			data:    `[{"requestTime":"60"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated string",
+			data:    `[{"host":"testshop.com}]`,
+			wantErr: true,
+		},
+		{
+			name:    "data after the batch",
+			data:    `[]#`,
+			wantErr: true,
+		},
+	}
+
+	values := newLabelValues(0)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := decodeStats([]byte(c.data), values)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error decoding %v", c.data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer releaseDecoder(d)
+
+			if len(d.batch) != len(c.want) || (len(c.want) > 0 && !reflect.DeepEqual(d.batch, c.want)) {
+				t.Errorf("expected %+v but %+v returned", c.want, d.batch)
+			}
+		})
+	}
+}
+
+func TestDecodeStatsReuse(t *testing.T) {
+	values := newLabelValues(0)
+
+	d, err := decodeStats([]byte(`[{"host":"a.com","modsecurityBlocked":true,"requestTime":1}]`), values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseDecoder(d)
+
+	d, err = decodeStats([]byte(`[{"host":"b.com"}]`), values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer releaseDecoder(d)
+
+	want := []socketData{{Host: "b.com"}}
+	if !reflect.DeepEqual(d.batch, want) {
+		t.Errorf("expected %+v but %+v returned", want, d.batch)
+	}
+}
+
+func TestDecodeStatsMaxBatchSize(t *testing.T) {
+	records := make([]string, config.MaxBatchSize+10)
+	for i := range records {
+		records[i] = `{"host":"a.com"}`
+	}
+
+	d, err := decodeStats([]byte("["+strings.Join(records, ",")+"]"), newLabelValues(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer releaseDecoder(d)
+
+	if len(d.batch) != config.MaxBatchSize {
+		t.Errorf("expected %v records but %v returned", config.MaxBatchSize, len(d.batch))
+	}
+}
+
+// BenchmarkDecodeStats compares the decoder with the encoding/json decoding of
+// the batches it replaced. Besides the memory allocated by each batch, it
+// reports the heap retained by the records of several batches kept alive, as
+// the label values of the metrics are.
+func BenchmarkDecodeStats(b *testing.B) {
+	const hosts = 100
+
+	msg := synthetic.Batch(1000, hosts, 0)
+
+	var d *statsDecoder
+
+	decoders := []struct {
+		name string
+		// decode returns a batch, valid until release is called
+		decode  func(values *labelValues) ([]socketData, error)
+		release func()
+	}{
+		{
+			name: "stats",
+			decode: func(values *labelValues) ([]socketData, error) {
+				var err error
+				d, err = decodeStats(msg, values)
+				if err != nil {
+					return nil, err
+				}
+
+				return d.batch, nil
+			},
+			release: func() { releaseDecoder(d) },
+		},
+		{
+			name: "json",
+			decode: func(values *labelValues) ([]socketData, error) {
+				var batch []socketData
+				err := json.Unmarshal(msg, &batch)
+				return batch, err
+			},
+			release: func() {},
+		},
+	}
+
+	for _, dec := range decoders {
+		b.Run("decoder="+dec.name, func(b *testing.B) {
+			values := newLabelValues(0)
+
+			decode := func() []socketData {
+				batch, err := dec.decode(values)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				defer dec.release()
+
+				return append([]socketData(nil), batch...)
+			}
+
+			// the first batch interns the label values
+			decode()
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := dec.decode(values); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				dec.release()
+			}
+
+			b.StopTimer()
+			b.ReportMetric(retainedHeap(decode), "retained-B/batch")
+		})
+	}
+}
+
+// retainedHeap returns the heap retained by each of the batches decoded
+// while all of them are kept alive
+func retainedHeap(decode func() []socketData) float64 {
+	const batches = 10
+
+	var before, after runtime.MemStats
+
+	retained := make([][]socketData, 0, batches)
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	for i := 0; i < batches; i++ {
+		retained = append(retained, decode())
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(retained)
+
+	return float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)) / batches
+}
@@ -25,6 +25,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/test/metrics-load/synthetic"
 )
 
 func TestNewUDPLogListener(t *testing.T) {
@@ -224,6 +226,125 @@ func TestCollector(t *testing.T) {
 			`,
 		},
 
+		{
+			name: "requests blocked by ModSecurity should increase the blocked requests metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"403",
+				"method":"POST",
+				"path":"/admin",
+				"requestLength":300.0,
+				"requestTime":0.001,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"upstreamResponseLength":-1,
+				"responseLength":150.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"modsecurityBlocked":true
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":300.0,
+				"requestTime":0.001,
+				"upstreamLatency":0.001,
+				"upstreamResponseTime":0.001,
+				"upstreamResponseLength":150.0,
+				"responseLength":150.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_modsecurity_blocked_requests"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_modsecurity_blocked_requests The total number of client requests blocked by ModSecurity.
+				# TYPE nginx_ingress_controller_modsecurity_blocked_requests counter
+				nginx_ingress_controller_modsecurity_blocked_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+		},
+
+		{
+			name: "requests rejected by a global rate limit should increase the exceeded requests metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"429",
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":300.0,
+				"requestTime":0.001,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"upstreamResponseLength":-1,
+				"responseLength":150.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"globalRateLimitExceeded":true
+			}]`},
+			metrics: []string{"nginx_ingress_controller_global_rate_limit_exceeded_requests"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_global_rate_limit_exceeded_requests The total number of client requests rejected by a global rate limit.
+				# TYPE nginx_ingress_controller_global_rate_limit_exceeded_requests counter
+				nginx_ingress_controller_global_rate_limit_exceeded_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+		},
+
+		{
+			name: "requests rejected because of the body size should increase the body too large metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"413",
+				"method":"POST",
+				"path":"/upload",
+				"requestLength":300.0,
+				"requestTime":0.001,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"upstreamResponseLength":-1,
+				"responseLength":150.0,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"requestBodyTooLarge":true
+			}]`},
+			metrics: []string{"nginx_ingress_controller_request_body_too_large_requests"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_request_body_too_large_requests The total number of client requests rejected because of the size of the body.
+				# TYPE nginx_ingress_controller_request_body_too_large_requests counter
+				nginx_ingress_controller_request_body_too_large_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+		},
+
+		{
+			name: "valid metric object with an upstream retry should update the retried requests metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"502",
+				"bytesSent":150.0,
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":300.0,
+				"requestTime":60.0,
+				"upstreamName":"test-upstream",
+				"upstreamIP":"1.1.1.1:8080",
+				"upstreamResponseTime":-1,
+				"upstreamStatus":"502",
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"upstreamRetried":true
+			}]`},
+			metrics: []string{"nginx_ingress_controller_upstream_retried_requests"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_upstream_retried_requests The total number of client requests retried in a different upstream server.
+				# TYPE nginx_ingress_controller_upstream_retried_requests counter
+				nginx_ingress_controller_upstream_retried_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+		},
+
 		{
 			name: "collector should be able to handle batched metrics correctly",
 			data: []string{`[
@@ -282,13 +403,64 @@ func TestCollector(t *testing.T) {
 			wantAfter: `
 			`,
 		},
+
+		{
+			name: "collector should remove the counters of a deleted ingress",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"403",
+				"bytesSent":150.0,
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":300.0,
+				"requestTime":60.0,
+				"upstreamName":"test-upstream",
+				"upstreamIP":"1.1.1.1:8080",
+				"upstreamResponseTime":200,
+				"upstreamStatus":"403",
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"modsecurityBlocked":true,
+				"globalRateLimitExceeded":true,
+				"requestBodyTooLarge":true,
+				"upstreamRetried":true
+			}]`},
+			metrics: []string{
+				"nginx_ingress_controller_requests",
+				"nginx_ingress_controller_modsecurity_blocked_requests",
+				"nginx_ingress_controller_global_rate_limit_exceeded_requests",
+				"nginx_ingress_controller_request_body_too_large_requests",
+				"nginx_ingress_controller_upstream_retried_requests",
+			},
+			wantBefore: `
+				# HELP nginx_ingress_controller_global_rate_limit_exceeded_requests The total number of client requests rejected by a global rate limit.
+				# TYPE nginx_ingress_controller_global_rate_limit_exceeded_requests counter
+				nginx_ingress_controller_global_rate_limit_exceeded_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+				# HELP nginx_ingress_controller_modsecurity_blocked_requests The total number of client requests blocked by ModSecurity.
+				# TYPE nginx_ingress_controller_modsecurity_blocked_requests counter
+				nginx_ingress_controller_modsecurity_blocked_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+				# HELP nginx_ingress_controller_request_body_too_large_requests The total number of client requests rejected because of the size of the body.
+				# TYPE nginx_ingress_controller_request_body_too_large_requests counter
+				nginx_ingress_controller_request_body_too_large_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+				# HELP nginx_ingress_controller_requests The total number of client requests.
+				# TYPE nginx_ingress_controller_requests counter
+				nginx_ingress_controller_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",status="403"} 1
+				# HELP nginx_ingress_controller_upstream_retried_requests The total number of client requests retried in a different upstream server.
+				# TYPE nginx_ingress_controller_upstream_retried_requests counter
+				nginx_ingress_controller_upstream_retried_requests{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			registry := prometheus.NewPedanticRegistry()
 
-			sc, err := NewSocketCollector("pod", "default", "ingress", true)
+			sc, err := NewSocketCollector("pod", "default", "ingress", true, 0)
 			if err != nil {
 				t.Errorf("%v: unexpected error creating new SocketCollector: %v", c.name, err)
 			}
@@ -308,8 +480,13 @@ func TestCollector(t *testing.T) {
 			}
 
 			if len(c.removeIngresses) > 0 {
-				sc.RemoveMetrics(c.removeIngresses, registry)
-				time.Sleep(1 * time.Second)
+				sc.RemoveMetrics(c.removeIngresses)
+
+				for _, ing := range c.removeIngresses {
+					if _, ok := sc.ingresses.series[ing]; ok {
+						t.Errorf("expected ingress %v to be removed from the index", ing)
+					}
+				}
 
 				if err := GatherAndCompare(sc, c.wantAfter, c.metrics, registry); err != nil {
 					t.Errorf("unexpected collecting result:\n%s", err)
@@ -322,3 +499,96 @@ func TestCollector(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelValues(t *testing.T) {
+	lv := newLabelValues(2)
+
+	first := lv.value([]byte("default"))
+	second := lv.value([]byte("default"))
+	if first != second || len(lv.values) != 1 {
+		t.Errorf("expected a single interned value but %v returned", len(lv.values))
+	}
+
+	for _, host := range []string{"a.com", "b.com", "a.com"} {
+		if lv.host(host) != host {
+			t.Errorf("expected the host label %v", host)
+		}
+	}
+
+	if host := lv.host("c.com"); host != "" {
+		t.Errorf("expected the host label to be cleared once the limit of hosts is reached but %v returned", host)
+	}
+
+	lv.setHosts(sets.NewString("c.com"))
+	if len(lv.hosts) != 0 {
+		t.Errorf("expected the hosts not served to be released but %v remain", len(lv.hosts))
+	}
+
+	if host := lv.host("c.com"); host != "c.com" {
+		t.Errorf("expected the host label c.com once hosts are released but %v returned", host)
+	}
+}
+
+func BenchmarkHandleMessage(b *testing.B) {
+	cases := []struct {
+		records int
+		hosts   int
+	}{
+		{records: 1, hosts: 1},
+		{records: 100, hosts: 1},
+		{records: 100, hosts: 100},
+		{records: 1000, hosts: 1000},
+	}
+
+	for _, c := range cases {
+		b.Run(fmt.Sprintf("records=%v/hosts=%v", c.records, c.hosts), func(b *testing.B) {
+			sc := newBenchmarkCollector(b, c.hosts)
+			defer sc.Stop()
+
+			msg := synthetic.Batch(c.records, c.hosts, 0)
+			// the first batch creates the series of the metrics
+			sc.handleMessage(msg)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				sc.handleMessage(msg)
+			}
+		})
+	}
+}
+
+// BenchmarkHandleMessageParallel simulates the NGINX workers sending their
+// batches at the same time
+func BenchmarkHandleMessageParallel(b *testing.B) {
+	const hosts = 100
+
+	sc := newBenchmarkCollector(b, hosts)
+	defer sc.Stop()
+
+	msg := synthetic.Batch(100, hosts, 0)
+	sc.handleMessage(msg)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sc.handleMessage(msg)
+		}
+	})
+}
+
+func newBenchmarkCollector(b *testing.B, hosts int) *SocketCollector {
+	sc, err := NewSocketCollector("pod", "default", "ingress", true, 0)
+	if err != nil {
+		b.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+
+	sc.SetHosts(synthetic.Hosts(hosts))
+
+	return sc
+}
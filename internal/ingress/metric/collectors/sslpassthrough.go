@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SSLPassthrough defines the metrics of the SSL Passthrough proxy
+type SSLPassthrough struct {
+	prometheus.Collector
+
+	connections     *prometheus.CounterVec
+	bytes           *prometheus.CounterVec
+	handshakeErrors prometheus.Counter
+}
+
+// NewSSLPassthrough creates a new prometheus collector for
+// the connections handled by the SSL Passthrough proxy
+func NewSSLPassthrough(pod, namespace, class string) *SSLPassthrough {
+	constLabels := prometheus.Labels{
+		"controller_namespace": namespace,
+		"controller_class":     class,
+		"controller_pod":       pod,
+	}
+
+	return &SSLPassthrough{
+		connections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "ssl_passthrough_connections_total",
+				Help:        `Cumulative number of connections proxied by the SSL Passthrough proxy`,
+				ConstLabels: constLabels,
+			},
+			[]string{"host"},
+		),
+		bytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "ssl_passthrough_bytes_total",
+				Help:        `Cumulative number of bytes proxied by the SSL Passthrough proxy`,
+				ConstLabels: constLabels,
+			},
+			[]string{"host", "direction"},
+		),
+		handshakeErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "ssl_passthrough_handshake_errors_total",
+				Help:        `Cumulative number of connections without a valid TLS ClientHello`,
+				ConstLabels: constLabels,
+			},
+		),
+	}
+}
+
+// IncConnections increments the number of connections proxied to a host
+func (sp *SSLPassthrough) IncConnections(host string) {
+	sp.connections.WithLabelValues(host).Inc()
+}
+
+// AddBytes adds the bytes sent to and received from a host
+func (sp *SSLPassthrough) AddBytes(host string, sent, received int64) {
+	sp.bytes.WithLabelValues(host, "sent").Add(float64(sent))
+	sp.bytes.WithLabelValues(host, "received").Add(float64(received))
+}
+
+// IncHandshakeErrors increments the number of invalid TLS handshakes
+func (sp *SSLPassthrough) IncHandshakeErrors() {
+	sp.handshakeErrors.Inc()
+}
+
+// RemoveMetrics removes metrics for hostnames not available anymore
+func (sp *SSLPassthrough) RemoveMetrics(hosts []string) {
+	for _, host := range hosts {
+		sp.connections.DeleteLabelValues(host)
+		sp.bytes.DeleteLabelValues(host, "sent")
+		sp.bytes.DeleteLabelValues(host, "received")
+	}
+}
+
+// Describe implements prometheus.Collector
+func (sp SSLPassthrough) Describe(ch chan<- *prometheus.Desc) {
+	sp.connections.Describe(ch)
+	sp.bytes.Describe(ch)
+	sp.handshakeErrors.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (sp SSLPassthrough) Collect(ch chan<- prometheus.Metric) {
+	sp.connections.Collect(ch)
+	sp.bytes.Collect(ch)
+	sp.handshakeErrors.Collect(ch)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSSLPassthroughCounters(t *testing.T) {
+	const metadata = `
+		# HELP nginx_ingress_controller_ssl_passthrough_bytes_total Cumulative number of bytes proxied by the SSL Passthrough proxy
+		# TYPE nginx_ingress_controller_ssl_passthrough_bytes_total counter
+		# HELP nginx_ingress_controller_ssl_passthrough_connections_total Cumulative number of connections proxied by the SSL Passthrough proxy
+		# TYPE nginx_ingress_controller_ssl_passthrough_connections_total counter
+	`
+	metrics := []string{
+		"nginx_ingress_controller_ssl_passthrough_bytes_total",
+		"nginx_ingress_controller_ssl_passthrough_connections_total",
+	}
+
+	cases := []struct {
+		name    string
+		test    func(*SSLPassthrough)
+		metrics []string
+		want    string
+	}{
+		{
+			name: "should count connections and bytes by host",
+			test: func(sp *SSLPassthrough) {
+				sp.IncConnections("foo.bar")
+				sp.IncConnections("foo.bar")
+				sp.AddBytes("foo.bar", 100, 2000)
+			},
+			want: metadata + `
+				nginx_ingress_controller_ssl_passthrough_bytes_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",direction="received",host="foo.bar"} 2000
+				nginx_ingress_controller_ssl_passthrough_bytes_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",direction="sent",host="foo.bar"} 100
+				nginx_ingress_controller_ssl_passthrough_connections_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="foo.bar"} 2
+			`,
+			metrics: metrics,
+		},
+		{
+			name: "should remove the metrics of removed hosts",
+			test: func(sp *SSLPassthrough) {
+				sp.IncConnections("foo.bar")
+				sp.AddBytes("foo.bar", 100, 2000)
+				sp.IncConnections("bar.foo")
+				sp.RemoveMetrics([]string{"foo.bar"})
+			},
+			want: `
+				# HELP nginx_ingress_controller_ssl_passthrough_connections_total Cumulative number of connections proxied by the SSL Passthrough proxy
+				# TYPE nginx_ingress_controller_ssl_passthrough_connections_total counter
+				nginx_ingress_controller_ssl_passthrough_connections_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="bar.foo"} 1
+			`,
+			metrics: metrics,
+		},
+		{
+			name: "should count handshake errors",
+			test: func(sp *SSLPassthrough) {
+				sp.IncHandshakeErrors()
+			},
+			want: `
+				# HELP nginx_ingress_controller_ssl_passthrough_handshake_errors_total Cumulative number of connections without a valid TLS ClientHello
+				# TYPE nginx_ingress_controller_ssl_passthrough_handshake_errors_total counter
+				nginx_ingress_controller_ssl_passthrough_handshake_errors_total{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_ssl_passthrough_handshake_errors_total"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sp := NewSSLPassthrough("pod", "default", "nginx")
+			reg := prometheus.NewPedanticRegistry()
+			if err := reg.Register(sp); err != nil {
+				t.Errorf("registering collector failed: %s", err)
+			}
+
+			c.test(sp)
+
+			if err := GatherAndCompare(sp, c.want, c.metrics, reg); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+
+			reg.Unregister(sp)
+		})
+	}
+}
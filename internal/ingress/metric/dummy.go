@@ -19,6 +19,7 @@ package metric
 import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
 )
 
 // NewDummyCollector returns a dummy metric collector
@@ -32,12 +33,21 @@ type DummyCollector struct{}
 // ConfigSuccess ...
 func (dc DummyCollector) ConfigSuccess(uint64, bool) {}
 
+// ConfigApplied ...
+func (dc DummyCollector) ConfigApplied(bool) {}
+
 // IncReloadCount ...
 func (dc DummyCollector) IncReloadCount() {}
 
 // IncReloadErrorCount ...
 func (dc DummyCollector) IncReloadErrorCount() {}
 
+// IncConfigDriftCount ...
+func (dc DummyCollector) IncConfigDriftCount(string) {}
+
+// IncReloadAvoidedCount ...
+func (dc DummyCollector) IncReloadAvoidedCount(string) {}
+
 // RemoveMetrics ...
 func (dc DummyCollector) RemoveMetrics(ingresses, endpoints []string) {}
 
@@ -52,3 +62,18 @@ func (dc DummyCollector) SetSSLExpireTime([]*ingress.Server) {}
 
 // SetHosts ...
 func (dc DummyCollector) SetHosts(hosts sets.String) {}
+
+// SetOrphanedIngresses ...
+func (dc DummyCollector) SetOrphanedIngresses([]collectors.OrphanedIngress) {}
+
+// SetConfigurationChecksum ...
+func (dc DummyCollector) SetConfigurationChecksum(string) {}
+
+// IncSSLPassthroughConnections ...
+func (dc DummyCollector) IncSSLPassthroughConnections(string) {}
+
+// AddSSLPassthroughBytes ...
+func (dc DummyCollector) AddSSLPassthroughBytes(string, int64, int64) {}
+
+// IncSSLPassthroughHandshakeErrors ...
+func (dc DummyCollector) IncSSLPassthroughHandshakeErrors() {}
@@ -27,15 +27,23 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
 	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+	"k8s.io/ingress-nginx/internal/nginx"
 )
 
 // Collector defines the interface for a metric collector
 type Collector interface {
 	ConfigSuccess(uint64, bool)
+	ConfigApplied(bool)
 
 	IncReloadCount()
 	IncReloadErrorCount()
 
+	// IncConfigDriftCount increments the number of configuration drifts detected by source
+	IncConfigDriftCount(string)
+
+	// IncReloadAvoidedCount increments the number of reloads avoided by reason
+	IncReloadAvoidedCount(string)
+
 	RemoveMetrics(ingresses, endpoints []string)
 
 	SetSSLExpireTime([]*ingress.Server)
@@ -43,6 +51,19 @@ type Collector interface {
 	// SetHosts sets the hostnames that are being served by the ingress controller
 	SetHosts(sets.String)
 
+	// SetOrphanedIngresses sets the Ingresses routing traffic to Services without Endpoints
+	SetOrphanedIngresses([]collectors.OrphanedIngress)
+
+	// SetConfigurationChecksum sets the checksum of the applied configuration
+	SetConfigurationChecksum(string)
+
+	// IncSSLPassthroughConnections increments the number of SSL Passthrough connections by host
+	IncSSLPassthroughConnections(string)
+	// AddSSLPassthroughBytes adds the bytes sent to and received from a SSL Passthrough host
+	AddSSLPassthroughBytes(string, int64, int64)
+	// IncSSLPassthroughHandshakeErrors increments the number of invalid TLS handshakes
+	IncSSLPassthroughHandshakeErrors()
+
 	Start()
 	Stop()
 }
@@ -55,11 +76,15 @@ type collector struct {
 
 	socket *collectors.SocketCollector
 
+	sslPassthrough *collectors.SSLPassthrough
+
+	buildInfo prometheus.Collector
+
 	registry *prometheus.Registry
 }
 
 // NewCollector creates a new metric collector the for ingress controller
-func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector, error) {
+func NewCollector(metricsPerHost bool, metricsMaxHosts int, registry *prometheus.Registry, ngxBuild *nginx.BuildInfo) (Collector, error) {
 	podNamespace := os.Getenv("POD_NAMESPACE")
 	if podNamespace == "" {
 		podNamespace = "default"
@@ -77,7 +102,7 @@ func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector
 		return nil, err
 	}
 
-	s, err := collectors.NewSocketCollector(podName, podNamespace, class.IngressClass, metricsPerHost)
+	s, err := collectors.NewSocketCollector(podName, podNamespace, class.IngressClass, metricsPerHost, metricsMaxHosts)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +117,10 @@ func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector
 
 		socket: s,
 
+		sslPassthrough: collectors.NewSSLPassthrough(podName, podNamespace, class.IngressClass),
+
+		buildInfo: collectors.NewBuildInfo(podName, podNamespace, class.IngressClass, ngxBuild),
+
 		registry: registry,
 	}), nil
 }
@@ -100,6 +129,10 @@ func (c *collector) ConfigSuccess(hash uint64, success bool) {
 	c.ingressController.ConfigSuccess(hash, success)
 }
 
+func (c *collector) ConfigApplied(success bool) {
+	c.ingressController.ConfigApplied(success)
+}
+
 func (c *collector) IncReloadCount() {
 	c.ingressController.IncReloadCount()
 }
@@ -108,9 +141,18 @@ func (c *collector) IncReloadErrorCount() {
 	c.ingressController.IncReloadErrorCount()
 }
 
+func (c *collector) IncConfigDriftCount(source string) {
+	c.ingressController.IncConfigDriftCount(source)
+}
+
+func (c *collector) IncReloadAvoidedCount(reason string) {
+	c.ingressController.IncReloadAvoidedCount(reason)
+}
+
 func (c *collector) RemoveMetrics(ingresses, hosts []string) {
-	c.socket.RemoveMetrics(ingresses, c.registry)
+	c.socket.RemoveMetrics(ingresses)
 	c.ingressController.RemoveMetrics(hosts, c.registry)
+	c.sslPassthrough.RemoveMetrics(hosts)
 }
 
 func (c *collector) Start() {
@@ -118,6 +160,8 @@ func (c *collector) Start() {
 	c.registry.MustRegister(c.nginxProcess)
 	c.registry.MustRegister(c.ingressController)
 	c.registry.MustRegister(c.socket)
+	c.registry.MustRegister(c.sslPassthrough)
+	c.registry.MustRegister(c.buildInfo)
 
 	// the default nginx.conf does not contains
 	// a server section with the status port
@@ -134,6 +178,8 @@ func (c *collector) Stop() {
 	c.registry.Unregister(c.nginxProcess)
 	c.registry.Unregister(c.ingressController)
 	c.registry.Unregister(c.socket)
+	c.registry.Unregister(c.sslPassthrough)
+	c.registry.Unregister(c.buildInfo)
 
 	c.nginxStatus.Stop()
 	c.nginxProcess.Stop()
@@ -147,3 +193,23 @@ func (c *collector) SetSSLExpireTime(servers []*ingress.Server) {
 func (c *collector) SetHosts(hosts sets.String) {
 	c.socket.SetHosts(hosts)
 }
+
+func (c *collector) SetOrphanedIngresses(orphaned []collectors.OrphanedIngress) {
+	c.ingressController.SetOrphanedIngresses(orphaned)
+}
+
+func (c *collector) SetConfigurationChecksum(checksum string) {
+	c.ingressController.SetConfigurationChecksum(checksum)
+}
+
+func (c *collector) IncSSLPassthroughConnections(host string) {
+	c.sslPassthrough.IncConnections(host)
+}
+
+func (c *collector) AddSSLPassthroughBytes(host string, sent, received int64) {
+	c.sslPassthrough.AddBytes(host, sent, received)
+}
+
+func (c *collector) IncSSLPassthroughHandshakeErrors() {
+	c.sslPassthrough.IncHandshakeErrors()
+}
@@ -39,6 +39,9 @@ type Resolver interface {
 
 	// GetService searches for services containing the namespace and name using a the character /
 	GetService(string) (*apiv1.Service, error)
+
+	// GetConfigMap searches for configmaps containing the namespace and name using a the character /
+	GetConfigMap(string) (*apiv1.ConfigMap, error)
 }
 
 // AuthSSLCert contains the necessary information to do certificate based
@@ -48,8 +51,14 @@ type AuthSSLCert struct {
 	Secret string `json:"secret"`
 	// CAFileName contains the path to the secrets 'ca.crt'
 	CAFileName string `json:"caFilename"`
+	// PemFileName contains the path to the secrets 'tls.crt' and 'tls.key'
+	PemFileName string `json:"pemFilename,omitempty"`
 	// PemSHA contains the SHA1 hash of the 'ca.crt' or combinations of (tls.crt, tls.key, tls.crt) depending on certs in secret
 	PemSHA string `json:"pemSha"`
+	// CRLFileName contains the path to the secrets 'ca.crl'
+	CRLFileName string `json:"crlFileName"`
+	// CRLSHA contains the SHA1 hash of the 'ca.crl'
+	CRLSHA string `json:"crlSha"`
 }
 
 // Equal tests for equality between two AuthSSLCert types
@@ -67,9 +76,18 @@ func (asslc1 *AuthSSLCert) Equal(assl2 *AuthSSLCert) bool {
 	if asslc1.CAFileName != assl2.CAFileName {
 		return false
 	}
+	if asslc1.PemFileName != assl2.PemFileName {
+		return false
+	}
 	if asslc1.PemSHA != assl2.PemSHA {
 		return false
 	}
+	if asslc1.CRLFileName != assl2.CRLFileName {
+		return false
+	}
+	if asslc1.CRLSHA != assl2.CRLSHA {
+		return false
+	}
 
 	return true
 }
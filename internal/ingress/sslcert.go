@@ -30,6 +30,10 @@ type SSLCert struct {
 	Certificate       *x509.Certificate `json:"certificate,omitempty"`
 	// CAFileName contains the path to the file with the root certificate
 	CAFileName string `json:"caFileName"`
+	// CRLFileName contains the path to the file with the certificate revocation list
+	CRLFileName string `json:"crlFileName,omitempty"`
+	// CRLSHA contains the sha1 of the certificate revocation list
+	CRLSHA string `json:"crlSha,omitempty"`
 	// PemFileName contains the path to the file with the certificate and key concatenated
 	PemFileName string `json:"pemFileName"`
 	// FullChainPemFileName contains the path to the file with the certificate and key concatenated
@@ -44,6 +48,14 @@ type SSLCert struct {
 	ExpireTime time.Time `json:"expires"`
 	// Pem encoded certificate and key concatenated
 	PemCertKey string `json:"pemCertKey"`
+	// OCSPResponse contains the DER encoded OCSP response stapled to the handshake
+	OCSPResponse []byte `json:"ocspResponse,omitempty"`
+	// OCSPFileName contains the path to the file with the OCSP response
+	OCSPFileName string `json:"ocspFileName,omitempty"`
+	// OCSPRefreshTime contains the time a new OCSP response should be requested
+	OCSPRefreshTime time.Time `json:"ocspRefreshTime,omitempty"`
+	// OCSPExpireTime contains the time after which the OCSP response must not be used
+	OCSPExpireTime time.Time `json:"ocspExpireTime,omitempty"`
 }
 
 // GetObjectKind implements the ObjectKind interface as a noop
@@ -53,5 +65,6 @@ func (s SSLCert) GetObjectKind() schema.ObjectKind {
 
 // HashInclude defines if a field should be used or not to calculate the hash
 func (s SSLCert) HashInclude(field string, v interface{}) (bool, error) {
-	return (field != "PemSHA" && field != "ExpireTime"), nil
+	return (field != "PemSHA" && field != "ExpireTime" &&
+		field != "OCSPRefreshTime" && field != "OCSPExpireTime"), nil
 }
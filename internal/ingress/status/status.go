@@ -23,6 +23,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -53,6 +54,10 @@ const (
 type Sync interface {
 	Run()
 	Shutdown()
+
+	// IsLeader returns if the instance is the current leader
+	// of the status updates
+	IsLeader() bool
 }
 
 type ingressLister interface {
@@ -94,6 +99,10 @@ type statusSync struct {
 
 	elector *leaderelection.LeaderElector
 
+	// leader is set to 1 while the instance is the leader, it is shared
+	// by the copies of statusSync
+	leader *int32
+
 	// workqueue used to keep in sync the status IP/s
 	// in the Ingress rules
 	syncQueue *task.Queue
@@ -124,6 +133,7 @@ func (s statusSync) Run() {
 	callbacks := leaderelection.LeaderCallbacks{
 		OnStartedLeading: func(ctx context.Context) {
 			klog.V(2).Infof("I am the new status update leader")
+			atomic.StoreInt32(s.leader, 1)
 			stopCh = make(chan struct{})
 			go s.syncQueue.Run(time.Second, stopCh)
 			// trigger initial sync
@@ -137,6 +147,7 @@ func (s statusSync) Run() {
 		},
 		OnStoppedLeading: func() {
 			klog.V(2).Info("I am not status update leader anymore")
+			atomic.StoreInt32(s.leader, 0)
 			close(stopCh)
 
 			// cancel the context
@@ -182,6 +193,11 @@ func (s statusSync) Run() {
 	cancelContext = newLeaderCtx(ctx)
 }
 
+// IsLeader returns if the instance is the current leader of the status updates
+func (s statusSync) IsLeader() bool {
+	return s.leader != nil && atomic.LoadInt32(s.leader) == 1
+}
+
 // Shutdown stop the sync. In case the instance is the leader it will remove the current IP
 // if there is no other instances running.
 func (s statusSync) Shutdown() {
@@ -251,7 +267,8 @@ func NewStatusSyncer(config Config) Sync {
 	}
 
 	st := statusSync{
-		pod: pod,
+		pod:    pod,
+		leader: new(int32),
 
 		Config: config,
 	}
@@ -290,7 +307,13 @@ func (s *statusSync) runningAddresses() ([]string, error) {
 	}
 
 	if s.PublishStatusAddress != "" {
-		addrs = append(addrs, s.PublishStatusAddress)
+		for _, addr := range strings.Split(s.PublishStatusAddress, ",") {
+			addr = strings.TrimSpace(addr)
+			if addr == "" {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
 		return addrs, nil
 	}
 
@@ -18,6 +18,8 @@ package status
 
 import (
 	"os"
+	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -411,6 +413,18 @@ func TestRunningAddresessWithPublishStatusAddress(t *testing.T) {
 	}
 }
 
+func TestRunningAddresessWithPublishStatusAddressList(t *testing.T) {
+	fk := buildStatusSync()
+	fk.PublishService = ""
+	fk.PublishStatusAddress = "127.0.0.1, lb.example.com,,10.0.0.1"
+
+	r, _ := fk.runningAddresses()
+	expected := []string{"127.0.0.1", "lb.example.com", "10.0.0.1"}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("returned %v but expected %v", r, expected)
+	}
+}
+
 /*
 TODO: this test requires a refactoring
 func TestUpdateStatus(t *testing.T) {
@@ -499,3 +513,20 @@ func TestIngressSliceEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestIsLeader(t *testing.T) {
+	fk := buildStatusSync()
+	if fk.IsLeader() {
+		t.Errorf("expected not to be the leader without leader election")
+	}
+
+	fk.leader = new(int32)
+	if fk.IsLeader() {
+		t.Errorf("expected not to be the leader before the election")
+	}
+
+	atomic.StoreInt32(fk.leader, 1)
+	if !fk.IsLeader() {
+		t.Errorf("expected to be the leader after the election")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamservice
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// Interface lists and watches StreamServices and updates their status
+type Interface interface {
+	List(namespace string, opts metav1.ListOptions) (*StreamServiceList, error)
+	Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error)
+	UpdateStatus(*StreamService) (*StreamService, error)
+}
+
+var (
+	scheme         = runtime.NewScheme()
+	codecs         = serializer.NewCodecFactory(scheme)
+	parameterCodec = runtime.NewParameterCodec(scheme)
+)
+
+func init() {
+	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Version: "v1"})
+	AddToScheme(scheme)
+}
+
+type client struct {
+	restClient rest.Interface
+}
+
+// NewForConfig creates a StreamService client using the
+// configuration of the Kubernetes API server client
+func NewForConfig(c *rest.Config) (Interface, error) {
+	config := *c
+	config.GroupVersion = &SchemeGroupVersion
+	config.APIPath = "/apis"
+	// custom resources do not support protobuf
+	config.ContentType = runtime.ContentTypeJSON
+	config.AcceptContentTypes = runtime.ContentTypeJSON
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: codecs}
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	restClient, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{restClient}, nil
+}
+
+// List returns the StreamServices of a namespace, or of
+// all the namespaces if the namespace is empty
+func (c *client) List(namespace string, opts metav1.ListOptions) (*StreamServiceList, error) {
+	result := &StreamServiceList{}
+	err := c.restClient.Get().
+		Namespace(namespace).
+		Resource(Resource).
+		VersionedParams(&opts, parameterCodec).
+		Do().
+		Into(result)
+	return result, err
+}
+
+// Watch returns a watch.Interface that watches the
+// StreamServices of a namespace, or of all the namespaces
+func (c *client) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.restClient.Get().
+		Namespace(namespace).
+		Resource(Resource).
+		VersionedParams(&opts, parameterCodec).
+		Watch()
+}
+
+// UpdateStatus updates the status subresource of a StreamService
+func (c *client) UpdateStatus(ss *StreamService) (*StreamService, error) {
+	result := &StreamService{}
+	err := c.restClient.Put().
+		Namespace(ss.Namespace).
+		Resource(Resource).
+		Name(ss.Name).
+		SubResource("status").
+		Body(ss).
+		Do().
+		Into(result)
+	return result, err
+}
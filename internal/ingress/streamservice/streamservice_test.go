@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamservice
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
+)
+
+func TestValidatePort(t *testing.T) {
+	backend := extensions.IngressBackend{ServiceName: "postgres", ServicePort: intstr.FromInt(5432)}
+
+	testCases := map[string]struct {
+		port      StreamPort
+		expectErr bool
+	}{
+		"default protocol": {StreamPort{Port: 5432, Backend: backend}, false},
+		"UDP":              {StreamPort{Port: 53, Protocol: "UDP", Backend: backend}, false},
+		"TCP with TLS and PROXY protocol": {StreamPort{
+			Port:          5432,
+			Protocol:      "TCP",
+			Backend:       backend,
+			ProxyProtocol: StreamProxyProtocol{Decode: true},
+			TLS:           &StreamTLS{SecretName: "postgres-tls"},
+		}, false},
+		"named Service port":      {StreamPort{Port: 5432, Backend: extensions.IngressBackend{ServiceName: "postgres", ServicePort: intstr.FromString("db")}}, false},
+		"invalid port":            {StreamPort{Port: 70000, Backend: backend}, true},
+		"invalid protocol":        {StreamPort{Port: 5432, Protocol: "SCTP", Backend: backend}, true},
+		"missing Service":         {StreamPort{Port: 5432}, true},
+		"missing Service port":    {StreamPort{Port: 5432, Backend: extensions.IngressBackend{ServiceName: "postgres"}}, true},
+		"UDP with PROXY protocol": {StreamPort{Port: 53, Protocol: "UDP", Backend: backend, ProxyProtocol: StreamProxyProtocol{Encode: true}}, true},
+		"UDP with TLS":            {StreamPort{Port: 53, Protocol: "UDP", Backend: backend, TLS: &StreamTLS{SecretName: "tls"}}, true},
+		"TLS without Secret":      {StreamPort{Port: 5432, Backend: backend, TLS: &StreamTLS{}}, true},
+		"snippet":                 {StreamPort{Port: 5432, Backend: backend, Snippet: "limit_conn tcp_conn 10;"}, false},
+		"blocked snippet":         {StreamPort{Port: 5432, Backend: backend, Snippet: "preread_by_lua_block { ngx.exit(403) }"}, true},
+	}
+
+	snippet.BlockedDirectives = []string{"*_by_lua*"}
+	defer func() { snippet.BlockedDirectives = []string{} }()
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			err := ValidatePort(tc.port)
+			if tc.expectErr && err == nil {
+				t.Errorf("expected an error validating %+v", tc.port)
+			}
+			if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error validating %+v: %v", tc.port, err)
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	status := &StreamServiceStatus{}
+
+	if !status.SetCondition(StreamServiceCondition{Type: StreamServiceReady, Status: "True", Reason: "PortsExposed"}) {
+		t.Errorf("expected a new condition to change the status")
+	}
+	if status.SetCondition(StreamServiceCondition{Type: StreamServiceReady, Status: "True", Reason: "PortsExposed"}) {
+		t.Errorf("expected the same condition to not change the status")
+	}
+	if !status.SetCondition(StreamServiceCondition{Type: StreamServiceReady, Status: "False", Reason: "PortConflict"}) {
+		t.Errorf("expected a different condition to change the status")
+	}
+
+	if len(status.Conditions) != 1 {
+		t.Fatalf("expected one condition but got %v", len(status.Conditions))
+	}
+	if c := status.GetCondition(StreamServiceReady); c == nil || c.Reason != "PortConflict" {
+		t.Errorf("expected the condition to be replaced but got %+v", c)
+	}
+}
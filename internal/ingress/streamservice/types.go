@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package streamservice contains the StreamService custom resource, used to
+// expose Services in TCP and UDP ports of the Ingress controller as an
+// alternative to the TCP and UDP services ConfigMaps.
+package streamservice
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the API group of the StreamService resource
+	GroupName = "ingress-nginx.kubernetes.io"
+	// Resource is the plural name of the StreamService resource
+	Resource = "streamservices"
+)
+
+// SchemeGroupVersion is the group version of the StreamService resource
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+var (
+	schemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme adds the StreamService types to a scheme
+	AddToScheme = schemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&StreamService{},
+		&StreamServiceList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
+
+// StreamService exposes Services in TCP or UDP ports of the Ingress controller
+// +k8s:deepcopy-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StreamService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StreamServiceSpec   `json:"spec"`
+	Status StreamServiceStatus `json:"status,omitempty"`
+}
+
+// StreamServiceSpec describes the ports exposed by a StreamService
+// +k8s:deepcopy-gen=true
+type StreamServiceSpec struct {
+	Ports []StreamPort `json:"ports"`
+}
+
+// StreamPort describes a TCP or UDP port of the Ingress controller and the
+// Service, located in the namespace of the StreamService, exposed in it
+// +k8s:deepcopy-gen=true
+type StreamPort struct {
+	// Port external port to expose
+	Port int `json:"port"`
+	// Protocol of the port, TCP or UDP. Defaults to TCP
+	// +optional
+	Protocol apiv1.Protocol `json:"protocol,omitempty"`
+	// Backend Service and port exposed
+	Backend extensions.IngressBackend `json:"backend"`
+	// ProxyProtocol configures the PROXY protocol in TCP ports
+	// +optional
+	ProxyProtocol StreamProxyProtocol `json:"proxyProtocol,omitempty"`
+	// TLS terminates the TLS connections of a TCP port in NGINX
+	// +optional
+	TLS *StreamTLS `json:"tls,omitempty"`
+	// Snippet adds custom configuration to the NGINX server of the port
+	// +optional
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// StreamProxyProtocol describes the PROXY protocol configuration of a TCP port
+type StreamProxyProtocol struct {
+	// Decode the PROXY protocol header sent by the clients
+	Decode bool `json:"decode,omitempty"`
+	// Encode the PROXY protocol header sent to the endpoints
+	Encode bool `json:"encode,omitempty"`
+}
+
+// StreamTLS describes the certificate used to terminate TLS connections
+type StreamTLS struct {
+	// SecretName of the Secret, in the namespace of the StreamService,
+	// containing the certificate and key
+	SecretName string `json:"secretName"`
+}
+
+// StreamServiceConditionType is the type of a StreamService condition
+type StreamServiceConditionType string
+
+const (
+	// StreamServiceReady indicates all the ports of the StreamService are exposed
+	StreamServiceReady StreamServiceConditionType = "Ready"
+	// StreamServiceConflict indicates some ports of the StreamService are
+	// already used by the controller, the ConfigMaps or other StreamServices
+	StreamServiceConflict StreamServiceConditionType = "Conflict"
+)
+
+// StreamServiceCondition describes the state of a StreamService
+// +k8s:deepcopy-gen=true
+type StreamServiceCondition struct {
+	Type               StreamServiceConditionType `json:"type"`
+	Status             apiv1.ConditionStatus      `json:"status"`
+	LastTransitionTime metav1.Time                `json:"lastTransitionTime,omitempty"`
+	Reason             string                     `json:"reason,omitempty"`
+	Message            string                     `json:"message,omitempty"`
+}
+
+// StreamServiceStatus describes the observed state of a StreamService
+// +k8s:deepcopy-gen=true
+type StreamServiceStatus struct {
+	Conditions []StreamServiceCondition `json:"conditions,omitempty"`
+}
+
+// StreamServiceList is a list of StreamServices
+// +k8s:deepcopy-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StreamServiceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []StreamService `json:"items"`
+}
+
+// GetCondition returns the condition of type t or nil if not present
+func (s *StreamServiceStatus) GetCondition(t StreamServiceConditionType) *StreamServiceCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type. The
+// transition time is only updated when the status of the condition changes.
+// Returns true if the condition was changed.
+func (s *StreamServiceStatus) SetCondition(c StreamServiceCondition) bool {
+	current := s.GetCondition(c.Type)
+	if current == nil {
+		s.Conditions = append(s.Conditions, c)
+		return true
+	}
+
+	if current.Status == c.Status && current.Reason == c.Reason && current.Message == c.Message {
+		return false
+	}
+
+	if current.Status == c.Status {
+		c.LastTransitionTime = current.LastTransitionTime
+	}
+	*current = c
+
+	return true
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamservice
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
+)
+
+// ValidatePort checks the configuration of a StreamService port. Conflicts
+// with other ports are checked by the controller.
+func ValidatePort(p StreamPort) error {
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("invalid port %v", p.Port)
+	}
+
+	protocol := Protocol(p)
+	if protocol != apiv1.ProtocolTCP && protocol != apiv1.ProtocolUDP {
+		return fmt.Errorf("invalid protocol %q in port %v, only TCP and UDP are supported", p.Protocol, p.Port)
+	}
+
+	if p.Backend.ServiceName == "" {
+		return fmt.Errorf("port %v does not define a backend Service", p.Port)
+	}
+	if (p.Backend.ServicePort.Type == intstr.Int && p.Backend.ServicePort.IntVal == 0) ||
+		(p.Backend.ServicePort.Type == intstr.String && p.Backend.ServicePort.StrVal == "") {
+		return fmt.Errorf("port %v does not define the port of the Service %q", p.Port, p.Backend.ServiceName)
+	}
+
+	if protocol == apiv1.ProtocolUDP {
+		if p.ProxyProtocol.Decode || p.ProxyProtocol.Encode {
+			return fmt.Errorf("PROXY protocol is not supported in UDP port %v", p.Port)
+		}
+		if p.TLS != nil {
+			return fmt.Errorf("TLS is not supported in UDP port %v", p.Port)
+		}
+	}
+
+	if p.TLS != nil && p.TLS.SecretName == "" {
+		return fmt.Errorf("port %v does not define the Secret of the TLS certificate", p.Port)
+	}
+
+	if p.Snippet != "" {
+		if err := snippet.Validate(p.Snippet); err != nil {
+			return fmt.Errorf("invalid snippet in port %v: %v", p.Port, err)
+		}
+	}
+
+	return nil
+}
+
+// Protocol returns the protocol of a port, TCP if not defined
+func Protocol(p StreamPort) apiv1.Protocol {
+	if p.Protocol == "" {
+		return apiv1.ProtocolTCP
+	}
+
+	return p.Protocol
+}
@@ -22,20 +22,33 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/modsecurity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/oidc"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/bodysize"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/globalratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwt"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/mirror"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentracing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamkeepalive"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/websocket"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/xforwardedprefix"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -74,6 +87,15 @@ type Configuration struct {
 
 	// ControllerPodsCount contains the list of running ingress controller Pod(s)
 	ControllerPodsCount int `json:"controllerPodsCount,omitempty"`
+
+	// SSLSessionTicketKeysChecksum contains the checksum of the TLS session ticket keys
+	SSLSessionTicketKeysChecksum string `json:"sslSessionTicketKeysChecksum,omitempty"`
+
+	// ACMEChallenges contains the responses to the ACME HTTP-01 challenges by token
+	ACMEChallenges map[string]string `json:"acmeChallenges,omitempty"`
+
+	// IPAccess contains the CIDRs denied and allowed to access every server
+	IPAccess IPAccess `json:"ipAccess"`
 }
 
 // Backend describes one or more remote server/s (endpoints) associated with a service
@@ -144,12 +166,16 @@ type SessionAffinityConfig struct {
 // CookieSessionAffinity defines the structure used in Affinity configured by Cookies.
 // +k8s:deepcopy-gen=true
 type CookieSessionAffinity struct {
-	Name      string              `json:"name"`
-	Hash      string              `json:"hash"`
-	Expires   string              `json:"expires,omitempty"`
-	MaxAge    string              `json:"maxage,omitempty"`
-	Locations map[string][]string `json:"locations,omitempty"`
-	Path      string              `json:"path,omitempty"`
+	Name            string              `json:"name"`
+	Hash            string              `json:"hash"`
+	Expires         string              `json:"expires,omitempty"`
+	MaxAge          string              `json:"maxage,omitempty"`
+	Locations       map[string][]string `json:"locations,omitempty"`
+	Path            string              `json:"path,omitempty"`
+	Secure          bool                `json:"secure,omitempty"`
+	SameSite        string              `json:"samesite,omitempty"`
+	Domain          string              `json:"domain,omitempty"`
+	ChangeOnFailure bool                `json:"changeonfailure,omitempty"`
 }
 
 // UpstreamHashByConfig described setting from the upstream-hash-by* annotations.
@@ -177,8 +203,14 @@ type Server struct {
 	// SSLPassthrough indicates if the TLS termination is realized in
 	// the server or in the remote endpoint
 	SSLPassthrough bool `json:"sslPassthrough"`
+	// SSLPassthroughProxyProtocol is the version of the PROXY protocol header
+	// sent to the upstream in passthrough mode. Empty disables it
+	SSLPassthroughProxyProtocol string `json:"sslPassthroughProxyProtocol,omitempty"`
 	// SSLCert describes the certificate that will be used on the server
 	SSLCert SSLCert `json:"sslCert"`
+	// SSLCertECDSA describes an additional ECDSA certificate served to the
+	// clients that support it when SSLCert uses an RSA key
+	SSLCertECDSA SSLCert `json:"sslCertECDSA"`
 	// Locations list of URIs configured in the server.
 	Locations []*Location `json:"locations,omitempty"`
 	// Alias return the alias of the server name
@@ -193,6 +225,12 @@ type Server struct {
 	ServerSnippet string `json:"serverSnippet"`
 	// SSLCiphers returns list of ciphers to be enabled
 	SSLCiphers string `json:"sslCiphers,omitempty"`
+	// SSLProtocols returns the list of protocols to be enabled
+	SSLProtocols string `json:"sslProtocols,omitempty"`
+	// UseHTTP2 indicates if HTTP/2 must be enabled in the server
+	UseHTTP2 bool `json:"useHTTP2"`
+	// UseHTTP3 indicates if the server must advertise HTTP/3 to clients
+	UseHTTP3 bool `json:"useHTTP3"`
 	// AuthTLSError contains the reason why the access to a server should be denied
 	AuthTLSError string `json:"authTLSError,omitempty"`
 }
@@ -233,6 +271,10 @@ type Location struct {
 	// vhost of the incoming request.
 	// +optional
 	UpstreamVhost string `json:"upstream-vhost"`
+	// UpstreamKeepalive describes the cache of keepalive connections to
+	// the upstream servers when it differs from the global one
+	// +optional
+	UpstreamKeepalive upstreamkeepalive.Config `json:"upstreamKeepalive,omitempty"`
 	// BasicDigestAuth returns authentication configuration for
 	// an Ingress rule.
 	// +optional
@@ -247,6 +289,30 @@ type Location struct {
 	// authentication using an external provider
 	// +optional
 	ExternalAuth authreq.Config `json:"externalAuth,omitempty"`
+	// EnableGlobalAuth indicates if the access to this location requires
+	// authentication using the global external authentication service
+	// configured in the ConfigMap, unless it defines its own
+	EnableGlobalAuth bool `json:"enableGlobalAuth"`
+	// OIDC indicates the access to this location requires
+	// authentication using an OpenID Connect provider
+	// +optional
+	OIDC oidc.Config `json:"oidc,omitempty"`
+	// JWT indicates the requests to this location must include
+	// a valid JSON Web Token in the Authorization header
+	// +optional
+	JWT jwt.Config `json:"jwt,omitempty"`
+	// Plugins is the list of Lua plugins that run in the location
+	// in addition to the plugins enabled globally in the configmap
+	// +optional
+	Plugins []string `json:"plugins,omitempty"`
+	// GRPCWeb indicates gRPC-Web requests must be translated to gRPC
+	// before being sent to the backend
+	// +optional
+	GRPCWeb bool `json:"grpcWeb,omitempty"`
+	// DisableCompression disables the gzip and brotli compression of
+	// the responses returned by the location
+	// +optional
+	DisableCompression bool `json:"disableCompression,omitempty"`
 	// HTTP2PushPreload allows to configure the HTTP2 Push Preload from backend
 	// original location.
 	// +optional
@@ -256,6 +322,14 @@ type Location struct {
 	// The Redirect annotation precedes RateLimit
 	// +optional
 	RateLimit ratelimit.Config `json:"rateLimit,omitempty"`
+	// GlobalRateLimit describes a limit in the number of requests
+	// enforced across all the replicas using a shared store.
+	// +optional
+	GlobalRateLimit globalratelimit.Config `json:"globalRateLimit,omitempty"`
+	// BypassIPAccess excludes the location from the global
+	// lists of denied and allowed CIDRs.
+	// +optional
+	BypassIPAccess bool `json:"bypassIPAccess,omitempty"`
 	// Redirect describes a temporal o permanent redirection this location.
 	// +optional
 	Redirect redirect.Config `json:"redirect,omitempty"`
@@ -280,10 +354,32 @@ type Location struct {
 	// to the request.
 	// +optional
 	Connection connection.Config `json:"connection"`
+	// WebSocket contains the Upgrade header and proxy timeouts
+	// of WebSocket connections
+	// +optional
+	WebSocket websocket.Config `json:"websocket"`
+	// Mirror contains the target receiving a copy of the requests
+	// +optional
+	Mirror mirror.Config `json:"mirror,omitempty"`
+	// FastCGI contains the index and params of FastCGI backends
+	// +optional
+	FastCGI fastcgi.Config `json:"fastcgi,omitempty"`
+	// CustomHeaders contains the headers read from ConfigMaps that are
+	// sent to the upstream servers and added to the responses
+	// +optional
+	CustomHeaders customheaders.Config `json:"customHeaders,omitempty"`
+	// ProxySSL contains the client certificate presented to the upstream
+	// servers and the verification of their certificates
+	// +optional
+	ProxySSL proxyssl.Config `json:"proxySSL"`
 	// ClientBodyBufferSize allows for the configuration of the client body
 	// buffer size for a specific location.
 	// +optional
 	ClientBodyBufferSize string `json:"clientBodyBufferSize,omitempty"`
+	// BodySizeError describes the response sent to the requests with a body
+	// larger than the maximum size allowed by the location.
+	// +optional
+	BodySizeError bodysize.Config `json:"bodySizeError,omitempty"`
 	// DefaultBackend allows the use of a custom default backend for this location.
 	// +optional
 	DefaultBackend *apiv1.Service `json:"defaultBackend,omitempty"`
@@ -293,10 +389,13 @@ type Location struct {
 	// XForwardedPrefix allows to add a header X-Forwarded-Prefix to the request with the
 	// original location.
 	// +optional
-	XForwardedPrefix bool `json:"xForwardedPrefix,omitempty"`
+	XForwardedPrefix xforwardedprefix.Config `json:"xForwardedPrefix,omitempty"`
 	// Logs allows to enable or disable the nginx logs
 	// By default access logs are enabled and rewrite logs are disabled
 	Logs log.Config `json:"logs,omitempty"`
+	// Opentracing overrides the global tracing of the requests
+	// +optional
+	Opentracing opentracing.Config `json:"opentracing,omitempty"`
 	// LuaRestyWAF contains parameters to configure lua-resty-waf
 	LuaRestyWAF luarestywaf.Config `json:"luaRestyWAF"`
 	// InfluxDB allows to monitor the incoming request by sending them to an influxdb database
@@ -313,6 +412,10 @@ type Location struct {
 	ModSecurity modsecurity.Config `json:"modsecurity"`
 	// Satisfy dictates allow access if any or all is set
 	Satisfy string `json:"satisfy"`
+	// HSTS defines the Strict-Transport-Security header returned
+	// in the responses of the location
+	// +optional
+	HSTS hsts.Config `json:"hsts"`
 }
 
 // SSLPassthroughBackend describes a SSL upstream server configured
@@ -326,6 +429,9 @@ type SSLPassthroughBackend struct {
 	Backend string `json:"namespace,omitempty"`
 	// Hostname returns the FQDN of the server
 	Hostname string `json:"hostname"`
+	// ProxyProtocol is the version of the PROXY protocol header
+	// sent to the upstream. Empty disables it
+	ProxyProtocol string `json:"proxyProtocol,omitempty"`
 }
 
 // L4Service describes a L4 Ingress service.
@@ -338,6 +444,10 @@ type L4Service struct {
 	Endpoints []Endpoint `json:"endpoints,omitempty"`
 	// k8s Service
 	Service *apiv1.Service `json:"service,omitempty"`
+	// SSLCert certificate used to terminate TLS connections in the port
+	SSLCert *SSLCert `json:"sslCert,omitempty"`
+	// Snippet contains custom configuration for the server of the port
+	Snippet string `json:"snippet,omitempty"`
 }
 
 // L4Backend describes the kubernetes service behind L4 Ingress service
@@ -348,6 +458,18 @@ type L4Backend struct {
 	Protocol  apiv1.Protocol     `json:"protocol"`
 	// +optional
 	ProxyProtocol ProxyProtocol `json:"proxyProtocol"`
+	// ProxyResponses number of datagrams expected from the endpoint in
+	// response to a client datagram. Only used in UDP services
+	// +optional
+	ProxyResponses string `json:"proxyResponses,omitempty"`
+	// ProxyTimeout timeout between two successive read or write operations
+	// on the client or endpoint connections. Only used in UDP services
+	// +optional
+	ProxyTimeout string `json:"proxyTimeout,omitempty"`
+	// SessionPersistence sends the datagrams of a client address to the
+	// same endpoint. Only used in UDP services
+	// +optional
+	SessionPersistence bool `json:"sessionPersistence,omitempty"`
 }
 
 // ProxyProtocol describes the proxy protocol configuration
@@ -362,6 +484,13 @@ type Ingress struct {
 	ParsedAnnotations *annotations.Ingress
 }
 
+// IPAccess holds the global lists of CIDRs denied and allowed to access the
+// servers. The allowed CIDRs take precedence over the denied ones.
+type IPAccess struct {
+	Denied  []string `json:"denied"`
+	Allowed []string `json:"allowed"`
+}
+
 // GeneralConfig holds the definition of lua general configuration data
 type GeneralConfig struct {
 	ControllerPodsCount int `json:"controllerPodsCount"`
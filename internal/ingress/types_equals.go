@@ -16,6 +16,10 @@ limitations under the License.
 
 package ingress
 
+import (
+	"bytes"
+)
+
 // Equal tests for equality between two Configuration types
 func (c1 *Configuration) Equal(c2 *Configuration) bool {
 	if c1 == c2 {
@@ -105,10 +109,58 @@ func (c1 *Configuration) Equal(c2 *Configuration) bool {
 		return false
 	}
 
+	if c1.SSLSessionTicketKeysChecksum != c2.SSLSessionTicketKeysChecksum {
+		return false
+	}
+
+	if len(c1.ACMEChallenges) != len(c2.ACMEChallenges) {
+		return false
+	}
+	for token, keyAuth := range c1.ACMEChallenges {
+		if c2.ACMEChallenges[token] != keyAuth {
+			return false
+		}
+	}
+
 	if c1.ControllerPodsCount != c2.ControllerPodsCount {
 		return false
 	}
 
+	if !(&c1.IPAccess).Equal(&c2.IPAccess) {
+		return false
+	}
+
+	return true
+}
+
+// Equal tests for equality between two IPAccess types
+func (a1 *IPAccess) Equal(a2 *IPAccess) bool {
+	if a1 == a2 {
+		return true
+	}
+	if a1 == nil || a2 == nil {
+		return false
+	}
+
+	if len(a1.Denied) != len(a2.Denied) {
+		return false
+	}
+	// CIDRs are sorted
+	for idx, cidr := range a1.Denied {
+		if cidr != a2.Denied[idx] {
+			return false
+		}
+	}
+
+	if len(a1.Allowed) != len(a2.Allowed) {
+		return false
+	}
+	for idx, cidr := range a1.Allowed {
+		if cidr != a2.Allowed[idx] {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -236,6 +288,18 @@ func (csa1 *CookieSessionAffinity) Equal(csa2 *CookieSessionAffinity) bool {
 	if csa1.MaxAge != csa2.MaxAge {
 		return false
 	}
+	if csa1.Secure != csa2.Secure {
+		return false
+	}
+	if csa1.SameSite != csa2.SameSite {
+		return false
+	}
+	if csa1.Domain != csa2.Domain {
+		return false
+	}
+	if csa1.ChangeOnFailure != csa2.ChangeOnFailure {
+		return false
+	}
 
 	return true
 }
@@ -323,9 +387,15 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLPassthrough != s2.SSLPassthrough {
 		return false
 	}
+	if s1.SSLPassthroughProxyProtocol != s2.SSLPassthroughProxyProtocol {
+		return false
+	}
 	if !(&s1.SSLCert).Equal(&s2.SSLCert) {
 		return false
 	}
+	if !(&s1.SSLCertECDSA).Equal(&s2.SSLCertECDSA) {
+		return false
+	}
 	if s1.Alias != s2.Alias {
 		return false
 	}
@@ -341,6 +411,15 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLCiphers != s2.SSLCiphers {
 		return false
 	}
+	if s1.SSLProtocols != s2.SSLProtocols {
+		return false
+	}
+	if s1.UseHTTP2 != s2.UseHTTP2 {
+		return false
+	}
+	if s1.UseHTTP3 != s2.UseHTTP3 {
+		return false
+	}
 	if s1.AuthTLSError != s2.AuthTLSError {
 		return false
 	}
@@ -404,12 +483,41 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if !(&l1.ExternalAuth).Equal(&l2.ExternalAuth) {
 		return false
 	}
+	if l1.EnableGlobalAuth != l2.EnableGlobalAuth {
+		return false
+	}
+	if !(&l1.OIDC).Equal(&l2.OIDC) {
+		return false
+	}
+	if !(&l1.JWT).Equal(&l2.JWT) {
+		return false
+	}
+	if len(l1.Plugins) != len(l2.Plugins) {
+		return false
+	}
+	for i, plugin := range l1.Plugins {
+		if plugin != l2.Plugins[i] {
+			return false
+		}
+	}
+	if l1.GRPCWeb != l2.GRPCWeb {
+		return false
+	}
+	if l1.DisableCompression != l2.DisableCompression {
+		return false
+	}
 	if l1.HTTP2PushPreload != l2.HTTP2PushPreload {
 		return false
 	}
 	if !(&l1.RateLimit).Equal(&l2.RateLimit) {
 		return false
 	}
+	if !(&l1.GlobalRateLimit).Equal(&l2.GlobalRateLimit) {
+		return false
+	}
+	if l1.BypassIPAccess != l2.BypassIPAccess {
+		return false
+	}
 	if !(&l1.Redirect).Equal(&l2.Redirect) {
 		return false
 	}
@@ -431,18 +539,42 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if l1.ClientBodyBufferSize != l2.ClientBodyBufferSize {
 		return false
 	}
+	if !(&l1.BodySizeError).Equal(&l2.BodySizeError) {
+		return false
+	}
 	if l1.UpstreamVhost != l2.UpstreamVhost {
 		return false
 	}
-	if l1.XForwardedPrefix != l2.XForwardedPrefix {
+	if !(&l1.UpstreamKeepalive).Equal(&l2.UpstreamKeepalive) {
+		return false
+	}
+	if !(&l1.XForwardedPrefix).Equal(&l2.XForwardedPrefix) {
 		return false
 	}
 	if !(&l1.Connection).Equal(&l2.Connection) {
 		return false
 	}
+	if !(&l1.WebSocket).Equal(&l2.WebSocket) {
+		return false
+	}
+	if !(&l1.Mirror).Equal(&l2.Mirror) {
+		return false
+	}
+	if !(&l1.FastCGI).Equal(&l2.FastCGI) {
+		return false
+	}
+	if !(&l1.CustomHeaders).Equal(&l2.CustomHeaders) {
+		return false
+	}
+	if !(&l1.ProxySSL).Equal(&l2.ProxySSL) {
+		return false
+	}
 	if !(&l1.Logs).Equal(&l2.Logs) {
 		return false
 	}
+	if !(&l1.Opentracing).Equal(&l2.Opentracing) {
+		return false
+	}
 	if !(&l1.LuaRestyWAF).Equal(&l2.LuaRestyWAF) {
 		return false
 	}
@@ -479,6 +611,10 @@ func (l1 *Location) Equal(l2 *Location) bool {
 		return false
 	}
 
+	if !(&l1.HSTS).Equal(&l2.HSTS) {
+		return false
+	}
+
 	if l1.DefaultBackendUpstreamName != l2.DefaultBackendUpstreamName {
 		return false
 	}
@@ -503,6 +639,9 @@ func (ptb1 *SSLPassthroughBackend) Equal(ptb2 *SSLPassthroughBackend) bool {
 	if ptb1.Port != ptb2.Port {
 		return false
 	}
+	if ptb1.ProxyProtocol != ptb2.ProxyProtocol {
+		return false
+	}
 
 	if ptb1.Service != ptb2.Service {
 		if ptb1.Service == nil || ptb2.Service == nil {
@@ -533,6 +672,12 @@ func (e1 *L4Service) Equal(e2 *L4Service) bool {
 	if !(&e1.Backend).Equal(&e2.Backend) {
 		return false
 	}
+	if !(e1.SSLCert).Equal(e2.SSLCert) {
+		return false
+	}
+	if e1.Snippet != e2.Snippet {
+		return false
+	}
 	if len(e1.Endpoints) != len(e2.Endpoints) {
 		return false
 	}
@@ -573,6 +718,18 @@ func (l4b1 *L4Backend) Equal(l4b2 *L4Backend) bool {
 	if l4b1.Protocol != l4b2.Protocol {
 		return false
 	}
+	if l4b1.ProxyProtocol != l4b2.ProxyProtocol {
+		return false
+	}
+	if l4b1.ProxyResponses != l4b2.ProxyResponses {
+		return false
+	}
+	if l4b1.ProxyTimeout != l4b2.ProxyTimeout {
+		return false
+	}
+	if l4b1.SessionPersistence != l4b2.SessionPersistence {
+		return false
+	}
 
 	return true
 }
@@ -597,9 +754,21 @@ func (s1 *SSLCert) Equal(s2 *SSLCert) bool {
 	if s1.FullChainPemFileName != s2.FullChainPemFileName {
 		return false
 	}
+	if s1.CRLFileName != s2.CRLFileName {
+		return false
+	}
+	if s1.CRLSHA != s2.CRLSHA {
+		return false
+	}
 	if s1.PemCertKey != s2.PemCertKey {
 		return false
 	}
+	if s1.OCSPFileName != s2.OCSPFileName {
+		return false
+	}
+	if !bytes.Equal(s1.OCSPResponse, s2.OCSPResponse) {
+		return false
+	}
 
 	for _, cn1 := range s1.CN {
 		found := false
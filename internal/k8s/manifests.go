@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog"
+)
+
+// ReadManifests returns the objects defined in the YAML or JSON files
+// (extensions .yaml, .yml and .json) of a directory and its subdirectories
+func ReadManifests(dir string) ([]runtime.Object, error) {
+	objects := []runtime.Object{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		objs, err := ParseManifests(f)
+		if err != nil {
+			return fmt.Errorf("error reading manifests from %v: %v", path, err)
+		}
+
+		objects = append(objects, objs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// ParseManifests returns the objects defined in a stream of YAML documents
+// or JSON objects. The items of List objects are returned as single objects
+// and the objects without namespace are placed in the default namespace.
+// Objects of unknown kinds are ignored.
+func ParseManifests(r io.Reader) ([]runtime.Object, error) {
+	objects := []runtime.Object{}
+
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		raw := runtime.RawExtension{}
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(raw.Raw) == 0 {
+			// empty document
+			continue
+		}
+
+		objs, err := decodeManifest(raw.Raw)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, objs...)
+	}
+
+	return objects, nil
+}
+
+func decodeManifest(data []byte) ([]runtime.Object, error) {
+	obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		klog.Warningf("Ignoring manifest of unknown kind: %v", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if list, ok := obj.(*apiv1.List); ok {
+		objects := []runtime.Object{}
+		for _, item := range list.Items {
+			objs, err := decodeManifest(item.Raw)
+			if err != nil {
+				return nil, err
+			}
+
+			objects = append(objects, objs...)
+		}
+
+		return objects, nil
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v object: %v", gvk.Kind, err)
+	}
+
+	switch obj.(type) {
+	case *apiv1.Namespace, *apiv1.Node:
+	default:
+		if accessor.GetNamespace() == "" {
+			accessor.SetNamespace(apiv1.NamespaceDefault)
+		}
+	}
+
+	return []runtime.Object{obj}, nil
+}
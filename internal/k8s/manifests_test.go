@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+const testManifests = `
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: demo
+spec:
+  backend:
+    serviceName: demo
+    servicePort: 80
+---
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: demo
+    namespace: apps
+  spec:
+    ports:
+    - port: 80
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: apps
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`
+
+func TestParseManifests(t *testing.T) {
+	objects, err := ParseManifests(strings.NewReader(testManifests))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects but returned %v", len(objects))
+	}
+
+	ing, ok := objects[0].(*extensions.Ingress)
+	if !ok {
+		t.Fatalf("expected an Ingress but returned %T", objects[0])
+	}
+	if ing.Namespace != apiv1.NamespaceDefault {
+		t.Errorf("expected the Ingress in the default namespace but returned %q", ing.Namespace)
+	}
+
+	svc, ok := objects[1].(*apiv1.Service)
+	if !ok {
+		t.Fatalf("expected a Service but returned %T", objects[1])
+	}
+	if svc.Namespace != "apps" {
+		t.Errorf("expected the Service in the namespace apps but returned %q", svc.Namespace)
+	}
+
+	ns, ok := objects[2].(*apiv1.Namespace)
+	if !ok {
+		t.Fatalf("expected a Namespace but returned %T", objects[2])
+	}
+	if ns.Namespace != "" {
+		t.Errorf("expected a Namespace without namespace but returned %q", ns.Namespace)
+	}
+}
+
+func TestParseInvalidManifests(t *testing.T) {
+	_, err := ParseManifests(strings.NewReader("kind: [Ingress"))
+	if err == nil {
+		t.Errorf("expected an error parsing an invalid manifest")
+	}
+}
+
+func TestReadManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifests")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"ingress.yaml":         testManifests,
+		"nested/secret.json":   `{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "tls"}}`,
+		"nested/README.md":     "apiVersion: v1",
+		"nested/configmap.yml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"nested/ignored.yaml~": "invalid",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	objects, err := ReadManifests(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(objects) != 5 {
+		t.Errorf("expected 5 objects but returned %v", len(objects))
+	}
+}
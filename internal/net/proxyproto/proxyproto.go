@@ -0,0 +1,324 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxyproto implements the versions 1 and 2 of the PROXY protocol
+// https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// V1 is the human-readable version of the PROXY protocol
+	V1 = "v1"
+	// V2 is the binary version of the PROXY protocol
+	V2 = "v2"
+
+	// maximum length of a version 1 header, including the CRLF
+	v1MaxLength = 107
+
+	v2HeaderLength = 16
+	v2CmdLocal     = 0x20
+	v2CmdProxy     = 0x21
+	v2FamTCP4      = 0x11
+	v2FamTCP6      = 0x21
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// Listener wraps a net.Listener decoding the PROXY protocol header
+// (version 1 or 2) sent at the beginning of the accepted connections.
+// Connections without a header are returned unmodified.
+type Listener struct {
+	Listener net.Listener
+	// ProxyHeaderTimeout is the maximum time to receive the header.
+	// Zero means no timeout.
+	ProxyHeaderTimeout time.Duration
+}
+
+// Accept waits for and returns the next connection to the listener
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn, l.ProxyHeaderTimeout), nil
+}
+
+// Close closes the underlying listener
+func (l *Listener) Close() error {
+	return l.Listener.Close()
+}
+
+// Addr returns the address of the underlying listener
+func (l *Listener) Addr() net.Addr {
+	return l.Listener.Addr()
+}
+
+// Conn is a connection that may start with a PROXY protocol header.
+// The header is read on the first call to Read, LocalAddr or RemoteAddr,
+// which then return the addresses of the original connection.
+type Conn struct {
+	net.Conn
+
+	bufReader     *bufio.Reader
+	headerTimeout time.Duration
+
+	once    sync.Once
+	srcAddr net.Addr
+	dstAddr net.Addr
+	err     error
+}
+
+// NewConn wraps a connection that may start with a PROXY protocol header
+func NewConn(conn net.Conn, headerTimeout time.Duration) *Conn {
+	return &Conn{
+		Conn:          conn,
+		bufReader:     bufio.NewReader(conn),
+		headerTimeout: headerTimeout,
+	}
+}
+
+// Read reads data from the connection, after the PROXY protocol header
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.bufReader.Read(b)
+}
+
+// LocalAddr returns the destination address sent in the PROXY protocol
+// header or the local address of the connection
+func (c *Conn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.dstAddr != nil {
+		return c.dstAddr
+	}
+
+	return c.Conn.LocalAddr()
+}
+
+// RemoteAddr returns the source address sent in the PROXY protocol
+// header or the remote address of the connection
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.srcAddr != nil {
+		return c.srcAddr
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.headerTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	first, err := c.bufReader.Peek(1)
+	if err != nil {
+		if err != io.EOF {
+			c.err = err
+		}
+		return
+	}
+
+	switch first[0] {
+	case v1Prefix[0]:
+		c.err = c.readV1()
+	case v2Signature[0]:
+		c.err = c.readV2()
+	}
+}
+
+// readV1 reads a header like "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"
+func (c *Conn) readV1() error {
+	prefix, err := c.bufReader.Peek(len(v1Prefix))
+	if err != nil || !bytes.Equal(prefix, v1Prefix) {
+		// not a PROXY protocol header
+		return nil
+	}
+
+	line, err := c.peekV1Line()
+	if err != nil {
+		return err
+	}
+	if _, err := c.bufReader.Discard(len(line)); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return fmt.Errorf("invalid PROXY protocol header %q", line)
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("invalid PROXY protocol header %q", line)
+	}
+
+	srcAddr, err := parseV1Addr(fields[2], fields[4])
+	if err != nil {
+		return err
+	}
+	dstAddr, err := parseV1Addr(fields[3], fields[5])
+	if err != nil {
+		return err
+	}
+
+	c.srcAddr = srcAddr
+	c.dstAddr = dstAddr
+
+	return nil
+}
+
+// peekV1Line returns the first line of the connection, without reading
+// more than v1MaxLength bytes when the line has no end of line.
+func (c *Conn) peekV1Line() (string, error) {
+	for n := len(v1Prefix) + 1; n <= v1MaxLength; n++ {
+		buf, err := c.bufReader.Peek(n)
+		if err != nil {
+			return "", fmt.Errorf("reading PROXY protocol header: %v", err)
+		}
+		if buf[n-1] == '\n' {
+			return string(buf), nil
+		}
+	}
+
+	return "", fmt.Errorf("PROXY protocol header longer than %v bytes", v1MaxLength)
+}
+
+func parseV1Addr(ip, port string) (*net.TCPAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("invalid address %q in PROXY protocol header", ip)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q in PROXY protocol header", port)
+	}
+
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+func (c *Conn) readV2() error {
+	header, err := c.bufReader.Peek(v2HeaderLength)
+	if err != nil || !bytes.Equal(header[:len(v2Signature)], v2Signature) {
+		// not a PROXY protocol header
+		return nil
+	}
+
+	cmd := header[12]
+	fam := header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+
+	if _, err := c.bufReader.Discard(v2HeaderLength); err != nil {
+		return err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.bufReader, payload); err != nil {
+		return fmt.Errorf("reading PROXY protocol header: %v", err)
+	}
+
+	switch cmd {
+	case v2CmdLocal:
+		// health checks from the proxy, keep the original addresses
+		return nil
+	case v2CmdProxy:
+	default:
+		return fmt.Errorf("invalid PROXY protocol version or command 0x%x", cmd)
+	}
+
+	var ipLength int
+	switch fam {
+	case v2FamTCP4:
+		ipLength = net.IPv4len
+	case v2FamTCP6:
+		ipLength = net.IPv6len
+	default:
+		// unsupported address family, keep the original addresses
+		return nil
+	}
+
+	if length < 2*ipLength+4 {
+		return fmt.Errorf("invalid PROXY protocol header length %v", length)
+	}
+
+	c.srcAddr = &net.TCPAddr{
+		IP:   net.IP(payload[:ipLength]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLength:])),
+	}
+	c.dstAddr = &net.TCPAddr{
+		IP:   net.IP(payload[ipLength : 2*ipLength]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLength+2:])),
+	}
+
+	return nil
+}
+
+// EncodeV1 returns the version 1 header of a connection from src to dst
+func EncodeV1(src, dst *net.TCPAddr) []byte {
+	protocol := "UNKNOWN"
+	if src.IP.To4() != nil {
+		protocol = "TCP4"
+	} else if src.IP.To16() != nil {
+		protocol = "TCP6"
+	}
+
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", protocol, src.IP.String(), dst.IP.String(), src.Port, dst.Port))
+}
+
+// EncodeV2 returns the version 2 header of a connection from src to dst
+func EncodeV2(src, dst *net.TCPAddr) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, v2HeaderLength+36))
+	buf.Write(v2Signature)
+	buf.WriteByte(v2CmdProxy)
+
+	srcIP, dstIP := src.IP.To4(), dst.IP.To4()
+	if srcIP != nil && dstIP != nil {
+		buf.WriteByte(v2FamTCP4)
+	} else {
+		srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+		buf.WriteByte(v2FamTCP6)
+	}
+
+	binary.Write(buf, binary.BigEndian, uint16(2*len(srcIP)+4))
+	buf.Write(srcIP)
+	buf.Write(dstIP)
+	binary.Write(buf, binary.BigEndian, uint16(src.Port))
+	binary.Write(buf, binary.BigEndian, uint16(dst.Port))
+
+	return buf.Bytes()
+}
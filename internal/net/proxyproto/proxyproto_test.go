@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyproto
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestConn(t *testing.T, data []byte) *Conn {
+	server, client := net.Pipe()
+	go func() {
+		client.Write(data)
+		client.Close()
+	}()
+
+	return NewConn(server, time.Second)
+}
+
+func TestConn(t *testing.T) {
+	src4 := &net.TCPAddr{IP: net.ParseIP("10.0.0.1").To4(), Port: 56324}
+	dst4 := &net.TCPAddr{IP: net.ParseIP("10.0.0.2").To4(), Port: 443}
+	src6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+	dst6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}
+
+	testCases := map[string]struct {
+		header      []byte
+		expectedSrc string
+		expectedDst string
+	}{
+		"no header":       {nil, "pipe", "pipe"},
+		"v1 TCP4":         {EncodeV1(src4, dst4), "10.0.0.1:56324", "10.0.0.2:443"},
+		"v1 TCP6":         {EncodeV1(src6, dst6), "[2001:db8::1]:56324", "[2001:db8::2]:443"},
+		"v1 UNKNOWN":      {[]byte("PROXY UNKNOWN\r\n"), "pipe", "pipe"},
+		"v2 TCP4":         {EncodeV2(src4, dst4), "10.0.0.1:56324", "10.0.0.2:443"},
+		"v2 TCP6":         {EncodeV2(src6, dst6), "[2001:db8::1]:56324", "[2001:db8::2]:443"},
+		"v2 LOCAL":        {append(append([]byte{}, v2Signature...), v2CmdLocal, 0x00, 0x00, 0x00), "pipe", "pipe"},
+		"v2 TCP4 and TLV": {append(tlvHeader(EncodeV2(src4, dst4)), 0x04, 0x00, 0x01, 0x00), "10.0.0.1:56324", "10.0.0.2:443"},
+	}
+
+	for name, tc := range testCases {
+		conn := newTestConn(t, append(tc.header, []byte("payload")...))
+
+		if conn.RemoteAddr().String() != tc.expectedSrc {
+			t.Errorf("%v: expected remote address %v but returned %v", name, tc.expectedSrc, conn.RemoteAddr())
+		}
+		if conn.LocalAddr().String() != tc.expectedDst {
+			t.Errorf("%v: expected local address %v but returned %v", name, tc.expectedDst, conn.LocalAddr())
+		}
+
+		data, err := ioutil.ReadAll(conn)
+		if err != nil {
+			t.Errorf("%v: unexpected error reading the connection: %v", name, err)
+		}
+		if string(data) != "payload" {
+			t.Errorf("%v: expected payload but returned %q", name, data)
+		}
+	}
+}
+
+// tlvHeader increments the length of a version 2 header
+// to include a 4 bytes TLV appended by the caller
+func tlvHeader(header []byte) []byte {
+	header[15] += 4
+	return header
+}
+
+func TestConnInvalidHeader(t *testing.T) {
+	invalid := [][]byte{
+		[]byte("PROXY TCP4 10.0.0.1\r\n"),
+		[]byte("PROXY TCP4 10.0.0.1 10.0.0.2 56324 99999\r\n"),
+		[]byte("PROXY TCP4 10.0.0.1 10.0.0.2 56324 443\n"),
+		[]byte("PROXY TCP4 10.0.0.1 10.0.0.2 56324 443" + strings.Repeat(" ", 100) + "\r\n"),
+		append(append([]byte{}, v2Signature...), 0x22, v2FamTCP4, 0x00, 0x0c),
+		append(append([]byte{}, v2Signature...), v2CmdProxy, v2FamTCP4, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01),
+	}
+
+	for _, header := range invalid {
+		conn := newTestConn(t, append(header, []byte("payload")...))
+		_, err := ioutil.ReadAll(conn)
+		if err == nil {
+			t.Errorf("expected an error reading a connection with header %q", header)
+		}
+	}
+}
+
+func TestEncodeV1(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 443}
+
+	expected := "PROXY TCP4 10.0.0.1 10.0.0.2 56324 443\r\n"
+	if header := string(EncodeV1(src, dst)); header != expected {
+		t.Errorf("expected %q but returned %q", expected, header)
+	}
+}
+
+func TestConnHeaderWithoutEndOfLine(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	go client.Write([]byte("PROXY " + strings.Repeat("A", 1<<20)))
+
+	// the client never ends the header, the read fails after v1MaxLength
+	// bytes instead of waiting for the end of line or the timeout
+	conn := NewConn(server, time.Minute)
+	_, err := conn.Read(make([]byte, 1))
+	if err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Errorf("expected an error reading a header without end of line but returned %v", err)
+	}
+}
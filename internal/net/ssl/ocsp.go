@@ -0,0 +1,386 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/zakjan/cert-chain-resolver/certUtil"
+)
+
+// Status of a certificate in an OCSP response
+// https://tools.ietf.org/html/rfc6960#section-4.2.1
+const (
+	OCSPGood    = 0
+	OCSPRevoked = 1
+	OCSPUnknown = 2
+)
+
+const (
+	// maximum size of a response returned by an OCSP responder
+	ocspMaxResponseSize = 1024 * 1024
+	// allowed clock skew between the controller and the OCSP responder
+	ocspClockSkew = 5 * time.Minute
+)
+
+var (
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+	ocspSignatureAlgorithms = []struct {
+		oid       asn1.ObjectIdentifier
+		algorithm x509.SignatureAlgorithm
+	}{
+		{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, x509.SHA1WithRSA},
+		{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, x509.SHA256WithRSA},
+		{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, x509.SHA384WithRSA},
+		{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, x509.SHA512WithRSA},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}, x509.ECDSAWithSHA1},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}, x509.ECDSAWithSHA256},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, x509.ECDSAWithSHA384},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, x509.ECDSAWithSHA512},
+	}
+
+	ocspClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// ASN.1 structures of OCSP requests and responses
+// https://tools.ietf.org/html/rfc6960#section-4
+
+type ocspCertID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspSingleRequest struct {
+	CertID ocspCertID
+}
+
+type ocspTBSRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []ocspSingleRequest
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw         asn1.RawContent
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponse
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// OCSPResponse contains a response from an OCSP responder
+// about the status of a certificate
+type OCSPResponse struct {
+	// Status is one of OCSPGood, OCSPRevoked or OCSPUnknown
+	Status     int
+	ThisUpdate time.Time
+	// NextUpdate is the time after which the response must not be used.
+	// Zero means newer information is always available
+	NextUpdate time.Time
+	// Raw contains the DER encoded response
+	Raw []byte
+}
+
+// RefreshTime returns the time a new response should be requested,
+// halfway through the validity period of the response
+func (r *OCSPResponse) RefreshTime() time.Time {
+	if r.NextUpdate.IsZero() {
+		return r.ThisUpdate.Add(time.Hour)
+	}
+
+	return r.ThisUpdate.Add(r.NextUpdate.Sub(r.ThisUpdate) / 2)
+}
+
+// CreateOCSPRequest returns the DER encoded OCSP request
+// for a certificate issued by issuer
+func CreateOCSPRequest(cert, issuer *x509.Certificate) ([]byte, error) {
+	certID, err := newOCSPCertID(cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{{CertID: *certID}},
+		},
+	})
+}
+
+func newOCSPCertID(cert, issuer *x509.Certificate) (*ocspCertID, error) {
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, fmt.Errorf("parsing public key of issuer %v: %v", issuer.Subject, err)
+	}
+
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(publicKeyInfo.PublicKey.RightAlign())
+
+	return &ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA1,
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		},
+		IssuerNameHash: nameHash[:],
+		IssuerKeyHash:  keyHash[:],
+		SerialNumber:   cert.SerialNumber,
+	}, nil
+}
+
+// ParseOCSPResponse parses and verifies a DER encoded OCSP response
+// for a certificate issued by issuer
+func ParseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (*OCSPResponse, error) {
+	var resp ocspResponse
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OCSP response: %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data in OCSP response")
+	}
+
+	if resp.Status != 0 {
+		return nil, fmt.Errorf("OCSP responder returned an error status %v", resp.Status)
+	}
+
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, fmt.Errorf("unsupported OCSP response type %v", resp.ResponseBytes.ResponseType)
+	}
+
+	var basic ocspBasicResponse
+	rest, err = asn1.Unmarshal(resp.ResponseBytes.Response, &basic)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OCSP basic response: %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data in OCSP basic response")
+	}
+
+	err = verifyOCSPSignature(&basic, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, single := range basic.TBSResponseData.Responses {
+		if single.CertID.SerialNumber == nil || single.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+
+		r := &OCSPResponse{
+			ThisUpdate: single.ThisUpdate,
+			NextUpdate: single.NextUpdate,
+			Raw:        der,
+		}
+
+		switch {
+		case bool(single.Good):
+			r.Status = OCSPGood
+		case bool(single.Unknown):
+			r.Status = OCSPUnknown
+		default:
+			r.Status = OCSPRevoked
+		}
+
+		now := time.Now()
+		if r.ThisUpdate.After(now.Add(ocspClockSkew)) {
+			return nil, fmt.Errorf("OCSP response is not yet valid (this update %v)", r.ThisUpdate)
+		}
+		if !r.NextUpdate.IsZero() && r.NextUpdate.Before(now) {
+			return nil, fmt.Errorf("OCSP response expired (next update %v)", r.NextUpdate)
+		}
+
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("OCSP response does not contain the status of certificate %v", cert.SerialNumber)
+}
+
+// verifyOCSPSignature checks the response is signed by the issuer or
+// by a responder certificate delegated by the issuer
+func verifyOCSPSignature(basic *ocspBasicResponse, issuer *x509.Certificate) error {
+	var algorithm x509.SignatureAlgorithm
+	for _, sa := range ocspSignatureAlgorithms {
+		if sa.oid.Equal(basic.SignatureAlgorithm.Algorithm) {
+			algorithm = sa.algorithm
+			break
+		}
+	}
+	if algorithm == x509.UnknownSignatureAlgorithm {
+		return fmt.Errorf("unsupported OCSP response signature algorithm %v", basic.SignatureAlgorithm.Algorithm)
+	}
+
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		responder, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return fmt.Errorf("parsing OCSP responder certificate: %v", err)
+		}
+
+		if !bytes.Equal(responder.Raw, issuer.Raw) {
+			err = responder.CheckSignatureFrom(issuer)
+			if err != nil {
+				return fmt.Errorf("OCSP responder certificate is not signed by the issuer: %v", err)
+			}
+
+			delegated := false
+			for _, usage := range responder.ExtKeyUsage {
+				if usage == x509.ExtKeyUsageOCSPSigning {
+					delegated = true
+					break
+				}
+			}
+			if !delegated {
+				return fmt.Errorf("OCSP responder certificate is not valid for OCSP signing")
+			}
+		}
+
+		signer = responder
+	}
+
+	err := signer.CheckSignature(algorithm, basic.TBSResponseData.Raw, basic.Signature.RightAlign())
+	if err != nil {
+		return fmt.Errorf("invalid OCSP response signature: %v", err)
+	}
+
+	return nil
+}
+
+// FetchOCSPResponse requests the status of a certificate
+// to the OCSP responder included in the certificate
+func FetchOCSPResponse(cert, issuer *x509.Certificate) (*OCSPResponse, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, fmt.Errorf("certificate %v does not contain an OCSP responder URL", cert.Subject)
+	}
+
+	req, err := CreateOCSPRequest(cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, cert.OCSPServer[0], bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := ocspClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("requesting OCSP response to %v: %v", cert.OCSPServer[0], err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %v returned status code %v", cert.OCSPServer[0], httpResp.StatusCode)
+	}
+
+	der, err := ioutil.ReadAll(&io.LimitedReader{R: httpResp.Body, N: ocspMaxResponseSize})
+	if err != nil {
+		return nil, fmt.Errorf("reading OCSP response from %v: %v", cert.OCSPServer[0], err)
+	}
+
+	return ParseOCSPResponse(der, cert, issuer)
+}
+
+// OCSPIssuer returns the issuer of a certificate from a PEM encoded
+// chain or, when it is not present, from the URL included in the certificate
+func OCSPIssuer(cert *x509.Certificate, chain []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		candidate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate, nil
+		}
+	}
+
+	certs, err := certUtil.FetchCertificateChain(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range certs {
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("issuer of certificate %v not found", cert.Subject)
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newOCSPResponse creates a DER encoded OCSP response signed by signer
+func newOCSPResponse(t *testing.T, cert *x509.Certificate, signer *keyPair, status int, thisUpdate, nextUpdate time.Time) []byte {
+	single := ocspSingleResponse{
+		CertID: ocspCertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidSHA1,
+				Parameters: asn1.RawValue{Tag: asn1.TagNull},
+			},
+			SerialNumber: cert.SerialNumber,
+		},
+		ThisUpdate: thisUpdate,
+		NextUpdate: nextUpdate,
+	}
+
+	switch status {
+	case OCSPGood:
+		single.Good = true
+	case OCSPUnknown:
+		single.Unknown = true
+	default:
+		single.Revoked = ocspRevokedInfo{RevocationTime: thisUpdate}
+	}
+
+	data := ocspResponseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: signer.Cert.RawSubject},
+		ProducedAt:  thisUpdate,
+		Responses:   []ocspSingleResponse{single},
+	}
+	tbs, err := asn1.Marshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error encoding OCSP response data: %v", err)
+	}
+
+	hashed := sha256.Sum256(tbs)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer.Key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("unexpected error signing OCSP response: %v", err)
+	}
+
+	basic, err := asn1.Marshal(ocspBasicResponse{
+		TBSResponseData: ocspResponseData{Raw: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11},
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		},
+		Signature: asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error encoding OCSP basic response: %v", err)
+	}
+
+	der, err := asn1.Marshal(ocspResponse{
+		ResponseBytes: ocspResponseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     basic,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error encoding OCSP response: %v", err)
+	}
+
+	return der
+}
+
+func TestCreateOCSPRequest(t *testing.T) {
+	cert, ca, err := generateRSACerts("echoheaders")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	der, err := CreateOCSPRequest(cert.Cert, ca.Cert)
+	if err != nil {
+		t.Fatalf("unexpected error creating OCSP request: %v", err)
+	}
+
+	var req ocspRequest
+	_, err = asn1.Unmarshal(der, &req)
+	if err != nil {
+		t.Fatalf("unexpected error parsing OCSP request: %v", err)
+	}
+
+	if len(req.TBSRequest.RequestList) != 1 {
+		t.Fatalf("expected one certificate in the OCSP request but %v returned", len(req.TBSRequest.RequestList))
+	}
+
+	certID := req.TBSRequest.RequestList[0].CertID
+	if certID.SerialNumber.Cmp(cert.Cert.SerialNumber) != 0 {
+		t.Errorf("expected serial number %v but %v returned", cert.Cert.SerialNumber, certID.SerialNumber)
+	}
+	if !certID.HashAlgorithm.Algorithm.Equal(oidSHA1) {
+		t.Errorf("expected SHA1 hash algorithm but %v returned", certID.HashAlgorithm.Algorithm)
+	}
+	if len(certID.IssuerNameHash) != 20 || len(certID.IssuerKeyHash) != 20 {
+		t.Errorf("expected SHA1 hashes of the issuer name and key")
+	}
+}
+
+func TestParseOCSPResponse(t *testing.T) {
+	cert, ca, err := generateRSACerts("echoheaders")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	other, err := newCA("other-ca")
+	if err != nil {
+		t.Fatalf("unexpected error creating CA: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	testCases := map[string]struct {
+		signer     *keyPair
+		status     int
+		thisUpdate time.Time
+		nextUpdate time.Time
+		valid      bool
+	}{
+		"good":           {ca, OCSPGood, now.Add(-time.Hour), now.Add(time.Hour), true},
+		"revoked":        {ca, OCSPRevoked, now.Add(-time.Hour), now.Add(time.Hour), true},
+		"unknown":        {ca, OCSPUnknown, now.Add(-time.Hour), now.Add(time.Hour), true},
+		"no next update": {ca, OCSPGood, now.Add(-time.Hour), time.Time{}, true},
+		"expired":        {ca, OCSPGood, now.Add(-2 * time.Hour), now.Add(-time.Hour), false},
+		"not yet valid":  {ca, OCSPGood, now.Add(time.Hour), now.Add(2 * time.Hour), false},
+		"invalid signer": {other, OCSPGood, now.Add(-time.Hour), now.Add(time.Hour), false},
+	}
+
+	for name, tc := range testCases {
+		der := newOCSPResponse(t, cert.Cert, tc.signer, tc.status, tc.thisUpdate, tc.nextUpdate)
+
+		resp, err := ParseOCSPResponse(der, cert.Cert, ca.Cert)
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("%v: expected an error parsing the OCSP response", name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error parsing the OCSP response: %v", name, err)
+			continue
+		}
+		if resp.Status != tc.status {
+			t.Errorf("%v: expected status %v but %v returned", name, tc.status, resp.Status)
+		}
+		if !resp.NextUpdate.Equal(tc.nextUpdate) {
+			t.Errorf("%v: expected next update %v but %v returned", name, tc.nextUpdate, resp.NextUpdate)
+		}
+		if !bytes.Equal(resp.Raw, der) {
+			t.Errorf("%v: expected the raw response to be returned", name)
+		}
+	}
+
+	_, err = ParseOCSPResponse([]byte("invalid"), cert.Cert, ca.Cert)
+	if err == nil {
+		t.Errorf("expected an error parsing an invalid OCSP response")
+	}
+}
+
+func TestOCSPResponseRefreshTime(t *testing.T) {
+	now := time.Now()
+
+	resp := &OCSPResponse{ThisUpdate: now, NextUpdate: now.Add(4 * 24 * time.Hour)}
+	if expected := now.Add(2 * 24 * time.Hour); !resp.RefreshTime().Equal(expected) {
+		t.Errorf("expected refresh time %v but %v returned", expected, resp.RefreshTime())
+	}
+
+	resp = &OCSPResponse{ThisUpdate: now}
+	if expected := now.Add(time.Hour); !resp.RefreshTime().Equal(expected) {
+		t.Errorf("expected refresh time %v but %v returned", expected, resp.RefreshTime())
+	}
+}
+
+func TestFetchOCSPResponse(t *testing.T) {
+	_, ca, err := generateRSACerts("echoheaders")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var cert *x509.Certificate
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req ocspRequest
+		if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write(newOCSPResponse(t, cert, ca, OCSPGood, now.Add(-time.Hour), now.Add(time.Hour)))
+	}))
+	defer server.Close()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "echoheaders"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		OCSPServer:   []string{server.URL},
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error creating private key: %v", err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.Key)
+	if err != nil {
+		t.Fatalf("unexpected error creating certificate: %v", err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error parsing certificate: %v", err)
+	}
+
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})...)
+
+	issuer, err := OCSPIssuer(cert, chain)
+	if err != nil {
+		t.Fatalf("unexpected error obtaining the issuer: %v", err)
+	}
+	if !bytes.Equal(issuer.Raw, ca.Cert.Raw) {
+		t.Fatalf("expected the CA certificate to be returned as issuer")
+	}
+
+	resp, err := FetchOCSPResponse(cert, issuer)
+	if err != nil {
+		t.Fatalf("unexpected error fetching OCSP response: %v", err)
+	}
+	if resp.Status != OCSPGood {
+		t.Errorf("expected status %v but %v returned", OCSPGood, resp.Status)
+	}
+
+	_, err = FetchOCSPResponse(&x509.Certificate{SerialNumber: big.NewInt(1)}, issuer)
+	if err == nil {
+		t.Errorf("expected an error fetching the OCSP response of a certificate without responder")
+	}
+}
@@ -20,10 +20,12 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -376,6 +378,50 @@ func AddCertAuth(name string, ca []byte, fs file.Filesystem) (*ingress.SSLCert,
 	}, nil
 }
 
+// ConfigureCRL creates the file with the certificate revocation list
+// of the CA used to authenticate clients
+func ConfigureCRL(name string, crl []byte, sslCert *ingress.SSLCert, fs file.Filesystem) error {
+	crlName := fmt.Sprintf("crl-%v.pem", name)
+	crlFileName := fmt.Sprintf("%v/%v", file.DefaultSSLDirectory, crlName)
+
+	pemCRLBlock, _ := pem.Decode(crl)
+	if pemCRLBlock == nil {
+		return fmt.Errorf("no valid PEM formatted block found in CRL %v", name)
+	}
+	// If the first block does not start with 'BEGIN X509 CRL' it's invalid and must not be used.
+	if pemCRLBlock.Type != "X509 CRL" {
+		return fmt.Errorf("CRL file %v contains invalid data, and must be created only with PEM formatted certificate revocation lists", name)
+	}
+
+	crlList, err := x509.ParseCRL(crl)
+	if err != nil {
+		return fmt.Errorf("parsing CRL %v: %v", name, err)
+	}
+
+	if crlList.HasExpired(time.Now()) {
+		klog.Warningf("The CRL %v expired on %v, client certificates will be rejected until it is updated", name, crlList.TBSCertList.NextUpdate)
+	}
+
+	crlFile, err := fs.Create(crlFileName)
+	if err != nil {
+		return fmt.Errorf("could not write CRL file %v: %v", crlFileName, err)
+	}
+	defer crlFile.Close()
+
+	_, err = crlFile.Write(crl)
+	if err != nil {
+		return fmt.Errorf("could not write CRL file %v: %v", crlFileName, err)
+	}
+
+	hash := sha1.Sum(crl)
+
+	sslCert.CRLFileName = crlFileName
+	sslCert.CRLSHA = hex.EncodeToString(hash[:])
+
+	klog.V(3).Infof("Created CRL for Authentication: %v", crlFileName)
+	return nil
+}
+
 // AddOrUpdateDHParam creates a dh parameters file with the specified name
 func AddOrUpdateDHParam(name string, dh []byte, fs file.Filesystem) (string, error) {
 	pemName := fmt.Sprintf("%v.pem", name)
@@ -527,3 +573,77 @@ func IsValidHostname(hostname string, commonNames []string) bool {
 
 	return false
 }
+
+// VerifyCertificateChain checks the PEM encoded certificate chain of a keypair
+// and returns a description of each issue found: certificates outside of their
+// validity period, certificates not ordered from the leaf to the root and
+// intermediate certificates missing to reach a root trusted by the system.
+// The ca.crt of a TLS Secret is not used, clients do not trust it.
+func VerifyCertificateChain(chain []byte, now time.Time) []string {
+	var certs []*x509.Certificate
+	for rest := chain; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid certificate: %v", err)}
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return []string{"no certificate found"}
+	}
+
+	var issues []string
+	for i, cert := range certs {
+		if now.After(cert.NotAfter) {
+			issues = append(issues, fmt.Sprintf("certificate %q expired on %v", cert.Subject.CommonName, cert.NotAfter))
+		}
+		if now.Before(cert.NotBefore) {
+			issues = append(issues, fmt.Sprintf("certificate %q is not valid before %v", cert.Subject.CommonName, cert.NotBefore))
+		}
+
+		if i > 0 && certs[i-1].CheckSignatureFrom(cert) != nil {
+			issues = append(issues, fmt.Sprintf("certificate %q is not issued by the next certificate in the chain %q",
+				certs[i-1].Subject.CommonName, cert.Subject.CommonName))
+		}
+	}
+
+	last := certs[len(certs)-1]
+	if last.CheckSignatureFrom(last) == nil {
+		// the chain ends with a self-signed certificate
+		return issues
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		klog.Warningf("Unable to read the system certificate pool: %v", err)
+		return issues
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if _, ok := err.(x509.UnknownAuthorityError); ok {
+		issues = append(issues, fmt.Sprintf("incomplete certificate chain: issuer %q of certificate %q not found",
+			last.Issuer.CommonName, last.Subject.CommonName))
+	}
+
+	return issues
+}
@@ -18,8 +18,11 @@ package ssl
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"testing"
 	"time"
@@ -141,6 +144,48 @@ func TestAddCertAuth(t *testing.T) {
 	}
 }
 
+func TestConfigureCRL(t *testing.T) {
+	fs := newFS(t)
+
+	cert, ca, err := generateRSACerts("demo")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	revoked := []pkix.RevokedCertificate{{SerialNumber: cert.Cert.SerialNumber, RevocationTime: time.Now()}}
+	der, err := ca.Cert.CreateCRL(rand.Reader, ca.Key, revoked, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error creating CRL: %v", err)
+	}
+	crl := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+
+	ic, err := AddCertAuth("demo-ca", certutil.EncodeCertPEM(ca.Cert), fs)
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	err = ConfigureCRL("demo-ca", crl, ic, fs)
+	if err != nil {
+		t.Fatalf("unexpected error configuring CRL: %v", err)
+	}
+	if ic.CRLFileName == "" || ic.CRLSHA == "" {
+		t.Fatalf("expected a valid CRL file name and checksum")
+	}
+
+	data, err := fs.ReadFile(ic.CRLFileName)
+	if err != nil {
+		t.Fatalf("unexpected error reading CRL file: %v", err)
+	}
+	if !bytes.Equal(data, crl) {
+		t.Errorf("expected the CRL file to contain the CRL")
+	}
+
+	err = ConfigureCRL("demo-ca", certutil.EncodeCertPEM(ca.Cert), ic, fs)
+	if err == nil {
+		t.Errorf("expected an error configuring a certificate as CRL")
+	}
+}
+
 func newFS(t *testing.T) file.Filesystem {
 	fs, err := file.NewFakeFS()
 	if err != nil {
@@ -241,3 +286,35 @@ func TestIsValidHostname(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyCertificateChain(t *testing.T) {
+	cert, ca, err := generateRSACerts("demo")
+	if err != nil {
+		t.Fatalf("unexpected error creating SSL certificate: %v", err)
+	}
+
+	leafPEM := certutil.EncodeCertPEM(cert.Cert)
+	caPEM := certutil.EncodeCertPEM(ca.Cert)
+	now := time.Now()
+
+	testCases := map[string]struct {
+		chain          []byte
+		now            time.Time
+		expectedIssues int
+	}{
+		"complete chain":                  {append(leafPEM, caPEM...), now, 0},
+		"missing intermediate":            {leafPEM, now, 1},
+		"chain in the wrong order":        {append(caPEM, leafPEM...), now, 2},
+		"expired certificates":            {append(leafPEM, caPEM...), cert.Cert.NotAfter.Add(time.Hour), 1},
+		"certificates not yet valid":      {append(leafPEM, caPEM...), cert.Cert.NotBefore.Add(-time.Hour), 2},
+		"no certificate":                  {certutil.EncodePrivateKeyPEM(cert.Key), now, 1},
+		"self-signed certificate expired": {caPEM, ca.Cert.NotAfter.Add(time.Hour), 1},
+	}
+
+	for name, tc := range testCases {
+		issues := VerifyCertificateChain(tc.chain, tc.now)
+		if len(issues) != tc.expectedIssues {
+			t.Errorf("%v: expected %v issues but returned %v: %v", name, tc.expectedIssues, len(issues), issues)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BuildInfo contains the version of NGINX and the modules it was built with
+type BuildInfo struct {
+	Version string   `json:"version"`
+	Modules []string `json:"modules"`
+}
+
+// ReadBuildInfo returns the build information of an NGINX binary
+func ReadBuildInfo(binary string) (*BuildInfo, error) {
+	out, err := exec.Command(binary, "-V").CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBuildInfo(string(out)), nil
+}
+
+// ParseBuildInfo parses the output of the command "nginx -V". The modules
+// are the ones enabled with --with-<name>_module, --with-stream and
+// --with-mail, and the third-party ones added with --add-module and
+// --add-dynamic-module, named after their directory.
+func ParseBuildInfo(out string) *BuildInfo {
+	info := &BuildInfo{
+		Modules: []string{},
+	}
+
+	modules := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "nginx version:") {
+			info.Version = strings.TrimSpace(strings.TrimPrefix(line, "nginx version:"))
+			continue
+		}
+
+		if !strings.HasPrefix(line, "configure arguments:") {
+			continue
+		}
+
+		for _, arg := range strings.Fields(line) {
+			name := strings.SplitN(arg, "=", 2)
+			switch {
+			case name[0] == "--with-stream" || name[0] == "--with-mail":
+				modules[strings.TrimPrefix(name[0], "--with-")] = true
+			case strings.HasPrefix(name[0], "--with-") && strings.HasSuffix(name[0], "_module"):
+				modules[strings.TrimPrefix(name[0], "--with-")] = true
+			case (name[0] == "--add-module" || name[0] == "--add-dynamic-module") && len(name) == 2:
+				modules[filepath.Base(strings.Trim(name[1], `'"`))] = true
+			}
+		}
+	}
+
+	for module := range modules {
+		info.Modules = append(info.Modules, module)
+	}
+	sort.Strings(info.Modules)
+
+	return info
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildInfo(t *testing.T) {
+	out := `nginx version: openresty/1.15.8.1
+built by gcc 8.3.0 (Debian 8.3.0-6)
+built with OpenSSL 1.1.1c  28 May 2019
+TLS SNI support enabled
+configure arguments: --prefix=/usr/local/openresty/nginx --with-cc-opt='-O2 -DNGX_LUA_ABORT_AT_PANIC' --add-module=../ngx_devel_kit-0.3.1rc1 --add-module=/tmp/build/headers-more-nginx-module-0.33 --with-http_ssl_module --with-http_v2_module --with-stream --with-stream_ssl_preread_module --with-http_geoip_module=dynamic --add-dynamic-module=/tmp/build/ngx_http_geoip2_module-3.2 --with-threads --with-pcre-jit
+`
+
+	expected := &BuildInfo{
+		Version: "openresty/1.15.8.1",
+		Modules: []string{
+			"headers-more-nginx-module-0.33",
+			"http_geoip_module",
+			"http_ssl_module",
+			"http_v2_module",
+			"ngx_devel_kit-0.3.1rc1",
+			"ngx_http_geoip2_module-3.2",
+			"stream",
+			"stream_ssl_preread_module",
+		},
+	}
+
+	info := ParseBuildInfo(out)
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %v but returned %v", expected, info)
+	}
+}
+
+func TestParseEmptyBuildInfo(t *testing.T) {
+	info := ParseBuildInfo("")
+	if info.Version != "" || len(info.Modules) != 0 {
+		t.Errorf("expected empty build information but returned %v", info)
+	}
+}
@@ -45,13 +45,28 @@ var StatusPath = "/nginx_status"
 // StreamSocket defines the location of the unix socket used by NGINX for the NGINX stream configuration socket
 var StreamSocket = "/tmp/ingress-stream.sock"
 
+// IntrospectionSocket defines the location of the unix socket used by the controller
+// to expose its running state
+var IntrospectionSocket = "/tmp/ingress-introspection.sock"
+
 var statusLocation = "nginx-status"
 
+var introspectionLocation = "ingress-introspection"
+
 var socketClient = buildUnixSocketClient()
 
 // NewGetStatusRequest creates a new GET request to the internal NGINX status server
 func NewGetStatusRequest(path string) (int, []byte, error) {
-	url := fmt.Sprintf("http+unix://%v%v", statusLocation, path)
+	return newGetRequest(statusLocation, path)
+}
+
+// NewGetIntrospectionRequest creates a new GET request to the introspection server of the controller
+func NewGetIntrospectionRequest(path string) (int, []byte, error) {
+	return newGetRequest(introspectionLocation, path)
+}
+
+func newGetRequest(location, path string) (int, []byte, error) {
+	url := fmt.Sprintf("http+unix://%v%v", location, path)
 	res, err := socketClient.Get(url)
 	if err != nil {
 		return 0, nil, err
@@ -135,6 +150,7 @@ func buildUnixSocketClient() *http.Client {
 		ResponseHeaderTimeout: 10 * time.Second,
 	}
 	u.RegisterLocation(statusLocation, StatusSocket)
+	u.RegisterLocation(introspectionLocation, IntrospectionSocket)
 
 	return &http.Client{
 		Transport: u,
@@ -25,28 +25,121 @@ import (
 	"strings"
 
 	libcontainercgroups "github.com/opencontainers/runc/libcontainer/cgroups"
+	"golang.org/x/sys/unix"
 )
 
+// cgroupV2CPUMax is the file used by the unified cgroup hierarchy (v2)
+// to configure the CPU quota and period of the container.
+var cgroupV2CPUMax = "/sys/fs/cgroup/cpu.max"
+
 // NumCPU returns the number of logical CPUs usable by the current process.
 // If CPU cgroups limits are configured, use cfs_quota_us / cfs_period_us
 // as formula
 //  https://www.kernel.org/doc/Documentation/scheduler/sched-bwc.txt
+// The result is never higher than the number of CPUs available to the
+// process, to avoid oversubscribing small containers on large nodes.
 func NumCPU() int {
 	cpus := runtime.NumCPU()
 
+	cpuQuota, cpuPeriod := cgroupCPUQuota()
+	if cpuQuota <= 0 || cpuPeriod <= 0 {
+		return cpus
+	}
+
+	limit := int(math.Ceil(float64(cpuQuota) / float64(cpuPeriod)))
+	if limit < cpus {
+		return limit
+	}
+
+	return cpus
+}
+
+// CPUAffinityMask returns the CPUs the current process can run on, in the
+// cpumask format of the NGINX directive worker_cpu_affinity, or an empty
+// string if the CPUs cannot be determined.
+func CPUAffinityMask() string {
+	var set unix.CPUSet
+	err := unix.SchedGetaffinity(0, &set)
+	if err != nil {
+		return ""
+	}
+
+	cpus := []int{}
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpuMask(cpus)
+}
+
+// cpuMask returns the cpumask of a list of CPUs, where the
+// rightmost character corresponds to the first CPU.
+func cpuMask(cpus []int) string {
+	if len(cpus) == 0 {
+		return ""
+	}
+
+	max := 0
+	for _, cpu := range cpus {
+		if cpu > max {
+			max = cpu
+		}
+	}
+
+	mask := []byte(strings.Repeat("0", max+1))
+	for _, cpu := range cpus {
+		mask[max-cpu] = '1'
+	}
+
+	return string(mask)
+}
+
+// cgroupCPUQuota returns the CPU quota and period of the cgroup of the
+// current process or -1 if there is no limit. The unified hierarchy (v2)
+// is checked first and the cpu controller of the v1 hierarchy otherwise.
+func cgroupCPUQuota() (int64, int64) {
+	contents, err := ioutil.ReadFile(cgroupV2CPUMax)
+	if err == nil {
+		return parseCPUMax(string(contents))
+	}
+
 	cgroupPath, err := libcontainercgroups.FindCgroupMountpoint("cpu")
 	if err != nil {
-		return cpus
+		return -1, -1
+	}
+
+	return cgroupV1CPUQuota(cgroupPath)
+}
+
+// cgroupV1CPUQuota returns the CPU quota and period configured in the files
+// cpu.cfs_quota_us and cpu.cfs_period_us of a cgroup v1 cpu controller. The
+// quota is -1 when there is no limit.
+func cgroupV1CPUQuota(cgroupPath string) (int64, int64) {
+	return readCgroupFileToInt64(cgroupPath, "cpu.cfs_quota_us"),
+		readCgroupFileToInt64(cgroupPath, "cpu.cfs_period_us")
+}
+
+// parseCPUMax parses the content of the cgroup v2 file cpu.max,
+// "$MAX $PERIOD", where $MAX is "max" when there is no limit.
+func parseCPUMax(contents string) (int64, int64) {
+	fields := strings.Fields(contents)
+	if len(fields) != 2 || fields[0] == "max" {
+		return -1, -1
 	}
 
-	cpuQuota := readCgroupFileToInt64(cgroupPath, "cpu.cfs_quota_us")
-	cpuPeriod := readCgroupFileToInt64(cgroupPath, "cpu.cfs_period_us")
+	quota, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return -1, -1
+	}
 
-	if cpuQuota == -1 || cpuPeriod == -1 {
-		return cpus
+	period, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return -1, -1
 	}
 
-	return int(math.Ceil(float64(cpuQuota) / float64(cpuPeriod)))
+	return quota, period
 }
 
 func readCgroupFileToInt64(cgroupPath, cgroupFile string) int64 {
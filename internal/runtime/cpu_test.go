@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUMax(t *testing.T) {
+	testCases := []struct {
+		contents string
+		quota    int64
+		period   int64
+	}{
+		{"max 100000\n", -1, -1},
+		{"200000 100000\n", 200000, 100000},
+		{"50000 100000", 50000, 100000},
+		{"", -1, -1},
+		{"abc 100000", -1, -1},
+		{"100000 abc", -1, -1},
+	}
+
+	for _, tc := range testCases {
+		quota, period := parseCPUMax(tc.contents)
+		if quota != tc.quota || period != tc.period {
+			t.Errorf("parseCPUMax(%q): expected (%v, %v) but returned (%v, %v)", tc.contents, tc.quota, tc.period, quota, period)
+		}
+	}
+}
+
+func TestCgroupV1CPUQuota(t *testing.T) {
+	testCases := []struct {
+		name   string
+		files  map[string]string
+		quota  int64
+		period int64
+	}{
+		{"limit", map[string]string{"cpu.cfs_quota_us": "150000\n", "cpu.cfs_period_us": "100000\n"}, 150000, 100000},
+		{"no limit", map[string]string{"cpu.cfs_quota_us": "-1\n", "cpu.cfs_period_us": "100000\n"}, -1, 100000},
+		{"invalid quota", map[string]string{"cpu.cfs_quota_us": "abc", "cpu.cfs_period_us": "100000"}, -1, 100000},
+		{"missing files", map[string]string{}, -1, -1},
+	}
+
+	for _, tc := range testCases {
+		dir, err := ioutil.TempDir("", "cgroup")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		for name, contents := range tc.files {
+			err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		quota, period := cgroupV1CPUQuota(dir)
+		if quota != tc.quota || period != tc.period {
+			t.Errorf("%v: expected (%v, %v) but returned (%v, %v)", tc.name, tc.quota, tc.period, quota, period)
+		}
+	}
+}
+
+func TestCPUMask(t *testing.T) {
+	testCases := []struct {
+		cpus []int
+		mask string
+	}{
+		{[]int{}, ""},
+		{[]int{0}, "1"},
+		{[]int{0, 1, 2, 3}, "1111"},
+		{[]int{2, 3}, "1100"},
+		{[]int{1, 4}, "10010"},
+	}
+
+	for _, tc := range testCases {
+		mask := cpuMask(tc.cpus)
+		if mask != tc.mask {
+			t.Errorf("cpuMask(%v): expected %q but returned %q", tc.cpus, tc.mask, mask)
+		}
+	}
+}
+
+func TestCPUAffinityMask(t *testing.T) {
+	mask := CPUAffinityMask()
+	if strings.Count(mask, "1") < 1 || strings.Count(mask, "1") > runtime.NumCPU() {
+		t.Errorf("expected a mask of between 1 and %v CPUs but returned %q", runtime.NumCPU(), mask)
+	}
+}
+
+func TestNumCPU(t *testing.T) {
+	cpus := NumCPU()
+	if cpus < 1 || cpus > runtime.NumCPU() {
+		t.Errorf("expected a number of CPUs between 1 and %v but returned %v", runtime.NumCPU(), cpus)
+	}
+}
@@ -22,12 +22,25 @@ import (
 
 	"k8s.io/klog"
 
+	"golang.org/x/time/rate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
+const (
+	// DefaultBaseDelay is the delay before requeuing an element the first
+	// time its sync fails
+	DefaultBaseDelay = 5 * time.Millisecond
+	// DefaultMaxDelay is the maximum delay before requeuing an element
+	// which sync keeps failing
+	DefaultMaxDelay = 1000 * time.Second
+	// DefaultBurst is the number of elements requeued without delay
+	// before the overall limit of 10 elements per second applies
+	DefaultBurst = 100
+)
+
 var (
 	keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
 )
@@ -168,10 +181,20 @@ func NewTaskQueue(syncFn func(interface{}) error) *Queue {
 	return NewCustomTaskQueue(syncFn, nil)
 }
 
+// NewRateLimitedTaskQueue creates a new task queue with the given sync
+// function, requeuing the elements which sync failed using rateLimiter.
+func NewRateLimitedTaskQueue(syncFn func(interface{}) error, rateLimiter workqueue.RateLimiter) *Queue {
+	return newQueue(syncFn, nil, rateLimiter)
+}
+
 // NewCustomTaskQueue ...
 func NewCustomTaskQueue(syncFn func(interface{}) error, fn func(interface{}) (interface{}, error)) *Queue {
+	return newQueue(syncFn, fn, workqueue.DefaultControllerRateLimiter())
+}
+
+func newQueue(syncFn func(interface{}) error, fn func(interface{}) (interface{}, error), rateLimiter workqueue.RateLimiter) *Queue {
 	q := &Queue{
-		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		queue:      workqueue.NewRateLimitingQueue(rateLimiter),
 		sync:       syncFn,
 		workerDone: make(chan bool),
 		fn:         fn,
@@ -184,6 +207,27 @@ func NewCustomTaskQueue(syncFn func(interface{}) error, fn func(interface{}) (in
 	return q
 }
 
+// NewRateLimiter returns a rate limiter delaying the elements which sync
+// failed exponentially, from baseDelay up to maxDelay, and limiting the
+// requeue of all the elements to 10 per second with bursts of burst
+// elements. Zero values are replaced with the defaults.
+func NewRateLimiter(baseDelay, maxDelay time.Duration, burst int) workqueue.RateLimiter {
+	if baseDelay == 0 {
+		baseDelay = DefaultBaseDelay
+	}
+	if maxDelay == 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	if burst == 0 {
+		burst = DefaultBurst
+	}
+
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), burst)},
+	)
+}
+
 // GetDummyObject returns a valid object that can be used in the Queue
 func GetDummyObject(name string) *metav1.ObjectMeta {
 	return &metav1.ObjectMeta{
@@ -157,3 +157,30 @@ func TestSkipEnqueue(t *testing.T) {
 	// shutdown queue before exit
 	q.Shutdown()
 }
+
+func TestNewRateLimiter(t *testing.T) {
+	rl := NewRateLimiter(10*time.Millisecond, 30*time.Millisecond, 5)
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		30 * time.Millisecond,
+	}
+	for i, delay := range expected {
+		if d := rl.When("item"); d != delay {
+			t.Errorf("expected a delay of %v in requeue %v but %v returned", delay, i+1, d)
+		}
+	}
+
+	rl.Forget("item")
+	if d := rl.When("item"); d != 10*time.Millisecond {
+		t.Errorf("expected the delay to be reset after Forget but %v returned", d)
+	}
+
+	// zero values use the defaults
+	rl = NewRateLimiter(0, 0, 0)
+	if d := rl.When("item"); d != DefaultBaseDelay {
+		t.Errorf("expected a delay of %v but %v returned", DefaultBaseDelay, d)
+	}
+}
@@ -8,6 +8,7 @@ local chash = require("balancer.chash")
 local chashsubset = require("balancer.chashsubset")
 local sticky = require("balancer.sticky")
 local ewma = require("balancer.ewma")
+local ip_hash = require("balancer.ip_hash")
 
 -- measured in seconds
 -- for an Nginx worker to pick up the new list of upstream peers
@@ -21,6 +22,7 @@ local IMPLEMENTATIONS = {
   chashsubset = chashsubset,
   sticky = sticky,
   ewma = ewma,
+  ip_hash = ip_hash,
 }
 
 local _M = {}
@@ -80,6 +82,14 @@ local function sync_backend(backend)
     return
   end
 
+  -- ExternalName services are resolved on every sync, the DNS cache honors the TTL of the records
+  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
+  if service_type == "ExternalName" then
+    backend = resolve_external_names(backend)
+  end
+
+  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
+
   local implementation = get_implementation(backend)
   local balancer = balancers[backend.name]
 
@@ -98,13 +108,6 @@ local function sync_backend(backend)
     return
   end
 
-  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
-  if service_type == "ExternalName" then
-    backend = resolve_external_names(backend)
-  end
-
-  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
-
   balancer:sync(backend)
 end
 
@@ -221,6 +224,22 @@ function _M.rewrite()
   end
 end
 
+-- websocket_timeouts returns the connect, send and read timeouts (in seconds)
+-- configured for WebSocket connections in the location of the request
+local function websocket_timeouts()
+  local read_timeout = ngx.var.websocket_read_timeout
+  if not read_timeout or read_timeout == "" then
+    return nil
+  end
+
+  local upgrade = ngx.var.http_upgrade
+  if not upgrade or string.lower(upgrade) ~= "websocket" then
+    return nil
+  end
+
+  return tonumber(ngx.var.websocket_connect_timeout), tonumber(ngx.var.websocket_send_timeout), tonumber(read_timeout)
+end
+
 function _M.balance()
   local balancer = get_balancer()
   if not balancer then
@@ -239,6 +258,14 @@ function _M.balance()
   if not ok then
     ngx.log(ngx.ERR, string.format("error while setting current upstream peer %s: %s", peer, err))
   end
+
+  local connect_timeout, send_timeout, read_timeout = websocket_timeouts()
+  if read_timeout then
+    ok, err = ngx_balancer.set_timeouts(connect_timeout, send_timeout, read_timeout)
+    if not ok then
+      ngx.log(ngx.ERR, "error while setting WebSocket timeouts: " .. tostring(err))
+    end
+  end
 end
 
 function _M.log()
@@ -257,6 +284,8 @@ end
 if _TEST then
   _M.get_implementation = get_implementation
   _M.sync_backend = sync_backend
+  _M.websocket_timeouts = websocket_timeouts
+  _M.route_to_alternative_balancer = route_to_alternative_balancer
 end
 
 return _M
@@ -18,8 +18,14 @@ function _M.new(self, backend)
 end
 
 function _M.balance(self)
-  local key = util.lua_ngx_var(self.hash_by)
+  local key = util.generate_var_value(self.hash_by)
   return self.instance:find(key)
 end
 
+function _M.sync(self, backend)
+  balancer_resty.sync(self, backend)
+
+  self.hash_by = backend["upstreamHashByConfig"]["upstream-hash-by"]
+end
+
 return _M
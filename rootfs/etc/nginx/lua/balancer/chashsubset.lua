@@ -5,12 +5,21 @@ local resty_chash = require("resty.chash")
 local util = require("util")
 
 local _M = { name = "chashsubset" }
+local DEFAULT_SUBSET_SIZE = 3
+
+local function get_subset_size(backend)
+  local subset_size = backend["upstreamHashByConfig"]["upstream-hash-by-subset-size"]
+  if not subset_size or subset_size < 1 then
+    return DEFAULT_SUBSET_SIZE
+  end
+  return subset_size
+end
 
 local function build_subset_map(backend)
   local endpoints = {}
   local subset_map = {}
   local subsets = {}
-  local subset_size = backend["upstreamHashByConfig"]["upstream-hash-by-subset-size"]
+  local subset_size = get_subset_size(backend)
 
   for _, endpoint in pairs(backend.endpoints) do
     table.insert(endpoints, endpoint)
@@ -49,6 +58,7 @@ function _M.new(self, backend)
     instance = resty_chash:new(subset_map),
     hash_by = backend["upstreamHashByConfig"]["upstream-hash-by"],
     subsets = subsets,
+    subset_size = get_subset_size(backend),
     current_endpoints = backend.endpoints
   }
   setmetatable(o, self)
@@ -57,7 +67,7 @@ function _M.new(self, backend)
 end
 
 function _M.balance(self)
-  local key = util.lua_ngx_var(self.hash_by)
+  local key = util.generate_var_value(self.hash_by)
   local subset_id = self.instance:find(key)
   local endpoints = self.subsets[subset_id]
   local endpoint = endpoints[math.random(#endpoints)]
@@ -67,12 +77,17 @@ end
 function _M.sync(self, backend)
   local subset_map
 
-  local changed = not util.deep_compare(self.current_endpoints, backend.endpoints)
+  self.hash_by = backend["upstreamHashByConfig"]["upstream-hash-by"]
+
+  local subset_size = get_subset_size(backend)
+  local changed = not util.deep_compare(self.current_endpoints, backend.endpoints) or
+    subset_size ~= self.subset_size
   if not changed then
     return
   end
 
   self.current_endpoints = backend.endpoints
+  self.subset_size = subset_size
 
   subset_map, self.subsets = build_subset_map(backend)
 
@@ -0,0 +1,27 @@
+-- Consistent hashing by the IP address of the client. When the real IP
+-- module is enabled, with use-forwarded-headers or use-proxy-protocol,
+-- $remote_addr is the address of the client instead of the one of the proxy.
+
+local balancer_resty = require("balancer.resty")
+local resty_chash = require("resty.chash")
+local util = require("util")
+
+local _M = balancer_resty:new({ factory = resty_chash, name = "ip_hash" })
+
+function _M.new(self, backend)
+  local nodes = util.get_nodes(backend.endpoints)
+  local o = {
+    instance = self.factory:new(nodes),
+    traffic_shaping_policy = backend.trafficShapingPolicy,
+    alternative_backends = backend.alternativeBackends,
+  }
+  setmetatable(o, self)
+  self.__index = self
+  return o
+end
+
+function _M.balance(self)
+  return self.instance:find(ngx.var.remote_addr)
+end
+
+return _M
@@ -2,9 +2,13 @@ local balancer_resty = require("balancer.resty")
 local resty_chash = require("resty.chash")
 local util = require("util")
 local ck = require("resty.cookie")
+local ngx_balancer = require("ngx.balancer")
+local split = require("util.split")
 
 local _M = balancer_resty:new({ factory = resty_chash, name = "sticky" })
 local DEFAULT_COOKIE_NAME = "route"
+-- maximum number of keys generated to find an endpoint that did not fail
+local MAX_NEW_UPSTREAM_TRIES = 10
 
 local function get_digest_func(hash)
   local digest_func = util.md5_digest
@@ -59,9 +63,22 @@ local function set_cookie(self, value)
     value = value,
     path = cookie_path,
     httponly = true,
-    secure = ngx.var.https == "on",
+    secure = self.cookie_session_affinity.secure == true or ngx.var.https == "on",
   }
 
+  if self.cookie_session_affinity.domain and self.cookie_session_affinity.domain ~= "" then
+    cookie_data.domain = self.cookie_session_affinity.domain
+  end
+
+  local samesite = self.cookie_session_affinity.samesite
+  if samesite and samesite ~= "" then
+    cookie_data.extension = "SameSite=" .. samesite
+    -- browsers reject the cookies with SameSite=None without the Secure attribute
+    if samesite == "None" then
+      cookie_data.secure = true
+    end
+  end
+
   if self.cookie_session_affinity.expires and self.cookie_session_affinity.expires ~= "" then
       cookie_data.expires = ngx.cookie_time(ngx.time() + tonumber(self.cookie_session_affinity.expires))
   end
@@ -77,6 +94,33 @@ local function set_cookie(self, value)
   end
 end
 
+-- get_failed_upstreams returns the endpoints already tried for the request
+local function get_failed_upstreams()
+  local failed_upstreams = {}
+  for _, addr in ipairs(split.split_upstream_var(ngx.var.upstream_addr) or {}) do
+    failed_upstreams[addr] = true
+  end
+  return failed_upstreams
+end
+
+local function pick_new_upstream(self, failed_upstreams)
+  for i = 1, MAX_NEW_UPSTREAM_TRIES do
+    local random_str = string.format("%s.%s.%s", ngx.now(), ngx.worker.pid(), i)
+    local key = encrypted_endpoint_string(self, random_str)
+
+    local upstream = self.instance:find(key)
+    if not failed_upstreams[upstream] then
+      return upstream, key
+    end
+  end
+
+  return nil, nil
+end
+
+function _M.get_last_failure()
+  return ngx_balancer.get_last_failure()
+end
+
 function _M.balance(self)
   local cookie, err = ck:new()
   if not cookie then
@@ -85,6 +129,18 @@ function _M.balance(self)
   end
 
   local key = cookie:get(self:cookie_name())
+  if key and self.cookie_session_affinity.changeonfailure and self.get_last_failure() then
+    -- the endpoint of the session failed. The request is retried in
+    -- a different endpoint and the session is moved to it
+    local upstream, new_key = pick_new_upstream(self, get_failed_upstreams())
+    if upstream then
+      set_cookie(self, new_key)
+      return upstream
+    end
+
+    ngx.log(ngx.WARN, "could not find a new endpoint for the session, using the failed one")
+  end
+
   if not key then
     local random_str = string.format("%s.%s", ngx.now(), ngx.worker.pid())
     key = encrypted_endpoint_string(self, random_str)
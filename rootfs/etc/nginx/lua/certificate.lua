@@ -1,9 +1,20 @@
 local ssl = require("ngx.ssl")
+local ocsp = require("ngx.ocsp")
 local configuration = require("configuration")
 local re_sub = ngx.re.sub
 
 local _M = {}
 
+-- names used by the ssl_protocols directive for the versions
+-- returned by ssl.get_tls1_version
+local TLS_PROTOCOLS = {
+  [0x0300] = "SSLv3",
+  [0x0301] = "TLSv1",
+  [0x0302] = "TLSv1.1",
+  [0x0303] = "TLSv1.2",
+  [0x0304] = "TLSv1.3",
+}
+
 local function set_pem_cert_key(pem_cert_key)
   local der_cert, der_cert_err = ssl.cert_pem_to_der(pem_cert_key)
   if not der_cert then
@@ -26,22 +37,36 @@ local function set_pem_cert_key(pem_cert_key)
   end
 end
 
+-- returns the certificate and the hostname it is configured for,
+-- that can be a wildcard hostname
 local function get_pem_cert_key(hostname)
   local pem_cert_key = configuration.get_pem_cert_key(hostname)
   if pem_cert_key then
-    return pem_cert_key
+    return pem_cert_key, hostname
   end
 
   local wildcard_hosatname, _, err = re_sub(hostname, "^[^\\.]+\\.", "*.", "jo")
   if err then
     ngx.log(ngx.ERR, "error: ", err)
-    return pem_cert_key
+    return pem_cert_key, hostname
   end
 
   if wildcard_hosatname then
     pem_cert_key = configuration.get_pem_cert_key(wildcard_hosatname)
   end
-  return pem_cert_key
+  return pem_cert_key, wildcard_hosatname
+end
+
+local function set_ocsp_response(hostname)
+  local der_response = configuration.get_ocsp_response(hostname)
+  if not der_response then
+    return
+  end
+
+  local ok, err = ocsp.set_ocsp_status_resp(der_response)
+  if not ok then
+    ngx.log(ngx.ERR, "failed to set OCSP response for hostname " .. tostring(hostname) .. ": " .. tostring(err))
+  end
 end
 
 function _M.call()
@@ -51,7 +76,7 @@ function _M.call()
     return
   end
 
-  local pem_cert_key = get_pem_cert_key(hostname)
+  local pem_cert_key, cert_hostname = get_pem_cert_key(hostname)
   if not pem_cert_key or pem_cert_key == "" then
     ngx.log(ngx.ERR, "Certificate not found, falling back on default certificate for hostname: " .. tostring(hostname))
     return
@@ -68,6 +93,40 @@ function _M.call()
     ngx.log(ngx.ERR, set_pem_cert_key_err)
     return ngx.exit(ngx.ERROR)
   end
+
+  -- OpenSSL keeps one certificate per key type, so an ECDSA certificate
+  -- is served to the clients that support it and the RSA one to the rest
+  local ecdsa_pem_cert_key = configuration.get_ecdsa_pem_cert_key(cert_hostname)
+  if ecdsa_pem_cert_key then
+    set_pem_cert_key_err = set_pem_cert_key(ecdsa_pem_cert_key)
+    if set_pem_cert_key_err then
+      ngx.log(ngx.ERR, "failed to set ECDSA certificate for hostname " .. tostring(hostname) .. ": " .. set_pem_cert_key_err)
+    end
+  end
+
+  -- a missing OCSP response must not break the handshake
+  set_ocsp_response(cert_hostname)
+end
+
+-- NGINX negotiates the protocol with the settings of the default server,
+-- before the server name selects the server. check_protocols aborts the
+-- handshakes of a server using a protocol not listed in protocols.
+function _M.check_protocols(protocols)
+  local version, err = ssl.get_tls1_version()
+  if not version then
+    ngx.log(ngx.ERR, "failed to get the TLS protocol version: " .. tostring(err))
+    return ngx.exit(ngx.ERROR)
+  end
+
+  local protocol = TLS_PROTOCOLS[version]
+  for enabled in string.gmatch(protocols, "%S+") do
+    if enabled == protocol then
+      return
+    end
+  end
+
+  ngx.log(ngx.INFO, "protocol " .. tostring(protocol) .. " not enabled in the server, aborting the handshake")
+  return ngx.exit(ngx.ERROR)
 end
 
 return _M
@@ -1,8 +1,10 @@
 local cjson = require("cjson.safe")
+local util = require("util")
 
 -- this is the Lua representation of Configuration struct in internal/ingress/types.go
 local configuration_data = ngx.shared.configuration_data
 local certificate_data = ngx.shared.certificate_data
+local ocsp_response_data = ngx.shared.ocsp_response_data
 
 local _M = {
   nameservers = {}
@@ -16,6 +18,10 @@ function _M.get_general_data()
   return configuration_data:get("general")
 end
 
+function _M.get_ip_access_data()
+  return configuration_data:get("ip_access")
+end
+
 local function fetch_request_body()
   ngx.req.read_body()
   local body = ngx.req.get_body_data()
@@ -40,6 +46,34 @@ function _M.get_pem_cert_key(hostname)
   return certificate_data:get(hostname)
 end
 
+-- returns the ECDSA certificate configured for the hostname
+-- next to the one returned by get_pem_cert_key, if any
+function _M.get_ecdsa_pem_cert_key(hostname)
+  return certificate_data:get(hostname .. ":ecdsa")
+end
+
+function _M.get_ocsp_response(hostname)
+  return ocsp_response_data:get(hostname)
+end
+
+local function set_ocsp_response(hostname, ocsp_response)
+  if type(ocsp_response) ~= "string" then
+    ocsp_response_data:delete(hostname)
+    return
+  end
+
+  local der_response = ngx.decode_base64(ocsp_response)
+  if not der_response then
+    ocsp_response_data:delete(hostname)
+    return "invalid OCSP response"
+  end
+
+  local success, err = ocsp_response_data:safe_set(hostname, der_response)
+  if not success then
+    return err
+  end
+end
+
 local function handle_servers()
   if ngx.var.request_method ~= "POST" then
     ngx.status = ngx.HTTP_BAD_REQUEST
@@ -71,11 +105,31 @@ local function handle_servers()
         local err_msg = string.format("error setting certificate for %s: %s\n", server.hostname, tostring(err))
         table.insert(err_buf, err_msg)
       end
+
+      local ecdsa_pem_cert_key = server.sslCertECDSA and server.sslCertECDSA.pemCertKey
+      if ecdsa_pem_cert_key and ecdsa_pem_cert_key ~= "" then
+        success, err = certificate_data:safe_set(server.hostname .. ":ecdsa", ecdsa_pem_cert_key)
+        if not success then
+          local err_msg = string.format("error setting ECDSA certificate for %s: %s\n", server.hostname, tostring(err))
+          table.insert(err_buf, err_msg)
+        end
+      else
+        certificate_data:delete(server.hostname .. ":ecdsa")
+      end
+
+      err = set_ocsp_response(server.hostname, server.sslCert.ocspResponse)
+      if err then
+        local err_msg = string.format("error setting OCSP response for %s: %s\n", server.hostname, tostring(err))
+        table.insert(err_buf, err_msg)
+      end
     else
       ngx.log(ngx.WARN, "hostname or pemCertKey are not present")
     end
   end
 
+  util.check_shared_dict_usage("certificate_data")
+  util.check_shared_dict_usage("ocsp_response_data")
+
   if #err_buf > 0 then
     ngx.log(ngx.ERR, table.concat(err_buf))
     ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
@@ -110,6 +164,25 @@ local function handle_general()
   ngx.status = ngx.HTTP_CREATED
 end
 
+local function handle_ip_access()
+  if ngx.var.request_method == "GET" then
+    ngx.status = ngx.HTTP_OK
+    ngx.print(_M.get_ip_access_data())
+    return
+  end
+
+  local ip_access = fetch_request_body()
+
+  local success, err = configuration_data:safe_set("ip_access", ip_access)
+  if not success then
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    ngx.log(ngx.ERR, "error setting IP access lists: " .. tostring(err))
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
 local function handle_certs()
   if ngx.var.request_method ~= "GET" then
     ngx.status = ngx.HTTP_BAD_REQUEST
@@ -143,6 +216,11 @@ function _M.call()
     return
   end
 
+  if ngx.var.request_uri == "/configuration/ping" then
+    ngx.status = ngx.HTTP_OK
+    return
+  end
+
   if ngx.var.request_uri == "/configuration/servers" then
     handle_servers()
     return
@@ -153,6 +231,11 @@ function _M.call()
     return
   end
 
+  if ngx.var.request_uri == "/configuration/ip-access" then
+    handle_ip_access()
+    return
+  end
+
   if ngx.var.uri == "/configuration/certs" then
     handle_certs()
     return
@@ -184,6 +267,8 @@ function _M.call()
     return
   end
 
+  util.check_shared_dict_usage("configuration_data")
+
   ngx.status = ngx.HTTP_CREATED
 end
 
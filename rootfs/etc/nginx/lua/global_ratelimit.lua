@@ -0,0 +1,154 @@
+local iputils = require("resty.iputils")
+
+local math_floor = math.floor
+local tonumber = tonumber
+local tostring = tostring
+
+local _M = {}
+
+local DEFAULT_PORTS = {
+  memcached = 11211,
+  redis = 6379,
+}
+
+-- configuration of the shared store, set in the master process
+local store = {}
+
+-- parsed ignored CIDRs of each Ingress. A change in the
+-- annotations modifies the configuration and reloads NGINX
+local ignored_cidrs = {}
+
+local function incr_memcached(key, ttl)
+  local memcached = require("resty.memcached")
+
+  local memc, err = memcached:new()
+  if not memc then
+    return nil, err
+  end
+
+  memc:set_timeout(store.connect_timeout)
+
+  local ok
+  ok, err = memc:connect(store.host, store.port)
+  if not ok then
+    return nil, err
+  end
+
+  local count
+  count, err = memc:incr(key, 1)
+  if err == "NOT_FOUND" then
+    ok, err = memc:add(key, 1, ttl)
+    if ok then
+      count = 1
+    elseif err == "NOT_STORED" then
+      -- the counter was created by another NGINX worker or replica
+      count, err = memc:incr(key, 1)
+    end
+  end
+
+  if not count then
+    memc:close()
+    return nil, err
+  end
+
+  memc:set_keepalive(store.max_idle_timeout, store.pool_size)
+  return tonumber(count)
+end
+
+local function incr_redis(key, ttl)
+  local redis = require("resty.redis")
+
+  local red, err = redis:new()
+  if not red then
+    return nil, err
+  end
+
+  red:set_timeout(store.connect_timeout)
+
+  local ok
+  ok, err = red:connect(store.host, store.port)
+  if not ok then
+    return nil, err
+  end
+
+  local count
+  count, err = red:incr(key)
+  if not count then
+    red:close()
+    return nil, err
+  end
+
+  if count == 1 then
+    ok, err = red:expire(key, ttl)
+    if not ok then
+      red:close()
+      return nil, err
+    end
+  end
+
+  red:set_keepalive(store.max_idle_timeout, store.pool_size)
+  return count
+end
+
+local BACKENDS = {
+  memcached = incr_memcached,
+  redis = incr_redis,
+}
+
+local function is_ignored(namespace, cidrs)
+  if #cidrs == 0 then
+    return false
+  end
+
+  local parsed = ignored_cidrs[namespace]
+  if not parsed then
+    parsed = iputils.parse_cidrs(cidrs)
+    ignored_cidrs[namespace] = parsed
+  end
+
+  return iputils.ip_in_cidrs(ngx.var.remote_addr, parsed)
+end
+
+function _M.configure(config)
+  store = config
+  if not store.port or store.port == 0 then
+    store.port = DEFAULT_PORTS[store.backend]
+  end
+end
+
+-- counts the requests in fixed windows, rejecting the request when the
+-- counter of the current window in the shared store exceeds the limit.
+-- Errors with the shared store never reject requests
+function _M.call(config)
+  local incr = BACKENDS[store.backend]
+  if not incr then
+    ngx.log(ngx.ERR, "invalid global rate limit backend: ", tostring(store.backend))
+    return
+  end
+
+  if is_ignored(config.namespace, config.ignored_cidrs) then
+    return
+  end
+
+  local key = ngx.var.global_rate_limit_key
+  if not key or key == "" then
+    return
+  end
+
+  local window_start = math_floor(ngx.now() / config.window_size) * config.window_size
+  -- the key may contain characters memcached does not accept
+  local counter = config.namespace .. ":" .. ngx.md5(key) .. ":" .. window_start
+
+  local count, err = incr(counter, config.window_size)
+  if not count then
+    ngx.log(ngx.ERR, "error incrementing global rate limit counter: ", tostring(err))
+    return
+  end
+
+  if count > config.limit then
+    ngx.var.global_rate_limit_exceeded = "1"
+    return ngx.exit(store.status_code)
+  end
+end
+
+return _M
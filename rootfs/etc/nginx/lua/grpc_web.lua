@@ -0,0 +1,92 @@
+local string_format = string.format
+local string_char = string.char
+local string_sub = string.sub
+local table_concat = table.concat
+
+local GRPC_WEB_CONTENT_TYPE = "application/grpc-web"
+local GRPC_CONTENT_TYPE = "application/grpc"
+-- the most significant bit of the frame flag marks a trailer frame
+-- https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+local TRAILER_FRAME_FLAG = 128
+
+local _M = {}
+
+local function is_grpc_web(content_type)
+  if not content_type then
+    return false
+  end
+
+  if string_sub(content_type, 1, #GRPC_WEB_CONTENT_TYPE) ~= GRPC_WEB_CONTENT_TYPE then
+    return false
+  end
+
+  -- application/grpc-web-text is base64 encoded and not supported
+  local suffix = string_sub(content_type, #GRPC_WEB_CONTENT_TYPE + 1, #GRPC_WEB_CONTENT_TYPE + 1)
+  return suffix == "" or suffix == "+" or suffix == ";"
+end
+
+local function encode_length(length)
+  return string_char(
+    math.floor(length / 16777216) % 256,
+    math.floor(length / 65536) % 256,
+    math.floor(length / 256) % 256,
+    length % 256
+  )
+end
+
+local function trailer_frame(status, message)
+  local trailers = { string_format("grpc-status:%s\r\n", status) }
+  if message and message ~= "" then
+    table.insert(trailers, string_format("grpc-message:%s\r\n", message))
+  end
+
+  local payload = table_concat(trailers)
+  return string_char(TRAILER_FRAME_FLAG) .. encode_length(#payload) .. payload
+end
+
+-- rewrite translates the content type of gRPC-Web requests
+-- so the request can be sent to the upstream using grpc_pass.
+-- Other requests are left untouched.
+function _M.rewrite()
+  local content_type = ngx.var.http_content_type
+  if not is_grpc_web(content_type) then
+    return
+  end
+
+  ngx.ctx.grpc_web_content_type = content_type
+  ngx.req.set_header("Content-Type", GRPC_CONTENT_TYPE .. string_sub(content_type, #GRPC_WEB_CONTENT_TYPE + 1))
+end
+
+function _M.header_filter()
+  local content_type = ngx.ctx.grpc_web_content_type
+  if not content_type then
+    return
+  end
+
+  ngx.header["Content-Type"] = content_type
+  -- the trailer frame is appended to the body
+  ngx.header["Content-Length"] = nil
+end
+
+-- body_filter appends the gRPC trailers received from the upstream to the
+-- response body as a gRPC-Web trailer frame. Trailers-only responses already
+-- contain the status in the headers and are not modified.
+function _M.body_filter()
+  if not ngx.ctx.grpc_web_content_type or not ngx.arg[2] then
+    return
+  end
+
+  local status = ngx.var.upstream_trailer_grpc_status
+  if not status or status == "" then
+    return
+  end
+
+  ngx.arg[1] = (ngx.arg[1] or "") .. trailer_frame(status, ngx.var.upstream_trailer_grpc_message)
+end
+
+if _TEST then
+  _M.is_grpc_web = is_grpc_web
+  _M.trailer_frame = trailer_frame
+end
+
+return _M
@@ -0,0 +1,136 @@
+local ffi = require("ffi")
+local bit = require("bit")
+local cjson = require("cjson.safe")
+local configuration = require("configuration")
+
+local tostring = tostring
+local tonumber = tonumber
+local ipairs = ipairs
+local string_byte = string.byte
+local string_sub = string.sub
+local math_floor = math.floor
+
+ffi.cdef[[
+int inet_pton(int af, const char *src, void *dst);
+]]
+
+-- address families of Linux
+local AF_INET = 2
+local AF_INET6 = 10
+
+-- IPv4 clients of the dual stack sockets are reported as ::ffff:a.b.c.d
+local IPV4_MAPPED_PREFIX = string.rep("\0", 10) .. "\255\255"
+
+local _M = {}
+
+-- the raw IP access lists of the last parsed configuration and their parsed
+-- CIDRs. The lists are parsed again only when the controller posts new ones
+local raw_ip_access
+local denied_cidrs = {}
+local allowed_cidrs = {}
+
+-- returns the 4 or 16 bytes of an IPv4 or IPv6 address, nil when invalid
+local function to_binary(addr)
+  local family, size = AF_INET, 4
+  if addr:find(":", 1, true) then
+    family, size = AF_INET6, 16
+  end
+
+  local buf = ffi.new("unsigned char[?]", size)
+  if ffi.C.inet_pton(family, addr, buf) ~= 1 then
+    return nil
+  end
+
+  local bin = ffi.string(buf, size)
+  if size == 16 and string_sub(bin, 1, 12) == IPV4_MAPPED_PREFIX then
+    return string_sub(bin, 13)
+  end
+
+  return bin
+end
+
+local function parse_cidrs(list)
+  local cidrs = {}
+  for _, entry in ipairs(list) do
+    local addr, bits = entry:match("^([^/]+)/?(%d*)$")
+    local bin = addr and to_binary(addr)
+    bits = bin and (tonumber(bits) or #bin * 8)
+    if not bin or bits > #bin * 8 then
+      ngx.log(ngx.ERR, "ignoring invalid CIDR ", tostring(entry))
+    else
+      cidrs[#cidrs + 1] = { addr = bin, bits = bits }
+    end
+  end
+
+  return cidrs
+end
+
+local function in_cidr(bin, cidr)
+  if #bin ~= #cidr.addr then
+    return false
+  end
+
+  local bytes = math_floor(cidr.bits / 8)
+  if string_sub(bin, 1, bytes) ~= string_sub(cidr.addr, 1, bytes) then
+    return false
+  end
+
+  local rest = cidr.bits % 8
+  if rest == 0 then
+    return true
+  end
+
+  local mask = bit.band(bit.lshift(0xff, 8 - rest), 0xff)
+  return bit.band(string_byte(bin, bytes + 1), mask) ==
+    bit.band(string_byte(cidr.addr, bytes + 1), mask)
+end
+
+local function ip_in_cidrs(bin, cidrs)
+  for _, cidr in ipairs(cidrs) do
+    if in_cidr(bin, cidr) then
+      return true
+    end
+  end
+
+  return false
+end
+
+local function sync_ip_access()
+  local raw = configuration.get_ip_access_data()
+  if raw == raw_ip_access then
+    return
+  end
+
+  local ip_access, err = cjson.decode(raw or "{}")
+  if not ip_access then
+    ngx.log(ngx.ERR, "could not parse IP access lists: ", tostring(err))
+    return
+  end
+
+  denied_cidrs = parse_cidrs(ip_access.denied or {})
+  allowed_cidrs = parse_cidrs(ip_access.allowed or {})
+  raw_ip_access = raw
+end
+
+-- rejects the requests from the denied CIDRs, unless the client
+-- address is also included in the allowed CIDRs
+function _M.call()
+  sync_ip_access()
+
+  if #denied_cidrs == 0 then
+    return
+  end
+
+  local remote_addr = to_binary(ngx.var.remote_addr)
+  if not remote_addr or not ip_in_cidrs(remote_addr, denied_cidrs) then
+    return
+  end
+
+  if #allowed_cidrs > 0 and ip_in_cidrs(remote_addr, allowed_cidrs) then
+    return
+  end
+
+  return ngx.exit(ngx.HTTP_FORBIDDEN)
+end
+
+return _M
@@ -0,0 +1,86 @@
+local util = require("util")
+
+local ipairs = ipairs
+local type = type
+
+local _M = {}
+
+local function audience_validator(audiences)
+  return function(value)
+    if type(value) == "string" then
+      value = { value }
+    end
+    if type(value) ~= "table" then
+      return false
+    end
+
+    for _, aud in ipairs(value) do
+      for _, audience in ipairs(audiences) do
+        if aud == audience then
+          return true
+        end
+      end
+    end
+
+    return false
+  end
+end
+
+local function claim_spec(config)
+  local validators = require("resty.jwt-validators")
+
+  local spec = {
+    exp = validators.is_not_expired(),
+    nbf = validators.opt_is_not_before(),
+  }
+
+  if config.issuer ~= "" then
+    spec.iss = validators.equals(config.issuer)
+  end
+
+  if #config.audiences > 0 then
+    spec.aud = audience_validator(config.audiences)
+  end
+
+  return spec
+end
+
+local function reject(config)
+  if ngx.var.http_authorization then
+    ngx.header["WWW-Authenticate"] = 'Bearer error="invalid_token"'
+  else
+    ngx.header["WWW-Authenticate"] = "Bearer"
+  end
+
+  if config.error_body == "" then
+    return ngx.exit(ngx.HTTP_UNAUTHORIZED)
+  end
+
+  ngx.status = ngx.HTTP_UNAUTHORIZED
+  ngx.header["Content-Type"] = config.error_content_type
+  ngx.print(config.error_body)
+  return ngx.exit(ngx.HTTP_OK)
+end
+
+function _M.call(config)
+  local opts = {
+    discovery = { jwks_uri = config.jwks_url },
+    jwk_expires_in = config.jwks_cache_duration,
+    ssl_verify = "yes",
+  }
+
+  local openidc = require("resty.openidc")
+  local claims, err = openidc.bearer_jwt_verify(opts, claim_spec(config))
+  if err then
+    ngx.log(ngx.INFO, "JWT validation failed: ", err)
+    return reject(config)
+  end
+
+  util.set_claim_headers(config.claim_headers, claims)
+end
+
+if _TEST then
+  _M.audience_validator = audience_validator
+end
+
+return _M
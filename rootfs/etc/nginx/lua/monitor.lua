@@ -5,11 +5,14 @@ local new_tab = require "table.new"
 local clear_tab = require "table.clear"
 local clone_tab = require "table.clone"
 
--- if an Nginx worker processes more than (MAX_BATCH_SIZE/FLUSH_INTERVAL) RPS then it will start dropping metrics
-local MAX_BATCH_SIZE = 10000
-local FLUSH_INTERVAL = 1 -- second
+-- if an Nginx worker processes more than (max_batch_size/flush_interval) RPS then it will start dropping metrics
+local DEFAULT_MAX_BATCH_SIZE = 10000
+local DEFAULT_FLUSH_INTERVAL = 1 -- second
 
-local metrics_batch = new_tab(MAX_BATCH_SIZE, 0)
+local max_batch_size = DEFAULT_MAX_BATCH_SIZE
+local flush_interval = DEFAULT_FLUSH_INTERVAL
+
+local metrics_batch = new_tab(max_batch_size, 0)
 
 local _M = {}
 
@@ -20,13 +23,80 @@ local function send(payload)
   assert(s:close())
 end
 
-local function metrics()
+-- ModSecurity inspects the request headers in the rewrite phase, before
+-- the variables of the location are set, and the request body in the
+-- preaccess phase, after the rewrite_by_lua handler and before the access
+-- phase. A 403 response without upstream is reported as blocked only when
+-- it was returned by one of these two steps
+local function modsecurity_blocked(opts)
+  if not opts.modsecurity then
+    return nil
+  end
+
+  if ngx.var.status ~= "403" or ngx.var.upstream_addr then
+    return nil
+  end
+
+  if not ngx.ctx.monitor_rewrite then
+    if not ngx.var.namespace or ngx.var.namespace == "" then
+      return true
+    end
+
+    -- denied by the rewrite module, like the whitelist of the location
+    return nil
+  end
+
+  -- allow, deny and the authentication modules reject requests in the
+  -- access phase too, before access_by_lua runs
+  if not ngx.ctx.monitor_access and not opts.access_checks then
+    return true
+  end
+
+  return nil
+end
+
+-- requests with a body larger than client_max_body_size are
+-- rejected by NGINX before reaching the upstream servers
+local function request_body_too_large()
+  if ngx.var.status == "413" and not ngx.var.upstream_addr then
+    return true
+  end
+
+  return nil
+end
+
+-- NGINX separates the addresses of the upstream servers contacted
+-- while processing a request with commas, so more than one address
+-- means a retry. The groups of addresses of internal redirects, like
+-- error_page or auth_request, are separated with colons instead
+local function upstream_retried()
+  local upstream_addr = ngx.var.upstream_addr
+  if not upstream_addr then
+    return nil
+  end
+
+  if string.find(upstream_addr, ", ", 1, true) then
+    return true
+  end
+
+  return nil
+end
+
+local function label(value, default)
+  if (not value or value == "") and default then
+    return default
+  end
+
+  return value or "-"
+end
+
+local function metrics(opts)
   return {
     host = ngx.var.host or "-",
-    namespace = ngx.var.namespace or "-",
-    ingress = ngx.var.ingress_name or "-",
-    service = ngx.var.service_name or "-",
-    path = ngx.var.location_path or "-",
+    namespace = label(ngx.var.namespace, opts.namespace),
+    ingress = label(ngx.var.ingress_name, opts.ingress),
+    service = label(ngx.var.service_name, opts.service),
+    path = label(ngx.var.location_path, opts.path),
 
     method = ngx.var.request_method or "-",
     status = ngx.var.status or "-",
@@ -38,6 +108,11 @@ local function metrics()
     upstreamResponseTime = tonumber(ngx.var.upstream_response_time) or -1,
     upstreamResponseLength = tonumber(ngx.var.upstream_response_length) or -1,
     --upstreamStatus = ngx.var.upstream_status or "-",
+
+    modsecurityBlocked = modsecurity_blocked(opts),
+    globalRateLimitExceeded = ngx.var.global_rate_limit_exceeded == "1" or nil,
+    requestBodyTooLarge = request_body_too_large(),
+    upstreamRetried = upstream_retried(),
   }
 end
 
@@ -62,21 +137,43 @@ local function flush(premature)
   send(payload)
 end
 
+-- configure sets how often (in seconds) and how many requests at most
+-- every worker reports to the collector
+function _M.configure(config)
+  flush_interval = config.flush_interval or DEFAULT_FLUSH_INTERVAL
+  max_batch_size = config.max_batch_size or DEFAULT_MAX_BATCH_SIZE
+
+  metrics_batch = new_tab(max_batch_size, 0)
+end
+
 function _M.init_worker()
-  local _, err = ngx.timer.every(FLUSH_INTERVAL, flush)
+  local _, err = ngx.timer.every(flush_interval, flush)
   if err then
     ngx.log(ngx.ERR, string.format("error when setting up timer.every: %s", tostring(err)))
   end
 end
 
-function _M.call()
+-- rewrite and access mark the phases the request went through in
+-- the locations with ModSecurity enabled
+function _M.rewrite()
+  ngx.ctx.monitor_rewrite = true
+end
+
+function _M.access()
+  ngx.ctx.monitor_access = true
+end
+
+-- call takes the options of the location: whether ModSecurity is enabled,
+-- whether it has access checks other than access_by_lua, and the labels to
+-- use when ModSecurity denies the request before they are set
+function _M.call(opts)
   local metrics_size = #metrics_batch
-  if metrics_size >= MAX_BATCH_SIZE then
+  if metrics_size >= max_batch_size then
     ngx.log(ngx.WARN, "omitting metrics for the request, current batch is full")
     return
   end
 
-  metrics_batch[metrics_size + 1] = metrics()
+  metrics_batch[metrics_size + 1] = metrics(opts or {})
 end
 
 if _TEST then
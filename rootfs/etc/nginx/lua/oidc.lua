@@ -0,0 +1,80 @@
+local cjson = require("cjson.safe")
+local util = require("util")
+
+local io = io
+local tostring = tostring
+
+local _M = {}
+
+-- client credentials of each location, read once per worker. A change in
+-- the credentials modifies the configuration and reloads NGINX
+local credentials = {}
+
+local function read_credentials(path)
+  local cached = credentials[path]
+  if cached then
+    return cached
+  end
+
+  local file, err = io.open(path, "r")
+  if not file then
+    return nil, "could not open credentials file: " .. tostring(err)
+  end
+
+  local content = file:read("*a")
+  file:close()
+
+  local creds
+  creds, err = cjson.decode(content)
+  if not creds then
+    return nil, "could not parse credentials file: " .. tostring(err)
+  end
+
+  credentials[path] = creds
+  return creds
+end
+
+function _M.call(config)
+  local creds, err = read_credentials(config.credentials_file)
+  if not creds then
+    ngx.log(ngx.ERR, err)
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  local opts = {
+    discovery = config.discovery_url,
+    client_id = creds.client_id,
+    client_secret = creds.client_secret,
+    scope = config.scope,
+    redirect_uri = config.redirect_uri,
+    logout_path = config.logout_path,
+    ssl_verify = "yes",
+    renew_access_token_on_expiry = true,
+  }
+
+  local session_opts = {
+    name = config.session_cookie_name,
+    secret = creds.session_secret,
+    cookie = {
+      lifetime = config.session_lifetime,
+      secure = ngx.var.scheme == "https",
+      httponly = true,
+    },
+  }
+
+  local openidc = require("resty.openidc")
+  local res
+  res, err = openidc.authenticate(opts, nil, nil, session_opts)
+  if err then
+    ngx.log(ngx.ERR, "OpenID Connect authentication failed: ", err)
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  util.set_claim_headers(config.claim_headers, res.id_token)
+end
+
+if _TEST then
+  _M.read_credentials = read_credentials
+end
+
+return _M
@@ -0,0 +1,69 @@
+local string_format = string.format
+local ipairs = ipairs
+local pcall = pcall
+local require = require
+
+local _M = {}
+
+-- phases in which a plugin can run. A plugin is a module returning a table
+-- with a function named after each of the phases it wants to hook into
+local PHASES = {
+  rewrite = true,
+  access = true,
+  header_filter = true,
+  log = true,
+}
+
+local plugins = {}
+
+local function load_plugin(name)
+  local path = string_format("plugins.%s.main", name)
+
+  local ok, plugin = pcall(require, path)
+  if not ok then
+    ngx.log(ngx.ERR, string_format("error loading plugin %s: %s", name, tostring(plugin)))
+    return
+  end
+
+  if type(plugin) ~= "table" then
+    ngx.log(ngx.ERR, string_format("plugin %s must return a table", name))
+    return
+  end
+
+  plugins[name] = plugin
+end
+
+-- init loads the plugins. It is called once when NGINX starts, a plugin
+-- that cannot be loaded is logged and skipped to not break the server
+function _M.init(names)
+  for _, name in ipairs(names) do
+    load_plugin(name)
+  end
+end
+
+-- run executes the hooks of the plugins for the current phase in order.
+-- An error in a plugin is logged and does not stop the request
+function _M.run(names)
+  local phase = ngx.get_phase()
+  if not PHASES[phase] then
+    ngx.log(ngx.ERR, string_format("plugins cannot run in phase %s", phase))
+    return
+  end
+
+  for _, name in ipairs(names) do
+    local plugin = plugins[name]
+    local hook = plugin and plugin[phase]
+    if hook then
+      local ok, err = pcall(hook)
+      if not ok then
+        ngx.log(ngx.ERR, string_format("error running plugin %s in phase %s: %s", name, phase, tostring(err)))
+      end
+    end
+  end
+end
+
+if _TEST then
+  _M.get_plugins = function() return plugins end
+end
+
+return _M
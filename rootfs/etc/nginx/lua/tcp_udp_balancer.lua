@@ -4,6 +4,7 @@ local util = require("util")
 local dns_util = require("util.dns")
 local configuration = require("tcp_udp_configuration")
 local round_robin = require("balancer.round_robin")
+local chash = require("balancer.chash")
 
 -- measured in seconds
 -- for an Nginx worker to pick up the new list of upstream peers
@@ -12,15 +13,23 @@ local BACKENDS_SYNC_INTERVAL = 1
 
 local DEFAULT_LB_ALG = "round_robin"
 local IMPLEMENTATIONS = {
-  round_robin = round_robin
+  round_robin = round_robin,
+  chash = chash,
 }
 
 local _M = {}
 local balancers = {}
+-- backend names indexed by protocol and exposed port, i.e. "tcp-9000"
+local backend_names = {}
 
 local function get_implementation(backend)
   local name = backend["load-balance"] or DEFAULT_LB_ALG
 
+  -- UDP services with session persistence hash the address of the client
+  if backend["upstreamHashByConfig"] and backend["upstreamHashByConfig"]["upstream-hash-by"] then
+    name = "chash"
+  end
+
   local implementation = IMPLEMENTATIONS[name]
   if not implementation then
     ngx.log(ngx.WARN, string.format("%s is not supported, falling back to %s", backend["load-balance"], DEFAULT_LB_ALG))
@@ -62,6 +71,15 @@ local function sync_backend(backend)
   end
 
   ngx.log(ngx.INFO, string.format("backend ", backend.name))
+
+  -- ExternalName services are resolved on every sync, the DNS cache honors the TTL of the records
+  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
+  if service_type == "ExternalName" then
+    backend = resolve_external_names(backend)
+  end
+
+  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
+
   local implementation = get_implementation(backend)
   local balancer = balancers[backend.name]
 
@@ -82,20 +100,25 @@ local function sync_backend(backend)
     return
   end
 
-  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
-  if service_type == "ExternalName" then
-    backend = resolve_external_names(backend)
-  end
+  balancer:sync(backend)
+end
 
-  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
+-- backend names are prefixed by the protocol, i.e. "tcp-namespace-service-port",
+-- and the port of the backend is the port exposed in NGINX
+local function backend_key(backend)
+  local protocol = backend.name:match("^(%a+)-")
+  if not protocol or not backend.port then
+    return nil
+  end
 
-  balancer:sync(backend)
+  return string.format("%s-%s", protocol, tostring(backend.port))
 end
 
 local function sync_backends()
   local backends_data = configuration.get_backends_data()
   if not backends_data then
     balancers = {}
+    backend_names = {}
     return
   end
 
@@ -106,10 +129,17 @@ local function sync_backends()
   end
 
   local balancers_to_keep = {}
+  local new_backend_names = {}
   for _, new_backend in ipairs(new_backends) do
     sync_backend(new_backend)
     balancers_to_keep[new_backend.name] = balancers[new_backend.name]
+
+    local key = backend_key(new_backend)
+    if key then
+      new_backend_names[key] = new_backend.name
+    end
   end
+  backend_names = new_backend_names
 
   for backend_name, _ in pairs(balancers) do
     if not balancers_to_keep[backend_name] then
@@ -136,6 +166,19 @@ function _M.init_worker()
   end
 end
 
+-- preread selects the backend of the Service exposed in the port
+-- of the connection. This allows changing the Service without a reload
+function _M.preread()
+  local key = string.format("%s-%s", string.lower(ngx.var.protocol), ngx.var.server_port)
+  local backend_name = backend_names[key]
+  if not backend_name then
+    ngx.log(ngx.WARN, "there is no backend configured for ", key)
+    return
+  end
+
+  ngx.var.proxy_upstream_name = backend_name
+end
+
 function _M.balance()
   local balancer = get_balancer()
   if not balancer then
@@ -172,6 +215,7 @@ end
 if _TEST then
   _M.get_implementation = get_implementation
   _M.sync_backend = sync_backend
+  _M.backend_key = backend_key
 end
 
 return _M
@@ -1,3 +1,5 @@
+local util = require("util")
+
 -- this is the Lua representation of TCP/UDP Configuration
 local tcp_udp_configuration_data = ngx.shared.tcp_udp_configuration_data
 
@@ -33,6 +35,8 @@ function _M.call()
     ngx.say("error: ", err_conf)
     return
   end
+
+  util.check_shared_dict_usage("tcp_udp_configuration_data")
 end
 
 return _M
@@ -24,5 +24,48 @@ describe("Balancer chash", function()
       local peer = instance:balance()
       assert.equal("10.184.7.40:8080", peer)
     end)
+
+    it("uses the combination of variables in the key", function()
+      _G.ngx = { var = { host = "example.com", request_uri = "/alma/armud" }}
+
+      local resty_chash = package.loaded["resty.chash"]
+      resty_chash.new = function(self, nodes)
+        return {
+          find = function(self, key)
+            assert.equal("example.com/alma/armud", key)
+            return "10.184.7.40:8080"
+          end
+        }
+      end
+
+      local backend = {
+        name = "my-dummy-backend", upstreamHashByConfig = { ["upstream-hash-by"] = "$host$request_uri" },
+        endpoints = { { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } }
+      }
+      local instance = balancer_chash:new(backend)
+
+      local peer = instance:balance()
+      assert.equal("10.184.7.40:8080", peer)
+    end)
+  end)
+
+  describe("sync()", function()
+    it("updates the key of the hash", function()
+      local resty_chash = package.loaded["resty.chash"]
+      resty_chash.new = function(self, nodes)
+        return { nodes = nodes, reinit = function() end }
+      end
+
+      local backend = {
+        name = "my-dummy-backend", upstreamHashByConfig = { ["upstream-hash-by"] = "$request_uri" },
+        endpoints = { { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } }
+      }
+      local instance = balancer_chash:new(backend)
+
+      backend.upstreamHashByConfig = { ["upstream-hash-by"] = "$http_x_user" }
+      instance:sync(backend)
+
+      assert.equal("$http_x_user", instance.hash_by)
+    end)
   end)
 end)
@@ -78,5 +78,32 @@ describe("Balancer chash subset", function()
         assert.are.equal(#endpoints, 3)
       end
     end)
+
+    it("uses the default subset size when the size is not valid", function()
+      local backend = get_test_backend(6)
+      backend["upstreamHashByConfig"]["upstream-hash-by-subset-size"] = 0
+
+      local instance = balancer_chashsubset:new(backend)
+      for id, endpoints in pairs(instance["subsets"]) do
+        assert.are.equal(#endpoints, 3)
+      end
+    end)
+  end)
+
+  describe("sync()", function()
+    it("rebuilds the subsets when the subset size changes", function()
+      local backend = get_test_backend(6)
+      local instance = balancer_chashsubset:new(backend)
+
+      backend = get_test_backend(6)
+      backend["upstreamHashByConfig"]["upstream-hash-by-subset-size"] = 2
+      backend["upstreamHashByConfig"]["upstream-hash-by"] = "$http_x_user"
+      instance:sync(backend)
+
+      assert.equal("$http_x_user", instance.hash_by)
+      for id, endpoints in pairs(instance["subsets"]) do
+        assert.are.equal(#endpoints, 2)
+      end
+    end)
   end)
 end)
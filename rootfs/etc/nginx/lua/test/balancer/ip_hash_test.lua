@@ -0,0 +1,28 @@
+describe("Balancer ip_hash", function()
+  local balancer_ip_hash = require("balancer.ip_hash")
+
+  describe("balance()", function()
+    it("uses the address of the client as key", function()
+      _G.ngx = { var = { remote_addr = "192.168.1.10", http_x_forwarded_for = "10.0.0.1" } }
+
+      local resty_chash = package.loaded["resty.chash"]
+      resty_chash.new = function(self, nodes)
+        return {
+          find = function(self, key)
+            assert.equal("192.168.1.10", key)
+            return "10.184.7.40:8080"
+          end
+        }
+      end
+
+      local backend = {
+        name = "my-dummy-backend", ["load-balance"] = "ip_hash",
+        endpoints = { { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 } }
+      }
+      local instance = balancer_ip_hash:new(backend)
+
+      local peer = instance:balance()
+      assert.equal("10.184.7.40:8080", peer)
+    end)
+  end)
+end)
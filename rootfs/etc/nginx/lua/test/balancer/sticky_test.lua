@@ -221,5 +221,104 @@ describe("Sticky", function()
         assert.equal(peer, test_backend_endpoint)
       end)
     end)
+
+    context("when the cookie attributes are configured", function()
+      it("sets the domain, SameSite and Secure attributes", function()
+        local s = {}
+        cookie.new = function(self)
+          local cookie_instance = {
+            set = function(self, payload)
+              assert.equal(payload.domain, ".test.com")
+              assert.equal(payload.extension, "SameSite=None")
+              assert.equal(payload.secure, true)
+              return true, nil
+            end,
+            get = function(k) return false end,
+          }
+          s = spy.on(cookie_instance, "set")
+          return cookie_instance, false
+        end
+        local b = get_test_backend()
+        b.sessionAffinityConfig.cookieSessionAffinity.domain = ".test.com"
+        b.sessionAffinityConfig.cookieSessionAffinity.samesite = "None"
+        b.sessionAffinityConfig.cookieSessionAffinity.locations = { ["test.com"] = {"/"} }
+        local sticky_balancer_instance = sticky:new(b)
+        assert.has_no.errors(function() sticky_balancer_instance:balance() end)
+        assert.spy(s).was_called()
+      end)
+
+      it("sets a secure cookie on plain HTTP requests when secure is enabled", function()
+        local s = {}
+        cookie.new = function(self)
+          local cookie_instance = {
+            set = function(self, payload)
+              assert.equal(payload.secure, true)
+              assert.equal(payload.extension, nil)
+              return true, nil
+            end,
+            get = function(k) return false end,
+          }
+          s = spy.on(cookie_instance, "set")
+          return cookie_instance, false
+        end
+        local b = get_test_backend()
+        b.sessionAffinityConfig.cookieSessionAffinity.secure = true
+        b.sessionAffinityConfig.cookieSessionAffinity.locations = { ["test.com"] = {"/"} }
+        local sticky_balancer_instance = sticky:new(b)
+        assert.has_no.errors(function() sticky_balancer_instance:balance() end)
+        assert.spy(s).was_called()
+      end)
+    end)
+
+    context("when the endpoint of the session fails", function()
+      local b, key, failed_endpoint, original_get_last_failure
+
+      before_each(function()
+        b = get_test_backend()
+        b.endpoints[2] = { address = "10.184.7.41", port = "8080", maxFails = 0, failTimeout = 0 }
+        key = "session-key"
+        failed_endpoint = sticky:new(b).instance:find(key)
+        ngx.var.upstream_addr = failed_endpoint
+        original_get_last_failure = sticky.get_last_failure
+        sticky.get_last_failure = function() return "failed" end
+      end)
+
+      after_each(function()
+        sticky.get_last_failure = original_get_last_failure
+      end)
+
+      it("moves the session to a new endpoint when change on failure is enabled", function()
+        local s = {}
+        cookie.new = function(self)
+          local cookie_instance = {
+            set = function(self, payload) return true, nil end,
+            get = function(self, k) return key end,
+          }
+          s = spy.on(cookie_instance, "set")
+          return cookie_instance, false
+        end
+        b.sessionAffinityConfig.cookieSessionAffinity.changeonfailure = true
+        local sticky_balancer_instance = sticky:new(b)
+        local peer = sticky_balancer_instance:balance()
+        assert.are_not.equal(failed_endpoint, peer)
+        assert.spy(s).was_called()
+      end)
+
+      it("keeps the session in the failed endpoint when change on failure is disabled", function()
+        local s = {}
+        cookie.new = function(self)
+          local cookie_instance = {
+            set = function(self, payload) return true, nil end,
+            get = function(self, k) return key end,
+          }
+          s = spy.on(cookie_instance, "set")
+          return cookie_instance, false
+        end
+        local sticky_balancer_instance = sticky:new(b)
+        local peer = sticky_balancer_instance:balance()
+        assert.equal(failed_endpoint, peer)
+        assert.spy(s).was_not_called()
+      end)
+    end)
   end)
 end)
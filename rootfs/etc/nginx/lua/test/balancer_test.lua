@@ -15,6 +15,7 @@ local function reset_expected_implementations()
     ["my-dummy-app-3"] = package.loaded["balancer.sticky"],
     ["my-dummy-app-4"] = package.loaded["balancer.ewma"],
     ["my-dummy-app-5"] = package.loaded["balancer.sticky"],
+    ["my-dummy-app-6"] = package.loaded["balancer.ip_hash"],
   }
 end
 
@@ -45,6 +46,7 @@ local function reset_backends()
       name = "my-dummy-app-5", ["load-balance"] = "ewma", ["upstream-hash-by"] = "$request_uri",
       sessionAffinityConfig = { name = "cookie", cookieSessionAffinity = { name = "route", hash = "sha1" } }
     },
+    { name = "my-dummy-app-6", ["load-balance"] = "ip_hash", },
   }
 end
 
@@ -118,6 +120,34 @@ describe("Balancer", function()
       assert.stub(mock_instance.sync).was_called_with(mock_instance, expected_backend)
     end)
 
+    it("resolves external name to endpoints when the balancer is initialized", function()
+      backend = {
+        name = "exmaple-com", service = { spec = { ["type"] = "ExternalName" } },
+        endpoints = {
+          { address = "example.com", port = "80", maxFails = 0, failTimeout = 0 }
+        }
+      }
+
+      local dns_helper = require("test/dns_helper")
+      dns_helper.mock_dns_query({
+        {
+          name = "example.com",
+          address = "192.168.1.1",
+          ttl = 3600,
+        },
+      })
+      expected_backend = {
+        name = "exmaple-com", service = { spec = { ["type"] = "ExternalName" } },
+        endpoints = {
+          { address = "192.168.1.1", port = "80" },
+        }
+      }
+
+      local s = spy.on(implementation, "new")
+      assert.has_no.errors(function() balancer.sync_backend(backend) end)
+      assert.spy(s).was_called_with(implementation, expected_backend)
+    end)
+
     it("wraps IPv6 addresses into square brackets", function()
       local backend = {
         name = "exmaple-com",
@@ -174,4 +204,97 @@ describe("Balancer", function()
       assert.stub(mock_instance.sync).was_called_with(mock_instance, backend)
     end)
   end)
+
+  describe("websocket_timeouts()", function()
+    local original_ngx_var = ngx.var
+
+    after_each(function()
+      ngx.var = original_ngx_var
+    end)
+
+    it("returns the WebSocket timeouts of the location for WebSocket handshakes", function()
+      ngx.var = { http_upgrade = "WebSocket", websocket_connect_timeout = "5",
+        websocket_send_timeout = "60", websocket_read_timeout = "3600" }
+
+      local connect_timeout, send_timeout, read_timeout = balancer.websocket_timeouts()
+      assert.are.same({ 5, 60, 3600 }, { connect_timeout, send_timeout, read_timeout })
+    end)
+
+    it("returns nil for requests that are not WebSocket handshakes", function()
+      ngx.var = { websocket_connect_timeout = "5", websocket_send_timeout = "60", websocket_read_timeout = "3600" }
+      assert.is_nil(balancer.websocket_timeouts())
+
+      ngx.var.http_upgrade = "h2c"
+      assert.is_nil(balancer.websocket_timeouts())
+    end)
+
+    it("returns nil when the location does not configure WebSocket timeouts", function()
+      ngx.var = { http_upgrade = "websocket" }
+      assert.is_nil(balancer.websocket_timeouts())
+    end)
+  end)
+
+  describe("route_to_alternative_balancer()", function()
+    local original_ngx_var = ngx.var
+    local primary, canary
+
+    before_each(function()
+      canary = {
+        name = "my-canary-app-80", ["load-balance"] = "round_robin",
+        endpoints = { { address = "10.184.7.41", port = "8080", maxFails = 0, failTimeout = 0 } },
+        trafficShapingPolicy = { weight = 0, header = "X-Canary", headerValue = "", cookie = "canary" },
+      }
+      primary = { alternative_backends = { canary.name } }
+      ngx.var = {}
+    end)
+
+    after_each(function()
+      ngx.var = original_ngx_var
+    end)
+
+    it("does not route to the canary without alternative backends", function()
+      assert.is_false(balancer.route_to_alternative_balancer({}))
+    end)
+
+    it("routes by header", function()
+      balancer.sync_backend(canary)
+
+      ngx.var = { http_x_canary = "always" }
+      assert.is_true(balancer.route_to_alternative_balancer(primary))
+
+      ngx.var = { http_x_canary = "never", cookie_canary = "always" }
+      assert.is_false(balancer.route_to_alternative_balancer(primary))
+    end)
+
+    it("routes by header value", function()
+      canary.trafficShapingPolicy.headerValue = "beta"
+      balancer.sync_backend(canary)
+
+      ngx.var = { http_x_canary = "beta" }
+      assert.is_true(balancer.route_to_alternative_balancer(primary))
+
+      ngx.var = { http_x_canary = "always" }
+      assert.is_false(balancer.route_to_alternative_balancer(primary))
+    end)
+
+    it("routes by cookie", function()
+      balancer.sync_backend(canary)
+
+      ngx.var = { cookie_canary = "always" }
+      assert.is_true(balancer.route_to_alternative_balancer(primary))
+
+      ngx.var = { cookie_canary = "never" }
+      assert.is_false(balancer.route_to_alternative_balancer(primary))
+    end)
+
+    it("routes by weight", function()
+      canary.trafficShapingPolicy.weight = 100
+      balancer.sync_backend(canary)
+      assert.is_true(balancer.route_to_alternative_balancer(primary))
+
+      canary.trafficShapingPolicy.weight = 0
+      balancer.sync_backend(canary)
+      assert.is_false(balancer.route_to_alternative_balancer(primary))
+    end)
+  end)
 end)
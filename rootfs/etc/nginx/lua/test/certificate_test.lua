@@ -7,6 +7,7 @@ local unmocked_ngx = _G.ngx
 describe("Certificate", function()
   describe("call", function()
     local ssl = require("ngx.ssl")
+    local ocsp = require("ngx.ocsp")
     local match = require("luassert.match")
 
     before_each(function()
@@ -14,6 +15,7 @@ describe("Certificate", function()
       ssl.clear_certs = function() return true, "" end
       ssl.set_der_cert = function(cert) return true, "" end
       ssl.set_der_priv_key = function(priv_key) return true, "" end
+      ocsp.set_ocsp_status_resp = function(der_response) return true, "" end
 
       ngx.exit = function(status) end
     end)
@@ -21,6 +23,7 @@ describe("Certificate", function()
     after_each(function()
       ngx = unmocked_ngx
       ngx.shared.certificate_data:flush_all()
+      ngx.shared.ocsp_response_data:flush_all()
     end)
 
     it("does not clear fallback certificates and logs error message when host is not in dictionary", function()
@@ -64,6 +67,20 @@ describe("Certificate", function()
       assert.spy(ssl.set_der_priv_key).was_called_with(ssl.priv_key_pem_to_der(PEM_CERT_KEY))
     end)
 
+    it("sets the ECDSA certificate and key next to the RSA ones when present", function()
+      ngx.shared.certificate_data:set("hostname", PEM_CERT_KEY)
+      ngx.shared.certificate_data:set("hostname:ecdsa", PEM_CERT_KEY)
+
+      spy.on(ngx, "log")
+      spy.on(ssl, "set_der_cert")
+      spy.on(ssl, "set_der_priv_key")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ngx.log).was_not_called_with(ngx.ERR, _)
+      assert.spy(ssl.set_der_cert).was_called(2)
+      assert.spy(ssl.set_der_priv_key).was_called(2)
+    end)
+
     it("successfully sets SSL certificate and key for wildcard cert", function()
       ssl.server_name = function() return "sub.hostname", nil end
       ngx.shared.certificate_data:set("*.hostname", PEM_CERT_KEY)
@@ -92,6 +109,51 @@ describe("Certificate", function()
       assert.spy(ssl.set_der_priv_key).was_called_with(ssl.priv_key_pem_to_der(PEM_CERT_KEY))
     end)
 
+    it("staples the OCSP response of the certificate when it is present", function()
+      ngx.shared.certificate_data:set("hostname", PEM_CERT_KEY)
+      ngx.shared.ocsp_response_data:set("hostname", "ocsp response")
+
+      spy.on(ngx, "log")
+      spy.on(ocsp, "set_ocsp_status_resp")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ngx.log).was_not_called_with(ngx.ERR, _)
+      assert.spy(ocsp.set_ocsp_status_resp).was_called_with("ocsp response")
+    end)
+
+    it("staples the OCSP response of a wildcard cert", function()
+      ssl.server_name = function() return "sub.hostname", nil end
+      ngx.shared.certificate_data:set("*.hostname", PEM_CERT_KEY)
+      ngx.shared.ocsp_response_data:set("*.hostname", "ocsp response")
+
+      spy.on(ocsp, "set_ocsp_status_resp")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ocsp.set_ocsp_status_resp).was_called_with("ocsp response")
+    end)
+
+    it("sets the certificate and logs error message when the OCSP response cannot be stapled", function()
+      ngx.shared.certificate_data:set("hostname", PEM_CERT_KEY)
+      ngx.shared.ocsp_response_data:set("hostname", "ocsp response")
+      ocsp.set_ocsp_status_resp = function(der_response) return nil, "error" end
+
+      spy.on(ngx, "log")
+      spy.on(ssl, "set_der_cert")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ssl.set_der_cert).was_called_with(ssl.cert_pem_to_der(PEM_CERT_KEY))
+      assert.spy(ngx.log).was_called_with(ngx.ERR, "failed to set OCSP response for hostname hostname: error")
+    end)
+
+    it("does not staple an OCSP response when there is none", function()
+      ngx.shared.certificate_data:set("hostname", PEM_CERT_KEY)
+
+      spy.on(ocsp, "set_ocsp_status_resp")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ocsp.set_ocsp_status_resp).was_not_called()
+    end)
+
     it("logs error message when certificate in dictionary is invalid", function()
       ngx.shared.certificate_data:set("hostname", "something invalid")
 
@@ -120,4 +182,42 @@ describe("Certificate", function()
       assert.spy(ssl.set_der_priv_key).was_not_called()
     end)
   end)
+
+  describe("check_protocols", function()
+    local ssl = require("ngx.ssl")
+    local unmocked_get_tls1_version = ssl.get_tls1_version
+
+    before_each(function()
+      ngx.exit = function(status) end
+    end)
+
+    after_each(function()
+      ngx = unmocked_ngx
+      ssl.get_tls1_version = unmocked_get_tls1_version
+    end)
+
+    it("accepts the handshake using an enabled protocol", function()
+      ssl.get_tls1_version = function() return 0x0304 end
+      spy.on(ngx, "exit")
+
+      assert.has_no.errors(function() certificate.check_protocols("TLSv1.2 TLSv1.3") end)
+      assert.spy(ngx.exit).was_not_called()
+    end)
+
+    it("aborts the handshake using a protocol not enabled", function()
+      ssl.get_tls1_version = function() return 0x0303 end
+      spy.on(ngx, "exit")
+
+      assert.has_no.errors(function() certificate.check_protocols("TLSv1.3") end)
+      assert.spy(ngx.exit).was_called_with(ngx.ERROR)
+    end)
+
+    it("aborts the handshake when the protocol is unknown", function()
+      ssl.get_tls1_version = function() return nil, "error" end
+      spy.on(ngx, "exit")
+
+      assert.has_no.errors(function() certificate.check_protocols("TLSv1.3") end)
+      assert.spy(ngx.exit).was_called_with(ngx.ERROR)
+    end)
+  end)
 end)
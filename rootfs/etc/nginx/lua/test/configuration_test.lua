@@ -92,6 +92,17 @@ describe("Configuration", function()
             end)
         end)
 
+        context("GET request to /configuration/ping", function()
+            it("returns a status of 200 without reading the backends", function()
+                ngx.var.request_method = "GET"
+                ngx.var.request_uri = "/configuration/ping"
+                local s = spy.on(ngx, "print")
+                assert.has_no.errors(configuration.call)
+                assert.equal(ngx.status, ngx.HTTP_OK)
+                assert.spy(s).was_not_called()
+            end)
+        end)
+
         context("POST request to /configuration/backends", function()
             before_each(function()
                 ngx.var.request_method = "POST"
@@ -206,6 +217,37 @@ describe("Configuration", function()
             assert.same(ngx.status, ngx.HTTP_CREATED)
         end)
 
+        it("should store and remove the OCSP response of each host", function()
+            ngx.var.request_method = "POST"
+            local mock_servers = cjson.encode({
+                {
+                    hostname = "hostname",
+                    sslCert = {
+                        pemCertKey = "pemCertKey",
+                        ocspResponse = ngx.encode_base64("ocspResponse")
+                    }
+                }
+            })
+            ngx.req.get_body_data = function() return mock_servers end
+
+            assert.has_no.errors(configuration.handle_servers)
+            assert.same(ngx.shared.ocsp_response_data:get("hostname"), "ocspResponse")
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+
+            mock_servers = cjson.encode({
+                {
+                    hostname = "hostname",
+                    sslCert = {
+                        pemCertKey = "pemCertKey"
+                    }
+                }
+            })
+
+            assert.has_no.errors(configuration.handle_servers)
+            assert.is_nil(ngx.shared.ocsp_response_data:get("hostname"))
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
         it("should log an err and set status to Internal Server Error when a certificate cannot be set", function()
             ngx.var.request_method = "POST"
             ngx.shared.certificate_data.safe_set = function(self, data) return false, "error" end
@@ -0,0 +1,107 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_redis(counters)
+  local red = {}
+  stub(red, "set_timeout")
+  stub(red, "connect", true)
+  stub(red, "expire", true)
+  stub(red, "set_keepalive")
+  stub(red, "close")
+  red.incr = function(_, key)
+    counters[key] = (counters[key] or 0) + 1
+    return counters[key]
+  end
+
+  package.loaded["resty.redis"] = { new = function() return red end }
+  return red
+end
+
+local function config()
+  return {
+    namespace = "default_example",
+    limit = 2,
+    window_size = 60,
+    ignored_cidrs = {},
+  }
+end
+
+describe("global_ratelimit", function()
+  local global_ratelimit
+  local status
+
+  before_each(function()
+    package.loaded["global_ratelimit"] = nil
+    global_ratelimit = require("global_ratelimit")
+    global_ratelimit.configure({
+      backend = "redis",
+      host = "redis.default.svc",
+      port = 0,
+      connect_timeout = 50,
+      max_idle_timeout = 10000,
+      pool_size = 50,
+      status_code = 429,
+    })
+
+    status = nil
+    mock_ngx({
+      var = { remote_addr = "192.168.1.1", global_rate_limit_key = "192.168.1.1" },
+      now = function() return 120 end,
+      exit = function(s) status = s end,
+    })
+  end)
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["resty.redis"] = nil
+  end)
+
+  it("rejects the requests exceeding the limit in the window", function()
+    local counters = {}
+    local red = mock_redis(counters)
+
+    global_ratelimit.call(config())
+    global_ratelimit.call(config())
+    assert.is_nil(status)
+
+    global_ratelimit.call(config())
+    assert.are.equal(429, status)
+    assert.are.equal("1", ngx.var.global_rate_limit_exceeded)
+
+    assert.stub(red.connect).was_called_with(red, "redis.default.svc", 6379)
+    assert.stub(red.expire).was.called(1)
+  end)
+
+  it("does not limit the ignored addresses", function()
+    local counters = {}
+    mock_redis(counters)
+
+    local cfg = config()
+    cfg.ignored_cidrs = { "192.168.0.0/16" }
+    for _ = 1, 5 do
+      global_ratelimit.call(cfg)
+    end
+
+    assert.is_nil(status)
+    assert.are.same({}, counters)
+  end)
+
+  it("allows the requests when the shared store is not available", function()
+    local red = mock_redis({})
+    red.connect = function() return nil, "connection refused" end
+
+    for _ = 1, 5 do
+      global_ratelimit.call(config())
+    end
+
+    assert.is_nil(status)
+  end)
+end)
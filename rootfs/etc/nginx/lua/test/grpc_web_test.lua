@@ -0,0 +1,62 @@
+_G._TEST = true
+
+local grpc_web = require("grpc_web")
+
+describe("grpc_web", function()
+  after_each(function()
+    ngx.ctx.grpc_web_content_type = nil
+  end)
+
+  describe("is_grpc_web()", function()
+    it("accepts binary gRPC-Web content types", function()
+      assert.is_true(grpc_web.is_grpc_web("application/grpc-web"))
+      assert.is_true(grpc_web.is_grpc_web("application/grpc-web+proto"))
+      assert.is_true(grpc_web.is_grpc_web("application/grpc-web; charset=utf-8"))
+    end)
+
+    it("rejects other content types", function()
+      assert.is_false(grpc_web.is_grpc_web(nil))
+      assert.is_false(grpc_web.is_grpc_web("application/grpc"))
+      assert.is_false(grpc_web.is_grpc_web("application/grpc-web-text"))
+      assert.is_false(grpc_web.is_grpc_web("application/json"))
+    end)
+  end)
+
+  describe("trailer_frame()", function()
+    it("encodes the status and message as a trailer frame", function()
+      local payload = "grpc-status:3\r\ngrpc-message:invalid\r\n"
+      local expected = string.char(128, 0, 0, 0, #payload) .. payload
+
+      assert.are.equal(expected, grpc_web.trailer_frame("3", "invalid"))
+    end)
+
+    it("omits an empty message", function()
+      local payload = "grpc-status:0\r\n"
+      local expected = string.char(128, 0, 0, 0, #payload) .. payload
+
+      assert.are.equal(expected, grpc_web.trailer_frame("0", ""))
+    end)
+  end)
+
+  describe("rewrite()", function()
+    it("translates the request content type", function()
+      ngx.var = { http_content_type = "application/grpc-web+proto" }
+      local s = spy.on(ngx.req, "set_header")
+
+      grpc_web.rewrite()
+
+      assert.spy(s).was_called_with("Content-Type", "application/grpc+proto")
+      assert.are.equal("application/grpc-web+proto", ngx.ctx.grpc_web_content_type)
+    end)
+
+    it("ignores requests that are not gRPC-Web", function()
+      ngx.var = { http_content_type = "application/grpc" }
+      local s = spy.on(ngx.req, "set_header")
+
+      grpc_web.rewrite()
+
+      assert.spy(s).was_not_called()
+      assert.is_nil(ngx.ctx.grpc_web_content_type)
+    end)
+  end)
+end)
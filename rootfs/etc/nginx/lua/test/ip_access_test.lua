@@ -0,0 +1,82 @@
+local cjson = require("cjson")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("ip_access", function()
+  local ip_access
+  local ip_access_data
+  local status
+
+  before_each(function()
+    ip_access_data = nil
+    package.loaded["configuration"] = {
+      get_ip_access_data = function() return ip_access_data end,
+    }
+    package.loaded["ip_access"] = nil
+    ip_access = require("ip_access")
+  end)
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["configuration"] = nil
+  end)
+
+  local function call(remote_addr)
+    status = nil
+    mock_ngx({ var = { remote_addr = remote_addr }, exit = function(s) status = s end })
+    ip_access.call()
+    return status
+  end
+
+  it("allows every request without IP access lists", function()
+    assert.is_nil(call("10.0.0.1"))
+  end)
+
+  it("rejects the requests from the denied CIDRs", function()
+    ip_access_data = cjson.encode({ denied = { "10.0.0.0/8" }, allowed = {} })
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("10.0.0.1"))
+    assert.is_nil(call("192.168.0.1"))
+  end)
+
+  it("allows the requests from the allowed CIDRs", function()
+    ip_access_data = cjson.encode({ denied = { "0.0.0.0/0" }, allowed = { "192.168.0.0/16" } })
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("10.0.0.1"))
+    assert.is_nil(call("192.168.0.1"))
+  end)
+
+  it("rejects the IPv6 clients from the denied CIDRs", function()
+    ip_access_data = cjson.encode({ denied = { "2001:db8::/32", "::/0" }, allowed = { "2001:db8:1::/48" } })
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("2001:db8::1"))
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("2001:db9::1"))
+    assert.is_nil(call("2001:db8:1::1"))
+    assert.is_nil(call("10.0.0.1"))
+  end)
+
+  it("matches the IPv4 clients of dual stack sockets", function()
+    ip_access_data = cjson.encode({ denied = { "10.0.0.0/8" }, allowed = { "10.1.0.0/17" } })
+
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("::ffff:10.0.0.1"))
+    assert.is_nil(call("::ffff:10.1.127.1"))
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("::ffff:10.1.128.1"))
+  end)
+
+  it("applies new IP access lists", function()
+    ip_access_data = cjson.encode({ denied = { "10.0.0.0/8" }, allowed = {} })
+    assert.are.equal(ngx.HTTP_FORBIDDEN, call("10.0.0.1"))
+
+    ip_access_data = cjson.encode({ denied = {}, allowed = {} })
+    assert.is_nil(call("10.0.0.1"))
+  end)
+end)
@@ -0,0 +1,87 @@
+_G._TEST = true
+
+local jwt_auth = require("jwt_auth")
+
+local function config()
+  return {
+    jwks_url = "https://auth.example.com/.well-known/jwks.json",
+    jwks_cache_duration = 600,
+    issuer = "https://auth.example.com/",
+    audiences = { "api" },
+    error_body = "",
+    error_content_type = "text/plain",
+    claim_headers = { { header = "X-Auth-User", claim = "sub" } },
+  }
+end
+
+describe("jwt_auth", function()
+  local headers
+  local clear_header, set_header = ngx.req.clear_header, ngx.req.set_header
+  local exit, print = ngx.exit, ngx.print
+
+  before_each(function()
+    headers = {}
+    ngx.req.clear_header = function(name) headers[name] = nil end
+    ngx.req.set_header = function(name, value) headers[name] = value end
+  end)
+
+  after_each(function()
+    ngx.req.clear_header, ngx.req.set_header = clear_header, set_header
+    ngx.exit, ngx.print = exit, print
+    package.loaded["resty.openidc"] = nil
+  end)
+
+  describe("audience_validator()", function()
+    local validator = jwt_auth.audience_validator({ "api", "web" })
+
+    it("accepts a token issued for one of the audiences", function()
+      assert.is_true(validator("web"))
+      assert.is_true(validator({ "other", "api" }))
+    end)
+
+    it("rejects a token issued for other audiences", function()
+      assert.is_false(validator("other"))
+      assert.is_false(validator({ "other" }))
+      assert.is_false(validator(nil))
+    end)
+  end)
+
+  describe("call()", function()
+    it("verifies the token and sets the claim headers", function()
+      local opts
+      package.loaded["resty.openidc"] = {
+        bearer_jwt_verify = function(o, _)
+          opts = o
+          return { sub = "jdoe" }, nil
+        end,
+      }
+
+      jwt_auth.call(config())
+
+      assert.are.equal("https://auth.example.com/.well-known/jwks.json", opts.discovery.jwks_uri)
+      assert.are.equal(600, opts.jwk_expires_in)
+      assert.are.equal("jdoe", headers["X-Auth-User"])
+    end)
+
+    it("returns the configured error body when the token is invalid", function()
+      package.loaded["resty.openidc"] = {
+        bearer_jwt_verify = function(_, _)
+          return nil, "invalid jwt"
+        end,
+      }
+
+      local status, body
+      ngx.exit = function(s) status = s end
+      ngx.print = function(b) body = b end
+
+      local cfg = config()
+      cfg.error_body = '{"error":"unauthorized"}'
+      jwt_auth.call(cfg)
+
+      assert.are.equal(ngx.HTTP_OK, status)
+      assert.are.equal(ngx.HTTP_UNAUTHORIZED, ngx.status)
+      assert.are.equal('{"error":"unauthorized"}', body)
+      assert.is_nil(headers["X-Auth-User"])
+    end)
+  end)
+end)
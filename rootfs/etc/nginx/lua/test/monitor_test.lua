@@ -41,6 +41,125 @@ describe("Monitor", function()
     assert.equal(10, #monitor.get_metrics_batch())
   end)
 
+  it("omits the requests once the batch is full", function()
+    local monitor = require("monitor")
+    monitor.configure({ max_batch_size = 2 })
+    mock_ngx({ var = {} })
+
+    for i = 1,3,1 do
+      monitor.call()
+    end
+
+    assert.equal(2, #monitor.get_metrics_batch())
+  end)
+
+  it("flushes the batch at the configured interval", function()
+    local monitor = require("monitor")
+    monitor.configure({ flush_interval = 0.5 })
+
+    local timer_mock = {}
+    stub(timer_mock, "every", true)
+    mock_ngx({ timer = timer_mock })
+
+    monitor.init_worker()
+
+    assert.stub(timer_mock.every).was_called_with(0.5, monitor.flush)
+  end)
+
+  it("reports the requests blocked by ModSecurity", function()
+    local monitor = require("monitor")
+
+    local opts = { modsecurity = true, access_checks = false, namespace = "default", ingress = "example" }
+
+    -- denied while inspecting the request headers, before the location variables are set
+    mock_ngx({ var = { status = "403" }, ctx = {} })
+    monitor.call(opts)
+
+    -- denied while inspecting the request body
+    mock_ngx({ var = { status = "403", namespace = "default" }, ctx = { monitor_rewrite = true } })
+    monitor.call(opts)
+
+    -- denied by the whitelist of the location in the rewrite phase
+    mock_ngx({ var = { status = "403", namespace = "default" }, ctx = {} })
+    monitor.call(opts)
+
+    -- denied by access_by_lua
+    mock_ngx({ var = { status = "403", namespace = "default" }, ctx = { monitor_rewrite = true, monitor_access = true } })
+    monitor.call(opts)
+
+    -- denied in a location with access checks
+    mock_ngx({ var = { status = "403", namespace = "default" }, ctx = { monitor_rewrite = true } })
+    monitor.call({ modsecurity = true, access_checks = true })
+
+    mock_ngx({ var = { status = "403", upstream_addr = "10.10.0.1" }, ctx = { monitor_rewrite = true } })
+    monitor.call(opts)
+
+    -- ModSecurity is not enabled in the location
+    mock_ngx({ var = { status = "403" }, ctx = {} })
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_true(metrics_batch[1].modsecurityBlocked)
+    assert.are.equal("default", metrics_batch[1].namespace)
+    assert.are.equal("example", metrics_batch[1].ingress)
+    assert.is_true(metrics_batch[2].modsecurityBlocked)
+    assert.is_nil(metrics_batch[3].modsecurityBlocked)
+    assert.is_nil(metrics_batch[4].modsecurityBlocked)
+    assert.is_nil(metrics_batch[5].modsecurityBlocked)
+    assert.is_nil(metrics_batch[6].modsecurityBlocked)
+    assert.is_nil(metrics_batch[7].modsecurityBlocked)
+  end)
+
+  it("reports the requests rejected by a global rate limit", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ var = { global_rate_limit_exceeded = "1", status = "429" } })
+    monitor.call()
+
+    mock_ngx({ var = { global_rate_limit_exceeded = "", status = "200" } })
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_true(metrics_batch[1].globalRateLimitExceeded)
+    assert.is_nil(metrics_batch[2].globalRateLimitExceeded)
+  end)
+
+  it("reports the requests rejected because of the body size", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ var = { status = "413" } })
+    monitor.call()
+
+    mock_ngx({ var = { status = "413", upstream_addr = "10.10.0.1" } })
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_true(metrics_batch[1].requestBodyTooLarge)
+    assert.is_nil(metrics_batch[2].requestBodyTooLarge)
+  end)
+
+  it("reports the requests retried in a different upstream server", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ var = { status = "200", upstream_addr = "10.10.0.1:8080, 10.10.0.2:8080" } })
+    monitor.call()
+
+    mock_ngx({ var = { status = "200", upstream_addr = "10.10.0.1:8080" } })
+    monitor.call()
+
+    mock_ngx({ var = { status = "502" } })
+    monitor.call()
+
+    mock_ngx({ var = { status = "404", upstream_addr = "10.10.0.1:8080 : 10.10.0.3:8080" } })
+    monitor.call()
+
+    local metrics_batch = monitor.get_metrics_batch()
+    assert.is_true(metrics_batch[1].upstreamRetried)
+    assert.is_nil(metrics_batch[2].upstreamRetried)
+    assert.is_nil(metrics_batch[3].upstreamRetried)
+    assert.is_nil(metrics_batch[4].upstreamRetried)
+  end)
+
   describe("flush", function()
     it("short circuits when premmature is true (when worker is shutting down)", function()
       local tcp_mock = mock_ngx_socket_tcp()
@@ -0,0 +1,83 @@
+_G._TEST = true
+
+local oidc = require("oidc")
+
+local function write_credentials(content)
+  local path = os.tmpname()
+  local file = io.open(path, "w")
+  file:write(content)
+  file:close()
+  return path
+end
+
+describe("oidc", function()
+  local headers
+  local clear_header, set_header = ngx.req.clear_header, ngx.req.set_header
+
+  before_each(function()
+    headers = {}
+    ngx.req.clear_header = function(name) headers[name] = nil end
+    ngx.req.set_header = function(name, value) headers[name] = value end
+  end)
+
+  after_each(function()
+    ngx.req.clear_header, ngx.req.set_header = clear_header, set_header
+    package.loaded["resty.openidc"] = nil
+  end)
+
+  describe("read_credentials()", function()
+    it("reads the client credentials", function()
+      local path = write_credentials('{"client_id":"id","client_secret":"secret","session_secret":"session"}')
+
+      local creds, err = oidc.read_credentials(path)
+      assert.is_nil(err)
+      assert.are.same({ client_id = "id", client_secret = "secret", session_secret = "session" }, creds)
+
+      os.remove(path)
+    end)
+
+    it("returns an error when the file is invalid", function()
+      local path = write_credentials("invalid")
+
+      local creds, err = oidc.read_credentials(path)
+      assert.is_nil(creds)
+      assert.is_not_nil(err)
+
+      os.remove(path)
+    end)
+  end)
+
+  describe("call()", function()
+    it("authenticates the request with the client credentials", function()
+      local path = write_credentials('{"client_id":"id","client_secret":"secret","session_secret":"session"}')
+
+      local opts, session_opts
+      package.loaded["resty.openidc"] = {
+        authenticate = function(o, _, _, s)
+          opts, session_opts = o, s
+          return { id_token = { email = "jdoe@example.com" } }, nil
+        end,
+      }
+
+      oidc.call({
+        discovery_url = "https://example.com/.well-known/openid-configuration",
+        credentials_file = path,
+        scope = "openid email",
+        redirect_uri = "/oauth2/callback",
+        logout_path = "",
+        session_cookie_name = "session",
+        session_lifetime = 3600,
+        claim_headers = { { header = "X-Auth-Email", claim = "email" } },
+      })
+
+      assert.are.equal("id", opts.client_id)
+      assert.are.equal("secret", opts.client_secret)
+      assert.are.equal("/oauth2/callback", opts.redirect_uri)
+      assert.are.equal("session", session_opts.secret)
+      assert.are.equal(3600, session_opts.cookie.lifetime)
+      assert.are.equal("jdoe@example.com", headers["X-Auth-Email"])
+
+      os.remove(path)
+    end)
+  end)
+end)
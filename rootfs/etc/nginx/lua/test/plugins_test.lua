@@ -0,0 +1,103 @@
+_G._TEST = true
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("plugins", function()
+  local calls
+
+  before_each(function()
+    calls = {}
+    package.loaded["plugins.billing.main"] = {
+      access = function() table.insert(calls, "billing.access") end,
+      log = function() table.insert(calls, "billing.log") end,
+    }
+    package.loaded["plugins.custom_auth.main"] = {
+      access = function() table.insert(calls, "custom_auth.access") end,
+    }
+    package.loaded["plugins.broken.main"] = {
+      access = function() error("boom") end,
+    }
+  end)
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["plugins"] = nil
+    package.loaded["plugins.billing.main"] = nil
+    package.loaded["plugins.custom_auth.main"] = nil
+    package.loaded["plugins.broken.main"] = nil
+  end)
+
+  describe("init()", function()
+    it("loads the plugins", function()
+      local plugins = require("plugins")
+      plugins.init({ "billing", "custom_auth" })
+
+      local loaded = plugins.get_plugins()
+      assert.is_not_nil(loaded["billing"])
+      assert.is_not_nil(loaded["custom_auth"])
+    end)
+
+    it("skips the plugins that cannot be loaded", function()
+      local plugins = require("plugins")
+      local s = spy.on(ngx, "log")
+
+      plugins.init({ "missing", "billing" })
+
+      local loaded = plugins.get_plugins()
+      assert.is_nil(loaded["missing"])
+      assert.is_not_nil(loaded["billing"])
+      assert.spy(s).was_called()
+    end)
+  end)
+
+  describe("run()", function()
+    it("runs the hooks of the current phase in order", function()
+      local plugins = require("plugins")
+      plugins.init({ "billing", "custom_auth" })
+
+      mock_ngx({ get_phase = function() return "access" end })
+      plugins.run({ "custom_auth", "billing" })
+
+      assert.are.same({ "custom_auth.access", "billing.access" }, calls)
+    end)
+
+    it("runs only the plugins enabled in the location", function()
+      local plugins = require("plugins")
+      plugins.init({ "billing", "custom_auth" })
+
+      mock_ngx({ get_phase = function() return "log" end })
+      plugins.run({ "billing" })
+
+      assert.are.same({ "billing.log" }, calls)
+    end)
+
+    it("continues when a plugin fails", function()
+      local plugins = require("plugins")
+      plugins.init({ "broken", "billing" })
+
+      mock_ngx({ get_phase = function() return "access" end, log = function() end })
+      plugins.run({ "broken", "billing" })
+
+      assert.are.same({ "billing.access" }, calls)
+    end)
+
+    it("does not run in an unsupported phase", function()
+      local plugins = require("plugins")
+      plugins.init({ "billing" })
+
+      mock_ngx({ get_phase = function() return "content" end, log = function() end })
+      plugins.run({ "billing" })
+
+      assert.are.same({}, calls)
+    end)
+  end)
+end)
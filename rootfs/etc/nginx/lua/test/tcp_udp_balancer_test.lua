@@ -0,0 +1,32 @@
+_G._TEST = true
+
+local tcp_udp_balancer = require("tcp_udp_balancer")
+
+describe("TCP/UDP balancer", function()
+  describe("get_implementation()", function()
+    it("uses round robin by default", function()
+      local implementation = tcp_udp_balancer.get_implementation({ name = "udp-default-dns-53" })
+      assert.are.equal("round_robin", implementation.name)
+    end)
+
+    it("uses consistent hashing when the backend has session persistence", function()
+      local implementation = tcp_udp_balancer.get_implementation({
+        name = "udp-default-dtls-4433",
+        upstreamHashByConfig = { ["upstream-hash-by"] = "$remote_addr" },
+      })
+      assert.are.equal("chash", implementation.name)
+    end)
+  end)
+
+  describe("backend_key()", function()
+    it("returns the protocol and the exposed port of the backend", function()
+      assert.are.equal("tcp-9000", tcp_udp_balancer.backend_key({ name = "tcp-default-echo-8080", port = 9000 }))
+      assert.are.equal("udp-53", tcp_udp_balancer.backend_key({ name = "udp-kube-system-kube-dns-53", port = 53 }))
+    end)
+
+    it("returns nil when the backend does not contain the expected fields", function()
+      assert.is_nil(tcp_udp_balancer.backend_key({ name = "tcp-default-echo-8080" }))
+      assert.is_nil(tcp_udp_balancer.backend_key({ name = "-", port = 9000 }))
+    end)
+  end)
+end)
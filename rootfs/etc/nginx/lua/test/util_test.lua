@@ -33,3 +33,57 @@ describe("lua_ngx_var", function()
     assert.equal(nil, util.lua_ngx_var("$foo_bar"))
   end)
 end)
+
+describe("generate_var_value", function()
+  local util = require("util")
+
+  before_each(function()
+    mock_ngx({ var = { host = "example.com", request_uri = "/alma/armud", [1] = "armud" } })
+  end)
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("returns the value of a single variable", function()
+    assert.equal("/alma/armud", util.generate_var_value("$request_uri"))
+    assert.equal("armud", util.generate_var_value("$1"))
+  end)
+
+  it("returns nil when a single variable is not defined", function()
+    assert.is_nil(util.generate_var_value("$foo_bar"))
+  end)
+
+  it("interpolates the variables in the string", function()
+    assert.equal("example.com/alma/armud", util.generate_var_value("$host$request_uri"))
+    assert.equal("key-example.com-", util.generate_var_value("key-$host-$foo_bar"))
+  end)
+end)
+
+describe("set_claim_headers", function()
+  local util = require("util")
+  local headers
+  local clear_header, set_header = ngx.req.clear_header, ngx.req.set_header
+
+  before_each(function()
+    headers = {}
+    ngx.req.clear_header = function(name) headers[name] = nil end
+    ngx.req.set_header = function(name, value) headers[name] = value end
+  end)
+
+  after_each(function()
+    ngx.req.clear_header, ngx.req.set_header = clear_header, set_header
+  end)
+
+  it("sets the claims and removes the headers sent by the client", function()
+    headers["X-Auth-Groups"] = "admin"
+
+    util.set_claim_headers({
+      { header = "X-Auth-User", claim = "sub" },
+      { header = "X-Auth-Roles", claim = "roles" },
+      { header = "X-Auth-Groups", claim = "groups" },
+    }, { sub = "jdoe", roles = { "reader", "writer" } })
+
+    assert.are.same({ ["X-Auth-User"] = "jdoe", ["X-Auth-Roles"] = "reader,writer" }, headers)
+  end)
+end)
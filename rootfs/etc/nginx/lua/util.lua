@@ -53,6 +53,21 @@ function _M.lua_ngx_var(ngx_var)
   return ngx.var[var_name]
 end
 
+-- generate_var_value returns the value of a string containing NGINX variables,
+-- like "$host$request_uri". A string with a single variable returns the value
+-- of the variable as is, nil when it is not defined
+function _M.generate_var_value(data)
+  if data:match("^%$[%w_]+$") then
+    return _M.lua_ngx_var(data)
+  end
+
+  local value = data:gsub("%$([%w_]+)", function(var_name)
+    return _M.lua_ngx_var("$" .. var_name) or ""
+  end)
+
+  return value
+end
+
 -- this implementation is taken from
 -- https://web.archive.org/web/20131225070434/http://snippets.luacode.org/snippets/Deep_Comparison_of_Two_Values_3
 -- and modified for use in this project
@@ -114,4 +129,54 @@ local function replace_special_char(str, a, b)
 end
 _M.replace_special_char = replace_special_char
 
+local function claim_value(value)
+  if type(value) == "table" then
+    local values = {}
+    for _, v in ipairs(value) do
+      values[#values + 1] = tostring(v)
+    end
+    return table.concat(values, ",")
+  end
+
+  return tostring(value)
+end
+
+-- sets the request headers sent to the upstream with the values of the
+-- claims of a token, removing the same headers when sent by the client
+function _M.set_claim_headers(claim_headers, claims)
+  for _, claim_header in ipairs(claim_headers) do
+    -- never trust the values sent by the client
+    ngx.req.clear_header(claim_header.header)
+
+    local value = claims and claims[claim_header.claim]
+    if value ~= nil then
+      ngx.req.set_header(claim_header.header, claim_value(value))
+    end
+  end
+end
+
+-- usage ratio of a shared dictionary above which a warning is logged
+local SHARED_DICT_USAGE_WARNING_THRESHOLD = 0.8
+
+-- check_shared_dict_usage logs a warning when the shared dictionary is
+-- almost full, before new items start failing or evicting others
+function _M.check_shared_dict_usage(name)
+  local dict = ngx.shared[name]
+  if not dict or not dict.capacity or not dict.free_space then
+    return
+  end
+
+  local capacity = dict:capacity()
+  if not capacity or capacity == 0 then
+    return
+  end
+
+  local used = capacity - dict:free_space()
+  if used / capacity > SHARED_DICT_USAGE_WARNING_THRESHOLD then
+    ngx.log(ngx.WARN, string.format("the Lua shared dictionary %s is %d%% full (%d of %d bytes), "
+      .. "increase its size with the lua-shared-dicts setting", name, math.floor(used * 100 / capacity),
+      used, capacity))
+  end
+end
+
 return _M
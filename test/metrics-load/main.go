@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// metrics-load floods the unix socket of the metrics collector with
+// synthetic batches of statistics, like the ones sent by the Lua monitor of
+// the NGINX workers, and reports the throughput of the collector, the dropped
+// messages and the memory used.
+//
+// By default the collector runs in the same process. With --in-process=false
+// the batches are sent to the socket of a running controller and only the
+// statistics of the sender are reported.
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/pflag"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+	"k8s.io/ingress-nginx/test/metrics-load/synthetic"
+)
+
+// number of different batches sent by each worker
+const batchVariants = 16
+
+type stats struct {
+	batches       uint64
+	records       uint64
+	failedBatches uint64
+	failedRecords uint64
+}
+
+func main() {
+	flags := pflag.NewFlagSet("metrics-load", pflag.ExitOnError)
+
+	var (
+		rps = flags.Int("rps", 10000,
+			`Records sent per second, across all the workers.`)
+		batchSize = flags.Int("batch-size", 1000,
+			`Records per batch. The Lua monitor sends up to 10000 records per worker every second.`)
+		workers = flags.Int("workers", 4,
+			`Concurrent senders, like the NGINX worker processes.`)
+		hosts = flags.Int("hosts", 100,
+			`Number of different hosts, each one with its own Ingress and Service.`)
+		duration = flags.Duration("duration", 30*time.Second,
+			`Duration of the test.`)
+		inProcess = flags.Bool("in-process", true,
+			`Run the collector in this process. It must not run alongside a controller using the same socket.`)
+		metricsPerHost = flags.Bool("metrics-per-host", true,
+			`Export metrics per-host in the collector run in this process.`)
+	)
+
+	flags.Parse(os.Args)
+
+	if *rps < 1 || *batchSize < 1 || *workers < 1 || *hosts < 1 {
+		fmt.Fprintf(os.Stderr, "Flags --rps, --batch-size, --workers and --hosts must be at least one\n")
+		os.Exit(1)
+	}
+
+	var registry *prometheus.Registry
+	if *inProcess {
+		sc, err := collectors.NewSocketCollector("metrics-load", "default", "nginx", *metricsPerHost, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unexpected error creating the socket collector: %v\n", err)
+			os.Exit(1)
+		}
+		defer sc.Stop()
+
+		sc.SetHosts(synthetic.Hosts(*hosts))
+
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(sc)
+
+		go sc.Start()
+	}
+
+	batches := syntheticBatches(*workers, *batchSize, *hosts)
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	// every worker sends its batches at the same interval
+	interval := time.Duration(float64(time.Second) * float64(*batchSize**workers) / float64(*rps))
+
+	start := time.Now()
+	sent := send(batches, *batchSize, interval, *duration)
+	elapsed := time.Since(start)
+
+	// the memory of the batches is not part of the collector
+	batches = nil
+
+	fmt.Printf("sent:      %v batches, %v records in %v (%.0f records/s, target %v)\n",
+		sent.batches, sent.records, elapsed.Round(time.Millisecond), float64(sent.records)/elapsed.Seconds(), *rps)
+	fmt.Printf("failed:    %v batches, %v records\n", sent.failedBatches, sent.failedRecords)
+
+	if registry == nil {
+		return
+	}
+
+	processed, drained := waitProcessed(registry, sent.records-sent.failedRecords)
+	elapsed = drained.Sub(start)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	gcs := after.NumGC - before.NumGC
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	perRecord := float64(allocated)
+	if processed > 0 {
+		perRecord /= float64(processed)
+	}
+
+	fmt.Printf("processed: %v records in %v (%.0f records/s)\n",
+		processed, elapsed.Round(time.Millisecond), float64(processed)/elapsed.Seconds())
+	fmt.Printf("dropped:   %v records\n", sent.records-processed)
+	fmt.Printf("memory:    %.1f MiB heap after GC, %.0f B allocated per record (sender included), %v GC cycles\n",
+		float64(after.HeapAlloc)/(1<<20), perRecord, gcs)
+}
+
+// syntheticBatches returns the batches sent by each worker
+func syntheticBatches(workers, batchSize, hosts int) [][][]byte {
+	batches := make([][][]byte, workers)
+	for w := range batches {
+		batches[w] = make([][]byte, batchVariants)
+		for i := range batches[w] {
+			batches[w][i] = synthetic.Batch(batchSize, hosts, (w*batchVariants+i)*batchSize)
+		}
+	}
+
+	return batches
+}
+
+// send runs a worker per list of batches, sending them to the socket until
+// the duration is over
+func send(workerBatches [][][]byte, batchSize int, interval, duration time.Duration) stats {
+	var (
+		total stats
+		wg    sync.WaitGroup
+	)
+
+	deadline := time.Now().Add(duration)
+	for _, batches := range workerBatches {
+		batches := batches
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for i := 0; time.Now().Before(deadline); i++ {
+				err := sendBatch(batches[i%len(batches)])
+
+				atomic.AddUint64(&total.batches, 1)
+				atomic.AddUint64(&total.records, uint64(batchSize))
+				if err != nil {
+					atomic.AddUint64(&total.failedBatches, 1)
+					atomic.AddUint64(&total.failedRecords, uint64(batchSize))
+				}
+
+				<-ticker.C
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return total
+}
+
+// sendBatch sends a batch like the Lua monitor, using a new connection
+func sendBatch(batch []byte) error {
+	conn, err := net.Dial("unix", collectors.SocketPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(batch)
+	return err
+}
+
+// waitProcessed waits until the collector processed the expected records,
+// or stops processing new ones, and returns the number of records processed
+func waitProcessed(registry *prometheus.Registry, expected uint64) (uint64, time.Time) {
+	var (
+		processed uint64
+		last      = time.Now()
+	)
+
+	for {
+		current := processedRecords(registry)
+		if current != processed {
+			processed = current
+			last = time.Now()
+		}
+
+		if processed >= expected || time.Since(last) > 2*time.Second {
+			return processed, last
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// processedRecords returns the number of records counted by the collector
+func processedRecords(registry *prometheus.Registry) uint64 {
+	families, err := registry.Gather()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unexpected error gathering the metrics: %v\n", err)
+		return 0
+	}
+
+	var total float64
+	for _, family := range families {
+		if family.GetName() != "nginx_ingress_controller_requests" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+
+	return uint64(total)
+}
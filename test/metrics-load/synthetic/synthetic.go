@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package synthetic generates batches of statistics like the ones sent by
+// the Lua monitor of the NGINX workers, to load the metrics collector in
+// benchmarks and load tests.
+package synthetic
+
+import (
+	"bytes"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	syntheticStatus  = []string{"200", "200", "200", "201", "301", "404", "500"}
+	syntheticMethods = []string{"GET", "GET", "POST", "PUT"}
+	syntheticPaths   = []string{"/", "/api", "/static"}
+)
+
+// Host returns the name of the n-th host used by Batch
+func Host(n int) string {
+	return fmt.Sprintf("host-%v.example.com", n)
+}
+
+// Hosts returns the hosts used by Batch, to be served by the collector
+// with SetHosts
+func Hosts(hosts int) sets.String {
+	s := sets.NewString()
+	for i := 0; i < hosts; i++ {
+		s.Insert(Host(i))
+	}
+
+	return s
+}
+
+// Batch returns a batch of statistics in the format sent by the Lua
+// monitor, with records spread over the given number of hosts. Each host is
+// served by its own Ingress and Service. The first record is offset, to
+// generate different batches.
+func Batch(records, hosts, offset int) []byte {
+	if hosts < 1 {
+		hosts = 1
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < records; i++ {
+		n := offset + i
+		host := n % hosts
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"host":"%v","namespace":"default","ingress":"ingress-%v","service":"service-%v",`+
+			`"path":"%v","method":"%v","status":"%v",`+
+			`"requestLength":%v,"requestTime":%v,"responseLength":%v,`+
+			`"upstreamLatency":%v,"upstreamResponseTime":%v,"upstreamResponseLength":%v,`+
+			`"upstreamName":"default-service-%v-80","upstreamIP":"10.0.0.%v:8080","upstreamStatus":"200"}`,
+			Host(host), host, host,
+			syntheticPaths[n%len(syntheticPaths)], syntheticMethods[n%len(syntheticMethods)], syntheticStatus[n%len(syntheticStatus)],
+			200+n%300, float64(n%1000)/1000, 1000+n%5000,
+			float64(n%10)/1000, float64(n%1000)/1000, 800+n%5000,
+			host, host%250)
+	}
+	buf.WriteByte(']')
+
+	return buf.Bytes()
+}